@@ -758,13 +758,28 @@ func (w *Walker) writeType(buf *bytes.Buffer, typ types.Type) {
 
 	case *types.Interface:
 		buf.WriteString("interface{")
-		if typ.NumMethods() > 0 {
+		if elems := append(sortedMethodNames(typ), w.typeSetTerms(typ)...); len(elems) > 0 {
 			buf.WriteByte(' ')
-			buf.WriteString(strings.Join(sortedMethodNames(typ), ", "))
+			buf.WriteString(strings.Join(elems, ", "))
 			buf.WriteByte(' ')
 		}
 		buf.WriteString("}")
 
+	case *types.Union:
+		for i := 0; i < typ.Len(); i++ {
+			if i > 0 {
+				buf.WriteString(" | ")
+			}
+			term := typ.Term(i)
+			if term.Tilde() {
+				buf.WriteByte('~')
+			}
+			w.writeType(buf, term.Type())
+		}
+
+	case *types.TypeParam:
+		buf.WriteString(typ.Obj().Name())
+
 	case *types.Map:
 		buf.WriteString("map[")
 		w.writeType(buf, typ.Key())
@@ -794,6 +809,16 @@ func (w *Walker) writeType(buf *bytes.Buffer, typ types.Type) {
 			buf.WriteByte('.')
 		}
 		buf.WriteString(typ.Obj().Name())
+		if targs := typ.TypeArgs(); targs.Len() > 0 {
+			buf.WriteByte('[')
+			for i := 0; i < targs.Len(); i++ {
+				if i > 0 {
+					buf.WriteString(", ")
+				}
+				w.writeType(buf, targs.At(i))
+			}
+			buf.WriteByte(']')
+		}
 
 	default:
 		panic(fmt.Sprintf("unknown type %T", typ))
@@ -801,6 +826,9 @@ func (w *Walker) writeType(buf *bytes.Buffer, typ types.Type) {
 }
 
 func (w *Walker) writeSignature(buf *bytes.Buffer, sig *types.Signature) {
+	if tparams := sig.TypeParams(); tparams != nil {
+		w.writeTypeParams(buf, tparams)
+	}
 	w.writeParams(buf, sig.Params(), sig.Variadic())
 	switch res := sig.Results(); res.Len() {
 	case 0:
@@ -814,6 +842,36 @@ func (w *Walker) writeSignature(buf *bytes.Buffer, sig *types.Signature) {
 	}
 }
 
+func (w *Walker) writeTypeParams(buf *bytes.Buffer, tparams *types.TypeParamList) {
+	buf.WriteByte('[')
+	for i, n := 0, tparams.Len(); i < n; i++ {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		tp := tparams.At(i)
+		w.writeType(buf, tp)
+		buf.WriteByte(' ')
+		w.writeType(buf, tp.Constraint())
+	}
+	buf.WriteByte(']')
+}
+
+// typeSetTerms returns the embedded elements of the constraint
+// interface typ that restrict its type set, such as ~int | ~string.
+// Embedded interfaces are omitted: their methods are already
+// part of typ's method set.
+func (w *Walker) typeSetTerms(typ *types.Interface) []string {
+	var terms []string
+	for i := 0; i < typ.NumEmbeddeds(); i++ {
+		t := typ.EmbeddedType(i)
+		if _, ok := t.Underlying().(*types.Interface); ok {
+			continue
+		}
+		terms = append(terms, w.typeString(t))
+	}
+	return terms
+}
+
 func (w *Walker) writeParams(buf *bytes.Buffer, t *types.Tuple, variadic bool) {
 	buf.WriteByte('(')
 	for i, n := 0, t.Len(); i < n; i++ {
@@ -872,6 +930,12 @@ func (w *Walker) emitType(obj *types.TypeName) {
 		w.emitf("type %s = %s", name, w.typeString(typ))
 		return
 	}
+	if tparams := typ.(*types.Named).TypeParams(); tparams != nil {
+		var buf bytes.Buffer
+		buf.WriteString(name)
+		w.writeTypeParams(&buf, tparams)
+		name = buf.String()
+	}
 	switch typ := typ.Underlying().(type) {
 	case *types.Struct:
 		w.emitStructType(name, typ)
@@ -960,13 +1024,14 @@ func (w *Walker) emitIfaceType(name string, typ *types.Interface) {
 		return
 	}
 
-	if len(methodNames) == 0 {
+	sort.Strings(methodNames)
+	elems := append(methodNames, w.typeSetTerms(typ)...)
+	if len(elems) == 0 {
 		w.emitf("type %s interface {}", name)
 		return
 	}
 
-	sort.Strings(methodNames)
-	w.emitf("type %s interface { %s }", name, strings.Join(methodNames, ", "))
+	w.emitf("type %s interface { %s }", name, strings.Join(elems, ", "))
 }
 
 func (w *Walker) emitFunc(f *types.Func) {
@@ -990,7 +1055,19 @@ func (w *Walker) emitMethod(m *types.Selection) {
 			log.Fatalf("exported method with unexported receiver base type: %s", m)
 		}
 	}
-	w.emitf("method (%s) %s%s", w.typeString(recv), m.Obj().Name(), w.signatureString(sig))
+	recvString := w.typeString(recv)
+	if rparams := sig.RecvTypeParams(); rparams != nil {
+		// The receiver of a method of a generic type
+		// is written with its type parameters, as Null[T].
+		names := make([]string, rparams.Len())
+		for i := range names {
+			names[i] = rparams.At(i).Obj().Name()
+		}
+		if named, ok := recv.(*types.Named); !ok || named.TypeArgs().Len() == 0 {
+			recvString += "[" + strings.Join(names, ", ") + "]"
+		}
+	}
+	w.emitf("method (%s) %s%s", recvString, m.Obj().Name(), w.signatureString(sig))
 }
 
 func (w *Walker) emitf(format string, args ...interface{}) {
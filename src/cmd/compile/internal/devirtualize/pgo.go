@@ -0,0 +1,185 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package devirtualize
+
+import (
+	"strings"
+
+	"cmd/compile/internal/base"
+	"cmd/compile/internal/ir"
+	"cmd/compile/internal/pgo"
+	"cmd/compile/internal/typecheck"
+	"cmd/compile/internal/types"
+	"cmd/internal/src"
+)
+
+// ProfileGuided devirtualizes the interface method calls in fn whose
+// profile shows that they are hot and usually call one method of this
+// package. Each such call
+//
+//	x.M(args)
+//
+// is rewritten to the equivalent of
+//
+//	if c, ok := x.(T); ok {
+//		c.M(args)
+//	} else {
+//		x.M(args)
+//	}
+//
+// where T is the receiver type of the dominant callee, so that the
+// direct call can be inlined. ProfileGuided must be called after
+// inline.CanInline(fn): the rewritten calls must not appear in the
+// inlinable body of fn, which may be exported. For the same reason,
+// calls in closures are not rewritten.
+func ProfileGuided(fn *ir.Func, p *pgo.Profile) {
+	if methods == nil {
+		methods = localMethods()
+	}
+	var edit func(n ir.Node) ir.Node
+	edit = func(n ir.Node) ir.Node {
+		switch n.Op() {
+		case ir.OCLOSURE:
+			return n
+		case ir.ODEFER, ir.OGO:
+			// The call must stay a call; only its
+			// arguments may be rewritten.
+			n := n.(*ir.GoDeferStmt)
+			ir.EditChildren(n.Call, edit)
+			return n
+		}
+		ir.EditChildren(n, edit)
+		if call, ok := n.(*ir.CallExpr); ok && call.Op() == ir.OCALLINTER {
+			if m := hotMethod(fn, call, p); m != nil {
+				return rewriteCondCall(fn, call, m)
+			}
+		}
+		return n
+	}
+	ir.WithFunc(fn, func() {
+		ir.EditChildren(fn, edit)
+	})
+}
+
+// methods maps the names of the methods declared in the package
+// being compiled to their functions.
+var methods map[string]*ir.Func
+
+// localMethods returns the methods declared in the package being
+// compiled, by the names the runtime reports for them.
+func localMethods() map[string]*ir.Func {
+	methods := make(map[string]*ir.Func)
+	for _, n := range typecheck.Target.Decls {
+		if n.Op() != ir.ODCLFUNC {
+			continue
+		}
+		fn := n.(*ir.Func)
+		recv := fn.Type().Recv()
+		if recv == nil || recv.Type.HasTParam() || recv.Type.HasShape() {
+			continue
+		}
+		methods[ir.PkgFuncName(fn)] = fn
+	}
+	return methods
+}
+
+// hotMethod returns the method of this package that the profile
+// shows call usually reaches, if call is hot and the method can be
+// called directly in its place.
+func hotMethod(curfn *ir.Func, call *ir.CallExpr, p *pgo.Profile) *ir.Func {
+	sel := call.X.(*ir.SelectorExpr)
+	callee, ok := p.HotCallee(ir.PkgFuncName(curfn), line(curfn.Pos()), line(call.Pos()), func(callee string) bool {
+		return methods[callee] != nil && strings.HasSuffix(callee, "."+sel.Sel.Name)
+	})
+	if !ok {
+		return nil
+	}
+	m := methods[callee]
+	if !typecheck.Implements(m.Type().Recv().Type, sel.X.Type()) {
+		return nil
+	}
+	return m
+}
+
+// rewriteCondCall returns an OINLCALL replacing the interface method
+// call with a call to method m when the receiver's dynamic type is m's
+// receiver type, and with the original call otherwise.
+func rewriteCondCall(curfn *ir.Func, call *ir.CallExpr, m *ir.Func) ir.Node {
+	pos := call.Pos()
+	sel := call.X.(*ir.SelectorExpr)
+	typ := m.Type().Recv().Type
+
+	if base.Flag.LowerM != 0 {
+		base.WarnfAt(pos, "PGO devirtualizing %v to %v", sel, typ)
+	}
+
+	// Evaluate the receiver and arguments once, before the type switch.
+	init := ir.TakeInit(call)
+	temp := func(x ir.Node) *ir.Name {
+		v := typecheck.TempAt(pos, curfn, x.Type())
+		init.Append(ir.NewDecl(pos, ir.ODCL, v))
+		init.Append(typecheck.Stmt(ir.NewAssignStmt(pos, v, x)))
+		return v
+	}
+	recv := temp(sel.X)
+	args := make([]ir.Node, len(call.Args))
+	for i, arg := range call.Args {
+		args[i] = temp(arg)
+	}
+
+	var retvars []ir.Node
+	if t := call.Type(); t != nil {
+		if t.IsFuncArgStruct() {
+			for _, f := range t.FieldSlice() {
+				retvars = append(retvars, typecheck.TempAt(pos, curfn, f.Type))
+			}
+		} else {
+			retvars = append(retvars, typecheck.TempAt(pos, curfn, t))
+		}
+	}
+	for _, v := range retvars {
+		init.Append(ir.NewDecl(pos, ir.ODCL, v.(*ir.Name)))
+	}
+
+	c := typecheck.TempAt(pos, curfn, typ)
+	ok := typecheck.TempAt(pos, curfn, types.Types[types.TBOOL])
+	dt := ir.NewTypeAssertExpr(pos, recv, nil)
+	dt.SetType(typ)
+	init.Append(typecheck.Stmt(ir.NewAssignListStmt(pos, ir.OAS2, []ir.Node{c, ok}, []ir.Node{dt})))
+
+	direct := typecheck.Call(pos, typecheck.Callee(ir.NewSelectorExpr(pos, ir.OXDOT, c, sel.Sel)), copyNodes(args), call.IsDDD)
+
+	sel.X = recv
+	call.Args = copyNodes(args)
+
+	nif := ir.NewIfStmt(pos, ok, []ir.Node{assignResults(pos, retvars, direct)}, []ir.Node{assignResults(pos, retvars, call)})
+	nif.Likely = true
+
+	res := ir.NewInlinedCallExpr(pos, []ir.Node{typecheck.Stmt(nif)}, retvars)
+	res.SetInit(init)
+	res.SetType(call.Type())
+	res.SetTypecheck(1)
+	return res
+}
+
+// assignResults returns a statement assigning the results of call to retvars.
+func assignResults(pos src.XPos, retvars []ir.Node, call ir.Node) ir.Node {
+	switch len(retvars) {
+	case 0:
+		return call
+	case 1:
+		return typecheck.Stmt(ir.NewAssignStmt(pos, retvars[0], call))
+	}
+	return typecheck.Stmt(ir.NewAssignListStmt(pos, ir.OAS2, copyNodes(retvars), []ir.Node{call}))
+}
+
+func copyNodes(list []ir.Node) []ir.Node {
+	return append([]ir.Node(nil), list...)
+}
+
+// line returns the line number of pos as reported by the runtime.
+func line(pos src.XPos) int {
+	return int(base.Ctxt.InnermostPos(pos).RelLine())
+}
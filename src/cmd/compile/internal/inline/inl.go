@@ -32,8 +32,10 @@ import (
 	"strings"
 
 	"cmd/compile/internal/base"
+	"cmd/compile/internal/devirtualize"
 	"cmd/compile/internal/ir"
 	"cmd/compile/internal/logopt"
+	"cmd/compile/internal/pgo"
 	"cmd/compile/internal/typecheck"
 	"cmd/compile/internal/types"
 	"cmd/internal/obj"
@@ -51,10 +53,28 @@ const (
 
 	inlineBigFunctionNodes   = 5000 // Functions with this many nodes are considered "big".
 	inlineBigFunctionMaxCost = 20   // Max cost of inlinee when inlining into a "big" function.
+
+	// With a profile, functions called from hot call sites may cost up
+	// to inlineHotMaxBudget, and are inlined only at hot call sites if
+	// they cost more than inlineMaxBudget.
+	inlineHotMaxBudget = 2000
 )
 
+// profile is the profile used to guide inlining, if any.
+var profile *pgo.Profile
+
+// inlinedFuncs maps the inlining tree indexes created while inlining
+// with a profile to the inlined functions.
+var inlinedFuncs map[int]*ir.Func
+
 // InlinePackage finds functions that can be inlined and clones them before walk expands them.
-func InlinePackage() {
+// If p is not nil, it is used to find the hot call sites and to devirtualize hot
+// interface method calls before inlining.
+func InlinePackage(p *pgo.Profile) {
+	profile = p
+	if p != nil {
+		inlinedFuncs = make(map[int]*ir.Func)
+	}
 	ir.VisitFuncsBottomUp(typecheck.Target.Decls, func(list []*ir.Func, recursive bool) {
 		numfns := numNonClosures(list)
 		for _, n := range list {
@@ -68,6 +88,9 @@ func InlinePackage() {
 					fmt.Printf("%v: cannot inline %v: recursive\n", ir.Line(n), n.Nname)
 				}
 			}
+			if p != nil {
+				devirtualize.ProfileGuided(n, p)
+			}
 			InlineCalls(n)
 		}
 	})
@@ -166,8 +189,13 @@ func CanInline(fn *ir.Func) {
 	// locals, and we use this map to produce a pruned Inline.Dcl
 	// list. See issue 25249 for more context.
 
+	budget := int32(inlineMaxBudget)
+	if profile != nil && profile.IsHotFunc(ir.PkgFuncName(fn)) {
+		budget = inlineHotMaxBudget
+	}
 	visitor := hairyVisitor{
-		budget:        inlineMaxBudget,
+		budget:        budget,
+		maxBudget:     budget,
 		extraCallCost: cc,
 	}
 	if visitor.tooHairy(fn) {
@@ -176,7 +204,7 @@ func CanInline(fn *ir.Func) {
 	}
 
 	n.Func.Inl = &ir.Inline{
-		Cost: inlineMaxBudget - visitor.budget,
+		Cost: budget - visitor.budget,
 		Dcl:  pruneUnusedAutos(n.Defn.(*ir.Func).Dcl, &visitor),
 		Body: inlcopylist(fn.Body),
 
@@ -184,12 +212,12 @@ func CanInline(fn *ir.Func) {
 	}
 
 	if base.Flag.LowerM > 1 {
-		fmt.Printf("%v: can inline %v with cost %d as: %v { %v }\n", ir.Line(fn), n, inlineMaxBudget-visitor.budget, fn.Type(), ir.Nodes(n.Func.Inl.Body))
+		fmt.Printf("%v: can inline %v with cost %d as: %v { %v }\n", ir.Line(fn), n, n.Func.Inl.Cost, fn.Type(), ir.Nodes(n.Func.Inl.Body))
 	} else if base.Flag.LowerM != 0 {
 		fmt.Printf("%v: can inline %v\n", ir.Line(fn), n)
 	}
 	if logopt.Enabled() {
-		logopt.LogOpt(fn.Pos(), "canInlineFunction", "inline", ir.FuncName(fn), fmt.Sprintf("cost: %d", inlineMaxBudget-visitor.budget))
+		logopt.LogOpt(fn.Pos(), "canInlineFunction", "inline", ir.FuncName(fn), fmt.Sprintf("cost: %d", n.Func.Inl.Cost))
 	}
 }
 
@@ -229,6 +257,7 @@ func canDelayResults(fn *ir.Func) bool {
 // hairiness and whether or not it can be inlined.
 type hairyVisitor struct {
 	budget        int32
+	maxBudget     int32
 	reason        string
 	extraCallCost int32
 	usedLocals    ir.NameSet
@@ -241,7 +270,7 @@ func (v *hairyVisitor) tooHairy(fn *ir.Func) bool {
 		return true
 	}
 	if v.budget < 0 {
-		v.reason = fmt.Sprintf("function too complex: cost %d exceeds budget %d", inlineMaxBudget-v.budget, inlineMaxBudget)
+		v.reason = fmt.Sprintf("function too complex: cost %d exceeds budget %d", v.maxBudget-v.budget, v.maxBudget)
 		return true
 	}
 	return false
@@ -547,6 +576,17 @@ func inlnode(n ir.Node, maxCost int32, inlMap map[*ir.Func]bool, edit func(ir.No
 	case ir.OTAILCALL:
 		n := n.(*ir.TailCallStmt)
 		n.Call.NoInline = true // Not inline a tail call for now. Maybe we could inline it just like RETURN fn(arg)?
+	case ir.OFOR:
+		n := n.(*ir.ForStmt)
+		if isTestingBLoop(n) {
+			// Keep the calls in the body of a "for b.Loop()" benchmark
+			// loop, so that they are not optimized away.
+			ir.VisitList(n.Body, func(n ir.Node) {
+				if n.Op() == ir.OCALLFUNC {
+					n.(*ir.CallExpr).NoInline = true
+				}
+			})
+		}
 
 	// TODO do them here (or earlier),
 	// so escape analysis can avoid more heapmoves.
@@ -600,6 +640,23 @@ func inlnode(n ir.Node, maxCost int32, inlMap map[*ir.Func]bool, edit func(ir.No
 	return n
 }
 
+// isTestingBLoop reports whether n is a "for b.Loop() { ... }" loop,
+// where b is a *testing.B.
+func isTestingBLoop(n *ir.ForStmt) bool {
+	if n.Cond == nil || n.Cond.Op() != ir.OCALLFUNC {
+		return false
+	}
+	call := n.Cond.(*ir.CallExpr)
+	if call.X.Op() != ir.OMETHEXPR {
+		return false
+	}
+	meth := ir.MethodExprName(call.X)
+	if meth == nil || meth.Sym().Name != "(*B).Loop" {
+		return false
+	}
+	return meth.Sym().Pkg.Path == "testing"
+}
+
 // inlCallee takes a function-typed expression and returns the underlying function ONAME
 // that it refers to if statically known. Otherwise, it returns nil.
 func inlCallee(fn ir.Node) *ir.Func {
@@ -675,11 +732,18 @@ func mkinlcall(n *ir.CallExpr, fn *ir.Func, maxCost int32, inlMap map[*ir.Func]b
 	if fn.Inl.Cost > maxCost {
 		// The inlined function body is too big. Typically we use this check to restrict
 		// inlining into very big functions.  See issue 26546 and 17566.
-		if logopt.Enabled() {
-			logopt.LogOpt(n.Pos(), "cannotInlineCall", "inline", ir.FuncName(ir.CurFunc),
-				fmt.Sprintf("cost %d of %s exceeds max large caller cost %d", fn.Inl.Cost, ir.PkgFuncName(fn), maxCost))
+		// Functions inlinable only because they are hot are inlined
+		// only at hot call sites, but those may be in big functions.
+		if !isHotCallSite(n, fn) {
+			if logopt.Enabled() {
+				logopt.LogOpt(n.Pos(), "cannotInlineCall", "inline", ir.FuncName(ir.CurFunc),
+					fmt.Sprintf("cost %d of %s exceeds max large caller cost %d", fn.Inl.Cost, ir.PkgFuncName(fn), maxCost))
+			}
+			return n
+		}
+		if base.Flag.LowerM > 1 {
+			fmt.Printf("%v: inlining hot call to %v with cost %d\n", ir.Line(n), fn, fn.Inl.Cost)
 		}
-		return n
 	}
 
 	if fn == ir.CurFunc {
@@ -717,6 +781,9 @@ func mkinlcall(n *ir.CallExpr, fn *ir.Func, maxCost int32, inlMap map[*ir.Func]b
 
 	sym := fn.Linksym()
 	inlIndex := base.Ctxt.InlTree.Add(parent, n.Pos(), sym)
+	if inlinedFuncs != nil {
+		inlinedFuncs[inlIndex] = fn
+	}
 
 	if base.Flag.GenDwarfInl > 0 {
 		if !sym.WasInlined() {
@@ -752,6 +819,22 @@ func mkinlcall(n *ir.CallExpr, fn *ir.Func, maxCost int32, inlMap map[*ir.Func]b
 	return res
 }
 
+// isHotCallSite reports whether the profile shows the call n to fn is hot.
+func isHotCallSite(n *ir.CallExpr, fn *ir.Func) bool {
+	if profile == nil {
+		return false
+	}
+	// If the call is in a body inlined into the current function,
+	// the profile records it as a call from the inlined function.
+	pos := base.Ctxt.InnermostPos(n.Pos())
+	caller := ir.CurFunc
+	if f := inlinedFuncs[pos.Base().InliningIndex()]; f != nil {
+		caller = f
+	}
+	start := base.Ctxt.InnermostPos(caller.Pos()).RelLine()
+	return profile.IsHotCall(ir.PkgFuncName(caller), int(start), int(pos.RelLine()), ir.PkgFuncName(fn))
+}
+
 // CalleeEffects appends any side effects from evaluating callee to init.
 func CalleeEffects(init *ir.Nodes, callee ir.Node) {
 	for {
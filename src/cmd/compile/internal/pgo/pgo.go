@@ -0,0 +1,250 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pgo reads CPU profiles in the pprof format for
+// profile-guided optimization.
+//
+// A profile is usually collected from a different build of the
+// program than the one being compiled, so code is matched by
+// function name and by line offset from the start of the function
+// rather than by address. Call sites and lines that have moved
+// relative to their function's start are simply not matched.
+package pgo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// hotCallSiteCDF is the percentage of the total call edge weight
+// covered by the call sites considered hot: the hottest call sites
+// are taken, in decreasing order of weight, until together they
+// account for this fraction of the weight.
+const hotCallSiteCDF = 99
+
+// A Profile holds the information the compiler uses from a CPU profile.
+type Profile struct {
+	// TotalWeight is the total weight of all samples.
+	TotalWeight int64
+
+	// startLine maps a function name to its first line, if known.
+	// Lines in functions without a known start are absolute.
+	startLine map[string]int
+
+	edges      map[callEdge]int64
+	sites      map[funcLine][]calleeWeight // callees by call site, heaviest first
+	lines      map[funcLine]int64
+	hotFuncs   map[string]bool
+	hotEdgeMin int64 // minimum weight of a hot call edge; 0 if there are none
+}
+
+// A funcLine identifies a line within a function, by its offset
+// from the function's start line.
+type funcLine struct {
+	fn     string
+	offset int
+}
+
+// A callEdge is a call from a line in the caller to callee.
+type callEdge struct {
+	site   funcLine
+	callee string
+}
+
+type calleeWeight struct {
+	callee string
+	weight int64
+}
+
+// Open reads the profile in the named file.
+func Open(file string) (*Profile, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return p, nil
+}
+
+// Parse parses a profile in the pprof format, which may be gzip-compressed.
+func Parse(data []byte) (*Profile, error) {
+	raw, err := decodeProfile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use the CPU time if the profile records it,
+	// or else the last sample value.
+	vi := len(raw.sampleTypes) - 1
+	for i, t := range raw.sampleTypes {
+		if raw.str(t) == "cpu" {
+			vi = i
+		}
+	}
+	if vi < 0 {
+		return nil, fmt.Errorf("profile has no sample types")
+	}
+
+	p := &Profile{
+		startLine: make(map[string]int),
+		edges:     make(map[callEdge]int64),
+		sites:     make(map[funcLine][]calleeWeight),
+		lines:     make(map[funcLine]int64),
+		hotFuncs:  make(map[string]bool),
+	}
+
+	// The same function may appear under several IDs, and its start
+	// line is unknown where it was only seen inlined, so record the
+	// start line by name.
+	for _, f := range raw.funcs {
+		if f.startLine > 0 {
+			p.startLine[raw.str(f.name)] = int(f.startLine)
+		}
+	}
+
+	type frame struct {
+		fn   string
+		line int
+	}
+	var stack []frame
+	seen := make(map[funcLine]bool)
+	for _, s := range raw.samples {
+		if vi >= len(s.values) {
+			continue
+		}
+		w := s.values[vi]
+		if w <= 0 {
+			continue
+		}
+		p.TotalWeight += w
+
+		// Locations are listed leaf first, and the lines of
+		// a location innermost first, so stack runs from the
+		// leaf to the root.
+		stack = stack[:0]
+		for _, id := range s.locs {
+			for _, l := range raw.locs[id] {
+				f, ok := raw.funcs[l.fn]
+				if !ok {
+					return nil, errMalformed
+				}
+				stack = append(stack, frame{raw.str(f.name), int(l.line)})
+			}
+		}
+
+		// Weigh each line once per sample, however many times it
+		// appears in a recursive stack.
+		for k := range seen {
+			delete(seen, k)
+		}
+		for i, f := range stack {
+			fl := p.lineKey(f.fn, 0, f.line)
+			if !seen[fl] {
+				seen[fl] = true
+				p.lines[fl] += w
+			}
+			if i+1 < len(stack) {
+				caller := stack[i+1]
+				p.edges[callEdge{p.lineKey(caller.fn, 0, caller.line), f.fn}] += w
+			}
+		}
+	}
+
+	p.computeHot()
+	return p, nil
+}
+
+// computeHot finds the hot call edges and builds the per-site
+// callee lists.
+func (p *Profile) computeHot() {
+	var all []callEdge
+	var total int64
+	for e, w := range p.edges {
+		all = append(all, e)
+		total += w
+		p.sites[e.site] = append(p.sites[e.site], calleeWeight{e.callee, w})
+	}
+	for _, cs := range p.sites {
+		sort.Slice(cs, func(i, j int) bool {
+			if cs[i].weight != cs[j].weight {
+				return cs[i].weight > cs[j].weight
+			}
+			return cs[i].callee < cs[j].callee
+		})
+	}
+	if total == 0 {
+		return
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return p.edges[all[i]] > p.edges[all[j]]
+	})
+	var cum int64
+	for _, e := range all {
+		w := p.edges[e]
+		p.hotEdgeMin = w
+		cum += w
+		if cum*100 >= total*hotCallSiteCDF {
+			break
+		}
+	}
+	for e, w := range p.edges {
+		if p.isHot(w) {
+			p.hotFuncs[e.callee] = true
+		}
+	}
+}
+
+func (p *Profile) isHot(w int64) bool {
+	return p.hotEdgeMin > 0 && w >= p.hotEdgeMin
+}
+
+// IsHotFunc reports whether fn is the callee of a hot call edge.
+func (p *Profile) IsHotFunc(fn string) bool {
+	return p.hotFuncs[fn]
+}
+
+// IsHotCall reports whether the call from caller to callee at the
+// given line is hot. The caller starts at line start. Lines are
+// absolute line numbers, as reported by the runtime.
+func (p *Profile) IsHotCall(caller string, start, line int, callee string) bool {
+	return p.isHot(p.edges[callEdge{p.lineKey(caller, start, line), callee}])
+}
+
+// HotCallee returns the callee that receives the most weight from the
+// calls at the given line of caller, among those for which match
+// reports true, if that call edge is hot.
+func (p *Profile) HotCallee(caller string, start, line int, match func(callee string) bool) (callee string, ok bool) {
+	for _, c := range p.sites[p.lineKey(caller, start, line)] {
+		if match(c.callee) {
+			return c.callee, p.isHot(c.weight)
+		}
+	}
+	return "", false
+}
+
+// LineWeight returns the total weight of the samples whose stacks
+// include the given line of fn, which starts at line start.
+func (p *Profile) LineWeight(fn string, start, line int) int64 {
+	return p.lines[p.lineKey(fn, start, line)]
+}
+
+// lineKey returns the key for a line in fn, which starts at line start.
+// Lines are matched by offset from the start if the profile recorded
+// where fn starts, and by absolute line otherwise. A start of 0 means
+// to use the start recorded in the profile.
+func (p *Profile) lineKey(fn string, start, line int) funcLine {
+	ps := p.startLine[fn]
+	if ps <= 0 {
+		return funcLine{fn, line}
+	}
+	if start <= 0 {
+		start = ps
+	}
+	return funcLine{fn, line - start}
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pgo
+
+import (
+	"bytes"
+	"internal/profile"
+	"strings"
+	"testing"
+)
+
+// A testFrame is a frame of a test stack, from the root to the leaf.
+type testFrame struct {
+	fn        string
+	startLine int64
+	line      int64
+}
+
+// buildProfile returns the encoded CPU profile with the given stacks,
+// each listed from the root, and their sample counts.
+func buildProfile(t *testing.T, stacks [][]testFrame, counts []int64) []byte {
+	t.Helper()
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		PeriodType: &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:     10000000,
+	}
+	funcs := make(map[testFrame]*profile.Function)
+	for i, stk := range stacks {
+		s := &profile.Sample{Value: []int64{counts[i], counts[i] * p.Period}}
+		for j := len(stk) - 1; j >= 0; j-- {
+			f := stk[j]
+			key := testFrame{fn: f.fn, startLine: f.startLine}
+			fn := funcs[key]
+			if fn == nil {
+				fn = &profile.Function{ID: uint64(len(p.Function) + 1), Name: f.fn, StartLine: f.startLine}
+				funcs[key] = fn
+				p.Function = append(p.Function, fn)
+			}
+			loc := &profile.Location{
+				ID:   uint64(len(p.Location) + 1),
+				Line: []profile.Line{{Function: fn, Line: f.line}},
+			}
+			p.Location = append(p.Location, loc)
+			s.Location = append(s.Location, loc)
+		}
+		p.Sample = append(p.Sample, s)
+	}
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	data := buildProfile(t, [][]testFrame{
+		{{"main.main", 10, 12}, {"main.hot", 20, 25}, {"main.(*T).M", 40, 41}},
+		{{"main.main", 10, 12}, {"main.hot", 20, 25}, {"main.(*U).M", 50, 51}},
+		{{"main.main", 10, 13}, {"main.cold", 30, 31}},
+	}, []int64{900, 99, 1})
+	p, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := int64(1000 * 10000000); p.TotalWeight != want {
+		t.Errorf("TotalWeight = %d, want %d", p.TotalWeight, want)
+	}
+	if !p.IsHotCall("main.main", 10, 12, "main.hot") {
+		t.Errorf("call main.main -> main.hot is not hot")
+	}
+	if p.IsHotCall("main.main", 10, 13, "main.cold") {
+		t.Errorf("call main.main -> main.cold is hot")
+	}
+	if !p.IsHotFunc("main.(*T).M") || p.IsHotFunc("main.cold") {
+		t.Errorf("IsHotFunc(main.(*T).M) = %v, IsHotFunc(main.cold) = %v; want true, false",
+			p.IsHotFunc("main.(*T).M"), p.IsHotFunc("main.cold"))
+	}
+	isM := func(callee string) bool { return strings.HasSuffix(callee, ".M") }
+	if callee, ok := p.HotCallee("main.hot", 20, 25, isM); !ok || callee != "main.(*T).M" {
+		t.Errorf("HotCallee(main.hot) = %q, %v; want main.(*T).M, true", callee, ok)
+	}
+	if w, want := p.LineWeight("main.hot", 20, 25), 999*p.TotalWeight/1000; w != want {
+		t.Errorf("LineWeight(main.hot:25) = %d, want %d", w, want)
+	}
+}
+
+// TestParseMoved checks that call sites are matched by their offset
+// from the start of their function when the function has moved since
+// the profile was collected.
+func TestParseMoved(t *testing.T) {
+	data := buildProfile(t, [][]testFrame{
+		{{"main.main", 10, 12}, {"main.f", 20, 25}},
+	}, []int64{100})
+	p, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsHotCall("main.main", 15, 17, "main.f") {
+		t.Errorf("moved call main.main -> main.f is not hot")
+	}
+	if p.IsHotCall("main.main", 15, 12, "main.f") {
+		t.Errorf("call at the old absolute line is hot")
+	}
+}
+
+// TestParseNoStartLine checks that lines are matched absolutely in
+// functions whose start line is not in the profile.
+func TestParseNoStartLine(t *testing.T) {
+	data := buildProfile(t, [][]testFrame{
+		{{"main.main", 0, 12}, {"main.f", 0, 25}},
+	}, []int64{100})
+	p, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsHotCall("main.main", 10, 12, "main.f") {
+		t.Errorf("call main.main -> main.f is not hot")
+	}
+	if p.LineWeight("main.f", 20, 25) == 0 {
+		t.Errorf("LineWeight(main.f:25) = 0")
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	for _, data := range []string{
+		"\x0a",                 // truncated field
+		"\x0a\x05ab",           // length past the end
+		"\x32\x01x",            // string table not starting with ""
+		"\x1f\x8b\x08\x00junk", // bad gzip
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", data)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pgo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// This file contains a minimal decoder for the pprof profile.proto
+// format, reading only the fields the compiler needs. The compiler
+// must build with the bootstrap toolchain, so it cannot use
+// internal/profile.
+
+// Field numbers from profile.proto.
+const (
+	tagProfile_SampleType  = 1
+	tagProfile_Sample      = 2
+	tagProfile_Location    = 4
+	tagProfile_Function    = 5
+	tagProfile_StringTable = 6
+
+	tagValueType_Type = 1
+
+	tagSample_LocationID = 1
+	tagSample_Value      = 2
+
+	tagLocation_ID   = 1
+	tagLocation_Line = 4
+
+	tagLine_FunctionID = 1
+	tagLine_Line       = 2
+
+	tagFunction_ID        = 1
+	tagFunction_Name      = 2
+	tagFunction_StartLine = 5
+)
+
+// Protocol buffer wire types.
+const (
+	wireVarint = 0
+	wire64     = 1
+	wireBytes  = 2
+	wire32     = 5
+)
+
+type rawSample struct {
+	locs   []uint64
+	values []int64
+}
+
+type rawLine struct {
+	fn   uint64
+	line int64
+}
+
+type rawFunction struct {
+	name      int64
+	startLine int64
+}
+
+// rawProfile is the decoded form of a profile.proto message,
+// with strings still referring to the string table.
+type rawProfile struct {
+	sampleTypes []int64 // string table indexes of the sample type names
+	samples     []rawSample
+	locs        map[uint64][]rawLine
+	funcs       map[uint64]rawFunction
+	strings     []string
+}
+
+var errMalformed = errors.New("malformed profile")
+
+// decodeProfile decodes a profile in the pprof format,
+// which may be gzip-compressed.
+func decodeProfile(data []byte) (*rawProfile, error) {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decompressing profile: %v", err)
+		}
+		data, err = ioutil.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing profile: %v", err)
+		}
+	}
+	p := &rawProfile{
+		locs:  make(map[uint64][]rawLine),
+		funcs: make(map[uint64]rawFunction),
+	}
+	err := decodeMessage(data, func(tag, wire int, v uint64, b []byte) error {
+		switch {
+		case tag == tagProfile_SampleType && wire == wireBytes:
+			var typ int64
+			err := decodeMessage(b, func(tag, wire int, v uint64, b []byte) error {
+				if tag == tagValueType_Type && wire == wireVarint {
+					typ = int64(v)
+				}
+				return nil
+			})
+			p.sampleTypes = append(p.sampleTypes, typ)
+			return err
+		case tag == tagProfile_Sample && wire == wireBytes:
+			var s rawSample
+			err := decodeMessage(b, func(tag, wire int, v uint64, b []byte) error {
+				switch tag {
+				case tagSample_LocationID:
+					return decodeRepeated(wire, v, b, func(v uint64) { s.locs = append(s.locs, v) })
+				case tagSample_Value:
+					return decodeRepeated(wire, v, b, func(v uint64) { s.values = append(s.values, int64(v)) })
+				}
+				return nil
+			})
+			p.samples = append(p.samples, s)
+			return err
+		case tag == tagProfile_Location && wire == wireBytes:
+			var id uint64
+			var lines []rawLine
+			err := decodeMessage(b, func(tag, wire int, v uint64, b []byte) error {
+				switch {
+				case tag == tagLocation_ID && wire == wireVarint:
+					id = v
+				case tag == tagLocation_Line && wire == wireBytes:
+					var l rawLine
+					err := decodeMessage(b, func(tag, wire int, v uint64, b []byte) error {
+						switch {
+						case tag == tagLine_FunctionID && wire == wireVarint:
+							l.fn = v
+						case tag == tagLine_Line && wire == wireVarint:
+							l.line = int64(v)
+						}
+						return nil
+					})
+					lines = append(lines, l)
+					return err
+				}
+				return nil
+			})
+			p.locs[id] = lines
+			return err
+		case tag == tagProfile_Function && wire == wireBytes:
+			var id uint64
+			var f rawFunction
+			err := decodeMessage(b, func(tag, wire int, v uint64, b []byte) error {
+				if wire != wireVarint {
+					return nil
+				}
+				switch tag {
+				case tagFunction_ID:
+					id = v
+				case tagFunction_Name:
+					f.name = int64(v)
+				case tagFunction_StartLine:
+					f.startLine = int64(v)
+				}
+				return nil
+			})
+			p.funcs[id] = f
+			return err
+		case tag == tagProfile_StringTable && wire == wireBytes:
+			p.strings = append(p.strings, string(b))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(p.strings) == 0 || p.strings[0] != "" {
+		return nil, errMalformed
+	}
+	return p, nil
+}
+
+// str returns the string at index i of the string table.
+func (p *rawProfile) str(i int64) string {
+	if i < 0 || i >= int64(len(p.strings)) {
+		return ""
+	}
+	return p.strings[i]
+}
+
+// decodeMessage calls f for each field of the encoded message b.
+// For varint and fixed-size fields, v holds the value;
+// for length-delimited fields, b holds the contents.
+func decodeMessage(b []byte, f func(tag, wire int, v uint64, b []byte) error) error {
+	for len(b) > 0 {
+		key, n := decodeVarint(b)
+		if n <= 0 {
+			return errMalformed
+		}
+		b = b[n:]
+		tag, wire := int(key>>3), int(key&7)
+		var v uint64
+		var data []byte
+		switch wire {
+		case wireVarint:
+			v, n = decodeVarint(b)
+			if n <= 0 {
+				return errMalformed
+			}
+			b = b[n:]
+		case wire64:
+			if len(b) < 8 {
+				return errMalformed
+			}
+			for i := 7; i >= 0; i-- {
+				v = v<<8 | uint64(b[i])
+			}
+			b = b[8:]
+		case wire32:
+			if len(b) < 4 {
+				return errMalformed
+			}
+			for i := 3; i >= 0; i-- {
+				v = v<<8 | uint64(b[i])
+			}
+			b = b[4:]
+		case wireBytes:
+			size, n := decodeVarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				return errMalformed
+			}
+			data = b[n : n+int(size)]
+			b = b[n+int(size):]
+		default:
+			return errMalformed
+		}
+		if err := f(tag, wire, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeRepeated decodes a repeated varint field, which may be
+// packed (wire type 2) or not.
+func decodeRepeated(wire int, v uint64, b []byte, add func(uint64)) error {
+	switch wire {
+	case wireVarint:
+		add(v)
+	case wireBytes:
+		for len(b) > 0 {
+			v, n := decodeVarint(b)
+			if n <= 0 {
+				return errMalformed
+			}
+			add(v)
+			b = b[n:]
+		}
+	default:
+		return errMalformed
+	}
+	return nil
+}
+
+// decodeVarint decodes a varint from the start of b and returns it
+// with the number of bytes read. The count is 0 if b is too short
+// and negative if the varint overflows 64 bits.
+func decodeVarint(b []byte) (uint64, int) {
+	var x uint64
+	for i := 0; i < len(b); i++ {
+		if i == 10 {
+			return 0, -1
+		}
+		c := b[i]
+		x |= uint64(c&0x7f) << (7 * uint(i))
+		if c < 0x80 {
+			return x, i + 1
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssagen
+
+import (
+	"cmd/compile/internal/base"
+	"cmd/compile/internal/ir"
+	"cmd/compile/internal/pgo"
+	"cmd/compile/internal/ssa"
+	"cmd/internal/src"
+)
+
+// Profile is the profile used for profile-guided optimization, if any.
+var Profile *pgo.Profile
+
+// profileBranchRatio is how many times heavier one successor of a
+// branch must be in the profile for the branch to be marked likely.
+const profileBranchRatio = 2
+
+// setProfileLikely sets the likely direction of the conditional
+// branches in f whose direction is not already known, by comparing
+// the profile weights of the first line of each successor. Block
+// layout then places the hot successor right after the branch.
+func setProfileLikely(f *ssa.Func, fn *ir.Func) {
+	name := ir.PkgFuncName(fn)
+	start := int(base.Ctxt.InnermostPos(fn.Pos()).RelLine())
+	weight := func(b *ssa.Block) int64 {
+		pos := firstPos(b)
+		if !pos.IsKnown() {
+			return 0
+		}
+		// Code inlined into fn is attributed to the line of the
+		// call, whose weight includes the time in the callee.
+		return Profile.LineWeight(name, start, int(base.Ctxt.OutermostPos(pos).RelLine()))
+	}
+	for _, b := range f.Blocks {
+		if b.Kind != ssa.BlockIf || b.Likely != ssa.BranchUnknown {
+			continue
+		}
+		w0, w1 := weight(b.Succs[0].Block()), weight(b.Succs[1].Block())
+		switch {
+		case w0 > profileBranchRatio*w1:
+			b.Likely = ssa.BranchLikely
+		case w1 > profileBranchRatio*w0:
+			b.Likely = ssa.BranchUnlikely
+		}
+	}
+}
+
+// firstPos returns the position of the first statement executed in b,
+// following blocks that only jump elsewhere.
+func firstPos(b *ssa.Block) src.XPos {
+	for i := 0; i < 10; i++ {
+		for _, v := range b.Values {
+			if v.Op != ssa.OpPhi && v.Pos.IsKnown() {
+				return v.Pos
+			}
+		}
+		if b.Pos.IsKnown() || b.Kind != ssa.BlockPlain {
+			return b.Pos
+		}
+		b = b.Succs[0].Block()
+	}
+	return src.NoXPos
+}
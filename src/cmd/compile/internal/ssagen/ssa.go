@@ -582,6 +582,10 @@ func buildssa(fn *ir.Func, worker int) *ssa.Func {
 
 	s.insertPhis()
 
+	if Profile != nil && base.Flag.N == 0 {
+		setProfileLikely(s.f, fn)
+	}
+
 	// Main call to ssa package to compile function
 	ssa.Compile(s.f)
 
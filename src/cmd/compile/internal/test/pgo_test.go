@@ -0,0 +1,134 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"internal/profile"
+	"internal/testenv"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const pgoSrc = `package main
+
+type I interface{ M(int) int }
+
+type T struct{ n int }
+
+func (t *T) M(x int) int {
+	return t.n + x
+}
+
+func big(x int) int {
+	s := 0
+	for i := 0; i < x; i++ {
+		s += i * x; s ^= s >> 3; s += i % 7; s -= i / 3; s ^= s << 1
+		s += i % 11; s -= i / 13; s ^= s >> 5; s += i % 17; s -= i / 19
+		s ^= s << 2; s += i % 23; s -= i / 29; s ^= s >> 7; s += i % 31
+		s -= i / 37; s ^= s << 3; s += i % 41; s -= i / 43; s ^= s >> 1
+	}
+	return s
+}
+
+func run(x I, n int) int {
+	return x.M(n) + big(n)
+}
+
+func main() {
+	println(run(&T{}, 10))
+}
+`
+
+// TestPGO checks that a profile makes the compiler devirtualize a hot
+// interface call and inline a hot function that is over the normal
+// inlining budget.
+func TestPGO(t *testing.T) {
+	testenv.MustHaveGoBuild(t)
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "x.go")
+	if err := os.WriteFile(src, []byte(pgoSrc), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lines of interest in pgoSrc.
+	const (
+		lineM    = 7
+		lineRun  = 22
+		lineCall = 23
+		lineMain = 26
+	)
+	fn := func(id uint64, name string, start int64) *profile.Function {
+		return &profile.Function{ID: id, Name: name, StartLine: start}
+	}
+	fMain := fn(1, "main.main", lineMain)
+	fRun := fn(2, "main.run", lineRun)
+	fM := fn(3, "main.(*T).M", lineM)
+	fBig := fn(4, "main.big", 11)
+	loc := func(id uint64, f *profile.Function, line int64) *profile.Location {
+		return &profile.Location{ID: id, Line: []profile.Line{{Function: f, Line: line}}}
+	}
+	lMain := loc(1, fMain, lineMain+1)
+	lRunM := loc(2, fRun, lineCall)
+	lRunBig := loc(3, fRun, lineCall)
+	lM := loc(4, fM, lineM+1)
+	lBig := loc(5, fBig, 14)
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "cpu", Unit: "nanoseconds"}},
+		PeriodType: &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:     1,
+		Sample: []*profile.Sample{
+			{Location: []*profile.Location{lM, lRunM, lMain}, Value: []int64{100}},
+			{Location: []*profile.Location{lBig, lRunBig, lMain}, Value: []int64{1000}},
+		},
+		Location: []*profile.Location{lMain, lRunM, lRunBig, lM, lBig},
+		Function: []*profile.Function{fMain, fRun, fM, fBig},
+	}
+	prof := filepath.Join(dir, "x.pprof")
+	f, err := os.Create(prof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Write(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		args = append([]string{"tool", "compile", "-p=main", "-m=2", "-o", filepath.Join(dir, "x.o")}, args...)
+		out, err := exec.Command(testenv.GoToolPath(t), append(args, src)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+
+	out := run()
+	for _, want := range []string{"cannot inline big: function too complex"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("without profile: output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "PGO devirtualizing") {
+		t.Errorf("without profile: call devirtualized:\n%s", out)
+	}
+
+	out = run("-pgoprofile=" + prof)
+	for _, want := range []string{
+		"x.go:23:12: PGO devirtualizing x.M to *T",
+		"x.go:23:12: inlining call to (*T).M",
+		"x.go:23:21: inlining hot call to big",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("with profile: output does not contain %q:\n%s", want, out)
+		}
+	}
+}
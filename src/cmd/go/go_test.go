@@ -47,6 +47,9 @@ var (
 	canRace = false // whether we can run the race detector
 	canCgo  = false // whether we can use cgo
 	canMSan = false // whether we can run the memory sanitizer
+
+	canFuzz             = false // whether we can fuzz
+	canFuzzInstrumented = false // whether fuzzing uses coverage instrumentation
 )
 
 var exeSuffix string = func() string {
@@ -199,6 +202,8 @@ func TestMain(m *testing.M) {
 
 		canMSan = canCgo && sys.MSanSupported(runtime.GOOS, runtime.GOARCH)
 		canRace = canCgo && sys.RaceDetectorSupported(runtime.GOOS, runtime.GOARCH)
+		canFuzz = sys.FuzzSupported(runtime.GOOS, runtime.GOARCH)
+		canFuzzInstrumented = sys.FuzzInstrumented(runtime.GOOS, runtime.GOARCH)
 		// The race detector doesn't work on Alpine Linux:
 		// golang.org/issue/14481
 		// gccgo does not support the race detector.
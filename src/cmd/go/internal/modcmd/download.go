@@ -96,17 +96,14 @@ func runDownload(ctx context.Context, cmd *base.Command, args []string) {
 	if modload.HasModRoot() {
 		modload.LoadModFile(ctx) // to fill MainModules
 
-		if len(modload.MainModules.Versions()) != 1 {
-			panic(modload.TODOWorkspaces("Support workspace mode in go mod download"))
-		}
-		mainModule := modload.MainModules.Versions()[0]
-
-		targetAtUpgrade := mainModule.Path + "@upgrade"
-		targetAtPatch := mainModule.Path + "@patch"
-		for _, arg := range args {
-			switch arg {
-			case mainModule.Path, targetAtUpgrade, targetAtPatch:
-				os.Stderr.WriteString("go: skipping download of " + arg + " that resolves to the main module\n")
+		for _, mainModule := range modload.MainModules.Versions() {
+			targetAtUpgrade := mainModule.Path + "@upgrade"
+			targetAtPatch := mainModule.Path + "@patch"
+			for _, arg := range args {
+				switch arg {
+				case mainModule.Path, targetAtUpgrade, targetAtPatch:
+					os.Stderr.WriteString("go: skipping download of " + arg + " that resolves to the main module\n")
+				}
 			}
 		}
 	}
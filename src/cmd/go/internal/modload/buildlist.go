@@ -292,11 +292,11 @@ func readModGraph(ctx context.Context, pruning modPruning, roots []module.Versio
 		}
 	)
 	for _, m := range MainModules.Versions() {
-		// Require all roots from all main modules.
-		_ = TODOWorkspaces("This flattens a level of the module graph, adding the dependencies " +
-			"of all main modules to a single requirements struct, and losing the information of which " +
-			"main module required which requirement. Rework the requirements struct and change this" +
-			"to reflect the structure of the main modules.")
+		// Require all roots from all main modules. The requirements of the
+		// workspace modules are merged into a single set of roots, so the graph
+		// does not record which main module required which root. That is
+		// sufficient for MVS, since every main module is a root of the graph
+		// and all of them are at the same (empty) version.
 		mg.g.Require(m, roots)
 	}
 
@@ -46,10 +46,6 @@ var (
 	allowMissingModuleImports bool
 )
 
-func TODOWorkspaces(s string) error {
-	return fmt.Errorf("need to support this for workspaces: %s", s)
-}
-
 // Variables set in Init.
 var (
 	initialized bool
@@ -62,6 +58,11 @@ var (
 	modRoots          []string
 	gopath            string
 	workFileGoVersion string
+
+	// workFileReplaceMap holds the replacements from the go.work file,
+	// keyed by the module version they replace. It is nil outside
+	// workspace mode.
+	workFileReplaceMap map[module.Version]module.Version
 )
 
 // Variable set in InitWorkfile
@@ -94,6 +95,8 @@ type MainModuleSet struct {
 
 	workFileGoVersion string
 
+	workFileReplaceMap map[module.Version]module.Version // replacements from the go.work file
+
 	indexMu sync.Mutex
 	indices map[module.Version]*modFileIndex
 }
@@ -193,6 +196,44 @@ func (mms *MainModuleSet) ModContainingCWD() module.Version {
 	return mms.modContainingCWD
 }
 
+// WorkFileReplaceMap returns the replacements specified in the go.work
+// file, keyed by the module version they replace. It is nil outside of
+// workspace mode.
+func (mms *MainModuleSet) WorkFileReplaceMap() map[module.Version]module.Version {
+	if mms == nil {
+		return nil
+	}
+	return mms.workFileReplaceMap
+}
+
+// highestReplaced returns the highest version of the module path that is
+// replaced in the go.work file or in any of the main modules' go.mod files,
+// or the empty string if the only replacements are wildcards that don't
+// specify a version. The boolean reports whether any replacement was found.
+func (mms *MainModuleSet) highestReplaced(path string) (version string, found bool) {
+	if mms == nil {
+		return "", false
+	}
+	consider := func(v string) {
+		if !found || semver.Compare(v, version) > 0 {
+			version, found = v, true
+		}
+	}
+	for old := range mms.workFileReplaceMap {
+		if old.Path == path {
+			consider(old.Version)
+		}
+	}
+	for _, mm := range mms.versions {
+		if index := mms.Index(mm); index != nil {
+			if v, ok := index.highestReplaced[path]; ok {
+				consider(v)
+			}
+		}
+	}
+	return version, found
+}
+
 // GoVersion returns the go version set on the single module, in module mode,
 // or the go.work file in workspace mode.
 func (mms *MainModuleSet) GoVersion() string {
@@ -377,9 +418,6 @@ func Init() {
 	// We're in module mode. Set any global variables that need to be set.
 	cfg.ModulesEnabled = true
 	setDefaultBuildMod()
-	_ = TODOWorkspaces("In workspace mode, mod will not be readonly for go mod download," +
-		"verify, graph, and why. Implement support for go mod download and add test cases" +
-		"to ensure verify, graph, and why work properly.")
 	list := filepath.SplitList(cfg.BuildContext.GOPATH)
 	if len(list) == 0 || list[0] == "" {
 		base.Fatalf("missing $GOPATH")
@@ -391,13 +429,18 @@ func Init() {
 
 	if inWorkspaceMode() {
 		var err error
-		workFileGoVersion, modRoots, err = loadWorkFile(workFilePath)
+		workFileGoVersion, modRoots, workFileReplaceMap, err = loadWorkFile(workFilePath)
 		if err != nil {
 			base.Fatalf("reading go.work: %v", err)
 		}
-		_ = TODOWorkspaces("Support falling back to individual module go.sum " +
-			"files for sums not in the workspace sum file.")
+		// Sums are recorded in go.work.sum, but sums already present in the
+		// go.sum files of the workspace modules are used too, so that those
+		// need not be duplicated in go.work.sum.
 		modfetch.GoSumFile = workFilePath + ".sum"
+		for _, modRoot := range modRoots {
+			sumFile := strings.TrimSuffix(modFilePath(modRoot), ".mod") + ".sum"
+			modfetch.WorkspaceGoSumFiles = append(modfetch.WorkspaceGoSumFiles, sumFile)
+		}
 	} else if modRoots == nil {
 		// We're in module mode, but not inside a module.
 		//
@@ -553,20 +596,17 @@ func (goModDirtyError) Error() string {
 
 var errGoModDirty error = goModDirtyError{}
 
-func loadWorkFile(path string) (goVersion string, modRoots []string, err error) {
-	_ = TODOWorkspaces("Clean up and write back the go.work file: add module paths for workspace modules.")
-	workDir := filepath.Dir(path)
-	workData, err := lockedfile.Read(path)
+// loadWorkFile reads the go.work file at path and returns its go version,
+// the absolute module root directories it lists, and its replacements.
+func loadWorkFile(path string) (goVersion string, modRoots []string, replaceMap map[module.Version]module.Version, err error) {
+	wf, err := ReadWorkFile(path)
 	if err != nil {
-		return "", nil, err
-	}
-	wf, err := modfile.ParseWork(path, workData, nil)
-	if err != nil {
-		return "", nil, err
+		return "", nil, nil, err
 	}
 	if wf.Go != nil {
 		goVersion = wf.Go.Version
 	}
+	workDir := filepath.Dir(path)
 	seen := map[string]bool{}
 	for _, d := range wf.Directory {
 		modRoot := d.Path
@@ -574,12 +614,37 @@ func loadWorkFile(path string) (goVersion string, modRoots []string, err error)
 			modRoot = filepath.Join(workDir, modRoot)
 		}
 		if seen[modRoot] {
-			return "", nil, fmt.Errorf("path %s appears multiple times in workspace", modRoot)
+			return "", nil, nil, fmt.Errorf("path %s appears multiple times in workspace", modRoot)
 		}
 		seen[modRoot] = true
 		modRoots = append(modRoots, modRoot)
 	}
-	return goVersion, modRoots, nil
+	replaceMap = make(map[module.Version]module.Version, len(wf.Replace))
+	for _, r := range wf.Replace {
+		if prev, dup := replaceMap[r.Old]; dup && prev != r.New {
+			return "", nil, nil, fmt.Errorf("conflicting replacements for %v:\n\t%v\n\t%v", r.Old, prev, r.New)
+		}
+		replaceMap[r.Old] = r.New
+	}
+	return goVersion, modRoots, replaceMap, nil
+}
+
+// ReadWorkFile reads and parses the go.work file at the given path.
+func ReadWorkFile(path string) (*modfile.WorkFile, error) {
+	workData, err := lockedfile.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return modfile.ParseWork(path, workData, nil)
+}
+
+// WriteWorkFile cleans and writes out the go.work file to the given path.
+func WriteWorkFile(path string, wf *modfile.WorkFile) error {
+	wf.SortBlocks()
+	wf.Cleanup()
+	out := modfile.Format(wf.Syntax)
+
+	return lockedfile.Write(path, bytes.NewReader(out), 0666)
 }
 
 // LoadModFile sets Target and, if there is a main module, parses the initial
@@ -607,6 +672,23 @@ func LoadModFile(ctx context.Context) *Requirements {
 	return rs
 }
 
+// EnterModule resets MainModules and requirements to refer to just this one
+// module. It is used by 'go work sync' to update each workspace module's
+// go.mod file in turn.
+func EnterModule(ctx context.Context, enterModroot string) {
+	MainModules = nil // reset MainModules
+	requirements = nil
+	loaded = nil
+	workFilePath = "" // Force module mode
+	workFileGoVersion = ""
+	workFileReplaceMap = nil
+	modfetch.Reset()
+
+	modRoots = []string{enterModroot}
+	modfetch.GoSumFile = strings.TrimSuffix(modFilePath(enterModroot), ".mod") + ".sum"
+	LoadModFile(ctx)
+}
+
 // loadModFile is like LoadModFile, but does not implicitly commit the
 // requirements back to disk after fixing inconsistencies.
 //
@@ -620,7 +702,9 @@ func loadModFile(ctx context.Context) (rs *Requirements, needCommit bool) {
 
 	Init()
 	if len(modRoots) == 0 {
-		_ = TODOWorkspaces("Instead of creating a fake module with an empty modroot, make MainModules.Len() == 0 mean that we're in module mode but not inside any module.")
+		// TODO(#49228): Instead of creating a fake module with an empty modroot,
+		// make MainModules.Len() == 0 mean that we're in module mode but not
+		// inside any module.
 		mainModule := module.Version{Path: "command-line-arguments"}
 		MainModules = makeMainModules([]module.Version{mainModule}, []string{""}, []*modfile.File{nil}, []*modFileIndex{nil}, "")
 		goVersion := LatestGoVersion()
@@ -820,8 +904,9 @@ func CreateWorkFile(ctx context.Context, workFile string, modDirs []string) {
 		workF.AddDirectory(ToDirectoryPath(dir), f.Module.Mod.Path)
 	}
 
-	data := modfile.Format(workF.Syntax)
-	lockedfile.Write(workFile, bytes.NewReader(data), 0666)
+	if err := WriteWorkFile(workFile, workF); err != nil {
+		base.Fatalf("go: %v", err)
+	}
 }
 
 // fixVersion returns a modfile.VersionFixer implemented using the Query function.
@@ -903,6 +988,8 @@ func makeMainModules(ms []module.Version, rootDirs []string, modFiles []*modfile
 		modFiles:          map[module.Version]*modfile.File{},
 		indices:           map[module.Version]*modFileIndex{},
 		workFileGoVersion: workFileGoVersion,
+
+		workFileReplaceMap: workFileReplaceMap,
 	}
 	for i, m := range ms {
 		mainModules.pathPrefix[m] = m.Path
@@ -988,7 +1075,7 @@ func setDefaultBuildMod() {
 	// to modload functions instead of relying on an implicit setting
 	// based on command name.
 	switch cfg.CmdName {
-	case "get", "mod download", "mod init", "mod tidy":
+	case "get", "mod download", "mod init", "mod tidy", "work sync":
 		// These commands are intended to update go.mod and go.sum.
 		cfg.BuildMod = "mod"
 		return
@@ -1013,7 +1100,9 @@ func setDefaultBuildMod() {
 		return
 	}
 
-	if len(modRoots) == 1 {
+	if len(modRoots) == 1 && !inWorkspaceMode() {
+		// Vendor directories are ignored in workspace mode: a single vendor
+		// directory cannot describe the build list of the whole workspace.
 		index := MainModules.GetSingleIndexOrNil()
 		if fi, err := fsys.Stat(filepath.Join(modRoots[0], "vendor")); err == nil && fi.IsDir() {
 			modGo := "unspecified"
@@ -1170,9 +1259,9 @@ func findWorkspaceFile(dir string) (root string) {
 			break
 		}
 		if d == cfg.GOROOT {
-			_ = TODOWorkspaces("If we end up checking in a go.work file to GOROOT/src," +
-				"remove this case.")
-			return "" // As a special case, don't cross GOROOT to find a go.work file.
+			// As a special case, don't cross GOROOT to find a go.work file.
+			// The standard library and cmd are never part of a workspace.
+			return ""
 		}
 		dir = d
 	}
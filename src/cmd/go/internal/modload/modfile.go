@@ -323,31 +323,46 @@ func CheckDeprecation(ctx context.Context, m module.Version) (deprecation string
 	return summary.deprecated, nil
 }
 
-func replacement(mod module.Version, index *modFileIndex) (fromVersion string, to module.Version, ok bool) {
-	if r, ok := index.replace[mod]; ok {
-		return mod.Version, r, true
+// replacement returns the replacement for mod in the given map of
+// replacements, if any. A replacement of the specific version of mod
+// takes precedence over a replacement of all of its versions.
+func replacement(mod module.Version, replace map[module.Version]module.Version) (to module.Version, ok bool) {
+	if r, ok := replace[mod]; ok {
+		return r, true
 	}
-	if r, ok := index.replace[module.Version{Path: mod.Path}]; ok {
-		return "", r, true
+	if r, ok := replace[module.Version{Path: mod.Path}]; ok {
+		return r, true
 	}
-	return "", module.Version{}, false
+	return module.Version{}, false
 }
 
-// Replacement returns the replacement for mod, if any, and and the module root
-// directory of the main module containing the replace directive.
+// Replacement returns the replacement for mod, if any, and the directory
+// that a relative replacement path is relative to: the directory of the
+// go.work file if the replacement comes from the go.work file, or else the
+// module root directory of the main module containing the replace directive.
 // If there is no replacement for mod, Replacement returns
 // a module.Version with Path == "".
+//
+// In workspace mode, replacements in the go.work file take precedence over
+// those in the go.mod files of the workspace modules, and may be used to
+// resolve conflicts between them. The main modules themselves are never
+// replaced.
 func Replacement(mod module.Version) (module.Version, string) {
-	_ = TODOWorkspaces("Support replaces in the go.work file.")
-	foundFrom, found, foundModRoot := "", module.Version{}, ""
+	if mod.Version == "" && MainModules.Contains(mod.Path) {
+		return module.Version{}, ""
+	}
+	if r, ok := replacement(mod, MainModules.WorkFileReplaceMap()); ok {
+		return r, filepath.Dir(workFilePath)
+	}
+	found, foundModRoot := module.Version{}, ""
 	for _, v := range MainModules.Versions() {
 		if index := MainModules.Index(v); index != nil {
-			if from, r, ok := replacement(mod, index); ok {
+			if r, ok := replacement(mod, index.replace); ok {
 				modRoot := MainModules.ModRoot(v)
-				if foundModRoot != "" && foundFrom != from && found != r {
-					_ = TODOWorkspaces("once the go.work file supports replaces, recommend them as a way to override conflicts")
-					base.Errorf("conflicting replacements found for %v in workspace modules defined by %v and %v",
-						mod, modFilePath(foundModRoot), modFilePath(modRoot))
+				if foundModRoot != "" && found != r {
+					base.Errorf("conflicting replacements found for %v in workspace modules defined by %v and %v"+
+						"\n\tadd a replace directive to %v to resolve the conflict",
+						mod, modFilePath(foundModRoot), modFilePath(modRoot), base.ShortPath(workFilePath))
 					return found, foundModRoot
 				}
 				found, foundModRoot = r, modRoot
@@ -996,15 +996,8 @@ func lookupRepo(proxy, path string) (repo versionRepo, err error) {
 		repo = emptyRepo{path: path, err: err}
 	}
 
-	// TODO(#45713): Join all the highestReplaced fields into a single value.
-	for _, mm := range MainModules.Versions() {
-		index := MainModules.Index(mm)
-		if index == nil {
-			continue
-		}
-		if _, ok := index.highestReplaced[path]; ok {
-			return &replacementRepo{repo: repo}, nil
-		}
+	if _, ok := MainModules.highestReplaced(path); ok {
+		return &replacementRepo{repo: repo}, nil
 	}
 
 	return repo, err
@@ -1108,22 +1101,7 @@ func (rr *replacementRepo) Latest() (*modfetch.RevInfo, error) {
 	info, err := rr.repo.Latest()
 	path := rr.ModulePath()
 
-	highestReplaced, found := "", false
-	for _, mm := range MainModules.Versions() {
-		if index := MainModules.Index(mm); index != nil {
-			if v, ok := index.highestReplaced[path]; ok {
-				if !found {
-					highestReplaced, found = v, true
-					continue
-				}
-				if semver.Compare(v, highestReplaced) > 0 {
-					highestReplaced = v
-				}
-			}
-		}
-	}
-
-	if found {
+	if highestReplaced, found := MainModules.highestReplaced(path); found {
 		v := highestReplaced
 
 		if v == "" {
@@ -19,6 +19,10 @@ var passFlagToTest = map[string]bool{
 	"cpu":                  true,
 	"cpuprofile":           true,
 	"failfast":             true,
+	"fuzz":                 true,
+	"fuzzminimizetime":     true,
+	"fuzztime":             true,
+	"goroutineleak":        true,
 	"list":                 true,
 	"memprofile":           true,
 	"memprofilerate":       true,
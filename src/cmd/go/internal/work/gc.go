@@ -142,6 +142,9 @@ func (gcToolchain) gc(b *Builder, a *Action, archive string, importcfg, embedcfg
 	if symabis != "" {
 		gcflags = append(gcflags, "-symabis", symabis)
 	}
+	if p.Internal.FuzzInstrument {
+		gcflags = append(gcflags, fuzzInstrumentFlags()...)
+	}
 
 	gcflags = append(gcflags, str.StringList(forcedGcflags, p.Internal.Gcflags)...)
 	if compilingRuntime {
@@ -180,6 +183,9 @@ func (gcToolchain) gc(b *Builder, a *Action, archive string, importcfg, embedcfg
 	if asmhdr {
 		args = append(args, "-asmhdr", objdir+"go_asm.h")
 	}
+	if cfg.BuildPGO != "" {
+		args = append(args, "-pgoprofile="+cfg.BuildPGO)
+	}
 
 	// Add -c=N to use concurrent backend compilation, if possible.
 	if c := gcBackendConcurrency(gcflags); c > 1 {
@@ -716,3 +722,15 @@ func (gcToolchain) ldShared(b *Builder, root *Action, toplevelactions []*Action,
 func (gcToolchain) cc(b *Builder, a *Action, ofile, cfile string) error {
 	return fmt.Errorf("%s: C source files not supported without cgo", mkAbs(a.Package.Dir, cfile))
 }
+
+// fuzzInstrumentFlags returns compiler flags that enable coverage
+// instrumentation for fuzzing, or nil if fuzzing is not instrumented
+// on the target platform.
+func fuzzInstrumentFlags() []string {
+	if !sys.FuzzInstrumented(cfg.Goos, cfg.Goarch) {
+		// Don't enable instrumentation on platforms where the fuzzer can't
+		// read the counters back.
+		return nil
+	}
+	return []string{"-d=libfuzzer"}
+}
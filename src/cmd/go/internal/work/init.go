@@ -39,6 +39,20 @@ func BuildInit() {
 		cfg.BuildPkgdir = p
 	}
 
+	// Likewise for -pgo, which must also name an existing file.
+	if cfg.BuildPGO != "" {
+		p, err := filepath.Abs(cfg.BuildPGO)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go: evaluating -pgo: %v\n", err)
+			base.SetExitStatus(2)
+			base.Exit()
+		}
+		if _, err := os.Stat(p); err != nil {
+			base.Fatalf("go: -pgo: %v", err)
+		}
+		cfg.BuildPGO = p
+	}
+
 	if cfg.BuildP <= 0 {
 		base.Fatalf("go: -p must be a positive integer: %v\n", cfg.BuildP)
 	}
@@ -2,9 +2,9 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// go mod editwork
+// go work edit
 
-package modcmd
+package workcmd
 
 import (
 	"bytes"
@@ -14,28 +14,30 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
 )
 
-var cmdEditwork = &base.Command{
-	UsageLine: "go mod editwork [editing flags] [go.work]",
+var cmdEdit = &base.Command{
+	UsageLine: "go work edit [editing flags] [go.work]",
 	Short:     "edit go.work from tools or scripts",
-	Long: `Editwork provides a command-line interface for editing go.work,
+	Long: `Edit provides a command-line interface for editing go.work,
 for use primarily by tools or scripts. It only reads go.work;
 it does not look up information about the modules involved.
-If no file is specified, editwork looks for a go.work file in the current
-directory and its parent directories
+If no file is specified, Edit looks for a go.work file in the current
+directory and its parent directories.
 
 The editing flags specify a sequence of editing operations.
 
 The -fmt flag reformats the go.work file without making other changes.
 This reformatting is also implied by any other modifications that use or
-rewrite the go.mod file. The only time this flag is needed is if no other
-flags are specified, as in 'go mod editwork -fmt'.
+rewrite the go.work file. The only time this flag is needed is if no other
+flags are specified, as in 'go work edit -fmt'.
 
 The -directory=path and -dropdirectory=path flags
 add and drop a directory from the go.work files set of module directories.
@@ -58,10 +60,10 @@ editing flags may be repeated, and the changes are applied in the order given.
 The -go=version flag sets the expected Go language version.
 
 The -print flag prints the final go.work in its text format instead of
-writing it back to go.mod.
+writing it back to go.work.
 
 The -json flag prints the final go.work file in JSON format instead of
-writing it back to go.mod. The JSON output corresponds to these Go types:
+writing it back to go.work. The JSON output corresponds to these Go types:
 
 	type Module struct {
 		Path    string
@@ -75,8 +77,8 @@ writing it back to go.mod. The JSON output corresponds to these Go types:
 	}
 
 	type Directory struct {
-		Path       string
-		ModulePath string
+		DiskPath string
+		ModPath  string
 	}
 
 	type Replace struct {
@@ -84,49 +86,47 @@ writing it back to go.mod. The JSON output corresponds to these Go types:
 		New Module
 	}
 
-See the workspaces design proposal at
-https://go.googlesource.com/proposal/+/master/design/45713-workspace.md for
-more information.
+See the workspaces reference at 'go help work' for more information.
 `,
 }
 
 var (
-	editworkFmt   = cmdEditwork.Flag.Bool("fmt", false, "")
-	editworkGo    = cmdEditwork.Flag.String("go", "", "")
-	editworkJSON  = cmdEditwork.Flag.Bool("json", false, "")
-	editworkPrint = cmdEditwork.Flag.Bool("print", false, "")
-	workedits     []func(file *modfile.WorkFile) // edits specified in flags
+	editFmt   = cmdEdit.Flag.Bool("fmt", false, "")
+	editGo    = cmdEdit.Flag.String("go", "", "")
+	editJSON  = cmdEdit.Flag.Bool("json", false, "")
+	editPrint = cmdEdit.Flag.Bool("print", false, "")
+	workedits []func(file *modfile.WorkFile) // edits specified in flags
 )
 
 func init() {
-	cmdEditwork.Run = runEditwork // break init cycle
+	cmdEdit.Run = runEdit // break init cycle
 
-	cmdEditwork.Flag.Var(flagFunc(flagEditworkDirectory), "directory", "")
-	cmdEditwork.Flag.Var(flagFunc(flagEditworkDropDirectory), "dropdirectory", "")
-	cmdEditwork.Flag.Var(flagFunc(flagEditworkReplace), "replace", "")
-	cmdEditwork.Flag.Var(flagFunc(flagEditworkDropReplace), "dropreplace", "")
+	cmdEdit.Flag.Var(flagFunc(flagEditDirectory), "directory", "")
+	cmdEdit.Flag.Var(flagFunc(flagEditDropDirectory), "dropdirectory", "")
+	cmdEdit.Flag.Var(flagFunc(flagEditReplace), "replace", "")
+	cmdEdit.Flag.Var(flagFunc(flagEditDropReplace), "dropreplace", "")
 
-	base.AddWorkfileFlag(&cmdEditwork.Flag)
+	base.AddWorkfileFlag(&cmdEdit.Flag)
 }
 
-func runEditwork(ctx context.Context, cmd *base.Command, args []string) {
+func runEdit(ctx context.Context, cmd *base.Command, args []string) {
 	anyFlags :=
-		*editworkGo != "" ||
-			*editworkJSON ||
-			*editworkPrint ||
-			*editworkFmt ||
+		*editGo != "" ||
+			*editJSON ||
+			*editPrint ||
+			*editFmt ||
 			len(workedits) > 0
 
 	if !anyFlags {
-		base.Fatalf("go: no flags specified (see 'go help mod edit').")
+		base.Fatalf("go: no flags specified (see 'go help work edit').")
 	}
 
-	if *editworkJSON && *editworkPrint {
+	if *editJSON && *editPrint {
 		base.Fatalf("go: cannot use both -json and -print")
 	}
 
 	if len(args) > 1 {
-		base.Fatalf("go: 'go mod editwork' accepts at most one argument")
+		base.Fatalf("go: 'go work edit' accepts at most one argument")
 	}
 	var gowork string
 	if len(args) == 1 {
@@ -136,9 +136,9 @@ func runEditwork(ctx context.Context, cmd *base.Command, args []string) {
 		gowork = modload.WorkFilePath()
 	}
 
-	if *editworkGo != "" {
-		if !modfile.GoVersionRE.MatchString(*editworkGo) {
-			base.Fatalf(`go mod: invalid -go option; expecting something like "-go %s"`, modload.LatestGoVersion())
+	if *editGo != "" {
+		if !modfile.GoVersionRE.MatchString(*editGo) {
+			base.Fatalf(`go work: invalid -go option; expecting something like "-go %s"`, modload.LatestGoVersion())
 		}
 	}
 
@@ -152,8 +152,8 @@ func runEditwork(ctx context.Context, cmd *base.Command, args []string) {
 		base.Fatalf("go: errors parsing %s:\n%s", base.ShortPath(gowork), err)
 	}
 
-	if *editworkGo != "" {
-		if err := workFile.AddGoStmt(*editworkGo); err != nil {
+	if *editGo != "" {
+		if err := workFile.AddGoStmt(*editGo); err != nil {
 			base.Fatalf("go: internal error: %v", err)
 		}
 	}
@@ -166,14 +166,14 @@ func runEditwork(ctx context.Context, cmd *base.Command, args []string) {
 	workFile.SortBlocks()
 	workFile.Cleanup() // clean file after edits
 
-	if *editworkJSON {
-		editworkPrintJSON(workFile)
+	if *editJSON {
+		editPrintJSON(workFile)
 		return
 	}
 
 	out := modfile.Format(workFile.Syntax)
 
-	if *editworkPrint {
+	if *editPrint {
 		os.Stdout.Write(out)
 		return
 	}
@@ -189,23 +189,22 @@ func runEditwork(ctx context.Context, cmd *base.Command, args []string) {
 	}
 }
 
-// flagEditworkDirectory implements the -directory flag.
-func flagEditworkDirectory(arg string) {
+// flagEditDirectory implements the -directory flag.
+func flagEditDirectory(arg string) {
 	workedits = append(workedits, func(f *modfile.WorkFile) {
 		_, mf, err := modload.ReadModFile(filepath.Join(arg, "go.mod"), nil)
 		modulePath := ""
 		if err == nil {
 			modulePath = mf.Module.Mod.Path
 		}
-		f.AddDirectory(modload.ToDirectoryPath(arg), modulePath)
-		if err := f.AddDirectory(modload.ToDirectoryPath(arg), ""); err != nil {
+		if err := f.AddDirectory(modload.ToDirectoryPath(arg), modulePath); err != nil {
 			base.Fatalf("go: -directory=%s: %v", arg, err)
 		}
 	})
 }
 
-// flagEditworkDropDirectory implements the -dropdirectory flag.
-func flagEditworkDropDirectory(arg string) {
+// flagEditDropDirectory implements the -dropdirectory flag.
+func flagEditDropDirectory(arg string) {
 	workedits = append(workedits, func(f *modfile.WorkFile) {
 		if err := f.DropDirectory(modload.ToDirectoryPath(arg)); err != nil {
 			base.Fatalf("go: -dropdirectory=%s: %v", arg, err)
@@ -213,8 +212,8 @@ func flagEditworkDropDirectory(arg string) {
 	})
 }
 
-// flagReplace implements the -replace flag.
-func flagEditworkReplace(arg string) {
+// flagEditReplace implements the -replace flag.
+func flagEditReplace(arg string) {
 	var i int
 	if i = strings.Index(arg, "="); i < 0 {
 		base.Fatalf("go: -replace=%s: need old[@v]=new[@w] (missing =)", arg)
@@ -242,8 +241,8 @@ func flagEditworkReplace(arg string) {
 	})
 }
 
-// flagDropReplace implements the -dropreplace flag.
-func flagEditworkDropReplace(arg string) {
+// flagEditDropReplace implements the -dropreplace flag.
+func flagEditDropReplace(arg string) {
 	path, version, err := parsePathVersionOptional("old", arg, true)
 	if err != nil {
 		base.Fatalf("go: -dropreplace=%s: %v", arg, err)
@@ -256,7 +255,7 @@ func flagEditworkDropReplace(arg string) {
 }
 
 // editPrintJSON prints the -json output.
-func editworkPrintJSON(workFile *modfile.WorkFile) {
+func editPrintJSON(workFile *modfile.WorkFile) {
 	var f workfileJSON
 	if workFile.Go != nil {
 		f.Go = workFile.Go.Version
@@ -287,3 +286,41 @@ type directoryJSON struct {
 	DiskPath string
 	ModPath  string `json:",omitempty"`
 }
+
+type replaceJSON struct {
+	Old module.Version
+	New module.Version
+}
+
+type flagFunc func(string)
+
+func (f flagFunc) String() string     { return "" }
+func (f flagFunc) Set(s string) error { f(s); return nil }
+
+// parsePathVersionOptional parses path[@version], using adj to
+// describe any errors.
+func parsePathVersionOptional(adj, arg string, allowDirPath bool) (path, version string, err error) {
+	if i := strings.Index(arg, "@"); i < 0 {
+		path = arg
+	} else {
+		path, version = strings.TrimSpace(arg[:i]), strings.TrimSpace(arg[i+1:])
+	}
+	if err := module.CheckImportPath(path); err != nil {
+		if !allowDirPath || !modfile.IsDirectoryPath(path) {
+			return path, version, fmt.Errorf("invalid %s path: %v", adj, err)
+		}
+	}
+	if path != arg && !allowedVersionArg(version) {
+		return path, version, fmt.Errorf("invalid %s version: %q", adj, version)
+	}
+	return path, version, nil
+}
+
+// allowedVersionArg returns whether a token may be used as a version in go.mod.
+// We don't call modfile.CheckPathVersion, because that insists on versions
+// being in semver form, but here we want to allow versions like "master" or
+// "1234abcdef", which the go command will resolve the next time it runs (or
+// during -fix).  Even so, we need to make sure the version is a valid token.
+func allowedVersionArg(arg string) bool {
+	return !modfile.MustQuote(arg)
+}
@@ -355,6 +355,10 @@ Script:
 				ok = canMSan
 			case "race":
 				ok = canRace
+			case "fuzz":
+				ok = canFuzz
+			case "fuzz-instrumented":
+				ok = canFuzzInstrumented
 			case "net":
 				ok = testenv.HasExternalNetwork()
 			case "link":
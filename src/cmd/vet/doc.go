@@ -28,6 +28,7 @@ program correctness.
 
 To list the available checks, run "go tool vet help":
 
+    appends      check for calls to append whose result is discarded
     asmdecl      report mismatches between assembly files and Go declarations
     assign       check for useless assignments
     atomic       check for common mistakes using the sync/atomic package
@@ -36,19 +37,24 @@ To list the available checks, run "go tool vet help":
     cgocall      detect some violations of the cgo pointer passing rules
     composites   check for unkeyed composite literals
     copylocks    check for locks erroneously passed by value
+    failnow      report helpers that stop a test called from goroutines started by it
     httpresponse check for mistakes using HTTP responses
     loopclosure  check references to loop variables from within nested functions
     lostcancel   check cancel func returned by context.WithCancel is called
     nilfunc      check for useless comparisons between functions and nil
     printf       check consistency of Printf format strings and arguments
     shift        check for shifts that equal or exceed the width of the integer
+    sortslice    check the argument type of sort.Slice
+    sqlrows      check for mistakes using database/sql result sets
     stdmethods   check signature of methods of well-known interfaces
     structtag    check that struct field tags conform to reflect.StructTag.Get
     tests        check for common mistaken usages of tests and examples
+    tickerstop   check for time.Tickers that are never stopped
     unmarshal    report passing non-pointer or non-interface values to unmarshal
     unreachable  check for unreachable code
     unsafeptr    check for invalid conversions of uintptr to unsafe.Pointer
     unusedresult check for unused results of calls to some functions
+    waitgroup    check for misuses of sync.WaitGroup
 
 For details and flags of a particular check, such as printf, run "go tool vet help printf".
 
@@ -0,0 +1,169 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package appends defines an Analyzer that checks for calls to
+// append whose result is lost.
+package appends
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const Doc = `check for calls to append whose result is discarded
+
+The built-in append returns the updated slice, which the caller must
+keep and use. This checker reports local variables and parameters that
+are only ever appended to, whose appended values are therefore lost:
+
+	func collect(names []string, n string) {
+		names = append(names, n) // result of append to names is never used
+	}
+
+It also reports calls that append no values, such as append(s), which
+return their argument unchanged and usually indicate that the values
+to append were forgotten.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "appends",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	nodeFilter := []ast.Node{
+		(*ast.FuncDecl)(nil),
+		(*ast.FuncLit)(nil),
+		(*ast.CallExpr)(nil),
+	}
+	inspect.Nodes(nodeFilter, func(n ast.Node, push bool) bool {
+		if !push {
+			return true
+		}
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Body != nil {
+				checkFunc(pass, n, n.Body)
+			}
+		case *ast.FuncLit:
+			checkFunc(pass, n, n.Body)
+		case *ast.CallExpr:
+			if len(n.Args) == 1 && !n.Ellipsis.IsValid() && isAppend(pass.TypesInfo, n) {
+				pass.ReportRangef(n, "append with no values")
+			}
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// checkFunc reports the variables local to fn, a function with the
+// given body, that are assigned the result of appending to themselves
+// but are never otherwise used.
+func checkFunc(pass *analysis.Pass, fn ast.Node, body *ast.BlockStmt) {
+	info := pass.TypesInfo
+
+	// Find the assignments v = append(v, ...) to variables of fn.
+	// Variables declared in nested functions are handled when
+	// the nested function is checked.
+	self := make(map[*ast.Ident]bool)      // identifiers in those assignments
+	first := make(map[*types.Var]ast.Expr) // first such append for each variable
+	var order []*types.Var
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			if n.Tok != token.ASSIGN || len(n.Lhs) != len(n.Rhs) {
+				return true
+			}
+			for i, rhs := range n.Rhs {
+				lhs, ok := n.Lhs[i].(*ast.Ident)
+				if !ok || !isAppend(info, rhs) {
+					continue
+				}
+				call := astutil.Unparen(rhs).(*ast.CallExpr)
+				arg, ok := call.Args[0].(*ast.Ident)
+				if !ok {
+					continue
+				}
+				v, ok := info.Uses[lhs].(*types.Var)
+				if !ok || v != info.Uses[arg] || !declaredIn(v, fn) {
+					continue
+				}
+				self[lhs], self[arg] = true, true
+				if first[v] == nil {
+					first[v] = rhs
+					order = append(order, v)
+				}
+			}
+		}
+		return true
+	})
+	if len(order) == 0 {
+		return
+	}
+
+	// Remove the variables used anywhere else, including in nested
+	// functions, and the named results, which a return statement may use.
+	if ftype := funcType(fn); ftype.Results != nil {
+		for _, field := range ftype.Results.List {
+			for _, name := range field.Names {
+				if v, ok := info.Defs[name].(*types.Var); ok {
+					delete(first, v)
+				}
+			}
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && !self[id] {
+			if v, ok := info.Uses[id].(*types.Var); ok {
+				delete(first, v)
+			}
+		}
+		return true
+	})
+
+	for _, v := range order {
+		if call := first[v]; call != nil {
+			pass.ReportRangef(call, "result of append to %s is never used", v.Name())
+		}
+	}
+}
+
+// declaredIn reports whether v is declared within fn, including as a parameter.
+func declaredIn(v *types.Var, fn ast.Node) bool {
+	return fn.Pos() <= v.Pos() && v.Pos() < fn.End()
+}
+
+// funcType returns the type of fn, an *ast.FuncDecl or *ast.FuncLit.
+func funcType(fn ast.Node) *ast.FuncType {
+	if decl, ok := fn.(*ast.FuncDecl); ok {
+		return decl.Type
+	}
+	return fn.(*ast.FuncLit).Type
+}
+
+// isAppend reports whether e is a call to the built-in append.
+func isAppend(info *types.Info, e ast.Expr) bool {
+	call, ok := astutil.Unparen(e).(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return false
+	}
+	id, ok := astutil.Unparen(call.Fun).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	b, ok := info.Uses[id].(*types.Builtin)
+	return ok && b.Name() == "append"
+}
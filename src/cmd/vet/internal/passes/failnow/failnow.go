@@ -0,0 +1,261 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package failnow defines an Analyzer that checks for calls that
+// stop a test from goroutines other than the test goroutine.
+package failnow
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"cmd/vet/internal/passes/internal/analysisutil"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const Doc = `report helpers that stop a test called from goroutines started by it
+
+The FailNow, Fatal, Fatalf, SkipNow, Skip and Skipf methods of
+testing.T, B, F and TB must be called from the goroutine running the
+test. The testinggoroutine checker reports direct calls to them from
+goroutines started by a test; this checker reports the calls it misses:
+calls made through helper functions and methods of the package, calls
+through a testing.TB or *testing.F, and goroutines started by functions
+other than the test itself. For example:
+
+	func mustGet(t *testing.T, url string) string {
+		...
+		t.Fatal(err)
+		...
+	}
+
+	func TestFoo(t *testing.T) {
+		go func() {
+			mustGet(t, url) // call to mustGet from a non-test goroutine; it calls (*T).Fatal
+		}()
+	}`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "failnow",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+var forbidden = map[string]bool{
+	"FailNow": true,
+	"Fatal":   true,
+	"Fatalf":  true,
+	"Skip":    true,
+	"Skipf":   true,
+	"SkipNow": true,
+}
+
+// A stopper describes a function of the package that may stop the test.
+type stopper struct {
+	method string // the forbidden method eventually called, such as "(*T).Fatal"
+	direct bool   // whether the function calls the method itself
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	if !analysisutil.Imports(pass.Pkg, "testing") {
+		return nil, nil
+	}
+
+	// Find the functions and methods of the package
+	// that may stop the test, directly or through others.
+	bodies := make(map[*types.Func]*ast.BlockStmt)
+	inspect.Preorder([]ast.Node{(*ast.FuncDecl)(nil)}, func(n ast.Node) {
+		decl := n.(*ast.FuncDecl)
+		if fn, ok := pass.TypesInfo.Defs[decl.Name].(*types.Func); ok && decl.Body != nil {
+			bodies[fn] = decl.Body
+		}
+	})
+	stoppers := make(map[*types.Func]stopper)
+	for changed := true; changed; {
+		changed = false
+		for fn, body := range bodies {
+			if _, ok := stoppers[fn]; ok {
+				continue
+			}
+			syncCalls(body, func(call *ast.CallExpr) bool {
+				if m := forbiddenMethod(pass.TypesInfo, call); m != "" {
+					stoppers[fn] = stopper{method: m, direct: true}
+					changed = true
+					return false
+				}
+				if s, ok := stoppers[typeutil.StaticCallee(pass.TypesInfo, call)]; ok {
+					stoppers[fn] = stopper{method: s.method}
+					changed = true
+					return false
+				}
+				return true
+			})
+		}
+	}
+
+	inspect.WithStack([]ast.Node{(*ast.GoStmt)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		goStmt := n.(*ast.GoStmt)
+
+		// The testinggoroutine checker examines the go statements
+		// in functions with a *testing.T or *testing.B parameter.
+		inTest := false
+		for i := len(stack) - 1; i >= 0; i-- {
+			if decl, ok := stack[i].(*ast.FuncDecl); ok {
+				inTest = hasTestingParam(decl.Type)
+				break
+			}
+		}
+
+		lit, ok := goStmt.Call.Fun.(*ast.FuncLit)
+		if !ok {
+			fn := typeutil.StaticCallee(pass.TypesInfo, goStmt.Call)
+			s, ok := stoppers[fn]
+			if !ok {
+				return true
+			}
+			// testinggoroutine reports a function called by name
+			// that calls (*T).Fatal and the like itself.
+			if id, isIdent := goStmt.Call.Fun.(*ast.Ident); inTest && isIdent && id.Obj != nil && s.direct && !isTBOrF(s.method) {
+				return true
+			}
+			pass.ReportRangef(goStmt, "call to %s from a non-test goroutine; it calls %s", funcName(fn), s.method)
+			return true
+		}
+		syncCalls(lit.Body, func(call *ast.CallExpr) bool {
+			if m := forbiddenMethod(pass.TypesInfo, call); m != "" {
+				// testinggoroutine reports calls on a parameter
+				// declared as a *testing.T or *testing.B.
+				if !inTest || isTBOrF(m) || !isTestingParam(call.Fun.(*ast.SelectorExpr).X) {
+					pass.ReportRangef(call, "call to %s from a non-test goroutine", m)
+				}
+			} else if fn := typeutil.StaticCallee(pass.TypesInfo, call); fn != nil {
+				if s, ok := stoppers[fn]; ok {
+					pass.ReportRangef(call, "call to %s from a non-test goroutine; it calls %s", funcName(fn), s.method)
+				}
+			}
+			return true
+		})
+		return true
+	})
+	return nil, nil
+}
+
+// isTBOrF reports whether the method named by forbiddenMethod
+// is one of testing.TB or *testing.F.
+func isTBOrF(method string) bool {
+	return strings.HasPrefix(method, "(TB).") || strings.HasPrefix(method, "(*F).")
+}
+
+// hasTestingParam reports whether the function type has a
+// parameter declared as *testing.T or *testing.B.
+func hasTestingParam(ftype *ast.FuncType) bool {
+	for _, field := range ftype.Params.List {
+		if isTestingTOrB(field.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTestingParam reports whether x is an identifier for a parameter
+// declared as *testing.T or *testing.B.
+func isTestingParam(x ast.Expr) bool {
+	id, ok := x.(*ast.Ident)
+	if !ok || id.Obj == nil {
+		return false
+	}
+	field, ok := id.Obj.Decl.(*ast.Field)
+	return ok && isTestingTOrB(field.Type)
+}
+
+// isTestingTOrB reports whether the type expression is *testing.T or *testing.B.
+func isTestingTOrB(expr ast.Expr) bool {
+	star, ok := expr.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "testing" && (sel.Sel.Name == "T" || sel.Sel.Name == "B")
+}
+
+// syncCalls calls f for each call in body made by the goroutine running
+// body, skipping function literals and go statements, until f returns false.
+func syncCalls(body *ast.BlockStmt, f func(*ast.CallExpr) bool) {
+	done := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if done {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.FuncLit, *ast.GoStmt:
+			return false
+		case *ast.CallExpr:
+			if !f(n) {
+				done = true
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// forbiddenMethod returns the name of the method that stops the test
+// called by call, such as "(*T).Fatal", or "" if call is not such a call.
+func forbiddenMethod(info *types.Info, call *ast.CallExpr) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !forbidden[sel.Sel.Name] {
+		return ""
+	}
+	if _, ok := info.Selections[sel]; !ok {
+		return "" // not a method call
+	}
+	typ := info.TypeOf(sel.X)
+	star := ""
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ, star = ptr.Elem(), "*"
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != "testing" {
+		return ""
+	}
+	switch name := named.Obj().Name(); {
+	case star == "*" && (name == "T" || name == "B" || name == "F"),
+		star == "" && name == "TB":
+		return "(" + star + name + ")." + sel.Sel.Name
+	}
+	return ""
+}
+
+// funcName returns the name of fn as it appears in diagnostics:
+// f for a function and (T).f or (*T).f for a method.
+func funcName(fn *types.Func) string {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return fn.Name()
+	}
+	typ := recv.Type()
+	star := ""
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ, star = ptr.Elem(), "*"
+	}
+	if named, ok := typ.(*types.Named); ok {
+		return "(" + star + named.Obj().Name() + ")." + fn.Name()
+	}
+	return fn.Name()
+}
@@ -0,0 +1,22 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package analysisutil defines helpers shared by the analyzers
+// in cmd/vet/internal/passes.
+package analysisutil
+
+import "go/types"
+
+// Imports reports whether pkg imports path, or is path itself.
+func Imports(pkg *types.Package, path string) bool {
+	if pkg.Path() == path {
+		return true
+	}
+	for _, imp := range pkg.Imports() {
+		if imp.Path() == path {
+			return true
+		}
+	}
+	return false
+}
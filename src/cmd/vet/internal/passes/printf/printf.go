@@ -0,0 +1,40 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package printf defines the printf Analyzer used by vet.
+//
+// It is the printf Analyzer of golang.org/x/tools, except that it
+// accepts calls to fmt.Errorf with more than one %w verb, which
+// x/tools does not yet allow. The rest of the arguments of such a
+// call are not checked.
+//
+// TODO: remove this package once the vendored x/tools accepts
+// multiple %w verbs.
+package printf
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/printf"
+)
+
+var Analyzer = newAnalyzer()
+
+func newAnalyzer() *analysis.Analyzer {
+	a := *printf.Analyzer
+	a.Run = run
+	return &a
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	p := *pass
+	p.Report = func(d analysis.Diagnostic) {
+		if strings.HasSuffix(d.Message, " call has more than one error-wrapping directive %w") {
+			return
+		}
+		pass.Report(d)
+	}
+	return printf.Analyzer.Run(&p)
+}
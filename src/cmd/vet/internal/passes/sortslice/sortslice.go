@@ -0,0 +1,73 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sortslice defines an Analyzer that checks for calls to
+// sort.Slice that do not pass a slice.
+package sortslice
+
+import (
+	"go/ast"
+	"go/types"
+
+	"cmd/vet/internal/passes/internal/analysisutil"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const Doc = `check the argument type of sort.Slice
+
+sort.Slice, sort.SliceStable and sort.SliceIsSorted take their first
+argument as an interface{} and panic at run time if it is not a slice.
+This checker reports calls whose argument has a static type that is
+not a slice, such as a pointer to a slice:
+
+	sort.Slice(&s, less) // sort.Slice's argument must be a slice; is called with *[]int`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "sortslice",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	if !analysisutil.Imports(pass.Pkg, "sort") {
+		return nil, nil
+	}
+
+	nodeFilter := []ast.Node{
+		(*ast.CallExpr)(nil),
+	}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+		if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "sort" || len(call.Args) == 0 {
+			return
+		}
+		switch fn.Name() {
+		case "Slice", "SliceStable", "SliceIsSorted":
+		default:
+			return
+		}
+
+		arg := call.Args[0]
+		typ := pass.TypesInfo.TypeOf(arg)
+		if typ == nil {
+			return
+		}
+		switch typ.Underlying().(type) {
+		case *types.Slice, *types.Interface:
+			// A slice, or an interface that may hold one.
+			return
+		}
+		pass.ReportRangef(arg, "sort.%s's argument must be a slice; is called with %s",
+			fn.Name(), types.TypeString(typ, types.RelativeTo(pass.Pkg)))
+	})
+	return nil, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sqlrows defines an Analyzer that checks for mistakes
+// using database/sql result sets.
+package sqlrows
+
+import (
+	"go/ast"
+	"go/types"
+
+	"cmd/vet/internal/passes/internal/analysisutil"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const Doc = `check for mistakes using database/sql result sets
+
+A common mistake when using the database/sql package is to defer a
+call to close the sql.Rows before checking the error that determines
+whether the rows are valid:
+
+	rows, err := db.Query(q)
+	defer rows.Close()
+	if err != nil {
+		return err
+	}
+	// (defer statement belongs here)
+
+If the query fails, rows is nil and the deferred call panics. This
+checker reports a diagnostic for such mistakes.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "sqlrows",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	// Fast path: if the package doesn't import database/sql,
+	// skip the traversal.
+	if !analysisutil.Imports(pass.Pkg, "database/sql") {
+		return nil, nil
+	}
+
+	nodeFilter := []ast.Node{
+		(*ast.CallExpr)(nil),
+	}
+	inspect.WithStack(nodeFilter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		call := n.(*ast.CallExpr)
+		if !returnsRowsAndError(pass.TypesInfo, call) {
+			return true // the function call is not related to this check.
+		}
+
+		// Find the innermost containing block, and get the list
+		// of statements starting with the one containing call.
+		stmts := restOfBlock(stack)
+		if len(stmts) < 2 {
+			return true // the call is the last statement of the block.
+		}
+
+		asg, ok := stmts[0].(*ast.AssignStmt)
+		if !ok || len(asg.Lhs) != 2 {
+			return true // the first statement is not a two-value assignment.
+		}
+		rows, ok := asg.Lhs[0].(*ast.Ident)
+		if !ok || rows.Name == "_" {
+			return true // could not find the sql.Rows in the assignment.
+		}
+
+		def, ok := stmts[1].(*ast.DeferStmt)
+		if !ok {
+			return true // the following statement is not a defer.
+		}
+		root := rootIdent(def.Call.Fun)
+		if root == nil {
+			return true // could not find the receiver of the defer call.
+		}
+
+		if obj := pass.TypesInfo.ObjectOf(rows); obj != nil && obj == pass.TypesInfo.ObjectOf(root) {
+			pass.ReportRangef(root, "using %s before checking for errors", rows.Name)
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// returnsRowsAndError reports whether call returns (*sql.Rows, error).
+func returnsRowsAndError(info *types.Info, call *ast.CallExpr) bool {
+	tuple, ok := info.TypeOf(call).(*types.Tuple)
+	if !ok || tuple.Len() != 2 {
+		return false // the function called does not return two values.
+	}
+	if ptr, ok := tuple.At(0).Type().(*types.Pointer); !ok || !isNamedType(ptr.Elem(), "database/sql", "Rows") {
+		return false // the first return type is not *sql.Rows.
+	}
+	errorType := types.Universe.Lookup("error").Type()
+	return types.Identical(tuple.At(1).Type(), errorType)
+}
+
+// restOfBlock, given a traversal stack, finds the innermost containing
+// block and returns the suffix of its statements starting with the
+// current node (the last element of stack).
+func restOfBlock(stack []ast.Node) []ast.Stmt {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if b, ok := stack[i].(*ast.BlockStmt); ok {
+			for j, v := range b.List {
+				if v == stack[i+1] {
+					return b.List[j:]
+				}
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// rootIdent finds the root identifier x in a chain of selections x.y.z, or nil if not found.
+func rootIdent(n ast.Node) *ast.Ident {
+	switch n := n.(type) {
+	case *ast.SelectorExpr:
+		return rootIdent(n.X)
+	case *ast.Ident:
+		return n
+	default:
+		return nil
+	}
+}
+
+// isNamedType reports whether t is the named type path.name.
+func isNamedType(t types.Type, path, name string) bool {
+	n, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := n.Obj()
+	return obj.Name() == name && obj.Pkg() != nil && obj.Pkg().Path() == path
+}
@@ -0,0 +1,42 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stdmethods defines the stdmethods Analyzer used by vet.
+//
+// It is the stdmethods Analyzer of golang.org/x/tools, except that it
+// accepts the Unwrap() []error method of errors wrapping several
+// errors, which x/tools does not yet know about.
+//
+// TODO: remove this package once the vendored x/tools accepts
+// Unwrap() []error.
+package stdmethods
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/stdmethods"
+)
+
+var Analyzer = newAnalyzer()
+
+func newAnalyzer() *analysis.Analyzer {
+	a := *stdmethods.Analyzer
+	a.Run = run
+	return &a
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	p := *pass
+	p.Report = func(d analysis.Diagnostic) {
+		if strings.HasPrefix(d.Message, "method Unwrap() ") {
+			if strings.HasPrefix(d.Message, "method Unwrap() []error ") {
+				return
+			}
+			d.Message += " or Unwrap() []error"
+		}
+		pass.Report(d)
+	}
+	return stdmethods.Analyzer.Run(&p)
+}
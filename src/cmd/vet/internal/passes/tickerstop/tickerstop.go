@@ -0,0 +1,176 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tickerstop defines an Analyzer that checks for
+// time.Tickers that are never stopped.
+package tickerstop
+
+import (
+	"go/ast"
+	"go/types"
+
+	"cmd/vet/internal/passes/internal/analysisutil"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const Doc = `check for time.Tickers that are never stopped
+
+A Ticker created by time.NewTicker keeps running, and is never garbage
+collected, until its Stop method is called. This checker reports
+tickers held in a local variable that the function never stops and
+never lets escape, for example:
+
+	func poll(ctx context.Context) {
+		t := time.NewTicker(time.Second) // ticker t is never stopped
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				check()
+			}
+		}
+	}
+
+It also reports tickers whose only use is their channel, as in
+<-time.NewTicker(d).C.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "tickerstop",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	// The time package itself implements Tick with a ticker
+	// that is never stopped.
+	if pass.Pkg.Path() == "time" || !analysisutil.Imports(pass.Pkg, "time") {
+		return nil, nil
+	}
+
+	nodeFilter := []ast.Node{
+		(*ast.FuncDecl)(nil),
+		(*ast.FuncLit)(nil),
+	}
+	inspect.Nodes(nodeFilter, func(n ast.Node, push bool) bool {
+		if !push {
+			return false
+		}
+		// Examine each outermost function; a ticker may be
+		// stopped by a closure within it.
+		var body *ast.BlockStmt
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			body = n.Body
+		case *ast.FuncLit:
+			body = n.Body
+		}
+		if body != nil {
+			checkFunc(pass, body)
+		}
+		return false
+	})
+	return nil, nil
+}
+
+// checkFunc reports the tickers created in body that are never stopped.
+func checkFunc(pass *analysis.Pass, body *ast.BlockStmt) {
+	// Find the local variables assigned a new ticker.
+	tickers := make(map[*types.Var]*ast.CallExpr)
+	var order []*types.Var
+	assigned := make(map[*ast.Ident]bool) // identifiers assigned a new ticker
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) != len(n.Rhs) {
+				return true
+			}
+			for i, rhs := range n.Rhs {
+				if call, ok := rhs.(*ast.CallExpr); ok && isNewTicker(pass.TypesInfo, call) {
+					if v := localVar(pass.TypesInfo, n.Lhs[i]); v != nil {
+						if tickers[v] == nil {
+							order = append(order, v)
+						}
+						tickers[v] = call
+						assigned[n.Lhs[i].(*ast.Ident)] = true
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			if len(n.Names) != len(n.Values) {
+				return true
+			}
+			for i, value := range n.Values {
+				if call, ok := value.(*ast.CallExpr); ok && isNewTicker(pass.TypesInfo, call) {
+					if v := localVar(pass.TypesInfo, n.Names[i]); v != nil {
+						if tickers[v] == nil {
+							order = append(order, v)
+						}
+						tickers[v] = call
+					}
+				}
+			}
+		case *ast.SelectorExpr:
+			// time.NewTicker(d).C: the ticker can never be stopped.
+			if call, ok := n.X.(*ast.CallExpr); ok && n.Sel.Name == "C" && isNewTicker(pass.TypesInfo, call) {
+				pass.ReportRangef(call, "ticker created by time.NewTicker is never stopped")
+			}
+		}
+		return true
+	})
+	if len(tickers) == 0 {
+		return
+	}
+
+	// Remove the tickers that are stopped or used other than
+	// to receive from or reset them.
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if _, ok := n.X.(*ast.Ident); ok && (n.Sel.Name == "C" || n.Sel.Name == "Reset") {
+				return false // a harmless use of the ticker
+			}
+		case *ast.Ident:
+			if !assigned[n] {
+				if v, ok := pass.TypesInfo.Uses[n].(*types.Var); ok {
+					delete(tickers, v)
+				}
+			}
+		}
+		return true
+	})
+
+	for _, v := range order {
+		if call := tickers[v]; call != nil {
+			pass.ReportRangef(call, "ticker %s is never stopped", v.Name())
+		}
+	}
+}
+
+// isNewTicker reports whether call is a call to time.NewTicker.
+func isNewTicker(info *types.Info, call *ast.CallExpr) bool {
+	fn, ok := typeutil.Callee(info, call).(*types.Func)
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == "time" && fn.Name() == "NewTicker"
+}
+
+// localVar returns the local variable denoted by the identifier e,
+// or nil if e is not an identifier for a local variable.
+func localVar(info *types.Info, e ast.Expr) *types.Var {
+	id, ok := e.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	v, ok := info.ObjectOf(id).(*types.Var)
+	if !ok || v.IsField() || v.Parent() == nil || v.Parent() == v.Pkg().Scope() {
+		return nil
+	}
+	return v
+}
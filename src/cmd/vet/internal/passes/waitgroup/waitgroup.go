@@ -0,0 +1,120 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package waitgroup defines an Analyzer that checks for calls to
+// sync.WaitGroup.Add from within the goroutine being waited for.
+package waitgroup
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"cmd/vet/internal/passes/internal/analysisutil"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const Doc = `check for misuses of sync.WaitGroup
+
+Calls to (*sync.WaitGroup).Add must happen before the Wait they are
+meant to delay, so they belong before the go statement that starts
+the goroutine, not within it:
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		go func() {
+			wg.Add(1) // WaitGroup.Add called from inside new goroutine
+			defer wg.Done()
+			work()
+		}()
+	}
+	wg.Wait() // may return before any work starts
+
+This checker reports a call to Add that is the first statement of a
+function literal started by a go statement, on a WaitGroup declared
+outside the literal.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "waitgroup",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	if !analysisutil.Imports(pass.Pkg, "sync") {
+		return nil, nil
+	}
+
+	nodeFilter := []ast.Node{
+		(*ast.GoStmt)(nil),
+	}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		lit, ok := n.(*ast.GoStmt).Call.Fun.(*ast.FuncLit)
+		if !ok || len(lit.Body.List) == 0 {
+			return
+		}
+		stmt, ok := lit.Body.List[0].(*ast.ExprStmt)
+		if !ok {
+			return
+		}
+		call, ok := stmt.X.(*ast.CallExpr)
+		if !ok || !isWaitGroupAdd(pass.TypesInfo, call) {
+			return
+		}
+		// A WaitGroup local to the goroutine is the goroutine's own business.
+		if v := waitGroupVar(pass.TypesInfo, call); v != nil && lit.Pos() <= v.Pos() && v.Pos() < lit.End() {
+			return
+		}
+		pass.ReportRangef(call, "WaitGroup.Add called from inside new goroutine")
+	})
+	return nil, nil
+}
+
+// isWaitGroupAdd reports whether call is a call to (*sync.WaitGroup).Add.
+func isWaitGroupAdd(info *types.Info, call *ast.CallExpr) bool {
+	fn, ok := typeutil.Callee(info, call).(*types.Func)
+	if !ok || fn.Name() != "Add" {
+		return false
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return false
+	}
+	ptr, ok := recv.Type().(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Name() == "WaitGroup" && obj.Pkg() != nil && obj.Pkg().Path() == "sync"
+}
+
+// waitGroupVar returns the variable holding the WaitGroup whose Add
+// method is called by call, or nil if it is not held in a variable.
+func waitGroupVar(info *types.Info, call *ast.CallExpr) *types.Var {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	x := sel.X
+	if u, ok := x.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		x = u.X
+	}
+	id, ok := x.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	v, _ := info.Uses[id].(*types.Var)
+	return v
+}
@@ -7,6 +7,15 @@ package main
 import (
 	"cmd/internal/objabi"
 
+	"cmd/vet/internal/passes/appends"
+	"cmd/vet/internal/passes/failnow"
+	"cmd/vet/internal/passes/printf"
+	"cmd/vet/internal/passes/sortslice"
+	"cmd/vet/internal/passes/sqlrows"
+	"cmd/vet/internal/passes/stdmethods"
+	"cmd/vet/internal/passes/tickerstop"
+	"cmd/vet/internal/passes/waitgroup"
+
 	"golang.org/x/tools/go/analysis/unitchecker"
 
 	"golang.org/x/tools/go/analysis/passes/asmdecl"
@@ -24,10 +33,8 @@ import (
 	"golang.org/x/tools/go/analysis/passes/loopclosure"
 	"golang.org/x/tools/go/analysis/passes/lostcancel"
 	"golang.org/x/tools/go/analysis/passes/nilfunc"
-	"golang.org/x/tools/go/analysis/passes/printf"
 	"golang.org/x/tools/go/analysis/passes/shift"
 	"golang.org/x/tools/go/analysis/passes/sigchanyzer"
-	"golang.org/x/tools/go/analysis/passes/stdmethods"
 	"golang.org/x/tools/go/analysis/passes/stringintconv"
 	"golang.org/x/tools/go/analysis/passes/structtag"
 	"golang.org/x/tools/go/analysis/passes/testinggoroutine"
@@ -42,6 +49,7 @@ func main() {
 	objabi.AddVersionFlag()
 
 	unitchecker.Main(
+		appends.Analyzer,
 		asmdecl.Analyzer,
 		assign.Analyzer,
 		atomic.Analyzer,
@@ -51,6 +59,7 @@ func main() {
 		composite.Analyzer,
 		copylock.Analyzer,
 		errorsas.Analyzer,
+		failnow.Analyzer,
 		framepointer.Analyzer,
 		httpresponse.Analyzer,
 		ifaceassert.Analyzer,
@@ -60,14 +69,18 @@ func main() {
 		printf.Analyzer,
 		shift.Analyzer,
 		sigchanyzer.Analyzer,
+		sortslice.Analyzer,
+		sqlrows.Analyzer,
 		stdmethods.Analyzer,
 		stringintconv.Analyzer,
 		structtag.Analyzer,
 		tests.Analyzer,
 		testinggoroutine.Analyzer,
+		tickerstop.Analyzer,
 		unmarshal.Analyzer,
 		unreachable.Analyzer,
 		unsafeptr.Analyzer,
 		unusedresult.Analyzer,
+		waitgroup.Analyzer,
 	)
 }
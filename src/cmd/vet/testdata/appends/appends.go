@@ -0,0 +1,59 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package appends
+
+func good(s []int, x int) []int {
+	s = append(s, x)
+	t := append(s[:0:0], s...)
+	return append(t, x, x)
+}
+
+func goodNamedResult(x int) (s []int) {
+	s = append(s, x)
+	return
+}
+
+func goodClosure(x int) func() []int {
+	var s []int
+	s = append(s, x)
+	return func() []int { return s }
+}
+
+func goodPointer(x int) *[]int {
+	var s []int
+	s = append(s, x)
+	return &s
+}
+
+func goodBlank(buf []byte) {
+	// Write into buf's existing backing array.
+	_ = append(buf[:0], 1, 2, 3)
+}
+
+func badParam(s []int, x int) {
+	s = append(s, x) // ERROR "result of append to s is never used"
+}
+
+func badLocal(xs []string) {
+	var out []string
+	for _, x := range xs {
+		if x != "" {
+			out = append(out, x) // ERROR "result of append to out is never used"
+		}
+	}
+}
+
+func badInClosure() {
+	f := func(x int) {
+		var s []int
+		s = append(s, x, x) // ERROR "result of append to s is never used"
+	}
+	f(1)
+}
+
+func badNoValues(s []int) []int {
+	s = append(s) // ERROR "append with no values"
+	return s
+}
@@ -0,0 +1,45 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package failnow
+
+import "testing"
+
+func mustGet(t *testing.T, key string) string {
+	if key == "" {
+		t.Fatal("empty key")
+	}
+	return key
+}
+
+func mustGetTB(tb testing.TB, key string) string {
+	if key == "" {
+		tb.Fatalf("empty key")
+	}
+	return key
+}
+
+func check(t *testing.T) {
+	mustGet(t, "")
+}
+
+type server struct {
+	t *testing.T
+}
+
+func (s *server) serve() {
+	s.t.Skip("not implemented")
+}
+
+func (s *server) start() {
+	go func() {
+		s.t.FailNow() // ERROR "call to \(\*T\).FailNow from a non-test goroutine"
+	}()
+}
+
+func runSubtest(t *testing.T) {
+	t.Run("sub", func(t *testing.T) {
+		t.Fatal("ok in a subtest")
+	})
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package failnow
+
+import "testing"
+
+func TestGood(t *testing.T) {
+	done := make(chan string)
+	go func() {
+		done <- "ok"
+	}()
+	mustGet(t, <-done)
+	runSubtest(t)
+}
+
+func TestDirect(t *testing.T) {
+	go func() {
+		t.Fatal("reported by testinggoroutine") // ERROR "call to \(\*T\).Fatal from a non-test goroutine"
+	}()
+}
+
+func TestHelper(t *testing.T) {
+	go func() {
+		mustGet(t, "") // ERROR "call to mustGet from a non-test goroutine; it calls \(\*T\).Fatal"
+	}()
+}
+
+func TestIndirect(t *testing.T) {
+	go check(t) // ERROR "call to check from a non-test goroutine; it calls \(\*T\).Fatal"
+}
+
+func TestMethod(t *testing.T) {
+	s := &server{t: t}
+	go s.serve() // ERROR "call to \(\*server\).serve from a non-test goroutine; it calls \(\*T\).Skip"
+}
+
+func TestTB(t *testing.T) {
+	var tb testing.TB = t
+	go func() {
+		tb.SkipNow()      // ERROR "call to \(TB\).SkipNow from a non-test goroutine"
+		mustGetTB(tb, "") // ERROR "call to mustGetTB from a non-test goroutine; it calls \(TB\).Fatalf"
+	}()
+}
+
+func BenchmarkHelper(b *testing.B) {
+	go func() {
+		mustGetTB(b, "") // ERROR "call to mustGetTB from a non-test goroutine; it calls \(TB\).Fatalf"
+	}()
+}
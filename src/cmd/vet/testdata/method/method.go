@@ -12,3 +12,19 @@ type MethodTest int
 
 func (t *MethodTest) Scan(x fmt.ScanState, c byte) { // ERROR "should have signature Scan\(fmt\.ScanState, rune\) error"
 }
+
+type MultiUnwrapTest int
+
+func (t MultiUnwrapTest) Error() string { return "" }
+
+func (t MultiUnwrapTest) Unwrap() []error { // OK
+	return nil
+}
+
+type BadUnwrapTest int
+
+func (t BadUnwrapTest) Error() string { return "" }
+
+func (t BadUnwrapTest) Unwrap() int { // ERROR "method Unwrap\(\) int should have signature Unwrap\(\) error or Unwrap\(\) \[\]error"
+	return 0
+}
@@ -251,6 +251,13 @@ func PrintfTests() {
 	errorf("INFO", "s=%s, n=%d", "foo", 1) // OK
 	errorf("ERROR", "%d")                  // ERROR "errorf format %d reads arg #1, but call has 0 args"
 
+	// Error wrapping
+	var err error
+	_ = fmt.Errorf("%w", err)          // OK
+	_ = fmt.Errorf("%w: %w", err, err) // OK
+	_ = fmt.Errorf("%w", "hi")         // ERROR "Errorf format %w has arg \x22hi\x22 of wrong type string"
+	Printf("%w", err)                  // ERROR "Printf does not support error-wrapping directive %w"
+
 	// Printf from external package
 	// externalprintf.Printf("%d", 42) // OK
 	// externalprintf.Printf("foobar") // OK
@@ -0,0 +1,26 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sortslice
+
+import "sort"
+
+type names []string
+
+func good(s []int, n names, v interface{}) {
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	sort.SliceStable(n, func(i, j int) bool { return n[i] < n[j] })
+	sort.Slice(v, func(i, j int) bool { return false })
+}
+
+type byName struct {
+	names []string
+}
+
+func bad(s []int, p *[]int, a [3]int, b byName) {
+	sort.Slice(p, func(i, j int) bool { return (*p)[i] < (*p)[j] }) // ERROR "sort.Slice's argument must be a slice; is called with \*\[\]int"
+	sort.SliceStable(a, func(i, j int) bool { return a[i] < a[j] }) // ERROR "sort.SliceStable's argument must be a slice; is called with \[3\]int"
+	_ = sort.SliceIsSorted(b, func(i, j int) bool { return false }) // ERROR "sort.SliceIsSorted's argument must be a slice; is called with byName"
+	sort.Slice(len(s), func(i, j int) bool { return s[i] < s[j] })  // ERROR "sort.Slice's argument must be a slice; is called with int"
+}
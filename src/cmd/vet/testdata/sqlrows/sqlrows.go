@@ -0,0 +1,62 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlrows
+
+import (
+	"context"
+	"database/sql"
+	"log"
+)
+
+func goodQuery(db *sql.DB) {
+	rows, err := db.Query("SELECT 1")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rows.Close()
+}
+
+func badQuery(db *sql.DB) {
+	rows, err := db.Query("SELECT 1")
+	defer rows.Close() // ERROR "using rows before checking for errors"
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func badQueryContext(ctx context.Context, tx *sql.Tx) {
+	r, err := tx.QueryContext(ctx, "SELECT 1")
+	defer r.Close() // ERROR "using r before checking for errors"
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func badStmtQuery(stmt *sql.Stmt) error {
+	rows, err := stmt.Query()
+	defer rows.Close() // ERROR "using rows before checking for errors"
+	return err
+}
+
+func query(db *sql.DB) (*sql.Rows, error) {
+	return db.Query("SELECT 1")
+}
+
+func badHelperQuery(db *sql.DB) {
+	rows, err := query(db)
+	defer rows.Close() // ERROR "using rows before checking for errors"
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func goodOtherDefer(db *sql.DB) {
+	rows, err := db.Query("SELECT 1")
+	defer log.Print("done")
+	if err != nil {
+		log.Fatal(err)
+	}
+	rows.Close()
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tickerstop
+
+import (
+	"context"
+	"time"
+)
+
+func goodDeferStop(ctx context.Context) {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func goodStopInClosure(ctx context.Context) {
+	t := time.NewTicker(time.Second)
+	go func() {
+		<-ctx.Done()
+		t.Stop()
+	}()
+	<-t.C
+}
+
+func goodReturned() *time.Ticker {
+	t := time.NewTicker(time.Second)
+	return t
+}
+
+type poller struct {
+	ticker *time.Ticker
+}
+
+func goodStored(p *poller) {
+	t := time.NewTicker(time.Second)
+	p.ticker = t
+}
+
+func goodPassed() {
+	t := time.NewTicker(time.Second)
+	stop(t)
+}
+
+func stop(t *time.Ticker) { t.Stop() }
+
+func badNeverStopped(ctx context.Context) {
+	t := time.NewTicker(time.Second) // ERROR "ticker t is never stopped"
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			t.Reset(2 * time.Second)
+		}
+	}
+}
+
+func badVar() {
+	var tick = time.NewTicker(time.Second) // ERROR "ticker tick is never stopped"
+	<-tick.C
+}
+
+func badInClosure() {
+	f := func() {
+		t := time.NewTicker(time.Second) // ERROR "ticker t is never stopped"
+		<-t.C
+	}
+	f()
+}
+
+func badChannelOnly() {
+	for range time.NewTicker(time.Second).C { // ERROR "ticker created by time.NewTicker is never stopped"
+	}
+}
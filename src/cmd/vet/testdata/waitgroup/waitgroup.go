@@ -0,0 +1,70 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package waitgroup
+
+import "sync"
+
+func work() {}
+
+func good(n int) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work()
+		}()
+	}
+	wg.Wait()
+}
+
+func goodNested() {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work()
+		}()
+	}()
+	wg.Wait()
+}
+
+func goodLocal() {
+	done := make(chan bool)
+	go func() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			work()
+		}()
+		wg.Wait()
+		done <- true
+	}()
+	<-done
+}
+
+func bad(n int) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		go func() {
+			wg.Add(1) // ERROR "WaitGroup.Add called from inside new goroutine"
+			defer wg.Done()
+			work()
+		}()
+	}
+	wg.Wait()
+}
+
+func badPointer(wg *sync.WaitGroup) {
+	go func() {
+		wg.Add(1) // ERROR "WaitGroup.Add called from inside new goroutine"
+		defer wg.Done()
+		work()
+	}()
+}
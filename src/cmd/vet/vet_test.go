@@ -78,6 +78,7 @@ func TestVet(t *testing.T) {
 	t.Parallel()
 	Build(t)
 	for _, pkg := range []string{
+		"appends",
 		"asm",
 		"assign",
 		"atomic",
@@ -87,6 +88,7 @@ func TestVet(t *testing.T) {
 		"composite",
 		"copylock",
 		"deadcode",
+		"failnow",
 		"httpresponse",
 		"lostcancel",
 		"method",
@@ -94,12 +96,16 @@ func TestVet(t *testing.T) {
 		"print",
 		"rangeloop",
 		"shift",
+		"sortslice",
+		"sqlrows",
 		"structtag",
 		"testingpkg",
 		// "testtag" has its own test
+		"tickerstop",
 		"unmarshal",
 		"unsafeptr",
 		"unused",
+		"waitgroup",
 	} {
 		pkg := pkg
 		t.Run(pkg, func(t *testing.T) {
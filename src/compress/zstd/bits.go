@@ -0,0 +1,108 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import "math/bits"
+
+// A bitReader reads bits from a block in little-endian order,
+// starting from the low bit of each byte. It is used for FSE
+// table descriptions (RFC 8878, section 4.1.1).
+type bitReader struct {
+	data []byte
+	off  int    // offset of the next byte to load
+	bits uint32 // loaded bits, lowest first
+	cnt  uint32 // number of valid bits in bits
+}
+
+// moreBits ensures that at least 24 bits are loaded,
+// or that all of the data has been loaded.
+func (br *bitReader) moreBits() {
+	for br.cnt <= 24 && br.off < len(br.data) {
+		br.bits |= uint32(br.data[br.off]) << br.cnt
+		br.off++
+		br.cnt += 8
+	}
+}
+
+// val returns the next b bits. It reports false if
+// there are not enough bits left in the data.
+func (br *bitReader) val(b uint32) (uint32, bool) {
+	if br.cnt < b {
+		return 0, false
+	}
+	v := br.bits & (1<<b - 1)
+	br.skip(b)
+	return v, true
+}
+
+// skip discards the next b bits, which must already be loaded.
+func (br *bitReader) skip(b uint32) {
+	br.bits >>= b
+	br.cnt -= b
+}
+
+// end returns the offset just after the last byte
+// from which bits have been used.
+func (br *bitReader) end() int {
+	return br.off - int(br.cnt/8)
+}
+
+// A reverseBitReader reads a bit stream backward, as used for
+// Huffman coded literals and FSE coded sequences (RFC 8878,
+// section 4.1). The stream is read from its last byte, most
+// significant bit first; the highest set bit of the last byte
+// marks the start of the stream.
+type reverseBitReader struct {
+	data  []byte
+	off   int    // offset of the last byte loaded
+	start int    // offset of the first byte of the stream
+	bits  uint64 // loaded bits; the valid ones are the low cnt bits
+	cnt   uint32
+}
+
+// newReverseBitReader returns a reader for the bit stream in data[start:end].
+// It reports false if the stream is empty or its final byte is zero.
+func newReverseBitReader(data []byte, start, end int) (reverseBitReader, bool) {
+	if end <= start || data[end-1] == 0 {
+		return reverseBitReader{}, false
+	}
+	last := data[end-1]
+	return reverseBitReader{
+		data:  data,
+		off:   end - 1,
+		start: start,
+		bits:  uint64(last),
+		cnt:   uint32(7 - bits.LeadingZeros8(last)),
+	}, true
+}
+
+// fetch loads bits until at least b are available.
+// It reports false if the stream runs out first.
+func (rbr *reverseBitReader) fetch(b uint32) bool {
+	for rbr.cnt < b {
+		if rbr.off <= rbr.start {
+			return false
+		}
+		rbr.off--
+		rbr.bits = rbr.bits<<8 | uint64(rbr.data[rbr.off])
+		rbr.cnt += 8
+	}
+	return true
+}
+
+// val returns the next b bits of the stream.
+// It reports false if the stream runs out.
+func (rbr *reverseBitReader) val(b uint8) (uint32, bool) {
+	if !rbr.fetch(uint32(b)) {
+		return 0, false
+	}
+	rbr.cnt -= uint32(b)
+	return uint32(rbr.bits>>rbr.cnt) & (1<<b - 1), true
+}
+
+// atEnd reports whether the whole stream has been consumed.
+func (rbr *reverseBitReader) atEnd() bool {
+	return rbr.cnt == 0 && rbr.off <= rbr.start
+}
@@ -0,0 +1,310 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+// compressedBlock decodes a compressed block (RFC 8878, section 3.1.1.3)
+// into z.buffer. The offsets reported in errors are relative to z.off,
+// the input offset of the start of the block.
+func (z *Reader) compressedBlock(data []byte) error {
+	z.buffer = z.buffer[:0]
+
+	off, literals, ok := z.readLiterals(data, 0, z.literals[:0])
+	if !ok {
+		return z.corrupt(off)
+	}
+	z.literals = literals
+
+	seqCount, off, ok := z.initSeqs(data, off)
+	if !ok {
+		return z.corrupt(off)
+	}
+
+	if seqCount == 0 {
+		// The block is only literals.
+		if off < len(data) {
+			return z.corrupt(off)
+		}
+		z.buffer = append(z.buffer, literals...)
+		return nil
+	}
+
+	return z.execSeqs(data, off, literals, seqCount)
+}
+
+// corrupt returns an error for corrupt input at offset off
+// in the current block.
+func (z *Reader) corrupt(off int) error {
+	return CorruptInputError(z.off + int64(off))
+}
+
+// initSeqs reads the sequences section header (RFC 8878,
+// section 3.1.1.3.2.1) and sets up the sequence tables.
+// It returns the number of sequences and the offset
+// of the sequences bit stream.
+func (z *Reader) initSeqs(data []byte, off int) (int, int, bool) {
+	if off >= len(data) {
+		return 0, off, false
+	}
+
+	var seqCount int
+	switch seqHdr := data[off]; {
+	case seqHdr == 0:
+		return 0, off + 1, true
+	case seqHdr < 128:
+		seqCount = int(seqHdr)
+		off++
+	case seqHdr < 255:
+		if off+1 >= len(data) {
+			return 0, off, false
+		}
+		seqCount = (int(seqHdr)-128)<<8 + int(data[off+1])
+		off += 2
+	default:
+		if off+2 >= len(data) {
+			return 0, off, false
+		}
+		seqCount = int(data[off+1]) + int(data[off+2])<<8 + 0x7f00
+		off += 3
+	}
+
+	if off >= len(data) {
+		return 0, off, false
+	}
+	modes := data[off]
+	if modes&3 != 0 {
+		// Reserved bits.
+		return 0, off, false
+	}
+	off++
+
+	// The tables are described in the order literal lengths,
+	// offsets, match lengths.
+	for i, mode := range [3]byte{modes >> 6, (modes >> 4) & 3, (modes >> 2) & 3} {
+		var ok bool
+		off, ok = z.setSeqTable(data, off, i, mode)
+		if !ok {
+			return 0, off, false
+		}
+	}
+
+	return seqCount, off, true
+}
+
+// setSeqTable uses the compression mode to set up
+// the sequence table of the given kind.
+func (z *Reader) setSeqTable(data []byte, off, kind int, mode byte) (int, bool) {
+	code := seqCodes[kind]
+	switch mode {
+	case 0: // Predefined_Mode
+		z.seqTables[kind] = predefinedTable(kind)
+		z.seqTableBits[kind] = uint8(predefinedNorm[kind].accuracyLog)
+		return off, true
+
+	case 1: // RLE_Mode
+		if off >= len(data) {
+			return off, false
+		}
+		baseline, basebits, ok := code.baseline(data[off])
+		if !ok {
+			return off, false
+		}
+		if len(z.seqTableBuffers[kind]) == 0 {
+			z.seqTableBuffers[kind] = make([]fseBaselineEntry, 1<<code.maxBits)
+		}
+		z.seqTableBuffers[kind][0] = fseBaselineEntry{
+			baseline: baseline,
+			basebits: basebits,
+		}
+		z.seqTables[kind] = z.seqTableBuffers[kind]
+		z.seqTableBits[kind] = 0
+		return off + 1, true
+
+	case 2: // FSE_Compressed_Mode
+		tableBits, noff, ok := z.readSeqTable(data, off, kind)
+		if !ok {
+			return off, false
+		}
+		z.seqTableBits[kind] = uint8(tableBits)
+		return noff, true
+
+	default: // Repeat_Mode
+		if len(z.seqTables[kind]) == 0 {
+			return off, false
+		}
+		return off, true
+	}
+}
+
+// readSeqTable reads an FSE table description for a kind of
+// sequence code into z.seqTableBuffers and makes it current.
+func (z *Reader) readSeqTable(data []byte, off, kind int) (int, int, bool) {
+	code := seqCodes[kind]
+	if len(z.fseScratch) < 1<<code.maxBits {
+		z.fseScratch = make([]fseEntry, 1<<code.maxBits)
+	}
+	tableBits, noff, ok := readFSE(data, off, code.maxSym, code.maxBits, z.fseScratch)
+	if !ok {
+		return 0, off, false
+	}
+	if len(z.seqTableBuffers[kind]) == 0 {
+		z.seqTableBuffers[kind] = make([]fseBaselineEntry, 1<<code.maxBits)
+	}
+	if !makeBaselineFSE(kind, z.fseScratch[:1<<tableBits], z.seqTableBuffers[kind]) {
+		return 0, off, false
+	}
+	z.seqTables[kind] = z.seqTableBuffers[kind]
+	return tableBits, noff, true
+}
+
+// execSeqs reads and executes the sequences (RFC 8878, section 3.1.1.4)
+// in the bit stream that starts at data[off], producing the block's
+// data in z.buffer.
+func (z *Reader) execSeqs(data []byte, off int, literals []byte, seqCount int) error {
+	rbr, ok := newReverseBitReader(data, off, len(data))
+	if !ok {
+		return z.corrupt(len(data))
+	}
+
+	// The initial states, in the order literal lengths,
+	// offsets, match lengths.
+	var state [3]uint32
+	for kind := range state {
+		v, ok := rbr.val(z.seqTableBits[kind])
+		if !ok {
+			return z.corrupt(rbr.off)
+		}
+		state[kind] = v
+	}
+
+	for seq := 0; seq < seqCount; seq++ {
+		if len(z.buffer) > z.blockLimit {
+			return z.corrupt(rbr.off)
+		}
+
+		ptoffset := &z.seqTables[seqOffset][state[seqOffset]]
+		ptmatch := &z.seqTables[seqMatch][state[seqMatch]]
+		ptliteral := &z.seqTables[seqLiteral][state[seqLiteral]]
+
+		// The additional bits are read in the order offset,
+		// match length, literal length.
+		add, ok1 := rbr.val(ptoffset.basebits)
+		offset := ptoffset.baseline + add
+		add, ok2 := rbr.val(ptmatch.basebits)
+		match := ptmatch.baseline + add
+		add, ok3 := rbr.val(ptliteral.basebits)
+		literal := ptliteral.baseline + add
+		if !ok1 || !ok2 || !ok3 {
+			return z.corrupt(rbr.off)
+		}
+
+		// Resolve repeat offsets (RFC 8878, section 3.1.1.5).
+		if offset > 3 {
+			offset -= 3
+			z.repeatedOffsets[2] = z.repeatedOffsets[1]
+			z.repeatedOffsets[1] = z.repeatedOffsets[0]
+			z.repeatedOffsets[0] = offset
+		} else {
+			idx := offset
+			if literal == 0 {
+				idx++
+			}
+			switch idx {
+			case 1:
+				offset = z.repeatedOffsets[0]
+			case 2:
+				offset = z.repeatedOffsets[1]
+				z.repeatedOffsets[1] = z.repeatedOffsets[0]
+				z.repeatedOffsets[0] = offset
+			case 3:
+				offset = z.repeatedOffsets[2]
+				z.repeatedOffsets[2] = z.repeatedOffsets[1]
+				z.repeatedOffsets[1] = z.repeatedOffsets[0]
+				z.repeatedOffsets[0] = offset
+			case 4:
+				offset = z.repeatedOffsets[0] - 1
+				if offset == 0 {
+					return z.corrupt(rbr.off)
+				}
+				z.repeatedOffsets[2] = z.repeatedOffsets[1]
+				z.repeatedOffsets[1] = z.repeatedOffsets[0]
+				z.repeatedOffsets[0] = offset
+			}
+		}
+
+		// Update the states for the next sequence, in the order
+		// literal lengths, match lengths, offsets.
+		if seq+1 < seqCount {
+			add, ok1 := rbr.val(ptliteral.bits)
+			state[seqLiteral] = uint32(ptliteral.base) + add
+			add, ok2 := rbr.val(ptmatch.bits)
+			state[seqMatch] = uint32(ptmatch.base) + add
+			add, ok3 := rbr.val(ptoffset.bits)
+			state[seqOffset] = uint32(ptoffset.base) + add
+			if !ok1 || !ok2 || !ok3 {
+				return z.corrupt(rbr.off)
+			}
+		}
+
+		if literal > uint32(len(literals)) {
+			return z.corrupt(rbr.off)
+		}
+		z.buffer = append(z.buffer, literals[:literal]...)
+		literals = literals[literal:]
+
+		if match > 0 {
+			if !z.copyMatch(offset, match) {
+				return z.corrupt(rbr.off)
+			}
+		}
+	}
+
+	z.buffer = append(z.buffer, literals...)
+	if len(z.buffer) > z.blockLimit || !rbr.atEnd() {
+		return z.corrupt(len(data))
+	}
+	return nil
+}
+
+// copyMatch appends match bytes to z.buffer copied from offset bytes
+// back, which may reach into the window of earlier blocks.
+// It reports false if the offset is out of range.
+func (z *Reader) copyMatch(offset, match uint32) bool {
+	// The offset may point into the window or into the buffer,
+	// and the match may extend past the end of the buffer:
+	//	|--z.window--|--z.buffer--|
+	//	       |<-----offset------|
+	//	       |------match----------->|
+	if offset == 0 {
+		return false
+	}
+	from := uint32(0)
+	bufLen := uint32(len(z.buffer))
+	if offset > bufLen {
+		windowLen := z.window.len()
+		n := offset - bufLen
+		if n > windowLen {
+			return false
+		}
+		start := windowLen - n
+		if n > match {
+			n = match
+		}
+		z.buffer = z.window.appendTo(z.buffer, start, start+n)
+		match -= n
+	} else {
+		from = bufLen - offset
+	}
+
+	// The match may copy bytes that it is itself appending.
+	for match > 0 {
+		n := uint32(len(z.buffer)) - from
+		if n > match {
+			n = match
+		}
+		z.buffer = append(z.buffer, z.buffer[from:from+n]...)
+		match -= n
+	}
+	return true
+}
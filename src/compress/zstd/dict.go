@@ -0,0 +1,71 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+// A dictionary holds the decoder state that frames
+// compressed with a dictionary start from.
+type dictionary struct {
+	id      uint32 // zero for raw content
+	content []byte
+
+	// The entropy tables and repeat offsets of a dictionary
+	// in the Zstandard format. They are not modified after
+	// parsing, so they may be shared by frames.
+	hasEntropy       bool
+	huffmanTable     []uint16
+	huffmanTableBits int
+	seqTables        [3][]fseBaselineEntry
+	seqTableBits     [3]uint8
+	repeatedOffsets  [3]uint32
+}
+
+// parseDictionary parses a dictionary (RFC 8878, section 5).
+// Data that does not start with the dictionary magic number
+// is raw content. The dictionary does not retain data.
+func parseDictionary(data []byte) (*dictionary, error) {
+	if len(data) < 8 || le.Uint32(data) != dictionaryMagic {
+		return &dictionary{content: append([]byte(nil), data...)}, nil
+	}
+
+	d := &dictionary{
+		id:         le.Uint32(data[4:]),
+		hasEntropy: true,
+	}
+
+	// The entropy tables are described as in a compressed block:
+	// a Huffman table for literals, then FSE tables for offsets,
+	// match lengths and literal lengths, in that order.
+	var z Reader
+	z.huffmanTableBuffer = make([]uint16, 1<<maxHuffmanBits)
+	tableBits, off, ok := z.readHuff(data, 8, z.huffmanTableBuffer)
+	if !ok {
+		return nil, ErrDictionary
+	}
+	d.huffmanTable = z.huffmanTableBuffer
+	d.huffmanTableBits = tableBits
+
+	for _, kind := range [3]int{seqOffset, seqMatch, seqLiteral} {
+		var tableBits int
+		tableBits, off, ok = z.readSeqTable(data, off, kind)
+		if !ok {
+			return nil, ErrDictionary
+		}
+		d.seqTables[kind] = z.seqTables[kind]
+		d.seqTableBits[kind] = uint8(tableBits)
+	}
+
+	if off+12 > len(data) {
+		return nil, ErrDictionary
+	}
+	d.content = append([]byte(nil), data[off+12:]...)
+	for i := range d.repeatedOffsets {
+		rep := le.Uint32(data[off+4*i:])
+		if rep == 0 || rep > uint32(len(d.content)) {
+			return nil, ErrDictionary
+		}
+		d.repeatedOffsets[i] = rep
+	}
+	return d, nil
+}
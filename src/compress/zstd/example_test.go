@@ -0,0 +1,37 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd_test
+
+import (
+	"bytes"
+	"compress/zstd"
+	"io"
+	"log"
+	"os"
+)
+
+func ExampleNewReader() {
+	compressed := []byte{
+		0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0x81, 0x00, 0x00, 0x48, 0x65, 0x6c,
+		0x6c, 0x6f, 0x2c, 0x20, 0x47, 0x6f, 0x70, 0x68, 0x65, 0x72, 0x73, 0x21,
+		0x0a, 0x3a, 0x0f, 0x63, 0x96,
+	}
+
+	zr, err := zstd.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := io.Copy(os.Stdout, zr); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := zr.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Output:
+	// Hello, Gophers!
+}
@@ -0,0 +1,350 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// An fseEntry is a single entry in an FSE decoding table.
+type fseEntry struct {
+	sym  uint8  // value that this entry records
+	bits uint8  // number of bits to read to determine the next state
+	base uint16 // base of the next state
+}
+
+// readFSE reads an FSE table description (RFC 8878, section 4.1.1)
+// starting at data[off] and builds the decoding table in table.
+// maxSym is the largest permitted symbol and maxBits the largest
+// permitted accuracy log. It returns the accuracy log of the table
+// and the offset just past the description.
+func readFSE(data []byte, off, maxSym, maxBits int, table []fseEntry) (tableBits, roff int, ok bool) {
+	br := bitReader{data: data, off: off}
+	br.moreBits()
+	v, ok := br.val(4)
+	if !ok {
+		return 0, 0, false
+	}
+	accuracyLog := int(v) + 5
+	if accuracyLog > maxBits {
+		return 0, 0, false
+	}
+
+	remaining := (1 << accuracyLog) + 1
+	threshold := 1 << accuracyLog
+	bitsNeeded := uint32(accuracyLog + 1)
+	sym := 0
+	prev0 := false
+	var norm [256]int16
+	for remaining > 1 && sym <= maxSym {
+		br.moreBits()
+
+		if prev0 {
+			// The previous count was zero, so a 2-bit repeat
+			// flag follows, giving the number of further zeros.
+			zsym := sym
+			for br.cnt >= 12 && br.bits&0xfff == 0xfff {
+				zsym += 3 * 6
+				br.skip(12)
+				br.moreBits()
+			}
+			for br.cnt >= 2 && br.bits&3 == 3 {
+				zsym += 3
+				br.skip(2)
+				br.moreBits()
+			}
+			v, ok := br.val(2)
+			if !ok {
+				return 0, 0, false
+			}
+			zsym += int(v)
+			if zsym > maxSym {
+				return 0, 0, false
+			}
+			for ; sym < zsym; sym++ {
+				norm[sym] = 0
+			}
+			prev0 = false
+			continue
+		}
+
+		max := uint32(2*threshold-1) - uint32(remaining)
+		var count int
+		if br.cnt < bitsNeeded-1 {
+			return 0, 0, false
+		}
+		if br.bits&uint32(threshold-1) < max {
+			// A small value, using one bit less.
+			count = int(br.bits & uint32(threshold-1))
+			br.skip(bitsNeeded - 1)
+		} else {
+			if br.cnt < bitsNeeded {
+				return 0, 0, false
+			}
+			count = int(br.bits & uint32(2*threshold-1))
+			if count >= threshold {
+				count -= int(max)
+			}
+			br.skip(bitsNeeded)
+		}
+
+		count-- // stored value is count + 1
+		if count >= 0 {
+			remaining -= count
+		} else {
+			remaining-- // probability less than 1
+		}
+		norm[sym] = int16(count)
+		sym++
+		prev0 = count == 0
+
+		for remaining < threshold {
+			bitsNeeded--
+			threshold >>= 1
+		}
+	}
+
+	if remaining != 1 {
+		return 0, 0, false
+	}
+	for ; sym <= maxSym; sym++ {
+		norm[sym] = 0
+	}
+
+	if !buildFSE(norm[:maxSym+1], table, accuracyLog) {
+		return 0, 0, false
+	}
+	return accuracyLog, br.end(), true
+}
+
+// buildFSE builds an FSE decoding table from a list of normalized
+// probabilities, following RFC 8878, section 4.1.1.
+func buildFSE(norm []int16, table []fseEntry, tableBits int) bool {
+	tableSize := 1 << tableBits
+	highThreshold := tableSize - 1
+
+	// Symbols with a probability of "less than 1" are
+	// placed at the end of the table.
+	var next [256]uint16
+	for i, n := range norm {
+		if n >= 0 {
+			next[i] = uint16(n)
+		} else {
+			table[highThreshold].sym = uint8(i)
+			highThreshold--
+			next[i] = 1
+		}
+	}
+
+	pos := 0
+	step := (tableSize >> 1) + (tableSize >> 3) + 3
+	mask := tableSize - 1
+	for i, n := range norm {
+		for j := 0; j < int(n); j++ {
+			table[pos].sym = uint8(i)
+			pos = (pos + step) & mask
+			for pos > highThreshold {
+				pos = (pos + step) & mask
+			}
+		}
+	}
+	if pos != 0 {
+		return false
+	}
+
+	for i := 0; i < tableSize; i++ {
+		sym := table[i].sym
+		nextState := next[sym]
+		next[sym]++
+		if nextState == 0 {
+			return false
+		}
+		highBit := 15 - bits.LeadingZeros16(nextState)
+		nbits := tableBits - highBit
+		table[i].bits = uint8(nbits)
+		table[i].base = uint16(int(nextState)<<nbits - tableSize)
+	}
+	return true
+}
+
+// An fseBaselineEntry is an entry in an FSE table used for sequences.
+// It records the baseline value and number of additional bits
+// for the symbol, so decoding need not look them up separately.
+type fseBaselineEntry struct {
+	baseline uint32 // baseline for value that this entry represents
+	basebits uint8  // number of bits to read to add to baseline
+	bits     uint8  // number of bits to read to determine the next state
+	base     uint16 // base of the next state
+}
+
+// The three kinds of sequence table, in the order in which
+// their states are initialized (RFC 8878, section 3.1.1.3.2.2).
+const (
+	seqLiteral = iota
+	seqOffset
+	seqMatch
+)
+
+// seqCode describes the codes of one kind of sequence table
+// (RFC 8878, section 3.1.1.3.2.1).
+type seqCode struct {
+	maxSym  int
+	maxBits int
+	// baseline returns the baseline and number of additional
+	// bits for sym, or false if sym is invalid.
+	baseline func(sym uint8) (uint32, uint8, bool)
+}
+
+var seqCodes = [3]seqCode{
+	seqLiteral: {maxSym: 35, maxBits: 9, baseline: literalBaseline},
+	seqOffset:  {maxSym: 31, maxBits: 8, baseline: offsetBaseline},
+	seqMatch:   {maxSym: 52, maxBits: 9, baseline: matchBaseline},
+}
+
+// literalLengthBase holds the baselines and additional bits of the
+// literal length codes from 16 on. The number of additional bits
+// is stored in the high byte.
+var literalLengthBase = [...]uint32{
+	16 | (1 << 24),
+	18 | (1 << 24),
+	20 | (1 << 24),
+	22 | (1 << 24),
+	24 | (2 << 24),
+	28 | (2 << 24),
+	32 | (3 << 24),
+	40 | (3 << 24),
+	48 | (4 << 24),
+	64 | (6 << 24),
+	128 | (7 << 24),
+	256 | (8 << 24),
+	512 | (9 << 24),
+	1024 | (10 << 24),
+	2048 | (11 << 24),
+	4096 | (12 << 24),
+	8192 | (13 << 24),
+	16384 | (14 << 24),
+	32768 | (15 << 24),
+	65536 | (16 << 24),
+}
+
+func literalBaseline(sym uint8) (uint32, uint8, bool) {
+	switch {
+	case sym < 16:
+		return uint32(sym), 0, true
+	case int(sym)-16 < len(literalLengthBase):
+		b := literalLengthBase[sym-16]
+		return b & 0xffffff, uint8(b >> 24), true
+	}
+	return 0, 0, false
+}
+
+func offsetBaseline(sym uint8) (uint32, uint8, bool) {
+	if sym > 31 {
+		return 0, 0, false
+	}
+	return 1 << sym, sym, true
+}
+
+// matchLengthBase holds the baselines and additional bits of the
+// match length codes from 32 on, stored as in literalLengthBase.
+var matchLengthBase = [...]uint32{
+	35 | (1 << 24),
+	37 | (1 << 24),
+	39 | (1 << 24),
+	41 | (1 << 24),
+	43 | (2 << 24),
+	47 | (2 << 24),
+	51 | (3 << 24),
+	59 | (3 << 24),
+	67 | (4 << 24),
+	83 | (4 << 24),
+	99 | (5 << 24),
+	131 | (7 << 24),
+	259 | (8 << 24),
+	515 | (9 << 24),
+	1027 | (10 << 24),
+	2051 | (11 << 24),
+	4099 | (12 << 24),
+	8195 | (13 << 24),
+	16387 | (14 << 24),
+	32771 | (15 << 24),
+	65539 | (16 << 24),
+}
+
+func matchBaseline(sym uint8) (uint32, uint8, bool) {
+	switch {
+	case sym < 32:
+		return uint32(sym) + 3, 0, true
+	case int(sym)-32 < len(matchLengthBase):
+		b := matchLengthBase[sym-32]
+		return b & 0xffffff, uint8(b >> 24), true
+	}
+	return 0, 0, false
+}
+
+// makeBaselineFSE converts an FSE table for a kind of sequence
+// code into a table that records baselines.
+func makeBaselineFSE(kind int, fseTable []fseEntry, baselineTable []fseBaselineEntry) bool {
+	for i, e := range fseTable {
+		baseline, basebits, ok := seqCodes[kind].baseline(e.sym)
+		if !ok {
+			return false
+		}
+		baselineTable[i] = fseBaselineEntry{
+			baseline: baseline,
+			basebits: basebits,
+			bits:     e.bits,
+			base:     e.base,
+		}
+	}
+	return true
+}
+
+// Predefined distributions of the sequence codes
+// (RFC 8878, section 3.1.1.3.2.2).
+var predefinedNorm = [3]struct {
+	accuracyLog int
+	norm        []int16
+}{
+	seqLiteral: {6, []int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}},
+	seqOffset: {5, []int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}},
+	seqMatch: {6, []int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}},
+}
+
+var (
+	predefinedOnce   sync.Once
+	predefinedTables [3][]fseBaselineEntry
+)
+
+// predefinedTable returns the baseline table for the
+// predefined distribution of a kind of sequence code.
+func predefinedTable(kind int) []fseBaselineEntry {
+	predefinedOnce.Do(func() {
+		for kind, p := range predefinedNorm {
+			size := 1 << p.accuracyLog
+			fseTable := make([]fseEntry, size)
+			baselineTable := make([]fseBaselineEntry, size)
+			if !buildFSE(p.norm, fseTable, p.accuracyLog) ||
+				!makeBaselineFSE(kind, fseTable, baselineTable) {
+				panic("zstd: bad predefined table")
+			}
+			predefinedTables[kind] = baselineTable
+		}
+	})
+	return predefinedTables[kind]
+}
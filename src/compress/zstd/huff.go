@@ -0,0 +1,164 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import "math/bits"
+
+// maxHuffmanBits is the largest possible Huffman table bits.
+const maxHuffmanBits = 11
+
+// readHuff reads a Huffman table description (RFC 8878, section 4.2.1)
+// starting at data[off] and builds the decoding table in table,
+// which must have room for 1<<maxHuffmanBits entries.
+// Each entry holds a symbol in the high byte and the number of
+// bits of its code in the low byte.
+// readHuff returns the number of bits of the table and the offset
+// just past the description.
+func (z *Reader) readHuff(data []byte, off int, table []uint16) (tableBits, roff int, ok bool) {
+	if off >= len(data) {
+		return 0, 0, false
+	}
+
+	hdr := data[off]
+	off++
+
+	var weights [256]uint8
+	var count int
+	if hdr < 128 {
+		// The weights are compressed using an FSE with two
+		// interleaved states (RFC 8878, section 4.2.1.2).
+		if len(z.fseScratch) < 1<<6 {
+			z.fseScratch = make([]fseEntry, 1<<6)
+		}
+		end := off + int(hdr)
+		if end > len(data) {
+			return 0, 0, false
+		}
+		fseBits, noff, ok := readFSE(data[:end], off, 255, 6, z.fseScratch)
+		if !ok {
+			return 0, 0, false
+		}
+		fseTable := z.fseScratch
+
+		rbr, ok := newReverseBitReader(data, noff, end)
+		if !ok {
+			return 0, 0, false
+		}
+		v1, ok1 := rbr.val(uint8(fseBits))
+		v2, ok2 := rbr.val(uint8(fseBits))
+		if !ok1 || !ok2 {
+			return 0, 0, false
+		}
+		state1, state2 := v1, v2
+
+		// Decode the states alternately. When the stream runs out
+		// while updating one state, the current symbol of the
+		// other state is the last weight.
+		for {
+			pt := &fseTable[state1]
+			if count >= 254 {
+				return 0, 0, false
+			}
+			weights[count] = pt.sym
+			count++
+			v, ok := rbr.val(pt.bits)
+			if !ok {
+				weights[count] = fseTable[state2].sym
+				count++
+				break
+			}
+			state1 = uint32(pt.base) + v
+
+			pt = &fseTable[state2]
+			weights[count] = pt.sym
+			count++
+			v, ok = rbr.val(pt.bits)
+			if !ok {
+				weights[count] = fseTable[state1].sym
+				count++
+				break
+			}
+			state2 = uint32(pt.base) + v
+		}
+		off = end
+	} else {
+		// The weights are stored directly, 4 bits each.
+		count = int(hdr) - 127
+		if off+(count+1)/2 > len(data) {
+			return 0, 0, false
+		}
+		for i := 0; i < count; i += 2 {
+			b := data[off]
+			off++
+			weights[i] = b >> 4
+			weights[i+1] = b & 0xf
+		}
+	}
+
+	// Build the table (RFC 8878, section 4.2.1.3).
+	var weightMark [13]uint32
+	weightMask := uint32(0)
+	for _, w := range weights[:count] {
+		if w > 12 {
+			return 0, 0, false
+		}
+		weightMark[w]++
+		if w > 0 {
+			weightMask += 1 << (w - 1)
+		}
+	}
+	if weightMask == 0 {
+		return 0, 0, false
+	}
+
+	tableBits = 32 - bits.LeadingZeros32(weightMask)
+	if tableBits > maxHuffmanBits {
+		return 0, 0, false
+	}
+
+	// The weight of the last symbol is omitted, because the
+	// weights must sum to a power of two.
+	left := uint32(1)<<tableBits - weightMask
+	if left == 0 || left&(left-1) != 0 {
+		return 0, 0, false
+	}
+	highBit := 31 - bits.LeadingZeros32(left)
+	if count >= 256 {
+		return 0, 0, false
+	}
+	weights[count] = uint8(highBit + 1)
+	count++
+	weightMark[highBit+1]++
+
+	if weightMark[1] < 2 || weightMark[1]&1 != 0 {
+		return 0, 0, false
+	}
+
+	// Turn weightMark from a count of the symbols with each
+	// weight into the index of the first table entry for
+	// that weight. Lower weights, which have longer codes,
+	// come first.
+	next := uint32(0)
+	for i := 0; i < tableBits; i++ {
+		cur := next
+		next += weightMark[i+1] << i
+		weightMark[i+1] = cur
+	}
+
+	for i, w := range weights[:count] {
+		if w == 0 {
+			continue
+		}
+		length := uint32(1) << (w - 1)
+		tval := uint16(i)<<8 | (uint16(tableBits) + 1 - uint16(w))
+		start := weightMark[w]
+		for j := uint32(0); j < length; j++ {
+			table[start+j] = tval
+		}
+		weightMark[w] += length
+	}
+
+	return tableBits, off, true
+}
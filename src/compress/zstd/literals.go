@@ -0,0 +1,179 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+// readLiterals reads and decodes the literals section of a compressed
+// block (RFC 8878, section 3.1.1.3.1) starting at data[off]. It appends
+// the literals to outbuf and returns the offset just past the section.
+func (z *Reader) readLiterals(data []byte, off int, outbuf []byte) (int, []byte, bool) {
+	if off >= len(data) {
+		return 0, nil, false
+	}
+
+	b0 := data[off]
+	blockType := b0 & 3
+	sizeFormat := (b0 >> 2) & 3
+
+	var regeneratedSize int
+	if blockType < 2 {
+		// Raw_Literals_Block or RLE_Literals_Block.
+		switch sizeFormat {
+		case 0, 2:
+			regeneratedSize = int(b0 >> 3)
+			off++
+		case 1:
+			if off+1 >= len(data) {
+				return 0, nil, false
+			}
+			regeneratedSize = int(b0>>4) + int(data[off+1])<<4
+			off += 2
+		case 3:
+			if off+2 >= len(data) {
+				return 0, nil, false
+			}
+			regeneratedSize = int(b0>>4) + int(data[off+1])<<4 + int(data[off+2])<<12
+			off += 3
+		}
+		if regeneratedSize > maxBlockSize {
+			return 0, nil, false
+		}
+
+		if blockType == 0 {
+			if off+regeneratedSize > len(data) {
+				return 0, nil, false
+			}
+			outbuf = append(outbuf, data[off:off+regeneratedSize]...)
+			off += regeneratedSize
+		} else {
+			if off >= len(data) {
+				return 0, nil, false
+			}
+			rle := data[off]
+			off++
+			for i := 0; i < regeneratedSize; i++ {
+				outbuf = append(outbuf, rle)
+			}
+		}
+		return off, outbuf, true
+	}
+
+	// Compressed_Literals_Block or Treeless_Literals_Block.
+	var compressedSize int
+	streams := 4
+	switch sizeFormat {
+	case 0, 1:
+		if off+2 >= len(data) {
+			return 0, nil, false
+		}
+		if sizeFormat == 0 {
+			streams = 1
+		}
+		regeneratedSize = int(b0>>4) + int(data[off+1]&0x3f)<<4
+		compressedSize = int(data[off+1]>>6) + int(data[off+2])<<2
+		off += 3
+	case 2:
+		if off+3 >= len(data) {
+			return 0, nil, false
+		}
+		regeneratedSize = int(b0>>4) + int(data[off+1])<<4 + int(data[off+2]&3)<<12
+		compressedSize = int(data[off+2]>>2) + int(data[off+3])<<6
+		off += 4
+	case 3:
+		if off+4 >= len(data) {
+			return 0, nil, false
+		}
+		regeneratedSize = int(b0>>4) + int(data[off+1])<<4 + int(data[off+2]&0x3f)<<12
+		compressedSize = int(data[off+2]>>6) + int(data[off+3])<<2 + int(data[off+4])<<10
+		off += 5
+	}
+	if regeneratedSize > maxBlockSize || off+compressedSize > len(data) {
+		return 0, nil, false
+	}
+	end := off + compressedSize
+
+	if blockType == 2 {
+		// The section starts with a new Huffman table.
+		if len(z.huffmanTableBuffer) < 1<<maxHuffmanBits {
+			z.huffmanTableBuffer = make([]uint16, 1<<maxHuffmanBits)
+		}
+		tableBits, hoff, ok := z.readHuff(data[:end], off, z.huffmanTableBuffer)
+		if !ok {
+			return 0, nil, false
+		}
+		z.huffmanTable = z.huffmanTableBuffer
+		z.huffmanTableBits = tableBits
+		off = hoff
+	} else if z.huffmanTableBits == 0 {
+		// A treeless block needs a table from an earlier block.
+		return 0, nil, false
+	}
+
+	if streams == 1 {
+		outbuf, ok := z.readLiteralsStream(data, off, end, regeneratedSize, outbuf)
+		return end, outbuf, ok
+	}
+
+	// Four streams, preceded by a jump table giving
+	// the sizes of the first three.
+	if off+6 > end {
+		return 0, nil, false
+	}
+	size1 := int(le.Uint16(data[off:]))
+	size2 := int(le.Uint16(data[off+2:]))
+	size3 := int(le.Uint16(data[off+4:]))
+	off += 6
+	if off+size1+size2+size3 > end {
+		return 0, nil, false
+	}
+	regen := (regeneratedSize + 3) / 4
+	if 3*regen > regeneratedSize {
+		return 0, nil, false
+	}
+	bounds := [5]int{off, off + size1, off + size1 + size2, off + size1 + size2 + size3, end}
+	for i := 0; i < 4; i++ {
+		n := regen
+		if i == 3 {
+			n = regeneratedSize - 3*regen
+		}
+		var ok bool
+		outbuf, ok = z.readLiteralsStream(data, bounds[i], bounds[i+1], n, outbuf)
+		if !ok {
+			return 0, nil, false
+		}
+	}
+	return end, outbuf, true
+}
+
+// readLiteralsStream decodes regeneratedSize literals from the
+// Huffman coded stream in data[start:end], appending them to outbuf.
+func (z *Reader) readLiteralsStream(data []byte, start, end, regeneratedSize int, outbuf []byte) ([]byte, bool) {
+	rbr, ok := newReverseBitReader(data, start, end)
+	if !ok {
+		return nil, false
+	}
+
+	table := z.huffmanTable
+	tableBits := uint32(z.huffmanTableBits)
+	mask := uint64(1)<<tableBits - 1
+	for i := 0; i < regeneratedSize; i++ {
+		// Near the end of the stream there may be fewer bits
+		// than a full table index; pad them with zeros.
+		rbr.fetch(tableBits)
+		var idx uint64
+		if rbr.cnt >= tableBits {
+			idx = rbr.bits >> (rbr.cnt - tableBits)
+		} else {
+			idx = rbr.bits << (tableBits - rbr.cnt)
+		}
+		t := table[idx&mask]
+		n := uint32(t & 0xff)
+		if n > rbr.cnt {
+			return nil, false
+		}
+		rbr.cnt -= n
+		outbuf = append(outbuf, byte(t>>8))
+	}
+	return outbuf, rbr.atEnd()
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+// A window stores up to size bytes of the most recently decoded data,
+// which later blocks of the frame may refer back to.
+// It is a circular buffer: once full, off is the index of the oldest byte.
+type window struct {
+	size int
+	data []byte
+	off  int
+}
+
+// reset clears the window and sets its size.
+func (w *window) reset(size int) {
+	w.data = w.data[:0]
+	w.off = 0
+	w.size = size
+}
+
+// len returns the number of bytes in the window.
+func (w *window) len() uint32 {
+	return uint32(len(w.data))
+}
+
+// save adds buf to the end of the window, discarding
+// the oldest data if there is not enough room.
+func (w *window) save(buf []byte) {
+	if w.size == 0 || len(buf) == 0 {
+		return
+	}
+
+	if len(buf) >= w.size {
+		w.data = append(w.data[:0], buf[len(buf)-w.size:]...)
+		w.off = 0
+		return
+	}
+
+	free := w.size - len(w.data)
+	if free == 0 {
+		n := copy(w.data[w.off:], buf)
+		if n == len(buf) {
+			w.off += n
+		} else {
+			w.off = copy(w.data, buf[n:])
+		}
+		return
+	}
+
+	if free >= len(buf) {
+		w.data = append(w.data, buf...)
+	} else {
+		w.data = append(w.data, buf[:free]...)
+		w.off = copy(w.data, buf[free:])
+	}
+}
+
+// appendTo appends the window bytes in [from, to) to buf,
+// where 0 is the oldest byte in the window.
+func (w *window) appendTo(buf []byte, from, to uint32) []byte {
+	dataLen := uint32(len(w.data))
+	from += uint32(w.off)
+	to += uint32(w.off)
+
+	wrap := false
+	if from > dataLen {
+		from -= dataLen
+		wrap = !wrap
+	}
+	if to > dataLen {
+		to -= dataLen
+		wrap = !wrap
+	}
+
+	if wrap {
+		buf = append(buf, w.data[from:]...)
+		return append(buf, w.data[:to]...)
+	}
+	return append(buf, w.data[from:to]...)
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import "math/bits"
+
+// The content checksum of a frame is the low 32 bits of the
+// XXH64 hash of the content, with a seed of zero
+// (RFC 8878, section 3.1.1).
+
+const (
+	prime64_1 = 11400714785074694791
+	prime64_2 = 14029467366897019727
+	prime64_3 = 1609587929392839161
+	prime64_4 = 9650029242287828579
+	prime64_5 = 2870177450012600261
+)
+
+// xxhash64 is the state of an XXH64 hash computation.
+type xxhash64 struct {
+	len uint64    // total bytes written
+	v   [4]uint64 // accumulators
+	buf [32]byte  // pending data
+	cnt int       // number of bytes in buf
+}
+
+// reset clears the hash state, using a seed of zero.
+func (xh *xxhash64) reset() {
+	xh.len = 0
+	xh.v[0] = prime64_1
+	xh.v[0] += prime64_2
+	xh.v[1] = prime64_2
+	xh.v[2] = 0
+	xh.v[3] = 0
+	xh.v[3] -= prime64_1
+	xh.cnt = 0
+}
+
+// update adds b to the hash.
+func (xh *xxhash64) update(b []byte) {
+	xh.len += uint64(len(b))
+
+	if xh.cnt+len(b) < len(xh.buf) {
+		xh.cnt += copy(xh.buf[xh.cnt:], b)
+		return
+	}
+
+	if xh.cnt > 0 {
+		n := copy(xh.buf[xh.cnt:], b)
+		b = b[n:]
+		xh.process(xh.buf[:])
+		xh.cnt = 0
+	}
+
+	for len(b) >= len(xh.buf) {
+		xh.process(b)
+		b = b[len(xh.buf):]
+	}
+
+	if len(b) > 0 {
+		xh.cnt = copy(xh.buf[:], b)
+	}
+}
+
+// process processes a 32-byte stripe from b.
+func (xh *xxhash64) process(b []byte) {
+	xh.v[0] = xxh64Round(xh.v[0], le.Uint64(b))
+	xh.v[1] = xxh64Round(xh.v[1], le.Uint64(b[8:]))
+	xh.v[2] = xxh64Round(xh.v[2], le.Uint64(b[16:]))
+	xh.v[3] = xxh64Round(xh.v[3], le.Uint64(b[24:]))
+}
+
+// digest returns the hash of the data written so far.
+func (xh *xxhash64) digest() uint64 {
+	var h64 uint64
+	if xh.len < 32 {
+		h64 = xh.v[2] + prime64_5
+	} else {
+		h64 = bits.RotateLeft64(xh.v[0], 1) +
+			bits.RotateLeft64(xh.v[1], 7) +
+			bits.RotateLeft64(xh.v[2], 12) +
+			bits.RotateLeft64(xh.v[3], 18)
+		h64 = xxh64MergeRound(h64, xh.v[0])
+		h64 = xxh64MergeRound(h64, xh.v[1])
+		h64 = xxh64MergeRound(h64, xh.v[2])
+		h64 = xxh64MergeRound(h64, xh.v[3])
+	}
+
+	h64 += xh.len
+
+	b := xh.buf[:xh.cnt]
+	for len(b) >= 8 {
+		h64 ^= xxh64Round(0, le.Uint64(b))
+		h64 = bits.RotateLeft64(h64, 27)*prime64_1 + prime64_4
+		b = b[8:]
+	}
+	if len(b) >= 4 {
+		h64 ^= uint64(le.Uint32(b)) * prime64_1
+		h64 = bits.RotateLeft64(h64, 23)*prime64_2 + prime64_3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h64 ^= uint64(c) * prime64_5
+		h64 = bits.RotateLeft64(h64, 11) * prime64_1
+	}
+
+	h64 ^= h64 >> 33
+	h64 *= prime64_2
+	h64 ^= h64 >> 29
+	h64 *= prime64_3
+	h64 ^= h64 >> 32
+	return h64
+}
+
+func xxh64Round(acc, input uint64) uint64 {
+	acc += input * prime64_2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * prime64_1
+}
+
+func xxh64MergeRound(acc, val uint64) uint64 {
+	acc ^= xxh64Round(0, val)
+	return acc*prime64_1 + prime64_4
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"strings"
+	"testing"
+)
+
+var xxHashTests = []struct {
+	input string
+	hash  uint64
+}{
+	{"", 0xef46db3751d8e999},
+	{"a", 0xd24ec4f1a98c6e5b},
+	{"abc", 0x44bc2cf5ad770999},
+	{"Nobody inspects the spammish repetition", 0xfbcea83c8a378bf1},
+}
+
+func TestXXHash(t *testing.T) {
+	var xh xxhash64
+	for _, tt := range xxHashTests {
+		xh.reset()
+		xh.update([]byte(tt.input))
+		if got := xh.digest(); got != tt.hash {
+			t.Errorf("xxhash64(%q) = %#x, want %#x", tt.input, got, tt.hash)
+		}
+	}
+}
+
+// TestXXHashSplit checks that the hash does not depend
+// on how the input is split across calls to update.
+func TestXXHashSplit(t *testing.T) {
+	input := []byte(strings.Repeat("The quick brown fox jumps over the lazy dog. ", 10))
+	var xh xxhash64
+	xh.reset()
+	xh.update(input)
+	want := xh.digest()
+	for size := 1; size < 70; size++ {
+		xh.reset()
+		for b := input; len(b) > 0; {
+			n := size
+			if n > len(b) {
+				n = len(b)
+			}
+			xh.update(b[:n])
+			b = b[n:]
+		}
+		if got := xh.digest(); got != want {
+			t.Errorf("writing %d bytes at a time: got %#x, want %#x", size, got, want)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package zstd implements reading of Zstandard compressed data,
+// as specified in RFC 8878.
+//
+// The package only implements decompression. A Reader decodes a
+// stream of zero or more Zstandard frames, skipping any skippable
+// frames, and verifies the content checksum of each frame that
+// carries one. Frames compressed with a dictionary may be decoded
+// by supplying that dictionary to NewReaderDict.
+package zstd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+)
+
+var (
+	// ErrChecksum is returned when reading Zstandard data that has an invalid checksum.
+	ErrChecksum = errors.New("zstd: invalid checksum")
+	// ErrDictionary is returned when reading Zstandard data that requires a
+	// dictionary other than the one supplied, or when the supplied
+	// dictionary is malformed.
+	ErrDictionary = errors.New("zstd: invalid dictionary")
+	// ErrHeader is returned when reading Zstandard data that has an invalid header.
+	ErrHeader = errors.New("zstd: invalid header")
+)
+
+// A CorruptInputError reports the presence of corrupt input at a given offset.
+type CorruptInputError int64
+
+func (e CorruptInputError) Error() string {
+	return "zstd: corrupt input before offset " + strconv.FormatInt(int64(e), 10)
+}
+
+var le = binary.LittleEndian
+
+// noEOF converts io.EOF to io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+const (
+	frameMagic       = 0xfd2fb528
+	skippableMagic   = 0x184d2a50 // low 4 bits are user-defined
+	skippableMask    = 0xfffffff0
+	dictionaryMagic  = 0xec30a437
+	maxBlockSize     = 128 << 10 // Block_Maximum_Size (RFC 8878, section 3.1.1.2.3)
+	maxWindowSize    = 1 << 27   // largest window the Reader will allocate
+	minWindowLog     = 10
+	frameHeaderBytes = 14 // largest frame header after the magic number
+)
+
+// A Reader is an io.Reader that can be read to retrieve
+// uncompressed data from a Zstandard stream.
+//
+// A Zstandard stream is a concatenation of frames. Reads from the Reader
+// return the concatenation of the uncompressed data of each frame;
+// skippable frames are ignored.
+//
+// Frames may store a checksum of the uncompressed data. The Reader will
+// return an ErrChecksum when Read reaches the end of such a frame if the
+// data does not match the checksum. Clients should treat data returned by
+// Read as tentative until they receive the io.EOF marking the end of the data.
+type Reader struct {
+	r    io.Reader
+	dict *dictionary
+	err  error
+
+	// Frame state.
+	off              int64  // offset in the input of the next unread byte
+	hasChecksum      bool   // frame has a content checksum
+	frameSizeUnknown bool   // frame content size is not recorded
+	remaining        uint64 // frame content size not yet produced
+	blockLimit       int    // Block_Maximum_Size for this frame
+	lastBlock        bool   // the last block of the frame has been read
+	checksum         xxhash64
+
+	// Uncompressed data of the current block, returned by Read.
+	buffer []byte
+	pos    int
+
+	window window
+
+	// Decoder state that persists across the blocks of a frame.
+	repeatedOffsets    [3]uint32
+	huffmanTable       []uint16 // previous Huffman table, for treeless literals
+	huffmanTableBits   int
+	huffmanTableBuffer []uint16
+	seqTables          [3][]fseBaselineEntry // previous sequence tables
+	seqTableBits       [3]uint8
+	seqTableBuffers    [3][]fseBaselineEntry
+
+	compressed []byte // compressed data of the current block
+	literals   []byte
+	fseScratch []fseEntry
+	scratch    [frameHeaderBytes]byte
+}
+
+// NewReader creates a new Reader reading the given reader.
+// If r does not also implement io.ByteReader,
+// the decompressor may read more data than necessary from r.
+//
+// NewReader reads the header of the first frame. If r is empty,
+// NewReader returns io.EOF.
+func NewReader(r io.Reader) (*Reader, error) {
+	return NewReaderDict(r, nil)
+}
+
+// NewReaderDict is like NewReader but decodes frames using the given
+// dictionary. The dictionary may be in the Zstandard dictionary format,
+// as produced by "zstd --train", or it may be raw content, which is
+// treated as data preceding each frame.
+//
+// Frames that do not name a dictionary are decoded using dict.
+// If a frame names a dictionary other than dict, Read returns ErrDictionary.
+// NewReaderDict returns ErrDictionary if dict is malformed.
+func NewReaderDict(r io.Reader, dict []byte) (*Reader, error) {
+	z := new(Reader)
+	if len(dict) > 0 {
+		d, err := parseDictionary(dict)
+		if err != nil {
+			return nil, err
+		}
+		z.dict = d
+	}
+	if err := z.Reset(r); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// Reset discards the Reader z's state and makes it equivalent to the
+// result of its original state from NewReader or NewReaderDict,
+// but reading from r instead. Any dictionary is retained.
+// This permits reusing a Reader rather than allocating a new one.
+func (z *Reader) Reset(r io.Reader) error {
+	if _, ok := r.(io.ByteReader); ok {
+		z.r = r
+	} else {
+		z.r = bufio.NewReader(r)
+	}
+	z.off = 0
+	z.buffer = z.buffer[:0]
+	z.pos = 0
+	z.err = z.readFrameHeader()
+	return z.err
+}
+
+// Read implements io.Reader, reading uncompressed bytes from its underlying Reader.
+func (z *Reader) Read(p []byte) (n int, err error) {
+	for z.pos >= len(z.buffer) {
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.err = z.refill()
+	}
+	n = copy(p, z.buffer[z.pos:])
+	z.pos += n
+	return n, nil
+}
+
+// Close closes the Reader. It does not close the underlying io.Reader.
+// In order for the frame checksums to be verified, the reader must be
+// fully consumed until the io.EOF.
+func (z *Reader) Close() error {
+	if z.err == io.EOF {
+		return nil
+	}
+	return z.err
+}
+
+// refill decodes the next block into z.buffer, moving on to the next
+// frame at the end of the current one.
+func (z *Reader) refill() error {
+	z.buffer = z.buffer[:0]
+	z.pos = 0
+	if z.lastBlock {
+		if err := z.finishFrame(); err != nil {
+			return err
+		}
+		return z.readFrameHeader()
+	}
+	return z.readBlock()
+}
+
+// readFrameHeader reads the header of the next frame according to
+// RFC 8878, section 3.1.1, skipping over any skippable frames.
+// It returns io.EOF if there are no more frames.
+func (z *Reader) readFrameHeader() error {
+	var magic uint32
+	for {
+		n, err := io.ReadFull(z.r, z.scratch[:4])
+		if err != nil {
+			if err == io.ErrUnexpectedEOF || (err == io.EOF && n > 0) {
+				return ErrHeader
+			}
+			return err
+		}
+		z.off += 4
+		magic = le.Uint32(z.scratch[:4])
+		if magic&skippableMask != skippableMagic {
+			break
+		}
+		if err := z.skipFrame(); err != nil {
+			return err
+		}
+	}
+	if magic != frameMagic {
+		return ErrHeader
+	}
+
+	if _, err := io.ReadFull(z.r, z.scratch[:1]); err != nil {
+		return noEOF(err)
+	}
+	descriptor := z.scratch[0]
+	fcsFlag := descriptor >> 6
+	singleSegment := descriptor&(1<<5) != 0
+	if descriptor&(1<<3) != 0 {
+		// Reserved bit.
+		return ErrHeader
+	}
+	z.hasChecksum = descriptor&(1<<2) != 0
+
+	// Work out the size of the rest of the header.
+	windowDescriptorSize := 1
+	if singleSegment {
+		windowDescriptorSize = 0
+	}
+	dictIDSize := [4]int{0, 1, 2, 4}[descriptor&3]
+	fcsSize := [4]int{0, 2, 4, 8}[fcsFlag]
+	if fcsFlag == 0 && singleSegment {
+		fcsSize = 1
+	}
+	size := windowDescriptorSize + dictIDSize + fcsSize
+	hdr := z.scratch[:size]
+	if _, err := io.ReadFull(z.r, hdr); err != nil {
+		return noEOF(err)
+	}
+	z.off += 1 + int64(size)
+
+	windowSize := uint64(0)
+	if !singleSegment {
+		exponent := uint64(hdr[0] >> 3)
+		mantissa := uint64(hdr[0] & 7)
+		windowLog := minWindowLog + exponent
+		windowBase := uint64(1) << windowLog
+		windowSize = windowBase + (windowBase/8)*mantissa
+		hdr = hdr[1:]
+	}
+
+	var dictID uint32
+	switch dictIDSize {
+	case 1:
+		dictID = uint32(hdr[0])
+	case 2:
+		dictID = uint32(le.Uint16(hdr))
+	case 4:
+		dictID = le.Uint32(hdr)
+	}
+	hdr = hdr[dictIDSize:]
+
+	z.frameSizeUnknown = false
+	switch fcsSize {
+	case 0:
+		z.frameSizeUnknown = true
+	case 1:
+		z.remaining = uint64(hdr[0])
+	case 2:
+		z.remaining = uint64(le.Uint16(hdr)) + 256
+	case 4:
+		z.remaining = uint64(le.Uint32(hdr))
+	case 8:
+		z.remaining = le.Uint64(hdr)
+	}
+
+	if singleSegment {
+		// The window is the whole frame (RFC 8878, section 3.1.1.1.2).
+		windowSize = z.remaining
+	}
+	if windowSize > maxWindowSize {
+		return ErrHeader
+	}
+
+	z.blockLimit = maxBlockSize
+	if windowSize < maxBlockSize {
+		z.blockLimit = int(windowSize)
+	}
+
+	if dictID != 0 && (z.dict == nil || z.dict.id != dictID) {
+		return ErrDictionary
+	}
+	z.startFrame(int(windowSize))
+	return nil
+}
+
+// startFrame resets the decoder state at the start of a frame,
+// priming it from the dictionary if there is one.
+func (z *Reader) startFrame(windowSize int) {
+	z.lastBlock = false
+	z.checksum.reset()
+	z.repeatedOffsets = [3]uint32{1, 4, 8}
+	z.huffmanTable = nil
+	z.huffmanTableBits = 0
+	z.seqTables = [3][]fseBaselineEntry{}
+	z.seqTableBits = [3]uint8{}
+
+	d := z.dict
+	if d == nil {
+		z.window.reset(windowSize)
+		return
+	}
+	// Dictionary content precedes the frame content and
+	// may always be referenced (RFC 8878, section 5).
+	z.window.reset(windowSize + len(d.content))
+	z.window.save(d.content)
+	if d.hasEntropy {
+		z.repeatedOffsets = d.repeatedOffsets
+		z.huffmanTable = d.huffmanTable
+		z.huffmanTableBits = d.huffmanTableBits
+		z.seqTables = d.seqTables
+		z.seqTableBits = d.seqTableBits
+	}
+}
+
+// skipFrame skips the content of a skippable frame
+// (RFC 8878, section 3.1.2) whose magic number has been read.
+func (z *Reader) skipFrame() error {
+	if _, err := io.ReadFull(z.r, z.scratch[:4]); err != nil {
+		return noEOF(err)
+	}
+	size := int64(le.Uint32(z.scratch[:4]))
+	z.off += 4
+	n, err := io.CopyN(io.Discard, z.r, size)
+	z.off += n
+	if err != nil {
+		return noEOF(err)
+	}
+	return nil
+}
+
+// readBlock reads and decodes the next block of the frame
+// (RFC 8878, section 3.1.1.2).
+func (z *Reader) readBlock() error {
+	if _, err := io.ReadFull(z.r, z.scratch[:3]); err != nil {
+		return noEOF(err)
+	}
+	z.off += 3
+	header := uint32(z.scratch[0]) | uint32(z.scratch[1])<<8 | uint32(z.scratch[2])<<16
+	z.lastBlock = header&1 != 0
+	blockType := (header >> 1) & 3
+	blockSize := int(header >> 3)
+
+	switch blockType {
+	case 0: // Raw_Block
+		if blockSize > z.blockLimit {
+			return CorruptInputError(z.off)
+		}
+		z.buffer = grow(z.buffer, blockSize)
+		if _, err := io.ReadFull(z.r, z.buffer); err != nil {
+			return noEOF(err)
+		}
+		z.off += int64(blockSize)
+	case 1: // RLE_Block
+		if blockSize > z.blockLimit {
+			return CorruptInputError(z.off)
+		}
+		if _, err := io.ReadFull(z.r, z.scratch[:1]); err != nil {
+			return noEOF(err)
+		}
+		z.off++
+		z.buffer = grow(z.buffer, blockSize)
+		for i := range z.buffer {
+			z.buffer[i] = z.scratch[0]
+		}
+	case 2: // Compressed_Block
+		if blockSize > z.blockLimit {
+			return CorruptInputError(z.off)
+		}
+		z.compressed = grow(z.compressed, blockSize)
+		if _, err := io.ReadFull(z.r, z.compressed); err != nil {
+			return noEOF(err)
+		}
+		if err := z.compressedBlock(z.compressed); err != nil {
+			return err
+		}
+		z.off += int64(blockSize)
+	default: // Reserved
+		return CorruptInputError(z.off)
+	}
+
+	if !z.frameSizeUnknown {
+		if uint64(len(z.buffer)) > z.remaining {
+			return CorruptInputError(z.off)
+		}
+		z.remaining -= uint64(len(z.buffer))
+	}
+	z.window.save(z.buffer)
+	if z.hasChecksum {
+		z.checksum.update(z.buffer)
+	}
+	return nil
+}
+
+// finishFrame checks the frame content size and checksum
+// after the last block of a frame.
+func (z *Reader) finishFrame() error {
+	if !z.frameSizeUnknown && z.remaining != 0 {
+		return CorruptInputError(z.off)
+	}
+	if !z.hasChecksum {
+		return nil
+	}
+	if _, err := io.ReadFull(z.r, z.scratch[:4]); err != nil {
+		return noEOF(err)
+	}
+	z.off += 4
+	if le.Uint32(z.scratch[:4]) != uint32(z.checksum.digest()) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// grow returns b resized to n bytes, reusing its storage if possible.
+func grow(b []byte, n int) []byte {
+	if cap(b) < n {
+		return make([]byte, n)
+	}
+	return b[:n]
+}
@@ -0,0 +1,232 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"testing/iotest"
+)
+
+// The compressed files in testdata were produced by the zstd
+// command line tool version 1.5.6:
+//
+//	zstd -19 gettysburg.txt -o gettysburg.txt.zst
+//	zstd -1 --no-check gettysburg.txt -o gettysburg-nocheck.txt.zst
+//	zstd -19 --zstd=wlog=10 e.txt -o e-window1k.txt.zst
+//	zstd -19 -D dict gettysburg.txt -o gettysburg-dict.txt.zst
+//	zstd -19 -D gettysburg.txt gettysburg.txt -o gettysburg-rawdict.txt.zst
+//
+// where dict was trained on the Go files of the bytes and strings packages:
+//
+//	zstd --train --maxdict=4096 samples/* -o dict
+
+var readerTests = []struct {
+	name string
+	file string // compressed file in testdata
+	raw  string // uncompressed file in ../testdata
+	dict string // dictionary, if any
+}{
+	{"checksum", "gettysburg.txt.zst", "gettysburg.txt", ""},
+	{"no-checksum", "gettysburg-nocheck.txt.zst", "gettysburg.txt", ""},
+	{"small-window", "e-window1k.txt.zst", "e.txt", ""},
+	{"dictionary", "gettysburg-dict.txt.zst", "gettysburg.txt", "testdata/dict"},
+	{"raw-dictionary", "gettysburg-rawdict.txt.zst", "gettysburg.txt", "../testdata/gettysburg.txt"},
+}
+
+func readFile(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReader(t *testing.T) {
+	for _, tt := range readerTests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed := readFile(t, "testdata/"+tt.file)
+			want := readFile(t, "../testdata/"+tt.raw)
+			var dict []byte
+			if tt.dict != "" {
+				dict = readFile(t, tt.dict)
+			}
+
+			for _, r := range []io.Reader{
+				bytes.NewReader(compressed),
+				iotest.OneByteReader(bytes.NewReader(compressed)),
+			} {
+				zr, err := NewReaderDict(r, dict)
+				if err != nil {
+					t.Fatalf("NewReaderDict: %v", err)
+				}
+				got, err := io.ReadAll(iotest.HalfReader(zr))
+				if err != nil {
+					t.Fatalf("ReadAll: %v", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("got %d bytes, want %d bytes matching %s", len(got), len(want), tt.raw)
+				}
+				if err := zr.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// hello is "Hello, Gophers!\n" compressed with a content checksum.
+var hello = []byte{
+	0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0x81, 0x00, 0x00, 0x48, 0x65, 0x6c,
+	0x6c, 0x6f, 0x2c, 0x20, 0x47, 0x6f, 0x70, 0x68, 0x65, 0x72, 0x73, 0x21,
+	0x0a, 0x3a, 0x0f, 0x63, 0x96,
+}
+
+// empty is an empty frame with a content checksum.
+var empty = []byte{
+	0x28, 0xb5, 0x2f, 0xfd, 0x24, 0x00, 0x01, 0x00, 0x00, 0x99, 0xe9, 0xd8,
+	0x51,
+}
+
+// skippable is a skippable frame holding four bytes of user data.
+var skippable = []byte{0x5a, 0x2a, 0x4d, 0x18, 0x04, 0x00, 0x00, 0x00, 'G', 'o', 'p', 'h'}
+
+func join(frames ...[]byte) []byte {
+	return bytes.Join(frames, nil)
+}
+
+func TestReaderFrames(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{"empty-frame", empty, ""},
+		{"concatenated", join(hello, empty, hello), "Hello, Gophers!\nHello, Gophers!\n"},
+		{"skippable", join(skippable, hello, skippable, skippable, hello), "Hello, Gophers!\nHello, Gophers!\n"},
+	}
+	for _, tt := range tests {
+		zr, err := NewReader(bytes.NewReader(tt.input))
+		if err != nil {
+			t.Errorf("%s: NewReader: %v", tt.name, err)
+			continue
+		}
+		got, err := io.ReadAll(zr)
+		if err != nil {
+			t.Errorf("%s: ReadAll: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	badChecksum := join(hello)
+	badChecksum[len(badChecksum)-1] ^= 0xff
+
+	tests := []struct {
+		name          string
+		input         []byte
+		newReaderErr  error
+		readErr       error
+		wantCorrupted bool
+	}{
+		{name: "empty-input", input: nil, newReaderErr: io.EOF},
+		{name: "only-skippable", input: skippable, newReaderErr: io.EOF},
+		{name: "bad-magic", input: []byte("Hello, Gophers!\n"), newReaderErr: ErrHeader},
+		{name: "short-magic", input: hello[:2], newReaderErr: ErrHeader},
+		{name: "short-header", input: hello[:5], newReaderErr: io.ErrUnexpectedEOF},
+		{name: "truncated", input: hello[:len(hello)-6], readErr: io.ErrUnexpectedEOF},
+		{name: "bad-checksum", input: badChecksum, readErr: ErrChecksum},
+		{name: "trailing-garbage", input: join(hello, []byte{1, 2, 3, 4, 5}), readErr: ErrHeader},
+		{name: "reserved-block", input: join(hello[:6], []byte{0x07, 0, 0}), wantCorrupted: true},
+	}
+	for _, tt := range tests {
+		zr, err := NewReader(bytes.NewReader(tt.input))
+		if err != tt.newReaderErr {
+			t.Errorf("%s: NewReader error = %v, want %v", tt.name, err, tt.newReaderErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		_, err = io.ReadAll(zr)
+		var corrupt CorruptInputError
+		if tt.wantCorrupted {
+			if !errors.As(err, &corrupt) {
+				t.Errorf("%s: ReadAll error = %v, want CorruptInputError", tt.name, err)
+			}
+		} else if err != tt.readErr {
+			t.Errorf("%s: ReadAll error = %v, want %v", tt.name, err, tt.readErr)
+		}
+	}
+}
+
+func TestReaderDictionaryMismatch(t *testing.T) {
+	compressed := readFile(t, "testdata/gettysburg-dict.txt.zst")
+	for _, dict := range [][]byte{nil, readFile(t, "../testdata/gettysburg.txt")} {
+		if _, err := NewReaderDict(bytes.NewReader(compressed), dict); err != ErrDictionary {
+			t.Errorf("NewReaderDict with %d byte dictionary: got error %v, want %v", len(dict), err, ErrDictionary)
+		}
+	}
+
+	dict := readFile(t, "testdata/dict")
+	dict = dict[:len(dict)/2]
+	dict[10] ^= 0xff
+	if _, err := NewReaderDict(bytes.NewReader(compressed), dict); err != ErrDictionary {
+		t.Errorf("NewReaderDict with malformed dictionary: got error %v, want %v", err, ErrDictionary)
+	}
+}
+
+// TestReaderCorrupt checks that corrupting any single byte of the
+// input either produces an error or, if the byte is unused,
+// leaves the output unchanged.
+func TestReaderCorrupt(t *testing.T) {
+	compressed := readFile(t, "testdata/gettysburg.txt.zst")
+	want := readFile(t, "../testdata/gettysburg.txt")
+	for i := range compressed {
+		for _, mask := range []byte{0x01, 0x80, 0xff} {
+			input := join(compressed)
+			input[i] ^= mask
+			zr, err := NewReader(bytes.NewReader(input))
+			if err != nil {
+				continue
+			}
+			got, err := io.ReadAll(zr)
+			if err == nil && !bytes.Equal(got, want) {
+				t.Errorf("byte %d ^= %#x: no error but wrong output", i, mask)
+			}
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	zr, err := NewReader(bytes.NewReader(hello))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(zr); err != nil {
+		t.Fatal(err)
+	}
+
+	compressed := readFile(t, "testdata/gettysburg.txt.zst")
+	want := readFile(t, "../testdata/gettysburg.txt")
+	if err := zr.Reset(bytes.NewReader(compressed)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("after Reset: got %d bytes, want %d bytes", len(got), len(want))
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package constraints
+
+import "testing"
+
+type (
+	testSigned[T Signed]     struct{ f T }
+	testUnsigned[T Unsigned] struct{ f T }
+	testInteger[T Integer]   struct{ f T }
+	testFloat[T Float]       struct{ f T }
+	testComplex[T Complex]   struct{ f T }
+	testOrdered[T Ordered]   struct{ f T }
+)
+
+// TestTypes passes if it compiles.
+type TestTypes struct {
+	_ testSigned[int]
+	_ testSigned[int64]
+	_ testUnsigned[uint]
+	_ testUnsigned[uintptr]
+	_ testInteger[int8]
+	_ testInteger[uint8]
+	_ testInteger[uintptr]
+	_ testFloat[float32]
+	_ testComplex[complex64]
+	_ testOrdered[int]
+	_ testOrdered[float64]
+	_ testOrdered[string]
+}
+
+func max[T Ordered](a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+type myString string
+
+func TestOrdered(t *testing.T) {
+	if got := max(1, 2); got != 2 {
+		t.Errorf("max(1, 2) = %v, want 2", got)
+	}
+	if got := max(myString("a"), myString("b")); got != "b" {
+		t.Errorf(`max("a", "b") = %q, want "b"`, got)
+	}
+}
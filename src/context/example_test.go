@@ -6,6 +6,7 @@ package context_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -118,3 +119,39 @@ func ExampleWithValue() {
 	// found value: Go
 	// key not found: color
 }
+
+// This example uses WithCancelCause to record why a fan-out of workers was
+// abandoned, and Cause to retrieve that reason after the context is done.
+func ExampleWithCancelCause() {
+	errBackendDown := errors.New("backend down")
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errBackendDown)
+
+	<-ctx.Done()
+	fmt.Println(ctx.Err())
+	fmt.Println(context.Cause(ctx))
+
+	// Output:
+	// context canceled
+	// backend down
+}
+
+// This example uses AfterFunc to run cleanup once a context is canceled,
+// without dedicating a goroutine to waiting on its Done channel.
+func ExampleAfterFunc() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cleaned := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		fmt.Println("cleaning up")
+		close(cleaned)
+	})
+	defer stop()
+
+	cancel()
+	<-cleaned
+
+	// Output:
+	// cleaning up
+}
@@ -0,0 +1,297 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"internal/tlsquic"
+	"testing"
+)
+
+type testQUICConn struct {
+	t           *testing.T
+	conn        *quicConn
+	readSecret  map[tlsquic.EncryptionLevel]suiteSecret
+	writeSecret map[tlsquic.EncryptionLevel]suiteSecret
+	gotParams   []byte
+	complete    bool
+}
+
+func newTestQUICClient(t *testing.T, config *Config) *testQUICConn {
+	q := &testQUICConn{t: t}
+	q.conn = newQUICConn(Client(nil, config)).(*quicConn)
+	t.Cleanup(func() {
+		q.conn.Close()
+	})
+	return q
+}
+
+func newTestQUICServer(t *testing.T, config *Config) *testQUICConn {
+	q := &testQUICConn{t: t}
+	q.conn = newQUICConn(Server(nil, config)).(*quicConn)
+	t.Cleanup(func() {
+		q.conn.Close()
+	})
+	return q
+}
+
+type suiteSecret struct {
+	suite  uint16
+	secret []byte
+}
+
+func (q *testQUICConn) setReadSecret(level tlsquic.EncryptionLevel, suite uint16, secret []byte) {
+	if _, ok := q.writeSecret[level]; !ok {
+		q.t.Errorf("SetReadSecret for level %v called before SetWriteSecret", level)
+	}
+	if level == tlsquic.EncryptionLevelApplication && !q.complete {
+		q.t.Errorf("SetReadSecret for level %v called before HandshakeComplete", level)
+	}
+	if _, ok := q.readSecret[level]; ok {
+		q.t.Errorf("SetReadSecret for level %v called twice", level)
+	}
+	if q.readSecret == nil {
+		q.readSecret = map[tlsquic.EncryptionLevel]suiteSecret{}
+	}
+	switch level {
+	case tlsquic.EncryptionLevelHandshake, tlsquic.EncryptionLevelApplication:
+		q.readSecret[level] = suiteSecret{suite, secret}
+	default:
+		q.t.Errorf("SetReadSecret for unexpected level %v", level)
+	}
+}
+
+func (q *testQUICConn) setWriteSecret(level tlsquic.EncryptionLevel, suite uint16, secret []byte) {
+	if _, ok := q.writeSecret[level]; ok {
+		q.t.Errorf("SetWriteSecret for level %v called twice", level)
+	}
+	if q.writeSecret == nil {
+		q.writeSecret = map[tlsquic.EncryptionLevel]suiteSecret{}
+	}
+	switch level {
+	case tlsquic.EncryptionLevelHandshake, tlsquic.EncryptionLevelApplication:
+		q.writeSecret[level] = suiteSecret{suite, secret}
+	default:
+		q.t.Errorf("SetWriteSecret for unexpected level %v", level)
+	}
+}
+
+var errTransportParametersRequired = errors.New("transport parameters required")
+
+func runTestQUICConnection(ctx context.Context, cli, srv *testQUICConn, onEvent func(e tlsquic.Event, src, dst *testQUICConn) bool) error {
+	a, b := cli, srv
+	for _, c := range []*testQUICConn{a, b} {
+		if !c.conn.conn.quic.started {
+			if err := c.conn.Start(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	idleCount := 0
+	for {
+		e := a.conn.NextEvent()
+		if onEvent != nil && onEvent(e, a, b) {
+			continue
+		}
+		switch e.Kind {
+		case tlsquic.NoEvent:
+			idleCount++
+			if idleCount == 2 {
+				if !a.complete || !b.complete {
+					return errors.New("handshake incomplete")
+				}
+				return nil
+			}
+			a, b = b, a
+		case tlsquic.SetReadSecret:
+			a.setReadSecret(e.Level, e.Suite, e.Data)
+		case tlsquic.SetWriteSecret:
+			a.setWriteSecret(e.Level, e.Suite, e.Data)
+		case tlsquic.WriteData:
+			if err := b.conn.HandleData(e.Level, e.Data); err != nil {
+				return err
+			}
+		case tlsquic.TransportParameters:
+			a.gotParams = e.Data
+			if a.gotParams == nil {
+				a.gotParams = []byte{}
+			}
+		case tlsquic.TransportParametersRequired:
+			return errTransportParametersRequired
+		case tlsquic.HandshakeDone:
+			a.complete = true
+		}
+		if e.Kind != tlsquic.NoEvent {
+			idleCount = 0
+		}
+	}
+}
+
+func testQUICConfig() *Config {
+	config := testConfig.Clone()
+	config.MinVersion = VersionTLS13
+	config.NextProtos = []string{"h3"}
+	return config
+}
+
+func TestQUICConnection(t *testing.T) {
+	config := testQUICConfig()
+
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters([]byte("client params"))
+
+	srv := newTestQUICServer(t, config)
+	srv.conn.SetTransportParameters([]byte("server params"))
+
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != nil {
+		t.Fatalf("error during connection handshake: %v", err)
+	}
+
+	for _, level := range []tlsquic.EncryptionLevel{tlsquic.EncryptionLevelHandshake, tlsquic.EncryptionLevelApplication} {
+		if _, ok := cli.readSecret[level]; !ok {
+			t.Errorf("client has no %v read secret", level)
+		}
+		if _, ok := srv.readSecret[level]; !ok {
+			t.Errorf("server has no %v read secret", level)
+		}
+		if !bytes.Equal(cli.readSecret[level].secret, srv.writeSecret[level].secret) {
+			t.Errorf("client read secret does not match server write secret for level %v", level)
+		}
+		if !bytes.Equal(cli.writeSecret[level].secret, srv.readSecret[level].secret) {
+			t.Errorf("client write secret does not match server read secret for level %v", level)
+		}
+	}
+
+	if got, want := string(cli.gotParams), "server params"; got != want {
+		t.Errorf("client got transport params %q, want %q", got, want)
+	}
+	if got, want := string(srv.gotParams), "client params"; got != want {
+		t.Errorf("server got transport params %q, want %q", got, want)
+	}
+
+	for _, q := range []*testQUICConn{cli, srv} {
+		cs := q.conn.conn.ConnectionState()
+		if !cs.HandshakeComplete || cs.Version != VersionTLS13 || cs.NegotiatedProtocol != "h3" {
+			t.Errorf("got connection state %+v, want a completed TLS 1.3 handshake negotiating h3", cs)
+		}
+	}
+}
+
+func TestQUICDelayedTransportParameters(t *testing.T) {
+	config := testQUICConfig()
+
+	cli := newTestQUICClient(t, config)
+	srv := newTestQUICServer(t, config)
+
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != errTransportParametersRequired {
+		t.Fatalf("handshake with no client parameters: %v; want errTransportParametersRequired", err)
+	}
+	cli.conn.SetTransportParameters([]byte("client params"))
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != errTransportParametersRequired {
+		t.Fatalf("handshake with no server parameters: %v; want errTransportParametersRequired", err)
+	}
+	if got, want := string(srv.gotParams), "client params"; got != want {
+		t.Errorf("server got transport params %q, want %q", got, want)
+	}
+	srv.conn.SetTransportParameters([]byte("server params"))
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != nil {
+		t.Fatalf("error during connection handshake: %v", err)
+	}
+	if got, want := string(cli.gotParams), "server params"; got != want {
+		t.Errorf("client got transport params %q, want %q", got, want)
+	}
+}
+
+func TestQUICEmptyTransportParameters(t *testing.T) {
+	config := testQUICConfig()
+
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters(nil)
+	srv := newTestQUICServer(t, config)
+	srv.conn.SetTransportParameters(nil)
+	if err := runTestQUICConnection(context.Background(), cli, srv, nil); err != nil {
+		t.Fatalf("error during connection handshake: %v", err)
+	}
+	if cli.gotParams == nil || len(cli.gotParams) != 0 {
+		t.Errorf("client got transport params %q, want empty", cli.gotParams)
+	}
+	if srv.gotParams == nil || len(srv.gotParams) != 0 {
+		t.Errorf("server got transport params %q, want empty", srv.gotParams)
+	}
+}
+
+func TestQUICNoApplicationProtocol(t *testing.T) {
+	config := testQUICConfig()
+	config.NextProtos = nil
+
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters(nil)
+	srv := newTestQUICServer(t, config)
+	srv.conn.SetTransportParameters(nil)
+	err := runTestQUICConnection(context.Background(), cli, srv, nil)
+	var alert tlsquic.AlertError
+	if !errors.As(err, &alert) || alert != tlsquic.AlertError(alertNoApplicationProtocol) {
+		t.Errorf("handshake without ALPN: %v; want tlsquic.AlertError(%v)", err, alertNoApplicationProtocol)
+	}
+}
+
+func TestQUICRequiresTLS13(t *testing.T) {
+	config := testQUICConfig()
+	config.MinVersion = VersionTLS12
+	cli := newTestQUICClient(t, config)
+	if err := cli.conn.Start(context.Background()); err == nil {
+		t.Errorf("Start with MinVersion TLS 1.2 succeeded, want error")
+	}
+}
+
+func TestQUICHandleDataWrongLevel(t *testing.T) {
+	config := testQUICConfig()
+
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters(nil)
+	if err := cli.conn.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	err := cli.conn.HandleData(tlsquic.EncryptionLevelApplication, []byte{0})
+	var alert tlsquic.AlertError
+	if !errors.As(err, &alert) {
+		t.Errorf("HandleData at the wrong level: %v; want an tlsquic.AlertError", err)
+	}
+}
+
+func TestQUICCanceledWaitingForData(t *testing.T) {
+	config := testQUICConfig()
+
+	cli := newTestQUICClient(t, config)
+	cli.conn.SetTransportParameters(nil)
+	if err := cli.conn.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for cli.conn.NextEvent().Kind != tlsquic.NoEvent {
+	}
+	err := cli.conn.Close()
+	var alert tlsquic.AlertError
+	if !errors.As(err, &alert) || alert != tlsquic.AlertError(alertCloseNotify) {
+		t.Errorf("conn.Close() = %v, want alertCloseNotify", err)
+	}
+}
+
+func TestQUICCanceledWaitingForTransportParams(t *testing.T) {
+	config := testQUICConfig()
+
+	cli := newTestQUICClient(t, config)
+	if err := cli.conn.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for cli.conn.NextEvent().Kind != tlsquic.TransportParametersRequired {
+	}
+	err := cli.conn.Close()
+	var alert tlsquic.AlertError
+	if !errors.As(err, &alert) || alert != tlsquic.AlertError(alertCloseNotify) {
+		t.Errorf("conn.Close() = %v, want alertCloseNotify", err)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// A Hook observes the operations a DB sends to its driver, for example
+// to trace statements or log slow queries. It is installed with
+// DB.SetHook and is called in the same way whatever driver or
+// driver.Connector the DB uses.
+//
+// A Hook's methods may be called concurrently from multiple goroutines.
+type Hook interface {
+	// Before is called before an operation is sent to the driver.
+	// The Duration, RowsAffected and Err fields of info are not yet set.
+	// The returned context is passed to the matching call of After;
+	// it is not passed to the driver. Before may return ctx itself.
+	//
+	// For HookCommit and HookRollback, ctx carries the values of the
+	// context passed to BeginTx, but has no deadline and is never
+	// canceled.
+	Before(ctx context.Context, info *HookInfo) context.Context
+
+	// After is called once the driver has completed the operation,
+	// with the same info passed to Before.
+	After(ctx context.Context, info *HookInfo)
+}
+
+// HookOp identifies the kind of operation described by a HookInfo.
+type HookOp int
+
+const (
+	HookExec     HookOp = iota + 1 // Exec or ExecContext
+	HookQuery                      // Query, QueryContext or QueryRow
+	HookPrepare                    // Prepare or PrepareContext
+	HookBegin                      // Begin or BeginTx
+	HookCommit                     // Tx.Commit
+	HookRollback                   // Tx.Rollback, or rollback after the context is canceled
+)
+
+var hookOpName = [...]string{
+	HookExec:     "exec",
+	HookQuery:    "query",
+	HookPrepare:  "prepare",
+	HookBegin:    "begin",
+	HookCommit:   "commit",
+	HookRollback: "rollback",
+}
+
+// String returns the name of the operation, such as "exec".
+func (op HookOp) String() string {
+	if op <= 0 || int(op) >= len(hookOpName) {
+		return "HookOp(" + strconv.Itoa(int(op)) + ")"
+	}
+	return hookOpName[op]
+}
+
+// HookInfo describes an operation observed by a Hook.
+type HookInfo struct {
+	Op HookOp
+
+	// Query is the query text. It is empty for HookBegin,
+	// HookCommit and HookRollback.
+	Query string
+
+	// Args are the arguments as passed to the Exec or Query method,
+	// before any conversion by the driver. The Hook must not modify them.
+	Args []interface{}
+
+	// Duration is the time the driver took to complete the operation.
+	// For HookQuery it does not include reading the rows.
+	Duration time.Duration
+
+	// RowsAffected is the number of rows affected by a successful
+	// HookExec operation, or -1 if it is unknown.
+	RowsAffected int64
+
+	// Err is the error returned by the operation, if any.
+	Err error
+}
+
+// hookHolder wraps a Hook so that a nil Hook can be stored in an atomic.Value.
+type hookHolder struct {
+	h Hook
+}
+
+// SetHook sets the Hook called around each operation the database
+// sends to its driver, including those made through a Conn, Tx or Stmt.
+// If h is nil, no hook is called.
+func (db *DB) SetHook(h Hook) {
+	db.hook.Store(hookHolder{h})
+}
+
+// hookCall records an operation in progress for the DB's Hook.
+// A nil *hookCall means no Hook is installed.
+type hookCall struct {
+	h     Hook
+	ctx   context.Context
+	info  HookInfo
+	start time.Time
+}
+
+// startHook calls the Before method of the DB's Hook, if any, for
+// an operation about to be sent to the driver. The caller must call
+// done on the result when the operation completes.
+func (db *DB) startHook(ctx context.Context, op HookOp, query string, args []interface{}) *hookCall {
+	hh, _ := db.hook.Load().(hookHolder)
+	if hh.h == nil {
+		return nil
+	}
+	c := &hookCall{
+		h:    hh.h,
+		info: HookInfo{Op: op, Query: query, Args: args, RowsAffected: -1},
+	}
+	c.ctx = hh.h.Before(ctx, &c.info)
+	c.start = time.Now()
+	return c
+}
+
+// done calls the Hook's After method with the outcome of the operation.
+// The Result res is consulted only for HookExec operations.
+func (c *hookCall) done(res Result, err error) {
+	if c == nil {
+		return
+	}
+	c.info.Duration = time.Since(c.start)
+	c.info.Err = err
+	if res != nil && err == nil {
+		if n, err := res.RowsAffected(); err == nil {
+			c.info.RowsAffected = n
+		}
+	}
+	c.h.After(c.ctx, &c.info)
+}
+
+// hookTxContext is the context of the HookCommit and HookRollback calls
+// of a transaction. The transaction's context is canceled before the
+// driver commits or rolls back, so hookTxContext keeps only its values.
+type hookTxContext struct {
+	context.Context
+}
+
+func (hookTxContext) Deadline() (deadline time.Time, ok bool) { return }
+func (hookTxContext) Done() <-chan struct{}                   { return nil }
+func (hookTxContext) Err() error                              { return nil }
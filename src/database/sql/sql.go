@@ -25,6 +25,7 @@ import (
 	"runtime"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -392,6 +393,46 @@ func (n NullTime) Value() (driver.Value, error) {
 	return n.Time, nil
 }
 
+// Null represents a value of type T that may be null.
+// Null implements the Scanner interface so it can be used
+// as a scan destination for any type that Scan supports:
+//
+//  var s Null[string]
+//  err := db.QueryRow("SELECT name FROM foo WHERE id=?", id).Scan(&s)
+//  ...
+//  if s.Valid {
+//     // use s.V
+//  } else {
+//     // NULL value
+//  }
+//
+type Null[T any] struct {
+	V     T
+	Valid bool // Valid is true if V is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (n *Null[T]) Scan(value interface{}) error {
+	if value == nil {
+		var zero T
+		n.V, n.Valid = zero, false
+		return nil
+	}
+	err := convertAssign(&n.V, value)
+	n.Valid = err == nil
+	return err
+}
+
+// Value implements the driver Valuer interface.
+// A valid value is converted to a driver.Value
+// by driver.DefaultParameterConverter.
+func (n Null[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(n.V)
+}
+
 // Scanner is an interface used by Scan.
 type Scanner interface {
 	// Scan assigns a value from a database driver.
@@ -464,6 +505,8 @@ type DB struct {
 	// connections in Stmt.css.
 	numClosed uint64
 
+	hook atomic.Value // of hookHolder; see SetHook
+
 	mu           sync.Mutex // protects following fields
 	freeConn     []*driverConn
 	connRequests map[uint64]chan connRequest
@@ -482,6 +525,8 @@ type DB struct {
 	maxOpen           int                    // <= 0 means unlimited
 	maxLifetime       time.Duration          // maximum amount of time a connection may be reused
 	maxIdleTime       time.Duration          // maximum amount of time a connection may be idle before being closed
+	maxWait           time.Duration          // maximum amount of time to wait for a connection from a full pool
+	trackStacks       bool                   // record the stack that took each connection; see SetConnStackTracking
 	cleanerCh         chan struct{}
 	waitCount         int64 // Total number of connections waited for.
 	maxIdleClosed     int64 // Total number of connections closed due to idle count.
@@ -511,6 +556,8 @@ type driverConn struct {
 	db        *DB
 	createdAt time.Time
 
+	numStmt int32 // atomic access only; len(openStmt), for DB.Conns
+
 	sync.Mutex  // guards following
 	ci          driver.Conn
 	needReset   bool // The connection session should be reset before use if true.
@@ -521,6 +568,9 @@ type driverConn struct {
 	// guarded by db.mu
 	inUse      bool
 	returnedAt time.Time // Time the connection was created or returned.
+	takenAt    time.Time // Time the connection was last taken from the pool.
+	label      string    // label of the context that took the connection; see WithConnLabel
+	takenStack string    // stack of the goroutine that took the connection, if db.trackStacks
 	onPut      []func()  // code (with db.mu held) run when conn is next returned
 	dbmuClosed bool      // same as closed, but guarded by db.mu, for removeClosedStmtLocked
 }
@@ -533,6 +583,17 @@ func (dc *driverConn) removeOpenStmt(ds *driverStmt) {
 	dc.Lock()
 	defer dc.Unlock()
 	delete(dc.openStmt, ds)
+	atomic.StoreInt32(&dc.numStmt, int32(len(dc.openStmt)))
+}
+
+// takenLocked records that dc was taken from the pool for use with ctx.
+// The db.mu lock must be held.
+func (dc *driverConn) takenLocked(ctx context.Context) {
+	dc.takenAt = nowFunc()
+	dc.label, _ = ctx.Value(connLabelKey{}).(string)
+	if dc.db.trackStacks {
+		dc.takenStack = stack()
+	}
 }
 
 func (dc *driverConn) expired(timeout time.Duration) bool {
@@ -594,6 +655,7 @@ func (dc *driverConn) prepareLocked(ctx context.Context, cg stmtConnGrabber, que
 		dc.openStmt = make(map[*driverStmt]bool)
 	}
 	dc.openStmt[ds] = true
+	atomic.StoreInt32(&dc.numStmt, int32(len(dc.openStmt)))
 	return ds, nil
 }
 
@@ -637,6 +699,7 @@ func (dc *driverConn) finalClose() error {
 			openStmt = append(openStmt, ds)
 		}
 		dc.openStmt = nil
+		atomic.StoreInt32(&dc.numStmt, 0)
 	})
 	for _, ds := range openStmt {
 		ds.Close()
@@ -1048,6 +1111,36 @@ func (db *DB) SetConnMaxIdleTime(d time.Duration) {
 	db.startCleanerLocked()
 }
 
+// SetConnMaxWait sets the maximum amount of time to wait for a connection
+// when all of the MaxOpenConns connections are in use.
+//
+// An operation that waits longer fails with a *ConnWaitTimeoutError,
+// even if its context has not expired. The wait does not include the
+// time taken to open a new connection.
+//
+// If d <= 0, operations wait until a connection is available or their
+// context is done. The default is 0.
+func (db *DB) SetConnMaxWait(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	db.mu.Lock()
+	db.maxWait = d
+	db.mu.Unlock()
+}
+
+// SetConnStackTracking sets whether the database records the stack of
+// the goroutine that takes each connection from the pool, as reported in
+// ConnInfo.Stack. Recording stacks makes each query slower; it is meant
+// for finding leaked Conn, Tx and Rows values.
+//
+// The default is false.
+func (db *DB) SetConnStackTracking(enabled bool) {
+	db.mu.Lock()
+	db.trackStacks = enabled
+	db.mu.Unlock()
+}
+
 // startCleanerLocked starts connectionCleaner if needed.
 func (db *DB) startCleanerLocked() {
 	if (db.maxLifetime > 0 || db.maxIdleTime > 0) && db.numOpen > 0 && db.cleanerCh == nil {
@@ -1169,6 +1262,76 @@ func (db *DB) Stats() DBStats {
 	return stats
 }
 
+// ConnInfo describes a connection open in a database's pool.
+type ConnInfo struct {
+	Age   time.Duration // The time since the connection was opened.
+	InUse bool          // Whether the connection is in use by a query, Rows, Conn or Tx.
+	Idle  time.Duration // The time since the connection was returned to the pool; zero if in use.
+	Held  time.Duration // The time since the connection was taken from the pool; zero if idle.
+	Label string        // The label of the context that took the connection, as set by WithConnLabel; empty if idle.
+	Stack string        // The stack of the goroutine that took the connection, if SetConnStackTracking is enabled; empty if idle.
+	Stmts int           // The number of prepared statements open on the connection.
+}
+
+// Conns returns information about each connection open in the pool,
+// oldest first.
+func (db *DB) Conns() []ConnInfo {
+	now := nowFunc()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var dcs []*driverConn
+	for x := range db.dep {
+		if dc, ok := x.(*driverConn); ok && !dc.dbmuClosed {
+			dcs = append(dcs, dc)
+		}
+	}
+	sort.Slice(dcs, func(i, j int) bool {
+		return dcs[i].createdAt.Before(dcs[j].createdAt)
+	})
+
+	conns := make([]ConnInfo, len(dcs))
+	for i, dc := range dcs {
+		ci := &conns[i]
+		ci.Age = now.Sub(dc.createdAt)
+		ci.InUse = dc.inUse
+		ci.Stmts = int(atomic.LoadInt32(&dc.numStmt))
+		if dc.inUse {
+			ci.Held = now.Sub(dc.takenAt)
+			ci.Label = dc.label
+			ci.Stack = dc.takenStack
+		} else {
+			ci.Idle = now.Sub(dc.returnedAt)
+		}
+	}
+	return conns
+}
+
+type connLabelKey struct{}
+
+// WithConnLabel returns a copy of ctx carrying label. A connection taken
+// from the pool for an operation using the returned context, including
+// the connection held by a Conn or Tx, reports label in ConnInfo.Label
+// until it is returned.
+func WithConnLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, connLabelKey{}, label)
+}
+
+// ConnWaitTimeoutError is returned when no connection becomes available
+// within the time set by DB.SetConnMaxWait.
+type ConnWaitTimeoutError struct {
+	Wait    time.Duration // The maximum wait that was exceeded.
+	MaxOpen int           // The maximum number of open connections, all of which were in use.
+}
+
+func (e *ConnWaitTimeoutError) Error() string {
+	return fmt.Sprintf("sql: no connection available after %v; all %d connections in use", e.Wait, e.MaxOpen)
+}
+
+// Timeout reports whether the error is a timeout. It always returns true.
+func (e *ConnWaitTimeoutError) Timeout() bool { return true }
+
 // Assumes db.mu is locked.
 // If there are connRequests and the connection limit hasn't been reached,
 // then tell the connectionOpener to open new connections.
@@ -1278,6 +1441,7 @@ func (db *DB) conn(ctx context.Context, strategy connReuseStrategy) (*driverConn
 		copy(db.freeConn, db.freeConn[1:])
 		db.freeConn = db.freeConn[:numFree-1]
 		conn.inUse = true
+		conn.takenLocked(ctx)
 		if conn.expired(lifetime) {
 			db.maxLifetimeClosed++
 			db.mu.Unlock()
@@ -1304,29 +1468,26 @@ func (db *DB) conn(ctx context.Context, strategy connReuseStrategy) (*driverConn
 		reqKey := db.nextRequestKeyLocked()
 		db.connRequests[reqKey] = req
 		db.waitCount++
+		maxWait, maxOpen := db.maxWait, db.maxOpen
 		db.mu.Unlock()
 
 		waitStart := nowFunc()
 
-		// Timeout the connection request with the context.
+		// Timeout the connection request with the context, or with
+		// the maximum wait if there is one.
+		var timeout <-chan time.Time
+		if maxWait > 0 {
+			t := time.NewTimer(maxWait)
+			defer t.Stop()
+			timeout = t.C
+		}
 		select {
 		case <-ctx.Done():
-			// Remove the connection request and ensure no value has been sent
-			// on it after removing.
-			db.mu.Lock()
-			delete(db.connRequests, reqKey)
-			db.mu.Unlock()
-
-			atomic.AddInt64(&db.waitDuration, int64(time.Since(waitStart)))
-
-			select {
-			default:
-			case ret, ok := <-req:
-				if ok && ret.conn != nil {
-					db.putConn(ret.conn, ret.err, false)
-				}
-			}
+			db.cancelConnRequest(reqKey, req, waitStart)
 			return nil, ctx.Err()
+		case <-timeout:
+			db.cancelConnRequest(reqKey, req, waitStart)
+			return nil, &ConnWaitTimeoutError{Wait: maxWait, MaxOpen: maxOpen}
 		case ret, ok := <-req:
 			atomic.AddInt64(&db.waitDuration, int64(time.Since(waitStart)))
 
@@ -1350,6 +1511,10 @@ func (db *DB) conn(ctx context.Context, strategy connReuseStrategy) (*driverConn
 				return nil, ret.err
 			}
 
+			db.mu.Lock()
+			ret.conn.takenLocked(ctx)
+			db.mu.Unlock()
+
 			// Reset the session if required.
 			if err := ret.conn.resetSession(ctx); err == driver.ErrBadConn {
 				ret.conn.Close()
@@ -1377,11 +1542,32 @@ func (db *DB) conn(ctx context.Context, strategy connReuseStrategy) (*driverConn
 		ci:         ci,
 		inUse:      true,
 	}
+	dc.takenLocked(ctx)
 	db.addDepLocked(dc, dc)
 	db.mu.Unlock()
 	return dc, nil
 }
 
+// cancelConnRequest removes the connection request made by conn at
+// waitStart, returning to the pool any connection sent on req.
+func (db *DB) cancelConnRequest(reqKey uint64, req chan connRequest, waitStart time.Time) {
+	// Remove the connection request and ensure no value has been sent
+	// on it after removing.
+	db.mu.Lock()
+	delete(db.connRequests, reqKey)
+	db.mu.Unlock()
+
+	atomic.AddInt64(&db.waitDuration, int64(time.Since(waitStart)))
+
+	select {
+	default:
+	case ret, ok := <-req:
+		if ok && ret.conn != nil {
+			db.putConn(ret.conn, ret.err, false)
+		}
+	}
+}
+
 // putConnHook is a hook for testing.
 var putConnHook func(*DB, *driverConn)
 
@@ -1435,6 +1621,7 @@ func (db *DB) putConn(dc *driverConn, err error, resetSession bool) {
 	}
 	dc.inUse = false
 	dc.returnedAt = nowFunc()
+	dc.label, dc.takenStack = "", ""
 
 	for _, fn := range dc.onPut {
 		fn()
@@ -1568,9 +1755,11 @@ func (db *DB) prepareDC(ctx context.Context, dc *driverConn, release func(error)
 	defer func() {
 		release(err)
 	}()
+	hc := db.startHook(ctx, HookPrepare, query, nil)
 	withLock(dc, func() {
 		ds, err = dc.prepareLocked(ctx, cg, query)
 	})
+	hc.done(nil, err)
 	if err != nil {
 		return nil, err
 	}
@@ -1630,6 +1819,11 @@ func (db *DB) execDC(ctx context.Context, dc *driverConn, release func(error), q
 	defer func() {
 		release(err)
 	}()
+	if hc := db.startHook(ctx, HookExec, query, args); hc != nil {
+		defer func() {
+			hc.done(res, err)
+		}()
+	}
 	execerCtx, ok := dc.ci.(driver.ExecerContext)
 	var execer driver.Execer
 	if !ok {
@@ -1704,7 +1898,12 @@ func (db *DB) query(ctx context.Context, query string, args []interface{}, strat
 // The connection gets released by the releaseConn function.
 // The ctx context is from a query method and the txctx context is from an
 // optional transaction context.
-func (db *DB) queryDC(ctx, txctx context.Context, dc *driverConn, releaseConn func(error), query string, args []interface{}) (*Rows, error) {
+func (db *DB) queryDC(ctx, txctx context.Context, dc *driverConn, releaseConn func(error), query string, args []interface{}) (rows *Rows, err error) {
+	if hc := db.startHook(ctx, HookQuery, query, args); hc != nil {
+		defer func() {
+			hc.done(nil, err)
+		}()
+	}
 	queryerCtx, ok := dc.ci.(driver.QueryerContext)
 	var queryer driver.Queryer
 	if !ok {
@@ -1739,7 +1938,6 @@ func (db *DB) queryDC(ctx, txctx context.Context, dc *driverConn, releaseConn fu
 	}
 
 	var si driver.Stmt
-	var err error
 	withLock(dc, func() {
 		si, err = ctxDriverPrepare(ctx, dc.ci, query)
 	})
@@ -1758,7 +1956,7 @@ func (db *DB) queryDC(ctx, txctx context.Context, dc *driverConn, releaseConn fu
 
 	// Note: ownership of ci passes to the *Rows, to be freed
 	// with releaseConn.
-	rows := &Rows{
+	rows = &Rows{
 		dc:          dc,
 		releaseConn: releaseConn,
 		rowsi:       rowsi,
@@ -1838,12 +2036,14 @@ func (db *DB) begin(ctx context.Context, opts *TxOptions, strategy connReuseStra
 func (db *DB) beginDC(ctx context.Context, dc *driverConn, release func(error), opts *TxOptions) (tx *Tx, err error) {
 	var txi driver.Tx
 	keepConnOnRollback := false
+	hc := db.startHook(ctx, HookBegin, "", nil)
 	withLock(dc, func() {
 		_, hasSessionResetter := dc.ci.(driver.SessionResetter)
 		_, hasConnectionValidator := dc.ci.(driver.Validator)
 		keepConnOnRollback = hasSessionResetter && hasConnectionValidator
 		txi, err = ctxDriverBegin(ctx, opts, dc.ci)
 	})
+	hc.done(nil, err)
 	if err != nil {
 		release(err)
 		return nil, err
@@ -2245,9 +2445,11 @@ func (tx *Tx) Commit() error {
 	tx.closemu.Unlock()
 
 	var err error
+	hc := tx.db.startHook(hookTxContext{tx.ctx}, HookCommit, "", nil)
 	withLock(tx.dc, func() {
 		err = tx.txi.Commit()
 	})
+	hc.done(nil, err)
 	if err != driver.ErrBadConn {
 		tx.closePrepared()
 	}
@@ -2277,9 +2479,11 @@ func (tx *Tx) rollback(discardConn bool) error {
 	tx.closemu.Unlock()
 
 	var err error
+	hc := tx.db.startHook(hookTxContext{tx.ctx}, HookRollback, "", nil)
 	withLock(tx.dc, func() {
 		err = tx.txi.Rollback()
 	})
+	hc.done(nil, err)
 	if err != driver.ErrBadConn {
 		tx.closePrepared()
 	}
@@ -2592,7 +2796,9 @@ func (s *Stmt) ExecContext(ctx context.Context, args ...interface{}) (Result, er
 			return nil, err
 		}
 
+		hc := s.db.startHook(ctx, HookExec, s.query, args)
 		res, err = resultFromStatement(ctx, dc.ci, ds, args...)
+		hc.done(res, err)
 		releaseConn(err)
 		if err != driver.ErrBadConn {
 			return res, err
@@ -2740,7 +2946,9 @@ func (s *Stmt) QueryContext(ctx context.Context, args ...interface{}) (*Rows, er
 			return nil, err
 		}
 
+		hc := s.db.startHook(ctx, HookQuery, s.query, args)
 		rowsi, err = rowsiFromStatement(ctx, dc.ci, ds, args...)
+		hc.done(nil, err)
 		if err == nil {
 			// Note: ownership of ci passes to the *Rows, to be freed
 			// with releaseConn.
@@ -2891,6 +3099,11 @@ type Rows struct {
 	// lastcols is only used in Scan, Next, and NextResultSet which are expected
 	// not to be called concurrently.
 	lastcols []driver.Value
+
+	// structCols maps the columns of the current result set to
+	// struct fields for ScanStruct. Like lastcols, it is only used
+	// by methods that are not called concurrently.
+	structCols *structColumns
 }
 
 // lasterrOrErrLocked returns either lasterr or the provided err.
@@ -3008,6 +3221,7 @@ func (rs *Rows) NextResultSet() bool {
 	}
 
 	rs.lastcols = nil
+	rs.structCols = nil
 	nextResultSet, ok := rs.rowsi.(driver.RowsNextResultSet)
 	if !ok {
 		doClose = true
@@ -3251,6 +3465,167 @@ func (rs *Rows) Scan(dest ...interface{}) error {
 	return nil
 }
 
+// ScanStruct copies the columns in the current row into the fields of
+// the struct pointed to by dest, as if by Scan.
+//
+// Each column is stored in the exported field whose name, or the name
+// given by its "db" struct tag, matches the column name, ignoring case.
+// A field with the tag `db:"-"` is ignored. The fields of an anonymous
+// struct field are treated as fields of the outer struct, unless it has
+// a name in its tag; between fields with the same name, the least nested
+// one is used. It is an error for a column to have no field, or to
+// have several fields at the same, least nested, depth.
+//
+// The columns are matched to fields using ColumnTypes once for each
+// result set and type of dest.
+func (rs *Rows) ScanStruct(dest interface{}) error {
+	dests, err := rs.structDest(dest)
+	if err != nil {
+		return err
+	}
+	return rs.Scan(dests...)
+}
+
+// structDest returns the pointers to the fields of the struct pointed
+// to by dest that the current columns are to be scanned into.
+func (rs *Rows) structDest(dest interface{}) ([]interface{}, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("sql: ScanStruct destination must be a non-nil pointer to a struct, not %T", dest)
+	}
+	v = v.Elem()
+	sc := rs.structCols
+	if sc == nil || sc.typ != v.Type() {
+		cols, err := rs.ColumnTypes()
+		if err != nil {
+			return nil, err
+		}
+		sc, err = newStructColumns(v.Type(), cols)
+		if err != nil {
+			return nil, err
+		}
+		rs.structCols = sc
+	}
+
+	dests := make([]interface{}, len(sc.index))
+	for i, index := range sc.index {
+		f := v
+		for _, x := range index {
+			if f.Kind() == reflect.Ptr {
+				if f.IsNil() {
+					f.Set(reflect.New(f.Type().Elem()))
+				}
+				f = f.Elem()
+			}
+			f = f.Field(x)
+		}
+		dests[i] = f.Addr().Interface()
+	}
+	return dests, nil
+}
+
+// structColumns maps the columns of a result set
+// to the fields of a struct type.
+type structColumns struct {
+	typ   reflect.Type
+	index [][]int // field index sequence for each column
+}
+
+// newStructColumns matches the columns cols to the fields of the struct type t.
+func newStructColumns(t reflect.Type, cols []*ColumnType) (*structColumns, error) {
+	type field struct {
+		index     []int
+		ambiguous bool
+	}
+	fields := make(map[string]*field) // by lower-case name
+
+	// Visit the fields breadth-first, so that less nested
+	// fields are found first.
+	// A struct type embedded twice at the same level contributes its
+	// fields twice, which makes them ambiguous, so types are only
+	// skipped when they embed themselves, to stop the recursion.
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+		path  []reflect.Type // embedding types, from t to typ
+	}
+	level := []embedded{{typ: t, path: []reflect.Type{t}}}
+	for len(level) > 0 {
+		var next []embedded
+		found := make(map[string]int) // names found at this level
+		for _, e := range level {
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				tag := sf.Tag.Get("db")
+				if tag == "-" {
+					continue
+				}
+				index := append(e.index[:len(e.index):len(e.index)], i)
+				ft := sf.Type
+				if sf.Anonymous && tag == "" {
+					if ft.Kind() == reflect.Ptr {
+						if !sf.IsExported() {
+							// A nil pointer to an unexported
+							// type could not be allocated.
+							continue
+						}
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						if !embedsType(e.path, ft) {
+							path := append(e.path[:len(e.path):len(e.path)], ft)
+							next = append(next, embedded{ft, index, path})
+						}
+						continue
+					}
+				}
+				if !sf.IsExported() {
+					continue
+				}
+				name := tag
+				if name == "" {
+					name = sf.Name
+				}
+				name = strings.ToLower(name)
+				if _, ok := fields[name]; ok && found[name] == 0 {
+					// Hidden by a less nested field.
+					continue
+				}
+				found[name]++
+				if found[name] > 1 {
+					fields[name].ambiguous = true
+					continue
+				}
+				fields[name] = &field{index: index}
+			}
+		}
+		level = next
+	}
+
+	sc := &structColumns{typ: t, index: make([][]int, len(cols))}
+	for i, col := range cols {
+		f := fields[strings.ToLower(col.Name())]
+		if f == nil {
+			return nil, fmt.Errorf("sql: no field in %v for column %q", t, col.Name())
+		}
+		if f.ambiguous {
+			return nil, fmt.Errorf("sql: ambiguous fields in %v for column %q", t, col.Name())
+		}
+		sc.index[i] = f.index
+	}
+	return sc, nil
+}
+
+// embedsType reports whether t is one of the types in path.
+func embedsType(path []reflect.Type, t reflect.Type) bool {
+	for _, p := range path {
+		if p == t {
+			return true
+		}
+	}
+	return false
+}
+
 // rowsCloseHook returns a function so tests may install the
 // hook through a test only mutex.
 var rowsCloseHook = func() func(*Rows, *error) { return nil }
@@ -3344,6 +3719,40 @@ func (r *Row) Scan(dest ...interface{}) error {
 	return r.rows.Close()
 }
 
+// ScanStruct copies the columns from the matched row into the fields
+// of the struct pointed to by dest, as described by Rows.ScanStruct.
+// If more than one row matches the query, ScanStruct uses the first
+// row and discards the rest. If no row matches the query, ScanStruct
+// returns ErrNoRows.
+func (r *Row) ScanStruct(dest interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return ErrNoRows
+	}
+	dests, err := r.rows.structDest(dest)
+	if err != nil {
+		return err
+	}
+	// As in Scan, the values must not refer to driver memory.
+	for _, dp := range dests {
+		if _, ok := dp.(*RawBytes); ok {
+			return errors.New("sql: RawBytes isn't allowed on Row.ScanStruct")
+		}
+	}
+	if err := r.rows.Scan(dests...); err != nil {
+		return err
+	}
+	// Make sure the query can be processed to completion with no errors.
+	return r.rows.Close()
+}
+
 // Err provides a way for wrapping packages to check for
 // query errors without calling Scan.
 // Err returns the error, if any, that was encountered while running the query.
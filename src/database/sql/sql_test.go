@@ -1882,6 +1882,341 @@ func TestNullTimeParam(t *testing.T) {
 	nullTestRun(t, spec)
 }
 
+func TestNullParam(t *testing.T) {
+	spec := nullTestSpec{"nullint32", "int32", [6]nullTestRow{
+		{Null[int32]{31, true}, 1, Null[int32]{31, true}},
+		{Null[int32]{-22, false}, 1, Null[int32]{0, false}},
+		{22, 1, Null[int32]{22, true}},
+		{Null[int32]{33, true}, 1, Null[int32]{33, true}},
+		{Null[int32]{222, false}, 1, Null[int32]{0, false}},
+		{0, Null[int32]{31, false}, nil},
+	}}
+	nullTestRun(t, spec)
+
+	spec = nullTestSpec{"nullstring", "string", [6]nullTestRow{
+		{Null[string]{"aqua", true}, "", Null[string]{"aqua", true}},
+		{Null[string]{"brown", false}, "", Null[string]{"", false}},
+		{"chartreuse", "", Null[string]{"chartreuse", true}},
+		{Null[string]{"darkred", true}, "", Null[string]{"darkred", true}},
+		{Null[string]{"eel", false}, "", Null[string]{"", false}},
+		{"foo", Null[string]{"black", false}, nil},
+	}}
+	nullTestRun(t, spec)
+}
+
+func TestNullScanError(t *testing.T) {
+	n := Null[int8]{V: 5, Valid: true}
+	if err := n.Scan(int64(300)); err == nil {
+		t.Fatal("expected error scanning 300 into Null[int8]")
+	}
+	if n.Valid {
+		t.Errorf("Null[int8] is valid after a failed Scan")
+	}
+}
+
+type scanStructName struct {
+	Name string
+}
+
+type scanStructPerson struct {
+	scanStructName
+	Years      int `db:"age"`
+	Photo      []byte
+	Ignored    string          `db:"-"`
+	Birthday   Null[time.Time] `db:"BDATE"`
+	unexported int
+}
+
+func TestRowsScanStruct(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	rows, err := db.Query("SELECT|people|age,name,photo,bdate|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []scanStructPerson
+	var cols *structColumns
+	for rows.Next() {
+		var p scanStructPerson
+		p.Ignored = "keep"
+		if err := rows.ScanStruct(&p); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, p)
+		if cols != nil && rows.structCols != cols {
+			t.Errorf("columns were matched to fields again for row %d", len(got))
+		}
+		cols = rows.structCols
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []scanStructPerson{
+		{scanStructName{"Alice"}, 1, []byte("APHOTO"), "keep", Null[time.Time]{}, 0},
+		{scanStructName{"Bob"}, 2, []byte("BPHOTO"), "keep", Null[time.Time]{}, 0},
+		{scanStructName{"Chris"}, 3, []byte("CPHOTO"), "keep", Null[time.Time]{chrisBirthday, true}, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanStruct got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestRowScanStruct(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	var p struct {
+		Name string
+		Age  *int64
+	}
+	if err := db.QueryRow("SELECT|people|age,name|name=?", "Bob").ScanStruct(&p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Bob" || p.Age == nil || *p.Age != 2 {
+		t.Errorf("ScanStruct got %+v", p)
+	}
+
+	err := db.QueryRow("SELECT|people|age|name=?", "Nobody").ScanStruct(&p)
+	if err != ErrNoRows {
+		t.Errorf("ScanStruct with no rows: err = %v, want ErrNoRows", err)
+	}
+
+	var raw struct{ Name RawBytes }
+	err = db.QueryRow("SELECT|people|name|name=?", "Bob").ScanStruct(&raw)
+	if err == nil || !strings.Contains(err.Error(), "RawBytes isn't allowed") {
+		t.Errorf("ScanStruct into RawBytes: err = %v", err)
+	}
+}
+
+type scanStructAge struct {
+	Age int
+}
+
+type scanStructOther struct {
+	Age int
+}
+
+type scanStructAgeA struct {
+	scanStructAge
+}
+
+type scanStructAgeB struct {
+	scanStructAge
+}
+
+type ScanStructNode struct {
+	*ScanStructNode
+	Age int
+}
+
+func TestScanStructErrors(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	tests := []struct {
+		query string
+		dest  interface{}
+		err   string
+	}{
+		{"SELECT|people|age|", scanStructAge{}, "sql: ScanStruct destination must be a non-nil pointer to a struct, not sql.scanStructAge"},
+		{"SELECT|people|age|", (*scanStructAge)(nil), "sql: ScanStruct destination must be a non-nil pointer to a struct, not *sql.scanStructAge"},
+		{"SELECT|people|age,dead|", &scanStructAge{}, `sql: no field in sql.scanStructAge for column "dead"`},
+		{"SELECT|people|name|", &scanStructPerson{}, ""},
+		{"SELECT|people|age|", &struct {
+			scanStructAge
+			scanStructOther
+		}{}, `sql: ambiguous fields in struct { sql.scanStructAge; sql.scanStructOther } for column "age"`},
+		{"SELECT|people|age|", &struct {
+			scanStructAge
+			scanStructOther
+			Age int8
+		}{}, ""},
+		{"SELECT|people|age|", &struct {
+			scanStructAgeA
+			scanStructAgeB
+		}{}, `sql: ambiguous fields in struct { sql.scanStructAgeA; sql.scanStructAgeB } for column "age"`},
+		{"SELECT|people|age|", &ScanStructNode{}, ""},
+		{"SELECT|people|name|", &struct {
+			Name int
+		}{}, `sql: Scan error on column index 0, name "name": converting driver.Value type []uint8 ("Alice") to a int: invalid syntax`},
+	}
+	for _, tt := range tests {
+		rows, err := db.Query(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rows.Next() {
+			t.Fatalf("%s: no rows", tt.query)
+		}
+		err = rows.ScanStruct(tt.dest)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%s: ScanStruct(%T) error = %v, want %q", tt.query, tt.dest, err, tt.err)
+		}
+		rows.Close()
+	}
+}
+
+type hookCtxKey struct{}
+
+// recordingHook is a Hook that records the operations it observes.
+type recordingHook struct {
+	mu  sync.Mutex
+	ops []string
+}
+
+func (h *recordingHook) Before(ctx context.Context, info *HookInfo) context.Context {
+	if info.Duration != 0 || info.RowsAffected != -1 || info.Err != nil {
+		panic(fmt.Sprintf("Before called with completed info %+v", info))
+	}
+	return context.WithValue(ctx, hookCtxKey{}, info.Op)
+}
+
+func (h *recordingHook) After(ctx context.Context, info *HookInfo) {
+	if op, _ := ctx.Value(hookCtxKey{}).(HookOp); op != info.Op {
+		panic(fmt.Sprintf("After called with context for %v, want %v", op, info.Op))
+	}
+	s := fmt.Sprintf("%v %q %v %d", info.Op, info.Query, info.Args, info.RowsAffected)
+	if info.Err != nil {
+		s += " err"
+	}
+	h.mu.Lock()
+	h.ops = append(h.ops, s)
+	h.mu.Unlock()
+}
+
+func TestHook(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	h := new(recordingHook)
+	db.SetHook(h)
+
+	exec(t, db, "INSERT|people|name=Dave,age=?", 4)
+	if _, err := db.Exec("INSERT|nosuchtable|name=Eve"); err == nil {
+		t.Fatal("expected error inserting into missing table")
+	}
+	var name string
+	if err := db.QueryRow("SELECT|people|name|age=?", 4).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, err := db.Prepare("SELECT|people|name|age=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stmt.QueryRow(1).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT|people|name=Frank,age=?", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	tx, err = db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	db.SetHook(nil)
+	exec(t, db, "INSERT|people|name=Gina,age=?", 6)
+
+	want := []string{
+		`exec "INSERT|people|name=Dave,age=?" [4] 1`,
+		`exec "INSERT|nosuchtable|name=Eve" [] -1 err`,
+		`query "SELECT|people|name|age=?" [4] -1`,
+		`prepare "SELECT|people|name|age=?" [] -1`,
+		`query "SELECT|people|name|age=?" [1] -1`,
+		`begin "" [] -1`,
+		`exec "INSERT|people|name=Frank,age=?" [5] 1`,
+		`commit "" [] -1`,
+		`begin "" [] -1`,
+		`rollback "" [] -1`,
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !reflect.DeepEqual(h.ops, want) {
+		t.Errorf("hook calls:\n%s\nwant:\n%s", strings.Join(h.ops, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// txContextHook is a Hook that records the context errors and values
+// seen by the HookCommit and HookRollback calls.
+type txContextHook struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (h *txContextHook) Before(ctx context.Context, info *HookInfo) context.Context {
+	if info.Op == HookCommit || info.Op == HookRollback {
+		v, _ := ctx.Value(hookCtxKey{}).(string)
+		h.mu.Lock()
+		h.seen = append(h.seen, fmt.Sprintf("%v %v %q", info.Op, ctx.Err(), v))
+		h.mu.Unlock()
+	}
+	return ctx
+}
+
+func (h *txContextHook) After(ctx context.Context, info *HookInfo) {}
+
+func TestHookTxContext(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	h := new(txContextHook)
+	db.SetHook(h)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), hookCtxKey{}, "tx"))
+	defer cancel()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`commit <nil> "tx"`,
+		`rollback <nil> "tx"`,
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !reflect.DeepEqual(h.seen, want) {
+		t.Errorf("hook calls:\n%s\nwant:\n%s", strings.Join(h.seen, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestHookOpString(t *testing.T) {
+	for op, want := range map[HookOp]string{
+		HookExec:     "exec",
+		HookRollback: "rollback",
+		0:            "HookOp(0)",
+		42:           "HookOp(42)",
+	} {
+		if got := op.String(); got != want {
+			t.Errorf("HookOp(%d).String() = %q, want %q", int(op), got, want)
+		}
+	}
+}
+
 func nullTestRun(t *testing.T, spec nullTestSpec) {
 	db := newTestDB(t, "")
 	defer closeDB(t, db)
@@ -2328,6 +2663,84 @@ func TestStats(t *testing.T) {
 	}
 }
 
+func TestConnMaxWait(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxWait(10 * time.Millisecond)
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.ExecContext(context.Background(), "INSERT|people|name=Dave,age=?", 4)
+	var werr *ConnWaitTimeoutError
+	if !errors.As(err, &werr) {
+		t.Fatalf("Exec with all connections in use: got error %v, want *ConnWaitTimeoutError", err)
+	}
+	if werr.Wait != 10*time.Millisecond || werr.MaxOpen != 1 {
+		t.Errorf("got %+v, want Wait 10ms and MaxOpen 1", werr)
+	}
+	db.mu.Lock()
+	pending := len(db.connRequests)
+	db.mu.Unlock()
+	if pending != 0 {
+		t.Errorf("%d connection requests pending after timeout; want 0", pending)
+	}
+	if got := db.Stats().WaitCount; got != 1 {
+		t.Errorf("WaitCount = %d; want 1", got)
+	}
+
+	conn.Close()
+	exec(t, db, "INSERT|people|name=Dave,age=?", 4)
+}
+
+func TestConns(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetConnStackTracking(true)
+
+	conn, err := db.Conn(WithConnLabel(context.Background(), "leaky"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := db.Prepare("SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	conns := db.Conns()
+	if len(conns) != 2 {
+		t.Fatalf("got %d connections; want 2", len(conns))
+	}
+	for _, ci := range conns {
+		if ci.Age < 0 || ci.Idle < 0 || ci.Held < 0 {
+			t.Errorf("negative duration in %+v", ci)
+		}
+		if ci.InUse {
+			if ci.Idle != 0 || ci.Label != "leaky" || !strings.Contains(ci.Stack, "TestConns") || ci.Stmts != 0 {
+				t.Errorf("in-use connection: got %+v", ci)
+			}
+		} else {
+			if ci.Held != 0 || ci.Label != "" || ci.Stack != "" || ci.Stmts != 1 {
+				t.Errorf("idle connection: got %+v", ci)
+			}
+		}
+	}
+	if !conns[0].InUse {
+		t.Errorf("first connection is not the oldest")
+	}
+
+	conn.Close()
+	for _, ci := range db.Conns() {
+		if ci.InUse || ci.Label != "" || ci.Stack != "" {
+			t.Errorf("after Conn.Close: got %+v", ci)
+		}
+	}
+}
+
 func TestConnMaxLifetime(t *testing.T) {
 	t0 := time.Unix(1000000, 0)
 	offset := time.Duration(0)
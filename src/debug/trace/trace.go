@@ -0,0 +1,381 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package trace implements reading of the execution traces written
+// by package runtime/trace, including its FlightRecorder, and by
+// "go test -trace".
+//
+// A Reader returns the events of a trace one at a time, in order,
+// without holding them in memory, so that it can read traces of
+// long-running programs. ReadAll reads all the events at once.
+//
+// Only traces written by Go 1.7 or later can be read.
+package trace
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"internal/trace"
+)
+
+// ErrTimeOrder is returned when the time stamps of the events in a trace
+// are inconsistent with their order. This happens on machines whose CPUs
+// have unsynchronized clocks.
+var ErrTimeOrder = trace.ErrTimeOrder
+
+// An EventKind is the kind of an event.
+type EventKind int
+
+const (
+	_ EventKind = iota
+
+	// Ps.
+	ProcStart  // a P started running on a thread; Value is the thread ID
+	ProcStop   // a P stopped
+	Gomaxprocs // GOMAXPROCS changed; Value is the new value
+
+	// Garbage collection.
+	GCStart           // a GC cycle started
+	GCDone            // a GC cycle finished
+	GCSTWStart        // the world was stopped; Name is "mark termination" or "sweep termination"
+	GCSTWDone         // the world was restarted
+	GCSweepStart      // G started sweeping
+	GCSweepDone       // G finished sweeping; Value is the number of bytes swept
+	GCMarkAssistStart // G started a GC mark assist
+	GCMarkAssistDone  // G finished a GC mark assist
+	HeapAlloc         // the live heap size changed; Value is the new size in bytes
+	HeapGoal          // the heap goal changed; Value is the new goal in bytes
+
+	// Goroutines.
+	GoCreate    // G created goroutine Target, which will start running at StartStack
+	GoStart     // G started running; Name is its label, if any
+	GoEnd       // G exited
+	GoStop      // G stopped forever, as in select{}
+	GoSched     // G called runtime.Gosched
+	GoPreempt   // G was preempted
+	GoBlock     // G blocked; Reason says why
+	GoUnblock   // G unblocked goroutine Target
+	GoWaiting   // G was blocked when tracing started
+	GoInSyscall // G was in a syscall when tracing started
+
+	// System calls.
+	Syscall      // G entered a syscall
+	SyscallBlock // G blocked in a syscall and released its P
+	SyscallExit  // G returned from a blocking syscall
+
+	// User annotations.
+	TaskCreate  // G created Task, a child of ParentTask, with the given Name
+	TaskEnd     // G ended Task
+	RegionStart // G started the region Name of Task
+	RegionEnd   // G ended the region Name of Task
+	Log         // G logged Message with category Name in Task
+)
+
+var kindNames = [...]string{
+	ProcStart:         "ProcStart",
+	ProcStop:          "ProcStop",
+	Gomaxprocs:        "Gomaxprocs",
+	GCStart:           "GCStart",
+	GCDone:            "GCDone",
+	GCSTWStart:        "GCSTWStart",
+	GCSTWDone:         "GCSTWDone",
+	GCSweepStart:      "GCSweepStart",
+	GCSweepDone:       "GCSweepDone",
+	GCMarkAssistStart: "GCMarkAssistStart",
+	GCMarkAssistDone:  "GCMarkAssistDone",
+	HeapAlloc:         "HeapAlloc",
+	HeapGoal:          "HeapGoal",
+	GoCreate:          "GoCreate",
+	GoStart:           "GoStart",
+	GoEnd:             "GoEnd",
+	GoStop:            "GoStop",
+	GoSched:           "GoSched",
+	GoPreempt:         "GoPreempt",
+	GoBlock:           "GoBlock",
+	GoUnblock:         "GoUnblock",
+	GoWaiting:         "GoWaiting",
+	GoInSyscall:       "GoInSyscall",
+	Syscall:           "Syscall",
+	SyscallBlock:      "SyscallBlock",
+	SyscallExit:       "SyscallExit",
+	TaskCreate:        "TaskCreate",
+	TaskEnd:           "TaskEnd",
+	RegionStart:       "RegionStart",
+	RegionEnd:         "RegionEnd",
+	Log:               "Log",
+}
+
+func (k EventKind) String() string {
+	if k > 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "EventKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// A BlockReason is the reason a goroutine blocked.
+type BlockReason int
+
+const (
+	BlockOther  BlockReason = iota // blocked for another reason
+	BlockSleep                     // called time.Sleep
+	BlockSend                      // blocked sending on a channel
+	BlockRecv                      // blocked receiving from a channel
+	BlockSelect                    // blocked in a select statement
+	BlockSync                      // blocked on a sync.Mutex or sync.RWMutex
+	BlockCond                      // blocked on a sync.Cond
+	BlockNet                       // blocked on the network
+	BlockGC                        // blocked on a GC mark assist
+)
+
+var reasonNames = [...]string{
+	BlockOther:  "other",
+	BlockSleep:  "sleep",
+	BlockSend:   "chan send",
+	BlockRecv:   "chan receive",
+	BlockSelect: "select",
+	BlockSync:   "sync",
+	BlockCond:   "sync.Cond",
+	BlockNet:    "network",
+	BlockGC:     "GC assist",
+}
+
+func (r BlockReason) String() string {
+	if r >= 0 && int(r) < len(reasonNames) {
+		return reasonNames[r]
+	}
+	return "BlockReason(" + strconv.Itoa(int(r)) + ")"
+}
+
+// A Frame is a frame of a stack trace.
+type Frame struct {
+	PC   uint64
+	Func string
+	File string
+	Line int
+}
+
+// An Event is an event in a trace.
+// The fields other than Kind, Time, P, G and Stack
+// are only set for the kinds of events documented to use them.
+type Event struct {
+	Kind EventKind
+	Time time.Duration // time since the first event of the trace
+	P    int           // P on which the event happened, or -1 if none
+	G    uint64        // goroutine on which the event happened, or 0 if none
+
+	// Stack is the stack of G at the event, innermost frame first.
+	// It is shared by all the events with the same stack and must
+	// not be modified.
+	Stack []Frame
+
+	Target     uint64      // goroutine created or unblocked
+	StartStack []Frame     // stack at which the created goroutine will start
+	Reason     BlockReason // reason G blocked
+	Task       uint64      // ID of the user task
+	ParentTask uint64      // ID of the parent of the created task, or 0
+	Name       string      // name of the event, see EventKind
+	Message    string      // message logged
+	Value      uint64      // value of the event, see EventKind
+}
+
+// A Reader reads the events of a trace.
+type Reader struct {
+	r      *trace.StreamReader
+	stacks map[uint64][]Frame
+}
+
+// NewReader returns a Reader reading the trace in r, which is size
+// bytes long. NewReader reads the entire trace once to index it, and
+// keeps only the index and the stack traces in memory.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	sr, err := trace.NewStreamReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{r: sr, stacks: make(map[uint64][]Frame)}, nil
+}
+
+// Next returns the next event in the trace. The events are returned
+// in order of Time, except that the Time of a SyscallExit event is the
+// time the syscall returned, which may precede the time of the events
+// returned before it. At the end of the trace, Next returns nil, io.EOF.
+func (r *Reader) Next() (*Event, error) {
+	for {
+		ev, err := r.r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if e := r.event(ev); e != nil {
+			return e, nil
+		}
+	}
+}
+
+// ReadAll reads the trace from r and returns all its events,
+// in order of Time.
+func ReadAll(r io.Reader) ([]*Event, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var events []*Event
+	for {
+		ev, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time < events[j].Time
+	})
+	return events, nil
+}
+
+// event converts ev to an Event,
+// or returns nil if the event is not reported.
+func (r *Reader) event(ev *trace.Event) *Event {
+	e := &Event{
+		Time:  time.Duration(ev.Ts),
+		P:     ev.P,
+		G:     ev.G,
+		Stack: r.stack(ev.StkID),
+	}
+	if e.P < 0 || e.P >= trace.FakeP {
+		e.P = -1
+	}
+	switch ev.Type {
+	case trace.EvProcStart:
+		e.Kind = ProcStart
+		e.Value = ev.Args[0]
+	case trace.EvProcStop:
+		e.Kind = ProcStop
+	case trace.EvGomaxprocs:
+		e.Kind = Gomaxprocs
+		e.Value = ev.Args[0]
+	case trace.EvGCStart:
+		e.Kind = GCStart
+	case trace.EvGCDone:
+		e.Kind = GCDone
+	case trace.EvGCSTWStart:
+		e.Kind = GCSTWStart
+		e.Name = ev.SArgs[0]
+	case trace.EvGCSTWDone:
+		e.Kind = GCSTWDone
+	case trace.EvGCSweepStart:
+		e.Kind = GCSweepStart
+	case trace.EvGCSweepDone:
+		e.Kind = GCSweepDone
+		e.Value = ev.Args[0]
+	case trace.EvGCMarkAssistStart:
+		e.Kind = GCMarkAssistStart
+	case trace.EvGCMarkAssistDone:
+		e.Kind = GCMarkAssistDone
+	case trace.EvHeapAlloc:
+		e.Kind = HeapAlloc
+		e.Value = ev.Args[0]
+	case trace.EvHeapGoal:
+		e.Kind = HeapGoal
+		e.Value = ev.Args[0]
+	case trace.EvGoCreate:
+		e.Kind = GoCreate
+		e.Target = ev.Args[0]
+		e.StartStack = r.stack(ev.Args[1])
+	case trace.EvGoStart:
+		e.Kind = GoStart
+	case trace.EvGoStartLabel:
+		e.Kind = GoStart
+		e.Name = ev.SArgs[0]
+	case trace.EvGoEnd:
+		e.Kind = GoEnd
+	case trace.EvGoStop:
+		e.Kind = GoStop
+	case trace.EvGoSched:
+		e.Kind = GoSched
+	case trace.EvGoPreempt:
+		e.Kind = GoPreempt
+	case trace.EvGoSleep, trace.EvGoBlock, trace.EvGoBlockSend, trace.EvGoBlockRecv,
+		trace.EvGoBlockSelect, trace.EvGoBlockSync, trace.EvGoBlockCond,
+		trace.EvGoBlockNet, trace.EvGoBlockGC:
+		e.Kind = GoBlock
+		e.Reason = blockReasons[ev.Type]
+	case trace.EvGoUnblock:
+		e.Kind = GoUnblock
+		e.Target = ev.Args[0]
+	case trace.EvGoWaiting:
+		e.Kind = GoWaiting
+	case trace.EvGoInSyscall:
+		e.Kind = GoInSyscall
+	case trace.EvGoSysCall:
+		e.Kind = Syscall
+	case trace.EvGoSysBlock:
+		e.Kind = SyscallBlock
+	case trace.EvGoSysExit:
+		e.Kind = SyscallExit
+	case trace.EvUserTaskCreate:
+		e.Kind = TaskCreate
+		e.Task = ev.Args[0]
+		e.ParentTask = ev.Args[1]
+		e.Name = ev.SArgs[0]
+	case trace.EvUserTaskEnd:
+		e.Kind = TaskEnd
+		e.Task = ev.Args[0]
+	case trace.EvUserRegion:
+		e.Kind = RegionStart
+		if ev.Args[1] != 0 {
+			e.Kind = RegionEnd
+		}
+		e.Task = ev.Args[0]
+		e.Name = ev.SArgs[0]
+	case trace.EvUserLog:
+		e.Kind = Log
+		e.Task = ev.Args[0]
+		e.Name = ev.SArgs[0]
+		e.Message = ev.SArgs[1]
+	default:
+		// EvFutileWakeup only matters to tools that
+		// remove futile wakeups, which a stream cannot do.
+		return nil
+	}
+	return e
+}
+
+var blockReasons = map[byte]BlockReason{
+	trace.EvGoSleep:       BlockSleep,
+	trace.EvGoBlock:       BlockOther,
+	trace.EvGoBlockSend:   BlockSend,
+	trace.EvGoBlockRecv:   BlockRecv,
+	trace.EvGoBlockSelect: BlockSelect,
+	trace.EvGoBlockSync:   BlockSync,
+	trace.EvGoBlockCond:   BlockCond,
+	trace.EvGoBlockNet:    BlockNet,
+	trace.EvGoBlockGC:     BlockGC,
+}
+
+// stack returns the stack with the given ID.
+func (r *Reader) stack(id uint64) []Frame {
+	if id == 0 {
+		return nil
+	}
+	if stk, ok := r.stacks[id]; ok {
+		return stk
+	}
+	var stk []Frame
+	for _, f := range r.r.Stacks()[id] {
+		stk = append(stk, Frame{PC: f.PC, Func: f.Fn, File: f.File, Line: f.Line})
+	}
+	r.stacks[id] = stk
+	return stk
+}
@@ -0,0 +1,218 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	. "debug/trace"
+	"io"
+	"runtime"
+	rtrace "runtime/trace"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// record returns a trace of a goroutine that creates
+// a task, a region and a log, and blocks on a channel.
+func record(t *testing.T) []byte {
+	if rtrace.IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	var buf bytes.Buffer
+	if err := rtrace.Start(&buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	ctx, task := rtrace.NewTask(context.Background(), "task0")
+	rtrace.WithRegion(ctx, "region0", func() {
+		rtrace.Log(ctx, "key0", "value0")
+	})
+	c := make(chan int)
+	go func() {
+		c <- 1
+	}()
+	<-c
+	runtime.GC()
+	task.End()
+	rtrace.Stop()
+	return buf.Bytes()
+}
+
+func TestReader(t *testing.T) {
+	data := record(t)
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []*Event
+	for {
+		ev, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err == ErrTimeOrder {
+			t.Skipf("skipping trace: %v", err)
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		events = append(events, ev)
+	}
+	checkEvents(t, events)
+
+	all, err := ReadAll(bytes.NewReader(data))
+	if err == ErrTimeOrder {
+		t.Skipf("skipping trace: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(all) != len(events) {
+		t.Errorf("ReadAll returned %d events, Next returned %d", len(all), len(events))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Time < all[i-1].Time {
+			t.Fatalf("ReadAll returned %v before %v", all[i-1], all[i])
+		}
+	}
+	checkEvents(t, all)
+}
+
+func checkEvents(t *testing.T, events []*Event) {
+	t.Helper()
+	var task *Event
+	seen := make(map[EventKind]bool)
+	for _, ev := range events {
+		seen[ev.Kind] = true
+		switch ev.Kind {
+		case TaskCreate:
+			if ev.Name != "task0" {
+				continue
+			}
+			task = ev
+			if !inFunc(ev.Stack, "record") {
+				t.Errorf("TaskCreate stack %v does not contain record", ev.Stack)
+			}
+		case RegionStart, RegionEnd:
+			if ev.Name != "region0" {
+				continue
+			}
+			if task == nil || ev.Task != task.Task || ev.G != task.G {
+				t.Errorf("%v of region0 is not in task0", ev.Kind)
+			}
+		case Log:
+			if task == nil || ev.Task != task.Task {
+				t.Errorf("log is not in task0")
+			}
+			if ev.Name != "key0" || ev.Message != "value0" {
+				t.Errorf("got log %q=%q, want key0=value0", ev.Name, ev.Message)
+			}
+			if !inFunc(ev.Stack, "record") {
+				t.Errorf("Log stack %v does not contain record", ev.Stack)
+			}
+		case GoCreate:
+			if ev.Target == 0 || len(ev.StartStack) == 0 {
+				t.Errorf("GoCreate of goroutine %d has start stack %v", ev.Target, ev.StartStack)
+			}
+		case GoBlock:
+			if ev.G == 0 {
+				t.Errorf("GoBlock with no goroutine")
+			}
+		case GCSTWStart:
+			if !strings.HasSuffix(ev.Name, "termination") {
+				t.Errorf("GCSTWStart has name %q", ev.Name)
+			}
+		}
+	}
+	if task == nil {
+		t.Fatalf("no TaskCreate event for task0")
+	}
+	for _, k := range []EventKind{GoCreate, GoStart, GoBlock, GoUnblock, GCStart, GCDone, RegionStart, RegionEnd, Log, TaskEnd} {
+		if !seen[k] {
+			t.Errorf("no %v event", k)
+		}
+	}
+}
+
+func inFunc(stk []Frame, name string) bool {
+	for _, f := range stk {
+		if strings.HasSuffix(f.Func, "."+name) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReaderCorrupt(t *testing.T) {
+	data := record(t)
+	if _, err := ReadAll(bytes.NewReader(data[:len(data)/2])); err == nil {
+		t.Errorf("ReadAll of truncated trace succeeded")
+	}
+	data[0] = 'x'
+	if _, err := NewReader(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Errorf("NewReader of corrupt header succeeded")
+	}
+}
+
+func TestEventKindString(t *testing.T) {
+	if got := GoBlock.String(); got != "GoBlock" {
+		t.Errorf("GoBlock.String() = %q", got)
+	}
+	if got := EventKind(1000).String(); got != "EventKind(1000)" {
+		t.Errorf("EventKind(1000).String() = %q", got)
+	}
+	if got := BlockRecv.String(); got != "chan receive" {
+		t.Errorf("BlockRecv.String() = %q", got)
+	}
+}
+
+func TestReaderFlightRecorder(t *testing.T) {
+	if rtrace.IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	fr := rtrace.NewFlightRecorder()
+	fr.SetPeriod(20 * time.Millisecond)
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		rtrace.Log(ctx, "flight", strconv.Itoa(i))
+		time.Sleep(5 * time.Millisecond)
+	}
+	var buf bytes.Buffer
+	_, err := fr.WriteTo(&buf)
+	fr.Stop()
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+
+	events, err := ReadAll(bytes.NewReader(buf.Bytes()))
+	if err == ErrTimeOrder {
+		t.Skipf("skipping trace: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	last := -1
+	for _, ev := range events {
+		if ev.Kind != Log || ev.Name != "flight" {
+			continue
+		}
+		i, _ := strconv.Atoi(ev.Message)
+		if i <= last {
+			t.Errorf("log %d after log %d", i, last)
+		}
+		last = i
+		if !inFunc(ev.Stack, "TestReaderFlightRecorder") {
+			t.Errorf("Log stack %v does not contain the test", ev.Stack)
+		}
+	}
+	if last != 9 {
+		t.Errorf("last log is %d, want 9", last)
+	}
+}
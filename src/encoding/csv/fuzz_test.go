@@ -0,0 +1,68 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package csv
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func FuzzRoundtrip(f *testing.F) {
+	f.Add([]byte("a,b,c\n1,2,3\n"))
+	f.Add([]byte("\"quoted, field\",\"with \"\"escapes\"\"\"\n"))
+	f.Add([]byte("# comment\nx;y\tz\n"))
+	f.Fuzz(func(t *testing.T, in []byte) {
+		buf := new(bytes.Buffer)
+
+		for _, tt := range []Reader{
+			{},
+			{Comma: ';'},
+			{Comma: '\t'},
+			{LazyQuotes: true},
+			{TrimLeadingSpace: true},
+			{Comment: '#'},
+			{Comment: ';'},
+		} {
+			r := NewReader(bytes.NewReader(in))
+			r.Comma = tt.Comma
+			r.Comment = tt.Comment
+			r.LazyQuotes = tt.LazyQuotes
+			r.TrimLeadingSpace = tt.TrimLeadingSpace
+
+			records, err := r.ReadAll()
+			if err != nil {
+				continue
+			}
+
+			buf.Reset()
+			w := NewWriter(buf)
+			w.Comma = tt.Comma
+			if err := w.WriteAll(records); err != nil {
+				t.Logf("writer  = %#v\n", w)
+				t.Logf("records = %v\n", records)
+				t.Fatal(err)
+			}
+
+			r = NewReader(buf)
+			r.Comma = tt.Comma
+			r.Comment = tt.Comment
+			r.LazyQuotes = tt.LazyQuotes
+			r.TrimLeadingSpace = tt.TrimLeadingSpace
+			result, err := r.ReadAll()
+			if err != nil {
+				t.Logf("reader  = %#v\n", r)
+				t.Logf("records = %v\n", records)
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(records, result) {
+				t.Log("records = \n", records)
+				t.Log("result  = \n", result)
+				t.Fatal("not equal")
+			}
+		}
+	})
+}
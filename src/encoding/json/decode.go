@@ -42,9 +42,12 @@ import (
 //
 // To unmarshal JSON into a struct, Unmarshal matches incoming object
 // keys to the keys used by Marshal (either the struct field name or its tag),
-// preferring an exact match but also accepting a case-insensitive match. By
-// default, object keys which don't have a corresponding struct field are
-// ignored (see Decoder.DisallowUnknownFields for an alternative).
+// preferring an exact match but also accepting a case-insensitive match
+// (see Decoder.CaseSensitive for an alternative). By default, object keys
+// which don't have a corresponding struct field are ignored (see
+// Decoder.DisallowUnknownFields for an alternative), unless the struct
+// has a field with the "inline" option of map type, in which case they
+// are stored in that map.
 //
 // To unmarshal JSON into an interface value,
 // Unmarshal stores one of these in the interface value:
@@ -216,6 +219,8 @@ type decodeState struct {
 	savedError            error
 	useNumber             bool
 	disallowUnknownFields bool
+	caseSensitive         bool
+	rejectDuplicateNames  bool
 }
 
 // readIndex returns the position of the last byte read.
@@ -660,6 +665,7 @@ func (d *decodeState) object(v reflect.Value) error {
 	}
 
 	var mapElem reflect.Value
+	var seen map[string]bool // keys already read, if rejecting duplicates
 	var origErrorContext errorContext
 	if d.errorContext != nil {
 		origErrorContext = *d.errorContext
@@ -684,10 +690,21 @@ func (d *decodeState) object(v reflect.Value) error {
 		if !ok {
 			panic(phasePanicMsg)
 		}
+		if d.rejectDuplicateNames {
+			if seen == nil {
+				seen = make(map[string]bool)
+			}
+			if seen[string(key)] {
+				d.saveError(fmt.Errorf("json: duplicate key %q in object", key))
+			}
+			seen[string(key)] = true
+		}
 
 		// Figure out field corresponding to key.
 		var subv reflect.Value
-		destring := false // whether the value is wrapped in a string to be decoded first
+		var inlineMap reflect.Value // map capturing an unknown key
+		destring := false           // whether the value is wrapped in a string to be decoded first
+		format := ""                // format directive of the field
 
 		if v.Kind() == reflect.Map {
 			elemType := t.Elem()
@@ -702,7 +719,7 @@ func (d *decodeState) object(v reflect.Value) error {
 			if i, ok := fields.nameIndex[string(key)]; ok {
 				// Found an exact name match.
 				f = &fields.list[i]
-			} else {
+			} else if !d.caseSensitive {
 				// Fall back to the expensive case-insensitive
 				// linear search.
 				for i := range fields.list {
@@ -714,35 +731,25 @@ func (d *decodeState) object(v reflect.Value) error {
 				}
 			}
 			if f != nil {
-				subv = v
-				destring = f.quoted
-				for _, i := range f.index {
-					if subv.Kind() == reflect.Ptr {
-						if subv.IsNil() {
-							// If a struct embeds a pointer to an unexported type,
-							// it is not possible to set a newly allocated value
-							// since the field is unexported.
-							//
-							// See https://golang.org/issue/21357
-							if !subv.CanSet() {
-								d.saveError(fmt.Errorf("json: cannot set embedded pointer to unexported struct: %v", subv.Type().Elem()))
-								// Invalidate subv to ensure d.value(subv) skips over
-								// the JSON value without assigning it to subv.
-								subv = reflect.Value{}
-								destring = false
-								break
-							}
-							subv.Set(reflect.New(subv.Type().Elem()))
-						}
-						subv = subv.Elem()
-					}
-					subv = subv.Field(i)
+				subv = d.fieldByIndex(v, f.index)
+				if subv.IsValid() {
+					destring = f.quoted
+					format = f.format
 				}
 				if d.errorContext == nil {
 					d.errorContext = new(errorContext)
 				}
 				d.errorContext.FieldStack = append(d.errorContext.FieldStack, f.name)
 				d.errorContext.Struct = t
+			} else if fields.inline != nil {
+				// Capture the unknown key in the inline map.
+				inlineMap = d.fieldByIndex(v, fields.inline.index)
+				if inlineMap.IsValid() {
+					if inlineMap.IsNil() {
+						inlineMap.Set(reflect.MakeMap(inlineMap.Type()))
+					}
+					subv = reflect.New(inlineMap.Type().Elem()).Elem()
+				}
 			} else if d.disallowUnknownFields {
 				d.saveError(fmt.Errorf("json: unknown field %q", key))
 			}
@@ -770,12 +777,20 @@ func (d *decodeState) object(v reflect.Value) error {
 			default:
 				d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal unquoted value into %v", subv.Type()))
 			}
+		} else if format != "" {
+			if err := d.formatValue(subv, format); err != nil {
+				return err
+			}
 		} else {
 			if err := d.value(subv); err != nil {
 				return err
 			}
 		}
 
+		if inlineMap.IsValid() {
+			inlineMap.SetMapIndex(reflect.ValueOf(string(key)).Convert(inlineMap.Type().Key()), subv)
+		}
+
 		// Write value back to map;
 		// if using struct, subv points into struct already.
 		if v.Kind() == reflect.Map {
@@ -838,6 +853,33 @@ func (d *decodeState) object(v reflect.Value) error {
 	return nil
 }
 
+// fieldByIndex returns the field of the struct v with the given index
+// sequence, allocating nil embedded pointers along the way. It returns
+// the zero Value if it cannot allocate one.
+func (d *decodeState) fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				// If a struct embeds a pointer to an unexported type,
+				// it is not possible to set a newly allocated value
+				// since the field is unexported.
+				//
+				// See https://golang.org/issue/21357
+				if !v.CanSet() {
+					d.saveError(fmt.Errorf("json: cannot set embedded pointer to unexported struct: %v", v.Type().Elem()))
+					// Return an invalid Value to ensure d.value skips over
+					// the JSON value without assigning it.
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
 // convertNumber converts the number literal s to a float64 or a Number
 // depending on the setting of d.useNumber.
 func (d *decodeState) convertNumber(s string) (interface{}, error) {
@@ -1101,6 +1143,9 @@ func (d *decodeState) objectInterface() map[string]interface{} {
 		if !ok {
 			panic(phasePanicMsg)
 		}
+		if _, dup := m[key]; dup && d.rejectDuplicateNames {
+			d.saveError(fmt.Errorf("json: duplicate key %q in object", key))
+		}
 
 		// Read : before value.
 		if d.opcode == scanSkipSpace {
@@ -30,6 +30,24 @@ type U struct {
 	Alphabet string `json:"alpha"`
 }
 
+type Formats struct {
+	Units time.Duration `json:",format:units"`
+	Sec   time.Duration `json:",format:sec"`
+	Nano  time.Duration `json:",format:nano"`
+	Hex   []byte        `json:",format:hex"`
+	URL   []byte        `json:",format:base64url"`
+	B32   []byte        `json:",format:base32"`
+	B32H  []byte        `json:",format:base32hex"`
+	Array []byte        `json:",format:array"`
+}
+
+type Inline struct {
+	X    int
+	Pt   Point          `json:",inline"`
+	Next *T             `json:",inline"`
+	Rest map[string]int `json:",inline"`
+}
+
 type V struct {
 	F1 interface{}
 	F2 int32
@@ -395,6 +413,8 @@ type unmarshalTest struct {
 	useNumber             bool
 	golden                bool
 	disallowUnknownFields bool
+	caseSensitive         bool
+	rejectDuplicateNames  bool
 }
 
 type B struct {
@@ -980,6 +1000,61 @@ var unmarshalTests = []unmarshalTest{
 		ptr: new(map[string]Number),
 		err: fmt.Errorf("json: invalid number literal, trying to unmarshal %q into Number", `"invalid"`),
 	},
+
+	// case-sensitive matching and duplicate names
+	{in: `{"ALPHA": "abc", "alpha": "xyz"}`, ptr: new(U), out: U{Alphabet: "xyz"}, caseSensitive: true},
+	{in: `{"alpha": "abc", "ALPHA": "xyz"}`, ptr: new(U), out: U{Alphabet: "abc"}, caseSensitive: true},
+	{in: `{"ALPHA": "abc"}`, ptr: new(U), err: fmt.Errorf("json: unknown field \"ALPHA\""), caseSensitive: true, disallowUnknownFields: true},
+	{in: `{"alpha": "abc", "ALPHA": "xyz"}`, ptr: new(U), out: U{Alphabet: "xyz"}, rejectDuplicateNames: true},
+	{in: `{"alpha": "abc", "alpha": "xyz"}`, ptr: new(U), err: fmt.Errorf("json: duplicate key \"alpha\" in object"), rejectDuplicateNames: true},
+	{in: `{"a": 1, "b": {"a": 2}}`, ptr: new(interface{}), out: map[string]interface{}{"a": 1.0, "b": map[string]interface{}{"a": 2.0}}, rejectDuplicateNames: true},
+	{in: `{"a": 1, "b": {"a": 2, "a": 3}}`, ptr: new(interface{}), err: fmt.Errorf("json: duplicate key \"a\" in object"), rejectDuplicateNames: true},
+	{in: `{"a": 1, "\u0061": 2}`, ptr: new(map[string]int), err: fmt.Errorf("json: duplicate key \"a\" in object"), rejectDuplicateNames: true},
+
+	// format directives
+	{
+		in:     `{"Units":"1m30s","Sec":-1.5,"Nano":7,"Hex":"0aff","URL":"-_8=","B32":"MFRGG===","B32H":"C5H66===","Array":[1,2]}`,
+		ptr:    new(Formats),
+		out:    Formats{90 * time.Second, -1500 * time.Millisecond, 7, []byte{10, 255}, []byte{0xfb, 0xff}, []byte("abc"), []byte("abc"), []byte{1, 2}},
+		golden: true,
+	},
+	{in: `{"Units":null,"Hex":null}`, ptr: new(Formats), out: Formats{}},
+	{in: `{"Sec":0.0000000015}`, ptr: new(Formats), out: Formats{Sec: 2}},
+	{
+		in:  `{"Units":"soon"}`,
+		ptr: new(Formats),
+		err: &UnmarshalTypeError{Value: `string "soon"`, Type: reflect.TypeOf(time.Duration(0)), Struct: "Formats", Field: "Units"},
+	},
+	{
+		in:  `{"Units":5}`,
+		ptr: new(Formats),
+		err: &UnmarshalTypeError{Value: "number", Type: reflect.TypeOf(time.Duration(0)), Struct: "Formats", Field: "Units"},
+	},
+	{
+		in:  `{"Sec":1e10}`,
+		ptr: new(Formats),
+		err: &UnmarshalTypeError{Value: "number 1e10", Type: reflect.TypeOf(time.Duration(0)), Struct: "Formats", Field: "Sec"},
+	},
+	{
+		in:  `{"Hex":true}`,
+		ptr: new(Formats),
+		err: &UnmarshalTypeError{Value: "bool", Type: reflect.TypeOf([]byte(nil)), Struct: "Formats", Field: "Hex"},
+	},
+	{in: `{"Hex":"0g"}`, ptr: new(Formats), err: errors.New("encoding/hex: invalid byte: U+0067 'g'")},
+
+	// inline fields
+	{
+		in:     `{"X":1,"Z":2,"Y":3,"W":4}`,
+		ptr:    new(Inline),
+		out:    Inline{X: 1, Pt: Point{Z: 2}, Next: &T{Y: 3}, Rest: map[string]int{"W": 4}},
+		golden: true,
+	},
+	{
+		in:                    `{"X":1,"w":4}`,
+		ptr:                   new(Inline),
+		out:                   Inline{X: 1, Rest: map[string]int{"w": 4}},
+		disallowUnknownFields: true,
+	},
 }
 
 func TestMarshal(t *testing.T) {
@@ -1130,6 +1205,12 @@ func TestUnmarshal(t *testing.T) {
 		if tt.disallowUnknownFields {
 			dec.DisallowUnknownFields()
 		}
+		if tt.caseSensitive {
+			dec.CaseSensitive()
+		}
+		if tt.rejectDuplicateNames {
+			dec.RejectDuplicateNames()
+		}
 		if err := dec.Decode(v.Interface()); !equalError(err, tt.err) {
 			t.Errorf("#%d: %v, want %v", i, err, tt.err)
 			continue
@@ -72,6 +72,12 @@ import (
 // false, 0, a nil pointer, a nil interface value, and any empty array,
 // slice, map, or string.
 //
+// The "omitzero" option specifies that the field should be omitted
+// from the encoding if the field has a zero value, as reported by its
+// IsZero method if it has one and by reflect.Value.IsZero otherwise.
+// Unlike "omitempty", it omits a struct such as time.Time that is zero,
+// and keeps an empty but non-nil slice or map.
+//
 // As a special case, if the field tag is "-", the field is always omitted.
 // Note that a field with name "-" can still be generated using the tag "-,".
 //
@@ -103,6 +109,30 @@ import (
 //
 //    Int64String int64 `json:",string"`
 //
+// The "format:" option selects an alternative encoding for fields of
+// type time.Duration and []byte. A time.Duration normally encodes as
+// an integer number of nanoseconds ("format:nano"); "format:units"
+// encodes it as a string such as "1h2m0.5s", as formatted by its String
+// method, and "format:sec" as a possibly fractional number of seconds.
+// A []byte normally encodes as a base64 string ("format:base64");
+// "format:base64url", "format:base32", "format:base32hex" and
+// "format:hex" select other encodings of the string, and "format:array"
+// encodes it as a JSON array of numbers. Unmarshal decodes the same
+// format. The option is ignored for fields of other types:
+//
+//    Timeout time.Duration `json:"timeout,format:units"`
+//
+// The "inline" option on a field with no name given in its tag treats
+// a struct, or pointer to struct, as if it were anonymous, so that its
+// fields are marshaled as fields of the outer struct. On a field of map
+// type with string keys, it causes the map entries to be marshaled as
+// members of the outer object after its fields, and Unmarshal stores
+// object members that do not match any field in the map. A struct may
+// have only one such map; if there are several at the least nested
+// level, all are ignored:
+//
+//    Extra map[string]json.RawMessage `json:",inline"`
+//
 // The key name will be used if it's a non-empty string consisting of
 // only Unicode letters, digits, and ASCII punctuation except quotation
 // marks, backslash, and comma.
@@ -356,6 +386,25 @@ func isEmptyValue(v reflect.Value) bool {
 	return false
 }
 
+type isZeroer interface {
+	IsZero() bool
+}
+
+var isZeroerType = reflect.TypeOf((*isZeroer)(nil)).Elem()
+
+// isZeroValue reports whether v is zero for the "omitzero" option.
+func isZeroValue(v reflect.Value) bool {
+	switch {
+	case (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil():
+		return true
+	case v.Type().Implements(isZeroerType):
+		return v.Interface().(isZeroer).IsZero()
+	case v.CanAddr() && reflect.PtrTo(v.Type()).Implements(isZeroerType):
+		return v.Addr().Interface().(isZeroer).IsZero()
+	}
+	return v.IsZero()
+}
+
 func (e *encodeState) reflectValue(v reflect.Value, opts encOpts) {
 	valueEncoder(v)(e, v, opts)
 }
@@ -727,6 +776,7 @@ type structEncoder struct {
 type structFields struct {
 	list      []field
 	nameIndex map[string]int
+	inline    *field // map field capturing unknown members, if any
 }
 
 func (se structEncoder) encode(e *encodeState, v reflect.Value, opts encOpts) {
@@ -747,7 +797,7 @@ FieldLoop:
 			fv = fv.Field(i)
 		}
 
-		if f.omitEmpty && isEmptyValue(fv) {
+		if f.omitEmpty && isEmptyValue(fv) || f.omitZero && isZeroValue(fv) {
 			continue
 		}
 		e.WriteByte(next)
@@ -760,6 +810,20 @@ FieldLoop:
 		opts.quoted = f.quoted
 		f.encoder(e, fv, opts)
 	}
+	if f := se.fields.inline; f != nil {
+		opts.quoted = false
+		if mv := structField(v, f.index); mv.IsValid() && mv.Len() > 0 {
+			keys := mv.MapKeys()
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+			for _, k := range keys {
+				e.WriteByte(next)
+				next = ','
+				e.string(k.String(), opts.escapeHTML)
+				e.WriteByte(':')
+				f.encoder(e, mv.MapIndex(k), opts)
+			}
+		}
+	}
 	if next == '{' {
 		e.WriteString("{}")
 	} else {
@@ -997,6 +1061,21 @@ func typeByIndex(t reflect.Type, index []int) reflect.Type {
 	return t
 }
 
+// structField is like v.FieldByIndex, but it returns the zero Value
+// if the index sequence goes through a nil embedded pointer.
+func structField(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
 type reflectWithString struct {
 	k  reflect.Value
 	v  reflect.Value
@@ -1184,7 +1263,9 @@ type field struct {
 	index     []int
 	typ       reflect.Type
 	omitEmpty bool
+	omitZero  bool
 	quoted    bool
+	format    string // "format:" option, if it applies to typ
 
 	encoder encoderFunc
 }
@@ -1225,6 +1306,9 @@ func typeFields(t reflect.Type) structFields {
 	// Fields found.
 	var fields []field
 
+	// Map fields with the "inline" option.
+	var inlineMaps []field
+
 	// Buffer to run HTMLEscape on field names.
 	var nameEscBuf bytes.Buffer
 
@@ -1268,6 +1352,15 @@ func typeFields(t reflect.Type) structFields {
 				copy(index, f.index)
 				index[len(f.index)] = i
 
+				inline := sf.Anonymous
+				if name == "" && opts.Contains("inline") {
+					if t := sf.Type; t.Kind() == reflect.Map && t.Key().Kind() == reflect.String {
+						inlineMaps = append(inlineMaps, field{name: sf.Name, index: index, typ: t})
+						continue
+					}
+					inline = true
+				}
+
 				ft := sf.Type
 				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
 					// Follow pointer.
@@ -1287,8 +1380,13 @@ func typeFields(t reflect.Type) structFields {
 					}
 				}
 
+				format, _ := opts.Get("format")
+				if !formatApplies(sf.Type, format) {
+					format = ""
+				}
+
 				// Record found field and index sequence.
-				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+				if name != "" || !inline || ft.Kind() != reflect.Struct {
 					tagged := name != ""
 					if name == "" {
 						name = sf.Name
@@ -1299,7 +1397,9 @@ func typeFields(t reflect.Type) structFields {
 						index:     index,
 						typ:       ft,
 						omitEmpty: opts.Contains("omitempty"),
-						quoted:    quoted,
+						omitZero:  opts.Contains("omitzero"),
+						quoted:    quoted && format == "",
+						format:    format,
 					}
 					field.nameBytes = []byte(field.name)
 					field.equalFold = foldFunc(field.nameBytes)
@@ -1382,13 +1482,28 @@ func typeFields(t reflect.Type) structFields {
 
 	for i := range fields {
 		f := &fields[i]
-		f.encoder = typeEncoder(typeByIndex(t, f.index))
+		if f.format != "" {
+			f.encoder = formatEncoder(f.format)
+		} else {
+			f.encoder = typeEncoder(typeByIndex(t, f.index))
+		}
 	}
 	nameIndex := make(map[string]int, len(fields))
 	for i, field := range fields {
 		nameIndex[field.name] = i
 	}
-	return structFields{fields, nameIndex}
+
+	// The inline map is the one at the least nested level;
+	// the fields were found in breadth-first order.
+	var inline *field
+	if len(inlineMaps) > 0 {
+		depth := len(inlineMaps[0].index)
+		if len(inlineMaps) == 1 || len(inlineMaps[1].index) > depth {
+			inline = &inlineMaps[0]
+			inline.encoder = typeEncoder(inline.typ.Elem())
+		}
+	}
+	return structFields{fields, nameIndex, inline}
 }
 
 // dominantField looks through the fields, all of which are known to
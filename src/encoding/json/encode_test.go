@@ -14,6 +14,7 @@ import (
 	"regexp"
 	"strconv"
 	"testing"
+	"time"
 	"unicode"
 )
 
@@ -71,6 +72,156 @@ func TestOmitEmpty(t *testing.T) {
 	}
 }
 
+type zeroByMethod struct{ n int }
+
+func (z zeroByMethod) IsZero() bool { return z.n < 0 }
+
+type zeroByPtrMethod struct{ n int }
+
+func (z *zeroByPtrMethod) IsZero() bool { return z.n < 0 }
+
+type OmitZero struct {
+	S   string          `json:"s,omitzero"`
+	Sl  []int           `json:"sl,omitzero"`
+	M   map[string]int  `json:"m,omitzero"`
+	P   *int            `json:"p,omitzero"`
+	A   [2]int          `json:"a,omitzero"`
+	St  struct{ X int } `json:"st,omitzero"`
+	T   time.Time       `json:"t,omitzero"`
+	Z   zeroByMethod    `json:"z,omitzero"`
+	ZP  zeroByPtrMethod `json:"zp,omitzero"`
+	Raw int             `json:"raw"`
+}
+
+func TestOmitZero(t *testing.T) {
+	for _, tt := range []struct {
+		in   OmitZero
+		want string
+	}{
+		{OmitZero{Z: zeroByMethod{-1}}, `{"raw":0}`},
+		{OmitZero{Sl: []int{}, M: map[string]int{}, A: [2]int{0, 1}, Z: zeroByMethod{-1}},
+			`{"sl":[],"m":{},"a":[0,1],"raw":0}`},
+		{OmitZero{Z: zeroByMethod{-1}, ZP: zeroByPtrMethod{-1}}, `{"zp":{},"raw":0}`},
+		{OmitZero{Z: zeroByMethod{0}, T: time.Unix(0, 0).UTC()},
+			`{"t":"1970-01-01T00:00:00Z","z":{},"raw":0}`},
+	} {
+		got, err := Marshal(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Marshal(%+v):\n got: %s\nwant: %s", tt.in, got, tt.want)
+		}
+	}
+
+	// An addressable value uses IsZero methods on the pointer.
+	got, err := Marshal(&OmitZero{Z: zeroByMethod{-1}, ZP: zeroByPtrMethod{-1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"raw":0}`; string(got) != want {
+		t.Errorf("Marshal(&OmitZero{...}):\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestMarshalFormat(t *testing.T) {
+	for _, tt := range []struct {
+		in   interface{}
+		want string
+	}{
+		{struct {
+			D time.Duration `json:",format:units"`
+		}{90 * time.Second}, `{"D":"1m30s"}`},
+		{struct {
+			D time.Duration `json:",format:sec"`
+		}{-1500 * time.Millisecond}, `{"D":-1.5}`},
+		{struct {
+			D time.Duration `json:",format:sec"`
+		}{time.Duration(math.MinInt64)}, `{"D":-9223372036.854775808}`},
+		{struct {
+			D time.Duration `json:",format:sec"`
+		}{time.Nanosecond}, `{"D":0.000000001}`},
+		{struct {
+			D time.Duration `json:",format:nano,string"`
+		}{5}, `{"D":5}`},
+		{struct {
+			B []byte `json:",format:hex"`
+		}{[]byte{0, 0xab}}, `{"B":"00ab"}`},
+		{struct {
+			B []byte `json:",format:base64url"`
+		}{[]byte{0xfb, 0xff}}, `{"B":"-_8="}`},
+		{struct {
+			B []byte `json:",format:base32"`
+		}{[]byte("abc")}, `{"B":"MFRGG==="}`},
+		{struct {
+			B []byte `json:",format:array"`
+		}{[]byte{1, 255}}, `{"B":[1,255]}`},
+		{struct {
+			B []byte `json:",format:array"`
+		}{}, `{"B":null}`},
+		// The directive is ignored for other types and unknown formats.
+		{struct {
+			I int           `json:",format:units"`
+			D time.Duration `json:",format:hex"`
+			B []byte        `json:",format:base99"`
+		}{1, 2, []byte{0xfb}}, `{"I":1,"D":2,"B":"+w=="}`},
+	} {
+		got, err := Marshal(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Marshal(%#v):\n got: %s\nwant: %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+type InlineBase struct {
+	ID   int
+	Name string
+}
+
+type InlinePtr struct {
+	Note string
+}
+
+type InlineOuter struct {
+	Base  InlineBase            `json:",inline"`
+	Ptr   *InlinePtr            `json:",inline"`
+	Kind  string                `json:"kind"`
+	Extra map[string]RawMessage `json:",inline"`
+	Named InlineBase            `json:"named,inline"`
+}
+
+func TestMarshalInline(t *testing.T) {
+	v := InlineOuter{
+		Base:  InlineBase{ID: 1, Name: "one"},
+		Kind:  "k",
+		Extra: map[string]RawMessage{"z": RawMessage(`[true]`), "a": RawMessage(`"<"`)},
+		Named: InlineBase{ID: 2},
+	}
+	got, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"ID":1,"Name":"one","kind":"k","named":{"ID":2,"Name":""},"a":"\u003c","z":[true]}`
+	if string(got) != want {
+		t.Errorf("Marshal:\n got: %s\nwant: %s", got, want)
+	}
+
+	// Two inline maps at the same level cancel out.
+	got, err = Marshal(struct {
+		A map[string]int `json:",inline"`
+		B map[string]int `json:",inline"`
+	}{map[string]int{"a": 1}, map[string]int{"b": 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{}`; string(got) != want {
+		t.Errorf("Marshal with two inline maps:\n got: %s\nwant: %s", got, want)
+	}
+}
+
 type StringTag struct {
 	BoolStr    bool    `json:",string"`
 	IntStr     int64   `json:",string"`
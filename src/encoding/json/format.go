@@ -0,0 +1,214 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package json
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	hexenc "encoding/hex"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// This file implements the "format:" struct field option,
+// which selects alternative encodings for time.Duration and []byte.
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// A byteEncoding is a binary-to-text encoding of a []byte.
+type byteEncoding interface {
+	EncodedLen(n int) int
+	Encode(dst, src []byte)
+	DecodedLen(n int) int
+	Decode(dst, src []byte) (int, error)
+}
+
+type hexEncoding struct{}
+
+func (hexEncoding) EncodedLen(n int) int                { return hexenc.EncodedLen(n) }
+func (hexEncoding) Encode(dst, src []byte)              { hexenc.Encode(dst, src) }
+func (hexEncoding) DecodedLen(n int) int                { return hexenc.DecodedLen(n) }
+func (hexEncoding) Decode(dst, src []byte) (int, error) { return hexenc.Decode(dst, src) }
+
+var byteEncodings = map[string]byteEncoding{
+	"base64":    base64.StdEncoding,
+	"base64url": base64.URLEncoding,
+	"base32":    base32.StdEncoding,
+	"base32hex": base32.HexEncoding,
+	"hex":       hexEncoding{},
+}
+
+// formatApplies reports whether the format directive applies to a
+// field of type t. It does not apply to types with their own marshaling
+// methods.
+func formatApplies(t reflect.Type, format string) bool {
+	if format == "" {
+		return false
+	}
+	if t == durationType {
+		return format == "units" || format == "nano" || format == "sec"
+	}
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+	for _, t := range [...]reflect.Type{t, t.Elem()} {
+		if t.Implements(marshalerType) || t.Implements(textMarshalerType) ||
+			reflect.PtrTo(t).Implements(marshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+			return false
+		}
+	}
+	return format == "array" || byteEncodings[format] != nil
+}
+
+// formatEncoder returns the encoder for a field with a format
+// directive that applies to its type.
+func formatEncoder(format string) encoderFunc {
+	switch format {
+	case "units":
+		return durationUnitsEncoder
+	case "nano":
+		return intEncoder
+	case "sec":
+		return durationSecondsEncoder
+	case "array":
+		return byteArrayEncoder
+	}
+	enc := byteEncodings[format]
+	return func(e *encodeState, v reflect.Value, _ encOpts) {
+		if v.IsNil() {
+			e.WriteString("null")
+			return
+		}
+		s := v.Bytes()
+		var dst []byte
+		if n := enc.EncodedLen(len(s)); n <= len(e.scratch) {
+			dst = e.scratch[:n]
+		} else {
+			dst = make([]byte, n)
+		}
+		enc.Encode(dst, s)
+		e.WriteByte('"')
+		e.Write(dst)
+		e.WriteByte('"')
+	}
+}
+
+func durationUnitsEncoder(e *encodeState, v reflect.Value, opts encOpts) {
+	e.string(time.Duration(v.Int()).String(), opts.escapeHTML)
+}
+
+func durationSecondsEncoder(e *encodeState, v reflect.Value, _ encOpts) {
+	// Format the seconds exactly, rather than going
+	// through the float64 returned by Duration.Seconds.
+	n := v.Int()
+	b := e.scratch[:0]
+	u := uint64(n)
+	if n < 0 {
+		b = append(b, '-')
+		u = -u
+	}
+	b = strconv.AppendUint(b, u/1e9, 10)
+	if frac := u % 1e9; frac != 0 {
+		var digits [10]byte
+		digits[0] = '.'
+		for i := 9; i > 0; i-- {
+			digits[i] = byte('0' + frac%10)
+			frac /= 10
+		}
+		end := len(digits)
+		for digits[end-1] == '0' {
+			end--
+		}
+		b = append(b, digits[:end]...)
+	}
+	e.Write(b)
+}
+
+func byteArrayEncoder(e *encodeState, v reflect.Value, _ encOpts) {
+	if v.IsNil() {
+		e.WriteString("null")
+		return
+	}
+	e.WriteByte('[')
+	for i, c := range v.Bytes() {
+		if i > 0 {
+			e.WriteByte(',')
+		}
+		b := strconv.AppendUint(e.scratch[:0], uint64(c), 10)
+		e.Write(b)
+	}
+	e.WriteByte(']')
+}
+
+// formatValue is like value, but it decodes the value into v using
+// the format directive of its struct field.
+func (d *decodeState) formatValue(v reflect.Value, format string) error {
+	if d.opcode != scanBeginLiteral || format == "array" || format == "nano" {
+		return d.value(v)
+	}
+	start := d.readIndex()
+	d.rescanLiteral()
+	item := d.data[start:d.readIndex()]
+	if item[0] == 'n' { // null
+		return d.literalStore(item, v, false)
+	}
+
+	if v.Type() == durationType {
+		switch {
+		case format == "units" && item[0] == '"':
+			s, ok := unquote(item)
+			if !ok {
+				panic(phasePanicMsg)
+			}
+			dur, err := time.ParseDuration(s)
+			if err != nil {
+				d.saveError(&UnmarshalTypeError{Value: "string " + strconv.Quote(s), Type: v.Type(), Offset: int64(d.readIndex())})
+				break
+			}
+			v.SetInt(int64(dur))
+		case format == "sec" && item[0] != '"' && item[0] != 't' && item[0] != 'f':
+			f, err := strconv.ParseFloat(string(item), 64)
+			if f *= 1e9; err != nil || f >= math.MaxInt64 || f < math.MinInt64 {
+				d.saveError(&UnmarshalTypeError{Value: "number " + string(item), Type: v.Type(), Offset: int64(d.readIndex())})
+				break
+			}
+			v.SetInt(int64(math.Round(f)))
+		default:
+			d.saveError(&UnmarshalTypeError{Value: literalKind(item), Type: v.Type(), Offset: int64(d.readIndex())})
+		}
+		return nil
+	}
+
+	if item[0] != '"' {
+		d.saveError(&UnmarshalTypeError{Value: literalKind(item), Type: v.Type(), Offset: int64(d.readIndex())})
+		return nil
+	}
+	s, ok := unquoteBytes(item)
+	if !ok {
+		panic(phasePanicMsg)
+	}
+	enc := byteEncodings[format]
+	b := make([]byte, enc.DecodedLen(len(s)))
+	n, err := enc.Decode(b, s)
+	if err != nil {
+		d.saveError(err)
+		return nil
+	}
+	v.SetBytes(b[:n])
+	return nil
+}
+
+// literalKind describes the JSON literal item for an UnmarshalTypeError.
+func literalKind(item []byte) string {
+	switch item[0] {
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "bool"
+	}
+	return "number"
+}
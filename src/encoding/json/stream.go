@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"strconv"
 )
 
 // A Decoder reads and decodes JSON values from an input stream.
@@ -20,6 +21,8 @@ type Decoder struct {
 	scan    scanner
 	err     error
 
+	peekScan scanner // copy of scan used by readValue
+
 	tokenState int
 	tokenStack []int
 }
@@ -41,6 +44,15 @@ func (dec *Decoder) UseNumber() { dec.d.useNumber = true }
 // non-ignored, exported fields in the destination.
 func (dec *Decoder) DisallowUnknownFields() { dec.d.disallowUnknownFields = true }
 
+// CaseSensitive causes the Decoder to match object keys to struct fields
+// only when they are exactly equal to the field's name, instead of also
+// accepting a case-insensitive match.
+func (dec *Decoder) CaseSensitive() { dec.d.caseSensitive = true }
+
+// RejectDuplicateNames causes the Decoder to return an error when
+// an object in the input contains the same key more than once.
+func (dec *Decoder) RejectDuplicateNames() { dec.d.rejectDuplicateNames = true }
+
 // Decode reads the next JSON-encoded value from its
 // input and stores it in the value pointed to by v.
 //
@@ -78,6 +90,40 @@ func (dec *Decoder) Decode(v interface{}) error {
 	return err
 }
 
+// ReadValue returns the encoding of the next JSON value in the input
+// stream without decoding it. Leading white space is omitted.
+//
+// The returned RawMessage aliases the Decoder's internal buffer
+// and is only valid until the next call to a method of the Decoder.
+// ReadValue may be mixed with calls to Token and ReadToken to
+// skip over or extract a value nested inside a larger one.
+func (dec *Decoder) ReadValue() (RawMessage, error) {
+	if dec.err != nil {
+		return nil, dec.err
+	}
+
+	if err := dec.tokenPrepareForDecode(); err != nil {
+		return nil, err
+	}
+
+	if !dec.tokenValueAllowed() {
+		return nil, &SyntaxError{msg: "not at beginning of value", Offset: dec.InputOffset()}
+	}
+
+	n, err := dec.readValue()
+	if err != nil {
+		return nil, err
+	}
+	v := dec.buf[dec.scanp : dec.scanp+n]
+	dec.scanp += n
+	for len(v) > 0 && isSpace(v[0]) {
+		v = v[1:]
+	}
+
+	dec.tokenValueEnd()
+	return v, nil
+}
+
 // Buffered returns a reader of the data remaining in the Decoder's
 // buffer. The reader is valid until the next call to Decode.
 func (dec *Decoder) Buffered() io.Reader {
@@ -99,6 +145,16 @@ Input:
 		// Look in the buffer for a new value.
 		for ; scanp < len(dec.buf); scanp++ {
 			c := dec.buf[scanp]
+			if (c == ',' || c == ':' || c == ']' || c == '}') && len(dec.tokenStack) > 0 && len(dec.scan.parseState) == 0 {
+				// Inside an array or object, a literal value or key
+				// may end at c. Check whether a space would end it
+				// instead, which avoids allocating the error the
+				// scanner saves for c after a top-level value.
+				dec.peekScan = dec.scan
+				if dec.peekScan.step(&dec.peekScan, ' ') == scanEnd {
+					break Input
+				}
+			}
 			dec.scan.bytes++
 			switch dec.scan.step(&dec.scan, c) {
 			case scanEnd:
@@ -186,6 +242,12 @@ type Encoder struct {
 	indentBuf    *bytes.Buffer
 	indentPrefix string
 	indentValue  string
+
+	// The state of a value being written with WriteToken.
+	// Its encoding is collected in tokenBuf.
+	tokenState int
+	tokenStack []int
+	tokenBuf   bytes.Buffer
 }
 
 // NewEncoder returns a new encoder that writes to w.
@@ -208,6 +270,13 @@ func (enc *Encoder) Encode(v interface{}) error {
 		return err
 	}
 
+	if enc.tokenState != tokenTopValue {
+		// v is an element of a value started with WriteToken.
+		err = enc.tokenWrite(e.Bytes())
+		encodeStatePool.Put(e)
+		return err
+	}
+
 	// Terminate each value with a newline.
 	// This makes the output look a little nicer
 	// when debugging, and some kind of space
@@ -235,6 +304,176 @@ func (enc *Encoder) Encode(v interface{}) error {
 	return err
 }
 
+// WriteToken writes a single JSON token to the stream. The token is given
+// in its encoded form, as returned by Decoder.ReadToken: one of the
+// delimiters [ ] { }, a quoted string, a number, true, false or null.
+// Encoded strings are accepted as object keys.
+//
+// The Encoder inserts the commas and colons that separate tokens
+// and checks that delimiters are properly nested and matched.
+// WriteValue and Encode may be used between calls to WriteToken
+// to write complete values nested inside the one being written.
+// When a top-level value is complete, it is written to the stream
+// followed by a newline character, as by Encode. If indentation is
+// not enabled, long values are written to the stream in pieces
+// while they are being built.
+func (enc *Encoder) WriteToken(raw []byte) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	if len(raw) == 1 {
+		switch c := raw[0]; c {
+		case '[', '{':
+			if !enc.tokenValuePrefix(c) {
+				return enc.tokenError(c)
+			}
+			enc.tokenBuf.WriteByte(c)
+			enc.tokenStack = append(enc.tokenStack, enc.tokenState)
+			if c == '[' {
+				enc.tokenState = tokenArrayStart
+			} else {
+				enc.tokenState = tokenObjectStart
+			}
+			return nil
+
+		case ']', '}':
+			if c == ']' && enc.tokenState != tokenArrayStart && enc.tokenState != tokenArrayComma ||
+				c == '}' && enc.tokenState != tokenObjectStart && enc.tokenState != tokenObjectComma {
+				return enc.tokenError(c)
+			}
+			enc.tokenBuf.WriteByte(c)
+			enc.tokenState = enc.tokenStack[len(enc.tokenStack)-1]
+			enc.tokenStack = enc.tokenStack[:len(enc.tokenStack)-1]
+			return enc.tokenValueEnd()
+		}
+	}
+	if len(raw) > 0 && (raw[0] == '[' || raw[0] == '{') {
+		return &SyntaxError{"invalid token " + quoteChar(raw[0]) + " followed by more data", 0}
+	}
+	return enc.tokenWrite(raw)
+}
+
+// WriteValue writes the JSON value v to the stream, compacting it
+// and escaping HTML characters in strings as Encode would. If v is
+// not nested in a value started with WriteToken, it is followed by a
+// newline character.
+func (enc *Encoder) WriteValue(v RawMessage) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	return enc.tokenWrite(v)
+}
+
+// tokenWrite validates and appends the complete value or object key b
+// to enc.tokenBuf, preceded by any separator it needs.
+func (enc *Encoder) tokenWrite(b []byte) error {
+	i := 0
+	for i < len(b) && isSpace(b[i]) {
+		i++
+	}
+	if i == len(b) {
+		return &SyntaxError{"unexpected end of JSON input", int64(len(b))}
+	}
+	c := b[i]
+	origLen, origState := enc.tokenBuf.Len(), enc.tokenState
+	if !enc.tokenValuePrefix(c) {
+		return enc.tokenError(c)
+	}
+	if err := compact(&enc.tokenBuf, b, enc.escapeHTML); err != nil {
+		enc.tokenBuf.Truncate(origLen)
+		enc.tokenState = origState
+		return err
+	}
+	if enc.tokenState == tokenObjectKey {
+		enc.tokenState = tokenObjectColon
+		return nil
+	}
+	return enc.tokenValueEnd()
+}
+
+// tokenValuePrefix writes the separator needed before a value starting
+// with c and reports whether such a value is allowed. A string at the
+// start of an object member is taken to be its key, and leaves the
+// Encoder in the tokenObjectKey state.
+func (enc *Encoder) tokenValuePrefix(c byte) bool {
+	switch enc.tokenState {
+	case tokenTopValue, tokenArrayStart:
+	case tokenArrayComma:
+		enc.tokenBuf.WriteByte(',')
+	case tokenObjectColon:
+		enc.tokenBuf.WriteByte(':')
+		enc.tokenState = tokenObjectValue
+	case tokenObjectStart, tokenObjectComma:
+		if c != '"' {
+			return false
+		}
+		if enc.tokenState == tokenObjectComma {
+			enc.tokenBuf.WriteByte(',')
+		}
+		enc.tokenState = tokenObjectKey
+	default:
+		return false
+	}
+	return true
+}
+
+// tokenValueEnd advances the token state past a complete value.
+// At the top level, it writes the value to the stream.
+func (enc *Encoder) tokenValueEnd() error {
+	switch enc.tokenState {
+	case tokenArrayStart, tokenArrayComma:
+		enc.tokenState = tokenArrayComma
+	case tokenObjectValue:
+		enc.tokenState = tokenObjectComma
+	case tokenTopValue:
+		enc.tokenBuf.WriteByte('\n')
+		return enc.tokenFlush(true)
+	}
+	if enc.indentPrefix == "" && enc.indentValue == "" && enc.tokenBuf.Len() >= tokenFlushSize {
+		return enc.tokenFlush(false)
+	}
+	return nil
+}
+
+// tokenFlushSize is the amount of buffered data of an incomplete
+// value above which it is written to the stream.
+const tokenFlushSize = 4096
+
+// tokenFlush writes the buffered data to the stream. The data is
+// indented if it is a complete value and indentation is enabled.
+func (enc *Encoder) tokenFlush(complete bool) error {
+	b := enc.tokenBuf.Bytes()
+	if complete && (enc.indentPrefix != "" || enc.indentValue != "") {
+		if enc.indentBuf == nil {
+			enc.indentBuf = new(bytes.Buffer)
+		}
+		enc.indentBuf.Reset()
+		if err := Indent(enc.indentBuf, b, enc.indentPrefix, enc.indentValue); err != nil {
+			return err
+		}
+		b = enc.indentBuf.Bytes()
+	}
+	enc.tokenBuf.Reset()
+	if _, err := enc.w.Write(b); err != nil {
+		enc.err = err
+		return err
+	}
+	return nil
+}
+
+func (enc *Encoder) tokenError(c byte) error {
+	var context string
+	switch enc.tokenState {
+	case tokenArrayComma, tokenObjectComma:
+		context = " after value"
+	case tokenObjectStart:
+		context = " looking for object key string"
+	default:
+		context = " looking for beginning of value"
+	}
+	return &SyntaxError{"invalid token " + quoteChar(c) + context, 0}
+}
+
 // SetIndent instructs the encoder to format each subsequent encoded
 // value as if indented by the package-level function Indent(dst, src, prefix, indent).
 // Calling SetIndent("", "") disables indentation.
@@ -461,6 +700,159 @@ func (dec *Decoder) Token() (Token, error) {
 	}
 }
 
+// A Kind identifies the kind of a JSON token or value by the
+// first byte of its encoding, with all numbers reported as '0'.
+type Kind byte
+
+const (
+	KindNull        Kind = 'n'
+	KindFalse       Kind = 'f'
+	KindTrue        Kind = 't'
+	KindString      Kind = '"'
+	KindNumber      Kind = '0'
+	KindBeginObject Kind = '{'
+	KindEndObject   Kind = '}'
+	KindBeginArray  Kind = '['
+	KindEndArray    Kind = ']'
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNull:
+		return "null"
+	case KindFalse:
+		return "false"
+	case KindTrue:
+		return "true"
+	case KindString:
+		return "string"
+	case KindNumber:
+		return "number"
+	case KindBeginObject:
+		return "{"
+	case KindEndObject:
+		return "}"
+	case KindBeginArray:
+		return "["
+	case KindEndArray:
+		return "]"
+	}
+	return "<invalid json.Kind: " + quoteChar(byte(k)) + ">"
+}
+
+// kindOf returns the Kind of a value whose encoding starts with c.
+func kindOf(c byte) Kind {
+	if c == '-' || '0' <= c && c <= '9' {
+		return KindNumber
+	}
+	return Kind(c)
+}
+
+// ReadToken returns the kind and encoding of the next JSON token in
+// the input stream. At the end of the input stream, ReadToken returns
+// 0, nil, io.EOF.
+//
+// ReadToken reads the same sequence of tokens as Token, but it returns
+// them in encoded form instead of as Go values: a string token includes
+// its quotes and escapes, which AppendUnquote removes, and a number
+// token holds its digits, as a Number would. The returned bytes alias
+// the Decoder's internal buffer and are only valid until the next call
+// to a method of the Decoder. Unlike Token, ReadToken does not allocate.
+func (dec *Decoder) ReadToken() (Kind, []byte, error) {
+	for {
+		c, err := dec.peek()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch c {
+		case '[', '{':
+			if !dec.tokenValueAllowed() {
+				return dec.readTokenError(c)
+			}
+			dec.scanp++
+			dec.tokenStack = append(dec.tokenStack, dec.tokenState)
+			if c == '[' {
+				dec.tokenState = tokenArrayStart
+			} else {
+				dec.tokenState = tokenObjectStart
+			}
+			return Kind(c), dec.buf[dec.scanp-1 : dec.scanp], nil
+
+		case ']', '}':
+			if c == ']' && dec.tokenState != tokenArrayStart && dec.tokenState != tokenArrayComma ||
+				c == '}' && dec.tokenState != tokenObjectStart && dec.tokenState != tokenObjectComma {
+				return dec.readTokenError(c)
+			}
+			dec.scanp++
+			dec.tokenState = dec.tokenStack[len(dec.tokenStack)-1]
+			dec.tokenStack = dec.tokenStack[:len(dec.tokenStack)-1]
+			dec.tokenValueEnd()
+			return Kind(c), dec.buf[dec.scanp-1 : dec.scanp], nil
+
+		case ':':
+			if dec.tokenState != tokenObjectColon {
+				return dec.readTokenError(c)
+			}
+			dec.scanp++
+			dec.tokenState = tokenObjectValue
+			continue
+
+		case ',':
+			if dec.tokenState == tokenArrayComma {
+				dec.scanp++
+				dec.tokenState = tokenArrayValue
+				continue
+			}
+			if dec.tokenState == tokenObjectComma {
+				dec.scanp++
+				dec.tokenState = tokenObjectKey
+				continue
+			}
+			return dec.readTokenError(c)
+
+		case '"':
+			if dec.tokenState == tokenObjectStart || dec.tokenState == tokenObjectKey {
+				old := dec.tokenState
+				dec.tokenState = tokenTopValue
+				b, err := dec.ReadValue()
+				dec.tokenState = old
+				if err != nil {
+					return 0, nil, err
+				}
+				dec.tokenState = tokenObjectColon
+				return KindString, b, nil
+			}
+			fallthrough
+
+		default:
+			if !dec.tokenValueAllowed() {
+				return dec.readTokenError(c)
+			}
+			b, err := dec.ReadValue()
+			if err != nil {
+				return 0, nil, err
+			}
+			return kindOf(c), b, nil
+		}
+	}
+}
+
+func (dec *Decoder) readTokenError(c byte) (Kind, []byte, error) {
+	_, err := dec.tokenError(c)
+	return 0, nil, err
+}
+
+// AppendUnquote appends the contents of the encoded JSON string s,
+// with its quotes removed and escape sequences decoded, to dst and
+// returns the extended buffer.
+func AppendUnquote(dst, s []byte) ([]byte, error) {
+	t, ok := unquoteBytes(s)
+	if !ok {
+		return dst, &SyntaxError{"invalid JSON string " + strconv.Quote(string(s)), 0}
+	}
+	return append(dst, t...), nil
+}
+
 func (dec *Decoder) tokenError(c byte) (Token, error) {
 	var context string
 	switch dec.tokenState {
@@ -472,3 +472,247 @@ func TestHTTPDecoding(t *testing.T) {
 		t.Errorf("err = %v; want io.EOF", err)
 	}
 }
+
+type rawToken struct {
+	kind Kind
+	raw  string
+}
+
+var readTokenTests = []struct {
+	json   string
+	tokens []rawToken
+}{
+	{json: `1 "two" null`, tokens: []rawToken{
+		{KindNumber, `1`}, {KindString, `"two"`}, {KindNull, `null`},
+	}},
+	{json: ` [ -1.5e3 , true,false ] `, tokens: []rawToken{
+		{KindBeginArray, `[`}, {KindNumber, `-1.5e3`}, {KindTrue, `true`},
+		{KindFalse, `false`}, {KindEndArray, `]`},
+	}},
+	{json: `{"a\"b": {"c": []}, "d": "é"}`, tokens: []rawToken{
+		{KindBeginObject, `{`}, {KindString, `"a\"b"`}, {KindBeginObject, `{`},
+		{KindString, `"c"`}, {KindBeginArray, `[`}, {KindEndArray, `]`},
+		{KindEndObject, `}`}, {KindString, `"d"`}, {KindString, `"é"`},
+		{KindEndObject, `}`},
+	}},
+	{json: `{"` + strings.Repeat("x", 600) + `":0}`, tokens: []rawToken{
+		{KindBeginObject, `{`}, {KindString, `"` + strings.Repeat("x", 600) + `"`},
+		{KindNumber, `0`}, {KindEndObject, `}`},
+	}},
+}
+
+func TestReadToken(t *testing.T) {
+	for _, tt := range readTokenTests {
+		dec := NewDecoder(strings.NewReader(tt.json))
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		for i, want := range tt.tokens {
+			kind, raw, err := dec.ReadToken()
+			if err != nil {
+				t.Fatalf("%#q: token %d: %v", tt.json, i, err)
+			}
+			if kind != want.kind || string(raw) != want.raw {
+				t.Fatalf("%#q: token %d = %v %#q, want %v %#q", tt.json, i, kind, raw, want.kind, want.raw)
+			}
+			if err := enc.WriteToken(raw); err != nil {
+				t.Fatalf("%#q: WriteToken(%#q): %v", tt.json, raw, err)
+			}
+		}
+		if _, _, err := dec.ReadToken(); err != io.EOF {
+			t.Errorf("%#q: ReadToken at end = %v, want io.EOF", tt.json, err)
+		}
+
+		// The tokens written back should form the same values.
+		var want bytes.Buffer
+		d := NewDecoder(strings.NewReader(tt.json))
+		for {
+			var v RawMessage
+			if err := d.Decode(&v); err != nil {
+				break
+			}
+			Compact(&want, v)
+			want.WriteByte('\n')
+		}
+		if buf.String() != want.String() {
+			t.Errorf("%#q: WriteToken wrote %#q, want %#q", tt.json, buf.String(), want.String())
+		}
+	}
+}
+
+func TestReadTokenErrors(t *testing.T) {
+	for _, tt := range []struct {
+		json string
+		err  string
+	}{
+		{`[1 2]`, "invalid character '2' after array element"},
+		{`{1:2}`, "invalid character '1'"},
+		{`{"a" "b"}`, "invalid character '\"' after object key"},
+		{`[}`, "invalid character '}' looking for beginning of value"},
+		{`[1,`, "EOF"},
+	} {
+		dec := NewDecoder(strings.NewReader(tt.json))
+		var err error
+		for err == nil {
+			_, _, err = dec.ReadToken()
+		}
+		if err.Error() != tt.err {
+			t.Errorf("%#q: error %q, want %q", tt.json, err, tt.err)
+		}
+	}
+}
+
+func TestReadValue(t *testing.T) {
+	const input = ` {"skip": [1, {"x": 2}], "keep": {"y" : [3]}} "s" `
+	dec := NewDecoder(strings.NewReader(input))
+	var got []string
+	for {
+		kind, raw, err := dec.ReadToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(raw))
+		if kind == KindString && dec.tokenState == tokenObjectColon {
+			v, err := dec.ReadValue()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, string(v))
+		}
+	}
+	want := []string{`{`, `"skip"`, `[1, {"x": 2}]`, `"keep"`, `{"y" : [3]}`, `}`, `"s"`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReadTokenAllocs(t *testing.T) {
+	input := strings.Repeat(`{"a": [1, "b", true, null], "c": {"d": -2.5}} `, 100)
+	dec := NewDecoder(strings.NewReader(input))
+	// Warm up the buffer and token stack.
+	for i := 0; i < 10; i++ {
+		dec.ReadToken()
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, _, err := dec.ReadToken(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("ReadToken allocated %v times, want 0", allocs)
+	}
+}
+
+func TestAppendUnquote(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{`""`, ""},
+		{`"abc"`, "abc"},
+		{`"a\"b\\c\né😀"`, "a\"b\\c\né\U0001f600"},
+	} {
+		got, err := AppendUnquote([]byte("x"), []byte(tt.in))
+		if err != nil || string(got) != "x"+tt.out {
+			t.Errorf("AppendUnquote(%#q) = %q, %v, want %q", tt.in, got, err, "x"+tt.out)
+		}
+	}
+	if _, err := AppendUnquote(nil, []byte(`"a`)); err == nil {
+		t.Errorf("AppendUnquote of unterminated string succeeded")
+	}
+}
+
+func TestEncoderWriteToken(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	write := func(raw string) {
+		t.Helper()
+		if err := enc.WriteToken([]byte(raw)); err != nil {
+			t.Fatalf("WriteToken(%#q): %v", raw, err)
+		}
+	}
+	write(`{`)
+	write(`"list"`)
+	write(`[`)
+	if err := enc.Encode(map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.WriteValue(RawMessage(` [ 1, 2 ] `)); err != nil {
+		t.Fatal(err)
+	}
+	write(`"<&>"`)
+	write(`]`)
+	if err := enc.Encode("key"); err != nil {
+		t.Fatal(err)
+	}
+	write(`null`)
+	write(`}`)
+	write(`3`)
+	const want = `{"list":[{"a":1},[1,2],"\u003c\u0026\u003e"],"key":null}` + "\n3\n"
+	if buf.String() != want {
+		t.Errorf("got %#q, want %#q", buf.String(), want)
+	}
+
+	buf.Reset()
+	enc = NewEncoder(&buf)
+	enc.SetIndent(">", ".")
+	write(`[`)
+	write(`1`)
+	write(`{`)
+	write(`"a"`)
+	write(`true`)
+	write(`}`)
+	write(`]`)
+	const wantIndent = "[\n>.1,\n>.{\n>..\"a\": true\n>.}\n>]\n"
+	if buf.String() != wantIndent {
+		t.Errorf("indented: got %#q, want %#q", buf.String(), wantIndent)
+	}
+}
+
+func TestEncoderWriteTokenFlush(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.WriteToken([]byte(`[`))
+	elem := []byte(`"` + strings.Repeat("x", 100) + `"`)
+	for buf.Len() == 0 {
+		if err := enc.WriteToken(elem); err != nil {
+			t.Fatal(err)
+		}
+		if enc.tokenBuf.Len() > 2*tokenFlushSize {
+			t.Fatalf("encoder buffered %d bytes of an incomplete value", enc.tokenBuf.Len())
+		}
+	}
+	enc.WriteToken([]byte(`]`))
+	if err := Unmarshal(buf.Bytes(), new([]string)); err != nil {
+		t.Errorf("written value is invalid: %v", err)
+	}
+}
+
+func TestEncoderWriteTokenErrors(t *testing.T) {
+	for _, tt := range []struct {
+		tokens []string
+		err    string
+	}{
+		{[]string{`]`}, "invalid token ']' looking for beginning of value"},
+		{[]string{`{`, `1`}, "invalid token '1' looking for object key string"},
+		{[]string{`{`, `"a"`, `]`}, "invalid token ']' looking for beginning of value"},
+		{[]string{`[`, `}`}, "invalid token '}' looking for beginning of value"},
+		{[]string{`[1]`}, "invalid token '[' followed by more data"},
+		{[]string{`tru`}, "invalid character ' ' in literal true (expecting 'e')"},
+		{[]string{`"a" "b"`}, "invalid character '\"' after top-level value"},
+		{[]string{``}, "unexpected end of JSON input"},
+	} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		var err error
+		for _, tok := range tt.tokens {
+			if err = enc.WriteToken([]byte(tok)); err != nil {
+				break
+			}
+		}
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%q: error %v, want %q", tt.tokens, err, tt.err)
+		}
+	}
+}
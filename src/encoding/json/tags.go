@@ -42,3 +42,21 @@ func (o tagOptions) Contains(optionName string) bool {
 	}
 	return false
 }
+
+// Get returns the value of an option of the form optionName:value
+// in a comma-separated list of options, and whether it is present.
+func (o tagOptions) Get(optionName string) (string, bool) {
+	s := string(o)
+	for s != "" {
+		var next string
+		i := strings.Index(s, ",")
+		if i >= 0 {
+			s, next = s[:i], s[i+1:]
+		}
+		if strings.HasPrefix(s, optionName) && len(s) > len(optionName) && s[len(optionName)] == ':' {
+			return s[len(optionName)+1:], true
+		}
+		s = next
+	}
+	return "", false
+}
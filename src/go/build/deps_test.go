@@ -70,7 +70,7 @@ import (
 var depsRules = `
 	# No dependencies allowed for any of these packages.
 	NONE
-	< container/list, container/ring,
+	< constraints, container/list, container/ring,
 	  internal/cfg, internal/cpu, internal/goarch,
 	  internal/goexperiment, internal/goos,
 	  internal/goversion, internal/nettrace,
@@ -103,6 +103,10 @@ var depsRules = `
 	< sort
 	< container/heap;
 
+	# slices and maps are generic helpers built only on constraints.
+	constraints, math/bits
+	< slices, maps;
+
 	RUNTIME
 	< io;
 
@@ -231,7 +235,8 @@ var depsRules = `
 	fmt !< encoding/base32, encoding/base64;
 
 	FMT, encoding/base32, encoding/base64
-	< encoding/ascii85, encoding/csv, encoding/gob, encoding/hex,
+	< encoding/hex
+	< encoding/ascii85, encoding/csv, encoding/gob,
 	  encoding/json, encoding/pem, encoding/xml, mime;
 
 	# hashes
@@ -245,7 +250,7 @@ var depsRules = `
 
 	# compression
 	FMT, encoding/binary, hash/adler32, hash/crc32
-	< compress/bzip2, compress/flate, compress/lzw
+	< compress/bzip2, compress/flate, compress/lzw, compress/zstd
 	< archive/zip, compress/gzip, compress/zlib;
 
 	# templates
@@ -346,6 +351,13 @@ var depsRules = `
 	sync
 	< internal/singleflight;
 
+	# netip is a small value type for IP addresses, used by net.
+	RUNTIME
+	< internal/intern;
+
+	internal/bytealg, internal/intern, internal/itoa, math, math/bits, strconv
+	< net/netip;
+
 	os
 	< golang.org/x/net/dns/dnsmessage,
 	  golang.org/x/net/lif,
@@ -363,6 +375,7 @@ var depsRules = `
 	internal/poll,
 	internal/singleflight,
 	internal/race,
+	net/netip,
 	os
 	< net;
 
@@ -378,10 +391,18 @@ var depsRules = `
 
 	# logging - most packages should not import; http and up is allowed
 	FMT
+	< log/internal
 	< log;
 
 	log !< crypto/tls, database/sql, go/importer, testing;
 
+	# slog is built on log and encoding/json; the same restrictions apply.
+	context, encoding, encoding/json, log
+	< log/slog/internal/buffer
+	< log/slog;
+
+	log/slog !< crypto/tls, database/sql, go/importer, testing;
+
 	FMT, log, net
 	< log/syslog;
 
@@ -420,7 +441,11 @@ var depsRules = `
 	CGO, net !< CRYPTO-MATH;
 
 	# TLS, Prince of Dependencies.
-	CRYPTO-MATH, NET, container/list, encoding/hex, encoding/pem
+	context, internal/itoa
+	< internal/tlsquic;
+
+	CRYPTO-MATH, NET, container/list, encoding/hex, encoding/pem,
+	internal/tlsquic
 	< golang.org/x/crypto/internal/subtle
 	< golang.org/x/crypto/chacha20
 	< golang.org/x/crypto/poly1305
@@ -443,7 +468,11 @@ var depsRules = `
 
 	FMT
 	< golang.org/x/net/http2/hpack
-	< net/http/internal, net/http/internal/ascii, net/http/internal/testcert;
+	< net/http/internal, net/http/internal/ascii, net/http/internal/testcert,
+	  net/http/internal/qpack;
+
+	crypto/tls
+	< net/http/internal/quic;
 
 	FMT, NET, container/list, encoding/binary, log
 	< golang.org/x/text/transform
@@ -457,11 +486,14 @@ var depsRules = `
 	< net/http/httptrace;
 
 	compress/gzip,
+	compress/zstd,
 	golang.org/x/net/http/httpguts,
 	golang.org/x/net/http/httpproxy,
 	golang.org/x/net/http2/hpack,
 	net/http/internal,
 	net/http/internal/ascii,
+	net/http/internal/qpack,
+	net/http/internal/quic,
 	net/http/internal/testcert,
 	net/http/httptrace,
 	mime/multipart,
@@ -513,7 +545,10 @@ var depsRules = `
 	FMT, flag, runtime/debug, runtime/trace, internal/sysinfo, math/rand
 	< testing;
 
-	internal/testlog, runtime/pprof, regexp
+	FMT, crypto/sha256, encoding/json, go/ast, go/parser, go/token
+	< internal/fuzz;
+
+	internal/fuzz, internal/testlog, runtime/pprof, regexp
 	< testing/internal/testdeps;
 
 	OS, flag, testing, internal/cfg
@@ -529,7 +564,8 @@ var depsRules = `
 	< golang.org/x/net/nettest;
 
 	FMT, container/heap, math/rand
-	< internal/trace;
+	< internal/trace
+	< debug/trace;
 `
 
 // listStdPkgs returns the same list of packages as "go list std".
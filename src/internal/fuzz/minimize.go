@@ -0,0 +1,86 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+// isMinimizable reports whether v is a value that minimizeBytes can shrink.
+func isMinimizable(v interface{}) bool {
+	switch v.(type) {
+	case []byte, string:
+		return true
+	}
+	return false
+}
+
+// minimizeBytes returns the smallest variation of v that it can find for
+// which try still returns true. try must not retain the slice it is given.
+// minimizeBytes gives up early, returning the best value found so far, once
+// shouldStop returns true.
+func minimizeBytes(v []byte, try func([]byte) bool, shouldStop func() bool) []byte {
+	v = append([]byte(nil), v...)
+	tmp := make([]byte, 0, len(v))
+
+	// First, try to cut the tail.
+	for n := 1024; n != 0; n /= 2 {
+		for len(v) > n {
+			if shouldStop() {
+				return v
+			}
+			candidate := v[:len(v)-n]
+			if !try(candidate) {
+				break
+			}
+			v = candidate
+		}
+	}
+
+	// Then, try to remove each individual byte.
+	for i := 0; i < len(v); {
+		if shouldStop() {
+			return v
+		}
+		candidate := append(append(tmp[:0], v[:i]...), v[i+1:]...)
+		if !try(candidate) {
+			i++
+			continue
+		}
+		// v[i] is now a different byte, so try again at the same index.
+		v = append(v[:0], candidate...)
+	}
+
+	// Then, try to remove each possible range of bytes.
+	for i := 0; i < len(v)-1; i++ {
+		for j := len(v); j > i+1; j-- {
+			if shouldStop() {
+				return v
+			}
+			candidate := append(append(tmp[:0], v[:i]...), v[j:]...)
+			if !try(candidate) {
+				continue
+			}
+			v = append(v[:0], candidate...)
+			j = len(v) + 1
+		}
+	}
+
+	// Finally, try to make the value easier to read by replacing each
+	// unprintable byte with a printable character.
+	printableChars := []byte("012789ABCXYZabcxyz !\"#$%&'()*+,.")
+	for i, b := range v {
+		if ' ' <= b && b <= '~' {
+			continue
+		}
+		if shouldStop() {
+			return v
+		}
+		for _, pc := range printableChars {
+			v[i] = pc
+			if try(v) {
+				break
+			}
+			v[i] = b
+		}
+	}
+	return v
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMinimizeBytes(t *testing.T) {
+	never := func() bool { return false }
+	for _, tc := range []struct {
+		name     string
+		input    []byte
+		fails    func([]byte) bool
+		expected []byte
+	}{
+		{
+			name:  "one_byte",
+			input: []byte{0, 0, 1, 0, 0},
+			fails: func(b []byte) bool {
+				return bytes.IndexByte(b, 1) >= 0
+			},
+			expected: []byte{1},
+		},
+		{
+			name:  "two_bytes",
+			input: []byte{0, 1, 0, 0, 0, 0, 1, 0},
+			fails: func(b []byte) bool {
+				return bytes.Count(b, []byte{1}) >= 2
+			},
+			expected: []byte{1, 1},
+		},
+		{
+			name:  "long_prefix",
+			input: append(bytes.Repeat([]byte{'a'}, 5000), 'b', 'c'),
+			fails: func(b []byte) bool {
+				return bytes.HasSuffix(b, []byte("bc"))
+			},
+			expected: []byte("bc"),
+		},
+		{
+			name:  "length",
+			input: bytes.Repeat([]byte{0xff}, 100),
+			fails: func(b []byte) bool {
+				return len(b) >= 3
+			},
+			expected: []byte("000"),
+		},
+		{
+			name:  "printable",
+			input: []byte{0x80, 'a', 0x81},
+			fails: func(b []byte) bool {
+				return len(b) == 3 && b[1] == 'a'
+			},
+			expected: []byte("0a0"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			input := append([]byte(nil), tc.input...)
+			got := minimizeBytes(input, tc.fails, never)
+			if !bytes.Equal(got, tc.expected) {
+				t.Errorf("got %q, want %q", got, tc.expected)
+			}
+			if !bytes.Equal(input, tc.input) {
+				t.Errorf("minimizeBytes modified its input")
+			}
+		})
+	}
+}
+
+func TestMinimizeBytesStop(t *testing.T) {
+	input := bytes.Repeat([]byte{1}, 100)
+	calls := 0
+	got := minimizeBytes(input, func(b []byte) bool {
+		calls++
+		return true
+	}, func() bool { return calls >= 3 })
+	if calls != 3 {
+		t.Errorf("try called %d times after shouldStop returned true", calls)
+	}
+	if len(got) != 100-64-32-2 {
+		t.Errorf("got %d bytes after stopping; want the last accepted value", len(got))
+	}
+}
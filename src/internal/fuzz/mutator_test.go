@@ -0,0 +1,224 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func BenchmarkMutatorBytes(b *testing.B) {
+	origEnv := os.Getenv("GODEBUG")
+	defer func() { os.Setenv("GODEBUG", origEnv) }()
+	os.Setenv("GODEBUG", fmt.Sprintf("%s,fuzzseed=123", origEnv))
+	m := newMutator()
+
+	for _, size := range []int{
+		1,
+		10,
+		100,
+		1000,
+		10000,
+		100000,
+	} {
+		size := size
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			buf := make([]byte, size)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				// resize buffer to the correct shape and reset the PCG
+				buf = buf[0:size]
+				m.r = newPcgRand()
+				m.mutate([]interface{}{buf}, workerSharedMemSize)
+			}
+		})
+	}
+}
+
+func TestMutatorReplay(t *testing.T) {
+	// The coordinator relies on being able to reproduce a worker's chain of
+	// mutations from the saved PRNG state.
+	orig := []interface{}{[]byte("hello, world"), "abc", int(-12), uint16(4), 3.5, true, byte('x'), rune('y')}
+	copyVals := func() []interface{} {
+		vals := append([]interface{}(nil), orig...)
+		vals[0] = append([]byte(nil), orig[0].([]byte)...)
+		return vals
+	}
+
+	m := newMutator()
+	var state, inc uint64
+	for chain := 0; chain < 100; chain++ {
+		m.r.save(&state, &inc)
+		vals := copyVals()
+		for i := 0; i < chainedMutations; i++ {
+			m.mutate(vals, workerSharedMemSize)
+		}
+
+		replay := newMutator()
+		replay.r.restore(state, inc)
+		got := copyVals()
+		for i := 0; i < chainedMutations; i++ {
+			replay.mutate(got, workerSharedMemSize)
+		}
+		if !bytes.Equal(marshalCorpusFile(got...), marshalCorpusFile(vals...)) {
+			t.Fatalf("chain %d: replayed mutations differ:\ngot  %q\nwant %q", chain, marshalCorpusFile(got...), marshalCorpusFile(vals...))
+		}
+	}
+	if !bytes.Equal(marshalCorpusFile(copyVals()...), marshalCorpusFile(orig...)) {
+		t.Fatalf("mutate modified the original values")
+	}
+}
+
+func TestByteSliceMutators(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		mutator  func(*mutator, []byte) []byte
+		randArg  []int
+		input    []byte
+		expected []byte
+	}{
+		{
+			name:     "byteSliceRemoveBytes",
+			mutator:  byteSliceRemoveBytes,
+			input:    []byte{1, 2, 3, 4},
+			randArg:  []int{1, 0, 0},
+			expected: []byte{1, 3, 4},
+		},
+		{
+			name:     "byteSliceInsertRandomBytes",
+			mutator:  byteSliceInsertRandomBytes,
+			input:    make([]byte, 4, 8),
+			randArg:  []int{3, 0, 2, 10, 11, 12},
+			expected: []byte{0, 0, 0, 10, 11, 12, 0},
+		},
+		{
+			name:     "byteSliceDuplicateBytes",
+			mutator:  byteSliceDuplicateBytes,
+			input:    append(make([]byte, 0, 13), []byte{1, 2, 3, 4}...),
+			randArg:  []int{0, 2, 0, 1},
+			expected: []byte{1, 2, 1, 2, 3, 4},
+		},
+		{
+			name:     "byteSliceOverwriteBytes",
+			mutator:  byteSliceOverwriteBytes,
+			input:    []byte{1, 2, 3, 4},
+			randArg:  []int{0, 2, 0, 0},
+			expected: []byte{1, 2, 1, 4},
+		},
+		{
+			name:     "byteSliceBitFlip",
+			mutator:  byteSliceBitFlip,
+			input:    []byte{1, 2, 3, 4},
+			randArg:  []int{0, 0},
+			expected: []byte{0, 2, 3, 4},
+		},
+		{
+			name:     "byteSliceXORByte",
+			mutator:  byteSliceXORByte,
+			input:    []byte{1, 2, 3, 4},
+			randArg:  []int{0, 254},
+			expected: []byte{254, 2, 3, 4},
+		},
+		{
+			name:     "byteSliceSwapByte",
+			mutator:  byteSliceSwapByte,
+			input:    []byte{1, 2, 3, 4},
+			randArg:  []int{0, 3},
+			expected: []byte{4, 2, 3, 1},
+		},
+		{
+			name:     "byteSliceOverwriteConstantBytes",
+			mutator:  byteSliceOverwriteConstantBytes,
+			input:    []byte{1, 2, 3, 4},
+			randArg:  []int{1, 0, 1, 7},
+			expected: []byte{1, 7, 7, 4},
+		},
+		{
+			name:     "byteSliceShuffleBytes",
+			mutator:  byteSliceShuffleBytes,
+			input:    []byte{1, 2, 3, 4},
+			randArg:  []int{0, 0, 2, 0, 0},
+			expected: []byte{2, 3, 1, 4},
+		},
+		{
+			name:     "byteSliceSwapBytes",
+			mutator:  byteSliceSwapBytes,
+			input:    append(make([]byte, 0, 9), []byte{1, 2, 3, 4}...),
+			randArg:  []int{0, 2, 0, 0},
+			expected: []byte{3, 2, 1, 4},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &mutator{r: &mockRand{values: tc.randArg}}
+			b := tc.mutator(m, tc.input)
+			if !bytes.Equal(b, tc.expected) {
+				t.Errorf("got %x, want %x", b, tc.expected)
+			}
+		})
+	}
+}
+
+func TestByteSliceMutatorsShortInputs(t *testing.T) {
+	// None of the mutators may panic, whatever the input length.
+	m := newMutator()
+	for n := 0; n < 16; n++ {
+		for i, mut := range byteSliceMutators {
+			for j := 0; j < 100; j++ {
+				b := make([]byte, n, n+8)
+				if got := mut(m, b); got != nil && cap(got) > cap(b) {
+					t.Fatalf("mutator %d grew the slice past its capacity", i)
+				}
+			}
+		}
+	}
+}
+
+// mockRand is a mutatorRand that returns a fixed sequence of values.
+type mockRand struct {
+	values  []int
+	counter int
+	b       bool
+}
+
+func (r *mockRand) uint32() uint32 {
+	c := r.values[r.counter]
+	r.counter++
+	return uint32(c)
+}
+
+func (r *mockRand) intn(n int) int {
+	c := r.values[r.counter]
+	r.counter++
+	if c >= n {
+		panic(fmt.Sprintf("mockRand value %d out of range [0, %d)", c, n))
+	}
+	return c
+}
+
+func (r *mockRand) uint32n(m uint32) uint32 {
+	return uint32(r.intn(int(m)))
+}
+
+func (r *mockRand) exp2() int {
+	return r.intn(32)
+}
+
+func (r *mockRand) bool() bool {
+	// the mutators only use bool for choosing a byte order, so alternate
+	r.b = !r.b
+	return r.b
+}
+
+func (r *mockRand) save(*uint64, *uint64) {
+	panic("unimplemented")
+}
+
+func (r *mockRand) restore(uint64, uint64) {
+	panic("unimplemented")
+}
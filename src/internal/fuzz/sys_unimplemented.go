@@ -0,0 +1,47 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !freebsd && !linux
+// +build !darwin,!freebsd,!linux
+
+// TODO: fuzzing on other platforms needs a way to share memory with the
+// worker processes and to pass them extra file descriptors.
+
+package fuzz
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+var errUnsupported = errors.New("fuzzing is not supported on this platform")
+
+func sharedMemMapFile(f *os.File, size int, removeOnClose bool) (*sharedMem, error) {
+	return nil, errUnsupported
+}
+
+func (m *sharedMem) Close() error {
+	panic("not implemented")
+}
+
+func setWorkerComm(cmd *exec.Cmd, comm workerComm) {
+	panic("not implemented")
+}
+
+func getWorkerComm() (comm workerComm, err error) {
+	return workerComm{}, errUnsupported
+}
+
+func isInterruptError(err error) bool {
+	return false
+}
+
+func terminationSignal(err error) (os.Signal, bool) {
+	return nil, false
+}
+
+func isCrashSignal(signal os.Signal) bool {
+	panic("not implemented")
+}
@@ -5,6 +5,8 @@
 package unix
 
 const (
-	getrandomTrap     uintptr = 318
-	copyFileRangeTrap uintptr = 326
+	getrandomTrap       uintptr = 318
+	copyFileRangeTrap   uintptr = 326
+	pidfdSendSignalTrap uintptr = 424
+	pidfdOpenTrap       uintptr = 434
 )
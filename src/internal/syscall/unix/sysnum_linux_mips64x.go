@@ -8,6 +8,8 @@
 package unix
 
 const (
-	getrandomTrap     uintptr = 5313
-	copyFileRangeTrap uintptr = 5320
+	getrandomTrap       uintptr = 5313
+	copyFileRangeTrap   uintptr = 5320
+	pidfdSendSignalTrap uintptr = 5424
+	pidfdOpenTrap       uintptr = 5434
 )
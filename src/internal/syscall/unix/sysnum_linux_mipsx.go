@@ -8,6 +8,8 @@
 package unix
 
 const (
-	getrandomTrap     uintptr = 4353
-	copyFileRangeTrap uintptr = 4360
+	getrandomTrap       uintptr = 4353
+	copyFileRangeTrap   uintptr = 4360
+	pidfdSendSignalTrap uintptr = 4424
+	pidfdOpenTrap       uintptr = 4434
 )
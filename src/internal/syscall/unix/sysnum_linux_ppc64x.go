@@ -8,6 +8,8 @@
 package unix
 
 const (
-	getrandomTrap     uintptr = 359
-	copyFileRangeTrap uintptr = 379
+	getrandomTrap       uintptr = 359
+	copyFileRangeTrap   uintptr = 379
+	pidfdSendSignalTrap uintptr = 424
+	pidfdOpenTrap       uintptr = 434
 )
@@ -5,6 +5,8 @@
 package unix
 
 const (
-	getrandomTrap     uintptr = 349
-	copyFileRangeTrap uintptr = 375
+	getrandomTrap       uintptr = 349
+	copyFileRangeTrap   uintptr = 375
+	pidfdSendSignalTrap uintptr = 424
+	pidfdOpenTrap       uintptr = 434
 )
@@ -0,0 +1,138 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tlsquic contains the types of the TLS handshake of QUIC
+// connections (RFC 9001), which the crypto/tls package implements.
+// This package is purely internal for use by the net/http/internal/quic
+// package, which gets a Conn from crypto/tls with go:linkname, and has
+// no stable API exposed to end users.
+package tlsquic
+
+import (
+	"context"
+	"internal/itoa"
+)
+
+// An EncryptionLevel is a QUIC encryption level used to transmit
+// handshake messages.
+type EncryptionLevel int
+
+const (
+	EncryptionLevelInitial = EncryptionLevel(iota)
+	EncryptionLevelEarly
+	EncryptionLevelHandshake
+	EncryptionLevelApplication
+)
+
+func (l EncryptionLevel) String() string {
+	switch l {
+	case EncryptionLevelInitial:
+		return "Initial"
+	case EncryptionLevelEarly:
+		return "Early"
+	case EncryptionLevelHandshake:
+		return "Handshake"
+	case EncryptionLevelApplication:
+		return "Application"
+	default:
+		return "EncryptionLevel(" + itoa.Itoa(int(l)) + ")"
+	}
+}
+
+// An EventKind is a type of operation on a QUIC connection.
+type EventKind int
+
+const (
+	// NoEvent indicates that there are no events available.
+	NoEvent EventKind = iota
+
+	// SetReadSecret and SetWriteSecret provide the read and write
+	// secrets for a given encryption level.
+	// Event.Level, Event.Data, and Event.Suite are set.
+	//
+	// Secrets for the Initial encryption level are derived from the initial
+	// destination connection ID, and are not provided by the Conn.
+	SetReadSecret
+	SetWriteSecret
+
+	// WriteData provides data to send to the peer in CRYPTO frames.
+	// Event.Data is set.
+	WriteData
+
+	// TransportParameters provides the peer's QUIC transport parameters.
+	// Event.Data is set.
+	TransportParameters
+
+	// TransportParametersRequired indicates that the caller must provide
+	// QUIC transport parameters to send to the peer. The caller should set
+	// the transport parameters with Conn.SetTransportParameters and call
+	// Conn.NextEvent again.
+	//
+	// If transport parameters are set before calling Conn.Start, the
+	// connection will never generate a TransportParametersRequired event.
+	TransportParametersRequired
+
+	// HandshakeDone indicates that the TLS handshake has completed.
+	HandshakeDone
+)
+
+// An Event is an event occurring on a QUIC connection.
+//
+// The type of event is specified by the Kind field.
+// The contents of the other fields are kind-specific.
+type Event struct {
+	Kind EventKind
+
+	// Set for SetReadSecret, SetWriteSecret, and WriteData.
+	Level EncryptionLevel
+
+	// Set for TransportParameters, SetReadSecret, SetWriteSecret, and WriteData.
+	// The contents are owned by crypto/tls, and are valid until the next NextEvent call.
+	Data []byte
+
+	// Set for SetReadSecret and SetWriteSecret.
+	Suite uint16
+}
+
+// A Conn is the TLS handshake of a QUIC connection, which uses the QUIC
+// implementation as the underlying transport.
+//
+// Methods of Conn are not safe for concurrent use.
+type Conn interface {
+	// Start starts the client or server handshake protocol.
+	// It may produce connection events, which may be read with NextEvent.
+	//
+	// Start must be called at most once.
+	Start(ctx context.Context) error
+
+	// NextEvent returns the next event occurring on the connection.
+	// It returns an event with a Kind of NoEvent when no events are
+	// available.
+	NextEvent() Event
+
+	// HandleData handles handshake bytes received from the peer.
+	// It may produce connection events, which may be read with NextEvent.
+	HandleData(level EncryptionLevel, data []byte) error
+
+	// SetTransportParameters sets the transport parameters to send to
+	// the peer.
+	//
+	// Server connections may delay setting the transport parameters
+	// until after receiving the client's transport parameters.
+	// See TransportParametersRequired.
+	SetTransportParameters(params []byte)
+
+	// Close closes the connection and stops any in-progress handshake.
+	Close() error
+}
+
+// An AlertError is a TLS alert.
+//
+// The methods of Conn return an error which wraps an AlertError rather
+// than sending a TLS alert.
+type AlertError uint8
+
+func (e AlertError) Error() string {
+	return "tls: alert(" + itoa.Itoa(int(e)) + ")"
+}
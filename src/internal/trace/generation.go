@@ -0,0 +1,130 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+// A flight recorder restarts tracing periodically, so the trace it writes
+// is a sequence of generations, each a complete trace. Each generation starts
+// with events describing the goroutines alive when it started, as if they
+// had just been created, and its stack IDs and time stamps start over.
+// The parsers join the generations into one stream of events.
+
+// maxStackID returns the largest ID in stacks.
+func maxStackID(stacks map[uint64][]*Frame) uint64 {
+	var max uint64
+	for id := range stacks {
+		if id > max {
+			max = id
+		}
+	}
+	return max
+}
+
+// renumberStacks adds base to the stack IDs of ev.
+func renumberStacks(ev *Event, base uint64) {
+	if ev.StkID != 0 {
+		ev.StkID += base
+	}
+	if ev.Type == EvGoCreate && ev.Args[1] != 0 {
+		ev.Args[1] += base
+	}
+}
+
+// genStartLen returns the number of events at the start of the events
+// of a generation that describe the goroutines alive when it started.
+func genStartLen(events []*Event) int {
+	for i, ev := range events {
+		if !isGenStart(ev) {
+			return i
+		}
+	}
+	return len(events)
+}
+
+func isGenStart(ev *Event) bool {
+	switch ev.Type {
+	case EvGoCreate, EvGoWaiting, EvGoInSyscall:
+		return true
+	}
+	return false
+}
+
+// A genJoiner tracks the state of goroutines across generations,
+// to join a generation to the ones before it.
+type genJoiner struct {
+	gs map[uint64]gStatus // state of the live goroutines
+}
+
+func newGenJoiner() *genJoiner {
+	return &genJoiner{gs: make(map[uint64]gStatus)}
+}
+
+// update records the state of goroutines after ev.
+func (j *genJoiner) update(ev *Event) {
+	switch ev.Type {
+	case EvGoCreate, EvGoUnblock:
+		j.gs[ev.Args[0]] = gRunnable
+	case EvGoStart, EvGoStartLabel:
+		j.gs[ev.G] = gRunning
+	case EvGoEnd, EvGoStop:
+		delete(j.gs, ev.G)
+	case EvGoSched, EvGoPreempt, EvGoSysExit:
+		j.gs[ev.G] = gRunnable
+	case EvGoWaiting, EvGoInSyscall, EvGoSleep, EvGoBlock, EvGoBlockSend,
+		EvGoBlockRecv, EvGoBlockSelect, EvGoBlockSync, EvGoBlockCond,
+		EvGoBlockNet, EvGoSysBlock, EvGoBlockGC:
+		j.gs[ev.G] = gWaiting
+	}
+}
+
+// join rewrites the events describing the goroutines alive at the start
+// of a generation so that they continue from the state of the goroutines
+// at the end of the previous generations. The goroutines created
+// earlier are not created again; the events instead change the state
+// of those whose state changed while tracing restarted.
+func (j *genJoiner) join(start []*Event) []*Event {
+	waiting := make(map[uint64]bool)
+	for _, ev := range start {
+		if ev.Type == EvGoWaiting || ev.Type == EvGoInSyscall {
+			waiting[ev.G] = true
+		}
+	}
+	var events []*Event
+	for _, ev := range start {
+		switch ev.Type {
+		case EvGoCreate:
+			g := ev.Args[0]
+			prev, ok := j.gs[g]
+			switch {
+			case !ok:
+				// The goroutine is new.
+			case prev == gWaiting && !waiting[g]:
+				// The goroutine was unblocked.
+				ev.Type = EvGoUnblock
+				ev.Args = [3]uint64{g}
+			case prev == gRunning && !waiting[g]:
+				// The goroutine stopped running.
+				ev.Type = EvGoPreempt
+				ev.G = g
+				ev.Args = [3]uint64{}
+				ev.StkID = 0
+				ev.Stk = nil
+			default:
+				continue
+			}
+		case EvGoWaiting, EvGoInSyscall:
+			prev, ok := j.gs[ev.G]
+			switch {
+			case !ok:
+			case prev == gWaiting:
+				continue
+			case prev == gRunning:
+				// The goroutine blocked after it stopped running.
+				ev.Type = EvGoBlock
+			}
+		}
+		events = append(events, ev)
+	}
+	return events
+}
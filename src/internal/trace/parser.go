@@ -100,25 +100,14 @@ func Parse(r io.Reader, bin string) (ParseResult, error) {
 // parse parses, post-processes and verifies the trace. It returns the
 // trace version and the list of events.
 func parse(r io.Reader, bin string) (int, ParseResult, error) {
-	ver, rawEvents, strings, err := readTrace(r)
+	ver, gens, err := readTrace(r)
 	if err != nil {
 		return 0, ParseResult{}, err
 	}
-	events, stacks, err := parseEvents(ver, rawEvents, strings)
+	events, stacks, err := parseGenerations(ver, gens, true)
 	if err != nil {
 		return 0, ParseResult{}, err
 	}
-	events = removeFutile(events)
-	err = postProcessTrace(ver, events)
-	if err != nil {
-		return 0, ParseResult{}, err
-	}
-	// Attach stack traces.
-	for _, ev := range events {
-		if ev.StkID != 0 {
-			ev.Stk = stacks[ev.StkID]
-		}
-	}
 	if ver < 1007 && bin != "" {
 		if err := symbolize(events, bin); err != nil {
 			return 0, ParseResult{}, err
@@ -127,6 +116,61 @@ func parse(r io.Reader, bin string) (int, ParseResult, error) {
 	return ver, ParseResult{Events: events, Stacks: stacks}, nil
 }
 
+// parseGenerations transforms the raw events of each generation into
+// events, post-processing them if post is set, and joins the generations.
+func parseGenerations(ver int, gens []generation, post bool) (events []*Event, stacks map[uint64][]*Frame, err error) {
+	var j *genJoiner
+	if len(gens) > 1 {
+		j = newGenJoiner()
+	}
+	var start0 int64
+	for i, gen := range gens {
+		evs, stks, start, freq, err := parseEvents(ver, gen.events, gen.strings)
+		if err != nil {
+			return nil, nil, err
+		}
+		if post {
+			evs = removeFutile(evs)
+			if err := postProcessTrace(ver, evs); err != nil {
+				return nil, nil, err
+			}
+		}
+		if i == 0 {
+			start0 = start
+			stacks = stks
+		} else {
+			// Make the stack IDs and time stamps of the generation
+			// follow those of the previous ones.
+			base := maxStackID(stacks)
+			for id, stk := range stks {
+				stacks[id+base] = stk
+			}
+			offset := int64(float64(start-start0) * freq)
+			for _, ev := range evs {
+				ev.Ts += offset
+				renumberStacks(ev, base)
+			}
+		}
+		// Attach stack traces.
+		for _, ev := range evs {
+			if ev.StkID != 0 {
+				ev.Stk = stacks[ev.StkID]
+			}
+		}
+		if j != nil {
+			if i > 0 {
+				n := genStartLen(evs)
+				evs = append(j.join(evs[:n]), evs[n:]...)
+			}
+			for _, ev := range evs {
+				j.update(ev)
+			}
+		}
+		events = append(events, evs...)
+	}
+	return events, stacks, nil
+}
+
 // rawEvent is a helper type used during parsing.
 type rawEvent struct {
 	off   int
@@ -135,139 +179,189 @@ type rawEvent struct {
 	sargs []string
 }
 
+// A generation is a complete trace, with its own string and stack tables.
+// The trace written by a runtime/trace.FlightRecorder is a sequence of
+// generations, each starting with a header.
+type generation struct {
+	events  []rawEvent
+	strings map[uint64]string
+}
+
 // readTrace does wire-format parsing and verification.
 // It does not care about specific event types and argument meaning.
-func readTrace(r io.Reader) (ver int, events []rawEvent, strings map[uint64]string, err error) {
-	// Read and validate trace header.
-	var buf [16]byte
-	off, err := io.ReadFull(r, buf[:])
+func readTrace(r io.Reader) (ver int, gens []generation, err error) {
+	br := bufio.NewReader(r)
+	ver, err = readHeader(br)
 	if err != nil {
-		err = fmt.Errorf("failed to read header: read %v, err %v", off, err)
 		return
 	}
-	ver, err = parseHeader(buf[:])
+	off := 16
+
+	// Read events.
+	gen := generation{strings: make(map[uint64]string)}
+	for {
+		if atHeader(br) {
+			var v int
+			v, err = readHeader(br)
+			if err != nil {
+				return
+			}
+			if v != ver {
+				err = fmt.Errorf("generation at offset 0x%x has version %v, want %v", off, v, ver)
+				return
+			}
+			off += 16
+			gens = append(gens, gen)
+			gen = generation{strings: make(map[uint64]string)}
+			continue
+		}
+		var ev rawEvent
+		ev, off, err = readEvent(br, ver, off, gen.strings)
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			return
+		}
+		if ev.typ != EvString {
+			gen.events = append(gen.events, ev)
+		}
+	}
+	gens = append(gens, gen)
+	return
+}
+
+// readHeader reads and validates the trace header.
+func readHeader(r io.Reader) (int, error) {
+	var buf [16]byte
+	n, err := io.ReadFull(r, buf[:])
 	if err != nil {
-		return
+		return 0, fmt.Errorf("failed to read header: read %v, err %v", n, err)
+	}
+	ver, err := parseHeader(buf[:])
+	if err != nil {
+		return 0, err
 	}
 	switch ver {
 	case 1005, 1007, 1008, 1009, 1010, 1011:
 		// Note: When adding a new version, add canned traces
 		// from the old version to the test suite using mkcanned.bash.
-		break
-	default:
-		err = fmt.Errorf("unsupported trace file version %v.%v (update Go toolchain) %v", ver/1000, ver%1000, ver)
-		return
+		return ver, nil
 	}
+	return 0, fmt.Errorf("unsupported trace file version %v.%v (update Go toolchain) %v", ver/1000, ver%1000, ver)
+}
 
-	// Read events.
-	strings = make(map[uint64]string)
-	for {
-		// Read event type and number of arguments (1 byte).
-		off0 := off
-		var n int
-		n, err = r.Read(buf[:1])
-		if err == io.EOF {
-			err = nil
-			break
-		}
-		if err != nil || n != 1 {
-			err = fmt.Errorf("failed to read trace at offset 0x%x: n=%v err=%v", off0, n, err)
+// atHeader reports whether r is at the header of a generation.
+// No event can start with a header: the first byte of a header
+// would be an EvGoUnblockLocal event with too few arguments.
+func atHeader(r *bufio.Reader) bool {
+	buf, err := r.Peek(16)
+	if err != nil || buf[0] != 'g' {
+		return false
+	}
+	_, err = parseHeader(buf)
+	return err == nil
+}
+
+// readEvent reads the next event from r, which is at offset off in the trace.
+// String dictionary entries are added to strings, or skipped if strings is nil,
+// and returned with no arguments. readEvent returns io.EOF at the end of r.
+func readEvent(r io.Reader, ver, off int, strings map[uint64]string) (ev rawEvent, off1 int, err error) {
+	// Read event type and number of arguments (1 byte).
+	off0 := off
+	var buf [1]byte
+	n, err := r.Read(buf[:])
+	if err == io.EOF {
+		return rawEvent{}, off, err
+	}
+	if err != nil || n != 1 {
+		return rawEvent{}, off, fmt.Errorf("failed to read trace at offset 0x%x: n=%v err=%v", off0, n, err)
+	}
+	off += n
+	typ := buf[0] << 2 >> 2
+	narg := buf[0]>>6 + 1
+	inlineArgs := byte(4)
+	if ver < 1007 {
+		narg++
+		inlineArgs++
+	}
+	if typ == EvNone || typ >= EvCount || EventDescriptions[typ].minVersion > ver {
+		return rawEvent{}, off, fmt.Errorf("unknown event type %v at offset 0x%x", typ, off0)
+	}
+	ev = rawEvent{typ: typ, off: off0}
+	if typ == EvString {
+		// String dictionary entry [ID, length, string].
+		var id uint64
+		id, off, err = readVal(r, off)
+		if err != nil {
 			return
 		}
-		off += n
-		typ := buf[0] << 2 >> 2
-		narg := buf[0]>>6 + 1
-		inlineArgs := byte(4)
-		if ver < 1007 {
-			narg++
-			inlineArgs++
+		if id == 0 {
+			return ev, off, fmt.Errorf("string at offset %d has invalid id 0", off)
 		}
-		if typ == EvNone || typ >= EvCount || EventDescriptions[typ].minVersion > ver {
-			err = fmt.Errorf("unknown event type %v at offset 0x%x", typ, off0)
+		if strings != nil && strings[id] != "" {
+			return ev, off, fmt.Errorf("string at offset %d has duplicate id %v", off, id)
+		}
+		var ln uint64
+		ln, off, err = readVal(r, off)
+		if err != nil {
 			return
 		}
-		if typ == EvString {
-			// String dictionary entry [ID, length, string].
-			var id uint64
-			id, off, err = readVal(r, off)
-			if err != nil {
-				return
-			}
-			if id == 0 {
-				err = fmt.Errorf("string at offset %d has invalid id 0", off)
-				return
-			}
-			if strings[id] != "" {
-				err = fmt.Errorf("string at offset %d has duplicate id %v", off, id)
-				return
-			}
-			var ln uint64
-			ln, off, err = readVal(r, off)
-			if err != nil {
-				return
-			}
-			if ln == 0 {
-				err = fmt.Errorf("string at offset %d has invalid length 0", off)
-				return
-			}
-			if ln > 1e6 {
-				err = fmt.Errorf("string at offset %d has too large length %v", off, ln)
-				return
-			}
-			buf := make([]byte, ln)
-			var n int
-			n, err = io.ReadFull(r, buf)
-			if err != nil {
-				err = fmt.Errorf("failed to read trace at offset %d: read %v, want %v, error %v", off, n, ln, err)
-				return
-			}
-			off += n
+		if ln == 0 {
+			return ev, off, fmt.Errorf("string at offset %d has invalid length 0", off)
+		}
+		if ln > 1e6 {
+			return ev, off, fmt.Errorf("string at offset %d has too large length %v", off, ln)
+		}
+		buf := make([]byte, ln)
+		var n int
+		n, err = io.ReadFull(r, buf)
+		if err != nil {
+			return ev, off, fmt.Errorf("failed to read trace at offset %d: read %v, want %v, error %v", off, n, ln, err)
+		}
+		off += n
+		if strings != nil {
 			strings[id] = string(buf)
-			continue
 		}
-		ev := rawEvent{typ: typ, off: off0}
-		if narg < inlineArgs {
-			for i := 0; i < int(narg); i++ {
-				var v uint64
-				v, off, err = readVal(r, off)
-				if err != nil {
-					err = fmt.Errorf("failed to read event %v argument at offset %v (%v)", typ, off, err)
-					return
-				}
-				ev.args = append(ev.args, v)
-			}
-		} else {
-			// More than inlineArgs args, the first value is length of the event in bytes.
+		return ev, off, nil
+	}
+	if narg < inlineArgs {
+		for i := 0; i < int(narg); i++ {
 			var v uint64
 			v, off, err = readVal(r, off)
 			if err != nil {
-				err = fmt.Errorf("failed to read event %v argument at offset %v (%v)", typ, off, err)
-				return
-			}
-			evLen := v
-			off1 := off
-			for evLen > uint64(off-off1) {
-				v, off, err = readVal(r, off)
-				if err != nil {
-					err = fmt.Errorf("failed to read event %v argument at offset %v (%v)", typ, off, err)
-					return
-				}
-				ev.args = append(ev.args, v)
+				return ev, off, fmt.Errorf("failed to read event %v argument at offset %v (%v)", typ, off, err)
 			}
-			if evLen != uint64(off-off1) {
-				err = fmt.Errorf("event has wrong length at offset 0x%x: want %v, got %v", off0, evLen, off-off1)
-				return
+			ev.args = append(ev.args, v)
+		}
+	} else {
+		// More than inlineArgs args, the first value is length of the event in bytes.
+		var v uint64
+		v, off, err = readVal(r, off)
+		if err != nil {
+			return ev, off, fmt.Errorf("failed to read event %v argument at offset %v (%v)", typ, off, err)
+		}
+		evLen := v
+		off1 := off
+		for evLen > uint64(off-off1) {
+			v, off, err = readVal(r, off)
+			if err != nil {
+				return ev, off, fmt.Errorf("failed to read event %v argument at offset %v (%v)", typ, off, err)
 			}
+			ev.args = append(ev.args, v)
 		}
-		switch ev.typ {
-		case EvUserLog: // EvUserLog records are followed by a value string of length ev.args[len(ev.args)-1]
-			var s string
-			s, off, err = readStr(r, off)
-			ev.sargs = append(ev.sargs, s)
+		if evLen != uint64(off-off1) {
+			return ev, off, fmt.Errorf("event has wrong length at offset 0x%x: want %v, got %v", off0, evLen, off-off1)
 		}
-		events = append(events, ev)
 	}
-	return
+	switch ev.typ {
+	case EvUserLog: // EvUserLog records are followed by a value string of length ev.args[len(ev.args)-1]
+		var s string
+		s, off, err = readStr(r, off)
+		ev.sargs = append(ev.sargs, s)
+	}
+	return ev, off, err
 }
 
 func readStr(r io.Reader, off0 int) (s string, off int, err error) {
@@ -313,37 +407,28 @@ func parseHeader(buf []byte) (int, error) {
 
 // Parse events transforms raw events into events.
 // It does analyze and verify per-event-type arguments.
-func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (events []*Event, stacks map[uint64][]*Frame, err error) {
-	var ticksPerSec, lastSeq, lastTs int64
-	var lastG uint64
+//
+// The time stamps of the events are in nanoseconds since the first event.
+// parseEvents also returns the time stamp of the first event in ticks,
+// and the number of nanoseconds per tick.
+func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (events []*Event, stacks map[uint64][]*Frame, start int64, freq float64, err error) {
+	var ticksPerSec int64
+	var ps procState // state of lastP
 	var lastP int
 	timerGoids := make(map[uint64]bool)
 	lastGs := make(map[int]uint64) // last goroutine running on P
 	stacks = make(map[uint64][]*Frame)
 	batches := make(map[int][]*Event) // events by P
 	for _, raw := range rawEvents {
-		desc := EventDescriptions[raw.typ]
-		if desc.Name == "" {
-			err = fmt.Errorf("missing description for event type %v", raw.typ)
-			return
-		}
-		narg := argNum(raw, ver)
-		if len(raw.args) != narg {
-			err = fmt.Errorf("%v has wrong number of arguments at offset 0x%x: want %v, got %v",
-				desc.Name, raw.off, narg, len(raw.args))
+		if err = checkArgs(ver, raw); err != nil {
 			return
 		}
 		switch raw.typ {
 		case EvBatch:
-			lastGs[lastP] = lastG
+			lastGs[lastP] = ps.g
 			lastP = int(raw.args[0])
-			lastG = lastGs[lastP]
-			if ver < 1007 {
-				lastSeq = int64(raw.args[1])
-				lastTs = int64(raw.args[2])
-			} else {
-				lastTs = int64(raw.args[1])
-			}
+			ps.g = lastGs[lastP]
+			ps.batch(ver, raw)
 		case EvFrequency:
 			ticksPerSec = int64(raw.args[0])
 			if ticksPerSec <= 0 {
@@ -356,99 +441,22 @@ func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (even
 		case EvTimerGoroutine:
 			timerGoids[raw.args[0]] = true
 		case EvStack:
-			if len(raw.args) < 2 {
-				err = fmt.Errorf("EvStack has wrong number of arguments at offset 0x%x: want at least 2, got %v",
-					raw.off, len(raw.args))
-				return
-			}
-			size := raw.args[1]
-			if size > 1000 {
-				err = fmt.Errorf("EvStack has bad number of frames at offset 0x%x: %v",
-					raw.off, size)
-				return
-			}
-			want := 2 + 4*size
-			if ver < 1007 {
-				want = 2 + size
-			}
-			if uint64(len(raw.args)) != want {
-				err = fmt.Errorf("EvStack has wrong number of arguments at offset 0x%x: want %v, got %v",
-					raw.off, want, len(raw.args))
+			var id uint64
+			var stk []*Frame
+			id, stk, err = parseStack(ver, raw, strings)
+			if err != nil {
 				return
 			}
-			id := raw.args[0]
-			if id != 0 && size > 0 {
-				stk := make([]*Frame, size)
-				for i := 0; i < int(size); i++ {
-					if ver < 1007 {
-						stk[i] = &Frame{PC: raw.args[2+i]}
-					} else {
-						pc := raw.args[2+i*4+0]
-						fn := raw.args[2+i*4+1]
-						file := raw.args[2+i*4+2]
-						line := raw.args[2+i*4+3]
-						stk[i] = &Frame{PC: pc, Fn: strings[fn], File: strings[file], Line: int(line)}
-					}
-				}
+			if stk != nil {
 				stacks[id] = stk
 			}
 		default:
-			e := &Event{Off: raw.off, Type: raw.typ, P: lastP, G: lastG}
-			var argOffset int
-			if ver < 1007 {
-				e.seq = lastSeq + int64(raw.args[0])
-				e.Ts = lastTs + int64(raw.args[1])
-				lastSeq = e.seq
-				argOffset = 2
-			} else {
-				e.Ts = lastTs + int64(raw.args[0])
-				argOffset = 1
-			}
-			lastTs = e.Ts
-			for i := argOffset; i < narg; i++ {
-				if i == narg-1 && desc.Stack {
-					e.StkID = raw.args[i]
-				} else {
-					e.Args[i-argOffset] = raw.args[i]
-				}
-			}
-			switch raw.typ {
-			case EvGoStart, EvGoStartLocal, EvGoStartLabel:
-				lastG = e.Args[0]
-				e.G = lastG
-				if raw.typ == EvGoStartLabel {
-					e.SArgs = []string{strings[e.Args[2]]}
-				}
-			case EvGCSTWStart:
-				e.G = 0
-				switch e.Args[0] {
-				case 0:
-					e.SArgs = []string{"mark termination"}
-				case 1:
-					e.SArgs = []string{"sweep termination"}
-				default:
-					err = fmt.Errorf("unknown STW kind %d", e.Args[0])
-					return
-				}
-			case EvGCStart, EvGCDone, EvGCSTWDone:
-				e.G = 0
-			case EvGoEnd, EvGoStop, EvGoSched, EvGoPreempt,
-				EvGoSleep, EvGoBlock, EvGoBlockSend, EvGoBlockRecv,
-				EvGoBlockSelect, EvGoBlockSync, EvGoBlockCond, EvGoBlockNet,
-				EvGoSysBlock, EvGoBlockGC:
-				lastG = 0
-			case EvGoSysExit, EvGoWaiting, EvGoInSyscall:
-				e.G = e.Args[0]
-			case EvUserTaskCreate:
-				// e.Args 0: taskID, 1:parentID, 2:nameID
-				e.SArgs = []string{strings[e.Args[2]]}
-			case EvUserRegion:
-				// e.Args 0: taskID, 1: mode, 2:nameID
-				e.SArgs = []string{strings[e.Args[2]]}
-			case EvUserLog:
-				// e.Args 0: taskID, 1:keyID, 2: stackID
-				e.SArgs = []string{strings[e.Args[1]], raw.sargs[0]}
+			var e *Event
+			e, err = ps.event(ver, raw, strings)
+			if err != nil {
+				return
 			}
+			e.P = lastP
 			batches[lastP] = append(batches[lastP], e)
 		}
 	}
@@ -480,11 +488,11 @@ func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (even
 	}
 
 	// Translate cpu ticks to real time.
-	minTs := events[0].Ts
+	start = events[0].Ts
 	// Use floating point to avoid integer overflows.
-	freq := 1e9 / float64(ticksPerSec)
+	freq = 1e9 / float64(ticksPerSec)
 	for _, ev := range events {
-		ev.Ts = int64(float64(ev.Ts-minTs) * freq)
+		ev.Ts = int64(float64(ev.Ts-start) * freq)
 		// Move timers and syscalls to separate fake Ps.
 		if timerGoids[ev.G] && ev.Type == EvGoUnblock {
 			ev.P = TimerP
@@ -497,6 +505,139 @@ func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (even
 	return
 }
 
+// checkArgs verifies that raw has the arguments its type requires.
+func checkArgs(ver int, raw rawEvent) error {
+	desc := EventDescriptions[raw.typ]
+	if desc.Name == "" {
+		return fmt.Errorf("missing description for event type %v", raw.typ)
+	}
+	narg := argNum(raw, ver)
+	if len(raw.args) != narg {
+		return fmt.Errorf("%v has wrong number of arguments at offset 0x%x: want %v, got %v",
+			desc.Name, raw.off, narg, len(raw.args))
+	}
+	return nil
+}
+
+// procState is the state of a P needed to decode the events in its batches.
+type procState struct {
+	g   uint64 // goroutine running on the P
+	ts  int64  // time stamp of the last event
+	seq int64  // sequence number of the last event (before 1.7)
+}
+
+// batch resets the time stamp and sequence number at the start of a batch.
+func (ps *procState) batch(ver int, raw rawEvent) {
+	if ver < 1007 {
+		ps.seq = int64(raw.args[1])
+		ps.ts = int64(raw.args[2])
+	} else {
+		ps.ts = int64(raw.args[1])
+	}
+}
+
+// event transforms raw, the next event on the P, into an Event.
+// The time stamp of the event is in ticks and its P is not set.
+func (ps *procState) event(ver int, raw rawEvent, strings map[uint64]string) (*Event, error) {
+	desc := EventDescriptions[raw.typ]
+	narg := argNum(raw, ver)
+	e := &Event{Off: raw.off, Type: raw.typ, G: ps.g}
+	var argOffset int
+	if ver < 1007 {
+		e.seq = ps.seq + int64(raw.args[0])
+		e.Ts = ps.ts + int64(raw.args[1])
+		ps.seq = e.seq
+		argOffset = 2
+	} else {
+		e.Ts = ps.ts + int64(raw.args[0])
+		argOffset = 1
+	}
+	ps.ts = e.Ts
+	for i := argOffset; i < narg; i++ {
+		if i == narg-1 && desc.Stack {
+			e.StkID = raw.args[i]
+		} else {
+			e.Args[i-argOffset] = raw.args[i]
+		}
+	}
+	switch raw.typ {
+	case EvGoStart, EvGoStartLocal, EvGoStartLabel:
+		ps.g = e.Args[0]
+		e.G = ps.g
+		if raw.typ == EvGoStartLabel {
+			e.SArgs = []string{strings[e.Args[2]]}
+		}
+	case EvGCSTWStart:
+		e.G = 0
+		switch e.Args[0] {
+		case 0:
+			e.SArgs = []string{"mark termination"}
+		case 1:
+			e.SArgs = []string{"sweep termination"}
+		default:
+			return nil, fmt.Errorf("unknown STW kind %d", e.Args[0])
+		}
+	case EvGCStart, EvGCDone, EvGCSTWDone:
+		e.G = 0
+	case EvGoEnd, EvGoStop, EvGoSched, EvGoPreempt,
+		EvGoSleep, EvGoBlock, EvGoBlockSend, EvGoBlockRecv,
+		EvGoBlockSelect, EvGoBlockSync, EvGoBlockCond, EvGoBlockNet,
+		EvGoSysBlock, EvGoBlockGC:
+		ps.g = 0
+	case EvGoSysExit, EvGoWaiting, EvGoInSyscall:
+		e.G = e.Args[0]
+	case EvUserTaskCreate:
+		// e.Args 0: taskID, 1:parentID, 2:nameID
+		e.SArgs = []string{strings[e.Args[2]]}
+	case EvUserRegion:
+		// e.Args 0: taskID, 1: mode, 2:nameID
+		e.SArgs = []string{strings[e.Args[2]]}
+	case EvUserLog:
+		// e.Args 0: taskID, 1:keyID, 2: stackID
+		e.SArgs = []string{strings[e.Args[1]], raw.sargs[0]}
+	}
+	return e, nil
+}
+
+// parseStack parses an EvStack event. It returns a nil stack
+// for the empty stacks, which are not recorded.
+func parseStack(ver int, raw rawEvent, strings map[uint64]string) (id uint64, stk []*Frame, err error) {
+	if len(raw.args) < 2 {
+		return 0, nil, fmt.Errorf("EvStack has wrong number of arguments at offset 0x%x: want at least 2, got %v",
+			raw.off, len(raw.args))
+	}
+	size := raw.args[1]
+	if size > 1000 {
+		return 0, nil, fmt.Errorf("EvStack has bad number of frames at offset 0x%x: %v",
+			raw.off, size)
+	}
+	want := 2 + 4*size
+	if ver < 1007 {
+		want = 2 + size
+	}
+	if uint64(len(raw.args)) != want {
+		return 0, nil, fmt.Errorf("EvStack has wrong number of arguments at offset 0x%x: want %v, got %v",
+			raw.off, want, len(raw.args))
+	}
+	id = raw.args[0]
+	if id == 0 || size == 0 {
+		return id, nil, nil
+	}
+	stk = make([]*Frame, size)
+	for i := 0; i < int(size); i++ {
+		if ver < 1007 {
+			stk[i] = &Frame{PC: raw.args[2+i]}
+		} else {
+			pc := raw.args[2+i*4+0]
+			fn := raw.args[2+i*4+1]
+			file := raw.args[2+i*4+2]
+			line := raw.args[2+i*4+3]
+			stk[i] = &Frame{PC: pc, Fn: strings[fn], File: strings[file], Line: int(line)}
+		}
+	}
+	return id, stk, nil
+}
+
 // removeFutile removes all constituents of futile wakeups (block, unblock, start).
 // For example, a goroutine was unblocked on a mutex, but another goroutine got
 // ahead and acquired the mutex before the first goroutine is scheduled,
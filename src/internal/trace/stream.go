@@ -0,0 +1,417 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// A StreamReader reads the events of a trace one at a time, in order.
+// Unlike Parse, it does not hold the events in memory: it only keeps
+// the string and stack tables, the position of the event batches of
+// each P in the trace, and the state of each goroutine.
+//
+// The events are ordered the way Parse orders them, except that
+// the time stamp of an EvGoSysExit event is the time the syscall
+// returned, which may be earlier than the time stamps of the events
+// preceding it. The events are not post-processed: futile wakeups
+// are not removed, events are not linked, and the trace is not
+// verified beyond what ordering the events requires.
+type StreamReader struct {
+	ver    int
+	stacks map[uint64][]*Frame
+	gens   []*streamGen // generations not read yet
+	ngen   int          // number of generations read
+	join   *genJoiner   // nil if the trace has one generation
+	queue  []*Event     // events to return
+}
+
+// A streamGen reads the events of a generation.
+type streamGen struct {
+	strings   map[uint64]string
+	timerGs   map[uint64]bool
+	procs     []*streamProc
+	started   bool // the first event of each P has been read
+	gs        map[uint64]gState
+	frontier  []orderEvent
+	freq      float64          // nanoseconds per tick
+	minTs     int64            // time stamp of the first event, in ticks
+	offset    int64            // time of the first event since the start of the trace
+	stackBase uint64           // added to the stack IDs of the generation
+	lastTs    int64            // time stamp of the last event, in ticks
+	lastBlock map[uint64]int64 // time stamp of the last syscall block of goroutines, in ticks
+}
+
+// A streamProc reads the events of one P.
+type streamProc struct {
+	p        int
+	r        io.ReaderAt
+	batches  []batchRange // remaining batches
+	br       *bufio.Reader
+	off      int // offset of br in the trace
+	end      int // end of the current batch
+	state    procState
+	ev       *Event // next event, or nil at the end of the batches
+	selected bool   // ev is in the frontier
+}
+
+// A batchRange is the position of a batch in the trace.
+type batchRange struct {
+	off, end int
+}
+
+// NewStreamReader returns a StreamReader reading the trace in r,
+// which is size bytes long. It reads the entire trace once to
+// collect the string and stack tables and locate the event batches.
+// Only traces produced by Go 1.7 or later can be read.
+func NewStreamReader(r io.ReaderAt, size int64) (*StreamReader, error) {
+	br := bufio.NewReader(io.NewSectionReader(r, 0, size))
+	ver, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	if ver < 1007 {
+		return nil, fmt.Errorf("unsupported trace file version %v.%v %v", ver/1000, ver%1000, ver)
+	}
+	s := &StreamReader{
+		ver:    ver,
+		stacks: make(map[uint64][]*Frame),
+	}
+	off := 16
+	for {
+		var g *streamGen
+		g, off, err = s.readGen(r, br, off)
+		if err != nil {
+			return nil, err
+		}
+		s.gens = append(s.gens, g)
+		if !atHeader(br) {
+			break
+		}
+		v, err := readHeader(br)
+		if err != nil {
+			return nil, err
+		}
+		if v != ver {
+			return nil, fmt.Errorf("generation at offset 0x%x has version %v, want %v", off, v, ver)
+		}
+		off += 16
+	}
+	for _, g := range s.gens {
+		g.offset = int64(float64(g.minTs-s.gens[0].minTs) * g.freq)
+	}
+	if len(s.gens) > 1 {
+		s.join = newGenJoiner()
+	}
+	return s, nil
+}
+
+// readGen reads the generation at offset off in r, up to the end of the
+// trace or the header of the next generation. br reads r from off.
+func (s *StreamReader) readGen(r io.ReaderAt, br *bufio.Reader, off int) (*streamGen, int, error) {
+	g := &streamGen{
+		strings:   make(map[uint64]string),
+		timerGs:   make(map[uint64]bool),
+		gs:        make(map[uint64]gState),
+		lastBlock: make(map[uint64]int64),
+		minTs:     -1,
+		stackBase: maxStackID(s.stacks),
+	}
+	procs := make(map[int]*streamProc)
+	var (
+		cur       *streamProc // P of the current batch
+		batch     batchRange
+		hasEvents bool // current batch has events to order
+		ps        procState
+		ticks     int64
+		rawStacks []rawEvent
+	)
+	endBatch := func(end int) {
+		if cur != nil && hasEvents {
+			batch.end = end
+			cur.batches = append(cur.batches, batch)
+		}
+	}
+	for !atHeader(br) {
+		off0 := off
+		raw, off1, err := readEvent(br, s.ver, off, g.strings)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		off = off1
+		if raw.typ == EvString {
+			continue
+		}
+		if err := checkArgs(s.ver, raw); err != nil {
+			return nil, 0, err
+		}
+		switch raw.typ {
+		case EvBatch:
+			endBatch(off0)
+			p := int(raw.args[0])
+			cur = procs[p]
+			if cur == nil {
+				cur = &streamProc{p: p, r: r}
+				procs[p] = cur
+			}
+			batch = batchRange{off: off0}
+			hasEvents = false
+			ps.batch(s.ver, raw)
+		case EvFrequency:
+			ticks = int64(raw.args[0])
+			if ticks <= 0 {
+				// See parseEvents.
+				return nil, 0, ErrTimeOrder
+			}
+		case EvTimerGoroutine:
+			g.timerGs[raw.args[0]] = true
+		case EvStack:
+			// The strings of the frames may follow the stack.
+			rawStacks = append(rawStacks, raw)
+		default:
+			if cur == nil {
+				return nil, 0, fmt.Errorf("event at offset 0x%x is not in a batch", raw.off)
+			}
+			hasEvents = true
+			ts := ps.ts + int64(raw.args[0])
+			ps.ts = ts
+			if g.minTs < 0 || ts < g.minTs {
+				g.minTs = ts
+			}
+		}
+	}
+	endBatch(off)
+	if g.minTs < 0 {
+		return nil, 0, fmt.Errorf("trace is empty")
+	}
+	if ticks == 0 {
+		return nil, 0, fmt.Errorf("no EvFrequency event")
+	}
+	g.freq = 1e9 / float64(ticks)
+	g.lastTs = g.minTs
+	for _, raw := range rawStacks {
+		id, stk, err := parseStack(s.ver, raw, g.strings)
+		if err != nil {
+			return nil, 0, err
+		}
+		if stk != nil {
+			s.stacks[id+g.stackBase] = stk
+		}
+	}
+	for _, p := range procs {
+		if len(p.batches) > 0 {
+			g.procs = append(g.procs, p)
+		}
+	}
+	return g, off, nil
+}
+
+// Version returns the version of the trace format, such as 1011 for Go 1.11.
+func (s *StreamReader) Version() int {
+	return s.ver
+}
+
+// Stacks returns the stack traces keyed by stack IDs from the trace.
+func (s *StreamReader) Stacks() map[uint64][]*Frame {
+	return s.stacks
+}
+
+// Next returns the next event in the trace.
+// At the end of the trace, it returns nil, io.EOF.
+func (s *StreamReader) Next() (*Event, error) {
+	for len(s.queue) == 0 {
+		if err := s.fill(); err != nil {
+			return nil, err
+		}
+	}
+	ev := s.queue[0]
+	s.queue = s.queue[1:]
+	if s.join != nil {
+		s.join.update(ev)
+	}
+	return ev, nil
+}
+
+// fill reads the next events into s.queue.
+func (s *StreamReader) fill() error {
+	if len(s.gens) == 0 {
+		return io.EOF
+	}
+	g := s.gens[0]
+	first := !g.started
+	ev, err := g.next(s)
+	if err == io.EOF {
+		s.gens = s.gens[1:]
+		s.ngen++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !first || s.ngen == 0 {
+		s.queue = append(s.queue, ev)
+		return nil
+	}
+	// Join the generation to the previous ones, see parseGenerations.
+	var start []*Event
+	for ev != nil && isGenStart(ev) {
+		start = append(start, ev)
+		ev, err = g.next(s)
+		if err == io.EOF {
+			ev = nil
+		} else if err != nil {
+			return err
+		}
+	}
+	s.queue = append(s.queue, s.join.join(start)...)
+	if ev != nil {
+		s.queue = append(s.queue, ev)
+	}
+	return nil
+}
+
+// next returns the next event of the generation.
+func (g *streamGen) next(s *StreamReader) (*Event, error) {
+	if !g.started {
+		g.started = true
+		for _, p := range g.procs {
+			if err := p.advance(s.ver, g.strings); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// See order1007.
+	for i, p := range g.procs {
+		if p.selected || p.ev == nil {
+			continue
+		}
+		ev := p.ev
+		gid, init, next := stateTransition(ev)
+		if !transitionReady(gid, g.gs[gid], init) {
+			continue
+		}
+		g.frontier = append(g.frontier, orderEvent{ev, i, gid, init, next})
+		p.selected = true
+		// Get rid of "Local" events, they are intended merely for ordering.
+		switch ev.Type {
+		case EvGoStartLocal:
+			ev.Type = EvGoStart
+		case EvGoUnblockLocal:
+			ev.Type = EvGoUnblock
+		case EvGoSysExitLocal:
+			ev.Type = EvGoSysExit
+		}
+	}
+	if len(g.frontier) == 0 {
+		for _, p := range g.procs {
+			if p.ev != nil {
+				return nil, fmt.Errorf("no consistent ordering of events possible")
+			}
+		}
+		return nil, io.EOF
+	}
+	min := 0
+	for i, f := range g.frontier {
+		if f.ev.Ts < g.frontier[min].ev.Ts {
+			min = i
+		}
+	}
+	f := g.frontier[min]
+	g.frontier[min] = g.frontier[len(g.frontier)-1]
+	g.frontier = g.frontier[:len(g.frontier)-1]
+	transition(g.gs, f.g, f.init, f.next)
+	p := g.procs[f.batch]
+	p.selected = false
+	if err := p.advance(s.ver, g.strings); err != nil {
+		return nil, err
+	}
+
+	ev := f.ev
+	if ev.Ts < g.lastTs {
+		return nil, ErrTimeOrder
+	}
+	g.lastTs = ev.Ts
+	switch ev.Type {
+	case EvGoSysBlock, EvGoInSyscall:
+		g.lastBlock[ev.G] = ev.Ts
+	case EvGoSysExit:
+		// Give the event the real time stamp of the syscall exit,
+		// see order1007.
+		if ts := int64(ev.Args[2]); ts != 0 {
+			block, ok := g.lastBlock[ev.G]
+			if !ok {
+				return nil, fmt.Errorf("stray syscall exit")
+			}
+			if ts < block {
+				return nil, ErrTimeOrder
+			}
+			ev.Ts = ts
+		}
+		delete(g.lastBlock, ev.G)
+	}
+
+	// Translate cpu ticks to real time, see parseEvents.
+	ev.Ts = int64(float64(ev.Ts-g.minTs)*g.freq) + g.offset
+	if g.timerGs[ev.G] && ev.Type == EvGoUnblock {
+		ev.P = TimerP
+	}
+	if ev.Type == EvGoSysExit {
+		ev.P = SyscallP
+	}
+	renumberStacks(ev, g.stackBase)
+	if ev.StkID != 0 {
+		ev.Stk = s.stacks[ev.StkID]
+	}
+	return ev, nil
+}
+
+// advance reads the next event of p into p.ev,
+// which is nil once all of p's batches have been read.
+func (p *streamProc) advance(ver int, strings map[uint64]string) error {
+	for {
+		if p.br == nil || p.off >= p.end {
+			if len(p.batches) == 0 {
+				p.ev = nil
+				return nil
+			}
+			b := p.batches[0]
+			p.batches = p.batches[1:]
+			sr := io.NewSectionReader(p.r, int64(b.off), int64(b.end-b.off))
+			if p.br == nil {
+				p.br = bufio.NewReader(sr)
+			} else {
+				p.br.Reset(sr)
+			}
+			p.off, p.end = b.off, b.end
+		}
+		var raw rawEvent
+		var err error
+		raw, p.off, err = readEvent(p.br, ver, p.off, nil)
+		if err == io.EOF {
+			return fmt.Errorf("unexpected end of batch at offset 0x%x", p.off)
+		}
+		if err != nil {
+			return err
+		}
+		switch raw.typ {
+		case EvBatch:
+			p.state.batch(ver, raw)
+		case EvString, EvFrequency, EvTimerGoroutine, EvStack:
+		default:
+			ev, err := p.state.event(ver, raw, strings)
+			if err != nil {
+				return err
+			}
+			ev.P = p.p
+			p.ev = ev
+			return nil
+		}
+	}
+}
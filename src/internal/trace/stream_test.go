@@ -0,0 +1,146 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	rtrace "runtime/trace"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamCorruptedInputs(t *testing.T) {
+	tests := []string{
+		"gotrace\x00\x020",
+		"go 1.5 trace\x00\x00\x00\x00\x020",
+		"go 1.7 trace\x00\x00\x00\x00",
+		"go 1.7 trace\x00\x00\x00\x00\x020",
+		"go 1.7 trace\x00\x00\x00\x00Q00\x020",
+		"go 1.7 trace\x00\x00\x00\x00\xc3\x0200",
+	}
+	for _, data := range tests {
+		if _, err := NewStreamReader(strings.NewReader(data), int64(len(data))); err == nil {
+			t.Errorf("no error on input: %q", data)
+		}
+	}
+}
+
+// TestStreamCanned checks that StreamReader returns the same events
+// as parseGenerations for the canned traces.
+func TestStreamCanned(t *testing.T) {
+	files, err := os.ReadDir("./testdata")
+	if err != nil {
+		t.Fatalf("failed to read ./testdata: %v", err)
+	}
+	for _, f := range files {
+		info, err := f.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if testing.Short() && info.Size() > 10000 {
+			continue
+		}
+		if !strings.HasSuffix(f.Name(), "_good") || strings.Contains(f.Name(), "_1_5_") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("./testdata", f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(f.Name(), func(t *testing.T) {
+			testStream(t, data)
+		})
+	}
+}
+
+func testStream(t *testing.T, data []byte) {
+	ver, gens, err := readTrace(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, stacks, err := parseGenerations(ver, gens, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantByOff := make(map[int]*Event)
+	for _, ev := range want {
+		wantByOff[ev.Off] = ev
+	}
+
+	r, err := NewStreamReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version() != ver {
+		t.Errorf("Version() = %d, want %d", r.Version(), ver)
+	}
+	if !reflect.DeepEqual(r.Stacks(), stacks) {
+		t.Errorf("stacks differ from parseEvents")
+	}
+	n := 0
+	var lastTs int64
+	for {
+		ev, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		n++
+		w := wantByOff[ev.Off]
+		if w == nil {
+			t.Fatalf("unexpected event %v", ev)
+		}
+		if !reflect.DeepEqual(ev, w) {
+			t.Fatalf("got event %v, want %v", ev, w)
+		}
+		if ev.Type != EvGoSysExit {
+			if ev.Ts < lastTs {
+				t.Fatalf("event %v is out of order", ev)
+			}
+			lastTs = ev.Ts
+		}
+	}
+	if n != len(want) {
+		t.Errorf("got %d events, want %d", n, len(want))
+	}
+}
+
+// TestStreamFlightRecorder checks that StreamReader joins the generations
+// of a flight recorder trace the way parseGenerations does.
+func TestStreamFlightRecorder(t *testing.T) {
+	if rtrace.IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	fr := rtrace.NewFlightRecorder()
+	fr.SetPeriod(20 * time.Millisecond)
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 50; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		close(done)
+	}()
+	<-done
+	var buf bytes.Buffer
+	_, err := fr.WriteTo(&buf)
+	fr.Stop()
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if _, _, err := parse(bytes.NewReader(buf.Bytes()), ""); err == ErrTimeOrder {
+		t.Skipf("skipping trace: %v", err)
+	}
+	testStream(t, buf.Bytes())
+}
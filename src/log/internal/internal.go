@@ -0,0 +1,12 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package internal contains definitions used by both log and log/slog.
+package internal
+
+// DefaultOutput holds a function which calls the default log.Logger's
+// output function with an explicit program counter.
+// It is set by package log and allows log/slog to write through the
+// standard logger without exporting that entry point.
+var DefaultOutput func(pc uintptr, data []byte) error
@@ -0,0 +1,76 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package buffer provides a pool-allocated byte buffer.
+package buffer
+
+import "sync"
+
+// Buffer is a byte buffer.
+//
+// This implementation is adapted from the unexported type buffer
+// in go/src/fmt/print.go.
+type Buffer []byte
+
+// Having an initial size gives a dramatic speedup.
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 1024)
+		return (*Buffer)(&b)
+	},
+}
+
+// New returns an empty Buffer from the pool.
+func New() *Buffer {
+	return bufPool.Get().(*Buffer)
+}
+
+// Free returns b to the pool. Large buffers are dropped
+// so that a single large message does not pin memory.
+func (b *Buffer) Free() {
+	// To reduce peak allocation, return only smaller buffers to the pool.
+	const maxBufferSize = 16 << 10
+	if cap(*b) <= maxBufferSize {
+		*b = (*b)[:0]
+		bufPool.Put(b)
+	}
+}
+
+// Reset empties b.
+func (b *Buffer) Reset() {
+	b.SetLen(0)
+}
+
+// Write appends p to b. It never returns an error.
+func (b *Buffer) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
+
+// WriteString appends s to b. It never returns an error.
+func (b *Buffer) WriteString(s string) (int, error) {
+	*b = append(*b, s...)
+	return len(s), nil
+}
+
+// WriteByte appends c to b. It never returns an error.
+func (b *Buffer) WriteByte(c byte) error {
+	*b = append(*b, c)
+	return nil
+}
+
+// String returns the contents of b as a string.
+func (b *Buffer) String() string {
+	return string(*b)
+}
+
+// Len returns the number of bytes in b.
+func (b *Buffer) Len() int {
+	return len(*b)
+}
+
+// SetLen truncates or extends b to n bytes.
+func (b *Buffer) SetLen(n int) {
+	*b = (*b)[:n]
+}
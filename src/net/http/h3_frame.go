@@ -0,0 +1,556 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/3 framing (RFC 9114, Section 7) and stream handling,
+// shared by the client and server.
+
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/internal/ascii"
+	"net/http/internal/qpack"
+	"net/http/internal/quic"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// HTTP/3 frame types (RFC 9114, Section 7.2).
+const (
+	http3FrameData        = 0x00
+	http3FrameHeaders     = 0x01
+	http3FrameCancelPush  = 0x03
+	http3FrameSettings    = 0x04
+	http3FramePushPromise = 0x05
+	http3FrameGoAway      = 0x07
+	http3FrameMaxPushID   = 0x0d
+)
+
+// HTTP/3 unidirectional stream types (RFC 9114, Section 6.2;
+// RFC 9204, Section 4.2).
+const (
+	http3StreamControl      = 0x00
+	http3StreamPush         = 0x01
+	http3StreamQPACKEncoder = 0x02
+	http3StreamQPACKDecoder = 0x03
+)
+
+// HTTP/3 settings (RFC 9114, Section 7.2.4.1; RFC 9204, Section 5).
+const (
+	http3SettingQPACKMaxTableCapacity = 0x01
+	http3SettingMaxFieldSectionSize   = 0x06
+	http3SettingQPACKBlockedStreams   = 0x07
+)
+
+// An http3ErrCode is an HTTP/3 or QPACK error code
+// (RFC 9114, Section 8.1; RFC 9204, Section 6).
+type http3ErrCode uint64
+
+const (
+	http3ErrNoError              http3ErrCode = 0x100
+	http3ErrGeneralProtocolError http3ErrCode = 0x101
+	http3ErrInternalError        http3ErrCode = 0x102
+	http3ErrStreamCreationError  http3ErrCode = 0x103
+	http3ErrClosedCriticalStream http3ErrCode = 0x104
+	http3ErrFrameUnexpected      http3ErrCode = 0x105
+	http3ErrFrameError           http3ErrCode = 0x106
+	http3ErrExcessiveLoad        http3ErrCode = 0x107
+	http3ErrIDError              http3ErrCode = 0x108
+	http3ErrSettingsError        http3ErrCode = 0x109
+	http3ErrMissingSettings      http3ErrCode = 0x10a
+	http3ErrRequestRejected      http3ErrCode = 0x10b
+	http3ErrRequestCancelled     http3ErrCode = 0x10c
+	http3ErrRequestIncomplete    http3ErrCode = 0x10d
+	http3ErrMessageError         http3ErrCode = 0x10e
+	http3ErrConnectError         http3ErrCode = 0x10f
+	http3ErrVersionFallback      http3ErrCode = 0x110
+
+	http3ErrQPACKDecompressionFailed http3ErrCode = 0x200
+)
+
+var http3ErrCodeName = map[http3ErrCode]string{
+	http3ErrNoError:              "H3_NO_ERROR",
+	http3ErrGeneralProtocolError: "H3_GENERAL_PROTOCOL_ERROR",
+	http3ErrInternalError:        "H3_INTERNAL_ERROR",
+	http3ErrStreamCreationError:  "H3_STREAM_CREATION_ERROR",
+	http3ErrClosedCriticalStream: "H3_CLOSED_CRITICAL_STREAM",
+	http3ErrFrameUnexpected:      "H3_FRAME_UNEXPECTED",
+	http3ErrFrameError:           "H3_FRAME_ERROR",
+	http3ErrExcessiveLoad:        "H3_EXCESSIVE_LOAD",
+	http3ErrIDError:              "H3_ID_ERROR",
+	http3ErrSettingsError:        "H3_SETTINGS_ERROR",
+	http3ErrMissingSettings:      "H3_MISSING_SETTINGS",
+	http3ErrRequestRejected:      "H3_REQUEST_REJECTED",
+	http3ErrRequestCancelled:     "H3_REQUEST_CANCELLED",
+	http3ErrRequestIncomplete:    "H3_REQUEST_INCOMPLETE",
+	http3ErrMessageError:         "H3_MESSAGE_ERROR",
+	http3ErrConnectError:         "H3_CONNECT_ERROR",
+	http3ErrVersionFallback:      "H3_VERSION_FALLBACK",
+
+	http3ErrQPACKDecompressionFailed: "QPACK_DECOMPRESSION_FAILED",
+}
+
+func (e http3ErrCode) String() string {
+	if s, ok := http3ErrCodeName[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown error code 0x%x", uint64(e))
+}
+
+// An http3ConnError is an error which closes the entire connection.
+type http3ConnError struct {
+	code http3ErrCode
+	msg  string
+}
+
+func (e http3ConnError) Error() string {
+	return fmt.Sprintf("http3: connection error: %v: %v", e.code, e.msg)
+}
+
+// An http3StreamError is an error which aborts a single request stream.
+type http3StreamError struct {
+	code http3ErrCode
+	msg  string
+}
+
+func (e http3StreamError) Error() string {
+	return fmt.Sprintf("http3: stream error: %v: %v", e.code, e.msg)
+}
+
+// http3MaxControlFrameSize bounds the size of frames other than DATA,
+// apart from HEADERS frames which are bounded by the field section size.
+const http3MaxControlFrameSize = 16 << 10
+
+// http3Reader reads frames from a stream.
+type http3Reader struct {
+	br *bufio.Reader
+
+	// remain is the number of bytes remaining in the current DATA frame.
+	remain int64
+}
+
+func newHTTP3Reader(r io.Reader) *http3Reader {
+	return &http3Reader{br: bufio.NewReader(r)}
+}
+
+// readFrameHeader reads the type and length of the next frame.
+// It returns io.EOF if the stream ends cleanly before a frame.
+func (r *http3Reader) readFrameHeader() (typ, length uint64, err error) {
+	typ, err = quic.ReadVarint(r.br)
+	if err != nil {
+		return 0, 0, err
+	}
+	length, err = quic.ReadVarint(r.br)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return typ, length, err
+}
+
+// readPayload reads a frame payload of up to max bytes.
+func (r *http3Reader) readPayload(length uint64, max int64) ([]byte, error) {
+	if length > uint64(max) {
+		return nil, http3ConnError{http3ErrExcessiveLoad, "frame too large"}
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r.br, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// skip discards n bytes.
+func (r *http3Reader) skip(n uint64) error {
+	_, err := io.CopyN(io.Discard, r.br, int64(n))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// nextMessageFrame reads frames on a request stream until it finds a
+// HEADERS or DATA frame, skipping frames of unknown type
+// (RFC 9114, Section 9). Frames which may not be sent on a request
+// stream are a connection error of type H3_FRAME_UNEXPECTED.
+func (r *http3Reader) nextMessageFrame() (typ, length uint64, err error) {
+	for {
+		typ, length, err = r.readFrameHeader()
+		if err != nil {
+			return 0, 0, err
+		}
+		switch typ {
+		case http3FrameData, http3FrameHeaders:
+			return typ, length, nil
+		case http3FrameCancelPush, http3FrameSettings, http3FrameGoAway, http3FrameMaxPushID:
+			return 0, 0, http3ConnError{http3ErrFrameUnexpected, "control frame on request stream"}
+		case http3FramePushPromise:
+			// We never send MAX_PUSH_ID, so the server may not push.
+			return 0, 0, http3ConnError{http3ErrIDError, "unexpected PUSH_PROMISE"}
+		}
+		if err := r.skip(length); err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+// readFields reads and decodes a HEADERS frame payload.
+// maxSize limits the size of the decoded field section.
+func (r *http3Reader) readFields(length uint64, maxSize uint64) ([]qpack.HeaderField, error) {
+	if length > maxSize {
+		return nil, http3StreamError{http3ErrExcessiveLoad, "header too large"}
+	}
+	b, err := r.readPayload(length, int64(maxSize))
+	if err != nil {
+		return nil, err
+	}
+	var fields []qpack.HeaderField
+	var size uint64
+	if err := qpack.DecodeFieldSection(b, func(f qpack.HeaderField) {
+		size += f.Size()
+		fields = append(fields, f)
+	}); err != nil {
+		return nil, http3ConnError{http3ErrQPACKDecompressionFailed, err.Error()}
+	}
+	if size > maxSize {
+		return nil, http3StreamError{http3ErrExcessiveLoad, "header too large"}
+	}
+	return fields, nil
+}
+
+// readData reads from the message body, consuming DATA frames.
+// When it reaches a HEADERS frame containing trailers, it calls
+// trailers with the length of the frame to read them.
+func (r *http3Reader) readData(p []byte, trailers func(length uint64) error) (int, error) {
+	for r.remain == 0 {
+		typ, length, err := r.nextMessageFrame()
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		if typ == http3FrameHeaders {
+			if err := trailers(length); err != nil {
+				return 0, err
+			}
+			// Nothing may follow the trailers.
+			if _, _, err := r.nextMessageFrame(); err != io.EOF {
+				if err == nil {
+					err = http3ConnError{http3ErrFrameUnexpected, "frame after trailers"}
+				}
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		r.remain = int64(length)
+	}
+	if int64(len(p)) > r.remain {
+		p = p[:r.remain]
+	}
+	n, err := r.br.Read(p)
+	r.remain -= int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// readSettings reads the SETTINGS frame which begins a control stream,
+// returning the peer's maximum field section size.
+func (r *http3Reader) readSettings() (maxFieldSectionSize uint64, err error) {
+	typ, length, err := r.readFrameHeader()
+	if err != nil {
+		return 0, err
+	}
+	if typ != http3FrameSettings {
+		return 0, http3ConnError{http3ErrMissingSettings, "control stream does not begin with SETTINGS"}
+	}
+	b, err := r.readPayload(length, http3MaxControlFrameSize)
+	if err != nil {
+		return 0, err
+	}
+	maxFieldSectionSize = 1<<62 - 1
+	seen := make(map[uint64]bool)
+	for len(b) > 0 {
+		id, n := quic.ConsumeVarint(b)
+		if n < 0 {
+			return 0, http3ConnError{http3ErrFrameError, "malformed SETTINGS"}
+		}
+		b = b[n:]
+		v, n := quic.ConsumeVarint(b)
+		if n < 0 {
+			return 0, http3ConnError{http3ErrFrameError, "malformed SETTINGS"}
+		}
+		b = b[n:]
+		if seen[id] {
+			return 0, http3ConnError{http3ErrSettingsError, "duplicate setting"}
+		}
+		seen[id] = true
+		switch id {
+		case 0x02, 0x03, 0x04, 0x05:
+			// HTTP/2 settings which have no HTTP/3 equivalent
+			// (RFC 9114, Section 7.2.4.1).
+			return 0, http3ConnError{http3ErrSettingsError, "HTTP/2 setting"}
+		case http3SettingMaxFieldSectionSize:
+			maxFieldSectionSize = v
+		}
+	}
+	return maxFieldSectionSize, nil
+}
+
+// readControlStream reads the peer's control stream after its SETTINGS frame,
+// calling goAway for each GOAWAY frame.
+// It always returns an error; the control stream must not be closed
+// (RFC 9114, Section 6.2.1).
+func (r *http3Reader) readControlStream(goAway func(id uint64)) error {
+	for {
+		typ, length, err := r.readFrameHeader()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return http3ConnError{http3ErrClosedCriticalStream, "control stream closed"}
+		}
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case http3FrameData, http3FrameHeaders, http3FramePushPromise:
+			return http3ConnError{http3ErrFrameUnexpected, "message frame on control stream"}
+		case http3FrameSettings:
+			return http3ConnError{http3ErrFrameUnexpected, "second SETTINGS frame"}
+		case http3FrameGoAway:
+			b, err := r.readPayload(length, http3MaxControlFrameSize)
+			if err != nil {
+				return err
+			}
+			id, n := quic.ConsumeVarint(b)
+			if n != len(b) {
+				return http3ConnError{http3ErrFrameError, "malformed GOAWAY"}
+			}
+			goAway(id)
+			continue
+		}
+		if err := r.skip(length); err != nil {
+			return err
+		}
+	}
+}
+
+func http3AppendFrameHeader(b []byte, typ, length uint64) []byte {
+	b = quic.AppendVarint(b, typ)
+	return quic.AppendVarint(b, length)
+}
+
+// http3AppendSettings appends a control stream header and SETTINGS frame.
+func http3AppendSettings(b []byte, maxFieldSectionSize uint64) []byte {
+	var p []byte
+	p = quic.AppendVarint(p, http3SettingMaxFieldSectionSize)
+	p = quic.AppendVarint(p, maxFieldSectionSize)
+	b = quic.AppendVarint(b, http3StreamControl)
+	b = http3AppendFrameHeader(b, http3FrameSettings, uint64(len(p)))
+	return append(b, p...)
+}
+
+// http3AppendGoAway appends a GOAWAY frame.
+func http3AppendGoAway(b []byte, id uint64) []byte {
+	b = http3AppendFrameHeader(b, http3FrameGoAway, uint64(quic.SizeVarint(id)))
+	return quic.AppendVarint(b, id)
+}
+
+// http3AppendHeaders appends a HEADERS frame containing the pseudo-header
+// fields followed by the fields of h.
+func http3AppendHeaders(b []byte, pseudo []qpack.HeaderField, h Header) []byte {
+	p := qpack.AppendFieldSectionPrefix(nil)
+	for _, f := range pseudo {
+		p = qpack.AppendField(p, f)
+	}
+	for k, vv := range h {
+		if !httpguts.ValidHeaderFieldName(k) {
+			continue
+		}
+		name, _ := ascii.ToLower(k) // k is ASCII, as checked above
+		if name == "host" || http3IsConnectionHeader(name) {
+			continue
+		}
+		for _, v := range vv {
+			if !httpguts.ValidHeaderFieldValue(v) {
+				continue
+			}
+			p = qpack.AppendField(p, qpack.HeaderField{
+				Name:      name,
+				Value:     v,
+				Sensitive: name == "authorization" || name == "proxy-authorization",
+			})
+		}
+	}
+	b = http3AppendFrameHeader(b, http3FrameHeaders, uint64(len(p)))
+	return append(b, p...)
+}
+
+// http3IsLower reports whether name contains no uppercase ASCII letters.
+// Field names in HTTP/3 messages must be lowercase (RFC 9114, Section 4.2).
+func http3IsLower(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if 'A' <= name[i] && name[i] <= 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// http3IsConnectionHeader reports whether name is a connection-specific
+// field, which may not appear in HTTP/3 messages (RFC 9114, Section 4.2).
+// The TE field is permitted with a value of "trailers", and is
+// handled separately.
+func http3IsConnectionHeader(name string) bool {
+	switch name {
+	case "connection", "keep-alive", "proxy-connection", "transfer-encoding", "upgrade", "te":
+		return true
+	}
+	return false
+}
+
+// http3ParseFields converts decoded fields into a Header, passing
+// pseudo-header fields to pseudo. It reports a malformed message for
+// invalid fields (RFC 9114, Section 4.1.2).
+func http3ParseFields(fields []qpack.HeaderField, pseudo func(name, value string) error) (Header, error) {
+	h := make(Header)
+	var cookies []string
+	regular := false
+	for _, f := range fields {
+		if strings.HasPrefix(f.Name, ":") {
+			if regular || pseudo == nil {
+				return nil, errHTTP3Malformed
+			}
+			if err := pseudo(f.Name, f.Value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		regular = true
+		if !httpguts.ValidHeaderFieldName(f.Name) || !http3IsLower(f.Name) ||
+			!httpguts.ValidHeaderFieldValue(f.Value) {
+			return nil, errHTTP3Malformed
+		}
+		if f.Name == "te" && f.Value == "trailers" {
+			h.Add("Te", f.Value)
+			continue
+		}
+		if http3IsConnectionHeader(f.Name) {
+			return nil, errHTTP3Malformed
+		}
+		if f.Name == "cookie" {
+			// Cookies may be split into several fields, which are
+			// joined with "; " (RFC 9114, Section 4.2.1).
+			cookies = append(cookies, f.Value)
+			continue
+		}
+		h.Add(CanonicalHeaderKey(f.Name), f.Value)
+	}
+	if len(cookies) > 0 {
+		h.Set("Cookie", strings.Join(cookies, "; "))
+	}
+	return h, nil
+}
+
+var errHTTP3Malformed = http3StreamError{http3ErrMessageError, "malformed message"}
+
+// http3Trailers converts decoded trailer fields into t.
+func http3Trailers(t Header, fields []qpack.HeaderField) error {
+	h, err := http3ParseFields(fields, nil)
+	if err != nil {
+		return err
+	}
+	for k, vv := range h {
+		t[k] = vv
+	}
+	return nil
+}
+
+// http3CloseCode returns the error code for closing a connection
+// or stream because of err.
+func http3CloseCode(err error) http3ErrCode {
+	var ce http3ConnError
+	if errors.As(err, &ce) {
+		return ce.code
+	}
+	var se http3StreamError
+	if errors.As(err, &se) {
+		return se.code
+	}
+	return http3ErrGeneralProtocolError
+}
+
+// http3Abort closes a connection because of err.
+func http3Abort(qconn *quic.Conn, err error) {
+	qconn.Abort(uint64(http3CloseCode(err)), err.Error())
+}
+
+// http3AcceptUniStreams accepts the peer's unidirectional streams until
+// the connection closes. The peer's SETTINGS are passed to settings,
+// and the stream IDs of its GOAWAY frames to goAway.
+func http3AcceptUniStreams(qconn *quic.Conn, settings func(maxFieldSectionSize uint64), goAway func(id uint64)) {
+	var mu sync.Mutex
+	seen := make(map[uint64]bool)
+	for {
+		st, err := qconn.AcceptUniStream(context.Background())
+		if err != nil {
+			return
+		}
+		go func() {
+			r := newHTTP3Reader(st)
+			typ, err := quic.ReadVarint(r.br)
+			if err != nil {
+				st.CloseRead(uint64(http3ErrNoError))
+				return
+			}
+			switch typ {
+			case http3StreamControl, http3StreamQPACKEncoder, http3StreamQPACKDecoder:
+				mu.Lock()
+				dup := seen[typ]
+				seen[typ] = true
+				mu.Unlock()
+				if dup {
+					http3Abort(qconn, http3ConnError{http3ErrStreamCreationError, "duplicate critical stream"})
+					return
+				}
+			case http3StreamPush:
+				if qconn.Side() == quic.ServerSide {
+					http3Abort(qconn, http3ConnError{http3ErrStreamCreationError, "push stream from client"})
+				} else {
+					// We never send MAX_PUSH_ID.
+					http3Abort(qconn, http3ConnError{http3ErrIDError, "unexpected push stream"})
+				}
+				return
+			default:
+				// Unknown stream types are reserved for extensions,
+				// and must be ignored (RFC 9114, Section 6.2).
+				st.CloseRead(uint64(http3ErrStreamCreationError))
+				return
+			}
+			if typ == http3StreamControl {
+				max, err := r.readSettings()
+				if err == nil {
+					settings(max)
+					err = r.readControlStream(goAway)
+				}
+				http3Abort(qconn, err)
+				return
+			}
+			// With no dynamic table, nothing of interest is sent on the
+			// QPACK encoder and decoder streams. They may not be closed
+			// (RFC 9204, Section 4.2).
+			if _, err := io.Copy(io.Discard, r.br); err == nil {
+				http3Abort(qconn, http3ConnError{http3ErrClosedCriticalStream, "QPACK stream closed"})
+			}
+		}()
+	}
+}
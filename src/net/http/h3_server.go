@@ -0,0 +1,791 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/3 server (RFC 9114).
+
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/internal/qpack"
+	"net/http/internal/quic"
+	"net/url"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// ListenAndServeQUIC listens on the UDP network address srv.Addr and
+// then calls ServeQUIC to handle HTTP/3 requests on incoming QUIC
+// connections.
+//
+// If srv.Addr is blank, ":https" is used.
+//
+// ListenAndServeQUIC always returns a non-nil error. After Shutdown or
+// Close, the returned error is ErrServerClosed.
+func (srv *Server) ListenAndServeQUIC(certFile, keyFile string) error {
+	if srv.shuttingDown() {
+		return ErrServerClosed
+	}
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":https"
+	}
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.ServeQUIC(pc, certFile, keyFile)
+}
+
+// ServeQUIC accepts incoming QUIC connections on the PacketConn pc,
+// and serves HTTP/3 requests on them, calling srv.Handler to reply
+// to them. ServeQUIC takes ownership of pc, and closes it when the
+// server is closed.
+//
+// Certificates are configured as for ServeTLS. The TLS configuration's
+// NextProtos is replaced with "h3".
+//
+// While ServeQUIC is running, responses to requests received over TLS
+// by other listeners of srv carry an Alt-Svc header (RFC 7838)
+// advertising HTTP/3 on pc's port, unless the Handler sets one.
+//
+// The Server's BaseContext, ConnContext and ConnState hooks are not
+// called for QUIC connections.
+//
+// ServeQUIC always returns a non-nil error. After Shutdown or Close,
+// the returned error is ErrServerClosed.
+func (srv *Server) ServeQUIC(pc net.PacketConn, certFile, keyFile string) error {
+	// Set up HTTP/2 as ServeTLS does, so srv.TLSConfig is not modified
+	// by a concurrent Serve or ServeTLS while we clone it. Any error
+	// concerns HTTP/2 alone, and is reported by those methods.
+	srv.setupHTTP2_ServeTLS()
+
+	config := cloneTLSConfig(srv.TLSConfig)
+	config.NextProtos = []string{"h3"}
+	configHasCert := len(config.Certificates) > 0 || config.GetCertificate != nil
+	if !configHasCert || certFile != "" || keyFile != "" {
+		var err error
+		config.Certificates = make([]tls.Certificate, 1)
+		config.Certificates[0], err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			pc.Close()
+			return err
+		}
+	}
+	qconf := &quic.Config{TLSConfig: config}
+	if d := srv.idleTimeout(); d > 0 {
+		qconf.MaxIdleTimeout = d
+	}
+
+	s := &http3Server{
+		srv:      srv,
+		endpoint: quic.NewEndpoint(pc, qconf),
+		conns:    make(map[*http3ServerConn]struct{}),
+	}
+	if addr, ok := pc.LocalAddr().(*net.UDPAddr); ok {
+		s.altSvc = fmt.Sprintf(`h3=":%d"; ma=86400`, addr.Port)
+	}
+	if !srv.trackHTTP3Server(s, true) {
+		s.endpoint.Close()
+		return ErrServerClosed
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := srv.getDoneChan()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	for {
+		qconn, err := s.endpoint.Accept(ctx)
+		if err != nil {
+			if srv.shuttingDown() {
+				return ErrServerClosed
+			}
+			srv.trackHTTP3Server(s, false)
+			s.endpoint.Close()
+			return err
+		}
+		sc := s.newConn(qconn)
+		if sc == nil {
+			qconn.Abort(uint64(http3ErrNoError), "")
+			continue
+		}
+		go sc.serve()
+	}
+}
+
+// trackHTTP3Server adds or removes an HTTP/3 server to the set of
+// tracked servers, updating the Alt-Svc advertisement.
+//
+// It reports whether the server is still up (not Shutdown or Closed).
+func (srv *Server) trackHTTP3Server(s *http3Server, add bool) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.http3Servers == nil {
+		srv.http3Servers = make(map[*http3Server]struct{})
+	}
+	if add {
+		if srv.shuttingDown() {
+			return false
+		}
+		srv.http3Servers[s] = struct{}{}
+		srv.altSvc.Store(s.altSvc)
+	} else {
+		srv.removeHTTP3ServerLocked(s)
+	}
+	return true
+}
+
+func (srv *Server) removeHTTP3ServerLocked(s *http3Server) {
+	delete(srv.http3Servers, s)
+	if v, _ := srv.altSvc.Load().(string); v == s.altSvc {
+		srv.altSvc.Store("")
+	}
+}
+
+// http3Server serves HTTP/3 on a QUIC endpoint.
+type http3Server struct {
+	srv      *Server
+	endpoint *quic.Endpoint
+	altSvc   string // Alt-Svc header value advertising the endpoint
+
+	mu      sync.Mutex
+	conns   map[*http3ServerConn]struct{}
+	closing int  // connections being closed by closeIdle
+	closed  bool // the endpoint has been closed
+}
+
+// newConn returns a connection to serve, or nil if the server is closed.
+func (s *http3Server) newConn(qconn *quic.Conn) *http3ServerConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = context.WithValue(ctx, ServerContextKey, s.srv)
+	ctx = context.WithValue(ctx, LocalAddrContextKey, qconn.LocalAddr())
+	sc := &http3ServerConn{
+		s:        s,
+		qconn:    qconn,
+		tlsState: qconn.ConnectionState(),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		cancel()
+		return nil
+	}
+	s.conns[sc] = struct{}{}
+	return sc
+}
+
+func (s *http3Server) removeConn(sc *http3ServerConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, sc)
+}
+
+// startShutdown sends GOAWAY frames on all connections.
+func (s *http3Server) startShutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sc := range s.conns {
+		go sc.goAway()
+	}
+}
+
+// closeIdle gracefully closes connections with no active requests, and
+// closes the endpoint once no connections remain. It reports whether
+// the endpoint has been closed.
+func (s *http3Server) closeIdle() bool {
+	s.mu.Lock()
+	for sc := range s.conns {
+		if !sc.idle() {
+			continue
+		}
+		delete(s.conns, sc)
+		s.closing++
+		go func(sc *http3ServerConn) {
+			ctx, cancel := context.WithTimeout(context.Background(), rstAvoidanceDelay)
+			defer cancel()
+			sc.qconn.Close(ctx, uint64(http3ErrNoError), "")
+			s.mu.Lock()
+			s.closing--
+			s.mu.Unlock()
+		}(sc)
+	}
+	if len(s.conns) > 0 || s.closing > 0 {
+		s.mu.Unlock()
+		return false
+	}
+	s.mu.Unlock()
+	s.close()
+	return true
+}
+
+// close closes the endpoint and all its connections.
+func (s *http3Server) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.endpoint.Close()
+}
+
+// http3ServerConn is an HTTP/3 connection accepted by a server.
+type http3ServerConn struct {
+	s        *http3Server
+	qconn    *quic.Conn
+	tlsState tls.ConnectionState
+	ctx      context.Context // canceled when the connection closes
+	cancel   context.CancelFunc
+
+	mu        sync.Mutex
+	ctrl      *quic.Stream // our control stream
+	active    int          // requests being handled
+	nextID    int64        // lowest request stream ID not yet accepted
+	goingAway bool         // a GOAWAY frame has been or will be sent
+}
+
+// maxFieldSectionSize returns the largest request header the server accepts.
+func (sc *http3ServerConn) maxFieldSectionSize() uint64 {
+	// As for HTTP/2, allow for the per-field overhead of
+	// a typical number of fields.
+	const perFieldOverhead = 32
+	const typicalHeaders = 10
+	return uint64(sc.s.srv.maxHeaderBytes() + typicalHeaders*perFieldOverhead)
+}
+
+func (sc *http3ServerConn) serve() {
+	defer sc.s.removeConn(sc)
+	defer sc.cancel()
+	go func() {
+		select {
+		case <-sc.qconn.Done():
+			sc.cancel()
+		case <-sc.ctx.Done():
+		}
+	}()
+
+	ctrl, err := sc.qconn.OpenUniStream(sc.ctx)
+	if err != nil {
+		return
+	}
+	if _, err := ctrl.Write(http3AppendSettings(nil, sc.maxFieldSectionSize())); err != nil {
+		return
+	}
+	sc.mu.Lock()
+	sc.ctrl = ctrl
+	goingAway := sc.goingAway
+	sc.mu.Unlock()
+	if goingAway {
+		sc.writeGoAway()
+	}
+	go http3AcceptUniStreams(sc.qconn, func(uint64) {}, func(uint64) {
+		// The client's GOAWAY only concerns server push, which we don't use.
+	})
+
+	for {
+		st, err := sc.qconn.AcceptStream(sc.ctx)
+		if err != nil {
+			return
+		}
+		sc.mu.Lock()
+		if sc.goingAway && st.ID() >= sc.nextID {
+			sc.mu.Unlock()
+			st.CloseRead(uint64(http3ErrRequestRejected))
+			st.Reset(uint64(http3ErrRequestRejected))
+			continue
+		}
+		if id := st.ID() + 4; id > sc.nextID {
+			sc.nextID = id
+		}
+		sc.active++
+		sc.mu.Unlock()
+		go sc.serveRequest(st)
+	}
+}
+
+// idle reports whether the connection has no active requests.
+func (sc *http3ServerConn) idle() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.active == 0
+}
+
+// goAway tells the client that no further requests will be processed
+// (RFC 9114, Section 5.2).
+func (sc *http3ServerConn) goAway() {
+	sc.mu.Lock()
+	if sc.goingAway {
+		sc.mu.Unlock()
+		return
+	}
+	sc.goingAway = true
+	ready := sc.ctrl != nil
+	sc.mu.Unlock()
+	if ready {
+		sc.writeGoAway()
+	}
+}
+
+func (sc *http3ServerConn) writeGoAway() {
+	sc.mu.Lock()
+	ctrl, id := sc.ctrl, sc.nextID
+	sc.mu.Unlock()
+	ctrl.Write(http3AppendGoAway(nil, uint64(id)))
+}
+
+func (sc *http3ServerConn) serveRequest(st *quic.Stream) {
+	defer func() {
+		sc.mu.Lock()
+		sc.active--
+		sc.mu.Unlock()
+	}()
+	srv := sc.s.srv
+	if d := srv.readHeaderTimeout(); d > 0 {
+		st.SetReadDeadline(time.Now().Add(d))
+	}
+	r := newHTTP3Reader(st)
+	req, err := sc.readRequest(st, r)
+	if err != nil {
+		var ce http3ConnError
+		if errors.As(err, &ce) {
+			http3Abort(sc.qconn, err)
+			return
+		}
+		code := http3ErrRequestIncomplete
+		var se http3StreamError
+		if errors.As(err, &se) {
+			code = se.code
+		}
+		st.CloseRead(uint64(code))
+		st.Reset(uint64(code))
+		return
+	}
+	now := time.Now()
+	if srv.ReadTimeout > 0 {
+		st.SetReadDeadline(now.Add(srv.ReadTimeout))
+	} else {
+		st.SetReadDeadline(time.Time{})
+	}
+	if srv.WriteTimeout > 0 {
+		st.SetWriteDeadline(now.Add(srv.WriteTimeout))
+	}
+
+	ctx, cancel := context.WithCancel(sc.ctx)
+	defer cancel()
+	req.ctx = ctx
+	w := &http3ResponseWriter{
+		sc:            sc,
+		st:            st,
+		req:           req,
+		bw:            bufio.NewWriterSize(st, 4<<10),
+		contentLength: -1,
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			if err != ErrAbortHandler {
+				const size = 64 << 10
+				buf := make([]byte, size)
+				buf = buf[:runtime.Stack(buf, false)]
+				srv.logf("http: panic serving %v: %v\n%s", req.RemoteAddr, err, buf)
+			}
+			st.CloseRead(uint64(http3ErrInternalError))
+			st.Reset(uint64(http3ErrInternalError))
+		}
+	}()
+	serverHandler{srv}.ServeHTTP(w, req)
+	w.finish()
+	// Stop the client sending a body we didn't read (RFC 9114, Section 4.1).
+	st.CloseRead(uint64(http3ErrNoError))
+}
+
+// readRequest reads a request's header.
+func (sc *http3ServerConn) readRequest(st *quic.Stream, r *http3Reader) (*Request, error) {
+	typ, length, err := r.nextMessageFrame()
+	if err != nil {
+		if err == io.EOF {
+			err = http3StreamError{http3ErrRequestIncomplete, "no request header"}
+		}
+		return nil, err
+	}
+	if typ != http3FrameHeaders {
+		return nil, http3ConnError{http3ErrFrameUnexpected, "DATA frame before HEADERS"}
+	}
+	fields, err := r.readFields(length, sc.maxFieldSectionSize())
+	if err != nil {
+		return nil, err
+	}
+	var method, scheme, authority, path string
+	header, err := http3ParseFields(fields, func(name, value string) error {
+		var p *string
+		switch name {
+		case ":method":
+			p = &method
+		case ":scheme":
+			p = &scheme
+		case ":authority":
+			p = &authority
+		case ":path":
+			p = &path
+		default:
+			return errHTTP3Malformed
+		}
+		if *p != "" {
+			return errHTTP3Malformed
+		}
+		*p = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !validMethod(method) {
+		return nil, errHTTP3Malformed
+	}
+	var u *url.URL
+	requestURI := path
+	if method == "CONNECT" {
+		// RFC 9114, Section 4.4.
+		if scheme != "" || path != "" || authority == "" {
+			return nil, errHTTP3Malformed
+		}
+		u = &url.URL{Host: authority}
+		requestURI = authority
+	} else {
+		if scheme == "" || path == "" {
+			return nil, errHTTP3Malformed
+		}
+		if u, err = url.ParseRequestURI(path); err != nil {
+			return nil, errHTTP3Malformed
+		}
+	}
+	host := authority
+	if host == "" {
+		host = header.Get("Host")
+	}
+	delete(header, "Host")
+
+	req := &Request{
+		Method:     method,
+		URL:        u,
+		Proto:      "HTTP/3.0",
+		ProtoMajor: 3,
+		Header:     header,
+		Host:       host,
+		RemoteAddr: sc.qconn.RemoteAddr().String(),
+		RequestURI: requestURI,
+		TLS:        &sc.tlsState,
+	}
+	for _, v := range header["Trailer"] {
+		foreachHeaderElement(v, func(key string) {
+			key = CanonicalHeaderKey(key)
+			if !httpguts.ValidTrailerHeader(key) {
+				return
+			}
+			if req.Trailer == nil {
+				req.Trailer = make(Header)
+			}
+			req.Trailer[key] = nil
+		})
+	}
+	delete(header, "Trailer")
+
+	req.ContentLength = -1
+	if vv := header["Content-Length"]; len(vv) > 0 {
+		n, err := strconv.ParseUint(vv[0], 10, 63)
+		if err != nil {
+			return nil, errHTTP3Malformed
+		}
+		for _, v := range vv[1:] {
+			if v != vv[0] {
+				return nil, errHTTP3Malformed
+			}
+		}
+		req.ContentLength = int64(n)
+	} else if r.atEOF(st) {
+		req.ContentLength = 0
+		req.Body = NoBody
+	}
+	if req.Body == nil {
+		req.Body = &http3RequestBody{sc: sc, st: st, r: r, req: req, remain: req.ContentLength}
+	}
+	return req, nil
+}
+
+// atEOF reports whether the stream has ended, without blocking.
+// It is used to detect requests which have no body.
+func (r *http3Reader) atEOF(st *quic.Stream) bool {
+	if r.br.Buffered() > 0 {
+		return false
+	}
+	st.SetReadDeadline(aLongTimeAgo)
+	_, err := r.br.Peek(1)
+	st.SetReadDeadline(time.Time{})
+	return err == io.EOF
+}
+
+// http3RequestBody is the Body of a request received over HTTP/3.
+type http3RequestBody struct {
+	sc     *http3ServerConn
+	st     *quic.Stream
+	r      *http3Reader
+	req    *Request
+	remain int64 // bytes remaining of the Content-Length, or -1
+
+	mu     sync.Mutex
+	err    error // sticky error
+	closed bool
+}
+
+func (b *http3RequestBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, ErrBodyReadAfterClose
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.r.readData(p, b.readTrailers)
+	if b.remain >= 0 {
+		if int64(n) > b.remain || err == io.EOF && int64(n) < b.remain {
+			// The body doesn't match the Content-Length
+			// (RFC 9114, Section 4.1.2).
+			n, err = 0, errHTTP3Malformed
+		} else {
+			b.remain -= int64(n)
+		}
+	}
+	if err != nil {
+		var ce http3ConnError
+		var se http3StreamError
+		switch {
+		case errors.As(err, &ce):
+			http3Abort(b.sc.qconn, err)
+		case errors.As(err, &se):
+			b.st.CloseRead(uint64(se.code))
+			b.st.Reset(uint64(se.code))
+		}
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *http3RequestBody) readTrailers(length uint64) error {
+	fields, err := b.r.readFields(length, b.sc.maxFieldSectionSize())
+	if err != nil {
+		return err
+	}
+	if b.req.Trailer == nil {
+		b.req.Trailer = make(Header)
+	}
+	return http3Trailers(b.req.Trailer, fields)
+}
+
+func (b *http3RequestBody) Close() error {
+	// Close may be called concurrently with a blocked Read,
+	// which CloseRead interrupts.
+	b.st.CloseRead(uint64(http3ErrNoError))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+// http3ResponseWriter is the ResponseWriter for HTTP/3 requests.
+type http3ResponseWriter struct {
+	sc  *http3ServerConn
+	st  *quic.Stream
+	req *Request
+	bw  *bufio.Writer
+
+	handlerHeader Header
+	header        Header // snapshot of handlerHeader taken by WriteHeader
+	wroteHeader   bool   // WriteHeader was called
+	sentHeader    bool   // the HEADERS frame was written
+	status        int
+	contentLength int64 // explicitly-declared Content-Length, or -1
+	written       int64 // body bytes written
+	buf           []byte
+	trailers      []string // declared trailers
+	handlerDone   bool
+	err           error // sticky write error
+}
+
+func (w *http3ResponseWriter) Header() Header {
+	if w.handlerHeader == nil {
+		w.handlerHeader = make(Header)
+	}
+	return w.handlerHeader
+}
+
+func (w *http3ResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		caller := relevantCaller()
+		w.sc.s.srv.logf("http: superfluous response.WriteHeader call from %s (%s:%d)", caller.Function, path.Base(caller.File), caller.Line)
+		return
+	}
+	checkWriteHeaderCode(code)
+	w.wroteHeader = true
+	w.status = code
+	w.header = w.handlerHeader.Clone()
+	if w.header == nil {
+		w.header = make(Header)
+	}
+	if cl := w.header.get("Content-Length"); cl != "" {
+		v, err := strconv.ParseInt(cl, 10, 64)
+		if err == nil && v >= 0 {
+			w.contentLength = v
+		} else {
+			w.sc.s.srv.logf("http: invalid Content-Length of %q", cl)
+			w.header.Del("Content-Length")
+		}
+	}
+}
+
+func (w *http3ResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if !bodyAllowedForStatus(w.status) {
+		return 0, ErrBodyNotAllowed
+	}
+	if w.req.Method == "HEAD" {
+		// Responses to HEAD requests have no body.
+		return len(p), nil
+	}
+	if w.contentLength != -1 && w.written+int64(len(p)) > w.contentLength {
+		return 0, ErrContentLength
+	}
+	w.written += int64(len(p))
+	if !w.sentHeader {
+		if len(w.buf)+len(p) <= bufferBeforeChunkingSize {
+			w.buf = append(w.buf, p...)
+			return len(p), nil
+		}
+		w.sendHeader(p)
+	}
+	w.writeData(p)
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+// sendHeader writes the HEADERS frame, followed by any body data
+// which was buffered to sniff its content type.
+// If no data was buffered, the content type is sniffed from p,
+// the data about to be written.
+func (w *http3ResponseWriter) sendHeader(p []byte) {
+	w.sentHeader = true
+	h := w.header
+	if bodyAllowedForStatus(w.status) {
+		if len(w.buf) > 0 {
+			p = w.buf
+		}
+		if _, ok := h["Content-Type"]; !ok && len(p) > 0 {
+			h.Set("Content-Type", DetectContentType(p))
+		}
+		if _, ok := h["Content-Length"]; !ok && w.handlerDone && w.req.Method != "HEAD" {
+			h.Set("Content-Length", strconv.Itoa(len(w.buf)))
+		}
+	}
+	if _, ok := h["Date"]; !ok {
+		h.Set("Date", time.Now().UTC().Format(TimeFormat))
+	}
+	for _, v := range h["Trailer"] {
+		foreachHeaderElement(v, func(key string) {
+			key = CanonicalHeaderKey(key)
+			if httpguts.ValidTrailerHeader(key) {
+				w.trailers = append(w.trailers, key)
+			}
+		})
+	}
+	pseudo := []qpack.HeaderField{{Name: ":status", Value: strconv.Itoa(w.status)}}
+	w.write(http3AppendHeaders(nil, pseudo, h))
+	if len(w.buf) > 0 {
+		w.writeData(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *http3ResponseWriter) writeData(p []byte) {
+	w.write(http3AppendFrameHeader(nil, http3FrameData, uint64(len(p))))
+	w.write(p)
+}
+
+func (w *http3ResponseWriter) write(p []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.bw.Write(p)
+}
+
+func (w *http3ResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if !w.sentHeader {
+		w.sendHeader(nil)
+	}
+	if w.err == nil {
+		w.err = w.bw.Flush()
+	}
+}
+
+// finish completes the response after the Handler returns.
+func (w *http3ResponseWriter) finish() {
+	w.handlerDone = true
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if !w.sentHeader {
+		w.sendHeader(nil)
+	}
+	var trailers Header
+	for k, vv := range w.handlerHeader {
+		if strings.HasPrefix(k, TrailerPrefix) {
+			if trailers == nil {
+				trailers = make(Header)
+			}
+			trailers[strings.TrimPrefix(k, TrailerPrefix)] = vv
+		}
+	}
+	for _, k := range w.trailers {
+		if vv := w.handlerHeader[k]; len(vv) > 0 {
+			if trailers == nil {
+				trailers = make(Header)
+			}
+			trailers[k] = vv
+		}
+	}
+	if len(trailers) > 0 {
+		w.write(http3AppendHeaders(nil, nil, trailers))
+	}
+	if w.err == nil {
+		w.err = w.bw.Flush()
+	}
+	if w.err != nil || w.contentLength != -1 && w.written < w.contentLength && w.req.Method != "HEAD" {
+		// The response is incomplete.
+		w.st.Reset(uint64(http3ErrInternalError))
+		return
+	}
+	w.st.CloseWrite()
+}
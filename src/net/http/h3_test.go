@@ -0,0 +1,438 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	. "net/http"
+	"net/http/httptest"
+	"net/http/internal/testcert"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// h3Test is a server serving HTTP/1.1 and HTTP/2 over TLS, and HTTP/3
+// over QUIC, and a Transport with HTTP/3 enabled.
+type h3Test struct {
+	t    *testing.T
+	ts   *httptest.Server
+	pc   net.PacketConn
+	tr   *Transport
+	c    *Client
+	errc chan error // result of ServeQUIC
+}
+
+func newH3Test(t *testing.T, h Handler) *h3Test {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on UDP loopback: %v", err)
+	}
+	cert, err := tls.X509KeyPair(testcert.LocalhostCert, testcert.LocalhostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(h)
+	ts.EnableHTTP2 = true
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2"},
+	}
+	ts.Config.TLSConfig = ts.TLS.Clone() // for ServeQUIC
+	ts.Config.ErrorLog = quietLog
+	ts.StartTLS()
+	h3 := &h3Test{
+		t:  t,
+		ts: ts,
+		pc: pc,
+		tr: &Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+			EnableHTTP3:       true,
+		},
+		errc: make(chan error, 1),
+	}
+	h3.c = &Client{Transport: h3.tr}
+	go func() {
+		h3.errc <- ts.Config.ServeQUIC(pc, "", "")
+	}()
+	t.Cleanup(h3.close)
+	return h3
+}
+
+func (h3 *h3Test) close() {
+	h3.tr.CloseIdleConnections()
+	h3.ts.Config.Close()
+	h3.ts.Close()
+	select {
+	case err := <-h3.errc:
+		if err != ErrServerClosed {
+			h3.t.Errorf("ServeQUIC = %v, want ErrServerClosed", err)
+		}
+	case <-time.After(10 * time.Second):
+		h3.t.Errorf("ServeQUIC did not return after Close")
+	}
+}
+
+// upgrade makes requests until one is sent over HTTP/3.
+func (h3 *h3Test) upgrade() {
+	h3.t.Helper()
+	for i := 0; i < 20; i++ {
+		res, err := h3.c.Get(h3.ts.URL)
+		if err != nil {
+			h3.t.Fatal(err)
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		if res.ProtoMajor == 3 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	h3.t.Fatal("requests were not upgraded to HTTP/3")
+}
+
+func TestHTTP3AltSvc(t *testing.T) {
+	h3 := newH3Test(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		if r.TLS == nil {
+			t.Errorf("request over %v has no TLS state", r.Proto)
+		}
+		fmt.Fprintf(w, "Host: %v", r.Host)
+	}))
+	want := fmt.Sprintf(`h3=":%d"; ma=86400`, h3.pc.LocalAddr().(*net.UDPAddr).Port)
+	var res *Response
+	for i := 0; ; i++ {
+		var err error
+		res, err = h3.c.Get(h3.ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		// ServeQUIC may not have started yet.
+		if res.ProtoMajor == 3 || i == 20 {
+			break
+		}
+		if got := res.Header.Get("Alt-Svc"); got != "" && got != want {
+			t.Fatalf("Alt-Svc: %q, want %q", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if res.Proto != "HTTP/3.0" || res.ProtoMajor != 3 || res.ProtoMinor != 0 {
+		t.Fatalf("response proto = %q (%v.%v), want HTTP/3.0", res.Proto, res.ProtoMajor, res.ProtoMinor)
+	}
+
+	res, err := h3.c.Get(h3.ts.URL + "/path?q=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Header.Get("X-Proto"); got != "HTTP/3.0" {
+		t.Errorf("server saw request proto %q, want HTTP/3.0", got)
+	}
+	if got := res.Header.Get("Alt-Svc"); got != "" {
+		t.Errorf("HTTP/3 response has Alt-Svc %q", got)
+	}
+	if want := "Host: " + h3.ts.Listener.Addr().String(); string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+	if res.TLS == nil || res.TLS.NegotiatedProtocol != "h3" {
+		t.Errorf("response TLS state = %+v, want negotiated protocol h3", res.TLS)
+	}
+}
+
+func TestHTTP3RequestResponse(t *testing.T) {
+	h3 := newH3Test(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path != "/echo" {
+			return
+		}
+		if r.Method != "POST" || r.URL.RawQuery != "a=b" {
+			t.Errorf("request = %v %v, want POST /echo?a=b", r.Method, r.URL)
+		}
+		if got := r.Header.Get("Cookie"); got != "a=1; b=2" {
+			t.Errorf("Cookie = %q, want %q", got, "a=1; b=2")
+		}
+		w.Header().Set("Trailer", "X-Trailer")
+		w.Header().Set("Content-Type", "application/octet-stream")
+		io.Copy(w, r.Body)
+		if got := r.Trailer.Get("X-Req-Trailer"); got != "req" {
+			t.Errorf("request trailer = %q, want %q", got, "req")
+		}
+		w.Header().Set("X-Trailer", "res")
+		w.Header().Set(TrailerPrefix+"X-Undeclared", "undeclared")
+	}))
+	h3.upgrade()
+
+	want := make([]byte, 1<<20)
+	rand.Read(want)
+	req, _ := NewRequest("POST", h3.ts.URL+"/echo?a=b", io.MultiReader(bytes.NewReader(want)))
+	req.Header.Add("Cookie", "a=1")
+	req.Header.Add("Cookie", "b=2")
+	req.Trailer = Header{"X-Req-Trailer": {"req"}}
+	res, err := h3.c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.ProtoMajor != 3 {
+		t.Fatalf("response proto = %v, want HTTP/3.0", res.Proto)
+	}
+	got, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("echoed %v bytes, not the %v bytes sent", len(got), len(want))
+	}
+	if got := res.Trailer.Get("X-Trailer"); got != "res" {
+		t.Errorf("response trailer X-Trailer = %q, want %q", got, "res")
+	}
+	if got := res.Trailer.Get("X-Undeclared"); got != "undeclared" {
+		t.Errorf("response trailer X-Undeclared = %q, want %q", got, "undeclared")
+	}
+}
+
+func TestHTTP3ResponseHeaders(t *testing.T) {
+	h3 := newH3Test(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		switch r.URL.Path {
+		case "/html":
+			io.WriteString(w, "<html><body>hello</body></html>")
+		case "/notfound":
+			NotFound(w, r)
+		case "/large":
+			io.WriteString(w, strings.Repeat("a", 10000))
+		}
+	}))
+	h3.upgrade()
+	for _, test := range []struct {
+		path          string
+		status        int
+		contentType   string
+		contentLength int64
+	}{
+		{"/html", 200, "text/html; charset=utf-8", 31},
+		{"/notfound", 404, "text/plain; charset=utf-8", 19},
+		{"/large", 200, "text/plain; charset=utf-8", -1},
+	} {
+		req, _ := NewRequest("GET", h3.ts.URL+test.path, nil)
+		req.Header.Set("Accept-Encoding", "identity")
+		res, err := h3.c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("%v: %v", test.path, err)
+		}
+		if res.StatusCode != test.status || res.Status != fmt.Sprintf("%d %s", test.status, StatusText(test.status)) {
+			t.Errorf("%v: status = %q, want %v", test.path, res.Status, test.status)
+		}
+		if got := res.Header.Get("Content-Type"); got != test.contentType {
+			t.Errorf("%v: Content-Type = %q, want %q", test.path, got, test.contentType)
+		}
+		if res.ContentLength != test.contentLength {
+			t.Errorf("%v: ContentLength = %v, want %v", test.path, res.ContentLength, test.contentLength)
+		}
+		if res.Header.Get("Date") == "" {
+			t.Errorf("%v: response has no Date header", test.path)
+		}
+		if test.contentLength >= 0 && int64(len(body)) != test.contentLength {
+			t.Errorf("%v: read %v bytes, want %v", test.path, len(body), test.contentLength)
+		}
+	}
+
+	res, err := h3.c.Head(h3.ts.URL + "/html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.ProtoMajor != 3 || res.StatusCode != 200 {
+		t.Errorf("HEAD response = %v %v, want HTTP/3.0 200", res.Proto, res.Status)
+	}
+}
+
+func TestHTTP3HandlerAbort(t *testing.T) {
+	h3 := newH3Test(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/abort" {
+			io.WriteString(w, "partial")
+			w.(Flusher).Flush()
+			panic(ErrAbortHandler)
+		}
+	}))
+	h3.upgrade()
+	res, err := h3.c.Get(h3.ts.URL + "/abort")
+	if err == nil {
+		_, err = io.ReadAll(res.Body)
+		res.Body.Close()
+	}
+	if err == nil {
+		t.Fatal("request to an aborted handler succeeded")
+	}
+	// The connection is still usable.
+	res, err = h3.c.Get(h3.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.ProtoMajor != 3 {
+		t.Errorf("response proto = %v, want HTTP/3.0", res.Proto)
+	}
+}
+
+func TestHTTP3RequestCancel(t *testing.T) {
+	unblock := make(chan struct{})
+	h3 := newH3Test(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/block" {
+			w.(Flusher).Flush()
+			select {
+			case <-unblock:
+			case <-r.Context().Done():
+			}
+		}
+	}))
+	defer close(unblock)
+	h3.upgrade()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := NewRequestWithContext(ctx, "GET", h3.ts.URL+"/block", nil)
+	res, err := h3.c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	cancel()
+	if _, err := io.ReadAll(res.Body); err != context.Canceled {
+		t.Errorf("reading body after cancel: %v, want context.Canceled", err)
+	}
+}
+
+func TestHTTP3Shutdown(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	h3 := newH3Test(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/block" {
+			close(started)
+			<-unblock
+			io.WriteString(w, "done")
+		}
+	}))
+	h3.upgrade()
+
+	type result struct {
+		res *Response
+		err error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		res, err := h3.c.Get(h3.ts.URL + "/block")
+		resc <- result{res, err}
+	}()
+	<-started
+	shutdownc := make(chan error, 1)
+	go func() {
+		shutdownc <- h3.ts.Config.Shutdown(context.Background())
+	}()
+	select {
+	case err := <-h3.errc:
+		if err != ErrServerClosed {
+			t.Errorf("ServeQUIC = %v, want ErrServerClosed", err)
+		}
+		h3.errc <- err // for close
+	case <-time.After(10 * time.Second):
+		t.Fatal("ServeQUIC did not return after Shutdown")
+	}
+	select {
+	case <-shutdownc:
+		t.Fatal("Shutdown returned with a request in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(unblock)
+	r := <-resc
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	body, err := io.ReadAll(r.res.Body)
+	r.res.Body.Close()
+	if err != nil || string(body) != "done" {
+		t.Errorf("response body = %q, %v; want %q", body, err, "done")
+	}
+	if err := <-shutdownc; err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+}
+
+func TestHTTP3Fallback(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	// Find a UDP port with nothing listening on it.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on UDP loopback: %v", err)
+	}
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	pc.Close()
+
+	ts := httptest.NewTLSServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Alt-Svc", fmt.Sprintf(`h3=":%d"`, port))
+		io.WriteString(w, r.Proto)
+	}))
+	defer ts.Close()
+	tr := &Transport{
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		TLSHandshakeTimeout: 100 * time.Millisecond,
+		EnableHTTP3:         true,
+	}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+	for i := 0; i < 3; i++ {
+		res, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != "HTTP/1.1" {
+			t.Errorf("request %v: server saw proto %q, want HTTP/1.1", i, body)
+		}
+	}
+}
+
+func TestHTTP3ConcurrentRequests(t *testing.T) {
+	h3 := newH3Test(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, r.URL.Path)
+	}))
+	h3.upgrade()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := fmt.Sprintf("/%d", i)
+			res, err := h3.c.Get(h3.ts.URL + path)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil || string(body) != path || res.ProtoMajor != 3 {
+				t.Errorf("GET %v = %v %q, %v", path, res.Proto, body, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,818 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/3 client (RFC 9114), and discovery of HTTP/3 servers with
+// HTTP Alternative Services (RFC 7838).
+
+package http
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http/internal/ascii"
+	"net/http/internal/qpack"
+	"net/http/internal/quic"
+	"net/textproto"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+const http3DefaultUserAgent = "Go-http-client/3.0"
+
+// errHTTP3Unavailable is returned by http3Transport.roundTrip when a
+// request can't be sent over HTTP/3, and should be sent over TCP instead.
+// The request body has not been read.
+var errHTTP3Unavailable = errors.New("net/http: HTTP/3 unavailable")
+
+var errHTTP3RequestHeaderTooLarge = errors.New("net/http: HTTP/3 request header larger than the server allows")
+
+// http3BrokenDuration is how long an alternative service which could not
+// be reached is not used (RFC 7838, Section 2.4).
+const http3BrokenDuration = 5 * time.Minute
+
+// http3 returns the Transport's HTTP/3 state, creating it if necessary.
+func (t *Transport) http3() *http3Transport {
+	t.h3Once.Do(func() {
+		t.h3transport = &http3Transport{
+			t:      t,
+			altSvc: make(map[string]http3AltSvc),
+			broken: make(map[string]time.Time),
+			conns:  make(map[string]*http3ClientConn),
+			all:    make(map[*http3ClientConn]struct{}),
+		}
+	})
+	return t.h3transport
+}
+
+// http3Transport is the HTTP/3 part of a Transport.
+type http3Transport struct {
+	t *Transport
+
+	mu       sync.Mutex
+	endpoint *quic.Endpoint
+	altSvc   map[string]http3AltSvc      // by origin "host:port"
+	broken   map[string]time.Time        // origins whose alternative failed, until the time
+	conns    map[string]*http3ClientConn // connections for new requests, by origin "host:port"
+	all      map[*http3ClientConn]struct{}
+}
+
+// An http3AltSvc is an HTTP/3 alternative service for an origin.
+type http3AltSvc struct {
+	addr    string // "host:port"
+	expires time.Time
+}
+
+// noteAltSvc records the HTTP/3 alternative service, if any,
+// advertised by a response received over TCP.
+func (h *http3Transport) noteAltSvc(req *Request, resp *Response) {
+	v := resp.Header.Get("Alt-Svc")
+	if v == "" {
+		return
+	}
+	origin := canonicalAddr(req.URL)
+	addr, maxAge, clear := parseAltSvcH3(v)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if clear {
+		delete(h.altSvc, origin)
+		return
+	}
+	if addr == "" {
+		return
+	}
+	if until, ok := h.broken[origin]; ok {
+		if time.Now().Before(until) {
+			return
+		}
+		delete(h.broken, origin)
+	}
+	if strings.HasPrefix(addr, ":") {
+		addr = net.JoinHostPort(req.URL.Hostname(), addr[1:])
+	}
+	h.altSvc[origin] = http3AltSvc{
+		addr:    addr,
+		expires: time.Now().Add(maxAge),
+	}
+}
+
+// parseAltSvcH3 parses an Alt-Svc header value (RFC 7838, Section 3),
+// returning the authority and lifetime of the first HTTP/3 alternative.
+// The authority's host may be empty. It reports whether the value is
+// "clear", invalidating previously advertised alternatives.
+func parseAltSvcH3(v string) (addr string, maxAge time.Duration, clear bool) {
+	v = textproto.TrimString(v)
+	if v == "clear" {
+		return "", 0, true
+	}
+	for _, alt := range splitQuoted(v, ',') {
+		params := splitQuoted(alt, ';')
+		alt = textproto.TrimString(params[0])
+		i := strings.Index(alt, "=")
+		if i < 0 || alt[:i] != "h3" {
+			continue
+		}
+		host, port, err := net.SplitHostPort(unquote(alt[i+1:]))
+		if err != nil {
+			continue
+		}
+		if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+			continue
+		}
+		maxAge = 24 * time.Hour
+		for _, p := range params[1:] {
+			p = textproto.TrimString(p)
+			if i := strings.Index(p, "="); i >= 0 && textproto.TrimString(p[:i]) == "ma" {
+				if n, err := strconv.ParseUint(unquote(textproto.TrimString(p[i+1:])), 10, 32); err == nil {
+					maxAge = time.Duration(n) * time.Second
+				}
+			}
+		}
+		if host == "" {
+			return ":" + port, maxAge, false
+		}
+		return net.JoinHostPort(host, port), maxAge, false
+	}
+	return "", 0, false
+}
+
+// splitQuoted splits s at each sep which is not inside a quoted string.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			quoted = !quoted
+		case c == '\\' && quoted:
+			i++
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote returns the contents of s if it is a quoted string,
+// or s otherwise.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
+
+// roundTrip sends a request over HTTP/3. It returns errHTTP3Unavailable
+// if the request should be sent over TCP instead.
+func (h *http3Transport) roundTrip(req *Request) (*Response, error) {
+	if req.Method != "" && !validMethod(req.Method) {
+		return nil, errHTTP3Unavailable
+	}
+	if h.t.Proxy != nil {
+		if u, err := h.t.Proxy(req); err != nil || u != nil {
+			return nil, errHTTP3Unavailable
+		}
+	}
+	origin := canonicalAddr(req.URL)
+	cc, err := h.getConn(req.Context(), origin)
+	if err != nil {
+		return nil, errHTTP3Unavailable
+	}
+	return cc.roundTrip(req)
+}
+
+// getConn returns a connection to the HTTP/3 alternative service of origin,
+// dialing one if necessary.
+func (h *http3Transport) getConn(ctx context.Context, origin string) (*http3ClientConn, error) {
+	for {
+		h.mu.Lock()
+		if cc := h.conns[origin]; cc != nil {
+			h.mu.Unlock()
+			select {
+			case <-cc.dialDone:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if cc.dialErr != nil {
+				return nil, cc.dialErr
+			}
+			if cc.usable() {
+				return cc, nil
+			}
+			h.removeConn(cc)
+			continue
+		}
+		alt, ok := h.altSvc[origin]
+		if ok && time.Now().After(alt.expires) {
+			delete(h.altSvc, origin)
+			ok = false
+		}
+		if !ok {
+			h.mu.Unlock()
+			return nil, errHTTP3Unavailable
+		}
+		cc := &http3ClientConn{
+			h:        h,
+			origin:   origin,
+			dialDone: make(chan struct{}),
+		}
+		h.conns[origin] = cc
+		h.all[cc] = struct{}{}
+		h.mu.Unlock()
+
+		cc.dialErr = cc.dial(ctx, alt.addr)
+		close(cc.dialDone)
+		if cc.dialErr != nil {
+			h.mu.Lock()
+			if h.conns[origin] == cc {
+				delete(h.conns, origin)
+			}
+			delete(h.all, cc)
+			if ctx.Err() == nil {
+				delete(h.altSvc, origin)
+				h.broken[origin] = time.Now().Add(http3BrokenDuration)
+			}
+			h.mu.Unlock()
+			return nil, cc.dialErr
+		}
+		return cc, nil
+	}
+}
+
+// removeConn stops new requests from using a connection.
+func (h *http3Transport) removeConn(cc *http3ClientConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conns[cc.origin] == cc {
+		delete(h.conns, cc.origin)
+	}
+}
+
+// connDone forgets a connection which has closed.
+func (h *http3Transport) connDone(cc *http3ClientConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conns[cc.origin] == cc {
+		delete(h.conns, cc.origin)
+	}
+	delete(h.all, cc)
+}
+
+// getEndpoint returns the endpoint used for client connections.
+func (h *http3Transport) getEndpoint() (*quic.Endpoint, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.endpoint == nil {
+		e, err := quic.Listen("udp", ":0", nil)
+		if err != nil {
+			return nil, err
+		}
+		h.endpoint = e
+	}
+	return h.endpoint, nil
+}
+
+// closeIdleConnections closes connections with no active requests,
+// and the endpoint if no connections remain.
+func (h *http3Transport) closeIdleConnections() {
+	h.mu.Lock()
+	var idle []*http3ClientConn
+	for cc := range h.all {
+		if cc.idle() {
+			idle = append(idle, cc)
+			if h.conns[cc.origin] == cc {
+				delete(h.conns, cc.origin)
+			}
+		}
+	}
+	var e *quic.Endpoint
+	if len(idle) == len(h.all) {
+		e = h.endpoint
+		h.endpoint = nil
+	}
+	h.mu.Unlock()
+	for _, cc := range idle {
+		cc.qconn.Abort(uint64(http3ErrNoError), "")
+	}
+	if e != nil {
+		e.Close()
+	}
+}
+
+// http3ClientConn is a client HTTP/3 connection.
+type http3ClientConn struct {
+	h        *http3Transport
+	origin   string
+	dialDone chan struct{} // closed when the dial completes
+	dialErr  error
+	qconn    *quic.Conn
+	tlsState tls.ConnectionState
+
+	mu                      sync.Mutex
+	peerMaxFieldSectionSize uint64
+	goAway                  bool // the server sent GOAWAY
+	active                  int  // requests in progress
+}
+
+func (cc *http3ClientConn) dial(ctx context.Context, addr string) error {
+	t := cc.h.t
+	e, err := cc.h.getEndpoint()
+	if err != nil {
+		return err
+	}
+	config := cloneTLSConfig(t.TLSClientConfig)
+	config.NextProtos = []string{"h3"}
+	if config.ServerName == "" {
+		host, _, _ := net.SplitHostPort(cc.origin)
+		config.ServerName = host
+	}
+	qconf := &quic.Config{TLSConfig: config}
+	if t.IdleConnTimeout > 0 {
+		qconf.MaxIdleTimeout = t.IdleConnTimeout
+	}
+	if t.TLSHandshakeTimeout > 0 {
+		qconf.HandshakeTimeout = t.TLSHandshakeTimeout
+	}
+	qconn, err := e.Dial(ctx, "udp", addr, qconf)
+	if err != nil {
+		return err
+	}
+	cc.qconn = qconn
+	cc.tlsState = qconn.ConnectionState()
+	cc.peerMaxFieldSectionSize = 1<<62 - 1
+	ctrl, err := qconn.OpenUniStream(ctx)
+	if err == nil {
+		_, err = ctrl.Write(http3AppendSettings(nil, uint64(cc.maxHeaderResponseSize())))
+	}
+	if err != nil {
+		qconn.Abort(uint64(http3ErrInternalError), "")
+		return err
+	}
+	go http3AcceptUniStreams(qconn, func(max uint64) {
+		cc.mu.Lock()
+		defer cc.mu.Unlock()
+		cc.peerMaxFieldSectionSize = max
+	}, func(uint64) {
+		cc.mu.Lock()
+		cc.goAway = true
+		cc.mu.Unlock()
+		cc.h.removeConn(cc)
+	})
+	go func() {
+		<-qconn.Done()
+		cc.h.connDone(cc)
+	}()
+	return nil
+}
+
+func (cc *http3ClientConn) maxHeaderResponseSize() int64 {
+	if v := cc.h.t.MaxResponseHeaderBytes; v != 0 {
+		return v
+	}
+	return 10 << 20 // conservative default; same as HTTP/1 and HTTP/2
+}
+
+// usable reports whether new requests may be sent on the connection.
+func (cc *http3ClientConn) usable() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return !cc.goAway && cc.qconn.Err() == nil
+}
+
+// idle reports whether the connection has been dialed, and has no
+// requests in progress.
+func (cc *http3ClientConn) idle() bool {
+	select {
+	case <-cc.dialDone:
+	default:
+		return false
+	}
+	if cc.dialErr != nil {
+		return false
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.active == 0
+}
+
+func (cc *http3ClientConn) roundTrip(req *Request) (*Response, error) {
+	ctx := req.Context()
+	cc.mu.Lock()
+	if cc.goAway {
+		cc.mu.Unlock()
+		return nil, errHTTP3Unavailable
+	}
+	cc.active++
+	cc.mu.Unlock()
+	var doneOnce sync.Once
+	done := make(chan struct{})
+	requestDone := func() {
+		doneOnce.Do(func() {
+			close(done)
+			cc.mu.Lock()
+			cc.active--
+			cc.mu.Unlock()
+		})
+	}
+
+	st, err := cc.qconn.OpenStream(ctx)
+	if err != nil {
+		requestDone()
+		if ctx.Err() != nil {
+			req.closeBody()
+			return nil, ctx.Err()
+		}
+		return nil, errHTTP3Unavailable
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			st.Reset(uint64(http3ErrRequestCancelled))
+			st.CloseRead(uint64(http3ErrRequestCancelled))
+		case <-done:
+		}
+	}()
+	fail := func(err error) (*Response, error) {
+		st.Reset(uint64(http3ErrRequestCancelled))
+		st.CloseRead(uint64(http3ErrRequestCancelled))
+		requestDone()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		var ce http3ConnError
+		if errors.As(err, &ce) {
+			http3Abort(cc.qconn, err)
+		}
+		return nil, err
+	}
+
+	hdr, requestedGzip, err := cc.encodeHeaders(req)
+	if err != nil {
+		req.closeBody()
+		return fail(err)
+	}
+	hasBody := req.outgoingLength() != 0
+	if _, err := st.Write(hdr); err != nil {
+		req.closeBody()
+		return fail(err)
+	}
+	if hasBody {
+		go cc.writeBody(st, req)
+	} else {
+		st.CloseWrite()
+	}
+
+	if d := cc.h.t.ResponseHeaderTimeout; d > 0 {
+		st.SetReadDeadline(time.Now().Add(d))
+	}
+	r := newHTTP3Reader(st)
+	var resp *Response
+	for resp == nil {
+		typ, length, err := r.nextMessageFrame()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			} else if errors.Is(err, os.ErrDeadlineExceeded) {
+				err = errTimeout
+			}
+			return fail(err)
+		}
+		if typ != http3FrameHeaders {
+			return fail(http3ConnError{http3ErrFrameUnexpected, "DATA frame before HEADERS"})
+		}
+		fields, err := r.readFields(length, uint64(cc.maxHeaderResponseSize()))
+		if err != nil {
+			return fail(err)
+		}
+		resp, err = cc.parseResponse(req, fields)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	st.SetReadDeadline(time.Time{})
+
+	if req.Method == "HEAD" || !bodyAllowedForStatus(resp.StatusCode) {
+		resp.Body = NoBody
+		st.CloseRead(uint64(http3ErrNoError))
+		requestDone()
+		return resp, nil
+	}
+	body := &http3ResponseBody{
+		cc:          cc,
+		st:          st,
+		r:           r,
+		ctx:         ctx,
+		resp:        resp,
+		remain:      resp.ContentLength,
+		requestDone: requestDone,
+	}
+	resp.Body = body
+	if requestedGzip && ascii.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Body = &http3GzipReader{body: body}
+		resp.Uncompressed = true
+	}
+	return resp, nil
+}
+
+// encodeHeaders returns the HEADERS frame for a request.
+func (cc *http3ClientConn) encodeHeaders(req *Request) (b []byte, requestedGzip bool, err error) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	host, err = httpguts.PunycodeHostPort(host)
+	if err != nil {
+		return nil, false, err
+	}
+	method := valueOrDefault(req.Method, "GET")
+	pseudo := []qpack.HeaderField{
+		{Name: ":method", Value: method},
+	}
+	if method != "CONNECT" {
+		pseudo = append(pseudo, qpack.HeaderField{Name: ":scheme", Value: "https"})
+	}
+	pseudo = append(pseudo, qpack.HeaderField{Name: ":authority", Value: host})
+	if method != "CONNECT" {
+		pseudo = append(pseudo, qpack.HeaderField{Name: ":path", Value: req.URL.RequestURI()})
+	}
+
+	h := req.Header.Clone()
+	if h == nil {
+		h = make(Header)
+	}
+	if v, ok := h["User-Agent"]; !ok {
+		h.Set("User-Agent", http3DefaultUserAgent)
+	} else if len(v) == 0 || v[0] == "" {
+		delete(h, "User-Agent")
+	}
+	if !cc.h.t.DisableCompression &&
+		h.Get("Accept-Encoding") == "" &&
+		h.Get("Range") == "" &&
+		method != "HEAD" {
+		// As for HTTP/1 and HTTP/2, only ask for gzip.
+		requestedGzip = true
+		h.Set("Accept-Encoding", "gzip")
+	}
+	delete(h, "Content-Length")
+	if cl := req.outgoingLength(); http3ShouldSendReqContentLength(method, cl) {
+		h.Set("Content-Length", strconv.FormatInt(cl, 10))
+	}
+	if len(req.Trailer) > 0 {
+		keys := make([]string, 0, len(req.Trailer))
+		for k := range req.Trailer {
+			k = CanonicalHeaderKey(k)
+			if httpguts.ValidTrailerHeader(k) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		h.Set("Trailer", strings.Join(keys, ","))
+	}
+
+	b = http3AppendHeaders(nil, pseudo, h)
+	cc.mu.Lock()
+	max := cc.peerMaxFieldSectionSize
+	cc.mu.Unlock()
+	if uint64(len(b)) > max {
+		return nil, false, errHTTP3RequestHeaderTooLarge
+	}
+	return b, requestedGzip, nil
+}
+
+// writeBody writes a request body and trailers.
+func (cc *http3ClientConn) writeBody(st *quic.Stream, req *Request) {
+	defer req.closeBody()
+	buf := make([]byte, 16<<10)
+	var written int64
+	for {
+		n, err := req.Body.Read(buf)
+		if n > 0 {
+			written += int64(n)
+			b := http3AppendFrameHeader(nil, http3FrameData, uint64(n))
+			if _, werr := st.Write(append(b, buf[:n]...)); werr != nil {
+				return
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			st.Reset(uint64(http3ErrRequestCancelled))
+			return
+		}
+	}
+	if req.ContentLength > 0 && written != req.ContentLength {
+		// The body did not match its declared length.
+		st.Reset(uint64(http3ErrRequestCancelled))
+		return
+	}
+	trailers := make(Header)
+	for k, vv := range req.Trailer {
+		if k = CanonicalHeaderKey(k); httpguts.ValidTrailerHeader(k) && len(vv) > 0 {
+			trailers[k] = vv
+		}
+	}
+	if len(trailers) > 0 {
+		if _, err := st.Write(http3AppendHeaders(nil, nil, trailers)); err != nil {
+			return
+		}
+	}
+	st.CloseWrite()
+}
+
+// parseResponse converts a response header. It returns a nil Response
+// for interim responses.
+func (cc *http3ClientConn) parseResponse(req *Request, fields []qpack.HeaderField) (*Response, error) {
+	var status string
+	header, err := http3ParseFields(fields, func(name, value string) error {
+		if name != ":status" || status != "" {
+			return errHTTP3Malformed
+		}
+		status = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil || len(status) != 3 || code < 100 {
+		return nil, errHTTP3Malformed
+	}
+	if code < 200 {
+		if code == StatusSwitchingProtocols {
+			// HTTP/3 has no Upgrade mechanism (RFC 9114, Section 4.5).
+			return nil, errHTTP3Malformed
+		}
+		return nil, nil
+	}
+	resp := &Response{
+		Status:        status + " " + StatusText(code),
+		StatusCode:    code,
+		Proto:         "HTTP/3.0",
+		ProtoMajor:    3,
+		Header:        header,
+		Request:       req,
+		TLS:           &cc.tlsState,
+		ContentLength: -1,
+	}
+	if vv := header["Content-Length"]; len(vv) > 0 {
+		n, err := strconv.ParseUint(vv[0], 10, 63)
+		if err != nil {
+			return nil, errHTTP3Malformed
+		}
+		resp.ContentLength = int64(n)
+	}
+	for _, v := range header["Trailer"] {
+		foreachHeaderElement(v, func(key string) {
+			key = CanonicalHeaderKey(key)
+			if !httpguts.ValidTrailerHeader(key) {
+				return
+			}
+			if resp.Trailer == nil {
+				resp.Trailer = make(Header)
+			}
+			resp.Trailer[key] = nil
+		})
+	}
+	return resp, nil
+}
+
+// http3ResponseBody is the Body of a response received over HTTP/3.
+type http3ResponseBody struct {
+	cc          *http3ClientConn
+	st          *quic.Stream
+	r           *http3Reader
+	ctx         context.Context
+	resp        *Response
+	remain      int64 // bytes remaining of the Content-Length, or -1
+	requestDone func()
+
+	mu     sync.Mutex
+	err    error // sticky error
+	closed bool
+}
+
+func (b *http3ResponseBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, errReadOnClosedResBody
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.r.readData(p, b.readTrailers)
+	if b.remain >= 0 {
+		if int64(n) > b.remain || err == io.EOF && int64(n) < b.remain {
+			n, err = 0, errHTTP3Malformed
+		} else {
+			b.remain -= int64(n)
+		}
+	}
+	if err != nil {
+		if ctxErr := b.ctx.Err(); ctxErr != nil && err != io.EOF {
+			err = ctxErr
+		}
+		var ce http3ConnError
+		var se http3StreamError
+		switch {
+		case errors.As(err, &ce):
+			http3Abort(b.cc.qconn, err)
+		case errors.As(err, &se):
+			b.st.CloseRead(uint64(se.code))
+		}
+		b.err = err
+		b.requestDone()
+	}
+	return n, err
+}
+
+func (b *http3ResponseBody) readTrailers(length uint64) error {
+	fields, err := b.r.readFields(length, uint64(b.cc.maxHeaderResponseSize()))
+	if err != nil {
+		return err
+	}
+	if b.resp.Trailer == nil {
+		b.resp.Trailer = make(Header)
+	}
+	return http3Trailers(b.resp.Trailer, fields)
+}
+
+func (b *http3ResponseBody) Close() error {
+	// Close may be called concurrently with a blocked Read,
+	// which CloseRead interrupts.
+	b.st.CloseRead(uint64(http3ErrRequestCancelled))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.requestDone()
+	return nil
+}
+
+// http3ShouldSendReqContentLength reports whether a request with the
+// given method and outgoing length should carry a Content-Length field.
+// As for HTTP/2, a zero length is only sent for methods which usually
+// have a body.
+func http3ShouldSendReqContentLength(method string, contentLength int64) bool {
+	if contentLength != 0 {
+		return contentLength > 0
+	}
+	switch method {
+	case "POST", "PUT", "PATCH":
+		return true
+	}
+	return false
+}
+
+// http3GzipReader decompresses a response body which the Transport
+// transparently requested with gzip.
+type http3GzipReader struct {
+	_    incomparable
+	body io.ReadCloser
+	zr   *gzip.Reader // lazily-initialized gzip reader
+	zerr error        // any error from gzip.NewReader; sticky
+}
+
+func (gz *http3GzipReader) Read(p []byte) (n int, err error) {
+	if gz.zerr != nil {
+		return 0, gz.zerr
+	}
+	if gz.zr == nil {
+		gz.zr, err = gzip.NewReader(gz.body)
+		if err != nil {
+			gz.zerr = err
+			return 0, err
+		}
+	}
+	return gz.zr.Read(p)
+}
+
+func (gz *http3GzipReader) Close() error {
+	return gz.body.Close()
+}
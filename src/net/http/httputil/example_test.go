@@ -103,7 +103,12 @@ func ExampleReverseProxy() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	frontendProxy := httptest.NewServer(httputil.NewSingleHostReverseProxy(rpURL))
+	frontendProxy := httptest.NewServer(&httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetXForwarded()
+			r.SetURL(rpURL)
+		},
+	})
 	defer frontendProxy.Close()
 
 	resp, err := http.Get(frontendProxy.URL)
@@ -121,3 +126,40 @@ func ExampleReverseProxy() {
 	// Output:
 	// this call was relayed by the reverse proxy
 }
+
+func ExampleLoadBalancer() {
+	var backends []*url.URL
+	for _, name := range []string{"a", "b"} {
+		name := name
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "backend %s got %s\n", name, r.URL.Path)
+		}))
+		defer backend.Close()
+		u, err := url.Parse(backend.URL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		backends = append(backends, u)
+	}
+
+	lb := &httputil.LoadBalancer{Policy: httputil.RoundRobin}
+	lb.SetBackends(backends)
+	client := &http.Client{Transport: lb}
+
+	for _, path := range []string{"/first", "/second"} {
+		resp, err := client.Get("http://service" + path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s", b)
+	}
+
+	// Output:
+	// backend a got /first
+	// backend b got /second
+}
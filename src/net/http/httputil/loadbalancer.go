@@ -0,0 +1,460 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP client-side load balancer
+
+package httputil
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A BalancePolicy selects the backend a LoadBalancer sends a request to.
+type BalancePolicy int
+
+const (
+	// RoundRobin sends requests to the backends in turn.
+	RoundRobin BalancePolicy = iota
+
+	// LeastOutstanding sends each request to the backend with the
+	// fewest requests in flight, taking the backends in turn in
+	// case of a tie.
+	LeastOutstanding
+
+	// PowerOfTwoChoices sends each request to the backend with the
+	// fewer requests in flight of two backends picked at random.
+	PowerOfTwoChoices
+)
+
+// Defaults of the LoadBalancer fields.
+const (
+	defaultMaxRetries     = 2
+	defaultMaxRetryAfter  = 10 * time.Second
+	defaultEjectThreshold = 3
+	defaultEjectDuration  = 30 * time.Second
+)
+
+// LoadBalancer is an http.RoundTripper that spreads requests over a set
+// of backends, each identified by a URL. It may be used as the Transport
+// of an http.Client or of a ReverseProxy.
+//
+// For each request, LoadBalancer selects a backend according to its
+// Policy, replaces the scheme and host of the request URL with those of
+// the backend, and prefixes the request path with the backend's path,
+// as ProxyRequest.SetURL does. The Host header of the request, if set,
+// is left unchanged.
+//
+// LoadBalancer passively tracks the health of the backends: a backend
+// whose requests fail with an error or a 5xx status EjectThreshold
+// times in a row is ejected, that is, no longer selected, for
+// EjectDuration. If all the backends are ejected, they are all selected
+// as if they were healthy. A request canceled by the caller, or whose
+// context expires, counts as neither a failure nor a success.
+//
+// A request that fails with an error, a 5xx status or a 429 (Too Many
+// Requests) status is retried on another backend, if there is one, if
+// it can be sent again: its method must be idempotent, or it must have
+// an Idempotency-Key or X-Idempotency-Key header, and it must either
+// have no body or have a GetBody function to obtain a new copy of it.
+// The idempotent methods are GET, HEAD, OPTIONS, TRACE, PUT and DELETE.
+// If the failed response has a Retry-After header, the retry waits for
+// the delay it specifies. A canceled request is not retried.
+//
+// The backends are set with SetBackends. The zero LoadBalancer is ready
+// to use once it has backends. A LoadBalancer is safe for concurrent use
+// by multiple goroutines, and its fields must not be changed once it
+// is in use.
+type LoadBalancer struct {
+	// Policy selects the backend of each request.
+	Policy BalancePolicy
+
+	// The transport used to send the requests to the backends.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// MaxRetries is the maximum number of times a request is retried.
+	// If zero, a default of 2 is used. If negative, requests are not
+	// retried.
+	MaxRetries int
+
+	// MaxRetryAfter is the longest delay requested by a Retry-After
+	// header that a retry waits for. A failed response requesting a
+	// longer delay is returned without retrying the request.
+	// If zero, a default of 10 seconds is used.
+	MaxRetryAfter time.Duration
+
+	// EjectThreshold is the number of consecutive failures after
+	// which a backend is ejected. If zero, a default of 3 is used.
+	// If negative, backends are never ejected.
+	EjectThreshold int
+
+	// EjectDuration is how long a backend stays ejected.
+	// If zero, a default of 30 seconds is used.
+	EjectDuration time.Duration
+
+	mu       sync.Mutex
+	backends []*lbBackend
+	next     int // index of the next backend in turn
+}
+
+// An lbBackend is a backend of a LoadBalancer, along with its state.
+// Its fields other than url are guarded by the LoadBalancer's mu.
+type lbBackend struct {
+	url          *url.URL
+	outstanding  int       // requests in flight
+	failures     int       // consecutive failures
+	ejectedUntil time.Time // zero if never ejected
+}
+
+// SetBackends sets the backends of the load balancer, replacing the
+// previous ones. It may be called at any time, for example when the
+// backends of a service change. The state of the backends that were
+// already set, such as their health, is kept.
+func (lb *LoadBalancer) SetBackends(backends []*url.URL) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	old := make(map[string]*lbBackend, len(lb.backends))
+	for _, b := range lb.backends {
+		old[b.url.String()] = b
+	}
+	lb.backends = make([]*lbBackend, 0, len(backends))
+	for _, u := range backends {
+		if b, ok := old[u.String()]; ok {
+			lb.backends = append(lb.backends, b)
+			continue
+		}
+		lb.backends = append(lb.backends, &lbBackend{url: u})
+	}
+	if lb.next >= len(lb.backends) {
+		lb.next = 0
+	}
+}
+
+// Backends returns the backends of the load balancer.
+func (lb *LoadBalancer) Backends() []*url.URL {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	urls := make([]*url.URL, len(lb.backends))
+	for i, b := range lb.backends {
+		urls[i] = b.url
+	}
+	return urls
+}
+
+func (lb *LoadBalancer) transport() http.RoundTripper {
+	if lb.Transport != nil {
+		return lb.Transport
+	}
+	return http.DefaultTransport
+}
+
+func (lb *LoadBalancer) maxRetries() int {
+	switch {
+	case lb.MaxRetries < 0:
+		return 0
+	case lb.MaxRetries == 0:
+		return defaultMaxRetries
+	}
+	return lb.MaxRetries
+}
+
+func (lb *LoadBalancer) maxRetryAfter() time.Duration {
+	if lb.MaxRetryAfter != 0 {
+		return lb.MaxRetryAfter
+	}
+	return defaultMaxRetryAfter
+}
+
+func (lb *LoadBalancer) ejectThreshold() int {
+	if lb.EjectThreshold != 0 {
+		return lb.EjectThreshold
+	}
+	return defaultEjectThreshold
+}
+
+func (lb *LoadBalancer) ejectDuration() time.Duration {
+	if lb.EjectDuration != 0 {
+		return lb.EjectDuration
+	}
+	return defaultEjectDuration
+}
+
+var errNoBackends = errors.New("httputil: LoadBalancer has no backends")
+
+// RoundTrip implements the http.RoundTripper interface.
+func (lb *LoadBalancer) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	retries := 0
+	if canRetry(req) {
+		retries = lb.maxRetries()
+	}
+	var tried []*lbBackend
+	for {
+		b := lb.pick(tried)
+		if b == nil {
+			closeRequestBody(req)
+			return nil, errNoBackends
+		}
+		tried = append(tried, b)
+
+		outreq := req.Clone(ctx)
+		if len(tried) > 1 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				lb.release(b)
+				closeRequestBody(req)
+				return nil, err
+			}
+			outreq.Body = body
+		}
+		rewriteRequestURL(outreq, b.url)
+
+		res, err := lb.transport().RoundTrip(outreq)
+		if err != nil {
+			if cerr := outreq.Context().Err(); cerr != nil {
+				// The caller gave up; that says nothing about the
+				// health of the backend, and there is no point in
+				// trying another.
+				lb.release(b)
+				closeRequestBody(req)
+				return nil, cerr
+			}
+		}
+		lb.report(b, err != nil || res.StatusCode >= 500)
+		if err != nil {
+			lb.release(b)
+		} else {
+			res.Body = newLBBody(res.Body, func() { lb.release(b) })
+		}
+
+		if retries == 0 || !shouldRetry(res, err) {
+			return res, err
+		}
+		var delay time.Duration
+		if err == nil {
+			var ok bool
+			delay, ok = retryAfter(res.Header.Get("Retry-After"), time.Now())
+			if ok && delay > lb.maxRetryAfter() {
+				return res, nil
+			}
+			discardBody(res.Body)
+		}
+		retries--
+		if delay > 0 {
+			t := time.NewTimer(delay)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				closeRequestBody(req)
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
+
+// pick selects a backend according to the policy and marks a request
+// in flight on it. It avoids the ejected backends and those that
+// were already tried, if it can. It returns nil if there are no
+// backends.
+func (lb *LoadBalancer) pick(tried []*lbBackend) *lbBackend {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if len(lb.backends) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	healthy := func(b *lbBackend) bool {
+		return !now.Before(b.ejectedUntil)
+	}
+	untried := func(b *lbBackend) bool {
+		for _, t := range tried {
+			if b == t {
+				return false
+			}
+		}
+		return true
+	}
+	// Each candidate is the index of a backend in lb.backends,
+	// in turn starting from lb.next.
+	var candidates []int
+	for _, keep := range []func(*lbBackend) bool{
+		func(b *lbBackend) bool { return healthy(b) && untried(b) },
+		healthy,
+		untried,
+		func(*lbBackend) bool { return true },
+	} {
+		for i := range lb.backends {
+			j := (lb.next + i) % len(lb.backends)
+			if keep(lb.backends[j]) {
+				candidates = append(candidates, j)
+			}
+		}
+		if len(candidates) > 0 {
+			break
+		}
+	}
+
+	i := candidates[0]
+	switch lb.Policy {
+	case LeastOutstanding:
+		for _, j := range candidates[1:] {
+			if lb.backends[j].outstanding < lb.backends[i].outstanding {
+				i = j
+			}
+		}
+	case PowerOfTwoChoices:
+		if len(candidates) > 1 {
+			k := rand.Intn(len(candidates))
+			l := rand.Intn(len(candidates) - 1)
+			if l >= k {
+				l++
+			}
+			i = candidates[k]
+			if j := candidates[l]; lb.backends[j].outstanding < lb.backends[i].outstanding {
+				i = j
+			}
+		}
+	}
+	lb.next = (i + 1) % len(lb.backends)
+
+	b := lb.backends[i]
+	b.outstanding++
+	return b
+}
+
+// report records the outcome of a request sent to b.
+func (lb *LoadBalancer) report(b *lbBackend, failed bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if !failed {
+		b.failures = 0
+		b.ejectedUntil = time.Time{}
+		return
+	}
+	b.failures++
+	if t := lb.ejectThreshold(); t > 0 && b.failures >= t {
+		b.failures = 0
+		b.ejectedUntil = time.Now().Add(lb.ejectDuration())
+	}
+}
+
+// release records the end of a request in flight on b.
+func (lb *LoadBalancer) release(b *lbBackend) {
+	lb.mu.Lock()
+	b.outstanding--
+	lb.mu.Unlock()
+}
+
+// canRetry reports whether req may be sent several times.
+func canRetry(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	// The Idempotency-Key, while non-standard, is widely used to
+	// mean a POST or other request is idempotent.
+	// See https://golang.org/issue/19943#issuecomment-421092421.
+	_, ok := req.Header["Idempotency-Key"]
+	_, xok := req.Header["X-Idempotency-Key"]
+	return ok || xok
+}
+
+// shouldRetry reports whether a request that resulted in res and err
+// should be retried.
+func shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+}
+
+// retryAfter returns the delay requested by the Retry-After header
+// value v, either a number of seconds or an HTTP date, relative to now.
+// It reports whether v is valid.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = textproto.TrimString(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseUint(v, 10, 32); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// maxDiscard is the most a body is read to reuse its connection
+// before it is closed.
+const maxDiscard = 4 << 10
+
+func discardBody(body io.ReadCloser) {
+	io.CopyN(io.Discard, body, maxDiscard)
+	body.Close()
+}
+
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// An lbBody is the body of a response from a backend. It calls done
+// once it is closed or read to the end.
+type lbBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+// An lbReadWriteBody is an lbBody that is also writable, as is the
+// body of a 101 Switching Protocols response.
+type lbReadWriteBody struct {
+	*lbBody
+	w io.Writer
+}
+
+func (b lbReadWriteBody) Write(p []byte) (int, error) {
+	return b.w.Write(p)
+}
+
+func newLBBody(body io.ReadCloser, done func()) io.ReadCloser {
+	b := &lbBody{ReadCloser: body, done: done}
+	if w, ok := body.(io.Writer); ok {
+		return lbReadWriteBody{b, w}
+	}
+	return b
+}
+
+func (b *lbBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.done)
+	}
+	return n, err
+}
+
+func (b *lbBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
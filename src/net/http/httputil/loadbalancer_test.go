@@ -0,0 +1,425 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Load balancer tests.
+
+package httputil
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newLBBackends starts a backend server for each handler and returns
+// their URLs.
+func newLBBackends(t *testing.T, handlers ...http.HandlerFunc) []*url.URL {
+	t.Helper()
+	var urls []*url.URL
+	for _, h := range handlers {
+		s := httptest.NewServer(h)
+		t.Cleanup(s.Close)
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+// reply returns a handler replying name with the given status.
+func reply(name string, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		io.WriteString(w, name)
+	}
+}
+
+// lbGet sends a GET request through lb and returns the body of the
+// response, and its status.
+func lbGet(t *testing.T, lb *LoadBalancer) (string, int) {
+	t.Helper()
+	res, err := (&http.Client{Transport: lb}).Get("http://service/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body), res.StatusCode
+}
+
+func TestLoadBalancerRoundRobin(t *testing.T) {
+	lb := &LoadBalancer{}
+	lb.SetBackends(newLBBackends(t, reply("a", 200), reply("b", 200), reply("c", 200)))
+	var got []string
+	for i := 0; i < 6; i++ {
+		body, _ := lbGet(t, lb)
+		got = append(got, body)
+	}
+	if got, want := strings.Join(got, ""), "abcabc"; got != want {
+		t.Errorf("backends got requests in order %q, want %q", got, want)
+	}
+}
+
+func TestLoadBalancerNoBackends(t *testing.T) {
+	lb := &LoadBalancer{}
+	req := httptest.NewRequest("GET", "http://service/", nil)
+	if _, err := lb.RoundTrip(req); err == nil {
+		t.Errorf("RoundTrip succeeded without backends")
+	}
+}
+
+func TestLoadBalancerSetURL(t *testing.T) {
+	urls := newLBBackends(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.RequestURI())
+	})
+	backend := *urls[0]
+	backend.Path = "/base"
+	backend.RawQuery = "sta=tic"
+	lb := &LoadBalancer{}
+	lb.SetBackends([]*url.URL{&backend})
+	res, err := (&http.Client{Transport: lb}).Get("http://service/dir?us=er")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if got, want := string(body), "/base/dir?sta=tic&us=er"; got != want {
+		t.Errorf("backend got request for %q, want %q", got, want)
+	}
+}
+
+// testOutstanding checks that the policy of lb sends requests to the
+// backend without a request in flight.
+func testOutstanding(t *testing.T, lb *LoadBalancer) {
+	var holding int32
+	block := make(chan struct{})
+	started := make(chan string)
+	// The first request is held by whichever backend receives it.
+	hold := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if atomic.CompareAndSwapInt32(&holding, 0, 1) {
+				started <- name
+				<-block
+			}
+			io.WriteString(w, name)
+		}
+	}
+	lb.SetBackends(newLBBackends(t, hold("a"), hold("b")))
+
+	errc := make(chan error, 1)
+	go func() {
+		res, err := (&http.Client{Transport: lb}).Get("http://service/")
+		if err == nil {
+			res.Body.Close()
+		}
+		errc <- err
+	}()
+	busy := <-started
+	for i := 0; i < 4; i++ {
+		if body, _ := lbGet(t, lb); body == busy {
+			t.Errorf("request %d went to %q, which has a request in flight", i, body)
+		}
+	}
+	close(block)
+	if err := <-errc; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadBalancerLeastOutstanding(t *testing.T) {
+	testOutstanding(t, &LoadBalancer{Policy: LeastOutstanding})
+}
+
+func TestLoadBalancerPowerOfTwoChoices(t *testing.T) {
+	testOutstanding(t, &LoadBalancer{Policy: PowerOfTwoChoices})
+}
+
+func TestLoadBalancerEjection(t *testing.T) {
+	lb := &LoadBalancer{
+		MaxRetries:     -1,
+		EjectThreshold: 2,
+		EjectDuration:  100 * time.Millisecond,
+	}
+	lb.SetBackends(newLBBackends(t, reply("a", 500), reply("b", 200)))
+	var got []string
+	for i := 0; i < 6; i++ {
+		body, _ := lbGet(t, lb)
+		got = append(got, body)
+	}
+	// a is ejected after its second failure.
+	if got, want := strings.Join(got, ""), "ababbb"; got != want {
+		t.Errorf("backends got requests in order %q, want %q", got, want)
+	}
+
+	// The ejection is kept along with the backend.
+	lb.SetBackends(append(lb.Backends(), newLBBackends(t, reply("c", 200))...))
+	for i := 0; i < 4; i++ {
+		if body, _ := lbGet(t, lb); body == "a" {
+			t.Fatalf("ejected backend got a request")
+		}
+	}
+
+	time.Sleep(lb.EjectDuration)
+	got = nil
+	for i := 0; i < 3; i++ {
+		body, _ := lbGet(t, lb)
+		got = append(got, body)
+	}
+	if joined := strings.Join(got, ""); !strings.Contains(joined, "a") {
+		t.Errorf("backends got requests %q after the ejection expired, want a among them", joined)
+	}
+}
+
+func TestLoadBalancerAllEjected(t *testing.T) {
+	lb := &LoadBalancer{MaxRetries: -1, EjectThreshold: 1}
+	lb.SetBackends(newLBBackends(t, reply("a", 503)))
+	for i := 0; i < 3; i++ {
+		if body, status := lbGet(t, lb); body != "a" || status != 503 {
+			t.Errorf("got %q with status %v, want a response from the ejected backend", body, status)
+		}
+	}
+}
+
+func TestLoadBalancerRetry(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		gotBody string
+	)
+	lb := &LoadBalancer{EjectThreshold: -1}
+	lb.SetBackends(newLBBackends(t,
+		reply("a", 503),
+		func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			gotBody = string(b)
+			mu.Unlock()
+			io.WriteString(w, "b")
+		},
+	))
+	client := &http.Client{Transport: lb}
+
+	if body, status := lbGet(t, lb); body != "b" || status != 200 {
+		t.Errorf("GET got %q with status %v, want a response from b", body, status)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		key     bool // set an Idempotency-Key header
+		getBody bool // keep the GetBody function of the request
+		want    string
+	}{
+		{"idempotency key", true, true, "b"},
+		{"no idempotency key", false, true, "a"},
+		{"body not replayable", true, false, "a"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			lb.mu.Lock()
+			lb.next = 0 // a first, again
+			lb.mu.Unlock()
+			mu.Lock()
+			gotBody = ""
+			mu.Unlock()
+			req, err := http.NewRequest("POST", "http://service/", strings.NewReader("request body"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.key {
+				req.Header.Set("Idempotency-Key", "123")
+			}
+			if !tt.getBody {
+				req.GetBody = nil
+			}
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			if string(body) != tt.want {
+				t.Errorf("got response from %q, want %q", body, tt.want)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if tt.want == "b" && gotBody != "request body" {
+				t.Errorf("retried request has body %q, want %q", gotBody, "request body")
+			}
+		})
+	}
+}
+
+func TestLoadBalancerRetryAfter(t *testing.T) {
+	var first int32 = 1
+	lb := &LoadBalancer{MaxRetryAfter: 2 * time.Second}
+	lb.SetBackends(newLBBackends(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.CompareAndSwapInt32(&first, 1, 0) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, "a")
+	}))
+	start := time.Now()
+	if body, status := lbGet(t, lb); body != "a" || status != 200 {
+		t.Errorf("got %q with status %v, want a retried response", body, status)
+	}
+	if d := time.Since(start); d < time.Second {
+		t.Errorf("retried after %v, want a delay of at least 1s", d)
+	}
+
+	// A longer delay than MaxRetryAfter is not waited for.
+	atomic.StoreInt32(&first, 1)
+	lb.MaxRetryAfter = 500 * time.Millisecond
+	if _, status := lbGet(t, lb); status != http.StatusTooManyRequests {
+		t.Errorf("got status %v, want %v", status, http.StatusTooManyRequests)
+	}
+}
+
+// closeRecorder is a request body which records whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed int32
+}
+
+func (c *closeRecorder) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+func TestLoadBalancerRetryAfterCanceled(t *testing.T) {
+	u, _ := url.Parse("http://backend/")
+	lb := &LoadBalancer{
+		MaxRetryAfter: time.Hour,
+		// Never close the request body, so that only RoundTrip can.
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Header:     http.Header{"Retry-After": {"60"}},
+				Body:       io.NopCloser(strings.NewReader("")),
+				Request:    req,
+			}, nil
+		}),
+	}
+	lb.SetBackends([]*url.URL{u})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	body := &closeRecorder{Reader: strings.NewReader("request body")}
+	req, err := http.NewRequestWithContext(ctx, "PUT", "http://service/", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("request body")), nil
+	}
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := lb.RoundTrip(req); err != context.Canceled {
+		t.Fatalf("RoundTrip = %v, want context.Canceled", err)
+	}
+	if atomic.LoadInt32(&body.closed) == 0 {
+		t.Errorf("request body was not closed")
+	}
+}
+
+func TestLoadBalancerCanceledRequest(t *testing.T) {
+	var retried int32
+	lb := &LoadBalancer{EjectThreshold: 1}
+	lb.SetBackends(newLBBackends(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				<-r.Context().Done()
+				return
+			}
+			io.WriteString(w, "a")
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				atomic.StoreInt32(&retried, 1)
+			}
+			io.WriteString(w, "b")
+		},
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://service/slow", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lb.RoundTrip(req); err != context.DeadlineExceeded {
+		t.Fatalf("RoundTrip = %v, want context.DeadlineExceeded", err)
+	}
+	if atomic.LoadInt32(&retried) != 0 {
+		t.Errorf("canceled request was retried on another backend")
+	}
+
+	// The backend that was slow to reply is still healthy.
+	var got []string
+	for i := 0; i < 2; i++ {
+		body, _ := lbGet(t, lb)
+		got = append(got, body)
+	}
+	if got, want := strings.Join(got, ""), "ba"; got != want {
+		t.Errorf("backends got requests in order %q, want %q", got, want)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, time.October, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		v      string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"-1", 0, false},
+		{"Fri, 01 Oct 2021 12:00:30 GMT", 30 * time.Second, true},
+		{"Fri, 01 Oct 2021 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	} {
+		got, ok := retryAfter(tt.v, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tt.v, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestLoadBalancerReverseProxy(t *testing.T) {
+	lb := &LoadBalancer{}
+	lb.SetBackends(newLBBackends(t,
+		reply("a", 502),
+		func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "b "+r.URL.Path+" "+r.Header.Get("X-Forwarded-Proto"))
+		},
+	))
+	frontend := httptest.NewServer(&ReverseProxy{
+		Rewrite: func(r *ProxyRequest) {
+			r.SetXForwarded()
+		},
+		Transport: lb,
+	})
+	defer frontend.Close()
+
+	res, err := frontend.Client().Get(frontend.URL + "/path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if got, want := string(body), "b /path http"; got != want {
+		t.Errorf("got response %q, want %q", got, want)
+	}
+}
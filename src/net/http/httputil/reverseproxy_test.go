@@ -10,6 +10,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -369,6 +370,238 @@ func TestXForwardedFor_Omit(t *testing.T) {
 	res.Body.Close()
 }
 
+func TestReverseProxyRewriteStripsForwarded(t *testing.T) {
+	headers := []string{
+		"Forwarded",
+		"X-Forwarded-For",
+		"X-Forwarded-Host",
+		"X-Forwarded-Proto",
+	}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, h := range headers {
+			if v := r.Header.Get(h); v != "" {
+				t.Errorf("got %v header: %q", h, v)
+			}
+		}
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyHandler := &ReverseProxy{
+		Rewrite: func(r *ProxyRequest) {
+			r.SetURL(backendURL)
+		},
+	}
+	frontend := httptest.NewServer(proxyHandler)
+	defer frontend.Close()
+
+	getReq, _ := http.NewRequest("GET", frontend.URL, nil)
+	getReq.Host = "some-name"
+	getReq.Close = true
+	for _, h := range headers {
+		getReq.Header.Set(h, "x")
+	}
+	res, err := frontend.Client().Do(getReq)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	res.Body.Close()
+}
+
+func TestReverseProxyRewriteKeepsHopHeaders(t *testing.T) {
+	// Rewrite is called after hop-by-hop headers are removed,
+	// so the headers it sets are sent to the backend.
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get(fakeHopHeader), "from rewrite"; got != want {
+			t.Errorf("backend got %v header %q, want %q", fakeHopHeader, got, want)
+		}
+		if got, want := r.Header.Get("X-From-Client"), "client"; got != want {
+			t.Errorf("backend got X-From-Client header %q, want %q", got, want)
+		}
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyHandler := &ReverseProxy{
+		Rewrite: func(r *ProxyRequest) {
+			if v := r.Out.Header.Get(fakeHopHeader); v != "" {
+				t.Errorf("Rewrite got %v header %q, want it removed", fakeHopHeader, v)
+			}
+			if v := r.In.Header.Get(fakeHopHeader); v != "client" {
+				t.Errorf("inbound request has %v header %q, want %q", fakeHopHeader, v, "client")
+			}
+			r.SetURL(backendURL)
+			r.Out.Header.Set(fakeHopHeader, "from rewrite")
+		},
+	}
+	frontend := httptest.NewServer(proxyHandler)
+	defer frontend.Close()
+
+	getReq, _ := http.NewRequest("GET", frontend.URL, nil)
+	getReq.Header.Set(fakeHopHeader, "client")
+	getReq.Header.Set("X-From-Client", "client")
+	getReq.Close = true
+	res, err := frontend.Client().Do(getReq)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	res.Body.Close()
+}
+
+func TestReverseProxyRewriteReplacesOut(t *testing.T) {
+	const content = "response_content"
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Replaced")))
+	}))
+	defer backend.Close()
+	proxyHandler := &ReverseProxy{
+		Rewrite: func(r *ProxyRequest) {
+			r.Out, _ = http.NewRequest("GET", backend.URL, nil)
+			r.Out.Header.Set("X-Replaced", content)
+		},
+	}
+	frontend := httptest.NewServer(proxyHandler)
+	defer frontend.Close()
+
+	res, err := frontend.Client().Get(frontend.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if got, want := string(body), content; got != want {
+		t.Errorf("got response %q, want %q", got, want)
+	}
+}
+
+func TestReverseProxyDirectorAndRewrite(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		proxy *ReverseProxy
+	}{
+		{"neither", &ReverseProxy{}},
+		{"both", &ReverseProxy{
+			Director: func(*http.Request) {},
+			Rewrite:  func(*ProxyRequest) {},
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.proxy.ErrorLog = log.New(io.Discard, "", 0) // quiet for tests
+			rw := httptest.NewRecorder()
+			tt.proxy.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+			if got, want := rw.Code, http.StatusBadGateway; got != want {
+				t.Errorf("got status %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSetURL(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + " " + r.URL.RequestURI()))
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL + "/base?sta=tic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyHandler := &ReverseProxy{
+		Rewrite: func(r *ProxyRequest) {
+			r.SetURL(backendURL)
+		},
+	}
+	frontend := httptest.NewServer(proxyHandler)
+	defer frontend.Close()
+
+	res, err := frontend.Client().Get(frontend.URL + "/dir?us=er")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Reading body: %v", err)
+	}
+	if got, want := string(body), backendURL.Host+" /base/dir?sta=tic&us=er"; got != want {
+		t.Errorf("backend got %q, want %q", got, want)
+	}
+}
+
+func TestSetXForwarded(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		remoteAddr string
+		tls        bool
+		prior      []string // X-Forwarded-For of the outbound request
+		wantFor    string
+		wantProto  string
+	}{
+		{
+			name:       "http",
+			remoteAddr: "192.0.2.1:1234",
+			wantFor:    "192.0.2.1",
+			wantProto:  "http",
+		},
+		{
+			name:       "https",
+			remoteAddr: "192.0.2.1:1234",
+			tls:        true,
+			wantFor:    "192.0.2.1",
+			wantProto:  "https",
+		},
+		{
+			name:       "ipv6",
+			remoteAddr: "[2001:db8::1]:1234",
+			wantFor:    "2001:db8::1",
+			wantProto:  "http",
+		},
+		{
+			name:       "prior",
+			remoteAddr: "192.0.2.1:1234",
+			prior:      []string{"198.51.100.1", "198.51.100.2"},
+			wantFor:    "198.51.100.1, 198.51.100.2, 192.0.2.1",
+			wantProto:  "http",
+		},
+		{
+			name:       "bad remote address",
+			remoteAddr: "not an address",
+			prior:      []string{"198.51.100.1"},
+			wantFor:    "",
+			wantProto:  "http",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			in := httptest.NewRequest("GET", "http://example.com/", nil)
+			in.RemoteAddr = tt.remoteAddr
+			if !tt.tls {
+				in.TLS = nil
+			} else if in.TLS == nil {
+				in.TLS = &tls.ConnectionState{}
+			}
+			out := in.Clone(context.Background())
+			out.Header.Del("X-Forwarded-For")
+			if tt.prior != nil {
+				out.Header["X-Forwarded-For"] = tt.prior
+			}
+			pr := &ProxyRequest{In: in, Out: out}
+			pr.SetXForwarded()
+			if got := out.Header.Get("X-Forwarded-For"); got != tt.wantFor {
+				t.Errorf("X-Forwarded-For = %q, want %q", got, tt.wantFor)
+			}
+			if got, want := out.Header.Get("X-Forwarded-Host"), "example.com"; got != want {
+				t.Errorf("X-Forwarded-Host = %q, want %q", got, want)
+			}
+			if got := out.Header.Get("X-Forwarded-Proto"); got != tt.wantProto {
+				t.Errorf("X-Forwarded-Proto = %q, want %q", got, tt.wantProto)
+			}
+		})
+	}
+}
+
 var proxyQueryTests = []struct {
 	baseSuffix string // suffix to add to backend URL
 	reqSuffix  string // suffix to add to frontend's request URL
@@ -0,0 +1,198 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package qpack implements the QPACK field compression format
+// used by HTTP/3, as specified in RFC 9204.
+//
+// Only the static table is supported. Encoded field sections never
+// refer to the dynamic table, and field sections which do are
+// rejected, which is permitted when the decoder advertises a
+// dynamic table capacity of zero.
+package qpack
+
+import (
+	"errors"
+
+	"golang.org/x/net/http2/hpack"
+)
+
+// A HeaderField is a name-value pair.
+type HeaderField struct {
+	Name, Value string
+
+	// Sensitive means that this header field should never be
+	// indexed by an intermediary.
+	Sensitive bool
+}
+
+// Size returns the size of the field as defined by RFC 9204, Section 3.2.1.
+// It is used to enforce limits on the size of field sections.
+func (f HeaderField) Size() uint64 {
+	return uint64(len(f.Name)) + uint64(len(f.Value)) + 32
+}
+
+// ErrDecompressionFailed is returned for field sections which cannot be
+// decoded. HTTP/3 treats it as a connection error of type
+// QPACK_DECOMPRESSION_FAILED.
+var ErrDecompressionFailed = errors.New("qpack: decompression failed")
+
+// AppendFieldSectionPrefix appends the prefix of an encoded field section
+// to b. The field lines follow, encoded with AppendField.
+func AppendFieldSectionPrefix(b []byte) []byte {
+	// Required Insert Count and Delta Base are both 0
+	// (RFC 9204, Section 4.5.1).
+	return append(b, 0, 0)
+}
+
+// AppendField appends the encoding of a field line to b.
+// Field names should be lowercase.
+func AppendField(b []byte, f HeaderField) []byte {
+	if !f.Sensitive {
+		if i, ok := staticByNameValue[pairNameValue{f.Name, f.Value}]; ok {
+			// Indexed Field Line, static table (RFC 9204, Section 4.5.2).
+			return appendInt(b, 6, 0xc0, uint64(i))
+		}
+	}
+	var n byte
+	if f.Sensitive {
+		n = 0x20
+	}
+	if i, ok := staticByName[f.Name]; ok {
+		// Literal Field Line with Name Reference, static table
+		// (RFC 9204, Section 4.5.4).
+		b = appendInt(b, 4, 0x50|n, uint64(i))
+	} else {
+		// Literal Field Line with Literal Name (RFC 9204, Section 4.5.6).
+		b = appendString(b, 3, 0x20|n>>1, f.Name)
+	}
+	return appendString(b, 7, 0, f.Value)
+}
+
+// DecodeFieldSection decodes an encoded field section,
+// calling f for each field line.
+func DecodeFieldSection(b []byte, f func(HeaderField)) error {
+	ric, b, ok := consumeInt(b, 8)
+	if !ok || ric != 0 {
+		// A non-zero Required Insert Count refers to the dynamic table,
+		// which has a capacity of zero.
+		return ErrDecompressionFailed
+	}
+	if _, b, ok = consumeInt(b, 7); !ok {
+		return ErrDecompressionFailed
+	}
+	for len(b) > 0 {
+		var hf HeaderField
+		switch {
+		case b[0]&0x80 != 0: // Indexed Field Line
+			if b[0]&0x40 == 0 {
+				return ErrDecompressionFailed // dynamic table
+			}
+			var i uint64
+			if i, b, ok = consumeInt(b, 6); !ok || i >= uint64(len(staticTable)) {
+				return ErrDecompressionFailed
+			}
+			hf = staticTable[i]
+		case b[0]&0x40 != 0: // Literal Field Line with Name Reference
+			if b[0]&0x10 == 0 {
+				return ErrDecompressionFailed // dynamic table
+			}
+			hf.Sensitive = b[0]&0x20 != 0
+			var i uint64
+			if i, b, ok = consumeInt(b, 4); !ok || i >= uint64(len(staticTable)) {
+				return ErrDecompressionFailed
+			}
+			hf.Name = staticTable[i].Name
+			if hf.Value, b, ok = consumeString(b, 7); !ok {
+				return ErrDecompressionFailed
+			}
+		case b[0]&0x20 != 0: // Literal Field Line with Literal Name
+			hf.Sensitive = b[0]&0x10 != 0
+			if hf.Name, b, ok = consumeString(b, 3); !ok {
+				return ErrDecompressionFailed
+			}
+			if hf.Value, b, ok = consumeString(b, 7); !ok {
+				return ErrDecompressionFailed
+			}
+		default: // post-base references to the dynamic table
+			return ErrDecompressionFailed
+		}
+		f(hf)
+	}
+	return nil
+}
+
+// appendInt appends v encoded as an integer with an n-bit prefix
+// (RFC 7541, Section 5.1). The high bits of the first byte are taken
+// from flags.
+func appendInt(b []byte, n uint, flags byte, v uint64) []byte {
+	max := uint64(1)<<n - 1
+	if v < max {
+		return append(b, flags|byte(v))
+	}
+	b = append(b, flags|byte(max))
+	v -= max
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// consumeInt parses an integer with an n-bit prefix.
+func consumeInt(b []byte, n uint) (v uint64, rest []byte, ok bool) {
+	if len(b) == 0 {
+		return 0, nil, false
+	}
+	max := uint64(1)<<n - 1
+	v = uint64(b[0]) & max
+	b = b[1:]
+	if v < max {
+		return v, b, true
+	}
+	for shift := uint(0); len(b) > 0; shift += 7 {
+		if shift > 56 {
+			return 0, nil, false
+		}
+		c := b[0]
+		b = b[1:]
+		v += uint64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			return v, b, true
+		}
+	}
+	return 0, nil, false
+}
+
+// appendString appends a string literal with a Huffman flag followed by
+// a length with an n-bit prefix, using Huffman encoding when it is shorter.
+func appendString(b []byte, n uint, flags byte, s string) []byte {
+	if l := hpack.HuffmanEncodeLength(s); l < uint64(len(s)) {
+		b = appendInt(b, n, flags|1<<n, l)
+		return hpack.AppendHuffmanString(b, s)
+	}
+	b = appendInt(b, n, flags, uint64(len(s)))
+	return append(b, s...)
+}
+
+// consumeString parses a string literal encoded by appendString.
+func consumeString(b []byte, n uint) (s string, rest []byte, ok bool) {
+	if len(b) == 0 {
+		return "", nil, false
+	}
+	huffman := b[0]&(1<<n) != 0
+	l, b, ok := consumeInt(b, n)
+	if !ok || l > uint64(len(b)) {
+		return "", nil, false
+	}
+	data := b[:l]
+	b = b[l:]
+	if !huffman {
+		return string(data), b, true
+	}
+	s, err := hpack.HuffmanDecodeToString(data)
+	if err != nil {
+		return "", nil, false
+	}
+	return s, b, true
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStaticTable(t *testing.T) {
+	if len(staticTable) != 99 {
+		t.Fatalf("static table has %v entries, want 99", len(staticTable))
+	}
+	for _, test := range []struct {
+		i    int
+		name string
+	}{
+		{0, ":authority"},
+		{17, ":method"},
+		{25, ":status"},
+		{63, ":status"},
+		{98, "x-frame-options"},
+	} {
+		if got := staticTable[test.i].Name; got != test.name {
+			t.Errorf("staticTable[%v].Name = %q, want %q", test.i, got, test.name)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	fields := []HeaderField{
+		{Name: ":method", Value: "GET"},
+		{Name: ":path", Value: "/index.html"},
+		{Name: ":authority", Value: "www.example.com"},
+		{Name: "user-agent", Value: "Go-http-client/3.0"},
+		{Name: "x-custom", Value: strings.Repeat("abc", 100)},
+		{Name: "x-empty"},
+		{Name: "authorization", Value: "secret", Sensitive: true},
+		{Name: "x-secret", Value: "secret", Sensitive: true},
+		{Name: "x-binary", Value: "\x00\xff"},
+	}
+	b := AppendFieldSectionPrefix(nil)
+	for _, f := range fields {
+		b = AppendField(b, f)
+	}
+	var got []HeaderField
+	if err := DecodeFieldSection(b, func(f HeaderField) {
+		got = append(got, f)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, fields) {
+		t.Errorf("round trip:\n got %+v\nwant %+v", got, fields)
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	for _, test := range []struct {
+		f    HeaderField
+		want string
+	}{
+		// Indexed field lines.
+		{HeaderField{Name: ":method", Value: "GET"}, "d1"},
+		{HeaderField{Name: ":status", Value: "500"}, "ff08"},
+		// Literal field lines with a static name reference,
+		// with a Huffman-encoded value when it is shorter.
+		{HeaderField{Name: ":path", Value: "/index.html"}, "518860d5485f2bce9a68"},
+		{HeaderField{Name: ":path", Value: "/{}"}, "51032f7b7d"},
+		// Literal field line with a literal name.
+		{HeaderField{Name: "a", Value: "b"}, "2161" + "0162"},
+		{HeaderField{Name: "a", Value: "b", Sensitive: true}, "3161" + "0162"},
+	} {
+		got := AppendField(nil, test.f)
+		if want, _ := hex.DecodeString(test.want); !bytes.Equal(got, want) {
+			t.Errorf("AppendField(%+v) = %x, want %v", test.f, got, test.want)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		b    string
+	}{
+		{"empty", ""},
+		{"dynamic table in use", "0200"},
+		{"indexed dynamic", "0000" + "80"},
+		{"name reference dynamic", "0000" + "4000"},
+		{"post-base index", "0000" + "10"},
+		{"static index out of range", "0000" + "ff24"},
+		{"truncated integer", "0000" + "ff"},
+		{"truncated string", "0000" + "2561"},
+		{"invalid huffman", "0000" + "5183ffffff"},
+	} {
+		b, _ := hex.DecodeString(test.b)
+		if err := DecodeFieldSection(b, func(HeaderField) {}); err != ErrDecompressionFailed {
+			t.Errorf("%v: DecodeFieldSection(%v) = %v, want ErrDecompressionFailed", test.name, test.b, err)
+		}
+	}
+}
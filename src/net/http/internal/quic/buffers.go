@@ -0,0 +1,168 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+// A sendBuffer holds data written to a stream or CRYPTO stream
+// until the peer acknowledges it.
+type sendBuffer struct {
+	buf    []byte   // data starting at offset base
+	base   int64    // all data before base has been acknowledged
+	acked  rangeset // acknowledged ranges at or after base
+	unsent rangeset // ranges which need to be sent, new or lost
+}
+
+// end returns the offset of the end of the buffered data.
+func (b *sendBuffer) end() int64 {
+	return b.base + int64(len(b.buf))
+}
+
+// unacked returns the number of buffered bytes which haven't been acknowledged.
+func (b *sendBuffer) unacked() int {
+	return len(b.buf)
+}
+
+// write appends data to the buffer.
+func (b *sendBuffer) write(p []byte) {
+	start := b.end()
+	b.buf = append(b.buf, p...)
+	b.unsent.add(start, b.end())
+}
+
+// hasUnsent reports whether there is data to send.
+func (b *sendBuffer) hasUnsent() bool {
+	return len(b.unsent) > 0
+}
+
+// next returns the first range of data which needs to be sent,
+// limited to n bytes and to data before offset limit.
+func (b *sendBuffer) next(n int, limit int64) (off int64, data []byte) {
+	if len(b.unsent) == 0 {
+		return 0, nil
+	}
+	r := b.unsent[0]
+	if r.end > limit {
+		r.end = limit
+	}
+	if r.end-r.start > int64(n) {
+		r.end = r.start + int64(n)
+	}
+	if r.end <= r.start {
+		return r.start, nil
+	}
+	return r.start, b.buf[r.start-b.base : r.end-b.base]
+}
+
+// sent records that [off, off+n) has been sent.
+func (b *sendBuffer) sent(off int64, n int) {
+	b.unsent.sub(off, off+int64(n))
+}
+
+// ack records that [off, off+n) has been acknowledged.
+func (b *sendBuffer) ack(off int64, n int) {
+	if off+int64(n) <= b.base {
+		return
+	}
+	b.acked.add(off, off+int64(n))
+	b.unsent.sub(off, off+int64(n)) // it may have been declared lost
+	if len(b.acked) > 0 && b.acked[0].start <= b.base {
+		end := b.acked[0].end
+		b.buf = b.buf[end-b.base:]
+		if len(b.buf) == 0 {
+			b.buf = nil
+		}
+		b.base = end
+		b.acked.removeBefore(end)
+	}
+}
+
+// lost records that [off, off+n) was lost and needs to be resent,
+// excluding any parts which have since been acknowledged.
+func (b *sendBuffer) lost(off int64, n int) {
+	start, end := off, off+int64(n)
+	if start < b.base {
+		start = b.base
+	}
+	for start < end {
+		if r := b.acked.rangeContaining(start); r.size() > 0 {
+			start = r.end
+			continue
+		}
+		stop := end
+		for _, r := range b.acked {
+			if r.start > start && r.start < stop {
+				stop = r.start
+				break
+			}
+		}
+		b.unsent.add(start, stop)
+		start = stop
+	}
+}
+
+// A recvBuffer reassembles data received out of order.
+type recvBuffer struct {
+	buf      []byte   // data starting at offset readOff
+	readOff  int64    // data before readOff has been consumed
+	received rangeset // ranges received at or after readOff
+}
+
+// write records data received at offset off.
+func (b *recvBuffer) write(off int64, data []byte) {
+	end := off + int64(len(data))
+	if end <= b.readOff {
+		return
+	}
+	if off < b.readOff {
+		data = data[b.readOff-off:]
+		off = b.readOff
+	}
+	if need := int(end - b.readOff); need > len(b.buf) {
+		if need <= cap(b.buf) {
+			b.buf = b.buf[:need]
+		} else {
+			b.buf = append(b.buf, make([]byte, need-len(b.buf))...)
+		}
+	}
+	copy(b.buf[off-b.readOff:], data)
+	b.received.add(off, end)
+}
+
+// end returns the offset just past the largest received byte.
+func (b *recvBuffer) end() int64 {
+	if e := b.received.end(); e > b.readOff {
+		return e
+	}
+	return b.readOff
+}
+
+// readable returns the number of bytes which may be read.
+func (b *recvBuffer) readable() int {
+	if len(b.received) == 0 || b.received[0].start > b.readOff {
+		return 0
+	}
+	return int(b.received[0].end - b.readOff)
+}
+
+// peek returns the contiguous data available to read.
+func (b *recvBuffer) peek() []byte {
+	return b.buf[:b.readable()]
+}
+
+// consume discards n bytes of readable data.
+func (b *recvBuffer) consume(n int) {
+	b.buf = b.buf[n:]
+	if len(b.buf) == 0 {
+		b.buf = nil
+	}
+	b.readOff += int64(n)
+	b.received.removeBefore(b.readOff)
+}
+
+// read reads contiguous data into p.
+func (b *recvBuffer) read(p []byte) int {
+	n := copy(p, b.peek())
+	b.consume(n)
+	return n
+}
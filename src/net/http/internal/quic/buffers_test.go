@@ -0,0 +1,92 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRangeset(t *testing.T) {
+	var s rangeset
+	s.add(10, 20)
+	s.add(0, 5)
+	s.add(30, 40)
+	s.add(20, 25) // adjacent ranges merge
+	want := rangeset{{0, 5}, {10, 25}, {30, 40}}
+	if !reflect.DeepEqual(s, want) {
+		t.Fatalf("after adds: %v, want %v", s, want)
+	}
+	s.add(3, 32) // overlapping several ranges
+	if want := (rangeset{{0, 40}}); !reflect.DeepEqual(s, want) {
+		t.Fatalf("after overlapping add: %v, want %v", s, want)
+	}
+	s.sub(10, 20)
+	if want := (rangeset{{0, 10}, {20, 40}}); !reflect.DeepEqual(s, want) {
+		t.Fatalf("after sub: %v, want %v", s, want)
+	}
+	if !s.contains(5) || s.contains(15) || s.contains(40) {
+		t.Errorf("contains(5, 15, 40) = %v, %v, %v; want true, false, false", s.contains(5), s.contains(15), s.contains(40))
+	}
+	if s.min() != 0 || s.max() != 39 || s.end() != 40 {
+		t.Errorf("min, max, end = %v, %v, %v; want 0, 39, 40", s.min(), s.max(), s.end())
+	}
+	s.removeBefore(25)
+	if !s.isrange(25, 40) {
+		t.Errorf("after removeBefore(25): %v, want [25, 40)", s)
+	}
+}
+
+func TestSendBuffer(t *testing.T) {
+	var b sendBuffer
+	b.write([]byte("0123456789"))
+	off, data := b.next(4, 100)
+	if off != 0 || string(data) != "0123" {
+		t.Fatalf("next(4, 100) = %v, %q; want 0, %q", off, data, "0123")
+	}
+	b.sent(off, len(data))
+	off, data = b.next(100, 8)
+	if off != 4 || string(data) != "4567" {
+		t.Fatalf("next(100, 8) = %v, %q; want 4, %q", off, data, "4567")
+	}
+	b.sent(off, len(data))
+
+	b.ack(4, 4)
+	if b.unacked() != 10 {
+		t.Errorf("unacked after acking a later range = %v, want 10", b.unacked())
+	}
+	b.lost(0, 8) // only [0, 4) needs to be resent
+	off, data = b.next(100, 100)
+	if off != 0 || string(data) != "0123" {
+		t.Fatalf("after loss, next = %v, %q; want 0, %q", off, data, "0123")
+	}
+	b.ack(0, 4) // the lost data is acknowledged after all
+	if b.unacked() != 2 {
+		t.Errorf("unacked = %v, want 2", b.unacked())
+	}
+	off, data = b.next(100, 100)
+	if off != 8 || string(data) != "89" {
+		t.Fatalf("next = %v, %q; want 8, %q", off, data, "89")
+	}
+}
+
+func TestRecvBuffer(t *testing.T) {
+	var b recvBuffer
+	b.write(5, []byte("56789"))
+	if b.readable() != 0 || b.end() != 10 {
+		t.Fatalf("readable, end = %v, %v; want 0, 10", b.readable(), b.end())
+	}
+	b.write(0, []byte("0123"))
+	b.write(2, []byte("2345")) // overlaps both
+	p := make([]byte, 20)
+	n := b.read(p)
+	if string(p[:n]) != "0123456789" {
+		t.Fatalf("read %q, want %q", p[:n], "0123456789")
+	}
+	b.write(0, []byte("old"))
+	if b.readable() != 0 {
+		t.Errorf("readable after writing consumed data = %v, want 0", b.readable())
+	}
+}
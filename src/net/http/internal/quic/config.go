@@ -0,0 +1,108 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// A Config configures a QUIC endpoint or connection.
+// A Config must not be modified after it has been passed to a QUIC function.
+type Config struct {
+	// TLSConfig is the TLS configuration used for connections.
+	// It must be non-nil, and its MinVersion is raised to TLS 1.3
+	// if it is lower.
+	TLSConfig *tls.Config
+
+	// MaxBidiRemoteStreams limits the number of simultaneous bidirectional
+	// streams the peer may open. If zero, the default is 100.
+	// If negative, the peer may not open bidirectional streams.
+	MaxBidiRemoteStreams int64
+
+	// MaxUniRemoteStreams limits the number of simultaneous unidirectional
+	// streams the peer may open. If zero, the default is 10.
+	// If negative, the peer may not open unidirectional streams.
+	MaxUniRemoteStreams int64
+
+	// MaxStreamReadBufferSize is the maximum amount of data the peer may
+	// send on a stream before the application reads it.
+	// If zero, the default is 1 MiB.
+	MaxStreamReadBufferSize int64
+
+	// MaxStreamWriteBufferSize is the maximum amount of data written to
+	// a stream which may be buffered until the peer acknowledges it.
+	// If zero, the default is 1 MiB.
+	MaxStreamWriteBufferSize int64
+
+	// MaxConnReadBufferSize is the maximum amount of data the peer may
+	// send on all streams of a connection before the application reads it.
+	// If zero, the default is 16 MiB.
+	MaxConnReadBufferSize int64
+
+	// MaxIdleTimeout is the time after which an idle connection is closed.
+	// The connection uses the smaller of this and the peer's timeout.
+	// If zero, the default is 30 seconds. If negative, the connection
+	// uses the peer's timeout, if any.
+	MaxIdleTimeout time.Duration
+
+	// HandshakeTimeout is the maximum time a handshake may take.
+	// If zero, the default is 10 seconds.
+	HandshakeTimeout time.Duration
+
+	// KeepAlivePeriod, if positive, is the idle time after which
+	// the connection sends a PING frame to keep the connection alive.
+	KeepAlivePeriod time.Duration
+}
+
+func (c *Config) maxBidiRemoteStreams() int64 {
+	return configDefault(c.MaxBidiRemoteStreams, defaultMaxBidiRemoteStreams)
+}
+
+func (c *Config) maxUniRemoteStreams() int64 {
+	return configDefault(c.MaxUniRemoteStreams, defaultMaxUniRemoteStreams)
+}
+
+func (c *Config) maxStreamReadBufferSize() int64 {
+	return configDefault(c.MaxStreamReadBufferSize, defaultMaxStreamReadBuffer)
+}
+
+func (c *Config) maxStreamWriteBufferSize() int64 {
+	return configDefault(c.MaxStreamWriteBufferSize, defaultMaxStreamReadBuffer)
+}
+
+func (c *Config) maxConnReadBufferSize() int64 {
+	return configDefault(c.MaxConnReadBufferSize, defaultMaxConnReadBuffer)
+}
+
+func (c *Config) maxIdleTimeout() time.Duration {
+	return time.Duration(configDefault(int64(c.MaxIdleTimeout), int64(defaultMaxIdleTimeout)))
+}
+
+func (c *Config) handshakeTimeout() time.Duration {
+	if c.HandshakeTimeout <= 0 {
+		return defaultHandshakeTimeout
+	}
+	return c.HandshakeTimeout
+}
+
+func configDefault(v, def int64) int64 {
+	switch {
+	case v == 0:
+		return def
+	case v < 0:
+		return 0
+	}
+	return v
+}
+
+// tlsConfig returns a copy of the TLS configuration suitable for QUIC.
+func (c *Config) tlsConfig() *tls.Config {
+	config := c.TLSConfig.Clone()
+	if config.MinVersion < tls.VersionTLS13 {
+		config.MinVersion = tls.VersionTLS13
+	}
+	return config
+}
@@ -0,0 +1,573 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"internal/tlsquic"
+	"net"
+	"sync"
+	"time"
+)
+
+// connState is the state of a connection.
+type connState int
+
+const (
+	stateActive   = connState(iota)
+	stateClosing  // we sent CONNECTION_CLOSE (RFC 9000, Section 10.2.1)
+	stateDraining // the peer sent CONNECTION_CLOSE (RFC 9000, Section 10.2.2)
+	stateDone
+)
+
+// maxDatagramsPerLoop limits the datagrams sent in one iteration of a
+// connection's loop, so that received datagrams are processed promptly.
+const maxDatagramsPerLoop = 32
+
+// maxAckRanges is the number of ranges of received packet numbers we track.
+const maxAckRanges = 32
+
+// A Conn is a QUIC connection.
+//
+// Each connection has a goroutine which processes received packets,
+// sends packets, and runs timers. Methods of Conn and Stream
+// communicate with it through the state guarded by mu.
+type Conn struct {
+	side     Side
+	config   *Config
+	endpoint *Endpoint
+	peerAddr net.Addr
+	tlsConn  *tls.Conn
+	tls      tlsquic.Conn
+
+	recvc   chan []byte   // datagrams received by the endpoint
+	wakec   chan struct{} // wakes the loop to send packets
+	readyc  chan struct{} // closed when the handshake completes or the conn closes
+	closedc chan struct{} // closed when the connection starts closing
+	donec   chan struct{} // closed when the loop exits
+
+	mu sync.Mutex
+
+	state     connState
+	err       error     // why the connection closed
+	closeCode uint64    // error code of our CONNECTION_CLOSE frame
+	closeApp  bool      // closeCode is an application error code
+	closeMsg  string    // reason phrase of our CONNECTION_CLOSE frame
+	sendClose bool      // a CONNECTION_CLOSE frame needs to be sent
+	exitFast  bool      // exit without waiting for the closing period
+	closeTime time.Time // end of the closing or draining period
+
+	// Connection IDs.
+	origDstConnID []byte // Destination Connection ID of the client's first Initial
+	localConnID   []byte
+	peerConnID    []byte
+	peerConnIDSet bool // the client has seen the server's connection ID
+
+	spaces [numberSpaceCount]spaceState
+
+	// 1-RTT key updates (RFC 9001, Section 6).
+	keyPhase      bool
+	keyPhaseStart packetNumber // first packet received in the current key phase
+	nextReadKeys  packetKeys
+	prevReadKeys  packetKeys
+
+	rtt        rttState
+	cc         congestion
+	ptoCount   int
+	lastSend   time.Time // time the last ack-eliciting packet was sent
+	createTime time.Time
+	w          packetWriter
+
+	localParams transportParameters
+	peerParams  transportParameters
+
+	handshakeComplete   bool
+	handshakeConfirmed  bool
+	handshakeDoneToSend bool
+	handshakeDeadline   time.Time
+
+	// Server anti-amplification limit (RFC 9000, Section 8.1).
+	addressValidated bool
+	bytesRecv        int
+	bytesSent        int
+
+	idleTimeout  time.Duration
+	idleDeadline time.Time
+	idleReset    bool // restart the idle timer on the next ack-eliciting packet sent
+	lastRecv     time.Time
+	pingToSend   bool
+
+	pathResponses [][8]byte // PATH_RESPONSE frames to send
+
+	// Streams.
+	streams          map[streamID]*Stream
+	sendq            []*Stream // streams with frames to send
+	acceptq          [2][]*Stream
+	localLimit       [2]int64 // MAX_STREAMS received from the peer
+	localOpened      [2]int64 // streams we have opened
+	remoteLimit      [2]int64 // MAX_STREAMS sent to the peer
+	remoteOpened     [2]int64 // streams the peer has opened
+	remoteClosed     [2]int64 // peer-opened streams which have completed
+	maxStreamsToSend [2]bool
+	changed          chan struct{} // closed and replaced on conn-level state changes
+
+	// Connection flow control (RFC 9000, Section 4.1).
+	connSendMax   int64 // MAX_DATA received from the peer
+	connSent      int64 // stream data sent, counting each byte once
+	connRecvMax   int64 // MAX_DATA sent to the peer
+	connRecvd     int64 // largest offsets received on all streams
+	connConsumed  int64 // stream data read or discarded
+	connWindow    int64
+	maxDataToSend bool
+}
+
+// spaceState is the state of a packet number space.
+type spaceState struct {
+	rkeys     packetKeys
+	wkeys     packetKeys
+	discarded bool
+
+	// Receiving.
+	seen            rangeset     // packet numbers received
+	seenFloor       packetNumber // packets below this are treated as duplicates
+	largestRecv     packetNumber
+	largestRecvTime time.Time
+	ackNeeded       bool // an ACK frame needs to be sent
+	ackElicited     int  // ack-eliciting packets received since the last ACK
+	ackDeadline     time.Time
+
+	// Sending.
+	nextPnum             packetNumber
+	largestAcked         packetNumber
+	sent                 []*sentPacket // ack-eliciting packets in flight
+	lastAckElicitingTime time.Time
+	lossTime             time.Time
+	probe                int // probe packets to send
+
+	cryptoSend sendBuffer
+	cryptoRecv recvBuffer
+}
+
+func newConn(e *Endpoint, side Side, config *Config, peerAddr net.Addr, now time.Time) *Conn {
+	c := &Conn{
+		side:       side,
+		config:     config,
+		endpoint:   e,
+		peerAddr:   peerAddr,
+		recvc:      make(chan []byte, 64),
+		wakec:      make(chan struct{}, 1),
+		readyc:     make(chan struct{}),
+		closedc:    make(chan struct{}),
+		donec:      make(chan struct{}),
+		streams:    make(map[streamID]*Stream),
+		changed:    make(chan struct{}),
+		createTime: now,
+	}
+	for i := range c.spaces {
+		c.spaces[i].largestRecv = -1
+		c.spaces[i].largestAcked = -1
+	}
+	c.rtt.init()
+	c.cc.init()
+	c.localConnID = newConnID()
+	c.connWindow = config.maxConnReadBufferSize()
+	c.connRecvMax = c.connWindow
+	c.remoteLimit[bidiStream] = config.maxBidiRemoteStreams()
+	c.remoteLimit[uniStream] = config.maxUniRemoteStreams()
+	c.peerParams = defaultTransportParameters()
+	c.idleTimeout = config.maxIdleTimeout()
+	c.handshakeDeadline = now.Add(config.handshakeTimeout())
+	c.localParams = transportParameters{
+		maxIdleTimeout:                 c.idleTimeout,
+		maxUDPPayloadSize:              maxReceiveDatagramSize,
+		initialMaxData:                 c.connRecvMax,
+		initialMaxStreamDataBidiLocal:  config.maxStreamReadBufferSize(),
+		initialMaxStreamDataBidiRemote: config.maxStreamReadBufferSize(),
+		initialMaxStreamDataUni:        config.maxStreamReadBufferSize(),
+		initialMaxStreamsBidi:          c.remoteLimit[bidiStream],
+		initialMaxStreamsUni:           c.remoteLimit[uniStream],
+		ackDelayExponent:               defaultAckDelayExponent,
+		maxAckDelay:                    defaultMaxAckDelay,
+		disableActiveMigration:         true,
+		activeConnIDLimit:              defaultActiveConnIDLimit,
+		initialSrcConnID:               c.localConnID,
+	}
+	return c
+}
+
+// newConnID returns a new random connection ID.
+func newConnID() []byte {
+	id := make([]byte, connIDLen)
+	if _, err := rand.Read(id); err != nil {
+		panic("quic: " + err.Error())
+	}
+	return id
+}
+
+// startClient starts the handshake of a client connection.
+func (c *Conn) startClient(now time.Time) error {
+	c.origDstConnID = newConnID()
+	c.peerConnID = c.origDstConnID
+	c.spaces[initialSpace].wkeys, c.spaces[initialSpace].rkeys = initialKeys(c.origDstConnID)
+	c.tlsConn = tls.Client(nil, c.config.tlsConfig())
+	c.tls = newQUICConn(c.tlsConn)
+	return c.startTLS(now)
+}
+
+// startServer starts the handshake of a server connection,
+// from the header of the client's first Initial packet.
+func (c *Conn) startServer(now time.Time, h packetHeader) error {
+	c.origDstConnID = cloneBytes(h.dstConnID)
+	c.peerConnID = cloneBytes(h.srcConnID)
+	c.peerConnIDSet = true
+	c.localParams.originalDstConnID = c.origDstConnID
+	c.spaces[initialSpace].rkeys, c.spaces[initialSpace].wkeys = initialKeys(c.origDstConnID)
+	c.tlsConn = tls.Server(nil, c.config.tlsConfig())
+	c.tls = newQUICConn(c.tlsConn)
+	return c.startTLS(now)
+}
+
+func (c *Conn) startTLS(now time.Time) error {
+	c.tls.SetTransportParameters(c.localParams.marshal())
+	if err := c.tls.Start(context.Background()); err != nil {
+		return tlsError(err)
+	}
+	return c.handleTLSEvents(now)
+}
+
+// loop is the connection's goroutine.
+func (c *Conn) loop() {
+	defer close(c.donec)
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		c.mu.Lock()
+		now := time.Now()
+		c.handleTimers(now)
+		if c.state != stateDone {
+			c.sendPackets(now)
+			if c.exitFast && c.state != stateActive {
+				c.setState(stateDone)
+			}
+		}
+		done := c.state == stateDone
+		next := c.nextDeadline()
+		c.mu.Unlock()
+		if done {
+			break
+		}
+		if next.IsZero() {
+			next = now.Add(time.Hour)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(next.Sub(now))
+		select {
+		case b := <-c.recvc:
+			c.mu.Lock()
+			c.handleDatagram(time.Now(), b)
+			for more := true; more; {
+				select {
+				case b := <-c.recvc:
+					c.handleDatagram(time.Now(), b)
+				default:
+					more = false
+				}
+			}
+			c.mu.Unlock()
+		case <-c.wakec:
+		case <-timer.C:
+		}
+	}
+	if c.tls != nil {
+		c.tls.Close()
+	}
+	c.endpoint.removeConn(c)
+}
+
+// wake wakes the connection's loop, to send packets.
+func (c *Conn) wake() {
+	select {
+	case c.wakec <- struct{}{}:
+	default:
+	}
+}
+
+// deliver passes a datagram received by the endpoint to the connection.
+func (c *Conn) deliver(b []byte) {
+	select {
+	case c.recvc <- b:
+	default:
+		// The connection isn't keeping up; drop the datagram.
+	}
+}
+
+// notify wakes goroutines waiting for a change in the connection's state.
+// The conn mutex must be held.
+func (c *Conn) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// waitLocked waits for the connection's state to change or ctx to be done.
+// The conn mutex must be held; it is released while waiting.
+func (c *Conn) waitLocked(ctx context.Context) error {
+	ch := c.changed
+	c.mu.Unlock()
+	defer c.mu.Lock()
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handleTimers runs expired timers.
+func (c *Conn) handleTimers(now time.Time) {
+	switch c.state {
+	case stateClosing, stateDraining:
+		if !now.Before(c.closeTime) {
+			c.setState(stateDone)
+		}
+		return
+	case stateDone:
+		return
+	}
+	if !c.idleDeadline.IsZero() && !now.Before(c.idleDeadline) {
+		c.err = errIdleTimeout
+		c.setState(stateDone)
+		return
+	}
+	if !c.handshakeComplete && !now.Before(c.handshakeDeadline) {
+		c.closeLocked(now, errHandshakeTimeout, uint64(errConnectionRefused), false, "")
+		return
+	}
+	if t, space, isPTO := c.lossTimer(); !t.IsZero() && !now.Before(t) {
+		if isPTO {
+			c.onPTO(now, space)
+		} else {
+			c.detectLost(now, space)
+		}
+	}
+	if p := c.config.KeepAlivePeriod; p > 0 && c.handshakeComplete && !now.Before(c.lastRecv.Add(p)) {
+		c.pingToSend = true
+		c.lastRecv = now // send the next PING after another period
+	}
+}
+
+// nextDeadline returns the time at which the loop should next run timers.
+func (c *Conn) nextDeadline() time.Time {
+	var next time.Time
+	add := func(t time.Time) {
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	switch c.state {
+	case stateClosing, stateDraining:
+		add(c.closeTime)
+		return next
+	case stateDone:
+		return next
+	}
+	add(c.idleDeadline)
+	if !c.handshakeComplete {
+		add(c.handshakeDeadline)
+	}
+	for i := range c.spaces {
+		if st := &c.spaces[i]; st.ackNeeded {
+			add(st.ackDeadline)
+		}
+	}
+	t, _, _ := c.lossTimer()
+	add(t)
+	if p := c.config.KeepAlivePeriod; p > 0 && c.handshakeComplete {
+		add(c.lastRecv.Add(p))
+	}
+	return next
+}
+
+// setState changes the state of the connection.
+func (c *Conn) setState(state connState) {
+	c.state = state
+	if state == stateDone && c.err == nil {
+		c.err = ErrClosed
+	}
+	if c.err != nil {
+		select {
+		case <-c.closedc:
+		default:
+			close(c.closedc)
+			c.notifyAll()
+		}
+	}
+}
+
+// notifyAll wakes all goroutines waiting on the connection or its streams.
+func (c *Conn) notifyAll() {
+	c.notify()
+	for _, s := range c.streams {
+		s.notify()
+	}
+	for _, q := range c.acceptq {
+		for _, s := range q {
+			s.notify()
+		}
+	}
+	select {
+	case <-c.readyc:
+	default:
+		close(c.readyc)
+	}
+}
+
+// closeLocked starts closing the connection with a CONNECTION_CLOSE frame.
+// The conn mutex must be held.
+func (c *Conn) closeLocked(now time.Time, err error, code uint64, app bool, reason string) {
+	if c.state != stateActive {
+		return
+	}
+	c.err = err
+	c.closeCode = code
+	c.closeApp = app
+	c.closeMsg = reason
+	c.sendClose = true
+	c.closeTime = now.Add(3 * c.rtt.pto(c.peerParams.maxAckDelay))
+	c.setState(stateClosing)
+	c.wake()
+}
+
+// closeWithError closes the connection because of an error.
+func (c *Conn) closeWithError(now time.Time, err error) {
+	var te *localTransportError
+	if errors.As(err, &te) {
+		c.closeLocked(now, err, uint64(te.code), false, te.reason)
+		return
+	}
+	c.closeLocked(now, err, uint64(errInternal), false, "")
+}
+
+// enterDraining starts draining the connection after receiving a
+// CONNECTION_CLOSE frame from the peer.
+func (c *Conn) enterDraining(now time.Time, err error) {
+	if c.state == stateDone || c.state == stateDraining {
+		return
+	}
+	if c.state == stateActive {
+		c.err = err
+	}
+	c.closeTime = now.Add(3 * c.rtt.pto(c.peerParams.maxAckDelay))
+	c.setState(stateDraining)
+}
+
+// Abort closes the connection immediately, sending the peer the given
+// application error code and reason. Data not yet acknowledged by the peer
+// may be lost.
+func (c *Conn) Abort(code uint64, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeLocked(time.Now(), ErrClosed, code, true, reason)
+}
+
+// Close closes the connection with the given application error code,
+// after waiting for the data written to its streams to be acknowledged
+// by the peer or for ctx to be done.
+func (c *Conn) Close(ctx context.Context, code uint64, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for c.state == stateActive && !c.allDataAcked() {
+		if err = c.waitLocked(ctx); err != nil {
+			break
+		}
+	}
+	c.closeLocked(time.Now(), ErrClosed, code, true, reason)
+	return err
+}
+
+// allDataAcked reports whether the peer has acknowledged all data
+// written to streams, and their FINs and resets.
+func (c *Conn) allDataAcked() bool {
+	for _, s := range c.streams {
+		if s.sendDone {
+			continue
+		}
+		if s.send.unacked() > 0 || s.writeClosed && !s.finAcked || s.reset == resetToSend || s.reset == resetSent {
+			return false
+		}
+	}
+	return true
+}
+
+// exit closes the connection on behalf of a closing Endpoint,
+// without waiting for the closing period.
+func (c *Conn) exit() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.exitFast = true
+	c.closeLocked(time.Now(), ErrClosed, 0, true, "")
+	c.wake()
+}
+
+// Done returns a channel which is closed when the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.closedc
+}
+
+// Err returns the error which closed the connection, or nil if it is open.
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == stateActive {
+		return nil
+	}
+	return c.err
+}
+
+// Side returns the side of the connection.
+func (c *Conn) Side() Side {
+	return c.side
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.endpoint.LocalAddr()
+}
+
+// RemoteAddr returns the peer's network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.peerAddr
+}
+
+// ConnectionState returns basic TLS details about the connection.
+func (c *Conn) ConnectionState() tls.ConnectionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tlsConn.ConnectionState()
+}
+
+// waitReady waits for the handshake to complete.
+func (c *Conn) waitReady(ctx context.Context) error {
+	select {
+	case <-c.readyc:
+	case <-ctx.Done():
+		c.Abort(0, "")
+		return ctx.Err()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.handshakeComplete || c.state != stateActive {
+		return c.err
+	}
+	return nil
+}
@@ -0,0 +1,581 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"time"
+)
+
+// handleDatagram processes a datagram received from the peer.
+// The conn mutex must be held.
+func (c *Conn) handleDatagram(now time.Time, b []byte) {
+	switch c.state {
+	case stateClosing:
+		// Respond to packets received while closing with another
+		// CONNECTION_CLOSE (RFC 9000, Section 10.2.1).
+		c.sendClose = true
+		return
+	case stateDraining, stateDone:
+		return
+	}
+	c.bytesRecv += len(b)
+	for len(b) > 0 && c.state == stateActive {
+		h, ok := parsePacketHeader(b)
+		if !ok {
+			return
+		}
+		pkt := b[:h.size]
+		b = b[h.size:]
+		switch h.ptype {
+		case packetTypeInitial, packetTypeHandshake, packetType1RTT:
+		default:
+			// We don't support 0-RTT or Retry, and only support one
+			// version, so Version Negotiation packets are of no use.
+			continue
+		}
+		if h.ptype != packetType1RTT && h.version != quicVersion1 {
+			return
+		}
+		if !bytes.Equal(h.dstConnID, c.localConnID) {
+			// Until the client learns our connection ID, it uses
+			// the one it chose for its first Initial.
+			if c.side != ServerSide || h.ptype != packetTypeInitial || !bytes.Equal(h.dstConnID, c.origDstConnID) {
+				continue
+			}
+		}
+		c.handlePacket(now, h, pkt)
+	}
+}
+
+// handlePacket processes a single packet.
+func (c *Conn) handlePacket(now time.Time, h packetHeader, pkt []byte) {
+	space := spaceForPacketType(h.ptype)
+	st := &c.spaces[space]
+	if st.discarded || !st.rkeys.isSet() {
+		return
+	}
+	pnum, pnumLen, err := st.rkeys.unprotectHeader(pkt, h.pnumOff, st.largestRecv)
+	if err != nil {
+		return
+	}
+	keys := &st.rkeys
+	keyUpdate := false
+	if space == appDataSpace && (pkt[0]&keyPhaseBit != 0) != c.keyPhase {
+		if pnum < c.keyPhaseStart && c.prevReadKeys.isSet() {
+			keys = &c.prevReadKeys
+		} else {
+			keys = &c.nextReadKeys
+			keyUpdate = true
+		}
+	}
+	payload, err := keys.open(pkt, h.pnumOff+pnumLen, pnum)
+	if err != nil {
+		return
+	}
+	if pnum < st.seenFloor || st.seen.contains(int64(pnum)) {
+		return // duplicate
+	}
+	reserved := byte(0x18)
+	if isLongHeader(pkt[0]) {
+		reserved = 0x0c
+	}
+	if pkt[0]&reserved != 0 {
+		c.closeWithError(now, &localTransportError{code: errProtocolViolation, reason: "reserved header bits are set"})
+		return
+	}
+	if keyUpdate {
+		// The peer has updated its keys (RFC 9001, Section 6.2).
+		c.keyPhase = !c.keyPhase
+		c.keyPhaseStart = pnum
+		c.prevReadKeys = st.rkeys
+		st.rkeys = c.nextReadKeys
+		c.nextReadKeys = st.rkeys.next()
+		st.wkeys = st.wkeys.next()
+	}
+
+	st.seen.add(int64(pnum), int64(pnum)+1)
+	if len(st.seen) > maxAckRanges {
+		st.seenFloor = packetNumber(st.seen[1].start)
+		st.seen.removeBefore(int64(st.seenFloor))
+	}
+	outOfOrder := pnum < st.largestRecv
+	if pnum > st.largestRecv {
+		st.largestRecv = pnum
+		st.largestRecvTime = now
+	}
+	if isLongHeader(pkt[0]) && c.side == ClientSide && !c.peerConnIDSet {
+		// The server's first packet chooses its connection ID
+		// (RFC 9000, Section 7.2).
+		c.peerConnID = cloneBytes(h.srcConnID)
+		c.peerConnIDSet = true
+	}
+	if space == handshakeSpace && c.side == ServerSide && !c.addressValidated {
+		// Receiving a Handshake packet validates the client's address,
+		// and the server no longer needs Initial keys
+		// (RFC 9000, Section 8.1; RFC 9001, Section 4.9.1).
+		c.addressValidated = true
+		c.discardKeys(initialSpace)
+	}
+	c.lastRecv = now
+	c.idleReset = true
+	if d := c.effectiveIdleTimeout(); d > 0 {
+		c.idleDeadline = now.Add(d)
+	}
+
+	ackEliciting, err := c.handleFrames(now, h.ptype, space, payload)
+	if err != nil {
+		c.closeWithError(now, err)
+		return
+	}
+	if ackEliciting && !st.discarded {
+		st.ackElicited++
+		if !st.ackNeeded {
+			st.ackNeeded = true
+			st.ackDeadline = now.Add(defaultMaxAckDelay)
+		}
+		// Acknowledge handshake packets, out of order packets, and every
+		// second ack-eliciting packet immediately (RFC 9000, Section 13.2.1).
+		if space != appDataSpace || st.ackElicited >= 2 || outOfOrder {
+			st.ackDeadline = now
+		}
+	}
+}
+
+// effectiveIdleTimeout returns the idle timeout, which is the smaller of
+// ours and the peer's (RFC 9000, Section 10.1).
+func (c *Conn) effectiveIdleTimeout() time.Duration {
+	d := c.idleTimeout
+	if p := c.peerParams.maxIdleTimeout; p > 0 && (d == 0 || p < d) {
+		d = p
+	}
+	if d > 0 {
+		// The timeout must be at least three times the PTO.
+		if min := 3 * c.rtt.pto(c.peerParams.maxAckDelay); d < min {
+			d = min
+		}
+	}
+	return d
+}
+
+func frameError(reason string) error {
+	return &localTransportError{code: errFrameEncoding, reason: reason}
+}
+
+func protocolError(reason string) error {
+	return &localTransportError{code: errProtocolViolation, reason: reason}
+}
+
+// handleFrames processes the frames in the payload of a packet.
+// It reports whether the packet was ack-eliciting.
+func (c *Conn) handleFrames(now time.Time, ptype packetType, space numberSpace, b []byte) (ackEliciting bool, err error) {
+	if len(b) == 0 {
+		return false, protocolError("packet with no frames")
+	}
+	for len(b) > 0 && c.state == stateActive {
+		ftype := uint64(b[0])
+		if b[0]&0xc0 != 0 {
+			// All the frame types we know of have one-byte encodings.
+			return false, frameError("unknown frame type")
+		}
+		if !frameAllowedIn(ftype, ptype) {
+			return false, protocolError("frame not allowed in " + ptype.String() + " packet")
+		}
+		if isAckEliciting(ftype) {
+			ackEliciting = true
+		}
+		n := -1
+		switch {
+		case ftype == frameTypePadding, ftype == frameTypePing:
+			n = 1
+		case ftype == frameTypeAck, ftype == frameTypeAckECN:
+			var acked rangeset
+			var delay uint64
+			acked, delay, n = consumeAckFrame(b)
+			if n >= 0 {
+				err = c.handleAck(now, space, acked, delay)
+			}
+		case ftype == frameTypeResetStream:
+			var id streamID
+			var code uint64
+			var finalSize int64
+			id, code, finalSize, n = consumeResetStreamFrame(b)
+			if n >= 0 {
+				err = c.handleResetStream(id, code, finalSize)
+			}
+		case ftype == frameTypeStopSending:
+			var id streamID
+			var code uint64
+			id, code, n = consumeStopSendingFrame(b)
+			if n >= 0 {
+				err = c.handleStopSending(id, code)
+			}
+		case ftype == frameTypeCrypto:
+			var off int64
+			var data []byte
+			off, data, n = consumeCryptoFrame(b)
+			if n >= 0 {
+				err = c.handleCrypto(now, space, off, data)
+			}
+		case ftype == frameTypeNewToken:
+			if c.side == ServerSide {
+				return false, protocolError("client sent NEW_TOKEN")
+			}
+			// We don't use tokens.
+			_, n = consumeNewTokenFrame(b)
+		case ftype >= frameTypeStreamBase && ftype < frameTypeStreamBase+8:
+			var id streamID
+			var off int64
+			var fin bool
+			var data []byte
+			id, off, fin, data, n = consumeStreamFrame(b)
+			if n >= 0 {
+				err = c.handleStreamFrame(id, off, fin, data)
+			}
+		case ftype == frameTypeMaxData:
+			var v uint64
+			v, n = consumeUint64Frame(b)
+			if n >= 0 && int64(v) > c.connSendMax {
+				c.connSendMax = int64(v)
+				c.wake()
+			}
+		case ftype == frameTypeMaxStreamData:
+			var id streamID
+			var v uint64
+			id, v, n = consumeStreamUint64Frame(b)
+			if n >= 0 {
+				err = c.handleMaxStreamData(id, int64(v))
+			}
+		case ftype == frameTypeMaxStreamsBidi, ftype == frameTypeMaxStreamsUni:
+			var v uint64
+			v, n = consumeUint64Frame(b)
+			if n >= 0 {
+				err = c.handleMaxStreams(streamType(ftype-frameTypeMaxStreamsBidi), v)
+			}
+		case ftype == frameTypeDataBlocked, ftype == frameTypeStreamsBlockedBidi,
+			ftype == frameTypeStreamsBlockedUni, ftype == frameTypeRetireConnectionID:
+			_, n = consumeUint64Frame(b)
+		case ftype == frameTypeStreamDataBlocked:
+			_, _, n = consumeStreamUint64Frame(b)
+		case ftype == frameTypeNewConnectionID:
+			// We never use more than one of the peer's connection IDs.
+			_, _, _, _, n = consumeNewConnectionIDFrame(b)
+		case ftype == frameTypePathChallenge:
+			var data [8]byte
+			data, n = consumePathFrame(b)
+			if n >= 0 {
+				c.pathResponses = append(c.pathResponses, data)
+			}
+		case ftype == frameTypePathResponse:
+			// We never send PATH_CHALLENGE.
+			_, n = consumePathFrame(b)
+		case ftype == frameTypeConnectionCloseTransport:
+			var code uint64
+			var reason string
+			code, reason, n = consumeConnectionCloseFrame(b)
+			if n >= 0 {
+				c.enterDraining(now, &peerTransportError{code: transportError(code), reason: reason})
+			}
+		case ftype == frameTypeConnectionCloseApplication:
+			var code uint64
+			var reason string
+			code, reason, n = consumeConnectionCloseFrame(b)
+			if n >= 0 {
+				c.enterDraining(now, &ApplicationError{Code: code, Reason: reason})
+			}
+		case ftype == frameTypeHandshakeDone:
+			if c.side == ServerSide {
+				return false, protocolError("client sent HANDSHAKE_DONE")
+			}
+			n = 1
+			c.confirmHandshake()
+		default:
+			return false, frameError("unknown frame type")
+		}
+		if err != nil {
+			return false, err
+		}
+		if n < 0 {
+			return false, frameError("invalid frame")
+		}
+		b = b[n:]
+	}
+	return ackEliciting, nil
+}
+
+// handleAck processes an ACK frame (RFC 9002, Section 6).
+func (c *Conn) handleAck(now time.Time, space numberSpace, acked rangeset, ackDelay uint64) error {
+	st := &c.spaces[space]
+	largest := packetNumber(acked.max())
+	if largest >= st.nextPnum {
+		return protocolError("acknowledgement of unsent packet")
+	}
+	newLargest := largest > st.largestAcked
+	if newLargest {
+		st.largestAcked = largest
+	}
+	var sample *sentPacket
+	ackedAny := false
+	i := 0
+	for _, p := range st.sent {
+		if !acked.contains(int64(p.pnum)) {
+			st.sent[i] = p
+			i++
+			continue
+		}
+		ackedAny = true
+		if p.pnum == largest {
+			sample = p
+		}
+		c.cc.onAcked(p)
+		for _, f := range p.frames {
+			c.onFrameAcked(space, f)
+		}
+	}
+	for j := i; j < len(st.sent); j++ {
+		st.sent[j] = nil
+	}
+	st.sent = st.sent[:i]
+	if newLargest && sample != nil {
+		var delay time.Duration
+		if space == appDataSpace {
+			delay = time.Duration(ackDelay<<uint(c.peerParams.ackDelayExponent)) * time.Microsecond
+		}
+		c.rtt.update(now.Sub(sample.time), delay, c.peerParams.maxAckDelay, c.handshakeConfirmed)
+	}
+	if ackedAny {
+		c.ptoCount = 0
+		c.notify()
+	}
+	c.detectLost(now, space)
+	return nil
+}
+
+// onFrameAcked handles the acknowledgement of a frame.
+func (c *Conn) onFrameAcked(space numberSpace, f sentFrame) {
+	switch f.kind {
+	case frameTypeCrypto:
+		c.spaces[space].cryptoSend.ack(f.off, f.n)
+	case frameTypeStreamBase:
+		s := c.streams[f.id]
+		if s == nil || s.reset != resetNone {
+			return
+		}
+		s.send.ack(f.off, f.n)
+		if f.fin {
+			s.finAcked = true
+		}
+		s.notify()
+		c.checkSendDone(s)
+	case frameTypeResetStream:
+		if s := c.streams[f.id]; s != nil {
+			s.reset = resetAcked
+			c.checkSendDone(s)
+		}
+	}
+}
+
+// onFrameLost handles the loss of a frame, arranging for it to be resent
+// if necessary.
+func (c *Conn) onFrameLost(space numberSpace, f sentFrame) {
+	switch f.kind {
+	case frameTypeCrypto:
+		c.spaces[space].cryptoSend.lost(f.off, f.n)
+	case frameTypeStreamBase:
+		s := c.streams[f.id]
+		if s == nil || s.reset != resetNone {
+			return
+		}
+		s.send.lost(f.off, f.n)
+		if f.fin && !s.finAcked {
+			s.finSent = false
+		}
+		c.queueStream(s)
+	case frameTypeResetStream:
+		if s := c.streams[f.id]; s != nil && s.reset == resetSent {
+			s.reset = resetToSend
+			c.queueStream(s)
+		}
+	case frameTypeStopSending:
+		if s := c.streams[f.id]; s != nil && s.finalSize < 0 && s.recvErr == nil {
+			s.stopSendingToSend = true
+			c.queueStream(s)
+		}
+	case frameTypeMaxStreamData:
+		if s := c.streams[f.id]; s != nil && s.finalSize < 0 && !s.readClosed {
+			s.maxStreamDataToSend = true
+			c.queueStream(s)
+		}
+	case frameTypeMaxData:
+		c.maxDataToSend = true
+	case frameTypeMaxStreamsBidi:
+		c.maxStreamsToSend[bidiStream] = true
+	case frameTypeMaxStreamsUni:
+		c.maxStreamsToSend[uniStream] = true
+	case frameTypeHandshakeDone:
+		c.handshakeDoneToSend = true
+	}
+}
+
+// detectLost declares packets lost (RFC 9002, Section 6.1).
+func (c *Conn) detectLost(now time.Time, space numberSpace) {
+	st := &c.spaces[space]
+	st.lossTime = time.Time{}
+	lossDelay := c.rtt.lossDelay()
+	i := 0
+	for _, p := range st.sent {
+		if p.pnum > st.largestAcked {
+			st.sent[i] = p
+			i++
+			continue
+		}
+		if !now.Before(p.time.Add(lossDelay)) || st.largestAcked >= p.pnum+packetThreshold {
+			c.cc.onLost(p, now)
+			for _, f := range p.frames {
+				c.onFrameLost(space, f)
+			}
+			continue
+		}
+		if t := p.time.Add(lossDelay); st.lossTime.IsZero() || t.Before(st.lossTime) {
+			st.lossTime = t
+		}
+		st.sent[i] = p
+		i++
+	}
+	for j := i; j < len(st.sent); j++ {
+		st.sent[j] = nil
+	}
+	st.sent = st.sent[:i]
+}
+
+// lossTimer returns the time of the next loss detection timer, its number
+// space, and whether it is a probe timeout (RFC 9002, Section 6.2).
+func (c *Conn) lossTimer() (t time.Time, space numberSpace, isPTO bool) {
+	for i := range c.spaces {
+		st := &c.spaces[i]
+		if !st.lossTime.IsZero() && (t.IsZero() || st.lossTime.Before(t)) {
+			t, space = st.lossTime, numberSpace(i)
+		}
+	}
+	if !t.IsZero() {
+		return t, space, false
+	}
+	backoff := c.ptoCount
+	if backoff > maxPTOBackoff {
+		backoff = maxPTOBackoff
+	}
+	inFlight := false
+	for i := range c.spaces {
+		st := &c.spaces[i]
+		if len(st.sent) == 0 {
+			continue
+		}
+		inFlight = true
+		var maxAckDelay time.Duration
+		if numberSpace(i) == appDataSpace {
+			if !c.handshakeConfirmed {
+				continue
+			}
+			maxAckDelay = c.peerParams.maxAckDelay
+		}
+		pt := st.lastAckElicitingTime.Add(c.rtt.pto(maxAckDelay) << uint(backoff))
+		if t.IsZero() || pt.Before(t) {
+			t, space = pt, numberSpace(i)
+		}
+	}
+	if !inFlight && c.side == ClientSide && !c.handshakeConfirmed {
+		// The client keeps probing until the handshake is confirmed, in case
+		// the server is blocked by the anti-amplification limit
+		// (RFC 9002, Section 6.2.2.1).
+		space = initialSpace
+		if c.spaces[handshakeSpace].wkeys.isSet() {
+			space = handshakeSpace
+		}
+		base := c.lastSend
+		if base.IsZero() {
+			base = c.createTime
+		}
+		t = base.Add(c.rtt.pto(0) << uint(backoff))
+	}
+	return t, space, !t.IsZero()
+}
+
+// onPTO handles the expiry of the probe timeout.
+func (c *Conn) onPTO(now time.Time, space numberSpace) {
+	c.ptoCount++
+	st := &c.spaces[space]
+	if st.discarded || !st.wkeys.isSet() {
+		return
+	}
+	st.probe = 2
+	// Probes carry the data from the oldest packets in flight.
+	// In the handshake spaces, that's all of it.
+	n := len(st.sent)
+	if space == appDataSpace && n > 2 {
+		n = 2
+	}
+	for _, p := range st.sent[:n] {
+		for _, f := range p.frames {
+			c.onFrameLost(space, f)
+		}
+	}
+	// Restart the timer from now, so that it doesn't immediately expire again.
+	st.lastAckElicitingTime = now
+	c.lastSend = now
+}
+
+// discardKeys discards the keys and state of a number space
+// (RFC 9001, Section 4.9).
+func (c *Conn) discardKeys(space numberSpace) {
+	st := &c.spaces[space]
+	if st.discarded {
+		return
+	}
+	for _, p := range st.sent {
+		c.cc.onDiscarded(p)
+	}
+	*st = spaceState{
+		discarded:    true,
+		largestRecv:  st.largestRecv,
+		largestAcked: st.largestAcked,
+	}
+	c.ptoCount = 0
+}
+
+// confirmHandshake records that the handshake is confirmed
+// (RFC 9001, Section 4.1.2).
+func (c *Conn) confirmHandshake() {
+	if c.handshakeConfirmed {
+		return
+	}
+	c.handshakeConfirmed = true
+	c.discardKeys(handshakeSpace)
+}
+
+// handleCrypto handles a CRYPTO frame.
+func (c *Conn) handleCrypto(now time.Time, space numberSpace, off int64, data []byte) error {
+	st := &c.spaces[space]
+	const maxCryptoBuffer = 64 << 10
+	if off+int64(len(data))-st.cryptoRecv.readOff > maxCryptoBuffer {
+		return &localTransportError{code: errCryptoBufferExceeded}
+	}
+	st.cryptoRecv.write(off, data)
+	for st.cryptoRecv.readable() > 0 {
+		b := st.cryptoRecv.peek()
+		if err := c.tls.HandleData(spaceLevel(space), b); err != nil {
+			return tlsError(err)
+		}
+		st = &c.spaces[space]
+		st.cryptoRecv.consume(len(b))
+		if err := c.handleTLSEvents(now); err != nil {
+			return err
+		}
+		st = &c.spaces[space]
+		if st.discarded {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,390 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"time"
+)
+
+// sendPackets sends as many datagrams as the connection has to send,
+// up to maxDatagramsPerLoop.
+// The conn mutex must be held.
+func (c *Conn) sendPackets(now time.Time) {
+	for i := 0; i < maxDatagramsPerLoop; i++ {
+		b := c.appendDatagram(now)
+		if b == nil {
+			return
+		}
+		c.endpoint.writeTo(b, c.peerAddr)
+	}
+	// There may be more to send; come back after processing
+	// any received datagrams.
+	c.wake()
+}
+
+// appendDatagram assembles the next datagram to send.
+// It returns nil if there is nothing to send.
+func (c *Conn) appendDatagram(now time.Time) []byte {
+	maxSize := maxDatagramSize
+	if c.side == ServerSide && !c.addressValidated {
+		// Don't send more than three times the data received
+		// from an unvalidated address (RFC 9000, Section 8.1).
+		limit := 3*c.bytesRecv - c.bytesSent
+		if limit < maxSize {
+			maxSize = limit
+		}
+		if maxSize <= 0 {
+			return nil
+		}
+	}
+	c.w.reset(maxSize)
+	if c.sendClose {
+		return c.appendCloseDatagram()
+	}
+	if c.state != stateActive {
+		return nil
+	}
+	padTo := 0
+	var last *sentPacket
+	sentHandshake := false
+	for space := initialSpace; space < numberSpaceCount; space++ {
+		st := &c.spaces[space]
+		if st.discarded || !st.wkeys.isSet() {
+			continue
+		}
+		p, ok := c.appendPacket(now, space)
+		if !ok {
+			continue
+		}
+		last = p
+		if space == initialSpace && (c.side == ClientSide || p != nil && p.ackEliciting) {
+			// Datagrams containing client Initial packets and ack-eliciting
+			// server Initial packets are padded (RFC 9000, Section 14.1).
+			padTo = minimumClientInitialDatagramSize
+		}
+		if space == handshakeSpace {
+			sentHandshake = true
+		}
+	}
+	if last != nil && padTo > 0 {
+		grow := c.w.lastPacketSize(padTo) - last.size
+		last.size += grow
+		if last.inFlight {
+			c.cc.bytesInFlight += grow
+		}
+	}
+	b := c.w.finishDatagram(padTo)
+	if b == nil {
+		return nil
+	}
+	c.bytesSent += len(b)
+	if sentHandshake && c.side == ClientSide {
+		// The client discards Initial keys when it first sends
+		// a Handshake packet (RFC 9001, Section 4.9.1).
+		c.discardKeys(initialSpace)
+	}
+	return b
+}
+
+// hasFramesToSend reports whether there are ack-eliciting frames to send
+// in a number space.
+func (c *Conn) hasFramesToSend(space numberSpace) bool {
+	if c.spaces[space].cryptoSend.hasUnsent() {
+		return true
+	}
+	if space != appDataSpace {
+		return false
+	}
+	return c.handshakeDoneToSend ||
+		c.maxDataToSend ||
+		c.maxStreamsToSend[bidiStream] ||
+		c.maxStreamsToSend[uniStream] ||
+		len(c.pathResponses) > 0 ||
+		c.pingToSend ||
+		len(c.sendq) > 0
+}
+
+// appendPacket appends a packet in the given number space to the datagram.
+// It reports whether a packet was appended, and returns the packet's
+// record if it was ack-eliciting.
+func (c *Conn) appendPacket(now time.Time, space numberSpace) (*sentPacket, bool) {
+	st := &c.spaces[space]
+	ackDue := st.ackNeeded && !now.Before(st.ackDeadline)
+	probe := st.probe > 0
+	sendFrames := c.hasFramesToSend(space) || probe
+	if sendFrames && !probe && !c.cc.canSend(maxDatagramSize) {
+		sendFrames = false
+	}
+	if !ackDue && !sendFrames {
+		return nil, false
+	}
+	pnum := st.nextPnum
+	var ok bool
+	switch space {
+	case initialSpace:
+		ok = c.w.startLongPacket(packetTypeInitial, &st.wkeys, c.peerConnID, c.localConnID, pnum, st.largestAcked)
+	case handshakeSpace:
+		ok = c.w.startLongPacket(packetTypeHandshake, &st.wkeys, c.peerConnID, c.localConnID, pnum, st.largestAcked)
+	default:
+		ok = c.w.start1RTTPacket(&st.wkeys, c.keyPhase, c.peerConnID, pnum, st.largestAcked)
+	}
+	if !ok {
+		return nil, false
+	}
+	p := &sentPacket{pnum: pnum, time: now}
+	if st.ackNeeded {
+		var delay uint64
+		if space == appDataSpace {
+			delay = uint64(now.Sub(st.largestRecvTime).Microseconds()) >> defaultAckDelayExponent
+		}
+		n := len(c.w.b)
+		c.w.b = appendAckFrame(c.w.b, st.seen, delay, c.w.avail())
+		if len(c.w.b) > n {
+			st.ackNeeded = false
+			st.ackElicited = 0
+		}
+	}
+	if sendFrames {
+		if space == appDataSpace {
+			c.appendAppFrames(p)
+		} else {
+			c.appendCryptoFrames(space, p)
+		}
+		if probe && !c.w.ackElicits && c.w.avail() > 0 {
+			c.w.b = append(c.w.b, frameTypePing)
+			c.w.ackElicits = true
+		}
+	}
+	p.ackEliciting = c.w.ackElicits
+	size := c.w.finishPacket()
+	if size == 0 {
+		return nil, false
+	}
+	st.nextPnum++
+	if !p.ackEliciting {
+		return nil, true
+	}
+	if st.probe > 0 {
+		st.probe--
+	}
+	p.size = size
+	p.inFlight = true
+	st.sent = append(st.sent, p)
+	c.cc.onSent(p)
+	st.lastAckElicitingTime = now
+	c.lastSend = now
+	if c.idleReset {
+		// Sending the first ack-eliciting packet after receiving restarts
+		// the idle timer (RFC 9000, Section 10.1).
+		c.idleReset = false
+		if d := c.effectiveIdleTimeout(); d > 0 {
+			c.idleDeadline = now.Add(d)
+		}
+	}
+	return p, true
+}
+
+// appendCryptoFrames appends CRYPTO frames to the current packet.
+func (c *Conn) appendCryptoFrames(space numberSpace, p *sentPacket) {
+	st := &c.spaces[space]
+	for st.cryptoSend.hasUnsent() {
+		off, _ := st.cryptoSend.next(0, MaxVarint)
+		avail := c.w.avail()
+		size := avail - cryptoFrameOverhead(off, avail)
+		if size <= 0 {
+			return
+		}
+		off, data := st.cryptoSend.next(size, MaxVarint)
+		c.w.b = appendCryptoFrame(c.w.b, off, data)
+		c.w.ackElicits = true
+		st.cryptoSend.sent(off, len(data))
+		p.frames = append(p.frames, sentFrame{kind: frameTypeCrypto, off: off, n: len(data)})
+	}
+}
+
+// appendAppFrames appends frames to the current 1-RTT packet.
+func (c *Conn) appendAppFrames(p *sentPacket) {
+	w := &c.w
+	if c.handshakeDoneToSend && w.avail() >= 1 {
+		w.b = append(w.b, frameTypeHandshakeDone)
+		w.ackElicits = true
+		c.handshakeDoneToSend = false
+		p.frames = append(p.frames, sentFrame{kind: frameTypeHandshakeDone})
+	}
+	if c.maxDataToSend && w.avail() >= 1+8 {
+		w.b = appendUint64Frame(w.b, frameTypeMaxData, uint64(c.connRecvMax))
+		w.ackElicits = true
+		c.maxDataToSend = false
+		p.frames = append(p.frames, sentFrame{kind: frameTypeMaxData})
+	}
+	for typ := bidiStream; typ <= uniStream; typ++ {
+		if c.maxStreamsToSend[typ] && w.avail() >= 1+8 {
+			kind := byte(frameTypeMaxStreamsBidi + typ)
+			w.b = appendUint64Frame(w.b, kind, uint64(c.remoteLimit[typ]))
+			w.ackElicits = true
+			c.maxStreamsToSend[typ] = false
+			p.frames = append(p.frames, sentFrame{kind: kind})
+		}
+	}
+	for len(c.pathResponses) > 0 && w.avail() >= 1+8 {
+		w.b = appendPathFrame(w.b, frameTypePathResponse, c.pathResponses[0])
+		w.ackElicits = true
+		c.pathResponses = c.pathResponses[1:]
+	}
+	if c.pingToSend && w.avail() >= 1 {
+		w.b = append(w.b, frameTypePing)
+		w.ackElicits = true
+		c.pingToSend = false
+	}
+	// Streams share the packet round-robin.
+	for len(c.sendq) > 0 {
+		s := c.sendq[0]
+		if c.appendStreamFrames(s, p) {
+			// The packet is full. Send the rest of this stream's
+			// frames after the other streams'.
+			c.sendq = append(c.sendq[1:], s)
+			return
+		}
+		s.queued = false
+		c.sendq[0] = nil
+		c.sendq = c.sendq[1:]
+	}
+}
+
+// maxStreamControlFrameSize is the largest of the frames other than STREAM
+// which appendStreamFrames sends.
+const maxStreamControlFrameSize = 1 + 8 + 8 + 8
+
+// appendStreamFrames appends a stream's frames to the current packet.
+// It reports whether it stopped because the packet is full.
+func (c *Conn) appendStreamFrames(s *Stream, p *sentPacket) (full bool) {
+	w := &c.w
+	if s.stopSendingToSend {
+		if w.avail() < maxStreamControlFrameSize {
+			return true
+		}
+		w.b = appendStopSendingFrame(w.b, s.id, s.stopSendingCode)
+		w.ackElicits = true
+		s.stopSendingToSend = false
+		p.frames = append(p.frames, sentFrame{kind: frameTypeStopSending, id: s.id})
+	}
+	if s.maxStreamDataToSend {
+		if w.avail() < maxStreamControlFrameSize {
+			return true
+		}
+		w.b = appendStreamUint64Frame(w.b, frameTypeMaxStreamData, s.id, uint64(s.recvMax))
+		w.ackElicits = true
+		s.maxStreamDataToSend = false
+		p.frames = append(p.frames, sentFrame{kind: frameTypeMaxStreamData, id: s.id})
+	}
+	switch s.reset {
+	case resetToSend:
+		if w.avail() < maxStreamControlFrameSize {
+			return true
+		}
+		w.b = appendResetStreamFrame(w.b, s.id, s.resetCode, s.sentMax)
+		w.ackElicits = true
+		s.reset = resetSent
+		p.frames = append(p.frames, sentFrame{kind: frameTypeResetStream, id: s.id})
+		return false
+	case resetNone:
+	default:
+		return false
+	}
+	if s.sendDone {
+		return false
+	}
+	for {
+		// Stream data is limited by the stream's and the connection's
+		// flow control limits (RFC 9000, Section 4.1).
+		limit := s.sendMax
+		if connLimit := s.sentMax + c.connSendMax - c.connSent; connLimit < limit {
+			limit = connLimit
+		}
+		var off int64
+		var data []byte
+		if s.send.hasUnsent() {
+			off, _ = s.send.next(0, limit)
+			if off >= limit {
+				return false // blocked by flow control
+			}
+			avail := w.avail()
+			size := avail - streamFrameOverhead(s.id, off, avail)
+			if size <= 0 {
+				return true
+			}
+			off, data = s.send.next(size, limit)
+		} else if s.writeClosed && !s.finSent {
+			off = s.send.end()
+			if w.avail() < streamFrameOverhead(s.id, off, 0) {
+				return true
+			}
+		} else {
+			return false
+		}
+		end := off + int64(len(data))
+		fin := s.writeClosed && end == s.send.end()
+		w.b = appendStreamFrame(w.b, s.id, off, data, fin)
+		w.ackElicits = true
+		s.send.sent(off, len(data))
+		if end > s.sentMax {
+			c.connSent += end - s.sentMax
+			s.sentMax = end
+		}
+		if fin {
+			s.finSent = true
+		}
+		p.frames = append(p.frames, sentFrame{kind: frameTypeStreamBase, id: s.id, off: off, n: len(data), fin: fin})
+	}
+}
+
+// appendCloseDatagram assembles a datagram containing CONNECTION_CLOSE
+// frames, in each number space for which we have keys
+// (RFC 9000, Section 10.2.3).
+func (c *Conn) appendCloseDatagram() []byte {
+	c.sendClose = false
+	reason := c.closeMsg
+	if len(reason) > 100 {
+		reason = reason[:100]
+	}
+	padTo := 0
+	for space := initialSpace; space < numberSpaceCount; space++ {
+		st := &c.spaces[space]
+		if st.discarded || !st.wkeys.isSet() {
+			continue
+		}
+		var ok bool
+		switch space {
+		case initialSpace:
+			ok = c.w.startLongPacket(packetTypeInitial, &st.wkeys, c.peerConnID, c.localConnID, st.nextPnum, st.largestAcked)
+			if c.side == ClientSide {
+				padTo = minimumClientInitialDatagramSize
+			}
+		case handshakeSpace:
+			ok = c.w.startLongPacket(packetTypeHandshake, &st.wkeys, c.peerConnID, c.localConnID, st.nextPnum, st.largestAcked)
+		default:
+			ok = c.w.start1RTTPacket(&st.wkeys, c.keyPhase, c.peerConnID, st.nextPnum, st.largestAcked)
+		}
+		if !ok {
+			continue
+		}
+		switch {
+		case !c.closeApp:
+			c.w.b = appendConnectionCloseTransportFrame(c.w.b, transportError(c.closeCode), reason)
+		case space == appDataSpace:
+			c.w.b = appendConnectionCloseApplicationFrame(c.w.b, c.closeCode, reason)
+		default:
+			// Application errors are only sent in 1-RTT packets;
+			// in other packets they are replaced by APPLICATION_ERROR.
+			c.w.b = appendConnectionCloseTransportFrame(c.w.b, errApplicationError, "")
+		}
+		if c.w.finishPacket() > 0 {
+			st.nextPnum++
+		}
+	}
+	b := c.w.finishDatagram(padTo)
+	c.bytesSent += len(b)
+	return b
+}
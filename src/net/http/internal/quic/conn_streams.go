@@ -0,0 +1,309 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"context"
+)
+
+// OpenStream opens a new bidirectional stream.
+// It blocks until the peer's stream limit permits the stream to be opened.
+//
+// The peer is not informed of the stream until data is written to it.
+func (c *Conn) OpenStream(ctx context.Context) (*Stream, error) {
+	return c.openStream(ctx, bidiStream)
+}
+
+// OpenUniStream opens a new unidirectional stream.
+// It blocks until the peer's stream limit permits the stream to be opened.
+func (c *Conn) OpenUniStream(ctx context.Context) (*Stream, error) {
+	return c.openStream(ctx, uniStream)
+}
+
+func (c *Conn) openStream(ctx context.Context, typ streamType) (*Stream, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		if c.state != stateActive {
+			return nil, c.err
+		}
+		if c.localOpened[typ] < c.localLimit[typ] {
+			break
+		}
+		if err := c.waitLocked(ctx); err != nil {
+			return nil, err
+		}
+	}
+	id := newStreamID(c.side, typ, c.localOpened[typ])
+	c.localOpened[typ]++
+	s := newStream(c, id)
+	c.streams[id] = s
+	return s, nil
+}
+
+// AcceptStream waits for the peer to open a bidirectional stream.
+func (c *Conn) AcceptStream(ctx context.Context) (*Stream, error) {
+	return c.acceptStream(ctx, bidiStream)
+}
+
+// AcceptUniStream waits for the peer to open a unidirectional stream.
+func (c *Conn) AcceptUniStream(ctx context.Context) (*Stream, error) {
+	return c.acceptStream(ctx, uniStream)
+}
+
+func (c *Conn) acceptStream(ctx context.Context, typ streamType) (*Stream, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		if q := c.acceptq[typ]; len(q) > 0 {
+			s := q[0]
+			q[0] = nil
+			c.acceptq[typ] = q[1:]
+			return s, nil
+		}
+		if c.state != stateActive {
+			return nil, c.err
+		}
+		if err := c.waitLocked(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// streamForFrame returns the stream a frame received from the peer refers to,
+// creating streams the peer has implicitly opened (RFC 9000, Section 3.2).
+// It returns nil if the stream has already been closed.
+// sendFrame is true for frames which refer to our send side of the stream.
+func (c *Conn) streamForFrame(id streamID, sendFrame bool) (*Stream, error) {
+	typ := id.streamType()
+	local := id.initiator() == c.side
+	if typ == uniStream && local != sendFrame {
+		return nil, &localTransportError{code: errStreamState, reason: "invalid frame for unidirectional stream"}
+	}
+	num := id.num()
+	if local {
+		if num >= c.localOpened[typ] {
+			return nil, &localTransportError{code: errStreamState, reason: "frame for stream not yet opened"}
+		}
+		return c.streams[id], nil
+	}
+	if num >= c.remoteLimit[typ] {
+		return nil, &localTransportError{code: errStreamLimit}
+	}
+	if num >= c.remoteOpened[typ] {
+		for n := c.remoteOpened[typ]; n <= num; n++ {
+			s := newStream(c, newStreamID(id.initiator(), typ, n))
+			c.streams[s.id] = s
+			c.acceptq[typ] = append(c.acceptq[typ], s)
+		}
+		c.remoteOpened[typ] = num + 1
+		c.notify()
+	}
+	return c.streams[id], nil
+}
+
+// handleStreamFrame handles a STREAM frame.
+func (c *Conn) handleStreamFrame(id streamID, off int64, fin bool, data []byte) error {
+	s, err := c.streamForFrame(id, false)
+	if err != nil || s == nil {
+		return err
+	}
+	end := off + int64(len(data))
+	if s.finalSize >= 0 && (end > s.finalSize || fin && end != s.finalSize) {
+		return &localTransportError{code: errFinalSize}
+	}
+	if fin {
+		if end < s.recvEnd {
+			return &localTransportError{code: errFinalSize}
+		}
+		s.finalSize = end
+	}
+	if err := c.recvFlow(s, end); err != nil {
+		return err
+	}
+	if s.readClosed || s.recvErr != nil {
+		// The application doesn't want the data.
+		if s.finalSize >= 0 && s.recvEnd == s.finalSize && !s.recvDone {
+			c.setRecvDone(s)
+		}
+		return nil
+	}
+	s.recv.write(off, data)
+	s.notify()
+	return nil
+}
+
+// recvFlow enforces flow control limits on data received on a stream
+// up to offset end.
+func (c *Conn) recvFlow(s *Stream, end int64) error {
+	if end > s.recvMax {
+		return &localTransportError{code: errFlowControl, reason: "stream data exceeds MAX_STREAM_DATA"}
+	}
+	if end <= s.recvEnd {
+		return nil
+	}
+	n := end - s.recvEnd
+	s.recvEnd = end
+	c.connRecvd += n
+	if c.connRecvd > c.connRecvMax {
+		return &localTransportError{code: errFlowControl, reason: "stream data exceeds MAX_DATA"}
+	}
+	if s.readClosed || s.recvErr != nil {
+		c.connDataConsumed(n)
+	}
+	return nil
+}
+
+// handleResetStream handles a RESET_STREAM frame.
+func (c *Conn) handleResetStream(id streamID, code uint64, finalSize int64) error {
+	s, err := c.streamForFrame(id, false)
+	if err != nil || s == nil {
+		return err
+	}
+	if s.finalSize >= 0 && finalSize != s.finalSize || finalSize < s.recvEnd {
+		return &localTransportError{code: errFinalSize}
+	}
+	if err := c.recvFlow(s, finalSize); err != nil {
+		return err
+	}
+	s.finalSize = finalSize
+	if s.recvDone || s.recvErr != nil {
+		return nil
+	}
+	s.recvErr = &StreamError{Code: code}
+	if !s.readClosed {
+		// Data received and not yet read is discarded.
+		c.connDataConsumed(finalSize - s.recv.readOff)
+	}
+	s.recv = recvBuffer{readOff: finalSize}
+	s.stopSendingToSend = false
+	s.maxStreamDataToSend = false
+	c.setRecvDone(s)
+	s.notify()
+	return nil
+}
+
+// handleStopSending handles a STOP_SENDING frame.
+func (c *Conn) handleStopSending(id streamID, code uint64) error {
+	s, err := c.streamForFrame(id, true)
+	if err != nil || s == nil {
+		return err
+	}
+	c.resetStream(s, code, &StreamError{Code: code})
+	return nil
+}
+
+// handleMaxStreamData handles a MAX_STREAM_DATA frame.
+func (c *Conn) handleMaxStreamData(id streamID, v int64) error {
+	s, err := c.streamForFrame(id, true)
+	if err != nil || s == nil {
+		return err
+	}
+	if v > s.sendMax {
+		s.sendMax = v
+		c.queueStream(s)
+	}
+	return nil
+}
+
+// handleMaxStreams handles a MAX_STREAMS frame.
+func (c *Conn) handleMaxStreams(typ streamType, v uint64) error {
+	if v > 1<<60 {
+		return frameError("invalid MAX_STREAMS")
+	}
+	if int64(v) > c.localLimit[typ] {
+		c.localLimit[typ] = int64(v)
+		c.notify()
+	}
+	return nil
+}
+
+// streamConsumed records that the application has read n bytes from a stream,
+// and extends the peer's flow control limits if necessary.
+func (c *Conn) streamConsumed(s *Stream, n int) {
+	if s.finalSize < 0 && !s.readClosed {
+		// Send MAX_STREAM_DATA when half the window has been used.
+		if s.recv.readOff+s.recvWindow-s.recvMax >= s.recvWindow/2 {
+			s.recvMax = s.recv.readOff + s.recvWindow
+			s.maxStreamDataToSend = true
+			c.queueStream(s)
+		}
+	}
+	c.connDataConsumed(int64(n))
+}
+
+// connDataConsumed records that n bytes of stream data have been read or
+// discarded, and extends the connection's flow control limit if necessary.
+func (c *Conn) connDataConsumed(n int64) {
+	c.connConsumed += n
+	if c.connConsumed+c.connWindow-c.connRecvMax >= c.connWindow/2 {
+		c.connRecvMax = c.connConsumed + c.connWindow
+		c.maxDataToSend = true
+		c.wake()
+	}
+}
+
+// resetStream aborts the send side of a stream with RESET_STREAM.
+func (c *Conn) resetStream(s *Stream, code uint64, err error) {
+	if s.sendErr == nil {
+		s.sendErr = err
+	}
+	if s.sendDone || s.reset != resetNone || s.finAcked {
+		return
+	}
+	s.resetCode = code
+	s.reset = resetToSend
+	s.send = sendBuffer{base: s.send.end()}
+	s.notify()
+	c.queueStream(s)
+}
+
+// queueStream schedules a stream's frames to be sent.
+func (c *Conn) queueStream(s *Stream) {
+	if !s.queued {
+		s.queued = true
+		c.sendq = append(c.sendq, s)
+	}
+	c.wake()
+}
+
+// setRecvDone records that the receive side of a stream is complete.
+func (c *Conn) setRecvDone(s *Stream) {
+	if s.recvDone {
+		return
+	}
+	s.recvDone = true
+	c.maybeRemoveStream(s)
+}
+
+// checkSendDone checks whether the send side of a stream is complete.
+func (c *Conn) checkSendDone(s *Stream) {
+	if s.sendDone {
+		return
+	}
+	if s.reset == resetAcked || s.reset == resetNone && s.finAcked && s.send.unacked() == 0 {
+		s.sendDone = true
+		c.maybeRemoveStream(s)
+	}
+}
+
+// maybeRemoveStream forgets a stream when both its sides are complete,
+// permitting the peer to open another stream in its place.
+func (c *Conn) maybeRemoveStream(s *Stream) {
+	if !s.recvDone || !s.sendDone {
+		return
+	}
+	if c.streams[s.id] != s {
+		return
+	}
+	delete(c.streams, s.id)
+	if s.id.initiator() != c.side {
+		typ := s.id.streamType()
+		c.remoteClosed[typ]++
+		c.remoteLimit[typ]++
+		c.maxStreamsToSend[typ] = true
+		c.wake()
+	}
+}
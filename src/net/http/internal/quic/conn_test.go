@@ -0,0 +1,378 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http/internal/testcert"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testTLSConfigs(t *testing.T) (client, server *tls.Config) {
+	cert, err := tls.X509KeyPair(testcert.LocalhostCert, testcert.LocalhostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"test"},
+	}
+	client = &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"test"},
+	}
+	return client, server
+}
+
+// lossyPacketConn is a PacketConn which drops some of the datagrams
+// it sends.
+type lossyPacketConn struct {
+	net.PacketConn
+	mu   sync.Mutex
+	rand *rand.Rand
+	rate float64
+}
+
+func (c *lossyPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.mu.Lock()
+	drop := c.rand.Float64() < c.rate
+	c.mu.Unlock()
+	if drop {
+		return len(b), nil
+	}
+	return c.PacketConn.WriteTo(b, addr)
+}
+
+func newLocalEndpoint(t *testing.T, config *Config, lossRate float64) *Endpoint {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on loopback: %v", err)
+	}
+	if lossRate > 0 {
+		pc = &lossyPacketConn{
+			PacketConn: pc,
+			rand:       rand.New(rand.NewSource(1)),
+			rate:       lossRate,
+		}
+	}
+	e := NewEndpoint(pc, config)
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+// newTestConns returns a connected pair of connections over loopback.
+func newTestConns(t *testing.T, config *Config, lossRate float64) (client, server *Conn) {
+	clientTLS, serverTLS := testTLSConfigs(t)
+	sconf := &Config{TLSConfig: serverTLS}
+	cconf := &Config{TLSConfig: clientTLS}
+	if config != nil {
+		c := *config
+		c.TLSConfig = serverTLS
+		sconf = &c
+		c2 := *config
+		c2.TLSConfig = clientTLS
+		cconf = &c2
+	}
+	se := newLocalEndpoint(t, sconf, lossRate)
+	ce := newLocalEndpoint(t, nil, lossRate)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	acceptc := make(chan *Conn, 1)
+	go func() {
+		c, err := se.Accept(ctx)
+		if err != nil {
+			t.Errorf("Accept: %v", err)
+		}
+		acceptc <- c
+	}()
+	client, err := ce.Dial(ctx, "udp", se.LocalAddr().String(), cconf)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	server = <-acceptc
+	if server == nil {
+		t.FailNow()
+	}
+	return client, server
+}
+
+func TestConnHandshake(t *testing.T) {
+	client, server := newTestConns(t, nil, 0)
+	if got := client.ConnectionState().NegotiatedProtocol; got != "test" {
+		t.Errorf("client negotiated protocol %q, want %q", got, "test")
+	}
+	if got := server.ConnectionState().Version; got != tls.VersionTLS13 {
+		t.Errorf("server TLS version %x, want %x", got, tls.VersionTLS13)
+	}
+	if client.Side() != ClientSide || server.Side() != ServerSide {
+		t.Errorf("sides = %v, %v; want client, server", client.Side(), server.Side())
+	}
+}
+
+func TestConnHandshakeFailure(t *testing.T) {
+	clientTLS, serverTLS := testTLSConfigs(t)
+	clientTLS.NextProtos = []string{"other"}
+	se := newLocalEndpoint(t, &Config{TLSConfig: serverTLS}, 0)
+	ce := newLocalEndpoint(t, nil, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := ce.Dial(ctx, "udp", se.LocalAddr().String(), &Config{TLSConfig: clientTLS})
+	var te *peerTransportError
+	if !errors.As(err, &te) || te.code != errTLSBase+120 {
+		t.Fatalf("Dial with mismatched ALPN: %v, want no_application_protocol", err)
+	}
+}
+
+// echo accepts streams and echoes their contents back.
+func echo(c *Conn) {
+	for {
+		s, err := c.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		go func() {
+			io.Copy(s, s)
+			s.Close()
+		}()
+	}
+}
+
+// streamEcho writes size bytes to a new stream and checks that the peer
+// echoes them back.
+func streamEcho(client *Conn, size int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	s, err := client.OpenStream(ctx)
+	if err != nil {
+		return err
+	}
+	s.SetDeadline(time.Now().Add(20 * time.Second))
+	want := make([]byte, size)
+	rand.Read(want)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.Write(want)
+		if err == nil {
+			err = s.CloseWrite()
+		}
+		errc <- err
+	}()
+	got, err := io.ReadAll(s)
+	if err != nil {
+		return fmt.Errorf("reading from stream: %v", err)
+	}
+	if err := <-errc; err != nil {
+		return fmt.Errorf("writing to stream: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("echoed %v bytes, not the %v bytes written", len(got), len(want))
+	}
+	return nil
+}
+
+func testStreamEcho(t *testing.T, client *Conn, size int) {
+	t.Helper()
+	if err := streamEcho(client, size); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConnStreamEcho(t *testing.T) {
+	client, server := newTestConns(t, nil, 0)
+	go echo(server)
+	testStreamEcho(t, client, 10)
+	testStreamEcho(t, client, 1<<20)
+}
+
+func TestConnStreamEchoLossy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	client, server := newTestConns(t, nil, 0.1)
+	go echo(server)
+	testStreamEcho(t, client, 200<<10)
+}
+
+func TestConnStreamFlowControl(t *testing.T) {
+	// A transfer much larger than the flow control windows.
+	client, server := newTestConns(t, &Config{
+		MaxStreamReadBufferSize:  4 << 10,
+		MaxStreamWriteBufferSize: 4 << 10,
+		MaxConnReadBufferSize:    8 << 10,
+	}, 0)
+	go echo(server)
+	testStreamEcho(t, client, 256<<10)
+}
+
+func TestConnManyStreams(t *testing.T) {
+	// More streams than the peer's limit, which must be raised as
+	// streams complete.
+	client, server := newTestConns(t, &Config{MaxBidiRemoteStreams: 4}, 0)
+	go echo(server)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := streamEcho(client, 1000); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestConnUniStream(t *testing.T) {
+	client, server := newTestConns(t, nil, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s, err := server.OpenUniStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Read on local unidirectional stream succeeded")
+	}
+	fmt.Fprintf(s, "hello")
+	s.Close()
+	cs, err := client.AcceptUniStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.Write([]byte("x")); err == nil {
+		t.Errorf("Write on remote unidirectional stream succeeded")
+	}
+	got, err := io.ReadAll(cs)
+	if err != nil || string(got) != "hello" {
+		t.Errorf("ReadAll = %q, %v; want %q", got, err, "hello")
+	}
+}
+
+func TestConnStreamReset(t *testing.T) {
+	client, server := newTestConns(t, nil, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s, err := client.OpenStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte("data"))
+	ss, err := server.AcceptStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Reset(42)
+	ss.SetReadDeadline(time.Now().Add(10 * time.Second))
+	_, err = io.ReadAll(ss)
+	var se *StreamError
+	if !errors.As(err, &se) || se.Code != 42 {
+		t.Errorf("read from reset stream: %v, want StreamError 42", err)
+	}
+
+	// STOP_SENDING causes the peer's writes to fail.
+	ss.CloseRead(7)
+	s2, err := server.OpenStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2.Write([]byte("x"))
+	cs2, err := client.AcceptStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs2.CloseRead(9)
+	s2.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	for {
+		if _, err = s2.Write([]byte("more")); err != nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !errors.As(err, &se) || se.Code != 9 {
+		t.Errorf("write to stopped stream: %v, want StreamError 9", err)
+	}
+}
+
+func TestConnReadDeadline(t *testing.T) {
+	client, _ := newTestConns(t, nil, 0)
+	s, err := client.OpenStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := s.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Read with expired deadline: %v", err)
+	}
+}
+
+func TestConnClose(t *testing.T) {
+	client, server := newTestConns(t, nil, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s, err := client.OpenStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte("request"))
+	s.CloseWrite()
+	if err := client.Close(ctx, 3, "bye"); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	// The data was delivered before the connection closed.
+	ss, err := server.AcceptStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(ss); string(got) != "request" {
+		t.Errorf("read %q from stream, want %q", got, "request")
+	}
+	select {
+	case <-server.Done():
+	case <-ctx.Done():
+		t.Fatal("server connection not closed")
+	}
+	want := &ApplicationError{Code: 3}
+	if err := server.Err(); !errors.Is(err, want) {
+		t.Errorf("server Err() = %v, want %v", err, want)
+	}
+	if _, err := server.AcceptStream(ctx); !errors.Is(err, want) {
+		t.Errorf("AcceptStream after close: %v, want %v", err, want)
+	}
+}
+
+func TestConnIdleTimeout(t *testing.T) {
+	client, server := newTestConns(t, &Config{MaxIdleTimeout: 100 * time.Millisecond}, 0)
+	server.endpoint.pc.Close() // the server vanishes
+	select {
+	case <-client.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("client connection not closed after idle timeout")
+	}
+	if err := client.Err(); err != errIdleTimeout {
+		t.Errorf("client Err() = %v, want %v", err, errIdleTimeout)
+	}
+}
+
+func TestConnKeepAlive(t *testing.T) {
+	client, _ := newTestConns(t, &Config{
+		MaxIdleTimeout:  500 * time.Millisecond,
+		KeepAlivePeriod: 100 * time.Millisecond,
+	}, 0)
+	select {
+	case <-client.Done():
+		t.Fatalf("connection closed despite keep-alives: %v", client.Err())
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
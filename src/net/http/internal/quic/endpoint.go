@@ -0,0 +1,244 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// An Endpoint handles QUIC traffic on a network address.
+// It can accept inbound connections, create outbound ones, or both.
+//
+// Multiple goroutines may invoke methods on an Endpoint simultaneously.
+type Endpoint struct {
+	pc     net.PacketConn
+	config *Config
+	donec  chan struct{} // closed when the read loop exits
+
+	mu           sync.Mutex
+	conns        map[string]*Conn // by local connection ID
+	initialConns map[string]*Conn // by the client's original destination connection ID
+	acceptq      []*Conn
+	acceptc      chan struct{} // closed and replaced when acceptq changes
+	closed       bool
+}
+
+// Listen listens on a local network address.
+// If config is non-nil and has a TLSConfig, the endpoint accepts
+// inbound connections.
+func Listen(network, address string, config *Config) (*Endpoint, error) {
+	pc, err := net.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewEndpoint(pc, config), nil
+}
+
+// NewEndpoint returns an Endpoint which sends and receives datagrams on pc.
+// The Endpoint takes ownership of pc, and closes it when the Endpoint is closed.
+// If config is non-nil and has a TLSConfig, the endpoint accepts
+// inbound connections.
+func NewEndpoint(pc net.PacketConn, config *Config) *Endpoint {
+	e := &Endpoint{
+		pc:           pc,
+		config:       config,
+		donec:        make(chan struct{}),
+		conns:        make(map[string]*Conn),
+		initialConns: make(map[string]*Conn),
+		acceptc:      make(chan struct{}),
+	}
+	go e.readLoop()
+	return e
+}
+
+// LocalAddr returns the local network address.
+func (e *Endpoint) LocalAddr() net.Addr {
+	return e.pc.LocalAddr()
+}
+
+// Close closes the Endpoint and its connections.
+// Connections are closed without waiting for the peer to
+// acknowledge data.
+func (e *Endpoint) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return ErrClosed
+	}
+	e.closed = true
+	conns := make([]*Conn, 0, len(e.conns))
+	for _, c := range e.conns {
+		conns = append(conns, c)
+	}
+	close(e.acceptc)
+	e.mu.Unlock()
+	for _, c := range conns {
+		c.exit()
+	}
+	for _, c := range conns {
+		<-c.donec
+	}
+	err := e.pc.Close()
+	<-e.donec
+	return err
+}
+
+// Accept waits for and returns the next inbound connection,
+// after its handshake has completed.
+func (e *Endpoint) Accept(ctx context.Context) (*Conn, error) {
+	for {
+		e.mu.Lock()
+		if len(e.acceptq) > 0 {
+			c := e.acceptq[0]
+			e.acceptq[0] = nil
+			e.acceptq = e.acceptq[1:]
+			e.mu.Unlock()
+			return c, nil
+		}
+		if e.closed {
+			e.mu.Unlock()
+			return nil, ErrClosed
+		}
+		ch := e.acceptc
+		e.mu.Unlock()
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Dial creates and returns a connection to a network address,
+// after its handshake has completed.
+// The config must have a TLSConfig with a ServerName or
+// InsecureSkipVerify set, as for a tls.Client.
+func (e *Endpoint) Dial(ctx context.Context, network, address string, config *Config) (*Conn, error) {
+	addr, err := net.ResolveUDPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	c := newConn(e, ClientSide, config, addr, now)
+	c.mu.Lock()
+	err = c.startClient(now)
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil, ErrClosed
+	}
+	e.conns[string(c.localConnID)] = c
+	e.mu.Unlock()
+	go c.loop()
+	if err := c.waitReady(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readLoop reads datagrams and dispatches them to connections.
+func (e *Endpoint) readLoop() {
+	defer close(e.donec)
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := e.pc.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		e.handleDatagram(append([]byte(nil), buf[:n]...), addr)
+	}
+}
+
+// handleDatagram dispatches a datagram.
+func (e *Endpoint) handleDatagram(b []byte, addr net.Addr) {
+	h, ok := parsePacketHeader(b)
+	if !ok {
+		return
+	}
+	e.mu.Lock()
+	c := e.conns[string(h.dstConnID)]
+	if c == nil && isLongHeader(b[0]) {
+		c = e.initialConns[string(h.dstConnID)]
+	}
+	closed := e.closed
+	e.mu.Unlock()
+	if c != nil {
+		// We don't support connection migration.
+		if addr.String() == c.peerAddr.String() {
+			c.deliver(b)
+		}
+		return
+	}
+	if closed || !isLongHeader(b[0]) {
+		return
+	}
+	if h.version != quicVersion1 {
+		if h.version != 0 && len(b) >= minimumClientInitialDatagramSize {
+			// Tell the client which versions we support
+			// (RFC 9000, Section 6).
+			e.writeTo(appendVersionNegotiation(nil, h.srcConnID, h.dstConnID), addr)
+		}
+		return
+	}
+	if e.config == nil || e.config.TLSConfig == nil || h.ptype != packetTypeInitial {
+		return
+	}
+	// A client's first Initial must be padded, and use a destination
+	// connection ID of at least 8 bytes (RFC 9000, Sections 7.2 and 14.1).
+	if len(b) < minimumClientInitialDatagramSize || len(h.dstConnID) < 8 {
+		return
+	}
+	now := time.Now()
+	c = newConn(e, ServerSide, e.config, addr, now)
+	if err := c.startServer(now, h); err != nil {
+		return
+	}
+	e.mu.Lock()
+	e.conns[string(c.localConnID)] = c
+	e.initialConns[string(c.origDstConnID)] = c
+	e.mu.Unlock()
+	go c.loop()
+	c.deliver(b)
+}
+
+// writeTo sends a datagram.
+func (e *Endpoint) writeTo(b []byte, addr net.Addr) {
+	e.pc.WriteTo(b, addr)
+}
+
+// queueAccept queues a connection whose handshake has completed for Accept.
+// The conn mutex is held.
+func (e *Endpoint) queueAccept(c *Conn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	e.acceptq = append(e.acceptq, c)
+	close(e.acceptc)
+	e.acceptc = make(chan struct{})
+}
+
+// removeConn forgets a connection which has exited.
+func (e *Endpoint) removeConn(c *Conn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conns[string(c.localConnID)] == c {
+		delete(e.conns, string(c.localConnID))
+	}
+	if e.initialConns[string(c.origDstConnID)] == c {
+		delete(e.initialConns, string(c.origDstConnID))
+	}
+}
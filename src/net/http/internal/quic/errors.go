@@ -0,0 +1,127 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"fmt"
+	"internal/tlsquic"
+)
+
+// A transportError is a transport error code (RFC 9000, Section 20.1).
+type transportError uint64
+
+const (
+	errNo                   = transportError(0x00)
+	errInternal             = transportError(0x01)
+	errConnectionRefused    = transportError(0x02)
+	errFlowControl          = transportError(0x03)
+	errStreamLimit          = transportError(0x04)
+	errStreamState          = transportError(0x05)
+	errFinalSize            = transportError(0x06)
+	errFrameEncoding        = transportError(0x07)
+	errTransportParameter   = transportError(0x08)
+	errConnectionIDLimit    = transportError(0x09)
+	errProtocolViolation    = transportError(0x0a)
+	errInvalidToken         = transportError(0x0b)
+	errApplicationError     = transportError(0x0c)
+	errCryptoBufferExceeded = transportError(0x0d)
+	errKeyUpdateError       = transportError(0x0e)
+	errAEADLimitReached     = transportError(0x0f)
+	errNoViablePath         = transportError(0x10)
+	errTLSBase              = transportError(0x0100) // 0x0100-0x01ff; base + TLS alert
+)
+
+var transportErrorNames = map[transportError]string{
+	errNo:                   "NO_ERROR",
+	errInternal:             "INTERNAL_ERROR",
+	errConnectionRefused:    "CONNECTION_REFUSED",
+	errFlowControl:          "FLOW_CONTROL_ERROR",
+	errStreamLimit:          "STREAM_LIMIT_ERROR",
+	errStreamState:          "STREAM_STATE_ERROR",
+	errFinalSize:            "FINAL_SIZE_ERROR",
+	errFrameEncoding:        "FRAME_ENCODING_ERROR",
+	errTransportParameter:   "TRANSPORT_PARAMETER_ERROR",
+	errConnectionIDLimit:    "CONNECTION_ID_LIMIT_ERROR",
+	errProtocolViolation:    "PROTOCOL_VIOLATION",
+	errInvalidToken:         "INVALID_TOKEN",
+	errApplicationError:     "APPLICATION_ERROR",
+	errCryptoBufferExceeded: "CRYPTO_BUFFER_EXCEEDED",
+	errKeyUpdateError:       "KEY_UPDATE_ERROR",
+	errAEADLimitReached:     "AEAD_LIMIT_REACHED",
+	errNoViablePath:         "NO_VIABLE_PATH",
+}
+
+func (e transportError) String() string {
+	if s, ok := transportErrorNames[e]; ok {
+		return s
+	}
+	if e >= errTLSBase && e <= errTLSBase+0xff {
+		return fmt.Sprintf("CRYPTO_ERROR(%v)", tlsquic.AlertError(e-errTLSBase))
+	}
+	return fmt.Sprintf("ERROR %d", uint64(e))
+}
+
+// A localTransportError is a transport error this endpoint detected,
+// and reported to the peer with a CONNECTION_CLOSE frame.
+type localTransportError struct {
+	code   transportError
+	reason string
+	err    error // underlying error, if any
+}
+
+func (e *localTransportError) Error() string {
+	s := "quic: " + e.code.String()
+	if e.reason != "" {
+		s += ": " + e.reason
+	}
+	if e.err != nil {
+		s += ": " + e.err.Error()
+	}
+	return s
+}
+
+func (e *localTransportError) Unwrap() error {
+	return e.err
+}
+
+// A peerTransportError is a transport error the peer reported
+// with a CONNECTION_CLOSE frame.
+type peerTransportError struct {
+	code   transportError
+	reason string
+}
+
+func (e *peerTransportError) Error() string {
+	return fmt.Sprintf("quic: peer closed connection: %v: %q", e.code, e.reason)
+}
+
+// An ApplicationError is an application protocol error (RFC 9000, Section 20.2).
+//
+// Conn.Abort closes a connection with an ApplicationError.
+// Methods of a Conn the peer closed with an application error return it.
+type ApplicationError struct {
+	Code   uint64
+	Reason string
+}
+
+func (e *ApplicationError) Error() string {
+	return fmt.Sprintf("quic: application error 0x%x: %q", e.Code, e.Reason)
+}
+
+// Is reports whether target is an ApplicationError with the same code.
+func (e *ApplicationError) Is(target error) bool {
+	e2, ok := target.(*ApplicationError)
+	return ok && e2.Code == e.Code
+}
+
+// A StreamError is returned by Stream methods when the peer
+// has reset the stream or asked it to stop sending data.
+type StreamError struct {
+	Code uint64
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("quic: stream error 0x%x", e.Code)
+}
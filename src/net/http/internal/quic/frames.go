@@ -0,0 +1,444 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+// Frame types, RFC 9000, Section 19.
+const (
+	frameTypePadding                    = 0x00
+	frameTypePing                       = 0x01
+	frameTypeAck                        = 0x02
+	frameTypeAckECN                     = 0x03
+	frameTypeResetStream                = 0x04
+	frameTypeStopSending                = 0x05
+	frameTypeCrypto                     = 0x06
+	frameTypeNewToken                   = 0x07
+	frameTypeStreamBase                 = 0x08 // low three bits carry stream frame flags
+	frameTypeMaxData                    = 0x10
+	frameTypeMaxStreamData              = 0x11
+	frameTypeMaxStreamsBidi             = 0x12
+	frameTypeMaxStreamsUni              = 0x13
+	frameTypeDataBlocked                = 0x14
+	frameTypeStreamDataBlocked          = 0x15
+	frameTypeStreamsBlockedBidi         = 0x16
+	frameTypeStreamsBlockedUni          = 0x17
+	frameTypeNewConnectionID            = 0x18
+	frameTypeRetireConnectionID         = 0x19
+	frameTypePathChallenge              = 0x1a
+	frameTypePathResponse               = 0x1b
+	frameTypeConnectionCloseTransport   = 0x1c
+	frameTypeConnectionCloseApplication = 0x1d
+	frameTypeHandshakeDone              = 0x1e
+)
+
+// STREAM frame type bits.
+const (
+	streamOffBit = 0x04
+	streamLenBit = 0x02
+	streamFinBit = 0x01
+)
+
+// frameAllowedIn reports whether a frame type may appear in a packet of the
+// given type (RFC 9000, Section 12.4, Table 3).
+func frameAllowedIn(ftype uint64, ptype packetType) bool {
+	if ptype == packetType1RTT {
+		return true
+	}
+	switch ftype {
+	case frameTypePadding, frameTypePing, frameTypeAck, frameTypeAckECN,
+		frameTypeCrypto, frameTypeConnectionCloseTransport:
+		return ptype == packetTypeInitial || ptype == packetTypeHandshake
+	}
+	return false
+}
+
+// isAckEliciting reports whether a frame type elicits an acknowledgement.
+func isAckEliciting(ftype uint64) bool {
+	switch ftype {
+	case frameTypePadding, frameTypeAck, frameTypeAckECN,
+		frameTypeConnectionCloseTransport, frameTypeConnectionCloseApplication:
+		return false
+	}
+	return true
+}
+
+// consumeAckFrame parses an ACK frame.
+// It returns the acknowledged packet numbers as a rangeset.
+func consumeAckFrame(b []byte) (acked rangeset, ackDelay uint64, n int) {
+	ftype := b[0]
+	n = 1
+	largest, ln := ConsumeVarint(b[n:])
+	if ln < 0 {
+		return nil, 0, -1
+	}
+	n += ln
+	ackDelay, ln = ConsumeVarint(b[n:])
+	if ln < 0 {
+		return nil, 0, -1
+	}
+	n += ln
+	rangeCount, ln := ConsumeVarint(b[n:])
+	if ln < 0 {
+		return nil, 0, -1
+	}
+	n += ln
+	firstRange, ln := ConsumeVarint(b[n:])
+	if ln < 0 || firstRange > largest {
+		return nil, 0, -1
+	}
+	n += ln
+	// Ranges are in descending order; build them in reverse.
+	var desc []i64range
+	end := int64(largest) + 1
+	start := end - 1 - int64(firstRange)
+	desc = append(desc, i64range{start, end})
+	for i := uint64(0); i < rangeCount; i++ {
+		gap, ln := ConsumeVarint(b[n:])
+		if ln < 0 {
+			return nil, 0, -1
+		}
+		n += ln
+		length, ln := ConsumeVarint(b[n:])
+		if ln < 0 {
+			return nil, 0, -1
+		}
+		n += ln
+		end = start - int64(gap) - 1
+		start = end - 1 - int64(length)
+		if start < 0 {
+			return nil, 0, -1
+		}
+		desc = append(desc, i64range{start, end})
+	}
+	if ftype == frameTypeAckECN {
+		for i := 0; i < 3; i++ {
+			_, ln := ConsumeVarint(b[n:])
+			if ln < 0 {
+				return nil, 0, -1
+			}
+			n += ln
+		}
+	}
+	acked = make(rangeset, len(desc))
+	for i, r := range desc {
+		acked[len(desc)-1-i] = r
+	}
+	return acked, ackDelay, n
+}
+
+// appendAckFrame appends an ACK frame acknowledging the packets in seen,
+// using no more than maxSize bytes. If there is not enough space
+// for all the ranges, the smallest ones are omitted.
+// It returns b unchanged if there is no room for the frame.
+func appendAckFrame(b []byte, seen rangeset, ackDelay uint64, maxSize int) []byte {
+	if len(seen) == 0 {
+		return b
+	}
+	orig := len(b)
+	last := seen[len(seen)-1]
+	b = append(b, frameTypeAck)
+	b = AppendVarint(b, uint64(last.end-1))
+	b = AppendVarint(b, ackDelay)
+	// Range count is filled in below; reserve space for a 2-byte varint.
+	countOff := len(b)
+	b = append(b, 0x40, 0)
+	b = AppendVarint(b, uint64(last.size()-1))
+	if len(b)-orig > maxSize {
+		return b[:orig]
+	}
+	count := 0
+	prev := last
+	for i := len(seen) - 2; i >= 0 && count < 1<<14-1; i-- {
+		r := seen[i]
+		gap := uint64(prev.start - r.end - 1)
+		length := uint64(r.size() - 1)
+		if len(b)-orig+SizeVarint(gap)+SizeVarint(length) > maxSize {
+			break
+		}
+		b = AppendVarint(b, gap)
+		b = AppendVarint(b, length)
+		prev = r
+		count++
+	}
+	b[countOff] = 0x40 | byte(count>>8)
+	b[countOff+1] = byte(count)
+	return b
+}
+
+// consumeResetStreamFrame parses a RESET_STREAM frame.
+func consumeResetStreamFrame(b []byte) (id streamID, code uint64, finalSize int64, n int) {
+	n = 1
+	v, ln := ConsumeVarint(b[n:])
+	if ln < 0 {
+		return 0, 0, 0, -1
+	}
+	id = streamID(v)
+	n += ln
+	code, ln = ConsumeVarint(b[n:])
+	if ln < 0 {
+		return 0, 0, 0, -1
+	}
+	n += ln
+	v, ln = ConsumeVarint(b[n:])
+	if ln < 0 {
+		return 0, 0, 0, -1
+	}
+	finalSize = int64(v)
+	n += ln
+	return id, code, finalSize, n
+}
+
+func appendResetStreamFrame(b []byte, id streamID, code uint64, finalSize int64) []byte {
+	b = append(b, frameTypeResetStream)
+	b = AppendVarint(b, uint64(id))
+	b = AppendVarint(b, code)
+	return AppendVarint(b, uint64(finalSize))
+}
+
+// consumeStopSendingFrame parses a STOP_SENDING frame.
+func consumeStopSendingFrame(b []byte) (id streamID, code uint64, n int) {
+	n = 1
+	v, ln := ConsumeVarint(b[n:])
+	if ln < 0 {
+		return 0, 0, -1
+	}
+	id = streamID(v)
+	n += ln
+	code, ln = ConsumeVarint(b[n:])
+	if ln < 0 {
+		return 0, 0, -1
+	}
+	n += ln
+	return id, code, n
+}
+
+func appendStopSendingFrame(b []byte, id streamID, code uint64) []byte {
+	b = append(b, frameTypeStopSending)
+	b = AppendVarint(b, uint64(id))
+	return AppendVarint(b, code)
+}
+
+// consumeCryptoFrame parses a CRYPTO frame.
+func consumeCryptoFrame(b []byte) (off int64, data []byte, n int) {
+	n = 1
+	v, ln := ConsumeVarint(b[n:])
+	if ln < 0 {
+		return 0, nil, -1
+	}
+	off = int64(v)
+	n += ln
+	data, ln = consumeVarintBytes(b[n:])
+	if ln < 0 || uint64(off)+uint64(len(data)) > MaxVarint {
+		return 0, nil, -1
+	}
+	n += ln
+	return off, data, n
+}
+
+// cryptoFrameOverhead returns the size of a CRYPTO frame header.
+func cryptoFrameOverhead(off int64, size int) int {
+	return 1 + SizeVarint(uint64(off)) + SizeVarint(uint64(size))
+}
+
+func appendCryptoFrame(b []byte, off int64, data []byte) []byte {
+	b = append(b, frameTypeCrypto)
+	b = AppendVarint(b, uint64(off))
+	return appendVarintBytes(b, data)
+}
+
+// consumeNewTokenFrame parses a NEW_TOKEN frame.
+func consumeNewTokenFrame(b []byte) (token []byte, n int) {
+	token, ln := consumeVarintBytes(b[1:])
+	if ln < 0 || len(token) == 0 {
+		return nil, -1
+	}
+	return token, 1 + ln
+}
+
+// consumeStreamFrame parses a STREAM frame.
+func consumeStreamFrame(b []byte) (id streamID, off int64, fin bool, data []byte, n int) {
+	ftype := b[0]
+	n = 1
+	v, ln := ConsumeVarint(b[n:])
+	if ln < 0 {
+		return 0, 0, false, nil, -1
+	}
+	id = streamID(v)
+	n += ln
+	if ftype&streamOffBit != 0 {
+		v, ln := ConsumeVarint(b[n:])
+		if ln < 0 {
+			return 0, 0, false, nil, -1
+		}
+		off = int64(v)
+		n += ln
+	}
+	if ftype&streamLenBit != 0 {
+		data, ln = consumeVarintBytes(b[n:])
+		if ln < 0 {
+			return 0, 0, false, nil, -1
+		}
+		n += ln
+	} else {
+		data = b[n:]
+		n = len(b)
+	}
+	if uint64(off)+uint64(len(data)) > MaxVarint {
+		return 0, 0, false, nil, -1
+	}
+	fin = ftype&streamFinBit != 0
+	return id, off, fin, data, n
+}
+
+// streamFrameOverhead returns the size of a STREAM frame header.
+func streamFrameOverhead(id streamID, off int64, size int) int {
+	n := 1 + SizeVarint(uint64(id)) + SizeVarint(uint64(size))
+	if off > 0 {
+		n += SizeVarint(uint64(off))
+	}
+	return n
+}
+
+func appendStreamFrame(b []byte, id streamID, off int64, data []byte, fin bool) []byte {
+	ftype := byte(frameTypeStreamBase | streamLenBit)
+	if off > 0 {
+		ftype |= streamOffBit
+	}
+	if fin {
+		ftype |= streamFinBit
+	}
+	b = append(b, ftype)
+	b = AppendVarint(b, uint64(id))
+	if off > 0 {
+		b = AppendVarint(b, uint64(off))
+	}
+	return appendVarintBytes(b, data)
+}
+
+// consumeUint64Frame parses a frame consisting of a type and a single varint,
+// such as MAX_DATA or MAX_STREAMS.
+func consumeUint64Frame(b []byte) (v uint64, n int) {
+	v, ln := ConsumeVarint(b[1:])
+	if ln < 0 {
+		return 0, -1
+	}
+	return v, 1 + ln
+}
+
+func appendUint64Frame(b []byte, ftype byte, v uint64) []byte {
+	b = append(b, ftype)
+	return AppendVarint(b, v)
+}
+
+// consumeStreamUint64Frame parses a frame consisting of a type, a stream ID,
+// and a single varint, such as MAX_STREAM_DATA.
+func consumeStreamUint64Frame(b []byte) (id streamID, v uint64, n int) {
+	n = 1
+	sid, ln := ConsumeVarint(b[n:])
+	if ln < 0 {
+		return 0, 0, -1
+	}
+	n += ln
+	v, ln = ConsumeVarint(b[n:])
+	if ln < 0 {
+		return 0, 0, -1
+	}
+	n += ln
+	return streamID(sid), v, n
+}
+
+func appendStreamUint64Frame(b []byte, ftype byte, id streamID, v uint64) []byte {
+	b = append(b, ftype)
+	b = AppendVarint(b, uint64(id))
+	return AppendVarint(b, v)
+}
+
+// consumeNewConnectionIDFrame parses a NEW_CONNECTION_ID frame.
+func consumeNewConnectionIDFrame(b []byte) (seq, retirePriorTo uint64, connID, resetToken []byte, n int) {
+	n = 1
+	seq, ln := ConsumeVarint(b[n:])
+	if ln < 0 {
+		return 0, 0, nil, nil, -1
+	}
+	n += ln
+	retirePriorTo, ln = ConsumeVarint(b[n:])
+	if ln < 0 || retirePriorTo > seq {
+		return 0, 0, nil, nil, -1
+	}
+	n += ln
+	if len(b) < n+1 {
+		return 0, 0, nil, nil, -1
+	}
+	cidLen := int(b[n])
+	n++
+	if cidLen < 1 || cidLen > 20 || len(b) < n+cidLen+16 {
+		return 0, 0, nil, nil, -1
+	}
+	connID = b[n:][:cidLen]
+	n += cidLen
+	resetToken = b[n:][:16]
+	n += 16
+	return seq, retirePriorTo, connID, resetToken, n
+}
+
+func appendNewConnectionIDFrame(b []byte, seq, retirePriorTo uint64, connID, resetToken []byte) []byte {
+	b = append(b, frameTypeNewConnectionID)
+	b = AppendVarint(b, seq)
+	b = AppendVarint(b, retirePriorTo)
+	b = append(b, byte(len(connID)))
+	b = append(b, connID...)
+	return append(b, resetToken...)
+}
+
+// consumePathFrame parses a PATH_CHALLENGE or PATH_RESPONSE frame.
+func consumePathFrame(b []byte) (data [8]byte, n int) {
+	if len(b) < 9 {
+		return data, -1
+	}
+	copy(data[:], b[1:9])
+	return data, 9
+}
+
+func appendPathFrame(b []byte, ftype byte, data [8]byte) []byte {
+	b = append(b, ftype)
+	return append(b, data[:]...)
+}
+
+// consumeConnectionCloseFrame parses a CONNECTION_CLOSE frame.
+// The frame type field of transport close frames is discarded.
+func consumeConnectionCloseFrame(b []byte) (code uint64, reason string, n int) {
+	ftype := b[0]
+	n = 1
+	code, ln := ConsumeVarint(b[n:])
+	if ln < 0 {
+		return 0, "", -1
+	}
+	n += ln
+	if ftype == frameTypeConnectionCloseTransport {
+		_, ln = ConsumeVarint(b[n:])
+		if ln < 0 {
+			return 0, "", -1
+		}
+		n += ln
+	}
+	r, ln := consumeVarintBytes(b[n:])
+	if ln < 0 {
+		return 0, "", -1
+	}
+	n += ln
+	return code, string(r), n
+}
+
+func appendConnectionCloseTransportFrame(b []byte, code transportError, reason string) []byte {
+	b = append(b, frameTypeConnectionCloseTransport)
+	b = AppendVarint(b, uint64(code))
+	b = append(b, 0) // Frame Type
+	return appendVarintBytes(b, []byte(reason))
+}
+
+func appendConnectionCloseApplicationFrame(b []byte, code uint64, reason string) []byte {
+	b = append(b, frameTypeConnectionCloseApplication)
+	b = AppendVarint(b, code)
+	return appendVarintBytes(b, []byte(reason))
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVarint(t *testing.T) {
+	// RFC 9000, Appendix A.1.
+	for _, test := range []struct {
+		enc string
+		v   uint64
+	}{
+		{"c2197c5eff14e88c", 151288809941952652},
+		{"9d7f3e7d", 494878333},
+		{"7bbd", 15293},
+		{"25", 37},
+	} {
+		b := unhex(test.enc)
+		v, n := ConsumeVarint(b)
+		if v != test.v || n != len(b) {
+			t.Errorf("ConsumeVarint(%v) = %v, %v; want %v, %v", test.enc, v, n, test.v, len(b))
+		}
+		if got := AppendVarint(nil, test.v); !bytes.Equal(got, b) {
+			t.Errorf("AppendVarint(%v) = %x, want %v", test.v, got, test.enc)
+		}
+		if got, err := ReadVarint(bytes.NewReader(b)); got != test.v || err != nil {
+			t.Errorf("ReadVarint(%v) = %v, %v; want %v, nil", test.enc, got, err, test.v)
+		}
+		if _, n := ConsumeVarint(b[:len(b)-1]); n >= 0 && len(b) > 1 {
+			t.Errorf("ConsumeVarint(truncated %v) = %v, want error", test.enc, n)
+		}
+	}
+	// The two-byte encoding of 37 is also valid.
+	if v, n := ConsumeVarint(unhex("4025")); v != 37 || n != 2 {
+		t.Errorf("ConsumeVarint(4025) = %v, %v; want 37, 2", v, n)
+	}
+}
+
+func TestAckFrame(t *testing.T) {
+	var seen rangeset
+	seen.add(0, 5)
+	seen.add(7, 8)
+	seen.add(10, 20)
+	b := appendAckFrame(nil, seen, 12, 100)
+	acked, delay, n := consumeAckFrame(b)
+	if n != len(b) || delay != 12 || !reflect.DeepEqual(acked, seen) {
+		t.Fatalf("consumeAckFrame = %v, %v, %v; want %v, 12, %v", acked, delay, n, seen, len(b))
+	}
+
+	// When there isn't room for all the ranges, the oldest are omitted.
+	short := appendAckFrame(nil, seen, 12, len(b)-2)
+	acked, _, _ = consumeAckFrame(short)
+	if want := seen[1:]; !reflect.DeepEqual(acked, want) {
+		t.Errorf("truncated ACK frame acknowledges %v, want %v", acked, want)
+	}
+}
+
+func TestStreamFrame(t *testing.T) {
+	for _, test := range []struct {
+		id   streamID
+		off  int64
+		data string
+		fin  bool
+	}{
+		{0, 0, "hello", false},
+		{4, 1000, "", true},
+		{newStreamID(ServerSide, uniStream, 7), 1 << 40, "data", true},
+	} {
+		b := appendStreamFrame(nil, test.id, test.off, []byte(test.data), test.fin)
+		if got, want := len(b), streamFrameOverhead(test.id, test.off, len(test.data))+len(test.data); got != want {
+			t.Errorf("STREAM frame size = %v, overhead says %v", got, want)
+		}
+		b = append(b, frameTypePing)
+		id, off, fin, data, n := consumeStreamFrame(b)
+		if id != test.id || off != test.off || fin != test.fin || string(data) != test.data || n != len(b)-1 {
+			t.Errorf("consumeStreamFrame(append(%v, %v, %q, %v)) = %v, %v, %v, %q, %v",
+				test.id, test.off, test.data, test.fin, id, off, fin, data, n)
+		}
+	}
+}
+
+func TestConnectionCloseFrame(t *testing.T) {
+	b := appendConnectionCloseTransportFrame(nil, errProtocolViolation, "oops")
+	if code, reason, n := consumeConnectionCloseFrame(b); code != uint64(errProtocolViolation) || reason != "oops" || n != len(b) {
+		t.Errorf("transport CONNECTION_CLOSE = %v, %q, %v", code, reason, n)
+	}
+	b = appendConnectionCloseApplicationFrame(nil, 0x100, "bye")
+	if code, reason, n := consumeConnectionCloseFrame(b); code != 0x100 || reason != "bye" || n != len(b) {
+		t.Errorf("application CONNECTION_CLOSE = %v, %q, %v", code, reason, n)
+	}
+}
+
+func TestTransportParameters(t *testing.T) {
+	p := transportParameters{
+		originalDstConnID:              []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		maxIdleTimeout:                 10 * time.Second,
+		statelessResetToken:            make([]byte, 16),
+		maxUDPPayloadSize:              1500,
+		initialMaxData:                 1 << 20,
+		initialMaxStreamDataBidiLocal:  1 << 10,
+		initialMaxStreamDataBidiRemote: 1 << 11,
+		initialMaxStreamDataUni:        1 << 12,
+		initialMaxStreamsBidi:          100,
+		initialMaxStreamsUni:           3,
+		ackDelayExponent:               5,
+		maxAckDelay:                    100 * time.Millisecond,
+		disableActiveMigration:         true,
+		activeConnIDLimit:              4,
+		initialSrcConnID:               []byte{9, 9},
+		retrySrcConnID:                 []byte{},
+	}
+	got, err := unmarshalTransportParameters(p.marshal(), ServerSide)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("round trip of transport parameters:\n got %+v\nwant %+v", got, p)
+	}
+
+	// Clients may not send server-only parameters.
+	if _, err := unmarshalTransportParameters(p.marshal(), ClientSide); err == nil {
+		t.Errorf("client parameters with original_destination_connection_id accepted")
+	}
+
+	for _, test := range []struct {
+		name string
+		b    []byte
+	}{
+		{"duplicate", append(appendVarintParam(nil, paramInitialMaxData, 1), appendVarintParam(nil, paramInitialMaxData, 2)...)},
+		{"small max_udp_payload_size", appendVarintParam(nil, paramMaxUDPPayloadSize, 1000)},
+		{"large ack_delay_exponent", appendVarintParam(nil, paramAckDelayExponent, 21)},
+		{"small active_connection_id_limit", appendVarintParam(nil, paramActiveConnectionIDLimit, 1)},
+		{"truncated", appendVarintParam(nil, paramInitialMaxData, 1<<20)[:3]},
+	} {
+		_, err := unmarshalTransportParameters(test.b, ServerSide)
+		if err == nil || !strings.Contains(err.Error(), "TRANSPORT_PARAMETER_ERROR") {
+			t.Errorf("%v: unmarshalTransportParameters error = %v, want TRANSPORT_PARAMETER_ERROR", test.name, err)
+		}
+	}
+}
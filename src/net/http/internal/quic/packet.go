@@ -0,0 +1,419 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "encoding/binary"
+
+// A packetNumber is a QUIC packet number.
+type packetNumber int64
+
+const maxPacketNumber = 1<<62 - 1
+
+// A packetType is the type of a QUIC packet.
+type packetType byte
+
+const (
+	packetTypeInvalid = packetType(iota)
+	packetTypeInitial
+	packetType0RTT
+	packetTypeHandshake
+	packetTypeRetry
+	packetType1RTT
+	packetTypeVersionNegotiation
+)
+
+func (p packetType) String() string {
+	switch p {
+	case packetTypeInitial:
+		return "Initial"
+	case packetType0RTT:
+		return "0-RTT"
+	case packetTypeHandshake:
+		return "Handshake"
+	case packetTypeRetry:
+		return "Retry"
+	case packetType1RTT:
+		return "1-RTT"
+	case packetTypeVersionNegotiation:
+		return "VersionNegotiation"
+	}
+	return "invalid"
+}
+
+// Bits in the first byte of a packet.
+const (
+	headerFormLong  = 0x80
+	fixedBit        = 0x40
+	keyPhaseBit     = 0x04
+	longPacketTypes = 0x30
+)
+
+// Long packet type bits, RFC 9000, Section 17.2.
+const (
+	longPacketTypeInitial   = 0x00 << 4
+	longPacketType0RTT      = 0x01 << 4
+	longPacketTypeHandshake = 0x02 << 4
+	longPacketTypeRetry     = 0x03 << 4
+)
+
+func isLongHeader(b byte) bool {
+	return b&headerFormLong != 0
+}
+
+// spaceForPacketType returns the number space of a packet type.
+func spaceForPacketType(p packetType) numberSpace {
+	switch p {
+	case packetTypeInitial:
+		return initialSpace
+	case packetTypeHandshake:
+		return handshakeSpace
+	}
+	return appDataSpace
+}
+
+// A packetHeader is the unprotected part of a packet header.
+type packetHeader struct {
+	ptype     packetType
+	version   uint32
+	dstConnID []byte
+	srcConnID []byte // long headers only
+	token     []byte // Initial packets only
+	pnumOff   int    // offset of the packet number
+	size      int    // size of the packet, including the header
+}
+
+// parsePacketHeader parses the header of the first packet in b,
+// which may contain several coalesced packets.
+// Short header packets are assumed to use connIDLen-byte connection IDs
+// and extend to the end of the datagram.
+func parsePacketHeader(b []byte) (h packetHeader, ok bool) {
+	if len(b) == 0 {
+		return h, false
+	}
+	if !isLongHeader(b[0]) {
+		if len(b) < 1+connIDLen {
+			return h, false
+		}
+		h.ptype = packetType1RTT
+		h.dstConnID = b[1:][:connIDLen]
+		h.pnumOff = 1 + connIDLen
+		h.size = len(b)
+		return h, true
+	}
+	if len(b) < 7 {
+		return h, false
+	}
+	h.version = binary.BigEndian.Uint32(b[1:5])
+	n := 5
+	dcidLen := int(b[n])
+	n++
+	if dcidLen > 20 || len(b) < n+dcidLen+1 {
+		return h, false
+	}
+	h.dstConnID = b[n:][:dcidLen]
+	n += dcidLen
+	scidLen := int(b[n])
+	n++
+	if scidLen > 20 || len(b) < n+scidLen {
+		return h, false
+	}
+	h.srcConnID = b[n:][:scidLen]
+	n += scidLen
+	if h.version == 0 {
+		h.ptype = packetTypeVersionNegotiation
+		h.size = len(b)
+		return h, true
+	}
+	if h.version != quicVersion1 {
+		// We can't parse the rest of packets of unknown versions.
+		h.size = len(b)
+		return h, true
+	}
+	switch b[0] & longPacketTypes {
+	case longPacketTypeInitial:
+		h.ptype = packetTypeInitial
+		token, tn := consumeVarintBytes(b[n:])
+		if tn < 0 {
+			return h, false
+		}
+		h.token = token
+		n += tn
+	case longPacketType0RTT:
+		h.ptype = packetType0RTT
+	case longPacketTypeHandshake:
+		h.ptype = packetTypeHandshake
+	case longPacketTypeRetry:
+		h.ptype = packetTypeRetry
+		h.size = len(b)
+		return h, true
+	}
+	length, ln := ConsumeVarint(b[n:])
+	if ln < 0 {
+		return h, false
+	}
+	n += ln
+	if length > uint64(len(b)-n) {
+		return h, false
+	}
+	h.pnumOff = n
+	h.size = n + int(length)
+	return h, true
+}
+
+// decodePacketNumber decodes a truncated packet number,
+// given the largest packet number received in its number space.
+// See RFC 9000, Section A.3.
+func decodePacketNumber(largest packetNumber, truncated uint64, pnumLen int) packetNumber {
+	expected := largest + 1
+	win := packetNumber(1) << (8 * pnumLen)
+	hwin := win / 2
+	mask := win - 1
+	candidate := (expected &^ mask) | packetNumber(truncated)
+	switch {
+	case candidate <= expected-hwin && candidate < (1<<62)-win:
+		return candidate + win
+	case candidate > expected+hwin && candidate >= win:
+		return candidate - win
+	}
+	return candidate
+}
+
+// packetNumberLength returns the number of bytes needed to encode pnum,
+// given the largest packet number acknowledged by the peer.
+// See RFC 9000, Section A.2.
+func packetNumberLength(pnum, largestAcked packetNumber) int {
+	numUnacked := pnum - largestAcked
+	switch {
+	case numUnacked < 1<<7:
+		return 1
+	case numUnacked < 1<<15:
+		return 2
+	case numUnacked < 1<<23:
+		return 3
+	}
+	return 4
+}
+
+func appendPacketNumber(b []byte, pnum packetNumber, pnumLen int) []byte {
+	for i := pnumLen - 1; i >= 0; i-- {
+		b = append(b, byte(pnum>>(8*i)))
+	}
+	return b
+}
+
+// A packetWriter assembles a datagram of one or more coalesced packets.
+//
+// Packets are protected when the datagram is finished,
+// so that the last packet in it may be padded.
+type packetWriter struct {
+	b       []byte
+	maxSize int
+	pkts    []pendingPacket
+
+	// The packet under construction.
+	cur        pendingPacket
+	inPacket   bool
+	ackElicits bool // the current packet contains an ack-eliciting frame
+}
+
+type pendingPacket struct {
+	keys    *packetKeys
+	ptype   packetType
+	start   int // offset of the packet in the datagram
+	lenOff  int // offset of the long header Length field
+	pnumOff int
+	pnumLen int
+	payOff  int
+	payEnd  int
+	pnum    packetNumber
+}
+
+// reset starts a new datagram of up to maxSize bytes.
+func (w *packetWriter) reset(maxSize int) {
+	if w.b == nil {
+		w.b = make([]byte, 0, maxReceiveDatagramSize)
+	}
+	w.b = w.b[:0]
+	w.maxSize = maxSize
+	w.pkts = w.pkts[:0]
+	w.inPacket = false
+}
+
+// datagramSize returns the size of the datagram so far,
+// including AEAD tags of the finished packets.
+func (w *packetWriter) datagramSize() int {
+	return len(w.b)
+}
+
+// startLongPacket starts a long header packet.
+// It reports false if there isn't room for a packet in the datagram.
+func (w *packetWriter) startLongPacket(ptype packetType, keys *packetKeys, dstConnID, srcConnID []byte, pnum, largestAcked packetNumber) bool {
+	pnumLen := packetNumberLength(pnum, largestAcked)
+	hdrSize := 1 + 4 + 1 + len(dstConnID) + 1 + len(srcConnID) + 2 + pnumLen
+	if ptype == packetTypeInitial {
+		hdrSize++ // empty token
+	}
+	if len(w.b)+hdrSize+4+aeadOverhead > w.maxSize {
+		return false
+	}
+	w.cur = pendingPacket{
+		keys:    keys,
+		ptype:   ptype,
+		start:   len(w.b),
+		pnum:    pnum,
+		pnumLen: pnumLen,
+	}
+	var typeBits byte
+	switch ptype {
+	case packetTypeInitial:
+		typeBits = longPacketTypeInitial
+	case packetTypeHandshake:
+		typeBits = longPacketTypeHandshake
+	}
+	w.b = append(w.b, headerFormLong|fixedBit|typeBits|byte(pnumLen-1))
+	w.b = append(w.b, 0, 0, 0, quicVersion1)
+	w.b = append(w.b, byte(len(dstConnID)))
+	w.b = append(w.b, dstConnID...)
+	w.b = append(w.b, byte(len(srcConnID)))
+	w.b = append(w.b, srcConnID...)
+	if ptype == packetTypeInitial {
+		w.b = append(w.b, 0) // Token Length
+	}
+	w.cur.lenOff = len(w.b)
+	w.b = append(w.b, 0x40, 0) // 2-byte Length, filled in later
+	w.cur.pnumOff = len(w.b)
+	w.b = appendPacketNumber(w.b, pnum, pnumLen)
+	w.cur.payOff = len(w.b)
+	w.inPacket = true
+	w.ackElicits = false
+	return true
+}
+
+// start1RTTPacket starts a short header packet.
+// It reports false if there isn't room for a packet in the datagram.
+func (w *packetWriter) start1RTTPacket(keys *packetKeys, keyPhase bool, dstConnID []byte, pnum, largestAcked packetNumber) bool {
+	pnumLen := packetNumberLength(pnum, largestAcked)
+	if len(w.b)+1+len(dstConnID)+pnumLen+4+aeadOverhead > w.maxSize {
+		return false
+	}
+	w.cur = pendingPacket{
+		keys:    keys,
+		ptype:   packetType1RTT,
+		start:   len(w.b),
+		lenOff:  -1,
+		pnum:    pnum,
+		pnumLen: pnumLen,
+	}
+	first := byte(fixedBit | byte(pnumLen-1))
+	if keyPhase {
+		first |= keyPhaseBit
+	}
+	w.b = append(w.b, first)
+	w.b = append(w.b, dstConnID...)
+	w.cur.pnumOff = len(w.b)
+	w.b = appendPacketNumber(w.b, pnum, pnumLen)
+	w.cur.payOff = len(w.b)
+	w.inPacket = true
+	w.ackElicits = false
+	return true
+}
+
+// avail returns the number of bytes of payload which may be added to
+// the current packet.
+func (w *packetWriter) avail() int {
+	n := w.maxSize - len(w.b) - aeadOverhead
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// payloadSize returns the size of the payload of the current packet.
+func (w *packetWriter) payloadSize() int {
+	return len(w.b) - w.cur.payOff
+}
+
+// abandonPacket discards the current packet.
+func (w *packetWriter) abandonPacket() {
+	w.b = w.b[:w.cur.start]
+	w.inPacket = false
+}
+
+// finishPacket completes the current packet.
+// It returns the size of the packet, or 0 if the packet was empty
+// and has been discarded.
+func (w *packetWriter) finishPacket() int {
+	if !w.inPacket {
+		return 0
+	}
+	w.inPacket = false
+	if w.payloadSize() == 0 {
+		w.abandonPacket()
+		return 0
+	}
+	// The header protection sample starts 4 bytes after the start of the
+	// packet number, and the packet must be long enough to contain it.
+	for len(w.b)-w.cur.pnumOff < 4 {
+		w.b = append(w.b, 0) // PADDING
+	}
+	w.cur.payEnd = len(w.b)
+	w.b = append(w.b, make([]byte, aeadOverhead)...) // room for the AEAD tag
+	w.pkts = append(w.pkts, w.cur)
+	return len(w.b) - w.cur.start
+}
+
+// finishDatagram pads the last packet in the datagram so that the datagram
+// is at least padTo bytes long, protects the packets,
+// and returns the datagram.
+// It returns nil if the datagram contains no packets.
+func (w *packetWriter) finishDatagram(padTo int) []byte {
+	if len(w.pkts) == 0 {
+		return nil
+	}
+	if padTo > w.maxSize {
+		padTo = w.maxSize
+	}
+	if len(w.b) < padTo {
+		last := &w.pkts[len(w.pkts)-1]
+		w.b = w.b[:last.payEnd]
+		for len(w.b)+aeadOverhead < padTo {
+			w.b = append(w.b, 0) // PADDING
+		}
+		last.payEnd = len(w.b)
+		w.b = append(w.b, make([]byte, aeadOverhead)...)
+	}
+	for _, p := range w.pkts {
+		if p.lenOff >= 0 {
+			length := p.payEnd + aeadOverhead - p.pnumOff
+			w.b[p.lenOff] = 0x40 | byte(length>>8)
+			w.b[p.lenOff+1] = byte(length)
+		}
+		p.keys.protect(w.b[p.start:p.payEnd], p.pnumOff-p.start, p.pnumLen, p.pnum)
+	}
+	return w.b
+}
+
+// lastPacketSize returns the size of the last packet of the datagram
+// when it is padded to padTo bytes.
+func (w *packetWriter) lastPacketSize(padTo int) int {
+	last := w.pkts[len(w.pkts)-1]
+	size := last.payEnd + aeadOverhead - last.start
+	if len(w.b) < padTo {
+		size += padTo - len(w.b)
+	}
+	return size
+}
+
+// appendVersionNegotiation appends a Version Negotiation packet
+// (RFC 9000, Section 17.2.1) listing the versions we support.
+func appendVersionNegotiation(b, dstConnID, srcConnID []byte) []byte {
+	b = append(b, headerFormLong|fixedBit)
+	b = append(b, 0, 0, 0, 0) // Version 0
+	b = append(b, byte(len(dstConnID)))
+	b = append(b, dstConnID...)
+	b = append(b, byte(len(srcConnID)))
+	b = append(b, srcConnID...)
+	return append(b, 0, 0, 0, quicVersion1)
+}
@@ -0,0 +1,230 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"hash"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Packet protection is described in RFC 9001, Section 5.
+
+var errInvalidPacket = errors.New("quic: invalid packet")
+
+// headerProtectionSampleSize is the size of the ciphertext sample
+// used for header protection (RFC 9001, Section 5.4.2).
+const headerProtectionSampleSize = 16
+
+// aeadOverhead is the size of the authentication tag of all the AEADs used by QUIC.
+const aeadOverhead = 16
+
+// initialSalt is the salt for deriving Initial secrets (RFC 9001, Section 5.2).
+var initialSalt = []byte{
+	0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17,
+	0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a,
+}
+
+// initialKeys returns the keys protecting Initial packets
+// sent by the client and the server, derived from the
+// Destination Connection ID of the client's first Initial packet.
+func initialKeys(cid []byte) (client, server packetKeys) {
+	initialSecret := hkdf.Extract(sha256.New, cid, initialSalt)
+	clientSecret := hkdfExpandLabel(sha256.New, initialSecret, "client in", nil, sha256.Size)
+	serverSecret := hkdfExpandLabel(sha256.New, initialSecret, "server in", nil, sha256.Size)
+	return newPacketKeys(tls.TLS_AES_128_GCM_SHA256, clientSecret),
+		newPacketKeys(tls.TLS_AES_128_GCM_SHA256, serverSecret)
+}
+
+// hkdfExpandLabel implements HKDF-Expand-Label from RFC 8446, Section 7.1.
+func hkdfExpandLabel(hash func() hash.Hash, secret []byte, label string, context []byte, length int) []byte {
+	var info []byte
+	info = append(info, byte(length>>8), byte(length))
+	info = append(info, byte(len("tls13 ")+len(label)))
+	info = append(info, "tls13 "...)
+	info = append(info, label...)
+	info = append(info, byte(len(context)))
+	info = append(info, context...)
+	out := make([]byte, length)
+	if _, err := hkdf.Expand(hash, secret, info).Read(out); err != nil {
+		panic("quic: HKDF-Expand-Label invocation failed unexpectedly")
+	}
+	return out
+}
+
+// suiteParams returns the hash and AEAD key length of a TLS 1.3 cipher suite.
+func suiteParams(suite uint16) (h crypto.Hash, keyLen int) {
+	switch suite {
+	case tls.TLS_AES_128_GCM_SHA256:
+		return crypto.SHA256, 16
+	case tls.TLS_AES_256_GCM_SHA384:
+		return crypto.SHA384, 32
+	case tls.TLS_CHACHA20_POLY1305_SHA256:
+		return crypto.SHA256, chacha20poly1305.KeySize
+	}
+	panic("quic: unsupported cipher suite")
+}
+
+// packetKeys are the keys protecting packets sent in one direction
+// at one encryption level.
+type packetKeys struct {
+	suite  uint16
+	secret []byte // kept to derive updated keys
+	aead   cipher.AEAD
+	iv     []byte
+	hp     headerProtection
+}
+
+func newPacketKeys(suite uint16, secret []byte) packetKeys {
+	h, keyLen := suiteParams(suite)
+	k := packetKeys{
+		suite:  suite,
+		secret: secret,
+		hp:     newHeaderProtection(suite, hkdfExpandLabel(h.New, secret, "quic hp", nil, keyLen)),
+	}
+	k.setAEAD()
+	return k
+}
+
+func (k *packetKeys) setAEAD() {
+	h, keyLen := suiteParams(k.suite)
+	key := hkdfExpandLabel(h.New, k.secret, "quic key", nil, keyLen)
+	k.iv = hkdfExpandLabel(h.New, k.secret, "quic iv", nil, 12)
+	var err error
+	if k.suite == tls.TLS_CHACHA20_POLY1305_SHA256 {
+		k.aead, err = chacha20poly1305.New(key)
+	} else {
+		var block cipher.Block
+		if block, err = aes.NewCipher(key); err == nil {
+			k.aead, err = cipher.NewGCM(block)
+		}
+	}
+	if err != nil {
+		panic("quic: " + err.Error())
+	}
+}
+
+// isSet reports whether the keys have been installed.
+func (k *packetKeys) isSet() bool {
+	return k.aead != nil
+}
+
+// next returns the keys of the next key phase (RFC 9001, Section 6).
+// Header protection keys are not updated.
+func (k *packetKeys) next() packetKeys {
+	h, _ := suiteParams(k.suite)
+	n := packetKeys{
+		suite:  k.suite,
+		secret: hkdfExpandLabel(h.New, k.secret, "quic ku", nil, h.Size()),
+		hp:     k.hp,
+	}
+	n.setAEAD()
+	return n
+}
+
+func (k *packetKeys) nonce(pnum packetNumber) []byte {
+	nonce := make([]byte, len(k.iv))
+	copy(nonce, k.iv)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(pnum >> (8 * i))
+	}
+	return nonce
+}
+
+// protect encrypts the payload of the packet in b and applies header protection.
+// The packet header is b[:pnumOff+pnumLen], and everything after it is the payload.
+// Space must be available at the end of b for the AEAD tag; it returns the
+// packet including the tag.
+func (k *packetKeys) protect(b []byte, pnumOff, pnumLen int, pnum packetNumber) []byte {
+	hdr := b[:pnumOff+pnumLen]
+	payload := b[len(hdr):]
+	b = k.aead.Seal(hdr, k.nonce(pnum), payload, hdr)
+	sample := b[pnumOff+4:][:headerProtectionSampleSize]
+	mask := k.hp.mask(sample)
+	if isLongHeader(b[0]) {
+		b[0] ^= mask[0] & 0x0f
+	} else {
+		b[0] ^= mask[0] & 0x1f
+	}
+	for i := 0; i < pnumLen; i++ {
+		b[pnumOff+i] ^= mask[1+i]
+	}
+	return b
+}
+
+// unprotectHeader removes header protection from the packet in b, in place.
+// It returns the packet number, decoded relative to the largest packet number
+// received in this number space, and its encoded length.
+func (k *packetKeys) unprotectHeader(b []byte, pnumOff int, pnumMax packetNumber) (pnum packetNumber, pnumLen int, err error) {
+	if len(b) < pnumOff+4+headerProtectionSampleSize {
+		return 0, 0, errInvalidPacket
+	}
+	mask := k.hp.mask(b[pnumOff+4:][:headerProtectionSampleSize])
+	if isLongHeader(b[0]) {
+		b[0] ^= mask[0] & 0x0f
+	} else {
+		b[0] ^= mask[0] & 0x1f
+	}
+	pnumLen = int(b[0]&0x03) + 1
+	var truncated uint64
+	for i := 0; i < pnumLen; i++ {
+		b[pnumOff+i] ^= mask[1+i]
+		truncated = truncated<<8 | uint64(b[pnumOff+i])
+	}
+	return decodePacketNumber(pnumMax, truncated, pnumLen), pnumLen, nil
+}
+
+// open decrypts the payload of a packet with its header already unprotected.
+// The payload is decrypted in place.
+func (k *packetKeys) open(b []byte, hdrLen int, pnum packetNumber) ([]byte, error) {
+	hdr, ciphertext := b[:hdrLen], b[hdrLen:]
+	payload, err := k.aead.Open(ciphertext[:0], k.nonce(pnum), ciphertext, hdr)
+	if err != nil {
+		return nil, errInvalidPacket
+	}
+	return payload, nil
+}
+
+// headerProtection computes header protection masks (RFC 9001, Section 5.4).
+type headerProtection struct {
+	block     cipher.Block // for AES-based suites
+	chachaKey []byte       // for ChaCha20
+}
+
+func newHeaderProtection(suite uint16, key []byte) headerProtection {
+	if suite == tls.TLS_CHACHA20_POLY1305_SHA256 {
+		return headerProtection{chachaKey: key}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic("quic: " + err.Error())
+	}
+	return headerProtection{block: block}
+}
+
+func (hp headerProtection) mask(sample []byte) (mask [5]byte) {
+	if hp.block != nil {
+		var out [aes.BlockSize]byte
+		hp.block.Encrypt(out[:], sample)
+		copy(mask[:], out[:])
+		return mask
+	}
+	c, err := chacha20.NewUnauthenticatedCipher(hp.chachaKey, sample[4:16])
+	if err != nil {
+		panic("quic: " + err.Error())
+	}
+	c.SetCounter(binary.LittleEndian.Uint32(sample[:4]))
+	c.XORKeyStream(mask[:], mask[:])
+	return mask
+}
@@ -0,0 +1,137 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Test vectors from RFC 9001, Appendix A.
+
+func TestInitialKeys(t *testing.T) {
+	client, server := initialKeys(unhex("8394c8f03e515708"))
+	for _, test := range []struct {
+		name   string
+		keys   packetKeys
+		secret string
+		iv     string
+	}{{
+		name:   "client",
+		keys:   client,
+		secret: "c00cf151ca5be075ed0ebfb5c80323c4 2d6b7db67881289af4008f1f6c357aea",
+		iv:     "fa044b2f42a3fd3b46fb255c",
+	}, {
+		name:   "server",
+		keys:   server,
+		secret: "3c199828fd139efd216c155ad844cc81 fb82fa8d7446fa7d78be803acdda951b",
+		iv:     "0ac1493ca1905853b0bba03e",
+	}} {
+		if got, want := test.keys.secret, unhex(test.secret); !bytes.Equal(got, want) {
+			t.Errorf("%v initial secret = %x, want %x", test.name, got, want)
+		}
+		if got, want := test.keys.iv, unhex(test.iv); !bytes.Equal(got, want) {
+			t.Errorf("%v IV = %x, want %x", test.name, got, want)
+		}
+	}
+}
+
+func TestServerInitialPacket(t *testing.T) {
+	// RFC 9001, Appendix A.3.
+	_, server := initialKeys(unhex("8394c8f03e515708"))
+	hdr := unhex("c1000000010008f067a5502a4262b50040750001")
+	payload := unhex(`
+		02000000000600405a020000560303ee fce7f7b37ba1d1632e96677825ddf739
+		88cfc79825df566dc5430b9a045a1200 130100002e00330024001d00209d3c94
+		0d89690b84d08a60993c144eca684d10 81287c834d5311bcf32bb9da1a002b00
+		020304`)
+	want := unhex(`
+		cf000000010008f067a5502a4262b500 4075c0d95a482cd0991cd25b0aac406a
+		5816b6394100f37a1c69797554780bb3 8cc5a99f5ede4cf73c3ec2493a1839b3
+		dbcba3f6ea46c5b7684df3548e7ddeb9 c3bf9c73cc3f3bded74b562bfb19fb84
+		022f8ef4cdd93795d77d06edbb7aaf2f 58891850abbdca3d20398c276456cbc4
+		2158407dd074ee`)
+	b := append(hdr, payload...)
+	b = append(b, make([]byte, aeadOverhead)...)[:len(b)]
+	pnumOff := len(hdr) - 2
+	got := server.protect(b, pnumOff, 2, 1)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("protected packet:\n got %x\nwant %x", got, want)
+	}
+
+	pnum, pnumLen, err := server.unprotectHeader(got, pnumOff, 0)
+	if err != nil || pnum != 1 || pnumLen != 2 {
+		t.Fatalf("unprotectHeader = %v, %v, %v; want 1, 2, nil", pnum, pnumLen, err)
+	}
+	opened, err := server.open(got, pnumOff+pnumLen, pnum)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if !bytes.Equal(opened, payload) {
+		t.Fatalf("opened payload:\n got %x\nwant %x", opened, payload)
+	}
+}
+
+func TestChaCha20ShortHeaderPacket(t *testing.T) {
+	// RFC 9001, Appendix A.5.
+	keys := newPacketKeys(tls.TLS_CHACHA20_POLY1305_SHA256,
+		unhex("9ac312a7f877468ebe69422748ad00a1 5443f18203a07d6060f688f30f21632b"))
+	const pnum = 654360564
+	b := unhex("4200bff401")
+	b = append(b, make([]byte, aeadOverhead)...)[:len(b)]
+	got := keys.protect(b, 1, 3, pnum)
+	want := unhex("4cfe4189655e5cd55c41f69080575d7999c25a5bfb")
+	if !bytes.Equal(got, want) {
+		t.Fatalf("protected packet:\n got %x\nwant %x", got, want)
+	}
+	gotPnum, pnumLen, err := keys.unprotectHeader(got, 1, pnum-1)
+	if err != nil || gotPnum != pnum || pnumLen != 3 {
+		t.Fatalf("unprotectHeader = %v, %v, %v; want %v, 3, nil", gotPnum, pnumLen, err, pnum)
+	}
+	if opened, err := keys.open(got, 4, pnum); err != nil || !bytes.Equal(opened, []byte{0x01}) {
+		t.Fatalf("open = %x, %v; want 01", opened, err)
+	}
+
+	// Key updates (RFC 9001, Section 6) derive new packet keys,
+	// but keep the header protection key.
+	next := keys.next()
+	if bytes.Equal(next.iv, keys.iv) {
+		t.Errorf("next keys have the same IV")
+	}
+	sample := make([]byte, headerProtectionSampleSize)
+	if next.hp.mask(sample) != keys.hp.mask(sample) {
+		t.Errorf("next keys have a different header protection key")
+	}
+}
+
+func TestDecodePacketNumber(t *testing.T) {
+	// RFC 9000, Appendix A.3.
+	if got := decodePacketNumber(0xa82f30ea, 0x9b32, 2); got != 0xa82f9b32 {
+		t.Errorf("decodePacketNumber = %x, want a82f9b32", got)
+	}
+	for _, pnum := range []packetNumber{0, 1, 0xff, 0x1234, 0xabcdef, 1 << 40} {
+		for _, largestAcked := range []packetNumber{-1, pnum - 1, pnum - 1000} {
+			if largestAcked >= pnum {
+				continue
+			}
+			n := packetNumberLength(pnum, largestAcked)
+			truncated := uint64(pnum) & (1<<(8*n) - 1)
+			if got := decodePacketNumber(pnum-1, truncated, n); got != pnum {
+				t.Errorf("decodePacketNumber(%v, %x, %v) = %v, want %v", pnum-1, truncated, n, got, pnum)
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package quic implements the QUIC transport protocol,
+// as described in RFC 9000 and RFC 9001, for use by net/http.
+//
+// Only the parts of QUIC needed to carry HTTP/3 are implemented.
+// Notably, there is no support for 0-RTT data, Retry packets,
+// stateless resets, connection migration, or version negotiation
+// beyond rejecting versions other than QUIC version 1.
+package quic
+
+import (
+	"errors"
+	"time"
+)
+
+// quicVersion1 is QUIC version 1, RFC 9000.
+const quicVersion1 = 0x00000001
+
+// connIDLen is the length of the connection IDs this package issues.
+// All locally-issued connection IDs have the same length, which lets
+// an Endpoint route short header packets without knowing their connection.
+const connIDLen = 8
+
+// Limits on the size of UDP datagrams.
+const (
+	// minimumClientInitialDatagramSize is the size to which datagrams
+	// containing a client Initial packet are padded (RFC 9000, Section 14.1).
+	minimumClientInitialDatagramSize = 1200
+
+	// maxDatagramSize is the size of the datagrams we send.
+	// Path MTU discovery is not implemented, so this is also
+	// the smallest size every QUIC path is required to support.
+	maxDatagramSize = 1200
+
+	// maxReceiveDatagramSize is the size of the buffer datagrams are read into.
+	maxReceiveDatagramSize = 1500
+)
+
+// Default values of the parameters in Config.
+const (
+	defaultMaxIdleTimeout        = 30 * time.Second
+	defaultHandshakeTimeout      = 10 * time.Second
+	defaultMaxBidiRemoteStreams  = 100
+	defaultMaxUniRemoteStreams   = 10
+	defaultMaxStreamReadBuffer   = 1 << 20
+	defaultMaxConnReadBuffer     = 16 << 20
+	defaultMaxAckDelay           = 25 * time.Millisecond
+	defaultAckDelayExponent      = 3
+	defaultActiveConnIDLimit     = 2
+	maxPeerAckDelay              = 1<<14*time.Millisecond - 1
+	defaultPeerMaxUDPPayloadSize = 65527
+)
+
+// A Side distinguishes the client and server endpoints of a connection.
+type Side int
+
+const (
+	ClientSide = Side(iota)
+	ServerSide
+)
+
+func (s Side) String() string {
+	if s == ServerSide {
+		return "server"
+	}
+	return "client"
+}
+
+// A numberSpace is a packet number space (RFC 9000, Section 12.3).
+type numberSpace int
+
+const (
+	initialSpace = numberSpace(iota)
+	handshakeSpace
+	appDataSpace
+	numberSpaceCount
+)
+
+func (s numberSpace) String() string {
+	switch s {
+	case initialSpace:
+		return "Initial"
+	case handshakeSpace:
+		return "Handshake"
+	default:
+		return "Application"
+	}
+}
+
+// ErrClosed is returned by operations on a closed Endpoint or Conn.
+var ErrClosed = errors.New("quic: closed")
+
+// errIdleTimeout is the error of a connection closed by its idle timeout.
+var errIdleTimeout = errors.New("quic: idle timeout")
+
+// errHandshakeTimeout is the error of a connection whose handshake did not
+// complete within Config.HandshakeTimeout.
+var errHandshakeTimeout = errors.New("quic: handshake timeout")
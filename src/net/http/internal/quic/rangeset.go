@@ -0,0 +1,144 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+// A rangeset is a set of int64s, stored as an ordered list of
+// non-overlapping, non-adjacent ranges.
+//
+// Rangesets are used to track received packet numbers,
+// and the parts of stream data which have been sent or received.
+type rangeset []i64range
+
+// An i64range is the range [start, end).
+type i64range struct {
+	start, end int64
+}
+
+func (r i64range) size() int64 {
+	return r.end - r.start
+}
+
+func (r i64range) contains(v int64) bool {
+	return r.start <= v && v < r.end
+}
+
+// add adds [start, end) to the set.
+func (s *rangeset) add(start, end int64) {
+	if start >= end {
+		return
+	}
+	ranges := *s
+	// Find the first range which ends at or after start.
+	i := 0
+	for i < len(ranges) && ranges[i].end < start {
+		i++
+	}
+	// Find the first range which starts after end.
+	j := i
+	for j < len(ranges) && ranges[j].start <= end {
+		j++
+	}
+	if i == j {
+		// No overlap: insert a new range.
+		ranges = append(ranges, i64range{})
+		copy(ranges[i+1:], ranges[i:])
+		ranges[i] = i64range{start, end}
+		*s = ranges
+		return
+	}
+	// Merge ranges[i:j] with [start, end).
+	if ranges[i].start < start {
+		start = ranges[i].start
+	}
+	if ranges[j-1].end > end {
+		end = ranges[j-1].end
+	}
+	ranges[i] = i64range{start, end}
+	*s = append(ranges[:i+1], ranges[j:]...)
+}
+
+// sub removes [start, end) from the set.
+func (s *rangeset) sub(start, end int64) {
+	if start >= end {
+		return
+	}
+	var out rangeset
+	for _, r := range *s {
+		if r.end <= start || r.start >= end {
+			out = append(out, r)
+			continue
+		}
+		if r.start < start {
+			out = append(out, i64range{r.start, start})
+		}
+		if r.end > end {
+			out = append(out, i64range{end, r.end})
+		}
+	}
+	*s = out
+}
+
+// contains reports whether v is in the set.
+func (s rangeset) contains(v int64) bool {
+	for _, r := range s {
+		if r.contains(v) {
+			return true
+		}
+		if r.start > v {
+			break
+		}
+	}
+	return false
+}
+
+// rangeContaining returns the range containing v, or the empty range.
+func (s rangeset) rangeContaining(v int64) i64range {
+	for _, r := range s {
+		if r.contains(v) {
+			return r
+		}
+	}
+	return i64range{}
+}
+
+// min returns the smallest value in the set, or 0 if it is empty.
+func (s rangeset) min() int64 {
+	if len(s) == 0 {
+		return 0
+	}
+	return s[0].start
+}
+
+// max returns the largest value in the set, or -1 if it is empty.
+func (s rangeset) max() int64 {
+	if len(s) == 0 {
+		return -1
+	}
+	return s[len(s)-1].end - 1
+}
+
+// end returns the end of the last range in the set, or 0 if it is empty.
+func (s rangeset) end() int64 {
+	if len(s) == 0 {
+		return 0
+	}
+	return s[len(s)-1].end
+}
+
+// isrange reports whether the set is exactly the range [start, end).
+func (s rangeset) isrange(start, end int64) bool {
+	switch len(s) {
+	case 0:
+		return start == end
+	case 1:
+		return s[0].start == start && s[0].end == end
+	}
+	return false
+}
+
+// removeBefore removes all values less than v from the set.
+func (s *rangeset) removeBefore(v int64) {
+	s.sub(s.min(), v)
+}
@@ -0,0 +1,189 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "time"
+
+// Loss detection and congestion control, as described in RFC 9002.
+
+const (
+	// packetThreshold is the reordering threshold at which a packet is
+	// declared lost (RFC 9002, Section 6.1.1).
+	packetThreshold = 3
+
+	// timeThreshold is the reordering threshold, in units of RTT
+	// (RFC 9002, Section 6.1.2).
+	timeThresholdNum, timeThresholdDen = 9, 8
+
+	// granularity is the timer granularity (RFC 9002, Section 6.1.2).
+	granularity = 1 * time.Millisecond
+
+	// initialRTT is the RTT assumed before a sample is taken
+	// (RFC 9002, Section 6.2.2).
+	initialRTT = 333 * time.Millisecond
+
+	// maxPTOBackoff bounds the exponential PTO backoff.
+	maxPTOBackoff = 10
+)
+
+// A sentFrame records a frame carried by a sent packet which needs to be
+// acted on when the packet is acknowledged or lost.
+type sentFrame struct {
+	kind byte // frame type; for STREAM frames, frameTypeStreamBase
+	id   streamID
+	off  int64
+	n    int
+	fin  bool
+}
+
+// A sentPacket is a packet which has been sent and not yet
+// acknowledged or declared lost.
+type sentPacket struct {
+	pnum         packetNumber
+	time         time.Time
+	size         int
+	ackEliciting bool
+	inFlight     bool
+	frames       []sentFrame
+}
+
+// rttState is the RTT estimate (RFC 9002, Section 5).
+type rttState struct {
+	latest   time.Duration
+	min      time.Duration
+	smoothed time.Duration
+	variance time.Duration
+	sampled  bool
+}
+
+func (r *rttState) init() {
+	r.smoothed = initialRTT
+	r.variance = initialRTT / 2
+}
+
+// update updates the estimate from an RTT sample.
+func (r *rttState) update(latest, ackDelay, maxAckDelay time.Duration, handshakeConfirmed bool) {
+	r.latest = latest
+	if !r.sampled {
+		r.sampled = true
+		r.min = latest
+		r.smoothed = latest
+		r.variance = latest / 2
+		return
+	}
+	if latest < r.min {
+		r.min = latest
+	}
+	if handshakeConfirmed && ackDelay > maxAckDelay {
+		ackDelay = maxAckDelay
+	}
+	adjusted := latest
+	if latest >= r.min+ackDelay {
+		adjusted = latest - ackDelay
+	}
+	diff := r.smoothed - adjusted
+	if diff < 0 {
+		diff = -diff
+	}
+	r.variance = (3*r.variance + diff) / 4
+	r.smoothed = (7*r.smoothed + adjusted) / 8
+}
+
+// pto returns the probe timeout duration, without backoff
+// (RFC 9002, Section 6.2.1).
+func (r *rttState) pto(maxAckDelay time.Duration) time.Duration {
+	v := 4 * r.variance
+	if v < granularity {
+		v = granularity
+	}
+	return r.smoothed + v + maxAckDelay
+}
+
+// lossDelay returns the time threshold for declaring a packet lost.
+func (r *rttState) lossDelay() time.Duration {
+	d := r.smoothed
+	if r.latest > d {
+		d = r.latest
+	}
+	d = d * timeThresholdNum / timeThresholdDen
+	if d < granularity {
+		d = granularity
+	}
+	return d
+}
+
+// congestion is a NewReno congestion controller
+// (RFC 9002, Section 7).
+type congestion struct {
+	window        int
+	ssthresh      int
+	bytesInFlight int
+	recoveryStart time.Time // start of the current recovery period
+}
+
+const (
+	minCongestionWindow = 2 * maxDatagramSize
+	lossReductionFactor = 2
+)
+
+func (cc *congestion) init() {
+	cc.window = 10 * maxDatagramSize
+	if cc.window > 14720 {
+		cc.window = 14720
+	}
+	cc.ssthresh = 1<<31 - 1
+}
+
+// canSend reports whether a packet of the given size may be sent.
+func (cc *congestion) canSend(size int) bool {
+	return cc.bytesInFlight+size <= cc.window
+}
+
+func (cc *congestion) onSent(p *sentPacket) {
+	if p.inFlight {
+		cc.bytesInFlight += p.size
+	}
+}
+
+// onAcked updates the window for an acknowledged packet.
+func (cc *congestion) onAcked(p *sentPacket) {
+	if !p.inFlight {
+		return
+	}
+	cc.bytesInFlight -= p.size
+	if !p.time.After(cc.recoveryStart) {
+		return // in a recovery period
+	}
+	if cc.window < cc.ssthresh {
+		cc.window += p.size // slow start
+	} else {
+		cc.window += maxDatagramSize * p.size / cc.window
+	}
+}
+
+// onLost updates the window for a lost packet.
+func (cc *congestion) onLost(p *sentPacket, now time.Time) {
+	if !p.inFlight {
+		return
+	}
+	cc.bytesInFlight -= p.size
+	if !p.time.After(cc.recoveryStart) {
+		return // already in recovery for this loss
+	}
+	cc.recoveryStart = now
+	cc.ssthresh = cc.window / lossReductionFactor
+	if cc.ssthresh < minCongestionWindow {
+		cc.ssthresh = minCongestionWindow
+	}
+	cc.window = cc.ssthresh
+}
+
+// onDiscarded removes a packet from the bytes in flight without
+// affecting the window, when its number space is discarded.
+func (cc *congestion) onDiscarded(p *sentPacket) {
+	if p.inFlight {
+		cc.bytesInFlight -= p.size
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// A streamID is a QUIC stream ID (RFC 9000, Section 2.1).
+type streamID uint64
+
+// A streamType distinguishes bidirectional and unidirectional streams.
+type streamType int
+
+const (
+	bidiStream = streamType(0)
+	uniStream  = streamType(1)
+)
+
+func newStreamID(initiator Side, typ streamType, num int64) streamID {
+	id := streamID(num << 2)
+	if initiator == ServerSide {
+		id |= 0x1
+	}
+	if typ == uniStream {
+		id |= 0x2
+	}
+	return id
+}
+
+func (id streamID) initiator() Side {
+	if id&0x1 != 0 {
+		return ServerSide
+	}
+	return ClientSide
+}
+
+func (id streamID) streamType() streamType {
+	if id&0x2 != 0 {
+		return uniStream
+	}
+	return bidiStream
+}
+
+func (id streamID) num() int64 {
+	return int64(id >> 2)
+}
+
+// resetState is the state of a RESET_STREAM frame.
+type resetState int
+
+const (
+	resetNone = resetState(iota)
+	resetToSend
+	resetSent
+	resetAcked
+)
+
+// A Stream is an ordered byte stream within a Conn.
+//
+// Bidirectional streams may be both read from and written to.
+// Unidirectional streams may only be written to by the side which
+// opened them, and read from by the other side.
+//
+// Methods of a Stream may be called concurrently with each other,
+// but Read and Write calls must not be made concurrently with
+// other calls to the same method.
+type Stream struct {
+	id   streamID
+	conn *Conn
+
+	// Fields below are guarded by conn.mu.
+
+	changed       chan struct{} // closed and replaced when the stream state changes
+	readDeadline  time.Time
+	writeDeadline time.Time
+
+	// Receive side.
+	recv                recvBuffer
+	recvMax             int64  // MAX_STREAM_DATA sent to the peer
+	recvEnd             int64  // largest offset received
+	recvWindow          int64  // flow control window size
+	finalSize           int64  // final size of the stream, or -1 if unknown
+	recvErr             error  // the peer reset the stream
+	readClosed          bool   // CloseRead was called
+	stopSendingCode     uint64 // error code of the STOP_SENDING frame
+	stopSendingToSend   bool   // a STOP_SENDING frame needs to be sent
+	maxStreamDataToSend bool   // a MAX_STREAM_DATA frame needs to be sent
+	recvDone            bool   // no more frames are expected for the receive side
+
+	// Send side.
+	send        sendBuffer
+	sendMax     int64 // MAX_STREAM_DATA received from the peer
+	sentMax     int64 // largest offset sent so far
+	writeClosed bool  // CloseWrite was called; the final size is send.end()
+	finSent     bool  // the FIN bit has been sent and not lost
+	finAcked    bool
+	resetCode   uint64
+	reset       resetState
+	sendErr     error // the peer asked us to stop sending, or we reset the stream
+	sendDone    bool  // no more frames need to be sent on the send side
+
+	queued bool // the stream is in conn.sendq
+}
+
+func newStream(c *Conn, id streamID) *Stream {
+	s := &Stream{
+		id:        id,
+		conn:      c,
+		changed:   make(chan struct{}),
+		finalSize: -1,
+	}
+	local := id.initiator() == c.side
+	if id.streamType() == uniStream {
+		if local {
+			s.recvDone = true
+		} else {
+			s.sendDone = true
+		}
+	}
+	if !s.recvDone {
+		s.recvWindow = c.config.maxStreamReadBufferSize()
+		s.recvMax = s.recvWindow
+	}
+	if !s.sendDone {
+		switch {
+		case id.streamType() == uniStream:
+			s.sendMax = c.peerParams.initialMaxStreamDataUni
+		case local:
+			s.sendMax = c.peerParams.initialMaxStreamDataBidiRemote
+		default:
+			s.sendMax = c.peerParams.initialMaxStreamDataBidiLocal
+		}
+	}
+	return s
+}
+
+// ID returns the stream ID.
+func (s *Stream) ID() int64 {
+	return int64(s.id)
+}
+
+// notify wakes goroutines waiting for a change in the stream's state.
+// The conn mutex must be held.
+func (s *Stream) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// wait waits for the stream's state to change or for the deadline to expire.
+// The conn mutex must be held; it is released while waiting.
+func (s *Stream) wait(deadline time.Time) error {
+	ch := s.changed
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		d := time.Until(deadline)
+		if d <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+	s.conn.mu.Unlock()
+	defer s.conn.mu.Lock()
+	select {
+	case <-ch:
+		return nil
+	case <-timeout:
+		return os.ErrDeadlineExceeded
+	}
+}
+
+var (
+	errReadClosed  = errors.New("quic: read from stream after CloseRead")
+	errWriteClosed = errors.New("quic: write to closed stream")
+	errWrongSide   = errors.New("quic: operation not permitted on unidirectional stream")
+)
+
+// Read reads data from the stream.
+// It returns io.EOF when the peer has closed its side of the stream,
+// and a *StreamError if the peer reset the stream.
+func (s *Stream) Read(b []byte) (int, error) {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s.id.streamType() == uniStream && s.id.initiator() == c.side {
+		return 0, errWrongSide
+	}
+	for {
+		if s.readClosed {
+			return 0, errReadClosed
+		}
+		if s.recvErr != nil {
+			return 0, s.recvErr
+		}
+		if s.recv.readable() > 0 {
+			if len(b) == 0 {
+				return 0, nil
+			}
+			n := s.recv.read(b)
+			c.streamConsumed(s, n)
+			return n, nil
+		}
+		if s.finalSize >= 0 && s.recv.readOff == s.finalSize {
+			c.setRecvDone(s)
+			return 0, io.EOF
+		}
+		if c.err != nil {
+			return 0, c.err
+		}
+		if err := s.wait(s.readDeadline); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Write writes data to the stream.
+// It blocks while the amount of unacknowledged data written to the stream
+// exceeds the stream's write buffer size.
+func (s *Stream) Write(b []byte) (int, error) {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s.id.streamType() == uniStream && s.id.initiator() != c.side {
+		return 0, errWrongSide
+	}
+	n := 0
+	for {
+		if s.sendErr != nil {
+			return n, s.sendErr
+		}
+		if s.writeClosed {
+			return n, errWriteClosed
+		}
+		if c.err != nil {
+			return n, c.err
+		}
+		if len(b) == 0 {
+			return n, nil
+		}
+		avail := int(c.config.maxStreamWriteBufferSize()) - s.send.unacked()
+		if avail > 0 {
+			if avail > len(b) {
+				avail = len(b)
+			}
+			s.send.write(b[:avail])
+			b = b[avail:]
+			n += avail
+			c.queueStream(s)
+			continue
+		}
+		if err := s.wait(s.writeDeadline); err != nil {
+			return n, err
+		}
+	}
+}
+
+// CloseWrite closes the write side of the stream, sending a FIN to the peer.
+// Data written before CloseWrite continues to be delivered.
+func (s *Stream) CloseWrite() error {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s.sendDone && !s.writeClosed {
+		return errWrongSide
+	}
+	if s.writeClosed || s.sendErr != nil {
+		return nil
+	}
+	s.writeClosed = true
+	c.queueStream(s)
+	return nil
+}
+
+// CloseRead aborts reads on the stream, asking the peer to stop sending
+// with the given application error code. It discards any data received
+// and not yet read.
+func (s *Stream) CloseRead(code uint64) {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s.readClosed || s.recvDone && s.recv.readable() == 0 {
+		s.readClosed = true
+		return
+	}
+	s.readClosed = true
+	c.connDataConsumed(s.recvEnd - s.recv.readOff)
+	s.recv = recvBuffer{readOff: s.recvEnd}
+	if s.finalSize < 0 && s.recvErr == nil {
+		s.stopSendingCode = code
+		s.stopSendingToSend = true
+		c.queueStream(s)
+	} else {
+		c.setRecvDone(s)
+	}
+	s.notify()
+}
+
+// Reset aborts writes on the stream, notifying the peer with the given
+// application error code. Data written and not yet acknowledged is discarded.
+func (s *Stream) Reset(code uint64) {
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resetStream(s, code, errWriteClosed)
+}
+
+// Close closes the stream: it closes the write side, as with CloseWrite,
+// and if the read side has not been fully read, aborts it with
+// CloseRead and an error code of 0.
+func (s *Stream) Close() error {
+	if !(s.id.streamType() == uniStream && s.id.initiator() != s.conn.side) {
+		s.CloseWrite()
+	}
+	if !(s.id.streamType() == uniStream && s.id.initiator() == s.conn.side) {
+		s.CloseRead(0)
+	}
+	return nil
+}
+
+// SetReadDeadline sets the deadline for future Read calls
+// and any currently-blocked Read call.
+func (s *Stream) SetReadDeadline(t time.Time) error {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+	s.readDeadline = t
+	s.notify()
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for future Write calls
+// and any currently-blocked Write call.
+func (s *Stream) SetWriteDeadline(t time.Time) error {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+	s.writeDeadline = t
+	s.notify()
+	return nil
+}
+
+// SetDeadline sets the read and write deadlines.
+func (s *Stream) SetDeadline(t time.Time) error {
+	s.SetReadDeadline(t)
+	return s.SetWriteDeadline(t)
+}
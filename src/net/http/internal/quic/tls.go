@@ -0,0 +1,123 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"internal/tlsquic"
+	"time"
+	_ "unsafe" // for go:linkname
+)
+
+// newQUICConn is defined in package crypto/tls, which does not export
+// its support for QUIC.
+//go:linkname newQUICConn crypto/tls.newQUICConn
+func newQUICConn(conn *tls.Conn) tlsquic.Conn
+
+// spaceLevel returns the TLS encryption level of a number space.
+func spaceLevel(space numberSpace) tlsquic.EncryptionLevel {
+	switch space {
+	case initialSpace:
+		return tlsquic.EncryptionLevelInitial
+	case handshakeSpace:
+		return tlsquic.EncryptionLevelHandshake
+	}
+	return tlsquic.EncryptionLevelApplication
+}
+
+// levelSpace returns the number space of a TLS encryption level.
+func levelSpace(level tlsquic.EncryptionLevel) numberSpace {
+	switch level {
+	case tlsquic.EncryptionLevelInitial:
+		return initialSpace
+	case tlsquic.EncryptionLevelHandshake:
+		return handshakeSpace
+	}
+	return appDataSpace
+}
+
+// tlsError converts an error from crypto/tls into a transport error
+// (RFC 9001, Section 4.8).
+func tlsError(err error) error {
+	var ae tlsquic.AlertError
+	if errors.As(err, &ae) {
+		return &localTransportError{code: errTLSBase + transportError(ae), err: err}
+	}
+	return &localTransportError{code: errInternal, err: err}
+}
+
+// handleTLSEvents processes the events produced by the TLS handshake.
+func (c *Conn) handleTLSEvents(now time.Time) error {
+	for {
+		e := c.tls.NextEvent()
+		switch e.Kind {
+		case tlsquic.NoEvent:
+			return nil
+		case tlsquic.SetReadSecret:
+			space := levelSpace(e.Level)
+			keys := newPacketKeys(e.Suite, cloneBytes(e.Data))
+			c.spaces[space].rkeys = keys
+			if space == appDataSpace {
+				c.nextReadKeys = keys.next()
+			}
+		case tlsquic.SetWriteSecret:
+			space := levelSpace(e.Level)
+			c.spaces[space].wkeys = newPacketKeys(e.Suite, cloneBytes(e.Data))
+		case tlsquic.WriteData:
+			space := levelSpace(e.Level)
+			c.spaces[space].cryptoSend.write(e.Data)
+		case tlsquic.TransportParameters:
+			if err := c.setPeerParams(e.Data); err != nil {
+				return err
+			}
+		case tlsquic.HandshakeDone:
+			c.handshakeComplete = true
+			if c.side == ServerSide {
+				// The server confirms the handshake when it completes,
+				// and tells the client with HANDSHAKE_DONE.
+				c.handshakeDoneToSend = true
+				c.confirmHandshake()
+				c.endpoint.queueAccept(c)
+			}
+			select {
+			case <-c.readyc:
+			default:
+				close(c.readyc)
+			}
+			c.notify()
+		}
+	}
+}
+
+// setPeerParams handles the peer's transport parameters.
+func (c *Conn) setPeerParams(b []byte) error {
+	peer := ClientSide
+	if c.side == ClientSide {
+		peer = ServerSide
+	}
+	p, err := unmarshalTransportParameters(b, peer)
+	if err != nil {
+		return err
+	}
+	// Authenticate the connection IDs (RFC 9000, Section 7.3).
+	if !bytes.Equal(p.initialSrcConnID, c.peerConnID) {
+		return paramError("initial_source_connection_id does not match")
+	}
+	if c.side == ClientSide {
+		if !bytes.Equal(p.originalDstConnID, c.origDstConnID) {
+			return paramError("original_destination_connection_id does not match")
+		}
+		if p.retrySrcConnID != nil {
+			return paramError("unexpected retry_source_connection_id")
+		}
+	}
+	c.peerParams = p
+	c.connSendMax = p.initialMaxData
+	c.localLimit[bidiStream] = p.initialMaxStreamsBidi
+	c.localLimit[uniStream] = p.initialMaxStreamsUni
+	return nil
+}
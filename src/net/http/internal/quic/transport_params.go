@@ -0,0 +1,241 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "time"
+
+// transportParameters are the QUIC transport parameters (RFC 9000, Section 18).
+type transportParameters struct {
+	originalDstConnID              []byte
+	maxIdleTimeout                 time.Duration
+	statelessResetToken            []byte
+	maxUDPPayloadSize              int64
+	initialMaxData                 int64
+	initialMaxStreamDataBidiLocal  int64
+	initialMaxStreamDataBidiRemote int64
+	initialMaxStreamDataUni        int64
+	initialMaxStreamsBidi          int64
+	initialMaxStreamsUni           int64
+	ackDelayExponent               int8
+	maxAckDelay                    time.Duration
+	disableActiveMigration         bool
+	activeConnIDLimit              int64
+	initialSrcConnID               []byte
+	retrySrcConnID                 []byte
+}
+
+// Transport parameter IDs.
+const (
+	paramOriginalDestinationConnectionID = 0x00
+	paramMaxIdleTimeout                  = 0x01
+	paramStatelessResetToken             = 0x02
+	paramMaxUDPPayloadSize               = 0x03
+	paramInitialMaxData                  = 0x04
+	paramInitialMaxStreamDataBidiLocal   = 0x05
+	paramInitialMaxStreamDataBidiRemote  = 0x06
+	paramInitialMaxStreamDataUni         = 0x07
+	paramInitialMaxStreamsBidi           = 0x08
+	paramInitialMaxStreamsUni            = 0x09
+	paramAckDelayExponent                = 0x0a
+	paramMaxAckDelay                     = 0x0b
+	paramDisableActiveMigration          = 0x0c
+	paramPreferredAddress                = 0x0d
+	paramActiveConnectionIDLimit         = 0x0e
+	paramInitialSourceConnectionID       = 0x0f
+	paramRetrySourceConnectionID         = 0x10
+)
+
+// defaultTransportParameters returns the values of parameters
+// which are absent from the peer's transport parameters.
+func defaultTransportParameters() transportParameters {
+	return transportParameters{
+		maxUDPPayloadSize: defaultPeerMaxUDPPayloadSize,
+		ackDelayExponent:  defaultAckDelayExponent,
+		maxAckDelay:       defaultMaxAckDelay,
+		activeConnIDLimit: defaultActiveConnIDLimit,
+	}
+}
+
+func appendParam(b []byte, id uint64, v []byte) []byte {
+	b = AppendVarint(b, id)
+	return appendVarintBytes(b, v)
+}
+
+func appendVarintParam(b []byte, id, v uint64) []byte {
+	b = AppendVarint(b, id)
+	b = AppendVarint(b, uint64(SizeVarint(v)))
+	return AppendVarint(b, v)
+}
+
+func (p *transportParameters) marshal() []byte {
+	var b []byte
+	if p.originalDstConnID != nil {
+		b = appendParam(b, paramOriginalDestinationConnectionID, p.originalDstConnID)
+	}
+	if p.maxIdleTimeout > 0 {
+		b = appendVarintParam(b, paramMaxIdleTimeout, uint64(p.maxIdleTimeout/time.Millisecond))
+	}
+	if p.statelessResetToken != nil {
+		b = appendParam(b, paramStatelessResetToken, p.statelessResetToken)
+	}
+	if p.maxUDPPayloadSize != defaultPeerMaxUDPPayloadSize {
+		b = appendVarintParam(b, paramMaxUDPPayloadSize, uint64(p.maxUDPPayloadSize))
+	}
+	if p.initialMaxData > 0 {
+		b = appendVarintParam(b, paramInitialMaxData, uint64(p.initialMaxData))
+	}
+	if p.initialMaxStreamDataBidiLocal > 0 {
+		b = appendVarintParam(b, paramInitialMaxStreamDataBidiLocal, uint64(p.initialMaxStreamDataBidiLocal))
+	}
+	if p.initialMaxStreamDataBidiRemote > 0 {
+		b = appendVarintParam(b, paramInitialMaxStreamDataBidiRemote, uint64(p.initialMaxStreamDataBidiRemote))
+	}
+	if p.initialMaxStreamDataUni > 0 {
+		b = appendVarintParam(b, paramInitialMaxStreamDataUni, uint64(p.initialMaxStreamDataUni))
+	}
+	if p.initialMaxStreamsBidi > 0 {
+		b = appendVarintParam(b, paramInitialMaxStreamsBidi, uint64(p.initialMaxStreamsBidi))
+	}
+	if p.initialMaxStreamsUni > 0 {
+		b = appendVarintParam(b, paramInitialMaxStreamsUni, uint64(p.initialMaxStreamsUni))
+	}
+	if p.ackDelayExponent != defaultAckDelayExponent {
+		b = appendVarintParam(b, paramAckDelayExponent, uint64(p.ackDelayExponent))
+	}
+	if p.maxAckDelay != defaultMaxAckDelay {
+		b = appendVarintParam(b, paramMaxAckDelay, uint64(p.maxAckDelay/time.Millisecond))
+	}
+	if p.disableActiveMigration {
+		b = appendParam(b, paramDisableActiveMigration, nil)
+	}
+	if p.activeConnIDLimit != defaultActiveConnIDLimit {
+		b = appendVarintParam(b, paramActiveConnectionIDLimit, uint64(p.activeConnIDLimit))
+	}
+	if p.initialSrcConnID != nil {
+		b = appendParam(b, paramInitialSourceConnectionID, p.initialSrcConnID)
+	}
+	if p.retrySrcConnID != nil {
+		b = appendParam(b, paramRetrySourceConnectionID, p.retrySrcConnID)
+	}
+	return b
+}
+
+// unmarshalTransportParameters parses the transport parameters sent by
+// a peer on the given side of the connection.
+func unmarshalTransportParameters(b []byte, side Side) (transportParameters, error) {
+	p := defaultTransportParameters()
+	seen := make(map[uint64]bool)
+	for len(b) > 0 {
+		id, n := ConsumeVarint(b)
+		if n < 0 {
+			return p, paramError("invalid parameter")
+		}
+		b = b[n:]
+		val, n := consumeVarintBytes(b)
+		if n < 0 {
+			return p, paramError("invalid parameter")
+		}
+		b = b[n:]
+		if seen[id] {
+			return p, paramError("duplicate parameter")
+		}
+		seen[id] = true
+		switch id {
+		case paramOriginalDestinationConnectionID, paramStatelessResetToken,
+			paramPreferredAddress, paramRetrySourceConnectionID:
+			// Parameters only servers may send.
+			if side == ClientSide {
+				return p, paramError("client sent a server-only parameter")
+			}
+		}
+		var v uint64
+		switch id {
+		case paramMaxIdleTimeout, paramMaxUDPPayloadSize, paramInitialMaxData,
+			paramInitialMaxStreamDataBidiLocal, paramInitialMaxStreamDataBidiRemote,
+			paramInitialMaxStreamDataUni, paramInitialMaxStreamsBidi,
+			paramInitialMaxStreamsUni, paramAckDelayExponent, paramMaxAckDelay,
+			paramActiveConnectionIDLimit:
+			var vn int
+			v, vn = ConsumeVarint(val)
+			if vn != len(val) {
+				return p, paramError("invalid integer parameter")
+			}
+		}
+		switch id {
+		case paramOriginalDestinationConnectionID:
+			p.originalDstConnID = cloneBytes(val)
+		case paramMaxIdleTimeout:
+			// Avoid overflow; a very large timeout is the same as none.
+			if v < 1<<32 {
+				p.maxIdleTimeout = time.Duration(v) * time.Millisecond
+			}
+		case paramStatelessResetToken:
+			if len(val) != 16 {
+				return p, paramError("invalid stateless_reset_token")
+			}
+			p.statelessResetToken = cloneBytes(val)
+		case paramMaxUDPPayloadSize:
+			if v < 1200 {
+				return p, paramError("invalid max_udp_payload_size")
+			}
+			p.maxUDPPayloadSize = int64(v)
+		case paramInitialMaxData:
+			p.initialMaxData = int64(v)
+		case paramInitialMaxStreamDataBidiLocal:
+			p.initialMaxStreamDataBidiLocal = int64(v)
+		case paramInitialMaxStreamDataBidiRemote:
+			p.initialMaxStreamDataBidiRemote = int64(v)
+		case paramInitialMaxStreamDataUni:
+			p.initialMaxStreamDataUni = int64(v)
+		case paramInitialMaxStreamsBidi:
+			if v > 1<<60 {
+				return p, paramError("invalid initial_max_streams_bidi")
+			}
+			p.initialMaxStreamsBidi = int64(v)
+		case paramInitialMaxStreamsUni:
+			if v > 1<<60 {
+				return p, paramError("invalid initial_max_streams_uni")
+			}
+			p.initialMaxStreamsUni = int64(v)
+		case paramAckDelayExponent:
+			if v > 20 {
+				return p, paramError("invalid ack_delay_exponent")
+			}
+			p.ackDelayExponent = int8(v)
+		case paramMaxAckDelay:
+			if v >= 1<<14 {
+				return p, paramError("invalid max_ack_delay")
+			}
+			p.maxAckDelay = time.Duration(v) * time.Millisecond
+		case paramDisableActiveMigration:
+			if len(val) != 0 {
+				return p, paramError("invalid disable_active_migration")
+			}
+			p.disableActiveMigration = true
+		case paramActiveConnectionIDLimit:
+			if v < 2 {
+				return p, paramError("invalid active_connection_id_limit")
+			}
+			p.activeConnIDLimit = int64(v)
+		case paramInitialSourceConnectionID:
+			p.initialSrcConnID = cloneBytes(val)
+		case paramRetrySourceConnectionID:
+			p.retrySrcConnID = cloneBytes(val)
+		}
+		// Unknown and unused parameters (preferred_address) are ignored.
+	}
+	return p, nil
+}
+
+func paramError(reason string) error {
+	return &localTransportError{code: errTransportParameter, reason: reason}
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "io"
+
+// MaxVarint is the largest value representable as a variable-length integer.
+const MaxVarint = 1<<62 - 1
+
+// SizeVarint returns the number of bytes needed to encode v as
+// a variable-length integer (RFC 9000, Section 16).
+func SizeVarint(v uint64) int {
+	switch {
+	case v < 1<<6:
+		return 1
+	case v < 1<<14:
+		return 2
+	case v < 1<<30:
+		return 4
+	case v <= MaxVarint:
+		return 8
+	}
+	panic("quic: varint too large")
+}
+
+// AppendVarint appends v to b as a variable-length integer.
+// It panics if v is larger than MaxVarint.
+func AppendVarint(b []byte, v uint64) []byte {
+	switch SizeVarint(v) {
+	case 1:
+		return append(b, byte(v))
+	case 2:
+		return append(b, 0x40|byte(v>>8), byte(v))
+	case 4:
+		return append(b, 0x80|byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	default:
+		return append(b, 0xc0|byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+			byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+}
+
+// ConsumeVarint parses a variable-length integer at the start of b.
+// It returns the value and the number of bytes consumed,
+// or a negative length if b does not start with a complete integer.
+func ConsumeVarint(b []byte) (v uint64, n int) {
+	if len(b) < 1 {
+		return 0, -1
+	}
+	n = 1 << (b[0] >> 6)
+	if len(b) < n {
+		return 0, -1
+	}
+	v = uint64(b[0] & 0x3f)
+	for _, c := range b[1:n] {
+		v = v<<8 | uint64(c)
+	}
+	return v, n
+}
+
+// ReadVarint reads a variable-length integer from r.
+// It returns io.ErrUnexpectedEOF if r ends in the middle of the integer.
+func ReadVarint(r io.ByteReader) (uint64, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	n := 1 << (c >> 6)
+	v := uint64(c & 0x3f)
+	for i := 1; i < n; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// consumeVarintBytes parses a varint length followed by that many bytes.
+func consumeVarintBytes(b []byte) ([]byte, int) {
+	size, n := ConsumeVarint(b)
+	if n < 0 || size > uint64(len(b)-n) {
+		return nil, -1
+	}
+	return b[n:][:size], n + int(size)
+}
+
+// appendVarintBytes appends len(v) as a varint, followed by v.
+func appendVarintBytes(b, v []byte) []byte {
+	b = AppendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
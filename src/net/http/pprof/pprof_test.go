@@ -49,6 +49,8 @@ func TestHandlers(t *testing.T) {
 		{"/debug/pprof/trace", Trace, http.StatusOK, "application/octet-stream", `attachment; filename="trace"`, nil},
 		{"/debug/pprof/mutex", Index, http.StatusOK, "application/octet-stream", `attachment; filename="mutex"`, nil},
 		{"/debug/pprof/block?seconds=1", Index, http.StatusOK, "application/octet-stream", `attachment; filename="block-delta"`, nil},
+		{"/debug/pprof/goroutineleak", Index, http.StatusOK, "application/octet-stream", `attachment; filename="goroutineleak"`, nil},
+		{"/debug/pprof/goroutineleak?debug=2", Index, http.StatusOK, "text/plain; charset=utf-8", "", nil},
 		{"/debug/pprof/goroutine?seconds=1", Index, http.StatusOK, "application/octet-stream", `attachment; filename="goroutine-delta"`, nil},
 		{"/debug/pprof/", Index, http.StatusOK, "text/html; charset=utf-8", "", []byte("Types of profiles available:")},
 	}
@@ -398,6 +398,120 @@ func TestServeMuxHandleFuncWithNilHandler(t *testing.T) {
 	mux.HandleFunc("/", nil)
 }
 
+func TestServeMuxMethodPatterns(t *testing.T) {
+	setParallel(t)
+	mux := NewServeMux()
+	mux.Handle("GET /items/{id}", serve(200))
+	mux.Handle("POST /items/{id}", serve(201))
+	mux.Handle("PUT /items/new", serve(202))
+	mux.Handle("DELETE /files/{path...}", serve(203))
+
+	for _, tt := range []struct {
+		method, path string
+		code         int
+		pattern      string
+		allow        string
+	}{
+		{"GET", "/items/1", 200, "GET /items/{id}", ""},
+		{"HEAD", "/items/1", 200, "GET /items/{id}", ""},
+		{"POST", "/items/1", 201, "POST /items/{id}", ""},
+		{"PUT", "/items/1", 405, "", "GET, HEAD, POST"},
+		{"PUT", "/items/new", 202, "PUT /items/new", ""},
+		{"DELETE", "/files/a/b/c", 203, "DELETE /files/{path...}", ""},
+		{"GET", "/files/a/b/c", 405, "", "DELETE"},
+		{"GET", "/nothing", 404, "", ""},
+	} {
+		r := &Request{Method: tt.method, Host: "example.com", URL: &url.URL{Path: tt.path}}
+		h, pattern := mux.Handler(r)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+		if pattern != tt.pattern || rr.Code != tt.code {
+			t.Errorf("%s %s = %d, %q, want %d, %q", tt.method, tt.path, rr.Code, pattern, tt.code, tt.pattern)
+		}
+		if got := rr.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s %s: Allow = %q, want %q", tt.method, tt.path, got, tt.allow)
+		}
+	}
+}
+
+func TestServeMuxPathValue(t *testing.T) {
+	setParallel(t)
+	for _, test := range []struct {
+		pattern string
+		url     string
+		want    map[string]string
+	}{
+		{
+			"/{a}/is/{b}/{c...}",
+			"/now/is/the/time/for/all",
+			map[string]string{
+				"a": "now",
+				"b": "the",
+				"c": "time/for/all",
+				"d": "",
+			},
+		},
+		{
+			"/names/{name}/{other...}",
+			"/names/%2fjohn/address",
+			map[string]string{
+				"name":  "/john",
+				"other": "address",
+			},
+		},
+		{
+			"/names/{name}/{other...}",
+			"/names/john%2Fdoe/there/is%2F/more",
+			map[string]string{
+				"name":  "john/doe",
+				"other": "there/is//more",
+			},
+		},
+	} {
+		mux := NewServeMux()
+		mux.HandleFunc(test.pattern, func(w ResponseWriter, r *Request) {
+			for name, want := range test.want {
+				got := r.PathValue(name)
+				if got != want {
+					t.Errorf("%q, %q: got %q, want %q", test.pattern, name, got, want)
+				}
+			}
+		})
+		server := httptest.NewServer(mux)
+		res, err := Get(server.URL + test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		server.Close()
+	}
+}
+
+func TestServeMuxRegisterConflict(t *testing.T) {
+	setParallel(t)
+	for _, test := range []struct {
+		p1, p2 string
+		want   string
+	}{
+		{"/a", "/a", "matches the same requests"},
+		{"/a/{x}", "/{y}/b", "both match some paths"},
+		{"GET /", "/index.html", "matches more methods"},
+		{"/{x", "", "bad wildcard segment"},
+	} {
+		func() {
+			defer func() {
+				err, ok := recover().(error)
+				if !ok || !strings.Contains(err.Error(), test.want) {
+					t.Errorf("registering %q then %q: got panic %v, want error containing %q", test.p1, test.p2, err, test.want)
+				}
+			}()
+			mux := NewServeMux()
+			mux.Handle(test.p1, serve(200))
+			mux.Handle(test.p2, serve(200))
+		}()
+	}
+}
+
 var serveMuxTests2 = []struct {
 	method  string
 	host    string
@@ -37,9 +37,80 @@ func BenchmarkServerMatch(b *testing.B) {
 		"/products/", "/products/3/image.jpg"}
 	b.StartTimer()
 	for i := 0; i < b.N; i++ {
-		if h, p := mux.match(paths[i%len(paths)]); h != nil && p == "" {
+		if n, _ := mux.tree.match("", "GET", paths[i%len(paths)]); n != nil && n.pattern == nil {
 			b.Error("impossible")
 		}
 	}
 	b.StopTimer()
 }
+
+func TestGodebug(t *testing.T) {
+	for _, test := range []struct {
+		env, want string
+	}{
+		{"", ""},
+		{"httpmuxgo117=1", "1"},
+		{"http2debug=1,httpmuxgo117=1", "1"},
+		{"httpmuxgo117=1,http2debug=1", "1"},
+		{"httpmuxgo117=0,httpmuxgo117=1", "1"},
+		{"httpmuxgo117=1,httpmuxgo117=0", "0"},
+		{"xhttpmuxgo117=1", ""},
+		{"httpmuxgo1171=1", ""},
+		{"httpmuxgo117", ""},
+	} {
+		if got := godebug(test.env, "httpmuxgo117"); got != test.want {
+			t.Errorf("godebug(%q, %q) = %q, want %q", test.env, "httpmuxgo117", got, test.want)
+		}
+	}
+}
+
+func TestServeMux117(t *testing.T) {
+	defer func(old bool) { use117 = old }(use117)
+
+	h := HandlerFunc(func(ResponseWriter, *Request) {})
+	for _, test := range []struct {
+		path    string
+		want    string // matching pattern
+		want117 string // matching pattern with GODEBUG=httpmuxgo117=1
+	}{
+		{"/{x}", "/{x}", "/{x}"},
+		{"/y", "/{x}", ""},
+		{"/a/b", "/a/b", "/a/b"},
+		{"/a%2Fb", "/{x}", "/a/b"},
+		{"/static/file", "/static/", "/static/"},
+		{"/static", "/{x}", "/static/"}, // redirect with httpmuxgo117
+	} {
+		for _, use := range []bool{false, true} {
+			use117 = use
+			mux := NewServeMux()
+			mux.Handle("/{x}", h)
+			mux.Handle("/a/b", h)
+			mux.Handle("/static/", h)
+			r, err := NewRequest("GET", "http://example.com"+test.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, got := mux.Handler(r)
+			want := test.want
+			if use {
+				want = test.want117
+			}
+			if got != want {
+				t.Errorf("httpmuxgo117=%v: %q matched %q, want %q", use, test.path, got, want)
+			}
+		}
+	}
+
+	// Only duplicate registrations are rejected.
+	use117 = true
+	mux := NewServeMux()
+	mux.Handle("/{", h)
+	mux.Handle("/a{x}", h)
+	mux.Handle("GET /", h)
+	defer func() {
+		if r := recover(); r != "http: multiple registrations for /{" {
+			t.Errorf("duplicate registration: recovered %v, want a panic", r)
+		}
+	}()
+	mux.Handle("/{", h)
+}
@@ -12,6 +12,7 @@ package http
 import (
 	"bufio"
 	"compress/gzip"
+	"compress/zstd"
 	"container/list"
 	"context"
 	"crypto/tls"
@@ -189,6 +190,15 @@ type Transport struct {
 	// uncompressed.
 	DisableCompression bool
 
+	// EnableZstd, if true, makes the Transport also request
+	// Zstandard compression when it requests compression on its
+	// own, sending "Accept-Encoding: gzip, zstd". A response with
+	// "Content-Encoding: zstd" is then transparently decoded in
+	// the Response.Body, as for gzip. EnableZstd has no effect
+	// if DisableCompression is set, and currently applies only
+	// to HTTP/1 connections.
+	EnableZstd bool
+
 	// MaxIdleConns controls the maximum number of idle (keep-alive)
 	// connections across all hosts. Zero means no limit.
 	MaxIdleConns int
@@ -281,6 +291,18 @@ type Transport struct {
 	// To use a custom dialer or TLS config and still attempt HTTP/2
 	// upgrades, set this to true.
 	ForceAttemptHTTP2 bool
+
+	// EnableHTTP3 enables HTTP/3 for "https" requests to servers
+	// which advertise support for it with an Alt-Svc header.
+	// Once a response received over TCP has advertised HTTP/3,
+	// later requests to the same server are sent over QUIC,
+	// falling back to TCP if a QUIC connection can't be established.
+	// HTTP/3 is not used for requests sent through a proxy, and
+	// connections are dialed without DialContext, Dial, or DialTLS.
+	EnableHTTP3 bool
+
+	h3Once      sync.Once // guards h3transport
+	h3transport *http3Transport
 }
 
 // A cancelKey is the key of the reqCanceler map.
@@ -316,6 +338,7 @@ func (t *Transport) Clone() *Transport {
 		TLSHandshakeTimeout:    t.TLSHandshakeTimeout,
 		DisableKeepAlives:      t.DisableKeepAlives,
 		DisableCompression:     t.DisableCompression,
+		EnableZstd:             t.EnableZstd,
 		MaxIdleConns:           t.MaxIdleConns,
 		MaxIdleConnsPerHost:    t.MaxIdleConnsPerHost,
 		MaxConnsPerHost:        t.MaxConnsPerHost,
@@ -326,6 +349,7 @@ func (t *Transport) Clone() *Transport {
 		GetProxyConnectHeader:  t.GetProxyConnectHeader,
 		MaxResponseHeaderBytes: t.MaxResponseHeaderBytes,
 		ForceAttemptHTTP2:      t.ForceAttemptHTTP2,
+		EnableHTTP3:            t.EnableHTTP3,
 		WriteBufferSize:        t.WriteBufferSize,
 		ReadBufferSize:         t.ReadBufferSize,
 	}
@@ -535,8 +559,22 @@ func (t *Transport) roundTrip(req *Request) (*Response, error) {
 	cancelKey := cancelKey{origReq}
 	req = setupRewindBody(req)
 
+	if t.EnableHTTP3 && scheme == "https" && !req.requiresHTTP1() {
+		resp, err := t.http3().roundTrip(req)
+		if err == nil {
+			resp.Request = origReq
+			return resp, nil
+		}
+		if err != errHTTP3Unavailable {
+			return nil, err
+		}
+	}
+
 	if altRT := t.alternateRoundTripper(req); altRT != nil {
 		if resp, err := altRT.RoundTrip(req); err != ErrSkipAltProtocol {
+			if err == nil && t.EnableHTTP3 && scheme == "https" {
+				t.http3().noteAltSvc(req, resp)
+			}
 			return resp, err
 		}
 		var err error
@@ -595,6 +633,9 @@ func (t *Transport) roundTrip(req *Request) (*Response, error) {
 		}
 		if err == nil {
 			resp.Request = origReq
+			if t.EnableHTTP3 && scheme == "https" {
+				t.http3().noteAltSvc(req, resp)
+			}
 			return resp, nil
 		}
 
@@ -773,6 +814,9 @@ func (t *Transport) CloseIdleConnections() {
 	if t2 := t.h2transport; t2 != nil {
 		t2.CloseIdleConnections()
 	}
+	if t.EnableHTTP3 {
+		t.http3().closeIdleConnections()
+	}
 }
 
 // CancelRequest cancels an in-flight request by closing its connection.
@@ -2187,8 +2231,13 @@ func (pc *persistConn) readLoop() {
 		}
 
 		resp.Body = body
-		if rc.addedGzip && ascii.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		switch ce := resp.Header.Get("Content-Encoding"); {
+		case rc.addedGzip && ascii.EqualFold(ce, "gzip"):
 			resp.Body = &gzipReader{body: body}
+		case rc.addedZstd && ascii.EqualFold(ce, "zstd"):
+			resp.Body = &zstdReader{body: body}
+		}
+		if resp.Body != body {
 			resp.Header.Del("Content-Encoding")
 			resp.Header.Del("Content-Length")
 			resp.ContentLength = -1
@@ -2472,6 +2521,10 @@ type requestAndChan struct {
 	// set it, only then do we transparently decode the gzip.
 	addedGzip bool
 
+	// whether the Transport also offered zstd in that header,
+	// in which case it transparently decodes zstd too.
+	addedZstd bool
+
 	// Optional blocking chan for Expect: 100-continue (for send).
 	// If the request has an "Expect: 100-continue" header and
 	// the server responds 100 Continue, readLoop send a value
@@ -2544,7 +2597,7 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 	// own value for Accept-Encoding. We only attempt to
 	// uncompress the gzip stream if we were the layer that
 	// requested it.
-	requestedGzip := false
+	requestedGzip, requestedZstd := false, false
 	if !pc.t.DisableCompression &&
 		req.Header.Get("Accept-Encoding") == "" &&
 		req.Header.Get("Range") == "" &&
@@ -2562,7 +2615,12 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 		// auto-decoding a portion of a gzipped document will just fail
 		// anyway. See https://golang.org/issue/8923
 		requestedGzip = true
-		req.extraHeaders().Set("Accept-Encoding", "gzip")
+		if pc.t.EnableZstd {
+			requestedZstd = true
+			req.extraHeaders().Set("Accept-Encoding", "gzip, zstd")
+		} else {
+			req.extraHeaders().Set("Accept-Encoding", "gzip")
+		}
 	}
 
 	var continueCh chan struct{}
@@ -2600,6 +2658,7 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 		cancelKey:  req.cancelKey,
 		ch:         resc,
 		addedGzip:  requestedGzip,
+		addedZstd:  requestedZstd,
 		continueCh: continueCh,
 		callerGone: gone,
 	}
@@ -2836,6 +2895,41 @@ func (gz *gzipReader) Close() error {
 	return gz.body.Close()
 }
 
+// zstdReader wraps a response body so it can lazily
+// call zstd.NewReader on the first call to Read
+type zstdReader struct {
+	_    incomparable
+	body *bodyEOFSignal // underlying HTTP/1 response body framing
+	zr   *zstd.Reader   // lazily-initialized zstd reader
+	zerr error          // any error from zstd.NewReader; sticky
+}
+
+func (zs *zstdReader) Read(p []byte) (n int, err error) {
+	if zs.zr == nil {
+		if zs.zerr == nil {
+			zs.zr, zs.zerr = zstd.NewReader(zs.body)
+		}
+		if zs.zerr != nil {
+			return 0, zs.zerr
+		}
+	}
+
+	zs.body.mu.Lock()
+	if zs.body.closed {
+		err = errReadOnClosedResBody
+	}
+	zs.body.mu.Unlock()
+
+	if err != nil {
+		return 0, err
+	}
+	return zs.zr.Read(p)
+}
+
+func (zs *zstdReader) Close() error {
+	return zs.body.Close()
+}
+
 type tlsHandshakeTimeoutError struct{}
 
 func (tlsHandshakeTimeoutError) Timeout() bool   { return true }
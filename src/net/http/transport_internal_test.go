@@ -15,6 +15,7 @@ import (
 	"net/http/internal/testcert"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Issue 15446: incorrect wrapping of errors when server closes an idle connection.
@@ -260,3 +261,31 @@ func TestTransportBodyAltRewind(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestParseAltSvcH3(t *testing.T) {
+	for _, test := range []struct {
+		v      string
+		addr   string
+		maxAge time.Duration
+		clear  bool
+	}{
+		{v: `h3=":443"`, addr: ":443", maxAge: 24 * time.Hour},
+		{v: `h3=":8443"; ma=60`, addr: ":8443", maxAge: time.Minute},
+		{v: `h3="alt.example.com:443"; ma="3600"; persist=1`, addr: "alt.example.com:443", maxAge: time.Hour},
+		{v: `h2=":443", h3-29=":443", h3=":444"`, addr: ":444", maxAge: 24 * time.Hour},
+		{v: `h3="[::1]:443"`, addr: "[::1]:443", maxAge: 24 * time.Hour},
+		{v: `h2="a,b;c:443", h3=":443"; ma=10`, addr: ":443", maxAge: 10 * time.Second},
+		{v: ` clear `, clear: true},
+		{v: `h2=":443"`},
+		{v: `h3=":0"`},
+		{v: `h3="noport"`},
+		{v: `h3`},
+		{v: ``},
+	} {
+		addr, maxAge, clear := parseAltSvcH3(test.v)
+		if addr != test.addr || maxAge != test.maxAge || clear != test.clear {
+			t.Errorf("parseAltSvcH3(%q) = %q, %v, %v; want %q, %v, %v",
+				test.v, addr, maxAge, clear, test.addr, test.maxAge, test.clear)
+		}
+	}
+}
@@ -1670,6 +1670,54 @@ func TestTransportGzipShort(t *testing.T) {
 	}
 }
 
+// zstdHello is "Hello, Gophers!\n" compressed with zstd.
+var zstdHello = []byte{
+	0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0x81, 0x00, 0x00, 0x48, 0x65, 0x6c,
+	0x6c, 0x6f, 0x2c, 0x20, 0x47, 0x6f, 0x70, 0x68, 0x65, 0x72, 0x73, 0x21,
+	0x0a, 0x3a, 0x0f, 0x63, 0x96,
+}
+
+func TestTransportZstd(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("X-Accept-Encoding", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "zstd")
+		w.Write(zstdHello)
+	}))
+	defer ts.Close()
+
+	for _, enable := range []bool{false, true} {
+		c := ts.Client()
+		c.Transport.(*Transport).EnableZstd = enable
+		res, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantAccept, wantBody, wantEncoding := "gzip", string(zstdHello), "zstd"
+		if enable {
+			wantAccept, wantBody, wantEncoding = "gzip, zstd", "Hello, Gophers!\n", ""
+		}
+		if g := res.Header.Get("X-Accept-Encoding"); g != wantAccept {
+			t.Errorf("EnableZstd=%v: Accept-Encoding = %q; want %q", enable, g, wantAccept)
+		}
+		if string(body) != wantBody {
+			t.Errorf("EnableZstd=%v: body = %q; want %q", enable, body, wantBody)
+		}
+		if g := res.Header.Get("Content-Encoding"); g != wantEncoding {
+			t.Errorf("EnableZstd=%v: Content-Encoding = %q; want %q", enable, g, wantEncoding)
+		}
+		if res.Uncompressed != enable {
+			t.Errorf("EnableZstd=%v: Uncompressed = %v", enable, res.Uncompressed)
+		}
+	}
+}
+
 // Wait until number of goroutines is no greater than nmax, or time out.
 func waitNumGoroutine(nmax int) int {
 	nfinal := runtime.NumGoroutine()
@@ -5887,6 +5935,7 @@ func TestTransportClone(t *testing.T) {
 		TLSHandshakeTimeout:    time.Second,
 		DisableKeepAlives:      true,
 		DisableCompression:     true,
+		EnableZstd:             true,
 		MaxIdleConns:           1,
 		MaxIdleConnsPerHost:    1,
 		MaxConnsPerHost:        1,
@@ -5897,6 +5946,7 @@ func TestTransportClone(t *testing.T) {
 		GetProxyConnectHeader:  func(context.Context, *url.URL, string) (Header, error) { return nil, nil },
 		MaxResponseHeaderBytes: 1,
 		ForceAttemptHTTP2:      true,
+		EnableHTTP3:            true,
 		TLSNextProto: map[string]func(authority string, c *tls.Conn) RoundTripper{
 			"foo": func(authority string, c *tls.Conn) RoundTripper { panic("") },
 		},
@@ -1055,7 +1055,7 @@ func serverHandle(c net.Conn, t *testing.T) error {
 			defer c.Close()
 			return serverHandleTLS(c, t)
 		default:
-			t.Fatalf("unrecognized command: %q", s.Text())
+			return fmt.Errorf("unrecognized command: %q", s.Text())
 		}
 	}
 	return s.Err()
@@ -1083,7 +1083,7 @@ func serverHandleTLS(c net.Conn, t *testing.T) error {
 			send("221 127.0.0.1 Service closing transmission channel")
 			return nil
 		default:
-			t.Fatalf("unrecognized command during TLS: %q", s.Text())
+			return fmt.Errorf("unrecognized command during TLS: %q", s.Text())
 		}
 	}
 	return s.Err()
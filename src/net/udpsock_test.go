@@ -10,6 +10,7 @@ package net
 import (
 	"errors"
 	"internal/testenv"
+	"net/netip"
 	"os"
 	"reflect"
 	"runtime"
@@ -475,6 +476,103 @@ func TestUDPReadTimeout(t *testing.T) {
 	}
 }
 
+func TestUDPAddrPortRoundTrip(t *testing.T) {
+	switch runtime.GOOS {
+	case "plan9":
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+	if !testableNetwork("udp4") {
+		t.Skip("udp4 not supported")
+	}
+
+	conn, err := ListenUDP("udp4", &UDPAddr{IP: IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	addr := conn.LocalAddr().(*UDPAddr).AddrPort()
+	if !addr.IsValid() || addr.Addr() != netip.AddrFrom4([4]byte{127, 0, 0, 1}) {
+		t.Fatalf("LocalAddr().AddrPort() = %v; want 127.0.0.1:port", addr)
+	}
+
+	want := []byte("HELLO-R-U-THERE")
+	if _, err := conn.WriteToUDPAddrPort(want, addr); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 32)
+	n, from, err := conn.ReadFromUDPAddrPort(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[:n]) != string(want) {
+		t.Errorf("ReadFromUDPAddrPort got %q; want %q", b[:n], want)
+	}
+	if from != addr {
+		t.Errorf("ReadFromUDPAddrPort got addr %v; want %v", from, addr)
+	}
+	if got := UDPAddrFromAddrPort(from).AddrPort(); got != from {
+		t.Errorf("UDPAddrFromAddrPort(%v).AddrPort() = %v", from, got)
+	}
+
+	if _, err := conn.WriteToUDPAddrPort(want, netip.AddrPort{}); err == nil {
+		t.Error("WriteToUDPAddrPort with zero AddrPort succeeded; want error")
+	}
+}
+
+func TestUDPAddrPortAllocs(t *testing.T) {
+	switch runtime.GOOS {
+	case "plan9":
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+	if !testableNetwork("udp4") {
+		t.Skip("udp4 not supported")
+	}
+
+	conn, err := ListenUDP("udp4", &UDPAddr{IP: IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	addr := conn.LocalAddr().(*UDPAddr).AddrPort()
+	buf := make([]byte, 8)
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		_, err := conn.WriteToUDPAddrPort(buf, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	// Windows has one alloc per I/O operation in internal/poll.
+	if runtime.GOOS != "windows" && allocs > 0 {
+		t.Errorf("WriteToUDPAddrPort/ReadFromUDPAddrPort: got %v allocs, want 0", allocs)
+	}
+}
+
+func BenchmarkWriteToReadFromUDPAddrPort(b *testing.B) {
+	conn, err := ListenUDP("udp4", &UDPAddr{IP: IPv4(127, 0, 0, 1)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	addr := conn.LocalAddr().(*UDPAddr).AddrPort()
+	buf := make([]byte, 8)
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := conn.WriteToUDPAddrPort(buf, addr)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, _, err = conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkWriteToReadFromUDP(b *testing.B) {
 	conn, err := ListenUDP("udp4", &UDPAddr{IP: IPv4(127, 0, 0, 1)})
 	if err != nil {
@@ -20,11 +20,15 @@ var ErrProcessDone = errors.New("os: process already finished")
 // Process stores the information about a process created by StartProcess.
 type Process struct {
 	Pid    int
-	handle uintptr      // handle is accessed atomically on Windows
+	handle uintptr      // accessed atomically; a process handle on Windows, a pidfd on Linux
 	isdone uint32       // process has been successfully waited on, non zero if true
 	sigMu  sync.RWMutex // avoid race between wait and signal
 }
 
+// unsetHandle is the handle of a Process without one,
+// as syscall.InvalidHandle on Windows.
+const unsetHandle = ^uintptr(0)
+
 func newProcess(pid int, handle uintptr) *Process {
 	p := &Process{Pid: pid, handle: handle}
 	runtime.SetFinalizer(p, (*Process).Release)
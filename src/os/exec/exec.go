@@ -33,6 +33,7 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 )
 
 // Error is returned by LookPath when it fails to classify a file as an
@@ -50,6 +51,25 @@ func (e *Error) Error() string {
 
 func (e *Error) Unwrap() error { return e.Err }
 
+// ErrWaitDelay is returned by Cmd.Wait if the process exits with a
+// successful status code but its output pipes are not closed before
+// the command's WaitDelay expires.
+var ErrWaitDelay = errors.New("exec: WaitDelay expired before I/O complete")
+
+// wrappedError wraps an error without relying on fmt.Errorf.
+type wrappedError struct {
+	prefix string
+	err    error
+}
+
+func (w wrappedError) Error() string {
+	return w.prefix + ": " + w.err.Error()
+}
+
+func (w wrappedError) Unwrap() error {
+	return w.err
+}
+
 // Cmd represents an external command being prepared or run.
 //
 // A Cmd cannot be reused after calling its Run, Output or CombinedOutput
@@ -133,6 +153,69 @@ type Cmd struct {
 	// available after a call to Wait or Run.
 	ProcessState *os.ProcessState
 
+	// If Cancel is non-nil, the command must have been created with
+	// CommandContext, and Cancel is called when the command's context
+	// becomes done before the command completes. CommandContext sets
+	// Cancel to call the Kill method of the command's Process.
+	//
+	// A custom Cancel typically sends a gentler signal to the Process,
+	// such as os.Interrupt or syscall.SIGTERM, to let the command shut
+	// down cleanly. Together with WaitDelay, it still ensures that the
+	// command is eventually killed.
+	//
+	// If the command exits with a successful status after Cancel is
+	// called, Wait reports an error: the context's error if Cancel
+	// returned nil, or an error wrapping the one returned by Cancel.
+	// An error returned by Cancel that wraps os.ErrProcessDone is
+	// ignored, since the command completed on its own.
+	//
+	// If Cancel is set to nil, nothing happens when the context becomes
+	// done, but a non-zero WaitDelay still takes effect.
+	//
+	// Cancel is not called if Start returns an error.
+	Cancel func() error
+
+	// If WaitDelay is non-zero, it bounds the time Wait spends waiting
+	// on two sources of unexpected delay: a command that does not exit
+	// after its context becomes done and Cancel is called, and a command
+	// that exits but leaves its I/O pipes open, typically because it
+	// started a subprocess that inherited them.
+	//
+	// WaitDelay starts when the context becomes done or, if it does not,
+	// when the command exits. When it expires, the command is killed
+	// with os.Process.Kill, or with its process group if ProcessGroup
+	// is set, the pipes between the command and the
+	// goroutines copying Stdin, Stdout and Stderr are closed, and those
+	// goroutines are abandoned. If the command otherwise exited with a
+	// successful status, Wait then returns ErrWaitDelay.
+	//
+	// If WaitDelay is zero, Wait waits until the command exits and all
+	// of the I/O pipes are closed.
+	WaitDelay time.Duration
+
+	// If ProcessGroup is true, the command is started in a new process
+	// group, of which it is the leader, and the whole group is killed
+	// instead of the command alone: by the Cancel function set by
+	// CommandContext, when WaitDelay expires, and when the command
+	// exits but WaitDelay expires before its I/O pipes are closed. This
+	// reliably stops the subprocesses started by the command, unless
+	// they move to another process group.
+	//
+	// Since the ID of the group may be reused once the command has been
+	// waited for and the group is empty, the group is never killed after
+	// Wait reaps the command. On Linux, Wait reaps the command only after
+	// its I/O pipes are closed or WaitDelay expires; on other systems,
+	// the group is thus not killed when the command exits but WaitDelay
+	// expires before its I/O pipes are closed. Wait does not wait for the
+	// other processes of the group to exit.
+	//
+	// ProcessGroup overrides the Setpgid and Pgid fields of SysProcAttr,
+	// and Start returns an error if a non-zero Pgid is set. On Linux,
+	// SysProcAttr.UseCgroupFD instead places the command in a cgroup.
+	//
+	// ProcessGroup is not supported on Windows, Plan 9 or js/wasm.
+	ProcessGroup bool
+
 	ctx             context.Context // nil means none
 	lookPathErr     error           // LookPath error, if any.
 	finished        bool            // when Wait was called
@@ -140,8 +223,24 @@ type Cmd struct {
 	closeAfterStart []io.Closer
 	closeAfterWait  []io.Closer
 	goroutine       []func() error
-	errch           chan error // one send per goroutine
-	waitDone        chan struct{}
+	parentIOPipes   []io.Closer // parent ends of the pipes of the goroutines
+
+	// goroutineErr receives the first error of the goroutines, or nil,
+	// once they are all done. It is nil if there are no goroutines.
+	goroutineErr chan error
+
+	// ctxResult receives the result of watchCtx once the process exits.
+	// It is nil if the context is not watched.
+	ctxResult chan ctxResult
+}
+
+// A ctxResult reports the result of watching the context of a Cmd.
+type ctxResult struct {
+	err error
+
+	// If timer is non-nil, it expires after WaitDelay has elapsed
+	// since the context became done.
+	timer *time.Timer
 }
 
 // Command returns the Cmd struct to execute the named program with
@@ -183,15 +282,23 @@ func Command(name string, arg ...string) *Cmd {
 
 // CommandContext is like Command but includes a context.
 //
-// The provided context is used to kill the process (by calling
-// os.Process.Kill) if the context becomes done before the command
-// completes on its own.
+// The provided context is used to interrupt the process
+// (by calling cmd.Cancel or os.Process.Kill)
+// if the context becomes done before the command completes on its own.
+//
+// CommandContext sets the command's Cancel function to invoke the Kill method
+// on its Process, or to kill its process group if ProcessGroup is set, and
+// leaves its WaitDelay unset. The caller may change the
+// cancellation behavior by modifying those fields before starting the command.
 func CommandContext(ctx context.Context, name string, arg ...string) *Cmd {
 	if ctx == nil {
 		panic("nil Context")
 	}
 	cmd := Command(name, arg...)
 	cmd.ctx = ctx
+	cmd.Cancel = func() error {
+		return cmd.kill()
+	}
 	return cmd
 }
 
@@ -262,6 +369,7 @@ func (c *Cmd) stdin() (f *os.File, err error) {
 
 	c.closeAfterStart = append(c.closeAfterStart, pr)
 	c.closeAfterWait = append(c.closeAfterWait, pw)
+	c.parentIOPipes = append(c.parentIOPipes, pw)
 	c.goroutine = append(c.goroutine, func() error {
 		_, err := io.Copy(pw, c.Stdin)
 		if skip := skipStdinCopyError; skip != nil && skip(err) {
@@ -307,6 +415,7 @@ func (c *Cmd) writerDescriptor(w io.Writer) (f *os.File, err error) {
 
 	c.closeAfterStart = append(c.closeAfterStart, pw)
 	c.closeAfterWait = append(c.closeAfterWait, pr)
+	c.parentIOPipes = append(c.parentIOPipes, pr)
 	c.goroutine = append(c.goroutine, func() error {
 		_, err := io.Copy(w, pr)
 		pr.Close() // in case io.Copy stopped due to write error
@@ -391,6 +500,21 @@ func (c *Cmd) Start() error {
 	if c.Process != nil {
 		return errors.New("exec: already started")
 	}
+	if c.Cancel != nil && c.ctx == nil {
+		c.closeDescriptors(c.closeAfterStart)
+		c.closeDescriptors(c.closeAfterWait)
+		return errors.New("exec: command with a non-nil Cancel was not created with CommandContext")
+	}
+	sys := c.SysProcAttr
+	if c.ProcessGroup {
+		var err error
+		sys, err = processGroupAttr(sys)
+		if err != nil {
+			c.closeDescriptors(c.closeAfterStart)
+			c.closeDescriptors(c.closeAfterWait)
+			return err
+		}
+	}
 	if c.ctx != nil {
 		select {
 		case <-c.ctx.Done():
@@ -423,7 +547,7 @@ func (c *Cmd) Start() error {
 		Dir:   c.Dir,
 		Files: c.childFiles,
 		Env:   addCriticalEnv(dedupEnv(envv)),
-		Sys:   c.SysProcAttr,
+		Sys:   sys,
 	})
 	if err != nil {
 		c.closeDescriptors(c.closeAfterStart)
@@ -433,30 +557,115 @@ func (c *Cmd) Start() error {
 
 	c.closeDescriptors(c.closeAfterStart)
 
-	// Don't allocate the channel unless there are goroutines to fire.
+	// Don't allocate the channels unless there are goroutines to fire.
 	if len(c.goroutine) > 0 {
-		c.errch = make(chan error, len(c.goroutine))
+		errch := make(chan error, len(c.goroutine)) // one send per goroutine
 		for _, fn := range c.goroutine {
 			go func(fn func() error) {
-				c.errch <- fn()
+				errch <- fn()
 			}(fn)
 		}
+		goroutineErr := make(chan error, 1)
+		c.goroutineErr = goroutineErr
+		go func(n int) {
+			var firstErr error
+			for i := 0; i < n; i++ {
+				if err := <-errch; err != nil && firstErr == nil {
+					firstErr = err
+				}
+			}
+			goroutineErr <- firstErr
+		}(len(c.goroutine))
 	}
 
-	if c.ctx != nil {
-		c.waitDone = make(chan struct{})
-		go func() {
-			select {
-			case <-c.ctx.Done():
-				c.Process.Kill()
-			case <-c.waitDone:
-			}
-		}()
+	if c.ctx != nil && c.ctx.Done() != nil && (c.Cancel != nil || c.WaitDelay != 0) {
+		c.ctxResult = make(chan ctxResult)
+		go c.watchCtx()
 	}
 
 	return nil
 }
 
+// watchCtx cancels the command if its context becomes done before the
+// process exits and, once WaitDelay expires, kills the process and
+// closes its I/O pipes. It sends its result to c.ctxResult once Wait
+// is ready to receive it, that is, once the process exited.
+func (c *Cmd) watchCtx() {
+	select {
+	case c.ctxResult <- ctxResult{}:
+		return
+	case <-c.ctx.Done():
+	}
+
+	var err error
+	if c.Cancel != nil {
+		if cancelErr := c.Cancel(); cancelErr == nil {
+			// The command was interrupted, so whatever it does
+			// from now on may be due to the context.
+			err = c.ctx.Err()
+		} else if !errors.Is(cancelErr, os.ErrProcessDone) {
+			err = wrappedError{prefix: "exec: canceling Cmd", err: cancelErr}
+		}
+	}
+	if c.WaitDelay == 0 {
+		c.ctxResult <- ctxResult{err: err}
+		return
+	}
+
+	timer := time.NewTimer(c.WaitDelay)
+	select {
+	case c.ctxResult <- ctxResult{err: err, timer: timer}:
+		// The process exited: Wait takes care of the goroutines,
+		// with the timer.
+		return
+	case <-timer.C:
+	}
+
+	killed := false
+	if killErr := c.kill(); killErr == nil {
+		// The process should now exit with an error, unless the
+		// kill raced with a successful exit, which is not an error.
+		killed = true
+	} else if !errors.Is(killErr, os.ErrProcessDone) {
+		err = wrappedError{prefix: "exec: killing Cmd", err: killErr}
+	}
+
+	if c.goroutineErr != nil {
+		select {
+		case goroutineErr := <-c.goroutineErr:
+			// Report the copying error only if it cannot be
+			// caused by Cancel or the kill above.
+			if err == nil && !killed {
+				err = goroutineErr
+			}
+		default:
+			// Close the pipes, in case a subprocess of the command
+			// inherited them and holds them open. Do so only after
+			// signaling the process, which should rather die of the
+			// signal than of SIGPIPE.
+			c.closeDescriptors(c.parentIOPipes)
+			// Any error of the goroutines may be due to the closed pipes.
+			<-c.goroutineErr
+			if err == nil {
+				err = ErrWaitDelay
+			}
+		}
+		// The only result of the goroutines was received.
+		c.goroutineErr = nil
+	}
+
+	c.ctxResult <- ctxResult{err: err}
+}
+
+// kill kills the command, along with its process group if ProcessGroup
+// is set.
+func (c *Cmd) kill() error {
+	if c.ProcessGroup {
+		return killProcessGroup(c.Process)
+	}
+	return c.Process.Kill()
+}
+
 // An ExitError reports an unsuccessful exit by a command.
 type ExitError struct {
 	*os.ProcessState
@@ -492,7 +701,8 @@ func (e *ExitError) Error() string {
 // returned for I/O problems.
 //
 // If any of c.Stdin, c.Stdout or c.Stderr are not an *os.File, Wait also waits
-// for the respective I/O loop copying to or from the process to complete.
+// for the respective I/O loop copying to or from the process to complete,
+// for at most c.WaitDelay if it is non-zero.
 //
 // Wait releases any resources associated with the Cmd.
 func (c *Cmd) Wait() error {
@@ -504,28 +714,96 @@ func (c *Cmd) Wait() error {
 	}
 	c.finished = true
 
+	// Killing the process group of the command is safe only until the
+	// command is reaped, so reap it last if possible.
+	var err error
+	reaped := false
+	if !c.ProcessGroup || !waitExited(c.Process) {
+		err = c.reap()
+		reaped = true
+	}
+
+	var timer *time.Timer
+	var ctxErr error
+	if c.ctxResult != nil {
+		watch := <-c.ctxResult
+		timer = watch.timer
+		ctxErr = watch.err
+	}
+
+	copyError := c.awaitGoroutines(timer)
+
+	if !reaped {
+		err = c.reap()
+	}
+	// Prefer the error of the process, if any, to that of the context,
+	// such as a cancellation or an expired WaitDelay. Report an error of
+	// the copying goroutines only if the process exited normally on its
+	// own: otherwise, the error may be due to the abnormal termination.
+	if err == nil {
+		err = ctxErr
+	}
+	if err == nil {
+		err = copyError
+	}
+
+	c.closeDescriptors(c.closeAfterWait)
+	return err
+}
+
+// reap waits for the command to exit, sets c.ProcessState, and returns
+// an *ExitError if it did not exit successfully.
+func (c *Cmd) reap() error {
 	state, err := c.Process.Wait()
-	if c.waitDone != nil {
-		close(c.waitDone)
+	if err == nil && !state.Success() {
+		err = &ExitError{ProcessState: state}
 	}
 	c.ProcessState = state
+	return err
+}
 
-	var copyError error
-	for range c.goroutine {
-		if err := <-c.errch; err != nil && copyError == nil {
-			copyError = err
+// awaitGoroutines waits for the goroutines copying the I/O of the
+// process to complete and returns their first error. If WaitDelay
+// expires first, it closes their pipes and returns ErrWaitDelay.
+// If timer is non-nil, WaitDelay expires with it.
+func (c *Cmd) awaitGoroutines(timer *time.Timer) error {
+	defer func() {
+		if timer != nil {
+			timer.Stop()
 		}
-	}
+		c.goroutineErr = nil
+	}()
 
-	c.closeDescriptors(c.closeAfterWait)
+	if c.goroutineErr == nil {
+		return nil
+	}
+	if timer == nil {
+		if c.WaitDelay == 0 {
+			return <-c.goroutineErr
+		}
+		select {
+		case err := <-c.goroutineErr:
+			// Avoid the timer if the goroutines are already done.
+			return err
+		default:
+		}
+		timer = time.NewTimer(c.WaitDelay)
+	}
 
-	if err != nil {
+	select {
+	case <-timer.C:
+		if c.ProcessGroup {
+			// The command exited, but the subprocesses holding
+			// its pipes open may still be in its process group.
+			killProcessGroup(c.Process)
+		}
+		c.closeDescriptors(c.parentIOPipes)
+		// Any error of the goroutines may be due to the closed pipes.
+		<-c.goroutineErr
+		return ErrWaitDelay
+	case err := <-c.goroutineErr:
 		return err
-	} else if !state.Success() {
-		return &ExitError{ProcessState: state}
 	}
-
-	return copyError
 }
 
 // Output runs the command and returns its standard output.
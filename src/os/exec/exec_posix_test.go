@@ -8,6 +8,12 @@
 package exec_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
 	"os/user"
 	"runtime"
 	"strconv"
@@ -87,3 +93,87 @@ func TestWaitid(t *testing.T) {
 
 	<-ch
 }
+
+func TestProcessGroupCancel(t *testing.T) {
+	// Killing the process group on cancellation also kills the
+	// subprocess keeping stdout open, so Wait does not wait for it.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := helperCommandContext(t, ctx, "leakpipe", "stay")
+	c.ProcessGroup = true
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	line := make([]byte, len("leaked\n"))
+	if _, err := io.ReadFull(stdout, line); err != nil || string(line) != "leaked\n" {
+		t.Fatalf("helper said %q, %v; want leaked", line, err)
+	}
+	start := time.Now()
+	cancel()
+	if _, err := io.ReadAll(stdout); err != nil {
+		t.Error(err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		// The subprocess sleeps for 3 seconds.
+		t.Errorf("stdout closed after %v, want the subprocess to be killed", d)
+	}
+	if err := c.Wait(); err == nil {
+		t.Errorf("Wait() = nil, want an error")
+	}
+}
+
+func TestProcessGroupWaitDelay(t *testing.T) {
+	c := helperCommand(t, "leakpipe")
+	c.ProcessGroup = true
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+	c.WaitDelay = 100 * time.Millisecond
+	if err := c.Run(); err != exec.ErrWaitDelay {
+		t.Errorf("Run() = %v, want %v", err, exec.ErrWaitDelay)
+	}
+	if got := stdout.String(); got != "leaked\n" {
+		t.Errorf("output is %q, want %q", got, "leaked\n")
+	}
+}
+
+func TestProcessGroupCancelAfterWait(t *testing.T) {
+	// Once the command is reaped, the ID of its process group may be
+	// reused, so Cancel does not kill it anymore, even though the
+	// subprocess of the command is still in the group.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := helperCommandContext(t, ctx, "leakpipe")
+	c.ProcessGroup = true
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+	if err := c.Cancel(); !errors.Is(err, os.ErrProcessDone) {
+		t.Errorf("Cancel() after Wait = %v, want %v", err, os.ErrProcessDone)
+	}
+}
+
+func TestProcessGroupPgid(t *testing.T) {
+	c := helperCommand(t, "echo")
+	c.ProcessGroup = true
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pgid: os.Getpid()}
+	if err := c.Run(); err == nil {
+		t.Errorf("Run succeeded with a ProcessGroup and a non-zero Pgid")
+	}
+
+	// The command leads its own process group.
+	c = helperCommand(t, "sleep")
+	c.ProcessGroup = true
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(-c.Process.Pid, syscall.SIGKILL); err != nil {
+		t.Errorf("killing process group %d: %v", c.Process.Pid, err)
+		c.Process.Kill()
+	}
+	c.Wait()
+}
@@ -11,6 +11,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"internal/poll"
 	"internal/testenv"
@@ -21,6 +22,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strconv"
@@ -915,6 +917,31 @@ func TestHelperProcess(*testing.T) {
 	case "sleep":
 		time.Sleep(3 * time.Second)
 		os.Exit(0)
+	case "trapinterrupt":
+		// Exit successfully on interrupt, or ignore it if asked to.
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt)
+		fmt.Println("ready")
+		<-c
+		if len(args) > 0 && args[0] == "ignore" {
+			select {}
+		}
+		fmt.Println("interrupted")
+		os.Exit(0)
+	case "leakpipe":
+		// Leave stdout open in a subprocess, and wait to be killed
+		// if asked to.
+		cmd := exec.Command(os.Args[0], "-test.run=TestHelperProcess", "--", "sleep")
+		cmd.Stdout = os.Stdout
+		if err := cmd.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "Child: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("leaked")
+		if len(args) > 0 && args[0] == "stay" {
+			time.Sleep(time.Hour)
+		}
+		os.Exit(0)
 	case "pipehandle":
 		handle, _ := strconv.ParseUint(args[0], 16, 64)
 		pipe := os.NewFile(uintptr(handle), "")
@@ -1089,6 +1116,119 @@ func TestContextCancel(t *testing.T) {
 	}
 }
 
+// startInterruptible starts a helper trapping interrupts with a Cancel
+// sending an interrupt, and waits for it to be ready.
+func startInterruptible(t *testing.T, ctx context.Context, args ...string) (*exec.Cmd, *bufio.Reader) {
+	switch runtime.GOOS {
+	case "windows", "plan9":
+		t.Skipf("skipping on %s; cannot send os.Interrupt", runtime.GOOS)
+	}
+	c := helperCommandContext(t, ctx, append([]string{"trapinterrupt"}, args...)...)
+	c.Cancel = func() error {
+		return c.Process.Signal(os.Interrupt)
+	}
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(stdout)
+	if line, err := r.ReadString('\n'); line != "ready\n" {
+		t.Fatalf("helper said %q, %v; want ready", line, err)
+	}
+	return c, r
+}
+
+func TestCancelInterrupt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c, stdout := startInterruptible(t, ctx)
+	cancel()
+	if line, err := stdout.ReadString('\n'); line != "interrupted\n" {
+		t.Errorf("helper said %q, %v; want interrupted", line, err)
+	}
+	// The command exited successfully, but only because it was canceled.
+	if err := c.Wait(); err != context.Canceled {
+		t.Errorf("Wait() = %v, want %v", err, context.Canceled)
+	}
+	if !c.ProcessState.Success() {
+		t.Errorf("helper exited with %v, want success", c.ProcessState)
+	}
+}
+
+func TestCancelError(t *testing.T) {
+	// Cancel is not called once the command completed.
+	ctx, cancel := context.WithCancel(context.Background())
+	c := helperCommandContext(t, ctx, "echo")
+	c.Cancel = func() error {
+		t.Errorf("Cancel called after Wait")
+		return nil
+	}
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	c = helperCommandContext(t, ctx, "sleep")
+	cancelErr := errors.New("cancel error")
+	c.Cancel = func() error {
+		c.Process.Kill()
+		return cancelErr
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	err := c.Wait()
+	if _, ok := err.(*exec.ExitError); !ok {
+		// The error of the process takes precedence.
+		t.Errorf("Wait() = %v, want an *exec.ExitError", err)
+	}
+
+	c = helperCommand(t, "echo")
+	c.Cancel = func() error { return nil }
+	if err := c.Start(); err == nil {
+		c.Wait()
+		t.Errorf("Start succeeded with a Cancel and no context")
+	}
+}
+
+func TestWaitDelayKill(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c, _ := startInterruptible(t, ctx, "ignore")
+	c.WaitDelay = 100 * time.Millisecond
+	start := time.Now()
+	cancel()
+	err := c.Wait()
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Errorf("Wait() = %v, want an *exec.ExitError", err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("Wait took %v, want about %v", d, c.WaitDelay)
+	}
+}
+
+func TestWaitDelayPipe(t *testing.T) {
+	c := helperCommand(t, "leakpipe")
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+	c.WaitDelay = 100 * time.Millisecond
+	start := time.Now()
+	if err := c.Run(); err != exec.ErrWaitDelay {
+		t.Errorf("Run() = %v, want %v", err, exec.ErrWaitDelay)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		// The subprocess sleeps for 3 seconds.
+		t.Errorf("Run took %v, want less than the subprocess keeping stdout open", d)
+	}
+	if got := stdout.String(); got != "leaked\n" {
+		t.Errorf("output is %q, want %q", got, "leaked\n")
+	}
+}
+
 // test that environment variables are de-duped.
 func TestDedupEnvEcho(t *testing.T) {
 	testenv.MustHaveExec(t)
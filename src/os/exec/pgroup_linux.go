@@ -0,0 +1,24 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exec
+
+import (
+	"internal/syscall/unix"
+	"os"
+	"syscall"
+)
+
+// waitExited blocks until p exits, without reaping it, and reports
+// whether it did so. As long as p is not reaped, its PID, and thus the
+// ID of the process group it leads, cannot be reused.
+func waitExited(p *os.Process) bool {
+	var info unix.SiginfoChild
+	for {
+		err := unix.Waitid(unix.P_PID, p.Pid, &info, syscall.WEXITED|syscall.WNOWAIT, nil)
+		if err != syscall.EINTR {
+			return err == nil
+		}
+	}
+}
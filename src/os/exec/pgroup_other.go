@@ -0,0 +1,27 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package exec
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+func processGroupAttr(sys *syscall.SysProcAttr) (*syscall.SysProcAttr, error) {
+	return nil, errors.New("exec: ProcessGroup is not supported on " + runtime.GOOS)
+}
+
+func killProcessGroup(p *os.Process) error {
+	return p.Kill()
+}
+
+func waitExited(p *os.Process) bool {
+	return false
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package exec
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// processGroupAttr returns a copy of sys that starts the process in a
+// new process group, of which it is the leader.
+func processGroupAttr(sys *syscall.SysProcAttr) (*syscall.SysProcAttr, error) {
+	var attr syscall.SysProcAttr
+	if sys != nil {
+		if sys.Setpgid && sys.Pgid != 0 {
+			return nil, errors.New("exec: ProcessGroup set with a non-zero SysProcAttr.Pgid")
+		}
+		attr = *sys
+	}
+	attr.Setpgid = true
+	attr.Pgid = 0
+	return &attr, nil
+}
+
+// killProcessGroup kills all the processes of the process group led
+// by p. The group outlives p as long as any of its members does, but
+// once p is reaped and the group is empty, its ID may be reused: so it
+// is not killed if p was reaped, which Signal reports through the
+// pidfd of p, if any.
+func killProcessGroup(p *os.Process) error {
+	if err := p.Signal(syscall.Signal(0)); err != nil {
+		return err
+	}
+	err := syscall.Kill(-p.Pid, syscall.SIGKILL)
+	if err == syscall.ESRCH {
+		return os.ErrProcessDone
+	}
+	if err != nil {
+		return os.NewSyscallError("kill", err)
+	}
+	return nil
+}
@@ -36,10 +36,13 @@ func startProcess(name string, argv []string, attr *ProcAttr) (p *Process, err e
 		}
 	}
 
+	// On Linux, request a pidfd for the Process, unless the caller did.
+	sys, needsPidfd := ensurePidfd(attr.Sys)
+
 	sysattr := &syscall.ProcAttr{
 		Dir: attr.Dir,
 		Env: attr.Env,
-		Sys: attr.Sys,
+		Sys: sys,
 	}
 	if sysattr.Env == nil {
 		sysattr.Env, err = execenv.Default(sysattr.Sys)
@@ -61,6 +64,10 @@ func startProcess(name string, argv []string, attr *ProcAttr) (p *Process, err e
 		return nil, &PathError{Op: "fork/exec", Path: name, Err: e}
 	}
 
+	// On Windows, syscall.StartProcess returned a process handle.
+	if runtime.GOOS != "windows" {
+		h = getPidfd(sysattr.Sys, needsPidfd)
+	}
 	return newProcess(pid, h), nil
 }
 
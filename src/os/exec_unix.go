@@ -10,6 +10,7 @@ package os
 import (
 	"errors"
 	"runtime"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -18,6 +19,9 @@ func (p *Process) wait() (ps *ProcessState, err error) {
 	if p.Pid == -1 {
 		return nil, syscall.EINVAL
 	}
+	if atomic.LoadUintptr(&p.handle) != unsetHandle {
+		return p.pidfdWait()
+	}
 
 	// If we can block until Wait4 will succeed immediately, do so.
 	ready, err := p.blockUntilWaitable()
@@ -76,6 +80,9 @@ func (p *Process) signal(sig Signal) error {
 	if !ok {
 		return errors.New("os: unsupported signal type")
 	}
+	if atomic.LoadUintptr(&p.handle) != unsetHandle {
+		return p.pidfdSendSignal(s)
+	}
 	if e := syscall.Kill(p.Pid, s); e != nil {
 		if e == syscall.ESRCH {
 			return ErrProcessDone
@@ -86,7 +93,7 @@ func (p *Process) signal(sig Signal) error {
 }
 
 func (p *Process) release() error {
-	// NOOP for unix.
+	p.closeHandle()
 	p.Pid = -1
 	// no need for a finalizer anymore
 	runtime.SetFinalizer(p, nil)
@@ -94,8 +101,7 @@ func (p *Process) release() error {
 }
 
 func findProcess(pid int) (p *Process, err error) {
-	// NOOP for unix.
-	return newProcess(pid, 0), nil
+	return newProcess(pid, pidfdFind(pid)), nil
 }
 
 func (p *ProcessState) userTime() time.Duration {
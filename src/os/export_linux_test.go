@@ -4,4 +4,14 @@
 
 package os
 
+import "sync/atomic"
+
 var PollCopyFileRangeP = &pollCopyFileRange
+
+var PidfdWorks = pidfdWorks
+
+const UnsetHandle = unsetHandle
+
+func ProcessHandle(p *Process) uintptr {
+	return atomic.LoadUintptr(&p.handle)
+}
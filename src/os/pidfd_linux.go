@@ -0,0 +1,151 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Support for pidfd was added during the course of a few Linux releases:
+//  v5.1: pidfd_send_signal syscall;
+//  v5.2: CLONE_PIDFD flag for clone syscall;
+//  v5.3: pidfd_open syscall, clone3 syscall;
+//  v5.4: P_PIDFD idtype support for waitid syscall;
+//
+// A Process refers to its process with a pidfd, kept in its handle, when
+// all of these are available. Unlike a PID, a pidfd cannot be reused for
+// another process after the process is waited on, so that signaling it
+// never signals another process.
+
+package os
+
+import (
+	"internal/syscall/unix"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// ensurePidfd returns sysAttr requesting a pidfd for the new process,
+// and reports whether the pidfd is for the Process, or for the caller.
+func ensurePidfd(sysAttr *syscall.SysProcAttr) (*syscall.SysProcAttr, bool) {
+	if !pidfdWorks() {
+		return sysAttr, false
+	}
+	var pidfd int
+	if sysAttr == nil {
+		return &syscall.SysProcAttr{PidFD: &pidfd}, true
+	}
+	if sysAttr.PidFD == nil {
+		newSys := *sysAttr // copy
+		newSys.PidFD = &pidfd
+		return &newSys, true
+	}
+	// The caller requested the pidfd, and owns it.
+	return sysAttr, false
+}
+
+// getPidfd returns the handle of a process started with sysAttr
+// returned by ensurePidfd.
+func getPidfd(sysAttr *syscall.SysProcAttr, needsPidfd bool) uintptr {
+	if !needsPidfd || *sysAttr.PidFD == -1 {
+		return unsetHandle
+	}
+	return uintptr(*sysAttr.PidFD)
+}
+
+// pidfdFind returns the handle of the process pid found by FindProcess.
+func pidfdFind(pid int) uintptr {
+	if !pidfdWorks() {
+		return unsetHandle
+	}
+	h, err := unix.PidFDOpen(pid, 0)
+	if err != nil {
+		// If the process does not exist, keep reporting it
+		// when it is signaled or waited on.
+		return unsetHandle
+	}
+	return h
+}
+
+// pidfdWait waits for the process of p, which has a pidfd, to exit.
+func (p *Process) pidfdWait() (*ProcessState, error) {
+	var (
+		info   unix.SiginfoChild
+		rusage syscall.Rusage
+	)
+	// Unlike waitid(P_PID), waitid(P_PIDFD) cannot wait on another
+	// process with the same PID, so there is no need to wait for the
+	// process to be waitable before marking it done.
+	err := ignoringEINTR(func() error {
+		return unix.Waitid(unix.P_PIDFD, int(p.handle), &info, syscall.WEXITED, &rusage)
+	})
+	if err != nil {
+		return nil, NewSyscallError("waitid", err)
+	}
+	p.setDone()
+	// Wait for any active call to the signal method to complete
+	// before closing the pidfd it may use.
+	p.sigMu.Lock()
+	p.closeHandle()
+	p.sigMu.Unlock()
+	return &ProcessState{
+		pid:    int(info.Pid),
+		status: info.WaitStatus(),
+		rusage: &rusage,
+	}, nil
+}
+
+// pidfdSendSignal signals the process of p, which has a pidfd.
+func (p *Process) pidfdSendSignal(s syscall.Signal) error {
+	if err := unix.PidFDSendSignal(p.handle, s); err != nil {
+		if err == syscall.ESRCH {
+			return ErrProcessDone
+		}
+		return NewSyscallError("pidfd_send_signal", err)
+	}
+	return nil
+}
+
+// closeHandle closes the pidfd of p, if any.
+func (p *Process) closeHandle() {
+	if h := atomic.SwapUintptr(&p.handle, unsetHandle); h != unsetHandle {
+		syscall.Close(int(h))
+	}
+}
+
+var (
+	pidfdOnce      sync.Once
+	pidfdSupported bool
+)
+
+// pidfdWorks reports whether all of the pidfd functionality is available.
+func pidfdWorks() bool {
+	pidfdOnce.Do(func() {
+		pidfdSupported = checkPidfd()
+	})
+	return pidfdSupported
+}
+
+// checkPidfd checks whether all of the pidfd functionality
+// used by Process is available.
+func checkPidfd() bool {
+	// pidfd_open (Linux 5.3).
+	fd, err := unix.PidFDOpen(syscall.Getpid(), 0)
+	if err != nil {
+		return false
+	}
+	defer syscall.Close(int(fd))
+
+	// waitid with P_PIDFD (Linux 5.4): the process is not our child,
+	// which is only reported as such if P_PIDFD is supported.
+	err = ignoringEINTR(func() error {
+		return unix.Waitid(unix.P_PIDFD, int(fd), nil, syscall.WEXITED|syscall.WNOHANG, nil)
+	})
+	if err != syscall.ECHILD {
+		return false
+	}
+
+	// pidfd_send_signal (Linux 5.1), with the null signal.
+	if err := unix.PidFDSendSignal(fd, 0); err != nil {
+		return false
+	}
+	// CLONE_PIDFD (Linux 5.2) is implied by the above.
+	return true
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package os_test
+
+import (
+	"internal/testenv"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func startShell(t *testing.T, script string, sys *syscall.SysProcAttr) *os.Process {
+	t.Helper()
+	testenv.MustHaveExec(t)
+	if !os.PidfdWorks() {
+		t.Skip("pidfd is not supported")
+	}
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	p, err := os.StartProcess("/bin/sh", []string{"sh", "-c", script}, &os.ProcAttr{Sys: sys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPidfdWait(t *testing.T) {
+	p := startShell(t, "exit 3", nil)
+	if os.ProcessHandle(p) == os.UnsetHandle {
+		t.Fatal("process has no pidfd")
+	}
+	st, err := p.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Pid() != p.Pid || st.ExitCode() != 3 {
+		t.Errorf("got pid %d, exit code %d; want %d, 3", st.Pid(), st.ExitCode(), p.Pid)
+	}
+	if os.ProcessHandle(p) != os.UnsetHandle {
+		t.Errorf("pidfd not closed after Wait")
+	}
+	if err := p.Signal(os.Kill); err != os.ErrProcessDone {
+		t.Errorf("Signal after Wait: got %v, want %v", err, os.ErrProcessDone)
+	}
+}
+
+func TestPidfdSignal(t *testing.T) {
+	p := startShell(t, "sleep 60", nil)
+	if err := p.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	st, err := p.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := st.Sys().(syscall.WaitStatus)
+	if !ws.Signaled() || ws.Signal() != syscall.SIGKILL {
+		t.Errorf("got status %v, want killed", st)
+	}
+}
+
+func TestPidfdFindProcess(t *testing.T) {
+	p := startShell(t, "sleep 60", nil)
+	defer p.Wait()
+	found, err := os.FindProcess(p.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer found.Release()
+	if os.ProcessHandle(found) == os.UnsetHandle {
+		t.Fatal("found process has no pidfd")
+	}
+	if err := found.Kill(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPidfdCallerOwned(t *testing.T) {
+	pidfd := -1
+	p := startShell(t, "exit 0", &syscall.SysProcAttr{PidFD: &pidfd})
+	defer p.Wait()
+	if pidfd < 0 {
+		t.Fatalf("PidFD is %d, want a pidfd", pidfd)
+	}
+	defer syscall.Close(pidfd)
+	// The caller owns the pidfd it requested.
+	if os.ProcessHandle(p) != os.UnsetHandle {
+		t.Errorf("process uses the pidfd of the caller")
+	}
+}
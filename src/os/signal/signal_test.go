@@ -78,7 +78,14 @@ func waitSigAll(t *testing.T, c <-chan os.Signal, sig os.Signal) {
 
 func waitSig1(t *testing.T, c <-chan os.Signal, sig os.Signal, all bool) {
 	t.Helper()
+	if err := waitSigErr(c, sig, all); err != nil {
+		t.Fatal(err)
+	}
+}
 
+// waitSigErr is like waitSig1, but returns an error instead of calling
+// t.Fatal, for goroutines other than the one running the test.
+func waitSigErr(c <-chan os.Signal, sig os.Signal, all bool) error {
 	// Sleep multiple times to give the kernel more tries to
 	// deliver the signal.
 	start := time.Now()
@@ -93,16 +100,16 @@ func waitSig1(t *testing.T, c <-chan os.Signal, sig os.Signal, all bool) {
 		select {
 		case s := <-c:
 			if s == sig {
-				return
+				return nil
 			}
 			if !all || s != syscall.SIGURG {
-				t.Fatalf("signal was %v, want %v", s, sig)
+				return fmt.Errorf("signal was %v, want %v", s, sig)
 			}
 		case <-timer.C:
 			timer.Reset(settleTime / 10)
 		}
 	}
-	t.Fatalf("timeout after %v waiting for %v", fatalWaitingTime, sig)
+	return fmt.Errorf("timeout after %v waiting for %v", fatalWaitingTime, sig)
 }
 
 // quiesce waits until we can be reasonably confident that all pending signals
@@ -890,7 +897,10 @@ func TestSignalTrace(t *testing.T) {
 			default:
 				syscall.Kill(pid, syscall.SIGHUP)
 			}
-			waitSig(t, c, syscall.SIGHUP)
+			if err := waitSigErr(c, syscall.SIGHUP, false); err != nil {
+				t.Error(err)
+				return
+			}
 		}
 	}()
 
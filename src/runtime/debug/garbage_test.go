@@ -6,6 +6,7 @@ package debug_test
 
 import (
 	"internal/testenv"
+	"math"
 	"runtime"
 	. "runtime/debug"
 	"testing"
@@ -172,6 +173,36 @@ func TestSetGCPercent(t *testing.T) {
 	}
 }
 
+func TestSetMemoryLimit(t *testing.T) {
+	// Test that the limit is being set and returned correctly.
+	old := SetMemoryLimit(123 << 20)
+	defer SetMemoryLimit(old)
+	if got := SetMemoryLimit(-1); got != 123<<20 {
+		t.Errorf("SetMemoryLimit(123<<20); SetMemoryLimit(-1) = %d, want %d", got, 123<<20)
+	}
+	if got := SetMemoryLimit(old); got != 123<<20 {
+		t.Errorf("SetMemoryLimit(123<<20); SetMemoryLimit(x) = %d, want %d", got, 123<<20)
+	}
+	if got := SetMemoryLimit(-1); got != old {
+		t.Errorf("SetMemoryLimit(x); SetMemoryLimit(-1) = %d, want %d", got, old)
+	}
+
+	// Test that the limit bounds the heap goal, even with GOGC=off.
+	defer SetGCPercent(SetGCPercent(-1))
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	if ms.NextGC != ^uint64(0) && old == math.MaxInt64 {
+		t.Fatalf("GOGC=off and no memory limit: NextGC = %d, want %d", ms.NextGC, ^uint64(0))
+	}
+	const limit = 64 << 20
+	SetMemoryLimit(limit)
+	runtime.ReadMemStats(&ms)
+	if ms.NextGC > limit {
+		t.Errorf("GOGC=off and memory limit %d MB: NextGC = %d MB, want at most %d MB", limit>>20, ms.NextGC>>20, limit>>20)
+	}
+}
+
 func abs64(a int64) int64 {
 	if a < 0 {
 		return -a
@@ -32,6 +32,22 @@ func TestGcSys(t *testing.T) {
 	}
 }
 
+func TestGCMemoryLimit(t *testing.T) {
+	got := runTestProg(t, "testprog", "GCMemoryLimit", "GOGC=off", "GOMEMLIMIT=64MiB")
+	want := "OK\n"
+	if got != want {
+		t.Fatalf("expected %q, but got %q", want, got)
+	}
+}
+
+func TestGCMemoryLimitGrowth(t *testing.T) {
+	got := runTestProg(t, "testprog", "GCMemoryLimitGrowth", "GOGC=off", "GOMEMLIMIT=64MiB")
+	want := "OK\n"
+	if got != want {
+		t.Fatalf("expected %q, but got %q", want, got)
+	}
+}
+
 func TestGcDeepNesting(t *testing.T) {
 	type T [2][2][2][2][2][2][2][2][2][2]*int
 	a := new(T)
@@ -73,6 +73,7 @@ func TestLFStack(t *testing.T) {
 	if *stack != 0 {
 		t.Fatalf("stack is not empty")
 	}
+	KeepAlive(nodes)
 }
 
 var stress []*MyNode
@@ -0,0 +1,22 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !libfuzzer
+// +build !libfuzzer
+
+package runtime
+
+// The compiler inserts calls to these functions around integer comparisons
+// when building with -d=libfuzzer. Without the libfuzzer build tag there is
+// no libFuzzer runtime to report to: 'go test -fuzz' only consumes the
+// edge counters, so the comparison hooks do nothing.
+
+func libfuzzerTraceCmp1(arg0, arg1 uint8)       {}
+func libfuzzerTraceCmp2(arg0, arg1 uint16)      {}
+func libfuzzerTraceCmp4(arg0, arg1 uint32)      {}
+func libfuzzerTraceCmp8(arg0, arg1 uint64)      {}
+func libfuzzerTraceConstCmp1(arg0, arg1 uint8)  {}
+func libfuzzerTraceConstCmp2(arg0, arg1 uint16) {}
+func libfuzzerTraceConstCmp4(arg0, arg1 uint32) {}
+func libfuzzerTraceConstCmp8(arg0, arg1 uint64) {}
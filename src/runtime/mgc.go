@@ -158,7 +158,7 @@ func gcinit() {
 
 	// Initialize GC pacer state.
 	// Use the environment variable GOGC for the initial gcPercent value.
-	gcController.init(readGOGC())
+	gcController.init(readGOGC(), readGOMEMLIMIT())
 
 	work.startSema = 1
 	work.markDoneSema = 1
@@ -613,6 +613,13 @@ func gcStart(trigger gcTrigger) {
 		mode = gcForceBlockMode
 	}
 
+	// A goroutine leak detection cycle must keep the blocked
+	// goroutines blocked, so it does not run user goroutines.
+	goroutineLeak.enabled = atomic.Load(&goroutineLeak.requests) != 0
+	if goroutineLeak.enabled && mode == gcBackgroundMode {
+		mode = gcForceBlockMode
+	}
+
 	// Ok, we're doing it! Stop everybody else
 	semacquire(&gcsema)
 	semacquire(&worldsema)
@@ -671,6 +678,10 @@ func gcStart(trigger gcTrigger) {
 		schedEnableUser(false)
 	}
 
+	if goroutineLeak.enabled {
+		gcLeakPrepare()
+	}
+
 	// Enter concurrent mark phase and enable
 	// write barriers.
 	//
@@ -859,6 +870,12 @@ top:
 				break
 			}
 		}
+		// In a leak detection cycle, marking may make more
+		// blocked goroutines reachable. Scan them, and resume
+		// marking.
+		if !restart && goroutineLeak.enabled {
+			restart = gcFindLeaks()
+		}
 	})
 	if restart {
 		getg().m.preemptoff = ""
@@ -903,6 +920,11 @@ func gcMarkTermination(nextTriggerRatio float64) {
 	// Start marktermination (write barrier remains enabled for now).
 	setGCPhase(_GCmarktermination)
 
+	if goroutineLeak.enabled {
+		goroutineLeak.enabled = false
+		atomic.Store(&goroutineLeak.cycle, work.cycles)
+	}
+
 	work.heap1 = gcController.heapLive
 	startTime := nanotime()
 
@@ -970,6 +992,7 @@ func gcMarkTermination(nextTriggerRatio float64) {
 	memstats.last_heap_inuse = memstats.heap_inuse
 
 	// Update GC trigger and pacing for the next cycle.
+	gcController.updateNonHeapInUse()
 	gcController.commit(nextTriggerRatio)
 
 	// Update timing memstats
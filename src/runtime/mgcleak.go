@@ -0,0 +1,193 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Garbage collector: goroutine leak detection.
+//
+// A goroutine blocked on a channel, a semaphore or a notify list can
+// only be woken by a goroutine that reaches the object it is blocked on.
+// If that object is unreachable from the roots and from the stacks of
+// the goroutines that can run again, the goroutine is leaked: it can
+// never be woken.
+//
+// A leak detection cycle is a GC cycle with user goroutines descheduled,
+// as in gcForceBlockMode, so the blocked goroutines stay blocked and
+// their stacks do not change. In sweep termination, gcLeakPrepare
+// selects the candidate goroutines, blocked on such objects. Their
+// stacks are not scanned as roots, and their sudogs are marked without
+// being scanned, so the objects they are blocked on are only marked if
+// they are reachable some other way. Whenever marking completes,
+// gcFindLeaks scans the stacks and sudogs of the candidates blocked on
+// a marked object, which may make more objects reachable, and marking
+// resumes. When no candidate is blocked on a marked object, the
+// remaining candidates are leaked. gcFindLeaks then scans them too, so
+// that no memory they reach is freed, and the cycle completes as usual.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+var goroutineLeak struct {
+	// requests is the number of goroutines waiting in
+	// detectGoroutineLeaks. Every GC cycle starting while it
+	// is non-zero detects leaks. Accessed atomically.
+	requests uint32
+
+	// enabled is set if the current GC cycle detects leaks.
+	// It is set in sweep termination and cleared in mark
+	// termination.
+	enabled bool
+
+	// cycle is the last GC cycle that detected leaks.
+	// Accessed atomically.
+	cycle uint32
+}
+
+// detectGoroutineLeaks runs a GC cycle that detects leaked goroutines,
+// and sets g.leaked for them. It blocks the calling goroutine until the
+// cycle completes.
+func detectGoroutineLeaks() {
+	atomic.Xadd(&goroutineLeak.requests, 1)
+	// A cycle that already started may not detect leaks, or may have
+	// started before the goroutines the caller is interested in leaked.
+	n := atomic.Load(&work.cycles)
+	for atomic.Load(&goroutineLeak.cycle) <= n {
+		c := atomic.Load(&work.cycles)
+		gcWaitOnMark(c)
+		gcStart(gcTrigger{kind: gcTriggerCycle, n: c + 1})
+		gcWaitOnMark(c + 1)
+	}
+	atomic.Xadd(&goroutineLeak.requests, -1)
+}
+
+// gcLeakPrepare selects the candidate goroutines of a leak detection
+// cycle and marks their sudogs.
+//
+// The world must be stopped, and mark bits must have been cleared.
+func gcLeakPrepare() {
+	forEachGRace(func(gp *g) {
+		gp.leaked = false
+		if readgstatus(gp) != _Gwaiting || isSystemGoroutine(gp, false) {
+			return
+		}
+		switch gp.waitreason {
+		case waitReasonChanReceive, waitReasonChanSend, waitReasonSelect,
+			waitReasonChanReceiveNilChan, waitReasonChanSendNilChan,
+			waitReasonSemacquire, waitReasonSyncCondWait:
+		default:
+			// A goroutine blocked in select with no cases
+			// is blocked on purpose.
+			return
+		}
+		gp.leakCandidate = true
+		for sg := gp.waiting; sg != nil; sg = sg.waitlink {
+			gcLeakMarkSudog(sg)
+		}
+		if sg := gp.syncsudog; sg != nil {
+			gcLeakMarkSudog(sg)
+		}
+	})
+}
+
+// gcLeakMarkSudog marks sg without queuing it for scanning.
+func gcLeakMarkSudog(sg *sudog) {
+	p := uintptr(unsafe.Pointer(sg))
+	s := spanOfHeap(p)
+	if s == nil {
+		throw("leak detection: sudog not in heap")
+	}
+	s.markBitsForIndex(s.objIndex(p)).setMarked()
+	arena, pageIdx, pageMask := pageIndexOf(s.base())
+	if arena.pageMarks[pageIdx]&pageMask == 0 {
+		atomic.Or8(&arena.pageMarks[pageIdx], pageMask)
+	}
+}
+
+// gcFindLeaks is called when marking completes in a leak detection
+// cycle. It scans the candidates that can be woken or, if there are
+// none, finds the leaked goroutines and scans them. It reports whether
+// it scanned any goroutine, in which case marking must resume.
+//
+// The world must be stopped. gcFindLeaks must run on the system stack
+// because it scans stacks.
+//
+//go:systemstack
+func gcFindLeaks() bool {
+	// Put the user G in _Gwaiting, as suspendG requires.
+	userG := getg().m.curg
+	casgstatus(userG, _Grunning, _Gwaiting)
+	userG.waitreason = waitReasonGarbageCollectionScan
+	defer casgstatus(userG, _Gwaiting, _Grunning)
+
+	gcw := &getg().m.p.ptr().gcw
+	woken := false
+	forEachGRace(func(gp *g) {
+		// A candidate that is no longer blocked was readied,
+		// for example by a timer.
+		if gp.leakCandidate && (readgstatus(gp) != _Gwaiting || gcLeakReachable(gp)) {
+			gcLeakScan(gp, gcw)
+			woken = true
+		}
+	})
+	if woken {
+		return true
+	}
+
+	// Nothing reachable is left to wake the remaining candidates.
+	leaked := false
+	forEachGRace(func(gp *g) {
+		if gp.leakCandidate {
+			gp.leaked = true
+			gcLeakScan(gp, gcw)
+			leaked = true
+		}
+	})
+	return leaked
+}
+
+// gcLeakReachable reports whether an object gp is blocked on is marked.
+func gcLeakReachable(gp *g) bool {
+	for sg := gp.waiting; sg != nil; sg = sg.waitlink {
+		if sg.c != nil && gcLeakMarked(uintptr(unsafe.Pointer(sg.c))) {
+			return true
+		}
+	}
+	if sg := gp.syncsudog; sg != nil && gcLeakMarked(uintptr(sg.elem)) {
+		return true
+	}
+	return false
+}
+
+// gcLeakMarked reports whether the object containing p is marked.
+// Objects outside the heap, such as globals, are always reachable.
+func gcLeakMarked(p uintptr) bool {
+	s := spanOfHeap(p)
+	if s == nil {
+		return true
+	}
+	return s.markBitsForIndex(s.objIndex(p)).isMarked()
+}
+
+// gcLeakScan scans the stack and the sudogs of the candidate gp.
+func gcLeakScan(gp *g, gcw *gcWork) {
+	gp.leakCandidate = false
+	for sg := gp.waiting; sg != nil; sg = sg.waitlink {
+		scanobject(uintptr(unsafe.Pointer(sg)), gcw)
+	}
+	if sg := gp.syncsudog; sg != nil {
+		scanobject(uintptr(unsafe.Pointer(sg)), gcw)
+	}
+	stopped := suspendG(gp)
+	if stopped.dead {
+		throw("leak detection: candidate goroutine exited")
+	}
+	if gp.gcscandone {
+		throw("g already scanned")
+	}
+	scanstack(gp, gcw)
+	gp.gcscandone = true
+	resumeG(stopped)
+}
@@ -201,6 +201,12 @@ func markroot(gcw *gcWork, i uint32) {
 			gp.waitsince = work.tstart
 		}
 
+		// The stack of a candidate of leak detection is
+		// scanned once it is known whether it can be woken.
+		if gp.leakCandidate {
+			return
+		}
+
 		// scanstack must be done on the system stack in case
 		// we're trying to scan our own stack.
 		systemstack(func() {
@@ -47,6 +47,28 @@ const (
 
 	// defaultHeapMinimum is the value of heapMinimum for GOGC==100.
 	defaultHeapMinimum = 4 << 20
+
+	// memoryLimitHeapGoalHeadroom is the amount of headroom the pacer
+	// leaves between the memory-limit-based heap goal and the limit
+	// itself, as a fraction of the limit. It absorbs the fact that the
+	// non-heap memory estimate is only refreshed once per GC cycle and
+	// that the heap goal is not a hard bound.
+	memoryLimitHeapGoalHeadroom = 0.03
+
+	// gcCPULimiterMax is the fraction of total CPU time the GC may
+	// consume across a whole cycle before the pacer stops tightening
+	// the heap goal to stay under the memory limit. Exceeding the
+	// memory limit is preferable to a GC death spiral, in which the
+	// application makes little or no progress because it is always
+	// collecting.
+	gcCPULimiterMax = 0.5
+
+	// memoryLimitMinHeapGrowth and memoryLimitLimitedHeapGrowth are
+	// the smallest heap growth, as a fraction of the marked heap, that
+	// the memory limit may impose on the next cycle. The latter applies
+	// while the GC CPU limiter is engaged.
+	memoryLimitMinHeapGrowth     = 1.0 / 16
+	memoryLimitLimitedHeapGrowth = 1.0 / 2
 )
 
 func init() {
@@ -54,6 +76,10 @@ func init() {
 		println(offset)
 		throw("gcController.heapLive not aligned to 8 bytes")
 	}
+	if offset := unsafe.Offsetof(gcController.memoryLimit); offset%8 != 0 {
+		println(offset)
+		throw("gcController.memoryLimit not aligned to 8 bytes")
+	}
 }
 
 // gcController implements the GC pacing controller that determines
@@ -77,6 +103,39 @@ type gcControllerState struct {
 
 	_ uint32 // padding so following 64-bit values are 8-byte aligned
 
+	// memoryLimit is the soft memory limit in bytes.
+	//
+	// Initialized from $GOMEMLIMIT. A value of maxInt64 means no limit.
+	// The pacer counts all memory mapped and in use by the runtime
+	// against this limit, not just the heap.
+	//
+	// Written with mheap_.lock held or the world stopped,
+	// and read atomically.
+	memoryLimit int64
+
+	// nonHeapInUse is the number of bytes carved out of the heap for
+	// non-heap purposes, such as goroutine stacks, GC work buffers and
+	// unrolled GC program bits, as of the last mark termination.
+	//
+	// Together with the other runtime *_sys statistics it forms the
+	// non-heap portion of the memory counted against memoryLimit.
+	//
+	// Written with the world stopped.
+	nonHeapInUse uint64
+
+	// lastCycleEnd is the nanotime at which the previous GC cycle's
+	// mark phase ended. It is used to compute the fraction of CPU time
+	// spent on the GC over a whole cycle.
+	lastCycleEnd int64
+
+	// gcCPULimited indicates that during the last cycle the GC used
+	// more than gcCPULimiterMax of the available CPU time. While set,
+	// the pacer gives the heap more room than the memory limit alone
+	// would allow, to avoid a GC death spiral.
+	//
+	// Written with the world stopped.
+	gcCPULimited bool
+
 	// heapMinimum is the minimum heap size at which to trigger GC.
 	// For small heaps, this overrides the usual GOGC*live set rule.
 	//
@@ -256,8 +315,9 @@ type gcControllerState struct {
 	_ cpu.CacheLinePad
 }
 
-func (c *gcControllerState) init(gcPercent int32) {
+func (c *gcControllerState) init(gcPercent int32, memoryLimit int64) {
 	c.heapMinimum = defaultHeapMinimum
+	c.memoryLimit = memoryLimit
 
 	// Set a reasonable initial GC trigger.
 	c.triggerRatio = 7 / 8.0
@@ -447,6 +507,18 @@ func (c *gcControllerState) revise() {
 // userForced indicates whether the current GC cycle was forced
 // by the application.
 func (c *gcControllerState) endCycle(userForced bool) float64 {
+	// Compute the fraction of CPU time spent on the GC since the
+	// end of the previous cycle and engage the GC CPU limiter if
+	// it exceeds gcCPULimiterMax. Idle marking is not counted,
+	// since it only uses otherwise unused CPU time.
+	now := nanotime()
+	if c.lastCycleEnd != 0 && now > c.lastCycleEnd {
+		gcTime := c.assistTime + c.dedicatedMarkTime + c.fractionalMarkTime
+		totalTime := (now - c.lastCycleEnd) * int64(gomaxprocs)
+		c.gcCPULimited = float64(gcTime)/float64(totalTime) > gcCPULimiterMax
+	}
+	c.lastCycleEnd = now
+
 	if userForced {
 		// Forced GC means this cycle didn't start at the
 		// trigger, so where it finished isn't good
@@ -473,7 +545,7 @@ func (c *gcControllerState) endCycle(userForced bool) float64 {
 	// heap growth is the error.
 	goalGrowthRatio := c.effectiveGrowthRatio()
 	actualGrowthRatio := float64(c.heapLive)/float64(c.heapMarked) - 1
-	assistDuration := nanotime() - c.markStartTime
+	assistDuration := now - c.markStartTime
 
 	// Assume background mark hit its utilization goal.
 	utilization := gcBackgroundUtilization
@@ -510,6 +582,7 @@ func (c *gcControllerState) endCycle(userForced bool) float64 {
 			" goalΔ=", goalGrowthRatio-h_t,
 			" actualΔ=", h_a-h_t,
 			" u_a/u_g=", u_a/u_g,
+			" limited=", c.gcCPULimited,
 			"\n")
 	}
 
@@ -658,9 +731,21 @@ func (c *gcControllerState) commit(triggerRatio float64) {
 		goal = c.heapMarked + c.heapMarked*uint64(c.gcPercent)/100
 	}
 
+	// If the memory limit implies a lower goal, use it instead.
+	// This is also what drives collection when GOGC=off and a
+	// memory limit is set.
+	scalingFactor := float64(c.gcPercent) / 100
+	if limitGoal := c.memoryLimitHeapGoal(); limitGoal < goal {
+		goal = limitGoal
+		scalingFactor = 1
+		if c.heapMarked > 0 {
+			scalingFactor = float64(goal-c.heapMarked) / float64(c.heapMarked)
+		}
+	}
+	enabled := goal != ^uint64(0)
+
 	// Set the trigger ratio, capped to reasonable bounds.
-	if c.gcPercent >= 0 {
-		scalingFactor := float64(c.gcPercent) / 100
+	if enabled {
 		// Ensure there's always a little margin so that the
 		// mutator assist ratio isn't infinity.
 		maxTriggerRatio := 0.95 * scalingFactor
@@ -685,9 +770,9 @@ func (c *gcControllerState) commit(triggerRatio float64) {
 			triggerRatio = minTriggerRatio
 		}
 	} else if triggerRatio < 0 {
-		// gcPercent < 0, so just make sure we're not getting a negative
+		// GC is disabled, so just make sure we're not getting a negative
 		// triggerRatio. This case isn't expected to happen in practice,
-		// and doesn't really matter because if gcPercent < 0 then we won't
+		// and doesn't really matter because if GC is disabled then we won't
 		// ever consume triggerRatio further on in this function, but let's
 		// just be defensive here; the triggerRatio being negative is almost
 		// certainly undesirable.
@@ -700,7 +785,7 @@ func (c *gcControllerState) commit(triggerRatio float64) {
 	// We trigger the next GC cycle when the allocated heap has
 	// grown by the trigger ratio over the marked heap size.
 	trigger := ^uint64(0)
-	if c.gcPercent >= 0 {
+	if enabled {
 		trigger = uint64(float64(c.heapMarked) * (1 + triggerRatio))
 		// Don't trigger below the minimum heap size.
 		minTrigger := c.heapMinimum
@@ -799,6 +884,65 @@ func (c *gcControllerState) effectiveGrowthRatio() float64 {
 	return egogc
 }
 
+// memoryLimitHeapGoal returns the heap goal implied by the memory
+// limit, or ^uint64(0) if there is no memory limit.
+//
+// All memory mapped and in use by the runtime counts against the
+// limit, so the goal is the limit minus the runtime's non-heap
+// memory, minus some headroom. Memory the heap has retained but is
+// not using is not counted: the scavenger is responsible for
+// returning it to the OS (see gcPaceScavenger and mheap.grow).
+//
+// The goal is never allowed to be so low that the GC would run
+// continuously. If the live heap alone is close to or above the
+// limit, the heap is allowed to grow past it, and it is allowed to
+// grow further still while the GC CPU limiter is engaged.
+//
+// mheap_.lock must be held or the world must be stopped.
+func (c *gcControllerState) memoryLimitHeapGoal() uint64 {
+	limit := atomic.Loadint64(&c.memoryLimit)
+	if limit == maxInt64 {
+		return ^uint64(0)
+	}
+	goal := uint64(0)
+	nonHeap := c.nonHeapMemory()
+	headroom := uint64(float64(limit) * memoryLimitHeapGoalHeadroom)
+	if uint64(limit) > nonHeap+headroom {
+		goal = uint64(limit) - nonHeap - headroom
+	}
+
+	minGrowth := memoryLimitMinHeapGrowth
+	if c.gcCPULimited {
+		minGrowth = memoryLimitLimitedHeapGrowth
+	}
+	if minGoal := c.heapMarked + uint64(float64(c.heapMarked)*minGrowth); goal < minGoal {
+		goal = minGoal
+	}
+	return goal
+}
+
+// nonHeapMemory returns an estimate of the number of bytes mapped and
+// in use by the runtime for purposes other than the heap.
+func (c *gcControllerState) nonHeapMemory() uint64 {
+	return c.nonHeapInUse +
+		memstats.stacks_sys.load() +
+		memstats.mspan_sys.load() +
+		memstats.mcache_sys.load() +
+		memstats.buckhash_sys.load() +
+		memstats.gcMiscSys.load() +
+		memstats.other_sys.load()
+}
+
+// updateNonHeapInUse refreshes nonHeapInUse from the consistent
+// heap statistics.
+//
+// The world must be stopped.
+func (c *gcControllerState) updateNonHeapInUse() {
+	var stats heapStatsDelta
+	memstats.heapStats.unsafeRead(&stats)
+	c.nonHeapInUse = uint64(stats.inStacks + stats.inWorkBufs + stats.inPtrScalarBits)
+}
+
 // setGCPercent updates gcPercent and all related pacer state.
 // Returns the old value of gcPercent.
 //
@@ -811,7 +955,10 @@ func (c *gcControllerState) setGCPercent(in int32) int32 {
 		in = -1
 	}
 	c.gcPercent = in
-	c.heapMinimum = defaultHeapMinimum * uint64(c.gcPercent) / 100
+	c.heapMinimum = defaultHeapMinimum
+	if in >= 0 {
+		c.heapMinimum = defaultHeapMinimum * uint64(c.gcPercent) / 100
+	}
 	// Update pacing in response to gcPercent change.
 	c.commit(c.triggerRatio)
 
@@ -836,6 +983,34 @@ func setGCPercent(in int32) (out int32) {
 	return out
 }
 
+// setMemoryLimit updates memoryLimit and all related pacer state.
+// Returns the old value of memoryLimit. A negative input leaves the
+// limit unchanged.
+//
+// The world must be stopped, or mheap_.lock must be held.
+func (c *gcControllerState) setMemoryLimit(in int64) int64 {
+	assertWorldStoppedOrLockHeld(&mheap_.lock)
+
+	out := c.memoryLimit
+	if in >= 0 {
+		atomic.Storeint64(&c.memoryLimit, in)
+		// Update pacing in response to the memory limit change.
+		c.commit(c.triggerRatio)
+	}
+	return out
+}
+
+//go:linkname setMemoryLimit runtime/debug.setMemoryLimit
+func setMemoryLimit(in int64) (out int64) {
+	// Run on the system stack since we grab the heap lock.
+	systemstack(func() {
+		lock(&mheap_.lock)
+		out = gcController.setMemoryLimit(in)
+		unlock(&mheap_.lock)
+	})
+	return out
+}
+
 func readGOGC() int32 {
 	p := gogetenv("GOGC")
 	if p == "off" {
@@ -846,3 +1021,16 @@ func readGOGC() int32 {
 	}
 	return 100
 }
+
+func readGOMEMLIMIT() int64 {
+	p := gogetenv("GOMEMLIMIT")
+	if p == "" || p == "off" {
+		return maxInt64
+	}
+	n, ok := parseByteCount(p)
+	if !ok {
+		print("GOMEMLIMIT=", p, "\n")
+		throw("malformed GOMEMLIMIT; see `go doc runtime/debug.SetMemoryLimit`")
+	}
+	return n
+}
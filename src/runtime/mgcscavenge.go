@@ -41,6 +41,12 @@
 // that there's more unscavenged memory to allocate out of, since each allocation
 // out of scavenged memory incurs a potentially expensive page fault.
 //
+// If a memory limit is set (see runtime/debug.SetMemoryLimit), the goal is
+// further capped so that the heap's retained memory plus the runtime's non-heap
+// memory stays below retainedLimitPercent of the limit. Because that goal is
+// only updated after each GC, heap growth that would exceed the limit between
+// GCs also scavenges synchronously (see mheap.grow).
+//
 // The goal is updated after each GC and the scavenger's pacing parameters
 // (which live in mheap_) are updated to match. The pacing parameters work much
 // like the background sweeping parameters. The parameters define a line whose
@@ -78,6 +84,12 @@ const (
 	// the ever-changing layout of the heap.
 	retainExtraPercent = 10
 
+	// retainedLimitPercent is the percent of the memory limit that the
+	// scavenger aims to keep the runtime's total retained memory under,
+	// if a memory limit is set. It leaves some slack below the limit so
+	// that the GC, not the scavenger, is what keeps the heap in check.
+	retainedLimitPercent = 95
+
 	// maxPagesPerPhysPage is the maximum number of supported runtime pages per
 	// physical page, based on maxPhysPageSize.
 	maxPagesPerPhysPage = maxPhysPageSize / pageSize
@@ -131,6 +143,21 @@ func gcPaceScavenger() {
 	// (e.g. if retainExtraPercent = 12.5, then we get a divisor of 8)
 	// that also avoids the overflow from a multiplication.
 	retainedGoal += retainedGoal / (1.0 / (retainExtraPercent / 100.0))
+
+	// If there's a memory limit, the heap's contribution to RSS must
+	// also stay below the limit, less the runtime's non-heap memory.
+	// Aim a little below the limit so that the GC, which paces against
+	// the limit too, isn't left with no room at all.
+	if limit := atomic.Loadint64(&gcController.memoryLimit); limit != maxInt64 {
+		limitGoal := uint64(0)
+		if l, nonHeap := uint64(float64(limit)*retainedLimitPercent/100), gcController.nonHeapMemory(); l > nonHeap {
+			limitGoal = l - nonHeap
+		}
+		if limitGoal < retainedGoal {
+			retainedGoal = limitGoal
+		}
+	}
+
 	// Align it to a physical page boundary to make the following calculations
 	// a bit more exact.
 	retainedGoal = (retainedGoal + uint64(physPageSize) - 1) &^ (uint64(physPageSize) - 1)
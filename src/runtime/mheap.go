@@ -1412,12 +1412,25 @@ func (h *mheap) grow(npage uintptr) bool {
 	// By scavenging inline we deal with the failure to allocate out of
 	// memory fragments by scavenging the memory fragments that are least
 	// likely to be re-used.
-	if retained := heapRetained(); retained+uint64(totalGrowth) > h.scavengeGoal {
+	retained := heapRetained()
+	if retained+uint64(totalGrowth) > h.scavengeGoal {
 		todo := totalGrowth
 		if overage := uintptr(retained + uint64(totalGrowth) - h.scavengeGoal); todo > overage {
 			todo = overage
 		}
 		h.pages.scavenge(todo, false)
+		retained = heapRetained()
+	}
+
+	// If the growth would take the runtime's memory past the memory
+	// limit, return the excess to the OS now. The scavenge goal is only
+	// updated at the end of each GC cycle and the background scavenger
+	// is rate-limited, so neither keeps up with an allocation burst.
+	if limit := atomic.Loadint64(&gcController.memoryLimit); limit != maxInt64 {
+		inUse := retained + gcController.nonHeapMemory() + uint64(totalGrowth)
+		if inUse > uint64(limit) {
+			h.pages.scavenge(uintptr(inUse-uint64(limit)), false)
+		}
 	}
 	return true
 }
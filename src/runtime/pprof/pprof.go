@@ -100,16 +100,25 @@ import (
 //
 // Each Profile has a unique name. A few profiles are predefined:
 //
-//	goroutine    - stack traces of all current goroutines
-//	heap         - a sampling of memory allocations of live objects
-//	allocs       - a sampling of all past memory allocations
-//	threadcreate - stack traces that led to the creation of new OS threads
-//	block        - stack traces that led to blocking on synchronization primitives
-//	mutex        - stack traces of holders of contended mutexes
+//	goroutine     - stack traces of all current goroutines
+//	goroutineleak - stack traces of goroutines that can never be woken
+//	heap          - a sampling of memory allocations of live objects
+//	allocs        - a sampling of all past memory allocations
+//	threadcreate  - stack traces that led to the creation of new OS threads
+//	block         - stack traces that led to blocking on synchronization primitives
+//	mutex         - stack traces of holders of contended mutexes
 //
 // These predefined profiles maintain themselves and panic on an explicit
 // Add or Remove method call.
 //
+// The goroutineleak profile reports the goroutines blocked on a channel,
+// sync.Mutex, sync.RWMutex, sync.WaitGroup or sync.Cond that nothing can
+// unblock anymore, because the object they are blocked on is unreachable
+// from the goroutines that can still run. Writing it runs a garbage
+// collection during which no goroutine runs; Count returns the number of
+// goroutines found when the profile was last written. Each stack of the
+// profile ends with the go statement that created the goroutine.
+//
 // The heap profile reports statistics as of the most recently completed
 // garbage collection; it elides more recent allocation to avoid skewing
 // the profile away from live data and toward garbage.
@@ -151,6 +160,12 @@ var goroutineProfile = &Profile{
 	write: writeGoroutine,
 }
 
+var goroutineLeakProfile = &Profile{
+	name:  "goroutineleak",
+	count: countGoroutineLeak,
+	write: writeGoroutineLeak,
+}
+
 var threadcreateProfile = &Profile{
 	name:  "threadcreate",
 	count: countThreadCreate,
@@ -186,12 +201,13 @@ func lockProfiles() {
 	if profiles.m == nil {
 		// Initial built-in profiles.
 		profiles.m = map[string]*Profile{
-			"goroutine":    goroutineProfile,
-			"threadcreate": threadcreateProfile,
-			"heap":         heapProfile,
-			"allocs":       allocsProfile,
-			"block":        blockProfile,
-			"mutex":        mutexProfile,
+			"goroutine":     goroutineProfile,
+			"goroutineleak": goroutineLeakProfile,
+			"threadcreate":  threadcreateProfile,
+			"heap":          heapProfile,
+			"allocs":        allocsProfile,
+			"block":         blockProfile,
+			"mutex":         mutexProfile,
 		}
 	}
 }
@@ -685,13 +701,40 @@ func writeGoroutine(w io.Writer, debug int) error {
 	return writeRuntimeProfile(w, debug, "goroutine", runtime_goroutineProfileWithLabels)
 }
 
+// runtime_goroutineLeakDetect, runtime_goroutineLeakProfileWithLabels and
+// runtime_goroutineLeakStacks are defined in runtime/mprof.go
+func runtime_goroutineLeakDetect()
+func runtime_goroutineLeakProfileWithLabels(p []runtime.StackRecord, labels []unsafe.Pointer) (n int, ok bool)
+func runtime_goroutineLeakStacks(buf []byte) int
+
+func countGoroutineLeak() int {
+	n, _ := runtime_goroutineLeakProfileWithLabels(nil, nil)
+	return n
+}
+
+// writeGoroutineLeak finds the leaked goroutines and writes their stacks to w.
+func writeGoroutineLeak(w io.Writer, debug int) error {
+	runtime_goroutineLeakDetect()
+	if debug >= 2 {
+		return writeStacks(w, runtime_goroutineLeakStacks)
+	}
+	return writeRuntimeProfile(w, debug, "goroutineleak", runtime_goroutineLeakProfileWithLabels)
+}
+
 func writeGoroutineStacks(w io.Writer) error {
+	return writeStacks(w, func(buf []byte) int {
+		return runtime.Stack(buf, true)
+	})
+}
+
+// writeStacks writes to w the tracebacks stack writes to a buffer.
+func writeStacks(w io.Writer, stack func([]byte) int) error {
 	// We don't know how big the buffer needs to be to collect
 	// all the goroutines. Start with 1 MB and try a few times, doubling each time.
 	// Give up and use a truncated trace if 64 MB is not enough.
 	buf := make([]byte, 1<<20)
 	for i := 0; ; i++ {
-		n := runtime.Stack(buf, true)
+		n := stack(buf)
 		if n < len(buf) {
 			buf = buf[:n]
 			break
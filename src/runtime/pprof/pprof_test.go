@@ -1131,6 +1131,172 @@ func TestGoroutineCounts(t *testing.T) {
 	time.Sleep(10 * time.Millisecond) // let goroutines exit
 }
 
+// The goroutines created by the leak functions can never be woken.
+// The objects they block on are allocated with a pointer next to them,
+// as the tiny allocator may put a pointer-free object in a block that
+// is reachable for holding other objects.
+
+func leakRecv() {
+	c := make(chan int)
+	go func() { <-c }()
+}
+
+func leakSend() {
+	c := make(chan int)
+	go func() { c <- 1 }()
+}
+
+func leakSelect() {
+	c, d := make(chan int), make(chan int)
+	go func() {
+		select {
+		case <-c:
+		case d <- 1:
+		}
+	}()
+}
+
+func leakNilChan() {
+	go func() {
+		var c chan int
+		<-c
+	}()
+}
+
+func leakMutex() {
+	m := &struct {
+		sync.Mutex
+		p *int
+	}{}
+	m.Lock()
+	go func() { m.Lock() }()
+}
+
+func leakWaitGroup() {
+	wg := &struct {
+		sync.WaitGroup
+		p *int
+	}{}
+	wg.Add(1)
+	go func() { wg.Wait() }()
+}
+
+func leakCond() {
+	c := sync.NewCond(new(sync.Mutex))
+	go func() {
+		c.L.Lock()
+		c.Wait()
+	}()
+}
+
+// leakChain leaks a goroutine blocked on a channel that only
+// another leaked goroutine can reach.
+func leakChain() {
+	c := make(chan int)
+	d := make(chan chan int)
+	go func() { d <- c }()
+	go func() { <-c }()
+}
+
+var leakGlobal = make(chan int)
+
+// blockForever creates goroutines that are blocked but not leaked:
+// something reachable can still wake them.
+func blockForever() {
+	go func() { <-leakGlobal }()
+	c := make(chan int)
+	go func() { <-c }()
+	time.AfterFunc(time.Hour, func() { close(c) })
+	d := make(chan int)
+	go func() { d <- 1 }()
+	go func() {
+		time.Sleep(time.Hour)
+		<-d
+	}()
+}
+
+func TestGoroutineLeakProfile(t *testing.T) {
+	leaks := []string{
+		"leakRecv", "leakSend", "leakSelect", "leakNilChan",
+		"leakMutex", "leakWaitGroup", "leakCond", "leakChain",
+	}
+	leakRecv()
+	leakSend()
+	leakSelect()
+	leakNilChan()
+	leakMutex()
+	leakWaitGroup()
+	leakCond()
+	leakChain()
+	blockForever()
+
+	// Wait for the goroutines to block.
+	var prof string
+	for i := 0; i < 100; i++ {
+		var w bytes.Buffer
+		if err := Lookup("goroutineleak").WriteTo(&w, 1); err != nil {
+			t.Fatal(err)
+		}
+		prof = w.String()
+		if strings.Count(prof, "pprof.leakChain+") == 2 &&
+			strings.Count(prof, "pprof.leak") == 2*len(leaks)+2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, name := range leaks {
+		// The stack of the goroutine ends with the function creating it.
+		if !containsInOrder(prof, "pprof."+name+".func1+", "pprof."+name+"+") {
+			t.Errorf("goroutine leaked by %s not in profile:\n%s", name, prof)
+		}
+	}
+	if !containsInOrder(prof, "pprof.leakChain.func2+", "pprof.leakChain+") {
+		t.Errorf("second goroutine leaked by leakChain not in profile:\n%s", prof)
+	}
+	if strings.Contains(prof, "blockForever") {
+		t.Errorf("goroutines that can be woken are in profile:\n%s", prof)
+	}
+	if n := Lookup("goroutineleak").Count(); n < len(leaks)+1 {
+		t.Errorf("Count() = %d, want at least %d", n, len(leaks)+1)
+	}
+
+	// Check proto profile
+	var w bytes.Buffer
+	if err := Lookup("goroutineleak").WriteTo(&w, 0); err != nil {
+		t.Fatal(err)
+	}
+	p, err := profile.Parse(&w)
+	if err != nil {
+		t.Fatalf("error parsing protobuf profile: %v", err)
+	}
+	if err := p.CheckValid(); err != nil {
+		t.Errorf("protobuf profile is invalid: %v", err)
+	}
+	found := false
+	for _, s := range p.Sample {
+		loc := s.Location
+		if fn := loc[len(loc)-2].Line[0].Function.Name; !strings.HasSuffix(fn, "leakCond.func1") {
+			continue
+		}
+		found = true
+		if fn := loc[len(loc)-1].Line[0].Function.Name; !strings.HasSuffix(fn, ".leakCond") {
+			t.Errorf("stack of goroutine leaked by leakCond ends in %s", fn)
+		}
+	}
+	if !found {
+		t.Errorf("goroutine leaked by leakCond not in protobuf profile")
+	}
+
+	// Check full tracebacks
+	w.Reset()
+	if err := Lookup("goroutineleak").WriteTo(&w, 2); err != nil {
+		t.Fatal(err)
+	}
+	if !containsInOrder(w.String(), "[sync.Cond.Wait]:\n", "leakCond.func1()", "created by runtime/pprof.leakCond") {
+		t.Errorf("goroutine leaked by leakCond not in tracebacks:\n%s", w.String())
+	}
+}
+
 func containsInOrder(s string, all ...string) bool {
 	for _, t := range all {
 		i := strings.Index(s, t)
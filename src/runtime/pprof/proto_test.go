@@ -435,3 +435,32 @@ func TestEmptyStack(t *testing.T) {
 		t.Fatalf("translating profile: %v", err)
 	}
 }
+
+//go:noinline
+func startLineFunc() uintptr {
+	pc, _, _, _ := runtime.Caller(0)
+	return pc
+}
+
+func TestFunctionStartLine(t *testing.T) {
+	pc := startLineFunc()
+	_, wantLine := runtime.FuncForPC(pc).FileLine(abi.FuncPCABIInternal(startLineFunc))
+
+	b := []uint64{
+		3, 0, 500, // hz = 500
+		4, 0, 10, uint64(pc + 1), // 10 samples in startLineFunc
+	}
+	p, err := translateCPUProfile(b)
+	if err != nil {
+		t.Fatalf("translating profile: %v", err)
+	}
+	for _, fn := range p.Function {
+		if strings.HasSuffix(fn.Name, ".startLineFunc") {
+			if fn.StartLine != int64(wantLine) {
+				t.Errorf("%s: StartLine = %d, want %d", fn.Name, fn.StartLine, wantLine)
+			}
+			return
+		}
+	}
+	t.Fatalf("startLineFunc not found in profile:\n%v", p)
+}
@@ -459,6 +459,11 @@ type g struct {
 	// park on a chansend or chanrecv. Used to signal an unsafe point
 	// for stack shrinking. It's a boolean value, but is updated atomically.
 	parkingOnChan uint8
+	// leakCandidate is set during a goroutine leak detection cycle
+	// while it is not known yet whether the goroutine can be woken.
+	// Its stack is not scanned until then. See mgcleak.go.
+	leakCandidate bool
+	leaked        bool // the last leak detection cycle found g can never be woken
 
 	raceignore     int8     // ignore race detection events
 	sysblocktraced bool     // StartTrace has emitted EvGoInSyscall about this goroutine
@@ -480,6 +485,7 @@ type g struct {
 	startpc        uintptr         // pc of goroutine function
 	racectx        uintptr
 	waiting        *sudog         // sudog structures this g is waiting on (that have a valid elem ptr); in lock order
+	syncsudog      *sudog         // sudog of semacquire or notifyListWait; its elem is the semaphore or notify list
 	cgoCtxt        []uintptr      // cgo traceback context
 	labels         unsafe.Pointer // profiler labels
 	timer          *timer         // cached timer for time.Sleep
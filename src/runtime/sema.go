@@ -141,7 +141,9 @@ func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags, skipframes i
 		// Any semrelease after the cansemacquire knows we're waiting
 		// (we set nwait above), so go to sleep.
 		root.queue(addr, s, lifo)
+		gp.syncsudog = s
 		goparkunlock(&root.lock, waitReasonSemacquire, traceEvGoBlockSync, 4+skipframes)
+		gp.syncsudog = nil
 		if s.ticket != 0 || cansemacquire(addr) {
 			break
 		}
@@ -510,7 +512,12 @@ func notifyListWait(l *notifyList, t uint32) {
 		l.tail.next = s
 	}
 	l.tail = s
+	// Record the notify list for goroutine leak detection.
+	s.elem = unsafe.Pointer(l)
+	s.g.syncsudog = s
 	goparkunlock(&l.lock, waitReasonSyncCondWait, traceEvGoBlockCond, 3)
+	s.g.syncsudog = nil
+	s.elem = nil
 	if t0 != 0 {
 		blockevent(s.releasetime-t0, 2)
 	}
@@ -202,6 +202,7 @@ func BenchmarkGrowSlice(b *testing.B) {
 var (
 	SinkIntSlice        []int
 	SinkIntPointerSlice []*int
+	SinkStringSlice     []string
 )
 
 func BenchmarkExtendSlice(b *testing.B) {
@@ -247,6 +248,7 @@ func BenchmarkAppendGrowByte(b *testing.B) {
 		for j := 0; j < 1<<20; j++ {
 			x = append(x, byte(j))
 		}
+		blackhole = x
 	}
 }
 
@@ -257,6 +259,7 @@ func BenchmarkAppendGrowString(b *testing.B) {
 		for j := 0; j < 1<<20; j++ {
 			x = append(x, s)
 		}
+		SinkStringSlice = x
 	}
 }
 
@@ -454,3 +454,83 @@ func TestAtoi32(t *testing.T) {
 		}
 	}
 }
+
+func TestAtoi64(t *testing.T) {
+	for i := range atoi64tests {
+		test := &atoi64tests[i]
+		out, ok := runtime.Atoi64(test.in)
+		if test.out != out || test.ok != ok {
+			t.Errorf("atoi64(%q) = (%v, %v) want (%v, %v)",
+				test.in, out, ok, test.out, test.ok)
+		}
+	}
+}
+
+func TestParseByteCount(t *testing.T) {
+	for _, test := range []struct {
+		in  string
+		out int64
+		ok  bool
+	}{
+		// Good numeric inputs.
+		{"1", 1, true},
+		{"12345", 12345, true},
+		{"012345", 12345, true},
+		{"98765432100", 98765432100, true},
+		{"9223372036854775807", 1<<63 - 1, true},
+
+		// Good trivial suffix inputs.
+		{"1B", 1, true},
+		{"12345B", 12345, true},
+		{"9223372036854775807B", 1<<63 - 1, true},
+
+		// Good binary suffix inputs.
+		{"1KiB", 1 << 10, true},
+		{"05KiB", 5 << 10, true},
+		{"1MiB", 1 << 20, true},
+		{"10MiB", 10 << 20, true},
+		{"1GiB", 1 << 30, true},
+		{"100GiB", 100 << 30, true},
+		{"1TiB", 1 << 40, true},
+		{"99TiB", 99 << 40, true},
+
+		// Good zero inputs.
+		{"0", 0, true},
+		{"0B", 0, true},
+		{"0KiB", 0, true},
+
+		// Bad inputs.
+		{"", 0, false},
+		{"-1", 0, false},
+		{"a12345", 0, false},
+		{"a12345B", 0, false},
+		{"12345x", 0, false},
+		{"0x12345", 0, false},
+
+		// Bad numeric inputs.
+		{"9223372036854775808", 0, false},
+		{"9223372036854775809", 0, false},
+		{"18446744073709551615", 0, false},
+		{"20496382327982653440", 0, false},
+
+		// Bad suffixes.
+		{"1KB", 0, false},
+		{"1kiB", 0, false},
+		{"1Ki", 0, false},
+		{"1iB", 0, false},
+		{"1PiB", 0, false},
+		{"KiB", 0, false},
+		{"B", 0, false},
+
+		// Overflow with suffix.
+		{"9223372036854775807KiB", 0, false},
+		{"8388608TiB", 0, false},
+		{"8388607TiB", 8388607 << 40, true},
+	} {
+		out, ok := runtime.ParseByteCount(test.in)
+		if test.out != out || test.ok != ok {
+			t.Errorf("parseByteCount(%q) = (%v, %v) want (%v, %v)",
+				test.in, out, ok, test.out, test.ok)
+		}
+	}
+}
@@ -21,6 +21,8 @@ func init() {
 	register("GCPhys", GCPhys)
 	register("DeferLiveness", DeferLiveness)
 	register("GCZombie", GCZombie)
+	register("GCMemoryLimit", GCMemoryLimit)
+	register("GCMemoryLimitGrowth", GCMemoryLimitGrowth)
 }
 
 func GCSys() {
@@ -300,3 +302,96 @@ func GCZombie() {
 	runtime.KeepAlive(keep)
 	runtime.KeepAlive(zombies)
 }
+
+// GCMemoryLimit runs with GOGC=off and GOMEMLIMIT set (see
+// TestGCMemoryLimit). It allocates much more garbage than the limit
+// while keeping a small live heap, and checks that the runtime keeps
+// its memory use near the limit by collecting.
+func GCMemoryLimit() {
+	const (
+		limit     = 64 << 20
+		liveSize  = 16 << 20
+		allocSize = 1 << 20
+		total     = 1 << 30
+	)
+	if got := debug.SetMemoryLimit(-1); got != limit {
+		fmt.Printf("memory limit = %d, want %d\n", got, limit)
+		return
+	}
+	live := make([][]byte, liveSize/allocSize)
+	for i := range live {
+		live[i] = make([]byte, allocSize)
+	}
+
+	var ms runtime.MemStats
+	var peak uint64
+	for i := 0; i < total/allocSize; i++ {
+		gcMemoryLimitSink = make([]byte, allocSize)
+		if i%16 == 0 {
+			runtime.ReadMemStats(&ms)
+			if used := ms.Sys - ms.HeapReleased; used > peak {
+				peak = used
+			}
+		}
+	}
+	runtime.KeepAlive(live)
+
+	runtime.ReadMemStats(&ms)
+	if ms.NumGC == 0 {
+		fmt.Println("no GC ran with GOGC=off and a memory limit")
+		return
+	}
+	// Allow some slack: the limit is soft, and the runtime's
+	// non-heap memory estimate lags behind by up to a GC cycle.
+	if peak > limit*11/10 {
+		fmt.Printf("peak memory use %d MB exceeds memory limit %d MB\n", peak>>20, limit>>20)
+		return
+	}
+	fmt.Println("OK")
+}
+
+var gcMemoryLimitSink []byte
+
+// GCMemoryLimitGrowth runs with GOGC=off and GOMEMLIMIT set (see
+// TestGCMemoryLimitGrowth). It leaves the heap full of free single-page
+// holes, then grows it with objects too large to fit in them, and
+// checks that the holes are returned to the OS quickly enough for the
+// runtime's memory use to stay under the limit.
+func GCMemoryLimitGrowth() {
+	const (
+		limit     = 64 << 20
+		holesSize = 24 << 20
+		smallSize = 8 << 10 // one page each
+		largeSize = 64 << 10
+		largeLive = 24 << 20
+	)
+	// Allocate pages, and keep every other one live.
+	small := make([][]byte, 2*holesSize/smallSize)
+	for i := range small {
+		small[i] = make([]byte, smallSize)
+	}
+	for i := 0; i < len(small); i += 2 {
+		small[i] = nil
+	}
+	runtime.GC()
+
+	var ms runtime.MemStats
+	var peak uint64
+	large := make([][]byte, largeLive/largeSize)
+	for i := range large {
+		large[i] = make([]byte, largeSize)
+		runtime.ReadMemStats(&ms)
+		if used := ms.Sys - ms.HeapReleased; used > peak {
+			peak = used
+		}
+	}
+	runtime.KeepAlive(small)
+	runtime.KeepAlive(large)
+
+	// Allow some slack, as the limit is soft.
+	if peak > limit*21/20 {
+		fmt.Printf("peak memory use %d MB exceeds memory limit %d MB\n", peak>>20, limit>>20)
+		return
+	}
+	fmt.Println("OK")
+}
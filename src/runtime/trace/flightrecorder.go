@@ -0,0 +1,241 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A FlightRecorder records the execution trace of the program continuously
+// into memory, keeping only the most recent part of it. WriteTo writes that
+// part out on demand, for example when a request exceeds its latency goal.
+//
+// The flight recorder restarts tracing every half period, and whenever
+// the trace since the last restart reaches half of the size limit, so that
+// it can drop the oldest parts of the trace. Each restart stops the world
+// briefly. The trace written by WriteTo is made of the traces between
+// restarts; the trace tool and package debug/trace read it as one trace.
+//
+// Only one FlightRecorder or tracing started by Start can be active at a time.
+type FlightRecorder struct {
+	period time.Duration
+	size   int
+
+	mu     sync.Mutex // protects the fields below and serializes restarts
+	active bool
+	gens   []*flightGen // complete traces, oldest first
+	cur    *flightGen   // trace being recorded
+	rotate chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// A flightGen is the trace recorded between two restarts.
+type flightGen struct {
+	start time.Time
+	data  [][]byte
+	size  int
+	done  chan struct{} // closed once all of the trace is read
+}
+
+// NewFlightRecorder returns an inactive flight recorder keeping
+// the last 10 seconds of execution, up to 10 MB of trace.
+func NewFlightRecorder() *FlightRecorder {
+	return &FlightRecorder{
+		period: 10 * time.Second,
+		size:   10 << 20,
+	}
+}
+
+// SetPeriod sets the approximate duration of the execution the flight
+// recorder keeps. It must be called before Start.
+func (r *FlightRecorder) SetPeriod(d time.Duration) {
+	if d <= 0 {
+		panic("trace: non-positive flight recorder period")
+	}
+	r.period = d
+}
+
+// SetSize sets the maximum size in bytes of the trace the flight recorder
+// keeps. It takes precedence over the period: when the trace of the period
+// is larger, the flight recorder keeps less. It must be called before Start.
+func (r *FlightRecorder) SetSize(bytes int) {
+	if bytes <= 0 {
+		panic("trace: non-positive flight recorder size")
+	}
+	r.size = bytes
+}
+
+// Start starts the flight recorder.
+// It returns an error if tracing is already enabled.
+func (r *FlightRecorder) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active {
+		return errors.New("trace: flight recorder already active")
+	}
+
+	tracing.Lock()
+	defer tracing.Unlock()
+	r.rotate = make(chan struct{}, 1)
+	if err := r.startGen(); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&tracing.enabled, 1)
+	tracing.recorder = true
+
+	r.active = true
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run(r.stop, r.done)
+	return nil
+}
+
+// Stop stops the flight recorder and drops the recorded trace.
+func (r *FlightRecorder) Stop() {
+	r.mu.Lock()
+	if !r.active {
+		r.mu.Unlock()
+		return
+	}
+	r.active = false
+	close(r.stop)
+
+	tracing.Lock()
+	atomic.StoreInt32(&tracing.enabled, 0)
+	tracing.recorder = false
+	runtime.StopTrace()
+	tracing.Unlock()
+
+	<-r.cur.done
+	r.gens, r.cur = nil, nil
+	done := r.done
+	r.mu.Unlock()
+	<-done
+}
+
+// Enabled reports whether the flight recorder is active.
+func (r *FlightRecorder) Enabled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active
+}
+
+// WriteTo writes the trace the flight recorder keeps to w.
+// It returns an error if the flight recorder is not active.
+func (r *FlightRecorder) WriteTo(w io.Writer) (n int64, err error) {
+	r.mu.Lock()
+	if !r.active {
+		r.mu.Unlock()
+		return 0, errors.New("trace: flight recorder is not active")
+	}
+	// Complete the current trace so that it can be written.
+	if err := r.restart(); err != nil {
+		r.mu.Unlock()
+		return 0, err
+	}
+	gens := append([]*flightGen(nil), r.gens...)
+	r.mu.Unlock()
+
+	for _, g := range gens {
+		for _, data := range g.data {
+			m, err := w.Write(data)
+			n += int64(m)
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// run restarts tracing periodically, and when asked to by the
+// goroutine reading the trace, until stop is closed.
+func (r *FlightRecorder) run(stop, done chan struct{}) {
+	defer close(done)
+	t := time.NewTicker(r.period / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		case <-r.rotate:
+		}
+		r.mu.Lock()
+		if r.active {
+			r.restart()
+		}
+		r.mu.Unlock()
+	}
+}
+
+// restart completes the current trace and starts a new one.
+// r.mu must be held.
+func (r *FlightRecorder) restart() error {
+	tracing.Lock()
+	defer tracing.Unlock()
+	runtime.StopTrace()
+	<-r.cur.done
+	r.gens = append(r.gens, r.cur)
+	r.cur = nil
+	if err := r.startGen(); err != nil {
+		// Tracing was started by someone else meanwhile.
+		atomic.StoreInt32(&tracing.enabled, 0)
+		tracing.recorder = false
+		r.active = false
+		r.gens = nil
+		close(r.stop)
+		return err
+	}
+
+	// Drop the oldest traces that are not needed to cover the period,
+	// or that do not fit in the size.
+	size := 0
+	for _, g := range r.gens {
+		size += g.size
+	}
+	min := r.cur.start.Add(-r.period)
+	for len(r.gens) > 1 && (size > r.size || !r.gens[1].start.After(min)) {
+		size -= r.gens[0].size
+		r.gens[0] = nil
+		r.gens = r.gens[1:]
+	}
+	return nil
+}
+
+// startGen starts tracing into a new trace.
+// r.mu and tracing must be held.
+func (r *FlightRecorder) startGen() error {
+	if err := runtime.StartTrace(); err != nil {
+		return err
+	}
+	g := &flightGen{start: time.Now(), done: make(chan struct{})}
+	r.cur = g
+	go func() {
+		defer close(g.done)
+		for {
+			data := runtime.ReadTrace()
+			if data == nil {
+				return
+			}
+			// ReadTrace reuses its buffers.
+			g.data = append(g.data, append([]byte(nil), data...))
+			if g.size < r.size/2 && g.size+len(data) >= r.size/2 {
+				select {
+				case r.rotate <- struct{}{}:
+				default:
+				}
+			}
+			g.size += len(data)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"internal/trace"
+	. "runtime/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	fr := NewFlightRecorder()
+	fr.SetPeriod(100 * time.Millisecond)
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	defer fr.Stop()
+	if !fr.Enabled() || !IsEnabled() {
+		t.Fatalf("flight recorder is not enabled after Start")
+	}
+
+	// Keep goroutines blocking and unblocking while tracing restarts.
+	stop := make(chan bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := make(chan int)
+			go func() {
+				for range c {
+				}
+			}()
+			for {
+				select {
+				case <-stop:
+					close(c)
+					return
+				case c <- 1:
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+	}
+
+	ctx := context.Background()
+	Log(ctx, "flight", "before")
+	time.Sleep(500 * time.Millisecond)
+	Log(ctx, "flight", "after")
+
+	buf := new(bytes.Buffer)
+	if _, err := fr.WriteTo(buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	close(stop)
+	wg.Wait()
+	saveTrace(t, buf, "TestFlightRecorder")
+
+	if n := bytes.Count(buf.Bytes(), []byte(" trace\x00")); n < 2 {
+		t.Errorf("flight recorder trace has %d generations, want at least 2", n)
+	}
+	events, _ := parseTrace(t, buf)
+	var before, after bool
+	for _, ev := range events {
+		if ev.Type == trace.EvUserLog && ev.SArgs[0] == "flight" {
+			before = before || ev.SArgs[1] == "before"
+			after = after || ev.SArgs[1] == "after"
+		}
+	}
+	if before {
+		t.Errorf("flight recorder kept the trace older than its period")
+	}
+	if !after {
+		t.Errorf("flight recorder lost the trace of the last period")
+	}
+	checkGoroutineStates(t, events)
+}
+
+// checkGoroutineStates checks that the goroutine events of a flight
+// recorder trace are consistent across the restarts of tracing.
+func checkGoroutineStates(t *testing.T, events []*trace.Event) {
+	const (
+		dead = iota
+		runnable
+		running
+		waiting
+	)
+	gs := make(map[uint64]int)
+	check := func(ev *trace.Event, g uint64, from, to int) {
+		if gs[g] != from {
+			t.Fatalf("%v: goroutine %d in state %d, want %d", ev, g, gs[g], from)
+		}
+		gs[g] = to
+	}
+	for _, ev := range events {
+		switch ev.Type {
+		case trace.EvGoCreate:
+			check(ev, ev.Args[0], dead, runnable)
+		case trace.EvGoStart, trace.EvGoStartLabel:
+			check(ev, ev.G, runnable, running)
+		case trace.EvGoEnd, trace.EvGoStop:
+			check(ev, ev.G, running, dead)
+		case trace.EvGoSched, trace.EvGoPreempt:
+			check(ev, ev.G, running, runnable)
+		case trace.EvGoUnblock:
+			check(ev, ev.Args[0], waiting, runnable)
+		case trace.EvGoSysExit:
+			check(ev, ev.G, waiting, runnable)
+		case trace.EvGoWaiting, trace.EvGoInSyscall:
+			check(ev, ev.G, runnable, waiting)
+		case trace.EvGoSleep, trace.EvGoBlock, trace.EvGoBlockSend,
+			trace.EvGoBlockRecv, trace.EvGoBlockSelect, trace.EvGoBlockSync,
+			trace.EvGoBlockCond, trace.EvGoBlockNet, trace.EvGoSysBlock,
+			trace.EvGoBlockGC:
+			check(ev, ev.G, running, waiting)
+		}
+	}
+}
+
+func TestFlightRecorderSize(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	const size = 1 << 20
+	fr := NewFlightRecorder()
+	fr.SetSize(size)
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	defer fr.Stop()
+	ctx := context.Background()
+	for i := 0; i < 2e5; i++ {
+		Log(ctx, "flight", "message")
+	}
+	buf := new(bytes.Buffer)
+	n, err := fr.WriteTo(buf)
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo returned %d, wrote %d bytes", n, buf.Len())
+	}
+	// The size is exceeded only by the trace since the last restart,
+	// which happens soon after that trace reaches half the size.
+	if n > 2*size {
+		t.Errorf("flight recorder kept %d bytes, want at most %d", n, 2*size)
+	}
+	parseTrace(t, buf)
+}
+
+func TestFlightRecorderStartStop(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	buf := new(bytes.Buffer)
+	if err := Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	fr := NewFlightRecorder()
+	if err := fr.Start(); err == nil {
+		t.Errorf("flight recorder started while tracing")
+		fr.Stop()
+	}
+	Stop()
+
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	if err := fr.Start(); err == nil {
+		t.Errorf("flight recorder started twice")
+	}
+	if err := Start(buf); err == nil {
+		t.Errorf("tracing started while flight recording")
+	}
+	Stop()
+	if !fr.Enabled() {
+		t.Errorf("Stop stopped the flight recorder")
+	}
+	if _, err := fr.WriteTo(new(bytes.Buffer)); err != nil {
+		t.Errorf("WriteTo failed: %v", err)
+	}
+	fr.Stop()
+	if fr.Enabled() || IsEnabled() {
+		t.Errorf("flight recorder is enabled after Stop")
+	}
+	if _, err := fr.WriteTo(new(bytes.Buffer)); err == nil {
+		t.Errorf("WriteTo succeeded after Stop")
+	}
+	fr.Stop()
+}
@@ -34,6 +34,11 @@
 // See the net/http/pprof package for more details about all of the
 // debug endpoints installed by this import.
 //
+// Tracing everything is too costly to leave on in production. Instead,
+// a FlightRecorder keeps the trace of the last few seconds of execution
+// in memory, and writes it out on demand, for example when a request
+// takes too long.
+//
 // User annotation
 //
 // Package trace provides user annotation APIs that can be used to
@@ -139,9 +144,13 @@ func Start(w io.Writer) error {
 
 // Stop stops the current tracing, if any.
 // Stop only returns after all the writes for the trace have completed.
+// Stop does not stop a FlightRecorder.
 func Stop() {
 	tracing.Lock()
 	defer tracing.Unlock()
+	if tracing.recorder {
+		return
+	}
 	atomic.StoreInt32(&tracing.enabled, 0)
 
 	runtime.StopTrace()
@@ -150,4 +159,5 @@ func Stop() {
 var tracing struct {
 	sync.Mutex       // gate mutators (Start, Stop)
 	enabled    int32 // accessed via atomic
+	recorder   bool  // tracing was started by a FlightRecorder
 }
@@ -8,6 +8,7 @@
 package syscall_test
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"internal/testenv"
@@ -642,3 +643,81 @@ func testAmbientCaps(t *testing.T, userns bool) {
 		t.Fatal(err.Error())
 	}
 }
+
+// prepareCgroupFD creates a new cgroup v2 as a child of the cgroup of the
+// current process and returns a descriptor for it along with its path
+// relative to the current cgroup. It skips the test if that's not possible.
+func prepareCgroupFD(t *testing.T) (int, string) {
+	t.Helper()
+
+	selfCg, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// Only cgroup v2 is supported by CLONE_INTO_CGROUP, and on a unified
+	// hierarchy the only entry is "0::/path".
+	cg := strings.TrimSpace(string(selfCg))
+	if strings.Contains(cg, "\n") || !strings.HasPrefix(cg, "0::") {
+		t.Skip("cgroup v2 unified hierarchy not in use")
+	}
+	cg = strings.TrimPrefix(cg, "0::")
+
+	var st syscall.Statfs_t
+	const cgroup2SuperMagic = 0x63677270
+	if err := syscall.Statfs("/sys/fs/cgroup", &st); err != nil || st.Type != cgroup2SuperMagic {
+		t.Skip("cgroup v2 not mounted at /sys/fs/cgroup")
+	}
+
+	subCgroup, err := os.MkdirTemp(filepath.Join("/sys/fs/cgroup", cg), "subcg-")
+	if err != nil {
+		// Permission denied, read-only file system, and the like.
+		t.Skip(err)
+	}
+	t.Cleanup(func() { syscall.Rmdir(subCgroup) })
+
+	cgroupFD, err := syscall.Open(subCgroup, syscall.O_DIRECTORY|syscall.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(&os.PathError{Op: "open", Path: subCgroup, Err: err})
+	}
+	t.Cleanup(func() { syscall.Close(cgroupFD) })
+
+	return cgroupFD, "/" + filepath.Base(subCgroup)
+}
+
+func TestUseCgroupFD(t *testing.T) {
+	fd, suffix := prepareCgroupFD(t)
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestUseCgroupFDHelper")
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		UseCgroupFD: true,
+		CgroupFD:    fd,
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		// clone3 with CLONE_INTO_CGROUP was added in Linux 5.7.
+		if errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.EINVAL) {
+			t.Skipf("clone3 with CLONE_INTO_CGROUP not supported: %v", err)
+		}
+		t.Fatalf("Cmd failed with err %v, output: %s", err, out)
+	}
+	// NB: this wouldn't work with cgroupns.
+	if !strings.HasSuffix(strings.TrimSpace(string(out)), suffix) {
+		t.Fatalf("got: %q, want: a line that ends with %q", out, suffix)
+	}
+}
+
+func TestUseCgroupFDHelper(*testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+	// Read and print own cgroup path.
+	selfCg, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	fmt.Print(string(selfCg))
+}
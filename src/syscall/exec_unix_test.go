@@ -254,13 +254,21 @@ func TestForegroundSignal(t *testing.T) {
 	cmd := create(t)
 
 	go func() {
+		defer close(ch2)
 		cmd.proc.SysProcAttr = &syscall.SysProcAttr{
 			Ctty:       int(tty.Fd()),
 			Foreground: true,
 		}
-		cmd.Start()
-		cmd.Stop()
-		close(ch2)
+		// Start and Stop call t.Fatal, which must not be called
+		// from this goroutine.
+		if err := cmd.proc.Start(); err != nil {
+			t.Error(err)
+			return
+		}
+		cmd.pipe.Close()
+		if err := cmd.proc.Wait(); err != nil {
+			t.Error(err)
+		}
 	}()
 
 	timer := time.NewTimer(30 * time.Second)
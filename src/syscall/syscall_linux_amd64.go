@@ -10,7 +10,10 @@ package syscall
 // ABI. See "man syscall".
 const archHonorsR2 = true
 
-const _SYS_setgroups = SYS_SETGROUPS
+const (
+	_SYS_setgroups = SYS_SETGROUPS
+	_SYS_clone3    = 435
+)
 
 //sys	Dup2(oldfd int, newfd int) (err error)
 //sysnb	EpollCreate(size int) (fd int, err error)
@@ -126,4 +129,4 @@ func (cmsg *Cmsghdr) SetLen(length int) {
 	cmsg.Len = uint64(length)
 }
 
-func rawVforkSyscall(trap, a1 uintptr) (r1 uintptr, err Errno)
+func rawVforkSyscall(trap, a1, a2, a3 uintptr) (r1 uintptr, err Errno)
@@ -12,7 +12,10 @@ import "unsafe"
 // ABI. See "man syscall".
 const archHonorsR2 = true
 
-const _SYS_setgroups = SYS_SETGROUPS
+const (
+	_SYS_setgroups = SYS_SETGROUPS
+	_SYS_clone3    = 435
+)
 
 func EpollCreate(size int) (fd int, err error) {
 	if size <= 0 {
@@ -190,4 +193,4 @@ func Pause() error {
 	return err
 }
 
-func rawVforkSyscall(trap, a1 uintptr) (r1 uintptr, err Errno)
+func rawVforkSyscall(trap, a1, a2, a3 uintptr) (r1 uintptr, err Errno)
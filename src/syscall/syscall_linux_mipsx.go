@@ -16,7 +16,10 @@ import "unsafe"
 // ABI. See "man syscall".
 const archHonorsR2 = true
 
-const _SYS_setgroups = SYS_SETGROUPS
+const (
+	_SYS_setgroups = SYS_SETGROUPS
+	_SYS_clone3    = 4435
+)
 
 func Syscall9(trap, a1, a2, a3, a4, a5, a6, a7, a8, a9 uintptr) (r1, r2 uintptr, err Errno)
 
@@ -201,4 +204,4 @@ func (cmsg *Cmsghdr) SetLen(length int) {
 	cmsg.Len = uint32(length)
 }
 
-func rawVforkSyscall(trap, a1 uintptr) (r1 uintptr, err Errno)
+func rawVforkSyscall(trap, a1, a2, a3 uintptr) (r1 uintptr, err Errno)
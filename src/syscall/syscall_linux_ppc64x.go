@@ -14,7 +14,10 @@ package syscall
 // ABI. See "man syscall".
 const archHonorsR2 = false
 
-const _SYS_setgroups = SYS_SETGROUPS
+const (
+	_SYS_setgroups = SYS_SETGROUPS
+	_SYS_clone3    = 435
+)
 
 //sys	Dup2(oldfd int, newfd int) (err error)
 //sysnb	EpollCreate(size int) (fd int, err error)
@@ -98,7 +101,7 @@ func (cmsg *Cmsghdr) SetLen(length int) {
 	cmsg.Len = uint64(length)
 }
 
-func rawVforkSyscall(trap, a1 uintptr) (r1 uintptr, err Errno)
+func rawVforkSyscall(trap, a1, a2, a3 uintptr) (r1 uintptr, err Errno)
 
 //sys	syncFileRange2(fd int, flags int, off int64, n int64) (err error) = SYS_SYNC_FILE_RANGE2
 
@@ -32,35 +32,36 @@ var (
 	matchBenchmarks *string
 	benchmarkMemory *bool
 
-	benchTime = benchTimeFlag{d: 1 * time.Second} // changed during test of testing package
+	benchTime = durationOrCountFlag{d: 1 * time.Second} // changed during test of testing package
 )
 
-type benchTimeFlag struct {
-	d time.Duration
-	n int
+type durationOrCountFlag struct {
+	d         time.Duration
+	n         int
+	allowZero bool
 }
 
-func (f *benchTimeFlag) String() string {
+func (f *durationOrCountFlag) String() string {
 	if f.n > 0 {
 		return fmt.Sprintf("%dx", f.n)
 	}
 	return time.Duration(f.d).String()
 }
 
-func (f *benchTimeFlag) Set(s string) error {
+func (f *durationOrCountFlag) Set(s string) error {
 	if strings.HasSuffix(s, "x") {
 		n, err := strconv.ParseInt(s[:len(s)-1], 10, 0)
-		if err != nil || n <= 0 {
+		if err != nil || n < 0 || (!f.allowZero && n == 0) {
 			return fmt.Errorf("invalid count")
 		}
-		*f = benchTimeFlag{n: int(n)}
+		*f = durationOrCountFlag{n: int(n), allowZero: f.allowZero}
 		return nil
 	}
 	d, err := time.ParseDuration(s)
-	if err != nil || d <= 0 {
+	if err != nil || d < 0 || (!f.allowZero && d == 0) {
 		return fmt.Errorf("invalid duration")
 	}
-	*f = benchTimeFlag{d: d}
+	*f = durationOrCountFlag{d: d, allowZero: f.allowZero}
 	return nil
 }
 
@@ -98,7 +99,7 @@ type B struct {
 	previousN        int           // number of iterations in the previous run
 	previousDuration time.Duration // total duration of the previous run
 	benchFunc        func(b *B)
-	benchTime        benchTimeFlag
+	benchTime        durationOrCountFlag
 	bytes            int64
 	missingBytes     bool // one of the subbenchmarks does not have bytes set.
 	timerOn          bool
@@ -113,6 +114,12 @@ type B struct {
 	netBytes  uint64
 	// Extra metrics collected by ReportMetric.
 	extra map[string]float64
+	// State of the iterations of Loop.
+	loop struct {
+		n    int  // target number of iterations
+		i    int  // number of iterations so far
+		done bool // Loop returned false
+	}
 }
 
 // StartTimer starts timing a test. This function is called automatically
@@ -186,12 +193,16 @@ func (b *B) runN(n int) {
 	runtime.GC()
 	b.raceErrors = -race.Errors()
 	b.N = n
+	b.loop.n, b.loop.i, b.loop.done = 0, 0, false
 	b.parallelism = 1
 	b.ResetTimer()
 	b.StartTimer()
 	b.benchFunc(b)
 	b.StopTimer()
-	b.previousN = n
+	if b.loop.n > 0 && !b.loop.done && !b.failed {
+		b.Error("benchmark function returned without B.Loop() == false (break or return in loop?)")
+	}
+	b.previousN = b.N
 	b.previousDuration = b.duration
 	b.raceErrors += race.Errors()
 	if b.raceErrors > 0 {
@@ -297,40 +308,112 @@ func (b *B) launch() {
 	}()
 
 	// Run the benchmark for at least the specified amount of time.
-	if b.benchTime.n > 0 {
+	// A benchmark using Loop already ran to completion in run1.
+	if b.loop.n > 0 {
+		// Nothing to do.
+	} else if b.benchTime.n > 0 {
 		b.runN(b.benchTime.n)
 	} else {
 		d := b.benchTime.d
 		for n := int64(1); !b.failed && b.duration < d && n < 1e9; {
 			last := n
-			// Predict required iterations.
-			goalns := d.Nanoseconds()
-			prevIters := int64(b.N)
-			prevns := b.duration.Nanoseconds()
-			if prevns <= 0 {
-				// Round up, to avoid div by zero.
-				prevns = 1
-			}
-			// Order of operations matters.
-			// For very fast benchmarks, prevIters ~= prevns.
-			// If you divide first, you get 0 or 1,
-			// which can hide an order of magnitude in execution time.
-			// So multiply first, then divide.
-			n = goalns * prevIters / prevns
-			// Run more iterations than we think we'll need (1.2x).
-			n += n / 5
-			// Don't grow too fast in case we had timing errors previously.
-			n = min(n, 100*last)
-			// Be sure to run at least one more than last time.
-			n = max(n, last+1)
-			// Don't run more than 1e9 times. (This also keeps n in int range on 32 bit platforms.)
-			n = min(n, 1e9)
+			n = predictN(d.Nanoseconds(), int64(b.N), b.duration.Nanoseconds(), last)
 			b.runN(int(n))
 		}
 	}
 	b.result = BenchmarkResult{b.N, b.duration, b.bytes, b.netAllocs, b.netBytes, b.extra}
 }
 
+// predictN predicts the number of iterations needed to run for goalns
+// nanoseconds, given that prevIters iterations took prevns nanoseconds.
+// last is the number of iterations of the previous run.
+func predictN(goalns, prevIters, prevns, last int64) int64 {
+	if prevns <= 0 {
+		// Round up, to avoid div by zero.
+		prevns = 1
+	}
+	// Order of operations matters.
+	// For very fast benchmarks, prevIters ~= prevns.
+	// If you divide first, you get 0 or 1,
+	// which can hide an order of magnitude in execution time.
+	// So multiply first, then divide.
+	n := goalns * prevIters / prevns
+	// Run more iterations than we think we'll need (1.2x).
+	n += n / 5
+	// Don't grow too fast in case we had timing errors previously.
+	n = min(n, 100*last)
+	// Be sure to run at least one more than last time.
+	n = max(n, last+1)
+	// Don't run more than 1e9 times. (This also keeps n in int range on 32 bit platforms.)
+	n = min(n, 1e9)
+	return n
+}
+
+// Loop reports whether the benchmark should run another iteration.
+// A benchmark using Loop is structured like:
+//
+//	func BenchmarkX(b *testing.B) {
+//		... setup ...
+//		for b.Loop() {
+//			... code to measure ...
+//		}
+//		... cleanup ...
+//	}
+//
+// Unlike a loop over b.N, such a benchmark function is called only once
+// per measurement: Loop itself runs as many iterations as the benchmark
+// time requires, so expensive setup runs only once. Loop resets the timer
+// the first time it is called, and stops it when it returns false, so
+// setup and cleanup are not measured. When the loop is done, b.N is the
+// number of iterations it ran.
+//
+// The compiler does not inline the calls in the body of a
+// "for b.Loop() { ... }" loop, so they are not optimized away even
+// when their results are unused.
+//
+// A benchmark must not use both Loop and b.N, and must not leave
+// the loop before Loop returns false.
+func (b *B) Loop() bool {
+	if b.loop.i < b.loop.n {
+		b.loop.i++
+		return true
+	}
+	return b.loopSlowPath()
+}
+
+// loopSlowPath is called by Loop when the iterations planned so far
+// are done. It starts the loop, or plans more iterations, or ends it.
+func (b *B) loopSlowPath() bool {
+	if b.loop.done {
+		panic("testing: B.Loop called after it returned false")
+	}
+	if !b.timerOn {
+		b.Fatal("B.Loop called with timer stopped")
+	}
+	if b.loop.n == 0 {
+		// First call: exclude the setup from the measurement.
+		b.loop.n = 1
+		if b.benchTime.n > 0 {
+			b.loop.n = b.benchTime.n
+		}
+		// b.N is meaningless until the loop is done.
+		b.N = 0
+		b.ResetTimer()
+	} else {
+		elapsed := b.duration + time.Since(b.start)
+		if b.benchTime.n > 0 || elapsed >= b.benchTime.d || b.loop.n >= 1e9 {
+			b.StopTimer()
+			b.N = b.loop.n
+			b.loop.done = true
+			return false
+		}
+		last := int64(b.loop.n)
+		b.loop.n = int(predictN(b.benchTime.d.Nanoseconds(), last, elapsed.Nanoseconds(), last))
+	}
+	b.loop.i++
+	return true
+}
+
 // ReportMetric adds "n unit" to the reported benchmark results.
 // If the metric is per-iteration, the caller should divide by b.N,
 // and by convention units should end in "/op".
@@ -557,6 +640,8 @@ func runBenchmarks(importPath string, matchString func(pat, str string) (bool, e
 // processBench runs bench b for the configured CPU counts and prints the results.
 func (ctx *benchContext) processBench(b *B) {
 	for i, procs := range cpuList {
+		var runs []BenchmarkResult // successful runs, to report their statistics
+		mem := false
 		for j := uint(0); j < *count; j++ {
 			runtime.GOMAXPROCS(procs)
 			benchName := benchmarkName(b.name, procs)
@@ -594,8 +679,12 @@ func (ctx *benchContext) processBench(b *B) {
 			}
 			if *benchmarkMemory || b.showAllocResult {
 				results += "\t" + r.MemString()
+				mem = true
 			}
 			fmt.Fprintln(b.w, results)
+			if *count > 1 {
+				runs = append(runs, r)
+			}
 			// Unlike with tests, we ignore the -chatty flag and always print output for
 			// benchmarks since the output generation time will skew the results.
 			if len(b.output) > 0 {
@@ -606,6 +695,9 @@ func (ctx *benchContext) processBench(b *B) {
 				fmt.Fprintf(os.Stderr, "testing: %s left GOMAXPROCS set to %d\n", benchName, p)
 			}
 		}
+		if len(runs) > 1 {
+			printBenchStats(b.w, benchmarkName(b.name, procs), runs, mem)
+		}
 	}
 }
 
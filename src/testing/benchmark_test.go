@@ -161,6 +161,59 @@ func TestReportMetric(t *testing.T) {
 	}
 }
 
+func TestBenchmarkLoop(t *testing.T) {
+	var setups, iters int
+	res := testing.Benchmark(func(b *testing.B) {
+		setups++
+		// The setup is not measured.
+		time.Sleep(50 * time.Millisecond)
+		for b.Loop() {
+			iters++
+		}
+		if b.N != iters {
+			t.Errorf("b.N = %d after the loop, want %d", b.N, iters)
+		}
+	})
+	if setups != 1 {
+		t.Errorf("benchmark function called %d times, want 1", setups)
+	}
+	if res.N != iters {
+		t.Errorf("res.N = %d, want %d", res.N, iters)
+	}
+	if res.N < 2 {
+		t.Errorf("res.N = %d, want the iterations to ramp up", res.N)
+	}
+}
+
+func TestBenchStats(t *testing.T) {
+	var results []testing.BenchmarkResult
+	for _, ns := range []time.Duration{90, 100, 110} {
+		results = append(results, testing.BenchmarkResult{
+			N:     10,
+			T:     10 * ns,
+			Extra: map[string]float64{"frobs/op": 3},
+		})
+	}
+	var buf bytes.Buffer
+	testing.PrintBenchStats(&buf, "BenchmarkX-4", results, false)
+	want := "--- STATS: BenchmarkX-4 (3 runs)\n" +
+		"\tns/op\t100 ± 24.84 (±24.8%)\tstddev 10\trange [90, 110]\n" +
+		"\tfrobs/op\t3 ± 0 (±0.0%)\tstddev 0\trange [3, 3]\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func ExampleB_Loop() {
+	testing.Benchmark(func(b *testing.B) {
+		// The setup runs once, and is not measured.
+		words := strings.Fields(strings.Repeat("hello world ", 1000))
+		for b.Loop() {
+			strings.Join(words, " ")
+		}
+	})
+}
+
 func ExampleB_ReportMetric() {
 	// This reports a custom benchmark metric relevant to a
 	// specific algorithm (in this case, sorting).
@@ -0,0 +1,144 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// benchMetrics returns the metrics of r in the order String and MemString
+// print them, and their values. mem reports whether the memory metrics
+// are printed.
+func benchMetrics(r BenchmarkResult, mem bool) (units []string, values map[string]float64) {
+	values = make(map[string]float64)
+	add := func(unit string, v float64) {
+		units = append(units, unit)
+		values[unit] = v
+	}
+	ns, ok := r.Extra["ns/op"]
+	if !ok && r.N > 0 {
+		ns = float64(r.T.Nanoseconds()) / float64(r.N)
+	}
+	if ns != 0 {
+		add("ns/op", ns)
+	}
+	if mbs := r.mbPerSec(); mbs != 0 {
+		add("MB/s", mbs)
+	}
+	var extraKeys []string
+	for k := range r.Extra {
+		switch k {
+		case "ns/op", "MB/s", "B/op", "allocs/op":
+			continue
+		}
+		extraKeys = append(extraKeys, k)
+	}
+	sort.Strings(extraKeys)
+	for _, k := range extraKeys {
+		add(k, r.Extra[k])
+	}
+	if mem {
+		add("B/op", float64(r.AllocedBytesPerOp()))
+		add("allocs/op", float64(r.AllocsPerOp()))
+	}
+	return units, values
+}
+
+// benchStats summarizes the values of a metric over several runs.
+type benchStats struct {
+	n        int
+	mean     float64
+	stddev   float64 // sample standard deviation
+	ci       float64 // half-width of the 95% confidence interval of the mean
+	min, max float64
+}
+
+func newBenchStats(xs []float64) benchStats {
+	s := benchStats{n: len(xs), min: math.Inf(1), max: math.Inf(-1)}
+	if s.n == 0 {
+		return benchStats{}
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += x
+		s.min = math.Min(s.min, x)
+		s.max = math.Max(s.max, x)
+	}
+	s.mean = sum / float64(s.n)
+	if s.n < 2 {
+		return s
+	}
+	var ss float64
+	for _, x := range xs {
+		d := x - s.mean
+		ss += d * d
+	}
+	s.stddev = math.Sqrt(ss / float64(s.n-1))
+	s.ci = studentT975(s.n-1) * s.stddev / math.Sqrt(float64(s.n))
+	return s
+}
+
+// studentT975 returns the 97.5th percentile of Student's t-distribution
+// with df degrees of freedom, which bounds a two-sided 95% confidence
+// interval.
+func studentT975(df int) float64 {
+	table := [...]float64{
+		12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+		2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+		2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+	}
+	switch {
+	case df < 1:
+		return math.NaN()
+	case df <= len(table):
+		return table[df-1]
+	case df <= 60:
+		return 2.000
+	case df <= 120:
+		return 1.980
+	}
+	return 1.960
+}
+
+// printBenchStats prints the statistics of the metrics of the results
+// of several runs of the benchmark name.
+func printBenchStats(w io.Writer, name string, results []BenchmarkResult, mem bool) {
+	var units []string
+	values := make(map[string][]float64)
+	for _, r := range results {
+		us, vs := benchMetrics(r, mem)
+		for _, u := range us {
+			if _, ok := values[u]; !ok {
+				units = append(units, u)
+			}
+			values[u] = append(values[u], vs[u])
+		}
+	}
+	fmt.Fprintf(w, "--- STATS: %s (%d runs)\n", name, len(results))
+	for _, u := range units {
+		s := newBenchStats(values[u])
+		fmt.Fprintf(w, "\t%s\t%s ± %s", u, formatStat(s.mean), formatStat(s.ci))
+		if s.mean != 0 {
+			fmt.Fprintf(w, " (±%.1f%%)", 100*s.ci/math.Abs(s.mean))
+		}
+		fmt.Fprintf(w, "\tstddev %s\trange [%s, %s]", formatStat(s.stddev), formatStat(s.min), formatStat(s.max))
+		if s.n < len(results) {
+			fmt.Fprintf(w, "\t(%d runs)", s.n)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// formatStat formats x with four significant digits, but without
+// an exponent for large values.
+func formatStat(x float64) string {
+	if math.Abs(x) >= 1e4 {
+		return fmt.Sprintf("%.0f", x)
+	}
+	return fmt.Sprintf("%.4g", x)
+}
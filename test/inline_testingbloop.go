@@ -0,0 +1,27 @@
+// errorcheck -0 -m
+
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test, using compiler diagnostic flags, that calls in the body
+// of a "for b.Loop()" benchmark loop are not inlined.
+
+package foo
+
+import "testing"
+
+func caninline(x int) int { // ERROR "can inline caninline"
+	return x
+}
+
+func test(b *testing.B) { // ERROR "leaking param: b"
+	caninline(1) // ERROR "inlining call to caninline"
+	for b.Loop() { // ERROR "inlining call to testing.\(\*B\).Loop"
+		caninline(1)
+		_ = caninline(2)
+	}
+	for i := 0; i < b.N; i++ {
+		caninline(1) // ERROR "inlining call to caninline"
+	}
+}